@@ -1,10 +1,15 @@
 package canvas
 
 import (
+	"bytes"
 	"image"
 	"image/color"
+	"image/jpeg"
 	"io"
+	"math"
 	"os"
+	"reflect"
+	"sort"
 )
 
 const mmPerPt = 25.4 / 72
@@ -27,18 +32,80 @@ type DPMM float64
 // DPI is a shortcut for Dots-per-Inch for the resolution of raster images.
 const DPI = DPMM(1 / 25.4)
 
+// JPEGImage is a decoded JPEG image that keeps its encoded data, so that renderers that embed images, such as PDF and SVG, can write the JPEG data untouched instead of encoding the pixels again.
+type JPEGImage struct {
+	image.Image
+	Data []byte
+}
+
+// NewJPEGImage decodes the JPEG data and returns an image that keeps the data for embedding.
+func NewJPEGImage(data []byte) (JPEGImage, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return JPEGImage{}, err
+	}
+	return JPEGImage{img, data}, nil
+}
+
 ////////////////////////////////////////////////////////////////
 
-// Style is the path style that defines how to draw the path. When FillColor is transparent it will not fill the path. If StrokeColor is transparent or StrokeWidth is zero, it will not stroke the path. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise).
+// Style is the path style that defines how to draw the path. When FillColor is transparent it will not fill the path. If StrokeColor is transparent or StrokeWidth is zero, it will not stroke the path. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise). FillCMYK and StrokeCMYK, when not nil, are the CMYK colors used instead of FillColor and StrokeColor by renderers that support the CMYK color space (PDF and EPS). FillColor and StrokeColor then hold their RGB equivalent and the alpha value, and are used by all other renderers. Similarly, FillSpot and StrokeSpot are the spot colors used by the PDF and EPS renderers.
 type Style struct {
 	FillColor    color.RGBA
+	FillPaint    Paint // overrides FillColor for renderers that support it, in which case FillColor is its representative color
 	StrokeColor  color.RGBA
+	StrokePaint  Paint // overrides StrokeColor, an AlongPathGradient is drawn by Context
+	FillCMYK     *color.CMYK
+	StrokeCMYK   *color.CMYK
+	FillSpot     *SpotColor
+	StrokeSpot   *SpotColor
 	StrokeWidth  float64
 	StrokeCapper Capper
 	StrokeJoiner Joiner
 	DashOffset   float64
 	Dashes       []float64
 	FillRule
+	Shadow             Shadow  // drawn by Context, renderers can ignore it
+	FillTransparency   float64 // one minus the opacity that multiplies the alpha of the fill by Context so that zero is opaque, renderers can ignore it
+	StrokeTransparency float64 // one minus the opacity that multiplies the alpha of the stroke by Context so that zero is opaque, renderers can ignore it
+}
+
+func (style *Style) setFillColor(col color.Color) {
+	r, g, b, a := col.RGBA()
+	style.FillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	style.FillCMYK = nil
+	style.FillSpot = nil
+	style.FillPaint = nil
+	if cmyk, ok := col.(color.CMYK); ok {
+		style.FillCMYK = &cmyk
+	} else if spot, ok := col.(SpotColor); ok {
+		style.FillSpot = &spot
+	}
+}
+
+func (style *Style) setFillPaint(paint Paint) {
+	style.FillPaint = paint
+	style.FillColor = paint.Color()
+	style.FillCMYK = nil
+	style.FillSpot = nil
+}
+
+// Shadow is a drop shadow that is drawn below paths and text, offset by (DX,DY) in the coordinate system of the context and blurred with standard deviation Sigma in mm. There is no shadow when the color is transparent.
+type Shadow struct {
+	DX, DY float64
+	Sigma  float64
+	Color  color.RGBA
+}
+
+// eraseStyle fills a region that is erased by a renderer that doesn't implement EraseRenderer.
+var eraseStyle = Style{
+	FillColor:    White,
+	StrokeColor:  Transparent,
+	StrokeWidth:  1.0,
+	StrokeCapper: ButtCap,
+	StrokeJoiner: MiterJoin,
+	Dashes:       []float64{},
+	FillRule:     NonZero,
 }
 
 // DefaultStyle is the default style for paths. It fills the path with a black color.
@@ -53,7 +120,7 @@ var DefaultStyle = Style{
 	FillRule:     NonZero,
 }
 
-// Renderer is an interface that renderers implement. It defines the size of the target (in mm) and functions to render paths, text objects and raster images.
+// Renderer is an interface that renderers implement, such as the SVG, PDF, EPS and raster backends, and can be implemented to write custom backends that are passed to Canvas.Render. It defines the size of the target (in mm) and functions to render paths, text objects and raster images. The coordinates are in millimeters with the origin in the bottom-left corner and the y-axis pointing upwards, and the matrix transforms the path, text or image to these coordinates. Images have a size of one unit per pixel before transformation, with the bottom-left corner at the origin. Text can be converted to paths with Text.RenderAsPath for renderers without text support. Renderers may additionally implement LinkRenderer, GroupRenderer, TitleRenderer, BlurRenderer and BlendRenderer.
 type Renderer interface {
 	Size() (float64, float64)
 	RenderPath(path *Path, style Style, m Matrix)
@@ -61,15 +128,152 @@ type Renderer interface {
 	RenderImage(img image.Image, m Matrix)
 }
 
+// LinkRenderer is implemented by renderers that support hyperlinks, such as PDF and SVG. All elements rendered between StartLink and EndLink are part of the link, and links may be nested. The area is the clickable region transformed by the matrix. Renderers that do not implement this interface ignore links.
+type LinkRenderer interface {
+	StartLink(url string, area *Path, m Matrix)
+	EndLink()
+}
+
+// GroupRenderer is implemented by renderers that support named groups of elements, such as SVG. All elements rendered between StartGroup and EndGroup are part of the group, and groups may be nested. The ID and class may be empty. Renderers that do not implement this interface flatten groups.
+type GroupRenderer interface {
+	StartGroup(id, class string)
+	EndGroup()
+}
+
+// TitleRenderer is implemented by renderers that support accessible titles and descriptions, such as SVG. SetTitle sets the title and description of the innermost open group, or of the document when no group is open. Either may be empty.
+type TitleRenderer interface {
+	SetTitle(title, desc string)
+}
+
+// BlurRenderer is implemented by renderers that support blurring a layer of elements, such as SVG, PDF and the rasterizer. All elements rendered between StartBlur and EndBlur are composited together, blurred with a Gaussian of standard deviation sigma (in mm), and then drawn. Blurs may be nested. Renderers that do not implement this interface draw the elements without blur.
+type BlurRenderer interface {
+	StartBlur(sigma float64)
+	EndBlur()
+}
+
+// BlendRenderer is implemented by renderers that support blend modes, such as SVG, PDF and the rasterizer. The blend mode applies to all elements rendered after SetBlendMode, and determines how their colors are mixed with the colors below them. Renderers that do not implement this interface draw all elements with BlendNormal.
+type BlendRenderer interface {
+	SetBlendMode(mode BlendMode)
+}
+
+// HitRenderer is implemented by renderers that record hit regions, such as Canvas. The hit ID applies to all paths, text and images rendered after SetHitID, and an empty ID stops tagging elements.
+type HitRenderer interface {
+	SetHitID(id string)
+}
+
+// EraseRenderer is implemented by renderers that can erase a region of what was drawn before, such as the rasterizer which makes the region transparent. Renderers that do not implement this interface fill the region with the background of the canvas, or with white if it has no background.
+type EraseRenderer interface {
+	Erase(region *Path, m Matrix)
+}
+
+// BlendMode is a separable blend mode that mixes the color of an element with the color of the backdrop below it, as defined by the W3C Compositing and Blending specification. The result is composited over the backdrop as usual.
+type BlendMode int
+
+// see BlendMode
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendColorDodge
+	BlendColorBurn
+	BlendHardLight
+	BlendSoftLight
+	BlendDifference
+	BlendExclusion
+)
+
+// String returns the name of the blend mode as used by the CSS mix-blend-mode property.
+func (mode BlendMode) String() string {
+	switch mode {
+	case BlendMultiply:
+		return "multiply"
+	case BlendScreen:
+		return "screen"
+	case BlendOverlay:
+		return "overlay"
+	case BlendDarken:
+		return "darken"
+	case BlendLighten:
+		return "lighten"
+	case BlendColorDodge:
+		return "color-dodge"
+	case BlendColorBurn:
+		return "color-burn"
+	case BlendHardLight:
+		return "hard-light"
+	case BlendSoftLight:
+		return "soft-light"
+	case BlendDifference:
+		return "difference"
+	case BlendExclusion:
+		return "exclusion"
+	}
+	return "normal"
+}
+
+// Blend returns the blended color channel for the backdrop color cb and source color cs, which are not premultiplied and between 0 and 1.
+func (mode BlendMode) Blend(cb, cs float64) float64 {
+	switch mode {
+	case BlendMultiply:
+		return cb * cs
+	case BlendScreen:
+		return cb + cs - cb*cs
+	case BlendOverlay:
+		return BlendHardLight.Blend(cs, cb)
+	case BlendDarken:
+		return math.Min(cb, cs)
+	case BlendLighten:
+		return math.Max(cb, cs)
+	case BlendColorDodge:
+		if cb == 0.0 {
+			return 0.0
+		} else if cs == 1.0 {
+			return 1.0
+		}
+		return math.Min(1.0, cb/(1.0-cs))
+	case BlendColorBurn:
+		if cb == 1.0 {
+			return 1.0
+		} else if cs == 0.0 {
+			return 0.0
+		}
+		return 1.0 - math.Min(1.0, (1.0-cb)/cs)
+	case BlendHardLight:
+		if cs <= 0.5 {
+			return BlendMultiply.Blend(cb, 2.0*cs)
+		}
+		return BlendScreen.Blend(cb, 2.0*cs-1.0)
+	case BlendSoftLight:
+		if cs <= 0.5 {
+			return cb - (1.0-2.0*cs)*cb*(1.0-cb)
+		}
+		d := math.Sqrt(cb)
+		if cb <= 0.25 {
+			d = ((16.0*cb-12.0)*cb + 4.0) * cb
+		}
+		return cb + (2.0*cs-1.0)*(d-cb)
+	case BlendDifference:
+		return math.Abs(cb - cs)
+	case BlendExclusion:
+		return cb + cs - 2.0*cb*cs
+	}
+	return cs
+}
+
 ////////////////////////////////////////////////////////////////
 
+// CoordSystem is the orientation of the coordinate system of a context, given by the quadrant of the Cartesian plane that covers the canvas.
 type CoordSystem int
 
+// see CoordSystem
 const (
-	CartesianI CoordSystem = iota
-	CartesianII
-	CartesianIII
-	CartesianIV
+	CartesianI   CoordSystem = iota // origin at the bottom-left with the y-axis pointing up, which is the default
+	CartesianII                     // origin at the bottom-right with the x-axis pointing left
+	CartesianIII                    // origin at the top-right with the x-axis pointing left and the y-axis pointing down
+	CartesianIV                     // origin at the top-left with the y-axis pointing down, as for images, HTML canvases and go-chart
 )
 
 // Context maintains the state for the current path, path style, and view transformation matrix.
@@ -83,11 +287,23 @@ type Context struct {
 	viewStack      []Matrix
 	coordView      Matrix
 	coordViewStack []Matrix
+	opacity        opacity
+	opacityStack   []opacity
+	blend          BlendMode
+	blendStack     []BlendMode
+	rendererBlend  BlendMode // blend mode that was last set on the renderer
+	hitID          string
+}
+
+// opacity is the global alpha of a context, which is relative to that of the enclosing draw state.
+type opacity struct {
+	alpha       float64 // global alpha that is applied
+	parentAlpha float64 // global alpha at the last Push
 }
 
 // NewContext returns a new Context which is a wrapper around a Renderer. Context maintains state for the current path, path style, and view transformation matrix.
 func NewContext(r Renderer) *Context {
-	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, Identity, nil}
+	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, Identity, nil, opacity{1.0, 1.0}, nil, BlendNormal, nil, BlendNormal, ""}
 }
 
 // Width returns the width of the canvas.
@@ -107,6 +323,9 @@ func (c *Context) Push() {
 	c.styleStack = append(c.styleStack, c.Style)
 	c.viewStack = append(c.viewStack, c.view)
 	c.coordViewStack = append(c.coordViewStack, c.coordView)
+	c.opacityStack = append(c.opacityStack, c.opacity)
+	c.opacity.parentAlpha = c.opacity.alpha
+	c.blendStack = append(c.blendStack, c.blend)
 }
 
 // Pop restores the last pushed draw state and uses that as the current draw state. If there are no states on the stack, this will do nothing.
@@ -120,14 +339,18 @@ func (c *Context) Pop() {
 	c.viewStack = c.viewStack[:len(c.viewStack)-1]
 	c.coordView = c.coordViewStack[len(c.coordViewStack)-1]
 	c.coordViewStack = c.coordViewStack[:len(c.coordViewStack)-1]
+	c.opacity = c.opacityStack[len(c.opacityStack)-1]
+	c.opacityStack = c.opacityStack[:len(c.opacityStack)-1]
+	c.blend = c.blendStack[len(c.blendStack)-1]
+	c.blendStack = c.blendStack[:len(c.blendStack)-1]
 }
 
-// SetCoordView sets the current affine transformation matrix through which all operation coordinates will be transformed.
+// SetCoordView sets the current affine transformation matrix through which all operation coordinates will be transformed. The coordinates of the current path are transformed as a whole, while paths, text and images drawn at a position are only moved to the transformed position, so that text stays upright in a mirrored coordinate system.
 func (c *Context) SetCoordView(rect Rect, width, height float64) {
 	c.coordView = Identity.Translate(rect.X, rect.Y).Scale(rect.W/width, rect.H/height)
 }
 
-// SetCoordSystem sets the current affine transformation matrix through which all operation coordinates will be transformed as a Cartesian coordinate system.
+// SetCoordSystem sets the current affine transformation matrix through which all operation coordinates will be transformed as a Cartesian coordinate system, such as CartesianIV to draw with the origin at the top-left and the y-axis pointing down. As for SetCoordView, the current path is transformed as a whole, while paths, text and images drawn at a position keep their orientation. The view is applied after the coordinate system.
 func (c *Context) SetCoordSystem(coordSystem CoordSystem) {
 	w, h := c.Size()
 	switch coordSystem {
@@ -217,16 +440,56 @@ func (c *Context) ShearAbout(sx, sy, x, y float64) {
 	c.view = c.view.Mul(Identity.ShearAbout(sx, sy, x, y))
 }
 
-// SetFillColor sets the color to be used for filling operations.
+// SetFillColor sets the color to be used for filling operations. A color.CMYK color is kept as CMYK for renderers that support it, other renderers use the naive conversion to RGB given by color.CMYK.RGBA, i.e. R = 255*(1-C)*(1-K). Likewise, a SpotColor is kept for renderers that support it while others use its alternate color.
 func (c *Context) SetFillColor(col color.Color) {
-	r, g, b, a := col.RGBA()
-	c.Style.FillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.setFillColor(col)
+}
+
+// SetFillPaint sets the paint to be used for filling operations, such as a LinearGradient, whose coordinates are in the coordinate system of the paths so that it is transformed along with them. The fill color is set to the representative color of the paint for renderers that do not support it.
+func (c *Context) SetFillPaint(paint Paint) {
+	c.Style.setFillPaint(paint)
+}
+
+// SetStrokePaint sets the paint to be used for stroking operations. An AlongPathGradient colors the stroke by the position along the path and is drawn by splitting the path into pieces of about constant color, which works for all renderers. The stroke color is set to the representative color of the paint for renderers that do not support it.
+func (c *Context) SetStrokePaint(paint Paint) {
+	c.Style.StrokePaint = paint
+	c.Style.StrokeColor = paint.Color()
+	c.Style.StrokeCMYK = nil
+	c.Style.StrokeSpot = nil
 }
 
-// SetStrokeColor sets the color to be used for stroking operations.
+// SetStrokeColor sets the color to be used for stroking operations. CMYK and spot colors are kept for renderers that support them, see SetFillColor.
 func (c *Context) SetStrokeColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.Style.StrokeColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.StrokeCMYK = nil
+	c.Style.StrokeSpot = nil
+	c.Style.StrokePaint = nil
+	if cmyk, ok := col.(color.CMYK); ok {
+		c.Style.StrokeCMYK = &cmyk
+	} else if spot, ok := col.(SpotColor); ok {
+		c.Style.StrokeSpot = &spot
+	}
+}
+
+// SetFillOpacity sets the opacity between 0 and 1 of fills and text, which multiplies the alpha of the fill color or paint.
+func (c *Context) SetFillOpacity(opacity float64) {
+	c.Style.FillTransparency = 1.0 - math.Max(0.0, math.Min(1.0, opacity))
+}
+
+// SetStrokeOpacity sets the opacity between 0 and 1 of strokes, which multiplies the alpha of the stroke color or paint.
+func (c *Context) SetStrokeOpacity(opacity float64) {
+	c.Style.StrokeTransparency = 1.0 - math.Max(0.0, math.Min(1.0, opacity))
+}
+
+// SetGlobalAlpha sets the opacity between 0 and 1 of everything that is drawn, including images and shadows, until the draw state is popped. It is relative to the global alpha of the draw state at the last Push, so that nested draw states compose their alphas.
+func (c *Context) SetGlobalAlpha(alpha float64) {
+	c.opacity.alpha = c.opacity.parentAlpha * math.Max(0.0, math.Min(1.0, alpha))
+}
+
+// SetBlendMode sets the blend mode that mixes the colors of everything that is drawn with the colors below it, until the draw state is popped. It is ignored by renderers that do not implement BlendRenderer.
+func (c *Context) SetBlendMode(mode BlendMode) {
+	c.blend = mode
 }
 
 // SetStrokeWidth sets the width in mm for stroking operations.
@@ -244,6 +507,17 @@ func (c *Context) SetStrokeJoiner(joiner Joiner) {
 	c.Style.StrokeJoiner = joiner
 }
 
+// SetMiterLimit sets the miter limit of the current miter or arcs line join, which is the maximum ratio of the miter length to the stroke width before the join falls back to a bevel join. It is 2 by default, and has no effect on other line joins.
+func (c *Context) SetMiterLimit(limit float64) {
+	if miter, ok := c.Style.StrokeJoiner.(MiterJoiner); ok {
+		miter.Limit = limit
+		c.Style.StrokeJoiner = miter
+	} else if arcs, ok := c.Style.StrokeJoiner.(ArcsJoiner); ok {
+		arcs.Limit = limit
+		c.Style.StrokeJoiner = arcs
+	}
+}
+
 // SetDashes sets the dash pattern to be used for stroking operations. The dash offset denotes the offset into the dash array in mm from where to start. Negative values are allowed.
 func (c *Context) SetDashes(offset float64, dashes ...float64) {
 	c.Style.DashOffset = offset
@@ -255,9 +529,34 @@ func (c *Context) SetFillRule(rule FillRule) {
 	c.Style.FillRule = rule
 }
 
+// SetShadow sets the drop shadow for paths and text, which is offset by (dx,dy) in the current coordinate system and view and blurred with standard deviation sigma in mm. The silhouettes of the shapes are drawn in the shadow color, blurred as with StartBlur, and then the shapes are drawn on top. Renderers that do not implement BlurRenderer draw an unblurred shadow. A transparent color removes the shadow.
+func (c *Context) SetShadow(dx, dy, sigma float64, col color.Color) {
+	r, g, b, a := col.RGBA()
+	c.Style.Shadow = Shadow{dx, dy, sigma, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}}
+}
+
 // ResetStyle resets the draw state to its default (colors, stroke widths, dashes, ...).
 func (c *Context) ResetStyle() {
 	c.Style = DefaultStyle
+	c.blend = BlendNormal
+}
+
+// SetStyle sets all of the style at once, including the fill and stroke opacities, such as one that was saved from the Style field before to switch between the styles of for example axes, grids and series. A saved style reproduces the same output. The dashes are copied so that the style can be stored and reused. A nil capper or joiner is taken from DefaultStyle, so that a partial style such as Style{FillColor: Red} can be used, and the zero Style resets the style like ResetStyle. Other fields are used as is, where the zero color is transparent, the zero stroke width draws no stroke, and the zero transparencies are opaque. The blend mode is not part of the style and is kept.
+func (c *Context) SetStyle(style Style) {
+	if reflect.DeepEqual(style, Style{}) {
+		c.ResetStyle()
+		return
+	}
+	if style.StrokeCapper == nil {
+		style.StrokeCapper = DefaultStyle.StrokeCapper
+	}
+	if style.StrokeJoiner == nil {
+		style.StrokeJoiner = DefaultStyle.StrokeJoiner
+	}
+	style.Dashes = append([]float64{}, style.Dashes...)
+	style.FillTransparency = math.Max(0.0, math.Min(1.0, style.FillTransparency))
+	style.StrokeTransparency = math.Max(0.0, math.Min(1.0, style.StrokeTransparency))
+	c.Style = style
 }
 
 // Pos returns the current position of the path, which is the end point of the last command.
@@ -312,33 +611,101 @@ func (c *Context) Close() {
 func (c *Context) Fill() {
 	style := c.Style
 	style.StrokeColor = Transparent
-	c.RenderPath(c.path, style, c.view)
+	style.StrokePaint = nil
+	c.RenderPath(c.path, style, c.pathView())
 	c.path = &Path{}
 }
 
 // Stroke strokes the current path and resets it.
 func (c *Context) Stroke() {
+	if gradient, ok := c.Style.StrokePaint.(AlongPathGradient); ok {
+		c.drawAlongPath(c.path, gradient, c.pathView(), false)
+		c.path = &Path{}
+		return
+	}
 	style := c.Style
 	style.FillColor = Transparent
-	c.RenderPath(c.path, style, c.view)
+	style.FillPaint = nil
+	c.RenderPath(c.path, style, c.pathView())
 	c.path = &Path{}
 }
 
 // FillStroke fills and then strokes the current path and resets it.
 func (c *Context) FillStroke() {
-	c.RenderPath(c.path, c.Style, c.view)
+	if gradient, ok := c.Style.StrokePaint.(AlongPathGradient); ok {
+		c.drawAlongPath(c.path, gradient, c.pathView(), true)
+		c.path = &Path{}
+		return
+	}
+	c.RenderPath(c.path, c.Style, c.pathView())
 	c.path = &Path{}
 }
 
+// pathView returns the transformation of the current path, which is in the coordinates of the coordinate system.
+func (c *Context) pathView() Matrix {
+	return c.view.Mul(c.coordView)
+}
+
+// RenderPath renders a path to the underlying renderer, where the opacity of the fill and stroke are multiplied by the fill opacity, stroke opacity and global alpha of the draw state.
+func (c *Context) RenderPath(path *Path, style Style, m Matrix) {
+	style = styleAlpha(style, (1.0-c.Style.FillTransparency)*c.opacity.alpha, (1.0-c.Style.StrokeTransparency)*c.opacity.alpha)
+	c.applyBlendMode()
+	c.Renderer.RenderPath(path, style, m)
+}
+
+// RenderText renders text to the underlying renderer, where the opacity of the text is multiplied by the fill opacity and global alpha of the draw state.
+func (c *Context) RenderText(text *Text, m Matrix) {
+	if alpha := (1.0 - c.Style.FillTransparency) * c.opacity.alpha; alpha != 1.0 {
+		text = text.withAlpha(alpha)
+	}
+	c.applyBlendMode()
+	c.Renderer.RenderText(text, m)
+}
+
+// RenderImage renders an image to the underlying renderer, where the opacity of the image is multiplied by the global alpha of the draw state.
+func (c *Context) RenderImage(img image.Image, m Matrix) {
+	c.applyBlendMode()
+	c.Renderer.RenderImage(imageAlpha(img, c.opacity.alpha), m)
+}
+
+// applyBlendMode sets the blend mode of the draw state on the underlying renderer if it has changed.
+func (c *Context) applyBlendMode() {
+	if c.blend != c.rendererBlend {
+		if r, ok := c.Renderer.(BlendRenderer); ok {
+			r.SetBlendMode(c.blend)
+		}
+		c.rendererBlend = c.blend
+	}
+}
+
 // DrawPath draws a path at position (x,y) using the current draw state.
 func (c *Context) DrawPath(x, y float64, paths ...*Path) {
-	if c.Style.FillColor.A == 0 && (c.Style.StrokeColor.A == 0 || c.Style.StrokeWidth == 0.0) {
+	if c.Style.FillColor.A == 0 && c.Style.FillPaint == nil && (c.Style.StrokeColor.A == 0 && c.Style.StrokePaint == nil || c.Style.StrokeWidth == 0.0) {
 		return
 	}
 
 	coord := c.coordView.Dot(Point{x, y})
 	m := c.view.Translate(coord.X, coord.Y)
+	if c.Style.Shadow.Color.A != 0 {
+		r := c.startShadow()
+		ms := c.shadowView().Translate(coord.X, coord.Y)
+		for _, path := range paths {
+			var dashes []float64
+			path, dashes = path.checkDash(c.Style.DashOffset, c.Style.Dashes)
+			if !path.Empty() {
+				style := c.Style
+				style.Dashes = dashes
+				r.RenderPath(path, style, ms)
+			}
+		}
+		c.endShadow()
+	}
 	for _, path := range paths {
+		if gradient, ok := c.Style.StrokePaint.(AlongPathGradient); ok {
+			c.drawAlongPath(path, gradient, m, true)
+			continue
+		}
+
 		var dashes []float64
 		path, dashes = path.checkDash(c.Style.DashOffset, c.Style.Dashes)
 		if path.Empty() {
@@ -350,10 +717,138 @@ func (c *Context) DrawPath(x, y float64, paths ...*Path) {
 	}
 }
 
+// DrawLine strokes a line from (x1,y1) to (x2,y2) using the current draw state, it is not filled. Nothing is drawn when both points are the same.
+func (c *Context) DrawLine(x1, y1, x2, y2 float64) {
+	c.DrawPolyline(Point{x1, y1}, Point{x2, y2})
+}
+
+// DrawPolyline strokes the lines through the points using the current draw state, they are not filled. Nothing is drawn when there are less than two distinct points.
+func (c *Context) DrawPolyline(points ...Point) {
+	if p := polylinePath(points); p != nil {
+		style := c.Style
+		c.Style.FillColor, c.Style.FillPaint = Transparent, nil
+		c.Style.FillCMYK, c.Style.FillSpot = nil, nil
+		c.drawShape(points[0].X, points[0].Y, p)
+		c.Style = style
+	}
+}
+
+// DrawPolygon draws the closed polygon through the points using the current draw state. Nothing is drawn when there are less than two distinct points.
+func (c *Context) DrawPolygon(points ...Point) {
+	if p := polylinePath(points); p != nil {
+		p.Close()
+		c.drawShape(points[0].X, points[0].Y, p)
+	}
+}
+
+// DrawRectangle draws a rectangle with its corner at (x,y) and width w and height h using the current draw state. Nothing is drawn when the width or height is zero.
+func (c *Context) DrawRectangle(x, y, w, h float64) {
+	c.drawShape(x, y, Rectangle(w, h))
+}
+
+// DrawEllipse draws an ellipse centered at (cx,cy) with radii rx and ry using the current draw state. Nothing is drawn when either radius is zero.
+func (c *Context) DrawEllipse(cx, cy, rx, ry float64) {
+	c.drawShape(cx, cy, Ellipse(rx, ry))
+}
+
+// DrawCircle draws a circle centered at (cx,cy) with radius r using the current draw state. Nothing is drawn when the radius is zero.
+func (c *Context) DrawCircle(cx, cy, r float64) {
+	c.drawShape(cx, cy, Circle(r))
+}
+
+// drawShape draws a path with coordinates relative to position (x,y) like DrawPath, but the path is mirrored along with the position by the coordinate system so that all coordinates of the shape are in the coordinate system.
+func (c *Context) drawShape(x, y float64, p *Path) {
+	if p.Empty() {
+		return
+	}
+	linear := c.coordView
+	linear[0][2], linear[1][2] = 0.0, 0.0
+	if !linear.Equals(Identity) {
+		p = p.Transform(linear)
+	}
+	c.DrawPath(x, y, p)
+}
+
+// polylinePath returns the path through the points relative to the first point, or nil if there are less than two distinct points.
+func polylinePath(points []Point) *Path {
+	distinct := false
+	for i := 1; i < len(points); i++ {
+		if !points[i].Equals(points[0]) {
+			distinct = true
+			break
+		}
+	}
+	if !distinct {
+		return nil
+	}
+
+	p := &Path{}
+	for _, point := range points[1:] {
+		p.LineTo(point.X-points[0].X, point.Y-points[0].Y)
+	}
+	return p
+}
+
+// drawAlongPath optionally fills the path, and draws its stroke in pieces that are colored by the gradient along the path. The pieces are drawn from last to first and are extended backwards by at least the stroke width, so that the anti-aliased start of each piece is covered by the previous piece and no seams appear. Line caps are drawn first by the first and last pieces of each open subpath.
+func (c *Context) drawAlongPath(path *Path, gradient AlongPathGradient, m Matrix, fill bool) {
+	style := c.Style
+	if fill && (style.FillColor.A != 0 || style.FillPaint != nil) {
+		style.StrokeColor, style.StrokePaint = Transparent, nil
+		c.RenderPath(path, style, m)
+	}
+	if c.Style.StrokeWidth <= 0.0 {
+		return
+	}
+
+	style = c.Style
+	style.FillColor, style.FillPaint = Transparent, nil
+	style.FillCMYK, style.FillSpot = nil, nil
+	style.StrokeCMYK, style.StrokeSpot = nil, nil
+	style.StrokePaint = nil
+	render := func(p *Path, col color.RGBA, offset float64, capper Capper) {
+		q, dashes := p.checkDash(c.Style.DashOffset+offset, c.Style.Dashes)
+		if q.Empty() || col.A == 0 {
+			return
+		}
+		style.StrokeColor = col
+		style.StrokeCapper = capper
+		style.DashOffset = c.Style.DashOffset + offset
+		style.Dashes = dashes
+		c.RenderPath(q, style, m)
+	}
+
+	pieces := gradient.pieces(path)
+	if _, ok := c.Style.StrokeCapper.(ButtCapper); !ok {
+		for _, piece := range pieces {
+			if piece.first || piece.last {
+				render(piece.path, piece.col, piece.offset, c.Style.StrokeCapper)
+			}
+		}
+	}
+	for i := len(pieces) - 1; 0 <= i; i-- {
+		p, offset := pieces[i].path, pieces[i].offset
+		for j := i - 1; 0 <= j && pieces[j].next && pieces[i].offset-offset < c.Style.StrokeWidth; j-- {
+			p = pieces[j].path.Copy().Join(p.Copy())
+			offset = pieces[j].offset
+		}
+		render(p, pieces[i].col, offset, ButtCap)
+	}
+}
+
 // DrawText draws text at position (x,y) using the current draw state. In particular, it only uses the current affine transformation matrix.
 func (c *Context) DrawText(x, y float64, texts ...*Text) {
 	coord := c.coordView.Dot(Point{x, y})
 	m := c.view.Translate(coord.X, coord.Y)
+	if c.Style.Shadow.Color.A != 0 {
+		r := c.startShadow()
+		ms := c.shadowView().Translate(coord.X, coord.Y)
+		for _, text := range texts {
+			if !text.Empty() {
+				r.RenderText(text, ms)
+			}
+		}
+		c.endShadow()
+	}
 	for _, text := range texts {
 		if text.Empty() {
 			continue
@@ -362,6 +857,57 @@ func (c *Context) DrawText(x, y float64, texts ...*Text) {
 	}
 }
 
+// startShadow starts the blur of the shadow and returns a renderer that draws silhouettes in the shadow color. It must be followed by endShadow.
+func (c *Context) startShadow() Renderer {
+	c.applyBlendMode()
+	if r, ok := c.Renderer.(HitRenderer); ok && c.hitID != "" {
+		r.SetHitID("")
+	}
+	c.StartBlur(c.Style.Shadow.Sigma)
+	return shadowRenderer{c.Renderer, scaleAlpha(c.Style.Shadow.Color, c.opacity.alpha)}
+}
+
+// endShadow ends the blur of the shadow.
+func (c *Context) endShadow() {
+	c.EndBlur()
+	if r, ok := c.Renderer.(HitRenderer); ok && c.hitID != "" {
+		r.SetHitID(c.hitID)
+	}
+}
+
+// shadowView returns the view translated by the shadow offset in the current coordinate system.
+func (c *Context) shadowView() Matrix {
+	d := c.coordView.Dot(Point{c.Style.Shadow.DX, c.Style.Shadow.DY}).Sub(c.coordView.Dot(Point{}))
+	return c.view.Translate(d.X, d.Y)
+}
+
+// shadowRenderer renders the silhouettes of paths and text in a single color. Images are not drawn.
+type shadowRenderer struct {
+	Renderer
+	col color.RGBA
+}
+
+func (r shadowRenderer) RenderPath(path *Path, style Style, m Matrix) {
+	if style.FillColor.A != 0 || style.FillPaint != nil {
+		style.FillColor = r.col
+	}
+	if style.StrokeColor.A != 0 || style.StrokePaint != nil {
+		style.StrokeColor = r.col
+	}
+	style.FillPaint, style.StrokePaint = nil, nil
+	style.FillCMYK, style.StrokeCMYK = nil, nil
+	style.FillSpot, style.StrokeSpot = nil, nil
+	style.Shadow = Shadow{}
+	r.Renderer.RenderPath(path, style, m)
+}
+
+func (r shadowRenderer) RenderText(text *Text, m Matrix) {
+	text.RenderAsPath(r, m)
+}
+
+func (r shadowRenderer) RenderImage(img image.Image, m Matrix) {
+}
+
 // DrawImage draws an image at position (x,y), using an image encoding (Lossy or Lossless) and DPM (dots-per-millimeter). A higher DPM will draw a smaller image.
 func (c *Context) DrawImage(x, y float64, img image.Image, dpm float64) {
 	if img.Bounds().Size().Eq(image.Point{}) {
@@ -373,24 +919,134 @@ func (c *Context) DrawImage(x, y float64, img image.Image, dpm float64) {
 	c.RenderImage(img, m)
 }
 
+// DrawImageTransformed draws an image using the current view, where the matrix maps the image to the coordinates of the context. The image has a size of one unit per pixel before transformation, with the bottom-left corner at the origin, so that Identity.Translate(x, y).Rotate(30.0).Scale(0.1, 0.1) draws the image rotated at position (x,y) at 10 pixels per millimeter.
+func (c *Context) DrawImageTransformed(img image.Image, m Matrix) {
+	if img.Bounds().Size().Eq(image.Point{}) {
+		return
+	}
+
+	coord := c.coordView.Dot(Point{m[0][2], m[1][2]})
+	m[0][2], m[1][2] = 0.0, 0.0
+	c.RenderImage(img, c.view.Translate(coord.X, coord.Y).Mul(m))
+}
+
+// SetHitID tags the paths, text and images drawn after it with the ID, so that they can be found by Canvas.HitTest, such as the data element under the cursor. An empty ID stops tagging. Hit IDs are ignored by renderers that do not implement HitRenderer, and shadows are not tagged.
+func (c *Context) SetHitID(id string) {
+	c.hitID = id
+	if r, ok := c.Renderer.(HitRenderer); ok {
+		r.SetHitID(id)
+	}
+}
+
+// Erase erases the region at position (x,y) using the current view, removing the elements drawn before within the region. Renderers that support it make the region transparent, others fill the region with the background of the canvas or with white, see EraseRenderer.
+func (c *Context) Erase(x, y float64, region *Path) {
+	coord := c.coordView.Dot(Point{x, y})
+	m := c.view.Translate(coord.X, coord.Y)
+	if r, ok := c.Renderer.(EraseRenderer); ok {
+		r.Erase(region, m)
+		return
+	}
+	blend := c.blend
+	c.blend = BlendNormal
+	c.applyBlendMode()
+	c.Renderer.RenderPath(region, eraseStyle, m)
+	c.blend = blend
+}
+
+// StartLink starts a hyperlink to the URL with the given clickable area drawn at position (x,y) using the current view, in the same way as DrawPath. All elements drawn until EndLink are part of the link. Links are ignored by renderers that do not implement LinkRenderer.
+func (c *Context) StartLink(url string, x, y float64, area *Path) {
+	if r, ok := c.Renderer.(LinkRenderer); ok {
+		coord := c.coordView.Dot(Point{x, y})
+		r.StartLink(url, area, c.view.Translate(coord.X, coord.Y))
+	}
+}
+
+// EndLink ends the last started hyperlink.
+func (c *Context) EndLink() {
+	if r, ok := c.Renderer.(LinkRenderer); ok {
+		r.EndLink()
+	}
+}
+
+// StartGroup starts a group with the given ID and class, which may be empty. All elements drawn until EndGroup are part of the group, such as a layer in SVG output. Groups are ignored by renderers that do not implement GroupRenderer.
+func (c *Context) StartGroup(id, class string) {
+	if r, ok := c.Renderer.(GroupRenderer); ok {
+		r.StartGroup(id, class)
+	}
+}
+
+// EndGroup ends the last started group.
+func (c *Context) EndGroup() {
+	if r, ok := c.Renderer.(GroupRenderer); ok {
+		r.EndGroup()
+	}
+}
+
+// SetTitle sets the title and description of the current group, or of the document when no group is open, which are used by assistive technologies such as screen readers. They are ignored by renderers that do not implement TitleRenderer.
+func (c *Context) SetTitle(title, desc string) {
+	if r, ok := c.Renderer.(TitleRenderer); ok {
+		r.SetTitle(title, desc)
+	}
+}
+
+// StartBlur starts a layer that is blurred with a Gaussian of standard deviation sigma in mm, which is not affected by the current view. All elements drawn until EndBlur are part of the layer. Blurs are ignored by renderers that do not implement BlurRenderer.
+func (c *Context) StartBlur(sigma float64) {
+	if r, ok := c.Renderer.(BlurRenderer); ok {
+		r.StartBlur(sigma)
+	}
+}
+
+// EndBlur ends the last started blur layer and draws it.
+func (c *Context) EndBlur() {
+	if r, ok := c.Renderer.(BlurRenderer); ok {
+		r.EndBlur()
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 ////////////////////////////////////////////////////////////////
 ////////////////////////////////////////////////////////////////
 
+type link struct {
+	url  string
+	area *Path
+}
+
+type group struct {
+	id, class string
+}
+
+type titleDesc struct {
+	title, desc string
+}
+
 type layer struct {
-	// path, text OR img is set
-	path *Path
-	text *Text
-	img  image.Image
+	// path, text, img, link, linkEnd, group, groupEnd, title, blur, blurEnd, blend OR erase is set
+	path     *Path
+	text     *Text
+	img      image.Image
+	link     *link
+	linkEnd  bool
+	group    *group
+	groupEnd bool
+	title    *titleDesc
+	blur     *float64
+	blurEnd  bool
+	blend    *BlendMode
+	erase    *Path
 
 	m     Matrix
-	style Style // only for path
+	style Style  // only for path
+	hitID string // only for path, text and img
 }
 
-// Canvas stores all drawing operations as layers that can be re-rendered to other renderers.
+// Canvas stores all drawing operations as layers that can be re-rendered to other renderers. A canvas and its contexts are not safe for concurrent use, to draw on multiple goroutines each goroutine draws to its own canvas from NewSubCanvas, which are then combined by Merge.
 type Canvas struct {
-	layers []layer
-	W, H   float64
+	layers     []layer
+	named      []*Layer // named layers in the order they were created
+	background *Style   // fill of the background, if any
+	hitID      string   // hit ID of the elements that are rendered
+	W, H       float64
 }
 
 // New returns a new Canvas that records all drawing operations into layers. The canvas can then be rendered to any other renderer.
@@ -410,78 +1066,408 @@ func (c *Canvas) Size() (float64, float64) {
 // RenderPath renders a path to the canvas using a style and a transformation matrix.
 func (c *Canvas) RenderPath(path *Path, style Style, m Matrix) {
 	path = path.Copy()
-	c.layers = append(c.layers, layer{path: path, m: m, style: style})
+	style.Dashes = append([]float64{}, style.Dashes...)
+	c.layers = append(c.layers, layer{path: path, m: m, style: style, hitID: c.hitID})
 }
 
 // RenderText renders a text object to the canvas using a transformation matrix.
 func (c *Canvas) RenderText(text *Text, m Matrix) {
-	c.layers = append(c.layers, layer{text: text, m: m})
+	c.layers = append(c.layers, layer{text: text, m: m, hitID: c.hitID})
 }
 
 // RenderImage renders an image to the canvas using a transformation matrix.
 func (c *Canvas) RenderImage(img image.Image, m Matrix) {
-	c.layers = append(c.layers, layer{img: img, m: m})
+	c.layers = append(c.layers, layer{img: img, m: m, hitID: c.hitID})
+}
+
+// StartLink starts a hyperlink to the URL with the given clickable area and transformation matrix.
+func (c *Canvas) StartLink(url string, area *Path, m Matrix) {
+	c.layers = append(c.layers, layer{link: &link{url, area.Copy()}, m: m})
+}
+
+// EndLink ends the last started hyperlink.
+func (c *Canvas) EndLink() {
+	c.layers = append(c.layers, layer{linkEnd: true})
+}
+
+// StartGroup starts a group with the given ID and class.
+func (c *Canvas) StartGroup(id, class string) {
+	c.layers = append(c.layers, layer{group: &group{id, class}})
+}
+
+// EndGroup ends the last started group.
+func (c *Canvas) EndGroup() {
+	c.layers = append(c.layers, layer{groupEnd: true})
+}
+
+// SetTitle sets the title and description of the current group or of the document.
+func (c *Canvas) SetTitle(title, desc string) {
+	c.layers = append(c.layers, layer{title: &titleDesc{title, desc}})
+}
+
+// StartBlur starts a layer that is blurred with the given standard deviation in mm.
+func (c *Canvas) StartBlur(sigma float64) {
+	c.layers = append(c.layers, layer{blur: &sigma})
+}
+
+// EndBlur ends the last started blur layer.
+func (c *Canvas) EndBlur() {
+	c.layers = append(c.layers, layer{blurEnd: true})
+}
+
+// SetBlendMode sets the blend mode of the elements that follow.
+func (c *Canvas) SetBlendMode(mode BlendMode) {
+	c.layers = append(c.layers, layer{blend: &mode})
+}
+
+// SetBackground sets the background color of the canvas, with which renderers fill the whole canvas before drawing the elements, such as the image of the rasterizer or the media box of a PDF. This is needed for formats without transparency such as JPEG, for which a transparent background turns black. The background always covers the canvas, also after resizing or Fit, and is removed by a transparent color.
+func (c *Canvas) SetBackground(col color.Color) {
+	if _, _, _, a := col.RGBA(); a == 0 {
+		c.background = nil
+		return
+	}
+	style := DefaultStyle
+	style.setFillColor(col)
+	c.background = &style
+}
+
+// SetBackgroundPaint sets the background of the canvas like SetBackground but with a paint, such as a LinearGradient, whose coordinates are in millimeters of the canvas.
+func (c *Canvas) SetBackgroundPaint(paint Paint) {
+	style := DefaultStyle
+	style.setFillPaint(paint)
+	c.background = &style
+}
+
+// Layer returns the named layer with its own drawing context, creating it on top of all other layers if it doesn't exist. Elements drawn to a layer are stacked by the z-order of the layers regardless of the order of drawing, and elements drawn to the canvas directly are at a z-index of zero below named layers of the same z-index. Renderers receive each layer as a group with the name as its ID, so that for example the SVG renderer writes a <g id="name"> element.
+func (c *Canvas) Layer(name string) *Layer {
+	top := 0
+	for _, l := range c.named {
+		if l.name == name {
+			return l
+		} else if top < l.z {
+			top = l.z
+		}
+	}
+	l := &Layer{
+		name:     name,
+		z:        top + 1,
+		visible:  true,
+		elements: New(c.W, c.H),
+	}
+	l.Context = NewContext(layerRenderer{l.elements, c})
+	c.named = append(c.named, l)
+	return l
+}
+
+// NewSubCanvas returns a new empty canvas of the same size, to be drawn to independently, for example on another goroutine, and combined with the canvas by Merge.
+func (c *Canvas) NewSubCanvas() *Canvas {
+	return New(c.W, c.H)
+}
+
+// Merge appends the elements of the child canvas transformed by the matrix on top of the elements drawn to the canvas directly, so that the merged order is the order of the calls to Merge regardless of when the children were drawn. The elements are shared with the child and not copied, and the child can be reset or drawn to afterwards without affecting the canvas. The background of the child is merged as a rectangle of its size, its named layers as groups in their z-order, and regions erased by the child also erase the elements of the canvas below it.
+func (c *Canvas) Merge(child *Canvas, m Matrix) {
+	if child.background != nil {
+		c.layers = append(c.layers, layer{path: Rectangle(child.W, child.H), m: m, style: *child.background})
+	}
+	elems := child.elements()
+	merged := make([]layer, len(elems))
+	for i, l := range elems {
+		l.m = m.Mul(l.m)
+		merged[i] = l
+	}
+	c.layers = appendBlended(c.layers, merged)
+}
+
+// SetHitID sets the hit ID of the paths, text and images rendered after it, see Context.SetHitID.
+func (c *Canvas) SetHitID(id string) {
+	c.hitID = id
+}
+
+// HitTest returns the hit IDs of the elements that contain the point (x,y) in millimeters of the canvas, with the ID of the topmost element first and without duplicates, see Context.SetHitID. Paths are hit by their fill or their stroke outline, text by its bounding box, and images by their area. Elements of hidden layers are not hit.
+func (c *Canvas) HitTest(x, y float64) []string {
+	ids := []string{}
+	elems := c.elements()
+Elements:
+	for i := len(elems) - 1; 0 <= i; i-- {
+		if elems[i].hitID == "" {
+			continue
+		}
+		for _, id := range ids {
+			if id == elems[i].hitID {
+				continue Elements
+			}
+		}
+		for _, region := range elems[i].hitRegions() {
+			if region.Path.Interior(x, y, region.FillRule) {
+				ids = append(ids, elems[i].hitID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// HitRegion is the area of an element that was drawn with a hit ID, see Context.SetHitID.
+type HitRegion struct {
+	ID       string
+	Path     *Path // area in millimeters of the canvas
+	FillRule       // fill rule of the area
+	Opaque   bool  // whether the area is drawn fully opaque and hides the elements below it
+}
+
+// HitRegions returns the areas of the elements with a hit ID in drawing order, so that the topmost area is last, as used by HitTest. Paths have an area for their fill and one for their stroke outline, text has the area of its bounding box, and images the area of the image. Elements of hidden layers are skipped.
+func (c *Canvas) HitRegions() []HitRegion {
+	regions := []HitRegion{}
+	for _, l := range c.elements() {
+		if l.hitID != "" {
+			regions = append(regions, l.hitRegions()...)
+		}
+	}
+	return regions
+}
+
+// hitRegions returns the areas of the visible geometry of the path, text or image. The stroke width is not affected by the transformation.
+func (l layer) hitRegions() []HitRegion {
+	regions := []HitRegion{}
+	if l.path != nil {
+		path := l.path.Transform(l.m)
+		if l.style.FillColor.A != 0 || l.style.FillPaint != nil {
+			opaque := l.style.FillColor.A == 255 && l.style.FillPaint == nil
+			regions = append(regions, HitRegion{l.hitID, path, l.style.FillRule, opaque})
+		}
+		if (l.style.StrokeColor.A != 0 || l.style.StrokePaint != nil) && 0.0 < l.style.StrokeWidth {
+			if 0 < len(l.style.Dashes) {
+				path = path.Dash(l.style.DashOffset, l.style.Dashes...)
+			}
+			opaque := l.style.StrokeColor.A == 255 && l.style.StrokePaint == nil
+			path = path.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner)
+			regions = append(regions, HitRegion{l.hitID, path, NonZero, opaque})
+		}
+	} else if l.text != nil {
+		regions = append(regions, HitRegion{l.hitID, l.text.Bounds().ToPath().Transform(l.m), NonZero, false})
+	} else if l.img != nil {
+		size := l.img.Bounds().Size()
+		opaque := false
+		if img, ok := l.img.(interface{ Opaque() bool }); ok {
+			opaque = img.Opaque()
+		}
+		rect := Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
+		regions = append(regions, HitRegion{l.hitID, rect.ToPath().Transform(l.m), NonZero, opaque})
+	}
+	return regions
+}
+
+// Erase erases the region transformed by the matrix, removing the elements drawn before within the region, see EraseRenderer.
+func (c *Canvas) Erase(region *Path, m Matrix) {
+	c.layers = append(c.layers, layer{erase: region.Copy(), m: m})
 }
 
 // Empty return true if the canvas is empty.
 func (c *Canvas) Empty() bool {
+	for _, l := range c.named {
+		if !l.elements.Empty() {
+			return false
+		}
+	}
 	return len(c.layers) == 0
 }
 
-// Reset empties the canvas.
+// Reset empties the canvas and removes all named layers and the background, so that the canvas can be reused for other drawings without allocating. The size of the canvas is kept.
 func (c *Canvas) Reset() {
+	for i := range c.layers {
+		c.layers[i] = layer{} // release the elements
+	}
 	c.layers = c.layers[:0]
+	c.named = nil
+	c.background = nil
+	c.hitID = ""
 }
 
-// Fit shrinks the canvas size so all elements fit. The elements are translated towards the origin when any left/bottom margins exist and the canvas size is decreased if any margins exist. It will maintain a given margin.
-func (c *Canvas) Fit(margin float64) {
-	if len(c.layers) == 0 {
-		c.W = 2 * margin
-		c.H = 2 * margin
-		return
+// elements returns the elements of the canvas and those of the visible named layers in z-order, where each named layer is enclosed in a group with its name as ID. The blend mode is reset after elements that change it, so that it doesn't carry over to the next layer.
+func (c *Canvas) elements() []layer {
+	if len(c.named) == 0 {
+		return c.layers
+	}
+	named := append([]*Layer{}, c.named...)
+	sort.SliceStable(named, func(i, j int) bool {
+		return named[i].z < named[j].z
+	})
+
+	elems := []layer{}
+	base := false
+	for _, l := range named {
+		if !base && 0 <= l.z {
+			elems = appendBlended(elems, c.layers)
+			base = true
+		}
+		if l.visible {
+			elems = append(elems, layer{group: &group{l.name, ""}})
+			elems = appendBlended(elems, l.elements.elements())
+			elems = append(elems, layer{groupEnd: true})
+		}
 	}
+	if !base {
+		elems = appendBlended(elems, c.layers)
+	}
+	return elems
+}
 
+// appendBlended appends the layers to the elements, followed by a reset of the blend mode if any of the layers changes it.
+func appendBlended(elems, layers []layer) []layer {
+	elems = append(elems, layers...)
+	for _, l := range layers {
+		if l.blend != nil {
+			mode := BlendNormal
+			return append(elems, layer{blend: &mode})
+		}
+	}
+	return elems
+}
+
+// transform transforms all elements of the canvas, including those of named layers.
+func (c *Canvas) transform(m Matrix) {
+	for i := range c.layers {
+		c.layers[i].m = m.Mul(c.layers[i].m)
+	}
+	for _, l := range c.named {
+		l.elements.transform(m)
+	}
+}
+
+// ContentBounds returns the bounding box in mm of everything drawn on the canvas, including the outlines of strokes and the spread of blurs (three standard deviations), which may extend beyond the canvas. It returns an empty rectangle at the origin when nothing is drawn.
+func (c *Canvas) ContentBounds() Rect {
 	rect := Rect{}
-	// TODO: slow when we have many paths (see Graph example)
-	for i, l := range c.layers {
+	first := true
+	blur := 0.0          // spread of the open blur layers
+	blurs := []float64{} // spreads of the enclosing blur layers
+	for _, l := range c.elements() {
 		bounds := Rect{}
-		if l.path != nil {
-			bounds = l.path.Bounds()
+		if l.blur != nil {
+			blurs = append(blurs, blur)
+			blur += 3.0 * *l.blur
+			continue
+		} else if l.blurEnd {
+			if 0 < len(blurs) {
+				blur = blurs[len(blurs)-1]
+				blurs = blurs[:len(blurs)-1]
+			}
+			continue
+		} else if l.path != nil {
+			// the stroke width is not affected by the transformation
+			path := l.path.Transform(l.m)
+			bounds = path.Bounds()
 			if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
-				bounds.X -= l.style.StrokeWidth / 2.0
-				bounds.Y -= l.style.StrokeWidth / 2.0
-				bounds.W += l.style.StrokeWidth
-				bounds.H += l.style.StrokeWidth
+				bounds = bounds.Add(path.Stroke(l.style.StrokeWidth, l.style.StrokeCapper, l.style.StrokeJoiner).Bounds())
 			}
 		} else if l.text != nil {
-			bounds = l.text.Bounds()
+			bounds = l.text.Bounds().Transform(l.m)
 		} else if l.img != nil {
 			size := l.img.Bounds().Size()
-			bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
+			bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}.Transform(l.m)
+		} else {
+			continue // links, groups, titles, blend modes and erased regions don't have a visible size
 		}
-		bounds = bounds.Transform(l.m)
-		if i == 0 {
+		if 0.0 < blur {
+			bounds = Rect{bounds.X - blur, bounds.Y - blur, bounds.W + 2.0*blur, bounds.H + 2.0*blur}
+		}
+		if first {
 			rect = bounds
+			first = false
 		} else {
 			rect = rect.Add(bounds)
 		}
 	}
-	for i := range c.layers {
-		c.layers[i].m = Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(c.layers[i].m)
-	}
+	return rect
+}
+
+// Fit shrinks the canvas size so all elements fit, as given by ContentBounds. The elements are translated so that the bottom-left of their bounds is at (margin,margin), and the canvas size is set to the size of the bounds plus the margin on all sides. An empty canvas gets a size of twice the margin.
+func (c *Canvas) Fit(margin float64) {
+	rect := c.ContentBounds()
+	c.transform(Identity.Translate(-rect.X+margin, -rect.Y+margin))
 	c.W = rect.W + 2*margin
 	c.H = rect.H + 2*margin
 }
 
-// Render renders the accumulated canvas drawing operations to another renderer.
+// Render renders the accumulated canvas drawing operations to another renderer, which may be a custom implementation of Renderer.
 func (c *Canvas) Render(r Renderer) {
-	view := Identity
+	c.RenderView(r, Identity)
+}
+
+// RenderView renders the accumulated canvas drawing operations to another renderer like Render, with all elements transformed by the view matrix. This can be used to scale the canvas to a renderer of a different size, although stroke widths and blurs are not scaled.
+func (c *Canvas) RenderView(r Renderer, view Matrix) {
 	if viewer, ok := r.(interface{ View() Matrix }); ok {
-		view = viewer.View()
+		view = viewer.View().Mul(view)
+	}
+	linker, _ := r.(LinkRenderer)
+	grouper, _ := r.(GroupRenderer)
+	titler, _ := r.(TitleRenderer)
+	blurrer, _ := r.(BlurRenderer)
+	blender, _ := r.(BlendRenderer)
+	eraser, _ := r.(EraseRenderer)
+	hitter, _ := r.(HitRenderer)
+	blended := false
+	blend := BlendNormal
+	hitID := ""
+	if c.background != nil {
+		r.RenderPath(Rectangle(c.W, c.H), *c.background, view)
 	}
-	for _, l := range c.layers {
+	for _, l := range c.elements() {
 		m := view.Mul(l.m)
-		if l.path != nil {
+		if hitter != nil && l.hitID != hitID && (l.path != nil || l.text != nil || l.img != nil) {
+			hitter.SetHitID(l.hitID)
+			hitID = l.hitID
+		}
+		if l.link != nil {
+			if linker != nil {
+				linker.StartLink(l.link.url, l.link.area, m)
+			}
+		} else if l.linkEnd {
+			if linker != nil {
+				linker.EndLink()
+			}
+		} else if l.group != nil {
+			if grouper != nil {
+				grouper.StartGroup(l.group.id, l.group.class)
+			}
+		} else if l.groupEnd {
+			if grouper != nil {
+				grouper.EndGroup()
+			}
+		} else if l.title != nil {
+			if titler != nil {
+				titler.SetTitle(l.title.title, l.title.desc)
+			}
+		} else if l.blur != nil {
+			if blurrer != nil {
+				blurrer.StartBlur(*l.blur)
+			}
+		} else if l.blurEnd {
+			if blurrer != nil {
+				blurrer.EndBlur()
+			}
+		} else if l.blend != nil {
+			if blender != nil {
+				blender.SetBlendMode(*l.blend)
+				blended = true
+				blend = *l.blend
+			}
+		} else if l.erase != nil {
+			if c.background == nil && eraser != nil {
+				eraser.Erase(l.erase, m)
+				continue
+			}
+			style := eraseStyle
+			if c.background != nil {
+				style = *c.background
+			}
+			if blend != BlendNormal {
+				blender.SetBlendMode(BlendNormal)
+			}
+			r.RenderPath(l.erase, style, m)
+			if blend != BlendNormal {
+				blender.SetBlendMode(blend)
+			}
+		} else if l.path != nil {
 			r.RenderPath(l.path, l.style, m)
 		} else if l.text != nil {
 			r.RenderText(l.text, m)
@@ -489,6 +1475,58 @@ func (c *Canvas) Render(r Renderer) {
 			r.RenderImage(l.img, m)
 		}
 	}
+	if blended {
+		blender.SetBlendMode(BlendNormal)
+	}
+	if hitID != "" {
+		hitter.SetHitID("")
+	}
+}
+
+// Layer is a named layer of a canvas, see Canvas.Layer. It embeds the drawing context of the layer, which keeps its own draw state.
+type Layer struct {
+	*Context
+
+	name     string
+	z        int
+	visible  bool
+	elements *Canvas
+}
+
+// layerRenderer records the elements drawn to a layer, and has the size of the canvas of the layer.
+type layerRenderer struct {
+	*Canvas
+	parent *Canvas
+}
+
+// Size returns the size of the canvas of the layer in mm.
+func (r layerRenderer) Size() (float64, float64) {
+	return r.parent.Size()
+}
+
+// Name returns the name of the layer.
+func (l *Layer) Name() string {
+	return l.name
+}
+
+// ZIndex returns the z-index of the layer.
+func (l *Layer) ZIndex() int {
+	return l.z
+}
+
+// SetZIndex sets the z-index of the layer, layers with a higher z-index are drawn on top of those with a lower z-index. Layers with the same z-index are drawn in the order they were created. Layers with a negative z-index are drawn below the elements drawn to the canvas directly.
+func (l *Layer) SetZIndex(z int) {
+	l.z = z
+}
+
+// Visible returns whether the layer is visible.
+func (l *Layer) Visible() bool {
+	return l.visible
+}
+
+// SetVisible sets whether the layer is visible, hidden layers are not rendered and don't count towards the bounds of the canvas.
+func (l *Layer) SetVisible(visible bool) {
+	l.visible = visible
 }
 
 // Writer can write a canvas to a writer
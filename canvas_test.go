@@ -1,7 +1,11 @@
 package canvas
 
 import (
+	"fmt"
 	"image"
+	"image/color"
+	"math"
+	"sync"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -36,8 +40,8 @@ func TestCanvas(t *testing.T) {
 	ctx.DrawImage(50.0, 50.0, img, 0.1) // 20x20 => -20x40
 
 	c.Fit(6.0)
-	test.Float(t, c.W, 72.5) // img upper bound - (path lower bound - path half stroke width) + margin
-	test.Float(t, c.H, 113)  // path bounds + path stroke width + margin
+	test.Float(t, c.W, 72.5)  // img upper bound - (path lower bound - path half stroke width) + margin
+	test.Float(t, c.H, 112.5) // img upper bound - (path lower bound - path half stroke width) + margin, the stroke width is not scaled by the view
 
 	//buf := &bytes.Buffer{}
 	//c.WriteSVG(buf)
@@ -58,10 +62,687 @@ func TestCanvas(t *testing.T) {
 	// TODO: test EPS when fully supported
 }
 
+func TestContextOpacity(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, Red)
+
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetFillOpacity(0.5)
+	ctx.SetStrokeOpacity(0.25)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.Push()
+	ctx.SetGlobalAlpha(0.5)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.Push()
+	ctx.SetGlobalAlpha(0.5) // relative to the enclosing draw state
+	ctx.SetFillOpacity(1.0)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.DrawImage(0.0, 0.0, img, 1.0)
+	ctx.Pop()
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.Pop()
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.ResetStyle()
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+
+	test.T(t, len(c.layers), 7)
+	test.T(t, c.layers[0].style.FillColor, color.RGBA{128, 0, 0, 128})
+	test.T(t, c.layers[0].style.StrokeColor, color.RGBA{0, 0, 64, 64})
+	test.T(t, c.layers[1].style.FillColor, color.RGBA{64, 0, 0, 64})
+	test.T(t, c.layers[1].style.StrokeColor, color.RGBA{0, 0, 32, 32})
+	test.T(t, c.layers[2].style.FillColor, color.RGBA{64, 0, 0, 64})
+	test.T(t, c.layers[3].img.At(0, 0), color.RGBA{64, 0, 0, 64})
+	test.T(t, c.layers[4].style.FillColor, color.RGBA{64, 0, 0, 64})
+	test.T(t, c.layers[5].style.FillColor, color.RGBA{128, 0, 0, 128})
+	test.T(t, c.layers[6].style.FillColor, Black)
+}
+
+func TestContextSetStyle(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetStrokeWidth(2.0)
+	ctx.SetStrokeJoiner(RoundJoin)
+	ctx.SetMiterLimit(3.0)
+	ctx.SetDashes(1.0, 2.0, 3.0)
+	ctx.SetFillRule(EvenOdd)
+	ctx.SetStrokeOpacity(0.5)
+	styles := map[string]Style{"series": ctx.Style}
+
+	// the saved style reproduces the output after other changes
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetFillColor(Green)
+	ctx.SetStrokeWidth(5.0)
+	ctx.SetDashes(0.0)
+	ctx.SetStrokeOpacity(1.0)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetStyle(styles["series"])
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, len(c.layers), 3)
+	test.T(t, c.layers[2].style, c.layers[0].style)
+	test.T(t, c.layers[2].style.StrokeColor.A, uint8(128)) // the stroke opacity is part of the style
+
+	// the dashes are copied
+	ctx.Style.Dashes[0] = 10.0
+	test.T(t, styles["series"].Dashes, []float64{2.0, 3.0})
+
+	// the zero style equals ResetStyle
+	ctx.SetFillOpacity(0.5)
+	ctx.SetStyle(Style{})
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetFillColor(Red)
+	ctx.SetFillOpacity(0.5)
+	ctx.ResetStyle()
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, ctx.Style, DefaultStyle)
+	test.T(t, c.layers[3].style, c.layers[4].style)
+	test.T(t, c.layers[3].style.FillColor, Black)
+
+	// a partial style is completed by the default capper and joiner and is opaque
+	ctx.SetStyle(Style{FillColor: Red, StrokeColor: Blue, StrokeWidth: 2.0})
+	test.T(t, ctx.StrokeCapper, DefaultStyle.StrokeCapper)
+	test.T(t, ctx.StrokeJoiner, DefaultStyle.StrokeJoiner)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, c.layers[5].style.FillColor, Red)
+	test.T(t, c.layers[5].style.StrokeColor, Blue)
+	test.T(t, c.layers[5].style.StrokeWidth, 2.0)
+
+	// zero stroke width and opacities survive a round trip
+	ctx.ResetStyle()
+	ctx.SetStrokeColor(Blue)
+	ctx.SetStrokeWidth(0.0)
+	ctx.SetFillOpacity(0.0)
+	ctx.SetStrokeOpacity(0.0)
+	style := ctx.Style
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.SetStyle(style)
+	ctx.DrawPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	test.T(t, ctx.Style, style)
+	test.T(t, c.layers[7].style, c.layers[6].style)
+	test.T(t, c.layers[7].style.StrokeWidth, 0.0)
+	test.T(t, c.layers[7].style.FillColor, Transparent)
+	test.T(t, c.layers[7].style.StrokeColor, Transparent)
+}
+
+func TestContextDrawShapes(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	ctx.DrawLine(10.0, 20.0, 30.0, 40.0)
+	ctx.DrawPolyline(Point{10.0, 20.0}, Point{30.0, 20.0}, Point{30.0, 40.0})
+	ctx.DrawPolygon(Point{10.0, 20.0}, Point{30.0, 20.0}, Point{30.0, 40.0})
+	ctx.DrawRectangle(10.0, 20.0, 5.0, 8.0)
+	ctx.DrawEllipse(50.0, 50.0, 10.0, 5.0)
+	ctx.DrawCircle(50.0, 50.0, 10.0)
+
+	// zero-size shapes are not drawn
+	ctx.DrawLine(10.0, 20.0, 10.0, 20.0)
+	ctx.DrawPolyline(Point{10.0, 20.0})
+	ctx.DrawPolygon(Point{10.0, 20.0}, Point{10.0, 20.0})
+	ctx.DrawPolygon()
+	ctx.DrawRectangle(10.0, 20.0, 0.0, 8.0)
+	ctx.DrawEllipse(50.0, 50.0, 10.0, 0.0)
+	ctx.DrawCircle(50.0, 50.0, 0.0)
+
+	manual := New(100, 100)
+	ctx = NewContext(manual)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	stroke := ctx.Style
+	stroke.FillColor = Transparent
+	manual.RenderPath(MustParseSVG("M10 20L30 40"), stroke, Identity)
+	manual.RenderPath(MustParseSVG("M10 20H30V40"), stroke, Identity)
+	ctx.DrawPath(0.0, 0.0, MustParseSVG("M10 20H30V40z"))
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 8.0))
+	ctx.DrawPath(50.0, 50.0, Ellipse(10.0, 5.0))
+	ctx.DrawPath(50.0, 50.0, Circle(10.0))
+
+	test.T(t, len(c.layers), len(manual.layers))
+	for i := range c.layers {
+		test.T(t, c.layers[i].path.Transform(c.layers[i].m), manual.layers[i].path.Transform(manual.layers[i].m), i)
+		test.T(t, c.layers[i].style, manual.layers[i].style, i)
+	}
+
+	// all coordinates are in the coordinate system
+	c = New(100, 100)
+	ctx = NewContext(c)
+	ctx.SetCoordSystem(CartesianIV)
+	ctx.DrawRectangle(10.0, 20.0, 5.0, 8.0)
+	ctx.DrawPolygon(Point{10.0, 20.0}, Point{30.0, 20.0}, Point{30.0, 40.0})
+	test.T(t, c.layers[0].path.Transform(c.layers[0].m).Bounds(), Rect{10.0, 72.0, 5.0, 8.0})
+	test.T(t, c.layers[1].path.Transform(c.layers[1].m).Bounds(), Rect{10.0, 60.0, 20.0, 20.0})
+}
+
+func TestBlendMode(t *testing.T) {
+	var tests = []struct {
+		mode     BlendMode
+		name     string
+		cb, cs   float64
+		expected float64
+	}{
+		{BlendNormal, "normal", 0.2, 0.6, 0.6},
+		{BlendMultiply, "multiply", 0.2, 0.6, 0.12},
+		{BlendScreen, "screen", 0.2, 0.6, 0.68},
+		{BlendOverlay, "overlay", 0.2, 0.6, 0.24},
+		{BlendOverlay, "overlay", 0.8, 0.6, 0.84},
+		{BlendDarken, "darken", 0.2, 0.6, 0.2},
+		{BlendLighten, "lighten", 0.2, 0.6, 0.6},
+		{BlendColorDodge, "color-dodge", 0.2, 0.6, 0.5},
+		{BlendColorDodge, "color-dodge", 0.0, 1.0, 0.0},
+		{BlendColorBurn, "color-burn", 0.8, 0.5, 0.6},
+		{BlendColorBurn, "color-burn", 1.0, 0.0, 1.0},
+		{BlendHardLight, "hard-light", 0.2, 0.6, 0.36},
+		{BlendSoftLight, "soft-light", 0.2, 0.6, 0.2 + 0.2*(((16.0*0.2-12.0)*0.2+4.0)*0.2-0.2)},
+		{BlendSoftLight, "soft-light", 0.5, 0.25, 0.375},
+		{BlendDifference, "difference", 0.2, 0.6, 0.4},
+		{BlendExclusion, "exclusion", 0.2, 0.6, 0.56},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.name, " ", tt.cb, " ", tt.cs), func(t *testing.T) {
+			test.T(t, tt.mode.String(), tt.name)
+			test.Float(t, tt.mode.Blend(tt.cb, tt.cs), tt.expected)
+		})
+	}
+}
+
 func TestCanvasFit(t *testing.T) {
 	c := New(100, 100)
+	test.T(t, c.ContentBounds(), Rect{})
 	c.Fit(10)
 
 	test.Float(t, c.W, 20)
 	test.Float(t, c.H, 20)
+
+	c = New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawPath(37.3, 61.7, Rectangle(10.0, 5.0))
+	ctx.StartBlur(1.0)
+	ctx.DrawPath(40.0, 50.0, Rectangle(5.0, 5.0)) // blurred by three standard deviations
+	ctx.EndBlur()
+	ctx.SetFillColor(Transparent)
+	ctx.SetStrokeColor(Black)
+	ctx.SetStrokeWidth(2.0)
+	ctx.SetMiterLimit(20.0)
+	ctx.DrawPath(75.0, -8.4, MustParseSVG("M0 0L10 0L0 2")) // the miter join extends far beyond the stroke width
+	ctx.DrawImage(-20.0, 30.0, image.NewRGBA(image.Rect(0, 0, 4, 2)), 1.0)
+
+	// the miter tip at (10,0) is at 1/sin(theta/2) times the half stroke width along the bisector
+	theta := math.Atan(0.2)
+	tip := 75.0 + 10.0 + math.Cos(theta/2.0)/math.Sin(theta/2.0)
+	bounds := c.ContentBounds()
+	test.Float(t, bounds.X, -20.0)
+	test.Float(t, bounds.Y, -9.4)
+	test.Float(t, bounds.X+bounds.W, tip)
+	test.Float(t, bounds.Y+bounds.H, 66.7)
+
+	c.Fit(1.5)
+	test.Float(t, c.W, tip+20.0+3.0)
+	test.Float(t, c.H, 76.1+3.0)
+	test.T(t, c.ContentBounds(), Rect{1.5, 1.5, tip + 20.0, 76.1})
+}
+
+type linkRecorder struct {
+	*Canvas
+	links []string
+}
+
+func (r *linkRecorder) StartLink(url string, area *Path, m Matrix) {
+	r.links = append(r.links, url+" "+area.Transform(m).String())
+}
+
+func (r *linkRecorder) EndLink() {
+	r.links = append(r.links, "end")
+}
+
+func TestCanvasLink(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.StartLink("https://a.example", 10.0, 20.0, Rectangle(5.0, 5.0))
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	ctx.StartLink("https://b.example", 12.0, 22.0, Rectangle(1.0, 1.0))
+	ctx.DrawPath(12.0, 22.0, Rectangle(1.0, 1.0))
+	ctx.EndLink()
+	ctx.EndLink()
+
+	r := &linkRecorder{Canvas: New(100, 100)}
+	c.Render(r)
+	test.T(t, r.links, []string{"https://a.example M10 20L15 20L15 25L10 25z", "https://b.example M12 22L13 22L13 23L12 23z", "end", "end"})
+	test.T(t, len(r.Canvas.layers), 2)
+
+	// links don't contribute to the bounds
+	ctx.StartLink("https://c.example", 80.0, 80.0, Rectangle(5.0, 5.0))
+	ctx.EndLink()
+	c.Fit(0.0)
+	test.Float(t, c.W, 5.0)
+	test.Float(t, c.H, 5.0)
+}
+
+type groupRecorder struct {
+	*Canvas
+	groups []string
+}
+
+func (r *groupRecorder) StartGroup(id, class string) {
+	r.groups = append(r.groups, id+"."+class)
+}
+
+func (r *groupRecorder) EndGroup() {
+	r.groups = append(r.groups, "end")
+}
+
+func (r *groupRecorder) SetTitle(title, desc string) {
+	r.groups = append(r.groups, "title:"+title+":"+desc)
+}
+
+func TestCanvasGroup(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetTitle("Chart", "")
+	ctx.StartGroup("layer1", "")
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	ctx.StartGroup("", "markers")
+	ctx.SetTitle("Markers", "Data points")
+	ctx.DrawPath(12.0, 22.0, Rectangle(1.0, 1.0))
+	ctx.EndGroup()
+	ctx.EndGroup()
+
+	r := &groupRecorder{Canvas: New(100, 100)}
+	c.Render(r)
+	test.T(t, r.groups, []string{"title:Chart:", "layer1.", ".markers", "title:Markers:Data points", "end", "end"})
+	test.T(t, len(r.Canvas.layers), 2)
+	c.Fit(0.0)
+	test.T(t, c.W, 5.0)
+
+	// groups are flattened by other renderers
+	r2 := New(100, 100)
+	c.Render(&struct{ Renderer }{r2})
+	test.T(t, len(r2.layers), 2)
+}
+
+func TestCanvasLayer(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	annotations := c.Layer("annotations")
+	data := c.Layer("data")
+	grid := c.Layer("grid")
+	grid.SetZIndex(-1)
+
+	// draw calls are interleaved across layers
+	data.DrawPath(10.0, 10.0, Rectangle(5.0, 5.0))
+	grid.DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+	annotations.DrawPath(20.0, 20.0, Rectangle(2.0, 2.0))
+	ctx.DrawPath(50.0, 50.0, Rectangle(3.0, 3.0))
+	data.DrawPath(30.0, 30.0, Rectangle(4.0, 4.0))
+	test.T(t, c.Layer("data"), data)
+	test.T(t, data.Name(), "data")
+	test.T(t, data.ZIndex(), 2)
+	data.SetZIndex(0) // above the canvas, below the annotations
+
+	r := &groupRecorder{Canvas: New(100, 100)}
+	c.Render(r)
+	test.T(t, r.groups, []string{"grid.", "end", "data.", "end", "annotations.", "end"})
+	test.T(t, len(r.Canvas.layers), 5)
+	bounds := []Rect{}
+	for _, l := range r.Canvas.layers {
+		bounds = append(bounds, l.path.Transform(l.m).Bounds())
+	}
+	test.T(t, bounds, []Rect{{0.0, 0.0, 1.0, 1.0}, {50.0, 50.0, 3.0, 3.0}, {10.0, 10.0, 5.0, 5.0}, {30.0, 30.0, 4.0, 4.0}, {20.0, 20.0, 2.0, 2.0}})
+
+	// undeclared layers are created on top
+	c.Layer("debug").DrawPath(90.0, 90.0, Rectangle(5.0, 5.0))
+	test.T(t, c.Layer("debug").ZIndex(), 2)
+	test.T(t, c.ContentBounds(), Rect{0.0, 0.0, 95.0, 95.0})
+
+	// hidden layers are not rendered and don't count towards the bounds
+	c.Layer("debug").SetVisible(false)
+	test.That(t, !c.Layer("debug").Visible())
+	r = &groupRecorder{Canvas: New(100, 100)}
+	c.Render(r)
+	test.T(t, r.groups, []string{"grid.", "end", "data.", "end", "annotations.", "end"})
+	test.T(t, len(r.Canvas.layers), 5)
+	test.T(t, c.ContentBounds(), Rect{0.0, 0.0, 53.0, 53.0})
+
+	c.Fit(1.0)
+	test.T(t, c.W, 55.0)
+	c.Layer("debug").SetVisible(true)
+	test.T(t, c.ContentBounds(), Rect{1.0, 1.0, 95.0, 95.0})
+
+	// blend modes don't carry over to the next layer
+	c.Reset()
+	test.That(t, c.Empty())
+	c.Layer("multiply").SetBlendMode(BlendMultiply)
+	c.Layer("multiply").DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+	c.Layer("normal").DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+	test.That(t, !c.Empty())
+	modes := []BlendMode{}
+	for _, l := range c.elements() {
+		if l.blend != nil {
+			modes = append(modes, *l.blend)
+		}
+	}
+	test.T(t, modes, []BlendMode{BlendMultiply, BlendNormal})
+}
+
+func TestCanvasBackground(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	c.SetBackground(White)
+	test.T(t, c.ContentBounds(), Rect{10.0, 20.0, 5.0, 5.0})
+
+	// the background is rendered first and covers the canvas after fitting
+	c.Fit(1.0)
+	r := New(100, 100)
+	c.Render(r)
+	test.T(t, len(r.layers), 2)
+	test.T(t, r.layers[0].path.Transform(r.layers[0].m).Bounds(), Rect{0.0, 0.0, 7.0, 7.0})
+	test.T(t, r.layers[0].style.FillColor, White)
+	test.T(t, r.layers[0].style.StrokeColor, Transparent)
+	test.T(t, r.layers[1].style.FillColor, Black)
+
+	g, err := NewLinearGradient(0.0, 0.0, 7.0, 0.0, PadSpread, Stop{0.0, Red}, Stop{1.0, Blue})
+	test.Error(t, err)
+	c.SetBackgroundPaint(g)
+	r = New(100, 100)
+	c.Render(r)
+	test.T(t, r.layers[0].style.FillPaint, Paint(g))
+
+	c.SetBackground(Transparent)
+	r = New(100, 100)
+	c.Render(r)
+	test.T(t, len(r.layers), 1)
+}
+
+func TestCanvasReset(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	c.SetBackground(Red)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	c.Layer("debug").DrawPath(0.0, 0.0, Circle(1.0))
+	c.Erase(Rectangle(2.0, 2.0), Identity)
+
+	c.Reset()
+	test.That(t, c.Empty())
+	test.T(t, c.W, 100.0)
+	test.T(t, c.ContentBounds(), Rect{})
+
+	// nothing of the first drawing is rendered after reuse
+	ctx = NewContext(c)
+	ctx.SetFillColor(Blue)
+	ctx.DrawPath(50.0, 50.0, Rectangle(1.0, 1.0))
+	r := &groupRecorder{Canvas: New(100, 100)}
+	c.Render(r)
+	test.T(t, len(r.groups), 0)
+	test.T(t, len(r.Canvas.layers), 1)
+	test.T(t, r.Canvas.layers[0].style.FillColor, Blue)
+}
+
+type blendRecorder struct {
+	modes []BlendMode
+}
+
+func (r *blendRecorder) SetBlendMode(mode BlendMode) {
+	r.modes = append(r.modes, mode)
+}
+
+func TestCanvasErase(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	c.SetBlendMode(BlendMultiply)
+	ctx.Translate(10.0, 20.0)
+	ctx.Erase(1.0, 1.0, Rectangle(2.0, 2.0))
+	test.T(t, len(c.layers), 3)
+	test.T(t, c.layers[2].erase.Transform(c.layers[2].m).Bounds(), Rect{11.0, 21.0, 2.0, 2.0})
+	test.T(t, c.ContentBounds(), Rect{10.0, 20.0, 5.0, 5.0})
+
+	// renderers that can't erase fill the region with white without blending
+	r := New(100, 100)
+	rb := &blendRecorder{}
+	c.Render(&struct {
+		Renderer
+		BlendRenderer
+	}{r, rb})
+	test.T(t, rb.modes, []BlendMode{BlendMultiply, BlendNormal, BlendMultiply, BlendNormal})
+	test.T(t, len(r.layers), 2)
+	test.T(t, r.layers[1].style.FillColor, White)
+	test.T(t, r.layers[1].path.Transform(r.layers[1].m).Bounds(), Rect{11.0, 21.0, 2.0, 2.0})
+
+	// or with the background of the canvas
+	c.SetBackground(Yellow)
+	r = New(100, 100)
+	c.Render(&struct{ Renderer }{r})
+	test.T(t, len(r.layers), 3)
+	test.T(t, r.layers[2].style.FillColor, Yellow)
+
+	// the erased region is recorded by canvases
+	c.SetBackground(Transparent)
+	r = New(100, 100)
+	c.Render(r)
+	test.T(t, len(r.layers), 4)
+	test.T(t, r.layers[2].erase.Transform(r.layers[2].m).Bounds(), Rect{11.0, 21.0, 2.0, 2.0})
+}
+
+func BenchmarkCanvasNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := New(100, 100)
+		ctx := NewContext(c)
+		for j := 0; j < 100; j++ {
+			ctx.DrawPath(float64(j), 0.0, Rectangle(1.0, 1.0))
+		}
+	}
+}
+
+func BenchmarkCanvasReuse(b *testing.B) {
+	b.ReportAllocs()
+	c := New(100, 100)
+	for i := 0; i < b.N; i++ {
+		c.Reset()
+		ctx := NewContext(c)
+		for j := 0; j < 100; j++ {
+			ctx.DrawPath(float64(j), 0.0, Rectangle(1.0, 1.0))
+		}
+	}
+}
+
+func TestCanvasHitTest(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetHitID("a")
+	ctx.SetShadow(20.0, 0.0, 1.0, Black) // shadows are not hit
+	ctx.DrawRectangle(10.0, 10.0, 20.0, 20.0)
+	ctx.SetShadow(0.0, 0.0, 0.0, Transparent)
+	ctx.SetHitID("b")
+	ctx.SetFillColor(Transparent)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetStrokeWidth(4.0)
+	ctx.DrawCircle(30.0, 30.0, 10.0)
+	ctx.SetHitID("image")
+	ctx.DrawImage(80.0, 10.0, image.NewRGBA(image.Rect(0, 0, 2, 2)), 0.5)
+	ctx.SetHitID("")
+	ctx.DrawRectangle(60.0, 60.0, 5.0, 5.0)
+
+	test.T(t, c.HitTest(21.0, 29.0), []string{"b", "a"})
+	test.T(t, c.HitTest(29.0, 29.0), []string{"a"}) // inside the unfilled circle
+	test.T(t, c.HitTest(38.0, 30.0), []string{"b"}) // within the stroke width
+	test.T(t, c.HitTest(50.0, 50.0), []string{})
+	test.T(t, c.HitTest(62.0, 62.0), []string{})
+	test.T(t, c.HitTest(83.0, 13.0), []string{"image"})
+	test.T(t, c.HitTest(40.0, 15.0), []string{}) // the shadow
+
+	// the hit regions move along with Fit
+	c.Fit(0.0)
+	test.T(t, c.HitTest(11.0, 19.0), []string{"b", "a"})
+
+	// the hit IDs are passed on when rendering to another canvas and in recordings
+	rec := NewRecorder(100, 100)
+	c.Render(rec)
+	test.T(t, rec.hitID, "")
+	test.T(t, rec.HitTest(11.0, 19.0), []string{"b", "a"})
+	b, err := rec.MarshalBinary()
+	test.Error(t, err)
+	rec2 := &Recorder{}
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, rec2.HitTest(11.0, 19.0), []string{"b", "a"})
+	test.T(t, rec2.HitTest(0.0, 0.0), []string{})
+	test.T(t, rec2.HitTest(73.0, 3.0), []string{"image"})
+}
+
+func TestCanvasMerge(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawRectangle(0.0, 0.0, 10.0, 10.0)
+
+	child := c.NewSubCanvas()
+	test.T(t, child.W, 100.0)
+	test.T(t, child.H, 100.0)
+	child.W, child.H = 20.0, 10.0
+	child.SetBackground(Red)
+	child.SetBlendMode(BlendMultiply)
+	childCtx := NewContext(child)
+	childCtx.SetHitID("child")
+	childCtx.DrawRectangle(1.0, 2.0, 3.0, 4.0)
+	child.Layer("top").DrawCircle(5.0, 5.0, 1.0)
+	child.Layer("hidden").SetVisible(false)
+
+	c.Merge(child, Identity.Translate(50.0, 50.0))
+	child.Reset() // doesn't affect the merged elements
+	c.Merge(child, Identity)
+	test.T(t, c.W, 100.0)
+	test.T(t, c.H, 100.0)
+	test.T(t, len(c.layers), 9)
+	test.T(t, c.layers[1].path, Rectangle(20.0, 10.0))
+	test.T(t, c.layers[1].style.FillColor, Red)
+	test.T(t, c.layers[1].m, Identity.Translate(50.0, 50.0))
+	test.T(t, *c.layers[2].blend, BlendMultiply)
+	test.T(t, c.layers[3].m, Identity.Translate(51.0, 52.0))
+	test.T(t, *c.layers[4].blend, BlendNormal)
+	test.T(t, c.layers[5].group, &group{"top", ""})
+	test.T(t, c.layers[6].m, Identity.Translate(55.0, 55.0))
+	test.T(t, c.layers[7].groupEnd, true)
+	test.T(t, *c.layers[8].blend, BlendNormal) // the blend mode doesn't carry over to later elements
+	test.T(t, c.HitTest(52.0, 53.0), []string{"child"})
+	test.T(t, c.HitTest(2.0, 3.0), []string{})
+}
+
+func TestCanvasMergeConcurrent(t *testing.T) {
+	// each goroutine draws to its own sub canvas, the merged order is that of the calls to Merge
+	c := New(100, 100)
+	children := make([]*Canvas, 16)
+	wg := sync.WaitGroup{}
+	for i := range children {
+		children[i] = c.NewSubCanvas()
+		wg.Add(1)
+		go func(child *Canvas) {
+			defer wg.Done()
+			ctx := NewContext(child)
+			for j := 0; j < 1000; j++ {
+				ctx.DrawRectangle(float64(j)*0.1, 0.0, 0.1, 1.0)
+			}
+		}(children[i])
+	}
+	wg.Wait()
+	for i, child := range children {
+		c.Merge(child, Identity.Translate(0.0, float64(i)))
+	}
+
+	test.T(t, len(c.layers), 16*1000)
+	for k, l := range c.layers {
+		i, j := k/1000, k%1000
+		if !l.m.Equals(Identity.Translate(float64(j)*0.1, float64(i))) {
+			test.Fail(t, "element", k, "out of order:", l.m)
+			break
+		}
+	}
+}
+
+// boundsRenderer is a custom renderer that collects the bounding box of everything that is rendered.
+type boundsRenderer struct {
+	w, h   float64
+	bounds Rect
+	empty  bool
+}
+
+func (r *boundsRenderer) Size() (float64, float64) {
+	return r.w, r.h
+}
+
+func (r *boundsRenderer) add(rect Rect) {
+	if r.empty {
+		r.bounds, r.empty = rect, false
+	} else {
+		r.bounds = r.bounds.Add(rect)
+	}
+}
+
+func (r *boundsRenderer) RenderPath(path *Path, style Style, m Matrix) {
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+	}
+	r.add(path.Transform(m).Bounds())
+}
+
+func (r *boundsRenderer) RenderText(text *Text, m Matrix) {
+	text.RenderAsPath(r, m)
+}
+
+func (r *boundsRenderer) RenderImage(img image.Image, m Matrix) {
+	size := img.Bounds().Size()
+	r.add(Rectangle(float64(size.X), float64(size.Y)).Transform(m).Bounds())
+}
+
+func ExampleRenderer() {
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(10.0, 20.0, Rectangle(30.0, 10.0))
+	ctx.DrawImage(50.0, 50.0, image.NewRGBA(image.Rect(0, 0, 20, 10)), 1.0)
+
+	r := &boundsRenderer{w: 100.0, h: 100.0, empty: true}
+	c.Render(r)
+	fmt.Println(r.bounds)
+	// Output: (10,20)-(70,60)
+}
+
+func TestContextDrawImageTransformed(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	ctx.SetView(Identity.Translate(5.0, 0.0))
+	ctx.SetCoordSystem(CartesianIV)
+	ctx.DrawImageTransformed(img, Identity.Translate(10.0, 20.0).Rotate(90.0).Scale(0.5, 0.5))
+
+	// the position is in the coordinate system and the rotation is under the view, a 10x5 image rotated by 90 degrees at (15,80)
+	r := &boundsRenderer{w: 100.0, h: 100.0, empty: true}
+	c.Render(r)
+	test.T(t, r.bounds, Rect{10.0, 80.0, 5.0, 10.0})
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(100.0, 100.0)
+	ctx := NewContext(rec)
+	path := Rectangle(30.0, 10.0)
+	dashes := []float64{1.0, 2.0}
+	ctx.SetStrokeColor(Black)
+	ctx.SetDashes(0.0, dashes...)
+	ctx.DrawPath(10.0, 20.0, path)
+
+	// modifying the source afterwards doesn't change the recording
+	path.LineTo(90.0, 90.0)
+	dashes[0] = 5.0
+
+	r := &boundsRenderer{w: 100.0, h: 100.0, empty: true}
+	rec.Replay(r)
+	test.T(t, r.bounds, Rect{9.5, 19.5, 31.0, 11.0})
+	test.T(t, rec.layers[0].style.Dashes, []float64{1.0, 2.0})
+
+	r = &boundsRenderer{w: 200.0, h: 200.0, empty: true}
+	rec.ReplayScaled(r, Identity.Scale(2.0, 2.0))
+	test.T(t, r.bounds, Rect{19.0, 39.0, 62.0, 22.0})
 }
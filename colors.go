@@ -1,6 +1,13 @@
 package canvas
 
-import "image/color"
+import (
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // Transparent when used as a fill or stroke color will indicate that the fill or stroke will not be drawn.
 var Transparent = color.RGBA{0x00, 0x00, 0x00, 0x00} // rgba(0, 0, 0, 0)
@@ -155,3 +162,382 @@ var (
 	Yellow               = color.RGBA{0xff, 0xff, 0x00, 0xff} // rgb(255, 255, 0)
 	Yellowgreen          = color.RGBA{0x9a, 0xcd, 0x32, 0xff} // rgb(154, 205, 50)
 )
+
+// colorNames maps the CSS named colors to their values.
+var colorNames = map[string]color.RGBA{
+	"aliceblue":            Aliceblue,
+	"antiquewhite":         Antiquewhite,
+	"aqua":                 Aqua,
+	"aquamarine":           Aquamarine,
+	"azure":                Azure,
+	"beige":                Beige,
+	"bisque":               Bisque,
+	"black":                Black,
+	"blanchedalmond":       Blanchedalmond,
+	"blue":                 Blue,
+	"blueviolet":           Blueviolet,
+	"brown":                Brown,
+	"burlywood":            Burlywood,
+	"cadetblue":            Cadetblue,
+	"chartreuse":           Chartreuse,
+	"chocolate":            Chocolate,
+	"coral":                Coral,
+	"cornflowerblue":       Cornflowerblue,
+	"cornsilk":             Cornsilk,
+	"crimson":              Crimson,
+	"cyan":                 Cyan,
+	"darkblue":             Darkblue,
+	"darkcyan":             Darkcyan,
+	"darkgoldenrod":        Darkgoldenrod,
+	"darkgray":             Darkgray,
+	"darkgreen":            Darkgreen,
+	"darkgrey":             Darkgrey,
+	"darkkhaki":            Darkkhaki,
+	"darkmagenta":          Darkmagenta,
+	"darkolivegreen":       Darkolivegreen,
+	"darkorange":           Darkorange,
+	"darkorchid":           Darkorchid,
+	"darkred":              Darkred,
+	"darksalmon":           Darksalmon,
+	"darkseagreen":         Darkseagreen,
+	"darkslateblue":        Darkslateblue,
+	"darkslategray":        Darkslategray,
+	"darkslategrey":        Darkslategrey,
+	"darkturquoise":        Darkturquoise,
+	"darkviolet":           Darkviolet,
+	"deeppink":             Deeppink,
+	"deepskyblue":          Deepskyblue,
+	"dimgray":              Dimgray,
+	"dimgrey":              Dimgrey,
+	"dodgerblue":           Dodgerblue,
+	"firebrick":            Firebrick,
+	"floralwhite":          Floralwhite,
+	"forestgreen":          Forestgreen,
+	"fuchsia":              Fuchsia,
+	"gainsboro":            Gainsboro,
+	"ghostwhite":           Ghostwhite,
+	"gold":                 Gold,
+	"goldenrod":            Goldenrod,
+	"gray":                 Gray,
+	"green":                Green,
+	"greenyellow":          Greenyellow,
+	"grey":                 Grey,
+	"honeydew":             Honeydew,
+	"hotpink":              Hotpink,
+	"indianred":            Indianred,
+	"indigo":               Indigo,
+	"ivory":                Ivory,
+	"khaki":                Khaki,
+	"lavender":             Lavender,
+	"lavenderblush":        Lavenderblush,
+	"lawngreen":            Lawngreen,
+	"lemonchiffon":         Lemonchiffon,
+	"lightblue":            Lightblue,
+	"lightcoral":           Lightcoral,
+	"lightcyan":            Lightcyan,
+	"lightgoldenrodyellow": Lightgoldenrodyellow,
+	"lightgray":            Lightgray,
+	"lightgreen":           Lightgreen,
+	"lightgrey":            Lightgrey,
+	"lightpink":            Lightpink,
+	"lightsalmon":          Lightsalmon,
+	"lightseagreen":        Lightseagreen,
+	"lightskyblue":         Lightskyblue,
+	"lightslategray":       Lightslategray,
+	"lightslategrey":       Lightslategrey,
+	"lightsteelblue":       Lightsteelblue,
+	"lightyellow":          Lightyellow,
+	"lime":                 Lime,
+	"limegreen":            Limegreen,
+	"linen":                Linen,
+	"magenta":              Magenta,
+	"maroon":               Maroon,
+	"mediumaquamarine":     Mediumaquamarine,
+	"mediumblue":           Mediumblue,
+	"mediumorchid":         Mediumorchid,
+	"mediumpurple":         Mediumpurple,
+	"mediumseagreen":       Mediumseagreen,
+	"mediumslateblue":      Mediumslateblue,
+	"mediumspringgreen":    Mediumspringgreen,
+	"mediumturquoise":      Mediumturquoise,
+	"mediumvioletred":      Mediumvioletred,
+	"midnightblue":         Midnightblue,
+	"mintcream":            Mintcream,
+	"mistyrose":            Mistyrose,
+	"moccasin":             Moccasin,
+	"navajowhite":          Navajowhite,
+	"navy":                 Navy,
+	"oldlace":              Oldlace,
+	"olive":                Olive,
+	"olivedrab":            Olivedrab,
+	"orange":               Orange,
+	"orangered":            Orangered,
+	"orchid":               Orchid,
+	"palegoldenrod":        Palegoldenrod,
+	"palegreen":            Palegreen,
+	"paleturquoise":        Paleturquoise,
+	"palevioletred":        Palevioletred,
+	"papayawhip":           Papayawhip,
+	"peachpuff":            Peachpuff,
+	"peru":                 Peru,
+	"pink":                 Pink,
+	"plum":                 Plum,
+	"powderblue":           Powderblue,
+	"purple":               Purple,
+	"red":                  Red,
+	"rosybrown":            Rosybrown,
+	"royalblue":            Royalblue,
+	"saddlebrown":          Saddlebrown,
+	"salmon":               Salmon,
+	"sandybrown":           Sandybrown,
+	"seagreen":             Seagreen,
+	"seashell":             Seashell,
+	"sienna":               Sienna,
+	"silver":               Silver,
+	"skyblue":              Skyblue,
+	"slateblue":            Slateblue,
+	"slategray":            Slategray,
+	"slategrey":            Slategrey,
+	"snow":                 Snow,
+	"springgreen":          Springgreen,
+	"steelblue":            Steelblue,
+	"tan":                  Tan,
+	"teal":                 Teal,
+	"thistle":              Thistle,
+	"tomato":               Tomato,
+	"turquoise":            Turquoise,
+	"violet":               Violet,
+	"wheat":                Wheat,
+	"white":                White,
+	"whitesmoke":           Whitesmoke,
+	"yellow":               Yellow,
+	"yellowgreen":          Yellowgreen,
+	"rebeccapurple":        {0x66, 0x33, 0x99, 0xff},
+	"transparent":          Transparent,
+}
+
+// ParseColor parses a CSS color, ie. in hexadecimal notation (#rgb, #rgba, #rrggbb, or #rrggbbaa), in functional notation (rgb(), rgba(), hsl(), or hsla()), or a named color such as "steelblue". Components of rgb() can be integers or percentages, and the alpha component can be a number or percentage. The function is case-insensitive and the returned color has premultiplied alpha.
+func ParseColor(s string) (color.RGBA, error) {
+	orig := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "#") {
+		if col, ok := parseHexColor(s[1:]); ok {
+			return col, nil
+		}
+		return color.RGBA{}, fmt.Errorf("bad color: invalid hexadecimal color '%s'", orig)
+	} else if open := strings.IndexByte(s, '('); open != -1 {
+		if !strings.HasSuffix(s, ")") {
+			return color.RGBA{}, fmt.Errorf("bad color: missing ')' in '%s'", orig)
+		}
+		name := strings.TrimSpace(s[:open])
+		args := strings.FieldsFunc(s[open+1:len(s)-1], func(r rune) bool {
+			return r == ',' || r == '/' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+		var col color.RGBA
+		var ok bool
+		switch name {
+		case "rgb", "rgba":
+			col, ok = parseRGBColor(args)
+		case "hsl", "hsla":
+			col, ok = parseHSLColor(args)
+		default:
+			return color.RGBA{}, fmt.Errorf("bad color: unknown function '%s' in '%s'", name, orig)
+		}
+		if !ok {
+			return color.RGBA{}, fmt.Errorf("bad color: invalid arguments in '%s'", orig)
+		}
+		return col, nil
+	} else if col, ok := colorNames[s]; ok {
+		return col, nil
+	}
+	return color.RGBA{}, fmt.Errorf("bad color: unknown color '%s'", orig)
+}
+
+// MustParseColor parses a CSS color and panics if it fails.
+func MustParseColor(s string) color.RGBA {
+	col, err := ParseColor(s)
+	if err != nil {
+		panic(err)
+	}
+	return col
+}
+
+// FormatColor formats a color in hexadecimal notation, using the short form if possible and including the alpha component only when the color is not opaque. It is the inverse of ParseColor for valid premultiplied colors, ie. ParseColor(FormatColor(col)) returns col when no color component exceeds the alpha component. Larger components are clamped to the alpha component.
+func FormatColor(col color.RGBA) string {
+	r, g, b, a := col.R, col.G, col.B, col.A
+	if a != 0xff {
+		// unpremultiply alpha
+		unpremultiply := func(c uint8) uint8 {
+			if a == 0 {
+				return 0
+			} else if a < c {
+				c = a
+			}
+			return uint8((uint32(c)*0xff + uint32(a)/2) / uint32(a))
+		}
+		r, g, b = unpremultiply(r), unpremultiply(g), unpremultiply(b)
+	}
+	buf := []byte{r, g, b}
+	if a != 0xff {
+		buf = append(buf, a)
+	}
+
+	s := hex.EncodeToString(buf)
+	short := true
+	for i := 0; i < len(s); i += 2 {
+		if s[i] != s[i+1] {
+			short = false
+			break
+		}
+	}
+	if short {
+		b := []byte{}
+		for i := 0; i < len(s); i += 2 {
+			b = append(b, s[i])
+		}
+		s = string(b)
+	}
+	return "#" + s
+}
+
+func parseHexColor(s string) (color.RGBA, bool) {
+	if len(s) == 3 || len(s) == 4 {
+		b := make([]byte, 0, 8)
+		for i := 0; i < len(s); i++ {
+			b = append(b, s[i], s[i])
+		}
+		s = string(b)
+	}
+	if len(s) != 6 && len(s) != 8 {
+		return color.RGBA{}, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	alpha := 1.0
+	if len(b) == 4 {
+		alpha = float64(b[3]) / 255.0
+	}
+	return premultiplyColor(float64(b[0])/255.0, float64(b[1])/255.0, float64(b[2])/255.0, alpha), true
+}
+
+func parseRGBColor(args []string) (color.RGBA, bool) {
+	if len(args) != 3 && len(args) != 4 {
+		return color.RGBA{}, false
+	}
+	var rgb [3]float64
+	for i := 0; i < 3; i++ {
+		f, percentage, ok := parseColorNumber(args[i])
+		if !ok {
+			return color.RGBA{}, false
+		} else if !percentage {
+			f /= 255.0
+		}
+		rgb[i] = f
+	}
+	alpha := 1.0
+	if len(args) == 4 {
+		var ok bool
+		if alpha, _, ok = parseColorNumber(args[3]); !ok {
+			return color.RGBA{}, false
+		}
+	}
+	return premultiplyColor(rgb[0], rgb[1], rgb[2], alpha), true
+}
+
+func parseHSLColor(args []string) (color.RGBA, bool) {
+	if len(args) != 3 && len(args) != 4 {
+		return color.RGBA{}, false
+	}
+	h, percentage, ok := parseColorNumber(strings.TrimSuffix(args[0], "deg"))
+	if !ok || percentage {
+		return color.RGBA{}, false
+	}
+	s, percentageS, okS := parseColorNumber(args[1])
+	l, percentageL, okL := parseColorNumber(args[2])
+	if !okS || !okL || !percentageS || !percentageL {
+		return color.RGBA{}, false
+	}
+	alpha := 1.0
+	if len(args) == 4 {
+		if alpha, _, ok = parseColorNumber(args[3]); !ok {
+			return color.RGBA{}, false
+		}
+	}
+
+	// see https://www.w3.org/TR/css-color-3/#hsl-color
+	h = math.Mod(math.Mod(h, 360.0)+360.0, 360.0) / 360.0
+	s = math.Max(0.0, math.Min(1.0, s))
+	l = math.Max(0.0, math.Min(1.0, l))
+	m2 := l + s - l*s
+	if l <= 0.5 {
+		m2 = l * (s + 1.0)
+	}
+	m1 := l*2.0 - m2
+	hue := func(h float64) float64 {
+		if h < 0.0 {
+			h += 1.0
+		} else if 1.0 < h {
+			h -= 1.0
+		}
+		if h*6.0 < 1.0 {
+			return m1 + (m2-m1)*h*6.0
+		} else if h*2.0 < 1.0 {
+			return m2
+		} else if h*3.0 < 2.0 {
+			return m1 + (m2-m1)*(2.0/3.0-h)*6.0
+		}
+		return m1
+	}
+	return premultiplyColor(hue(h+1.0/3.0), hue(h), hue(h-1.0/3.0), alpha), true
+}
+
+// parseColorNumber parses a number or a percentage, which is returned as a fraction.
+func parseColorNumber(s string) (float64, bool, bool) {
+	percentage := strings.HasSuffix(s, "%")
+	if percentage {
+		s = s[:len(s)-1]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0.0, false, false
+	}
+	if percentage {
+		f /= 100.0
+	}
+	return f, percentage, true
+}
+
+// premultiplyColor returns the color with premultiplied alpha for components in [0,1], which are clamped.
+func premultiplyColor(r, g, b, a float64) color.RGBA {
+	a = math.Max(0.0, math.Min(1.0, a))
+	channel := func(f float64) uint8 {
+		f = math.Max(0.0, math.Min(1.0, f))
+		return uint8(math.Round(f*255.0)*a + 0.5)
+	}
+	return color.RGBA{channel(r), channel(g), channel(b), uint8(a*255.0 + 0.5)}
+}
+
+// SpotColor is a named spot color, such as PANTONE 186 C, that is printed with its own ink. Alternate is the color of the ink at full tint and is either a color.CMYK or an opaque RGB color. Tint is the amount of ink between 0 and 1. Renderers that do not support spot colors draw the alternate color at the given tint instead.
+type SpotColor struct {
+	Name      string
+	Alternate color.Color
+	Tint      float64
+}
+
+// RGBA returns the alternate color at the given tint, which for RGB colors is mixed with white. It implements the color.Color interface.
+func (c SpotColor) RGBA() (uint32, uint32, uint32, uint32) {
+	tint := math.Max(0.0, math.Min(1.0, c.Tint))
+	if cmyk, ok := c.Alternate.(color.CMYK); ok {
+		channel := func(v uint8) uint8 {
+			return uint8(math.Round(float64(v) * tint))
+		}
+		return color.CMYK{channel(cmyk.C), channel(cmyk.M), channel(cmyk.Y), channel(cmyk.K)}.RGBA()
+	}
+	channel := func(v uint32) uint32 {
+		return 0xffff - uint32(math.Round(float64(0xffff-v)*tint))
+	}
+	r, g, b, _ := c.Alternate.RGBA()
+	return channel(r), channel(g), channel(b), 0xffff
+}
@@ -0,0 +1,124 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestParseColor(t *testing.T) {
+	var tts = []struct {
+		s   string
+		col color.RGBA
+	}{
+		{"#1f77b4", color.RGBA{0x1f, 0x77, 0xb4, 0xff}},
+		{"#1F77B4", color.RGBA{0x1f, 0x77, 0xb4, 0xff}},
+		{"  #0f0 ", Lime},
+		{"#0f08", color.RGBA{0x00, 0x88, 0x00, 0x88}},
+		{"#ff000080", color.RGBA{0x80, 0x00, 0x00, 0x80}},
+		{"#00000000", Transparent},
+		{"rgb(31,119,180)", color.RGBA{31, 119, 180, 0xff}},
+		{"RGB( 31 , 119 , 180 )", color.RGBA{31, 119, 180, 0xff}},
+		{"rgb(31 119 180)", color.RGBA{31, 119, 180, 0xff}},
+		{"rgb(100%,50%,0%)", color.RGBA{0xff, 0x80, 0x00, 0xff}},
+		{"rgb(300,-10,0)", color.RGBA{0xff, 0x00, 0x00, 0xff}},
+		{"rgba(0,0,0,.5)", color.RGBA{0x00, 0x00, 0x00, 0x80}},
+		{"rgba(255,255,255,50%)", color.RGBA{0x80, 0x80, 0x80, 0x80}},
+		{"rgb(255 255 255 / 0.5)", color.RGBA{0x80, 0x80, 0x80, 0x80}},
+		{"rgba(255,0,0,2)", Red},
+		{"hsl(0,100%,50%)", Red},
+		{"hsl(120deg, 100%, 25%)", color.RGBA{0x00, 0x80, 0x00, 0xff}},
+		{"hsl(-120,100%,50%)", Blue},
+		{"hsl(0,0%,100%)", White},
+		{"hsla(240,100%,50%,0.5)", color.RGBA{0x00, 0x00, 0x80, 0x80}},
+		{"steelblue", Steelblue},
+		{"SteelBlue", Steelblue},
+		{" rebeccapurple", color.RGBA{0x66, 0x33, 0x99, 0xff}},
+		{"transparent", Transparent},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			col, err := ParseColor(tt.s)
+			test.Error(t, err)
+			test.T(t, col, tt.col)
+		})
+	}
+}
+
+func TestParseColorErrors(t *testing.T) {
+	var tts = []struct {
+		s   string
+		err string
+	}{
+		{"", "bad color: unknown color ''"},
+		{"steelbleu", "bad color: unknown color 'steelbleu'"},
+		{"#12", "bad color: invalid hexadecimal color '#12'"},
+		{"#12345", "bad color: invalid hexadecimal color '#12345'"},
+		{"#gggggg", "bad color: invalid hexadecimal color '#gggggg'"},
+		{"rgb(1,2,3", "bad color: missing ')' in 'rgb(1,2,3'"},
+		{"rgb(1,2)", "bad color: invalid arguments in 'rgb(1,2)'"},
+		{"rgb(1,2,x)", "bad color: invalid arguments in 'rgb(1,2,x)'"},
+		{"hsl(0,1,1)", "bad color: invalid arguments in 'hsl(0,1,1)'"},
+		{"hsl(10%,100%,50%)", "bad color: invalid arguments in 'hsl(10%,100%,50%)'"},
+		{"cmyk(0,0,0,0)", "bad color: unknown function 'cmyk' in 'cmyk(0,0,0,0)'"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			_, err := ParseColor(tt.s)
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestFormatColor(t *testing.T) {
+	test.String(t, FormatColor(Cyan), "#0ff")
+	test.String(t, FormatColor(Steelblue), "#4682b4")
+	test.String(t, FormatColor(Transparent), "#0000")
+	test.String(t, FormatColor(color.RGBA{0x80, 0x00, 0x00, 0x80}), "#ff000080")
+	test.String(t, FormatColor(color.RGBA{0x11, 0x22, 0x33, 0xff}), "#123")
+
+	// components that exceed alpha are not valid premultiplied colors and are clamped
+	var tts = []struct {
+		col color.RGBA
+		s   string
+	}{
+		{color.RGBA{200, 10, 10, 100}, "#ff1a1a64"},
+		{color.RGBA{255, 255, 255, 128}, "#ffffff80"},
+		{color.RGBA{101, 100, 0, 100}, "#ffff0064"},
+		{color.RGBA{10, 20, 30, 0}, "#0000"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			test.String(t, FormatColor(tt.col), tt.s)
+		})
+	}
+
+	// round-trip all premultiplied colors of a single channel
+	for a := 0; a < 256; a++ {
+		for r := 0; r <= a; r++ {
+			col := color.RGBA{uint8(r), 0, uint8(a - r), uint8(a)}
+			test.T(t, MustParseColor(FormatColor(col)), col, FormatColor(col))
+		}
+	}
+}
+
+func TestSpotColor(t *testing.T) {
+	pantone := SpotColor{"PANTONE 186 C", color.CMYK{0, 255, 204, 20}, 1.0}
+	test.T(t, color.RGBAModel.Convert(pantone), color.RGBA{235, 0, 47, 255})
+	pantone.Tint = 0.5
+	test.T(t, color.RGBAModel.Convert(pantone), color.RGBA{245, 122, 147, 255})
+	pantone.Tint = 0.0
+	test.T(t, color.RGBAModel.Convert(pantone), color.RGBA{255, 255, 255, 255})
+
+	gold := SpotColor{"Gold", color.RGBA{200, 160, 40, 255}, 0.5}
+	test.T(t, color.RGBAModel.Convert(gold), color.RGBA{228, 208, 148, 255})
+
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetFillColor(pantone)
+	test.T(t, *ctx.FillSpot, pantone)
+	test.That(t, ctx.FillCMYK == nil)
+	ctx.SetFillColor(Red)
+	test.That(t, ctx.FillSpot == nil)
+}
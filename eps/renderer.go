@@ -12,46 +12,30 @@ import (
 	"github.com/tdewolff/minify/v2"
 )
 
-var psEllipseDef = `/ellipse {
-/rot exch def
-/endangle exch def
-/startangle exch def
-/yrad exch def
-/xrad exch def
-/y exch def
-/x exch def
-/savematrix matrix currentmatrix def
-x y translate
-rot rotate
-xrad yrad scale
-0 0 1 startangle endangle arc
-savematrix setmatrix
-} def /ellipsen {
-/rot exch def
-/endangle exch def
-/startangle exch def
-/yrad exch def
-/xrad exch def
-/y exch def
-/x exch def
-/savematrix matrix currentmatrix def
-x y translate
-rot rotate
-xrad yrad scale
-0 0 1 startangle endangle arcn
-savematrix setmatrix
-} def`
+const ptPerMm = 72 / 25.4
 
+// Renderer is an encapsulated PostScript renderer, it must be closed with Close to write the trailer.
 type Renderer struct {
 	w             io.Writer
 	width, height float64
-	color         color.RGBA
+	color         color.Color // color.RGBA, color.CMYK or canvas.SpotColor
+	customColors  bool        // whether the custom color procedures have been defined
+
+	lineWidth  float64
+	lineCap    int
+	lineJoin   int
+	miterLimit float64
+	dashOffset float64
+	dashes     []float64
 }
 
-// New creates an encapsulated PostScript renderer.
+// New creates an encapsulated PostScript renderer of the given size in millimeters. The bounding box is written in points, while the coordinates are scaled so that paths are in millimeters.
 func New(w io.Writer, width, height float64) *Renderer {
-	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height))
-	fmt.Fprintf(w, psEllipseDef)
+	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%Creator: tdewolff/canvas\n")
+	fmt.Fprintf(w, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(width*ptPerMm-1e-6)), int(math.Ceil(height*ptPerMm-1e-6)))
+	fmt.Fprintf(w, "%%%%HiResBoundingBox: 0 0 %v %v\n", dec(width*ptPerMm), dec(height*ptPerMm))
+	fmt.Fprintf(w, "%%%%Pages: 1\n%%%%EndComments\n%%%%Page: 1 1\n")
+	fmt.Fprintf(w, "%v %v scale", dec(ptPerMm), dec(ptPerMm))
 	// TODO: (EPS) generate and add preview
 
 	return &Renderer{
@@ -59,6 +43,10 @@ func New(w io.Writer, width, height float64) *Renderer {
 		width:  width,
 		height: height,
 		color:  canvas.Black,
+
+		// initial graphics state of PostScript
+		lineWidth:  1.0,
+		miterLimit: 10.0,
 	}
 }
 
@@ -69,18 +57,172 @@ func (r *Renderer) setColor(color color.RGBA) {
 	}
 }
 
+func (r *Renderer) setCMYKColor(color color.CMYK) {
+	if color != r.color {
+		fmt.Fprintf(r.w, " %v %v %v %v setcmykcolor", dec(float64(color.C)/255.0), dec(float64(color.M)/255.0), dec(float64(color.Y)/255.0), dec(float64(color.K)/255.0))
+		r.color = color
+	}
+}
+
+// Close writes the showpage operator and the trailer.
+func (r *Renderer) Close() error {
+	_, err := fmt.Fprintf(r.w, "\nshowpage\n%%%%Trailer\n%%%%EOF\n")
+	return err
+}
+
+// setSpotColor sets a spot color using the custom color conventions of Adobe's Technical Note #5044, where interpreters without separation support use the CMYK equivalent of the alternate color at the given tint.
+func (r *Renderer) setSpotColor(spot canvas.SpotColor) {
+	if spot != r.color {
+		if !r.customColors {
+			fmt.Fprintf(r.w, " /findcmykcustomcolor where {pop} {/findcmykcustomcolor {5 array astore} def} ifelse")
+			fmt.Fprintf(r.w, " /setcustomcolor where {pop} {/setcustomcolor {exch aload pop pop 4 {4 index mul 4 1 roll} repeat 5 -1 roll pop setcmykcolor} def} ifelse")
+			r.customColors = true
+		}
+
+		alternate, ok := spot.Alternate.(color.CMYK)
+		if !ok {
+			alternate = color.CMYKModel.Convert(spot.Alternate).(color.CMYK)
+		}
+		name := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(spot.Name)
+		fmt.Fprintf(r.w, " %v %v %v %v (%v) findcmykcustomcolor %v setcustomcolor", dec(float64(alternate.C)/255.0), dec(float64(alternate.M)/255.0), dec(float64(alternate.Y)/255.0), dec(float64(alternate.K)/255.0), name, dec(math.Max(0.0, math.Min(1.0, spot.Tint))))
+		r.color = spot
+	}
+}
+
 func (r *Renderer) Size() (float64, float64) {
 	return r.width, r.height
 }
 
+// setPaintColor sets the current color, which is a spot color if spot is not nil or a CMYK color if cmyk is not nil.
+func (r *Renderer) setPaintColor(col color.RGBA, cmyk *color.CMYK, spot *canvas.SpotColor) {
+	if spot != nil {
+		r.setSpotColor(*spot)
+	} else if cmyk != nil {
+		r.setCMYKColor(*cmyk)
+	} else {
+		r.setColor(col)
+	}
+}
+
+// setLineStyle sets the line width, cap, join, miter limit, and dashes of the style. The joiner must be supported by PostScript.
+func (r *Renderer) setLineStyle(style canvas.Style) {
+	if style.StrokeWidth != r.lineWidth {
+		fmt.Fprintf(r.w, " %v setlinewidth", dec(style.StrokeWidth))
+		r.lineWidth = style.StrokeWidth
+	}
+
+	var lineCap int
+	if _, ok := style.StrokeCapper.(canvas.ButtCapper); ok {
+		lineCap = 0
+	} else if _, ok := style.StrokeCapper.(canvas.RoundCapper); ok {
+		lineCap = 1
+	} else if _, ok := style.StrokeCapper.(canvas.SquareCapper); ok {
+		lineCap = 2
+	} else {
+		panic("EPS: line cap not support")
+	}
+	if lineCap != r.lineCap {
+		fmt.Fprintf(r.w, " %d setlinecap", lineCap)
+		r.lineCap = lineCap
+	}
+
+	var lineJoin int
+	miterLimit := r.miterLimit
+	if _, ok := style.StrokeJoiner.(canvas.BevelJoiner); ok {
+		lineJoin = 2
+	} else if _, ok := style.StrokeJoiner.(canvas.RoundJoiner); ok {
+		lineJoin = 1
+	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+		lineJoin = 0
+		miterLimit = math.Max(1.0, miter.Limit)
+	}
+	if lineJoin != r.lineJoin {
+		fmt.Fprintf(r.w, " %d setlinejoin", lineJoin)
+		r.lineJoin = lineJoin
+	}
+	if miterLimit != r.miterLimit {
+		fmt.Fprintf(r.w, " %v setmiterlimit", dec(miterLimit))
+		r.miterLimit = miterLimit
+	}
+
+	dashesEqual := len(style.Dashes) == len(r.dashes)
+	if dashesEqual {
+		for i, dash := range style.Dashes {
+			if dash != r.dashes[i] {
+				dashesEqual = false
+				break
+			}
+		}
+	}
+	if !dashesEqual || 0 < len(style.Dashes) && style.DashOffset != r.dashOffset {
+		fmt.Fprintf(r.w, " [")
+		for i, dash := range style.Dashes {
+			if i != 0 {
+				fmt.Fprintf(r.w, " ")
+			}
+			fmt.Fprintf(r.w, "%v", dec(dash))
+		}
+		fmt.Fprintf(r.w, "] %v setdash", dec(style.DashOffset))
+		r.dashOffset = style.DashOffset
+		r.dashes = append(r.dashes[:0], style.Dashes...)
+	}
+}
+
 func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
 	// TODO: (EPS) test ellipse, rotations etc
 	// TODO: (EPS) add drawState support
 	// TODO: (EPS) use dither to fake transparency
-	r.setColor(style.FillColor)
-	r.w.Write([]byte(" "))
-	r.w.Write([]byte(path.Transform(m).ToPS()))
-	r.w.Write([]byte(" fill"))
+	fill := style.FillColor.A != 0
+	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+
+	// PostScript doesn't support the arcs joiner, miter joiner (not clipped), or miter joiner (clipped) with non-bevel fallback
+	strokeUnsupported := false
+	if _, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok {
+		strokeUnsupported = true
+	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+		if math.IsNaN(miter.Limit) {
+			strokeUnsupported = true
+		} else if _, ok := miter.GapJoiner.(canvas.BevelJoiner); !ok {
+			strokeUnsupported = true
+		}
+	}
+
+	// paths are stroked in output coordinates so that the stroke width and dashes are not transformed
+	path = path.Transform(m)
+	data := path.ToPS()
+	if fill {
+		r.setPaintColor(style.FillColor, style.FillCMYK, style.FillSpot)
+		r.w.Write([]byte(" "))
+		r.w.Write([]byte(data))
+		op := " fill"
+		if style.FillRule == canvas.EvenOdd {
+			op = " eofill"
+		}
+		if stroke && !strokeUnsupported {
+			r.w.Write([]byte(" gsave" + op + " grestore"))
+		} else {
+			r.w.Write([]byte(op))
+		}
+	}
+	if stroke && !strokeUnsupported {
+		r.setPaintColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
+		r.setLineStyle(style)
+		if !fill {
+			r.w.Write([]byte(" "))
+			r.w.Write([]byte(data))
+		}
+		r.w.Write([]byte(" stroke"))
+	} else if stroke {
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+
+		r.setPaintColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
+		r.w.Write([]byte(" "))
+		r.w.Write([]byte(path.ToPS()))
+		r.w.Write([]byte(" fill"))
+	}
 }
 
 func (r *Renderer) RenderText(text *canvas.Text, m canvas.Matrix) {
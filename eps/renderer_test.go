@@ -2,9 +2,13 @@ package eps
 
 import (
 	"bytes"
+	"errors"
+	"image/color"
+	"strings"
 	"testing"
 
 	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
 )
 
 func TestEPS(t *testing.T) {
@@ -13,3 +17,114 @@ func TestEPS(t *testing.T) {
 	eps.setColor(canvas.Red)
 	//test.String(t, string(w.Bytes()), "")
 }
+
+func TestEPSCMYK(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Cyan
+	style.FillCMYK = &color.CMYK{255, 0, 0, 0}
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+
+	style.FillColor = canvas.Red
+	style.FillCMYK = nil
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	test.String(t, w.String(), " 1 0 0 0 setcmykcolor 0 0 moveto 10 0 lineto 10 10 lineto closepath fill 0 0 moveto 10 0 lineto 10 10 lineto closepath fill 1 0 0 setrgbcolor 0 0 moveto 10 0 lineto 10 10 lineto closepath fill")
+}
+
+func TestEPSLineStyle(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Transparent
+	style.StrokeColor = canvas.Red
+	style.StrokeWidth = 2.0
+	polyline := canvas.MustParseSVG("M0 0L10 0L0 5")
+	eps.RenderPath(polyline, style, canvas.Identity)
+	style.StrokeJoiner = canvas.MiterClipJoin(canvas.BevelJoin, 3.0)
+	eps.RenderPath(polyline, style, canvas.Identity)
+	style.StrokeCapper = canvas.RoundCap
+	style.StrokeJoiner = canvas.RoundJoin
+	style.DashOffset = 1.0
+	style.Dashes = []float64{2.0, 1.0}
+	eps.RenderPath(polyline, style, canvas.Identity)
+	style.FillColor = canvas.Black
+	style.StrokeCapper = canvas.SquareCap
+	style.StrokeJoiner = canvas.BevelJoin
+	style.Dashes = nil
+	eps.RenderPath(polyline, style, canvas.Identity)
+	test.String(t, w.String(), " 1 0 0 setrgbcolor 2 setlinewidth 2 setmiterlimit 0 0 moveto 10 0 lineto 0 5 lineto stroke 3 setmiterlimit 0 0 moveto 10 0 lineto 0 5 lineto stroke 1 setlinecap 1 setlinejoin [2 1] 1 setdash 0 0 moveto 10 0 lineto 0 5 lineto stroke 0 0 0 setrgbcolor 0 0 moveto 10 0 lineto 0 5 lineto gsave fill grestore 1 0 0 setrgbcolor 2 setlinecap 2 setlinejoin [] 1 setdash stroke")
+
+	// unsupported line joins are stroked explicitly
+	w.Reset()
+	style.FillColor = canvas.Transparent
+	style.StrokeCapper = canvas.ButtCap
+	style.StrokeJoiner = canvas.ArcsJoin
+	eps.RenderPath(canvas.MustParseSVG("M0 0L10 0"), style, canvas.Identity)
+	test.String(t, w.String(), " 0 -1 moveto 10 -1 lineto 10 1 lineto 0 1 lineto closepath fill")
+}
+
+func TestEPSFillRule(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	style := canvas.DefaultStyle
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	style.FillRule = canvas.EvenOdd
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	test.String(t, w.String(), " 0 0 moveto 10 0 lineto 10 10 lineto closepath fill 0 0 moveto 10 0 lineto 10 10 lineto closepath eofill")
+}
+
+func TestEPSStructure(t *testing.T) {
+	c := canvas.New(100.0, 80.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	w := &bytes.Buffer{}
+	test.Error(t, Writer(w, c))
+
+	lines := strings.Split(w.String(), "\n")
+	test.T(t, lines[:7], []string{
+		"%!PS-Adobe-3.0 EPSF-3.0",
+		"%%Creator: tdewolff/canvas",
+		"%%BoundingBox: 0 0 284 227",
+		"%%HiResBoundingBox: 0 0 283.46457 226.77165",
+		"%%Pages: 1",
+		"%%EndComments",
+		"%%Page: 1 1",
+	})
+	test.T(t, lines[len(lines)-4:], []string{"showpage", "%%Trailer", "%%EOF", ""})
+	test.That(t, strings.Contains(w.String(), "2.8346457 2.8346457 scale 0 0 moveto 10 0 lineto 10 10 lineto 0 10 lineto closepath fill\n"))
+}
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestEPSWriterError(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	test.That(t, Writer(errorWriter{}, c) != nil, "write errors must be returned by Close")
+}
+
+func TestEPSSpotColor(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	style := canvas.DefaultStyle
+	style.FillSpot = &canvas.SpotColor{"PANTONE (186) C", color.CMYK{0, 255, 204, 20}, 0.5}
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	style.FillSpot = &canvas.SpotColor{"Gold", color.RGBA{255, 204, 0, 255}, 1.0}
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	test.String(t, w.String(), " /findcmykcustomcolor where {pop} {/findcmykcustomcolor {5 array astore} def} ifelse /setcustomcolor where {pop} {/setcustomcolor {exch aload pop pop 4 {4 index mul 4 1 roll} repeat 5 -1 roll pop setcmykcolor} def} ifelse"+
+		" 0 1 .8 .07843137 (PANTONE \\(186\\) C) findcmykcustomcolor .5 setcustomcolor 0 0 moveto 10 0 lineto 10 10 lineto closepath fill"+
+		" 0 .2 1 0 (Gold) findcmykcustomcolor 1 setcustomcolor 0 0 moveto 10 0 lineto 10 10 lineto closepath fill")
+}
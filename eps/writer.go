@@ -11,5 +11,5 @@ import (
 func Writer(w io.Writer, c *canvas.Canvas) error {
 	eps := New(w, c.W, c.H)
 	c.Render(eps)
-	return nil
+	return eps.Close()
 }
@@ -1057,7 +1057,7 @@ func (sfnt *SFNT) parseLoca() error {
 			return fmt.Errorf("loca: bad table")
 		}
 		for i := 0; i < int(sfnt.Maxp.NumGlyphs+1); i++ {
-			sfnt.Loca.Offsets[i] = uint32(r.ReadUint16())
+			sfnt.Loca.Offsets[i] = 2 * uint32(r.ReadUint16()) // short offsets are divided by two
 			if 0 < i && sfnt.Loca.Offsets[i] < sfnt.Loca.Offsets[i-1] {
 				return fmt.Errorf("loca: bad offsets")
 			}
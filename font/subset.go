@@ -0,0 +1,270 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Subset returns a TrueType font that contains only the given glyphs, so that glyph i of the subset is glyphIDs[i] of the original font. Glyphs referenced by composite glyphs are appended after the given glyphs. Only the tables needed to render the glyphs are kept (cmap, glyf, loca, head, hhea, hmtx, maxp, name, OS/2, post and the hinting tables cvt, fpgm and prep), where cmap only maps the characters of the Basic Multilingual Plane and post has no glyph names. The glyph IDs must be unique and should start with the .notdef glyph 0.
+func (sfnt *SFNT) Subset(glyphIDs []uint16) ([]byte, error) {
+	if !sfnt.IsTrueType {
+		return nil, fmt.Errorf("CFF not supported")
+	}
+
+	glyphMap := make(map[uint16]uint16, len(glyphIDs))
+	glyphs := make([]uint16, 0, len(glyphIDs))
+	for _, glyphID := range glyphIDs {
+		if _, ok := glyphMap[glyphID]; ok {
+			return nil, fmt.Errorf("glyf: duplicate glyphID %v", glyphID)
+		}
+		glyphMap[glyphID] = uint16(len(glyphs))
+		glyphs = append(glyphs, glyphID)
+	}
+
+	// add the glyphs referenced by composite glyphs, which may be composite glyphs themselves
+	for i := 0; i < len(glyphs); i++ {
+		b := sfnt.Glyf.Get(glyphs[i])
+		if b == nil {
+			return nil, fmt.Errorf("glyf: bad glyphID %v", glyphs[i])
+		}
+		positions, err := glyfComponents(b)
+		if err != nil {
+			return nil, fmt.Errorf("glyf: bad table for glyphID %v", glyphs[i])
+		}
+		for _, pos := range positions {
+			subGlyphID := binary.BigEndian.Uint16(b[pos:])
+			if _, ok := glyphMap[subGlyphID]; !ok {
+				glyphMap[subGlyphID] = uint16(len(glyphs))
+				glyphs = append(glyphs, subGlyphID)
+			}
+		}
+	}
+
+	// glyf and loca tables, where the glyph IDs of components are remapped
+	glyf := []byte{}
+	offsets := make([]uint32, len(glyphs)+1)
+	for i, glyphID := range glyphs {
+		b := sfnt.Glyf.Get(glyphID)
+		start := len(glyf)
+		glyf = append(glyf, b...)
+		positions, _ := glyfComponents(b)
+		for _, pos := range positions {
+			subGlyphID := binary.BigEndian.Uint16(b[pos:])
+			binary.BigEndian.PutUint16(glyf[start+int(pos):], glyphMap[subGlyphID])
+		}
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0x00)
+		}
+		offsets[i+1] = uint32(len(glyf))
+	}
+
+	var indexToLocFormat int16
+	loca := newBinaryWriter([]byte{})
+	if uint32(len(glyf))/2 <= 0xFFFF {
+		for _, offset := range offsets {
+			loca.WriteUint16(uint16(offset / 2))
+		}
+	} else {
+		indexToLocFormat = 1
+		for _, offset := range offsets {
+			loca.WriteUint32(offset)
+		}
+	}
+
+	// all glyphs have a long horizontal metric
+	hmtx := newBinaryWriter([]byte{})
+	for _, glyphID := range glyphs {
+		hmtx.WriteUint16(sfnt.Hmtx.Advance(glyphID))
+		hmtx.WriteInt16(sfnt.Hmtx.LeftSideBearing(glyphID))
+	}
+
+	head := append([]byte{}, sfnt.Tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0x00000000) // checksumAdjustment
+	binary.BigEndian.PutUint16(head[50:], uint16(indexToLocFormat))
+
+	hhea := append([]byte{}, sfnt.Tables["hhea"]...)
+	binary.BigEndian.PutUint16(hhea[34:], uint16(len(glyphs))) // numberOfHMetrics
+
+	maxp := append([]byte{}, sfnt.Tables["maxp"]...)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(glyphs))) // numGlyphs
+
+	post := append([]byte{}, sfnt.Tables["post"][:32]...)
+	binary.BigEndian.PutUint32(post, 0x00030000) // version without glyph names
+
+	tables := map[string][]byte{
+		"cmap": sfnt.subsetCmap(glyphMap),
+		"glyf": glyf,
+		"head": head,
+		"hhea": hhea,
+		"hmtx": hmtx.Bytes(),
+		"loca": loca.Bytes(),
+		"maxp": maxp,
+		"post": post,
+	}
+	for _, tag := range []string{"cvt ", "fpgm", "name", "OS/2", "prep"} {
+		if b, ok := sfnt.Tables[tag]; ok {
+			tables[tag] = b
+		}
+	}
+	return writeSFNT(0x00010000, tables), nil
+}
+
+// subsetCmap returns a cmap table with a format 4 subtable for the Windows Unicode BMP encoding that maps the characters of the original font to the glyph IDs of the subset.
+func (sfnt *SFNT) subsetCmap(glyphMap map[uint16]uint16) []byte {
+	// segments of consecutive characters that map to consecutive glyphs
+	var startCode, endCode []uint16
+	var idDelta []int16
+	for r := 0; r < 0xFFFF; r++ {
+		glyphID, ok := glyphMap[sfnt.Cmap.Get(rune(r))]
+		if !ok || glyphID == 0 {
+			continue
+		}
+		delta := int16(glyphID - uint16(r))
+		if n := len(endCode); 0 < n && int(endCode[n-1])+1 == r && idDelta[n-1] == delta {
+			endCode[n-1] = uint16(r)
+		} else {
+			startCode = append(startCode, uint16(r))
+			endCode = append(endCode, uint16(r))
+			idDelta = append(idDelta, delta)
+		}
+	}
+	startCode = append(startCode, 0xFFFF)
+	endCode = append(endCode, 0xFFFF)
+	idDelta = append(idDelta, 1)
+
+	segCount := uint16(len(startCode))
+	var searchRange uint16 = 1
+	var entrySelector uint16
+	for searchRange*2 <= segCount {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 2
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(0) // version
+	w.WriteUint16(1) // numTables
+	w.WriteUint16(3) // platformID
+	w.WriteUint16(1) // encodingID
+	w.WriteUint32(12)
+	w.WriteUint16(4)               // format
+	w.WriteUint16(16 + 8*segCount) // length
+	w.WriteUint16(0)               // language
+	w.WriteUint16(2 * segCount)
+	w.WriteUint16(searchRange)
+	w.WriteUint16(entrySelector)
+	w.WriteUint16(2*segCount - searchRange)
+	for _, code := range endCode {
+		w.WriteUint16(code)
+	}
+	w.WriteUint16(0) // reservedPad
+	for _, code := range startCode {
+		w.WriteUint16(code)
+	}
+	for _, delta := range idDelta {
+		w.WriteInt16(delta)
+	}
+	for range startCode {
+		w.WriteUint16(0) // idRangeOffset
+	}
+	return w.Bytes()
+}
+
+// glyfComponents returns the positions of the glyph IDs of the components in the data of a composite glyph. It returns nil for simple glyphs.
+func glyfComponents(b []byte) ([]uint32, error) {
+	if len(b) < 10 || 0 <= int16(binary.BigEndian.Uint16(b)) {
+		return nil, nil
+	}
+
+	positions := []uint32{}
+	r := newBinaryReader(b)
+	r.Seek(10)
+	for {
+		if r.Len() < 4 {
+			return nil, ErrInvalidFontData
+		}
+		flags := r.ReadUint16()
+		positions = append(positions, r.Pos())
+		_ = r.ReadUint16() // glyphIndex
+
+		n := uint32(2)
+		if flags&0x0001 != 0 { // ARG_1_AND_2_ARE_WORDS
+			n = 4
+		}
+		if flags&0x0008 != 0 { // WE_HAVE_A_SCALE
+			n += 2
+		} else if flags&0x0040 != 0 { // WE_HAVE_AN_X_AND_Y_SCALE
+			n += 4
+		} else if flags&0x0080 != 0 { // WE_HAVE_A_TWO_BY_TWO
+			n += 8
+		}
+		if r.Len() < n {
+			return nil, ErrInvalidFontData
+		}
+		_ = r.ReadBytes(n)
+		if flags&0x0020 == 0 { // MORE_COMPONENTS
+			break
+		}
+	}
+	return positions, nil
+}
+
+// writeSFNT writes an SFNT font file with the given tables and sets the checksums, including the checksum adjustment of the head table.
+func writeSFNT(sfntVersion uint32, tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := uint16(len(tags))
+	var searchRange uint16 = 1
+	var entrySelector uint16
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+	rangeShift := numTables*16 - searchRange
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(sfntVersion)
+	w.WriteUint16(numTables)
+	w.WriteUint16(searchRange)
+	w.WriteUint16(entrySelector)
+	w.WriteUint16(rangeShift)
+
+	// write table record entries
+	var checksumAdjustmentPos uint32
+	offset := 12 + 16*uint32(numTables)
+	for _, tag := range tags {
+		data := tables[tag]
+		length := uint32(len(data))
+		padded := append(data[:length:length], make([]byte, (4-length&3)&3)...)
+		if tag == "head" {
+			checksumAdjustmentPos = offset + 8
+		}
+
+		w.WriteString(tag)
+		w.WriteUint32(calcChecksum(padded))
+		w.WriteUint32(offset)
+		w.WriteUint32(length)
+		offset += uint32(len(padded))
+	}
+
+	// write tables
+	for _, tag := range tags {
+		data := tables[tag]
+		w.WriteBytes(data)
+		for i := 0; i < (4-len(data)&3)&3; i++ {
+			w.WriteByte(0x00)
+		}
+	}
+
+	buf := w.Bytes()
+	if checksumAdjustmentPos != 0 {
+		binary.BigEndian.PutUint32(buf[checksumAdjustmentPos:], 0xB1B0AFBA-calcChecksum(buf))
+	}
+	return buf
+}
@@ -0,0 +1,45 @@
+package font
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSFNTSubset(t *testing.T) {
+	b, err := ioutil.ReadFile("DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := ParseSFNT(b)
+	test.Error(t, err)
+
+	glyphIDs := []uint16{0, font.GlyphIndex('b'), font.GlyphIndex('a'), font.GlyphIndex('é')}
+	subset, err := font.Subset(glyphIDs)
+	test.Error(t, err)
+	test.That(t, len(subset) < len(b)/20, len(subset), len(b))
+
+	sub, err := ParseSFNT(subset)
+	test.Error(t, err)
+	test.That(t, len(glyphIDs) <= int(sub.Maxp.NumGlyphs), sub.Maxp.NumGlyphs)
+	test.T(t, sub.GlyphIndex('a'), uint16(2))
+	test.T(t, sub.GlyphIndex('é'), uint16(3))
+	test.T(t, sub.GlyphIndex('c'), uint16(0))
+	for i, glyphID := range glyphIDs {
+		test.T(t, sub.GlyphAdvance(uint16(i)), font.GlyphAdvance(glyphID))
+
+		contour, err := font.GlyphContour(glyphID)
+		test.Error(t, err)
+		subContour, err := sub.GlyphContour(uint16(i))
+		test.Error(t, err)
+		if contour == nil {
+			test.That(t, subContour == nil)
+			continue
+		}
+		test.T(t, subContour.XCoordinates, contour.XCoordinates)
+		test.T(t, subContour.YCoordinates, contour.YCoordinates)
+	}
+
+	_, err = font.Subset([]uint16{0, 1, 1})
+	test.That(t, err != nil, "duplicate glyph IDs must return an error")
+}
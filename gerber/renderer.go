@@ -0,0 +1,229 @@
+package gerber
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Gerber is a renderer for PCB artwork in the extended Gerber format (RS-274X). Coordinates are written in millimeters with six decimals.
+type Gerber struct {
+	w             io.Writer
+	width, height float64
+	tolerance     float64
+	arcs          bool
+
+	apertures map[string]int
+	aperture  int    // current aperture D code
+	interp    string // current interpolation mode
+	clear     bool   // current polarity is clear
+	warnings  []string
+}
+
+// New creates a Gerber (RS-274X) renderer. Stroked paths are drawn with circular apertures of the stroke width, and filled paths are drawn as regions where holes are drawn with clear polarity. Be aware that clear polarity also clears any artwork drawn earlier at the location of the hole.
+func New(w io.Writer, width, height float64) *Gerber {
+	fmt.Fprintf(w, "%%FSLAX46Y46*%%\n%%MOMM*%%\n%%LPD*%%\nG75*\nG01*\n")
+	return &Gerber{
+		w:         w,
+		width:     width,
+		height:    height,
+		tolerance: canvas.Tolerance,
+		arcs:      true,
+		apertures: map[string]int{},
+		interp:    "G01",
+	}
+}
+
+// SetTolerance sets the maximum deviation in millimeters when flattening Bézier curves and elliptical arcs, which is canvas.Tolerance by default.
+func (r *Gerber) SetTolerance(tolerance float64) {
+	r.tolerance = tolerance
+}
+
+// FlattenArcs flattens circular arcs as well instead of writing them as circular interpolations (G02/G03).
+func (r *Gerber) FlattenArcs(flatten bool) {
+	r.arcs = !flatten
+}
+
+// Warnings returns the unsupported features that were skipped while rendering, each reported once.
+func (r *Gerber) Warnings() []string {
+	return r.warnings
+}
+
+func (r *Gerber) warn(msg string) {
+	for _, warning := range r.warnings {
+		if warning == msg {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, msg)
+}
+
+func (r *Gerber) Close() error {
+	_, err := fmt.Fprintf(r.w, "M02*\n")
+	return err
+}
+
+func (r *Gerber) Size() (float64, float64) {
+	return r.width, r.height
+}
+
+func num(f float64) int64 {
+	return int64(math.Round(f * 1e6))
+}
+
+func (r *Gerber) setInterpolation(interp string) {
+	if interp != r.interp {
+		fmt.Fprintf(r.w, "%s*\n", interp)
+		r.interp = interp
+	}
+}
+
+func (r *Gerber) setPolarity(clear bool) {
+	if clear != r.clear {
+		if clear {
+			fmt.Fprintf(r.w, "%%LPC*%%\n")
+		} else {
+			fmt.Fprintf(r.w, "%%LPD*%%\n")
+		}
+		r.clear = clear
+	}
+}
+
+// setAperture selects a circular aperture of the given diameter, defining it when it is used for the first time. Apertures of equal diameter (in the output precision) are reused.
+func (r *Gerber) setAperture(diameter float64) {
+	size := strconv.FormatFloat(float64(num(diameter))/1e6, 'f', -1, 64)
+	code, ok := r.apertures[size]
+	if !ok {
+		code = 10 + len(r.apertures)
+		fmt.Fprintf(r.w, "%%ADD%dC,%s*%%\n", code, size)
+		r.apertures[size] = code
+	}
+	if code != r.aperture {
+		fmt.Fprintf(r.w, "D%d*\n", code)
+		r.aperture = code
+	}
+}
+
+// flatten flattens the path with the renderer's tolerance. Since Flatten uses canvas.Tolerance, the path is scaled so that the maximum deviation corresponds to the renderer's tolerance.
+func (r *Gerber) flatten(p *canvas.Path) *canvas.Path {
+	k := canvas.Tolerance / r.tolerance
+	return p.Transform(canvas.Identity.Scale(k, k)).Flatten().Transform(canvas.Identity.Scale(1.0/k, 1.0/k))
+}
+
+// writePath writes the path as moves (D02) and interpolations (D01), where Bézier curves and elliptical arcs are flattened.
+func (r *Gerber) writePath(path *canvas.Path) {
+	move := func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, "X%dY%dD02*\n", num(end.X), num(end.Y))
+	}
+	line := func(start, end canvas.Point) {
+		r.setInterpolation("G01")
+		fmt.Fprintf(r.w, "X%dY%dD01*\n", num(end.X), num(end.Y))
+	}
+	curve := func(segment *canvas.Path) {
+		coords := r.flatten(segment).Coords()
+		for i := 1; i < len(coords); i++ {
+			line(coords[i-1], coords[i])
+		}
+	}
+	path.Iterate(move, line, func(start, cp, end canvas.Point) {
+		curve((&canvas.Path{}).MoveTo(start.X, start.Y).QuadTo(cp.X, cp.Y, end.X, end.Y))
+	}, func(start, cp1, cp2, end canvas.Point) {
+		curve((&canvas.Path{}).MoveTo(start.X, start.Y).CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y))
+	}, func(start canvas.Point, rx, ry, rot float64, large, sweep bool, end canvas.Point) {
+		if !r.arcs || !canvas.Equal(rx, ry) {
+			curve((&canvas.Path{}).MoveTo(start.X, start.Y).ArcTo(rx, ry, rot, large, sweep, end.X, end.Y))
+			return
+		}
+		cx, cy, _, _ := canvas.EllipseToCenter(start.X, start.Y, rx, ry, rot, large, sweep, end.X, end.Y)
+		if sweep {
+			r.setInterpolation("G03") // counter clockwise
+		} else {
+			r.setInterpolation("G02") // clockwise
+		}
+		fmt.Fprintf(r.w, "X%dY%dI%dJ%dD01*\n", num(end.X), num(end.Y), num(cx-start.X), num(cy-start.Y))
+	}, line)
+}
+
+func (r *Gerber) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if path.Empty() {
+		return
+	}
+	path = path.Transform(m)
+
+	if style.FillColor.A != 0 {
+		r.renderFill(path, style.FillRule)
+	}
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		r.setPolarity(false)
+		r.setAperture(style.StrokeWidth)
+		r.writePath(path)
+	}
+}
+
+// renderFill writes the closed subpaths as regions (G36/G37). Subpaths are drawn from the outermost to the innermost, where subpaths that are not filled according to the fill rule (ie. holes) are drawn with clear polarity.
+func (r *Gerber) renderFill(path *canvas.Path, fillRule canvas.FillRule) {
+	ps := path.Split()
+	for i := range ps {
+		ps[i] = ps[i].Copy().Close()
+	}
+	fillings := path.Filling(fillRule)
+
+	polylines := make([]*canvas.Polyline, len(ps))
+	for i := range ps {
+		polylines[i] = canvas.PolylineFromPath(ps[i])
+	}
+	depths := make([]int, len(ps))
+	for i := range ps {
+		if ps[i].Empty() {
+			continue
+		}
+		test := ps[i].StartPos()
+		for j := range ps {
+			if i != j && !ps[j].Empty() && polylines[j].FillCount(test.X, test.Y) != 0 {
+				depths[i]++
+			}
+		}
+	}
+	order := make([]int, len(ps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return depths[order[a]] < depths[order[b]]
+	})
+
+	region := false
+	for _, i := range order {
+		if ps[i].Empty() || !fillings[i] && depths[i] == 0 {
+			continue // not filled and not a hole of another subpath
+		}
+		if clear := !fillings[i]; clear != r.clear || !region {
+			if region {
+				fmt.Fprintf(r.w, "G37*\n")
+			}
+			r.setPolarity(clear)
+			fmt.Fprintf(r.w, "G36*\n")
+			region = true
+		}
+		r.writePath(ps[i])
+	}
+	if region {
+		fmt.Fprintf(r.w, "G37*\n")
+	}
+}
+
+func (r *Gerber) RenderText(text *canvas.Text, m canvas.Matrix) {
+	text.RenderAsPath(r, m)
+}
+
+func (r *Gerber) RenderImage(img image.Image, m canvas.Matrix) {
+	r.warn("images are not supported and are skipped")
+}
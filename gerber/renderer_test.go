@@ -0,0 +1,249 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestGerber(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(0.2)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0L1 0"))
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 1A1 1 0 0 1 2 1"))
+	ctx.SetStrokeWidth(0.5)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 3L1 3"))
+	ctx.SetStrokeWidth(0.2)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 4L1 4"))
+	ctx.SetFillColor(canvas.Black)
+	ctx.SetStrokeColor(canvas.Transparent)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 5H3V8H0zM1 6V7H2V6z"))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.String(t, buf.String(), `%FSLAX46Y46*%
+%MOMM*%
+%LPD*%
+G75*
+G01*
+%ADD10C,0.2*%
+D10*
+X0Y0D02*
+X1000000Y0D01*
+X0Y1000000D02*
+G03*
+X2000000Y1000000I1000000J0D01*
+%ADD11C,0.5*%
+D11*
+X0Y3000000D02*
+G01*
+X1000000Y3000000D01*
+D10*
+X0Y4000000D02*
+X1000000Y4000000D01*
+G36*
+X0Y5000000D02*
+X3000000Y5000000D01*
+X3000000Y8000000D01*
+X0Y8000000D01*
+X0Y5000000D01*
+G37*
+%LPC*%
+G36*
+X1000000Y6000000D02*
+X1000000Y7000000D01*
+X2000000Y7000000D01*
+X2000000Y6000000D01*
+X1000000Y6000000D01*
+G37*
+M02*
+`)
+}
+
+// gerberObject is a region or a set of traces drawn with a circular aperture, as read back by gerberReader.
+type gerberObject struct {
+	clear    bool
+	region   bool
+	diameter float64
+	contours [][]canvas.Point // for traces each contour is a polyline
+}
+
+func (o gerberObject) covers(p canvas.Point) bool {
+	for _, contour := range o.contours {
+		if o.region {
+			inside := false
+			for i := 1; i < len(contour); i++ {
+				a, b := contour[i-1], contour[i]
+				if (a.Y <= p.Y) != (b.Y <= p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+					inside = !inside
+				}
+			}
+			if inside {
+				return true
+			}
+		} else {
+			for i := 1; i < len(contour); i++ {
+				a, b := contour[i-1], contour[i]
+				t := 0.0
+				if ab := b.Sub(a); !ab.IsZero() {
+					t = math.Max(0.0, math.Min(1.0, p.Sub(a).Dot(ab)/ab.Dot(ab)))
+				}
+				if p.Sub(a.Interpolate(b, t)).Length() <= o.diameter/2.0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// readGerber is a simple reader for the subset of RS-274X that the renderer writes.
+func readGerber(t *testing.T, s string) []gerberObject {
+	objects := []gerberObject{}
+	apertures := map[string]float64{}
+	scale := 1.0
+	clear, region := false, false
+	interp := "G01"
+	var diameter float64
+	var pos canvas.Point
+	var cur *gerberObject
+
+	newObject := func() {
+		objects = append(objects, gerberObject{clear: clear, region: region, diameter: diameter})
+		cur = &objects[len(objects)-1]
+	}
+	for _, cmd := range strings.Split(strings.Replace(strings.Replace(s, "\n", "", -1), "%", "", -1), "*") {
+		switch {
+		case cmd == "" || cmd == "MOMM" || cmd == "G75" || cmd == "M02":
+		case strings.HasPrefix(cmd, "FSLAX"):
+			scale = math.Pow(10.0, float64(cmd[6]-'0'))
+		case cmd == "LPD" || cmd == "LPC":
+			clear = cmd == "LPC"
+			cur = nil
+		case cmd == "G01" || cmd == "G02" || cmd == "G03":
+			interp = cmd
+		case cmd == "G36" || cmd == "G37":
+			region = cmd == "G36"
+			cur = nil
+		case strings.HasPrefix(cmd, "ADD"):
+			i := strings.Index(cmd, "C,")
+			f, err := strconv.ParseFloat(cmd[i+2:], 64)
+			test.Error(t, err)
+			apertures["D"+cmd[3:i]] = f
+		case cmd[0] == 'D':
+			diameter = apertures[cmd]
+			cur = nil
+		case cmd[0] == 'X':
+			var x, y, i, j float64
+			var d string
+			for len(cmd) != 0 {
+				k := 1
+				for k < len(cmd) && (cmd[k] == '-' || '0' <= cmd[k] && cmd[k] <= '9') {
+					k++
+				}
+				if cmd[0] == 'D' {
+					d = cmd[:k]
+				} else {
+					f, err := strconv.ParseFloat(cmd[1:k], 64)
+					test.Error(t, err)
+					switch cmd[0] {
+					case 'X':
+						x = f / scale
+					case 'Y':
+						y = f / scale
+					case 'I':
+						i = f / scale
+					case 'J':
+						j = f / scale
+					}
+				}
+				cmd = cmd[k:]
+			}
+			end := canvas.Point{x, y}
+			if d == "D02" {
+				if cur == nil {
+					newObject()
+				}
+				cur.contours = append(cur.contours, []canvas.Point{end})
+			} else if d == "D01" {
+				contour := &cur.contours[len(cur.contours)-1]
+				if interp == "G01" {
+					*contour = append(*contour, end)
+				} else {
+					center := pos.Add(canvas.Point{i, j})
+					theta0 := pos.Sub(center).Angle()
+					theta1 := end.Sub(center).Angle()
+					if interp == "G03" && theta1 <= theta0 {
+						theta1 += 2.0 * math.Pi
+					} else if interp == "G02" && theta0 <= theta1 {
+						theta1 -= 2.0 * math.Pi
+					}
+					r := pos.Sub(center).Length()
+					for k := 1; k <= 100; k++ {
+						theta := theta0 + (theta1-theta0)*float64(k)/100.0
+						*contour = append(*contour, center.Add(canvas.Point{r * math.Cos(theta), r * math.Sin(theta)}))
+					}
+				}
+			} else {
+				t.Fatalf("unexpected operation %s", d)
+			}
+			pos = end
+		default:
+			t.Fatalf("unexpected command %s", cmd)
+		}
+	}
+	return objects
+}
+
+func TestGerberArea(t *testing.T) {
+	c := canvas.New(40.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeColor(canvas.Transparent)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M5 5H25V25H5zM10 10V20H20V10z")) // donut of 300mm²
+	ctx.DrawPath(32.0, 12.0, canvas.Ellipse(4.0, 2.0))                           // 8π mm²
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(1.0)
+	ctx.DrawPath(32.0, 32.0, canvas.Circle(4.0))                // 8π mm²
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M28 20L38 20")) // 10+π/4 mm²
+	ctx.SetStrokeWidth(2.0)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M2 2Q2 20 2 38")) // 72+π mm²
+	area := 300.0 + 8.0*math.Pi + 8.0*math.Pi + 10.0 + math.Pi/4.0 + 72.0 + math.Pi
+
+	for _, flattenArcs := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		gerber := New(buf, c.W, c.H)
+		gerber.SetTolerance(0.001)
+		gerber.FlattenArcs(flattenArcs)
+		c.Render(gerber)
+		test.Error(t, gerber.Close())
+		test.T(t, strings.Count(buf.String(), "%ADD"), 2)
+		test.T(t, strings.Contains(buf.String(), "G03*"), !flattenArcs)
+
+		objects := readGerber(t, buf.String())
+		step := 0.1
+		covered := 0
+		for y := step / 2.0; y < c.H; y += step {
+			for x := step / 2.0; x < c.W; x += step {
+				dark := false
+				for _, object := range objects {
+					if object.covers(canvas.Point{x, y}) {
+						dark = !object.clear
+					}
+				}
+				if dark {
+					covered++
+				}
+			}
+		}
+		test.That(t, math.Abs(float64(covered)*step*step-area) < 0.01*area, float64(covered)*step*step, area)
+	}
+}
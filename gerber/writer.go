@@ -0,0 +1,14 @@
+package gerber
+
+import (
+	"io"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Writer writes the canvas as an extended Gerber (RS-274X) file for PCB artwork such as silkscreen or solder mask layers. Colors are ignored, everything that is filled or stroked is drawn.
+func Writer(w io.Writer, c *canvas.Canvas) error {
+	gerber := New(w, c.W, c.H)
+	c.Render(gerber)
+	return gerber.Close()
+}
@@ -1,6 +1,7 @@
 package canvas
 
 import (
+	"image/color"
 	"io"
 	"math"
 
@@ -20,23 +21,41 @@ type GoChart struct {
 	fontSize     float64
 	fontColor    drawing.Color
 	textRotation float64
+	background   color.Color
+	className    string // class of the open group, if any
+}
+
+// GoChartOption is an option for the go-chart renderer.
+type GoChartOption func(*GoChart)
+
+// WithBackground draws the chart over a background of the given color. This is needed for formats without transparency such as JPEG, for which a transparent background turns black. Encoder options such as the JPEG quality or the number of GIF colors are set by the writer, eg. rasterizer.JPGWriter(resolution, &jpeg.Options{Quality: 92}).
+func WithBackground(background color.Color) GoChartOption {
+	return func(r *GoChart) {
+		r.background = background
+	}
 }
 
 // NewGoChart returns a new github.com/wcharczuk/go-chart renderer.
-func NewGoChart(writer Writer) func(int, int) (chart.Renderer, error) {
+func NewGoChart(writer Writer, opts ...GoChartOption) func(int, int) (chart.Renderer, error) {
 	return func(w, h int) (chart.Renderer, error) {
 		font := NewFontFamily("font")
 		font.LoadLocalFont("Arimo", FontRegular)
 
 		c := New(float64(w), float64(h))
-		return &GoChart{
+		ctx := NewContext(c)
+		ctx.SetCoordSystem(CartesianIV) // go-chart has the origin at the top-left
+		r := &GoChart{
 			c:      c,
-			ctx:    NewContext(c),
+			ctx:    ctx,
 			height: float64(h),
 			writer: writer,
 			dpi:    72.0,
 			font:   font,
-		}, nil
+		}
+		for _, opt := range opts {
+			opt(r)
+		}
+		return r, nil
 	}
 }
 
@@ -56,9 +75,17 @@ func (r *GoChart) SetDPI(dpi float64) {
 	r.dpi = dpi
 }
 
-// SetClassName sets the current class name.
+// SetClassName sets the current class name. Elements drawn with the same class name are put in a group with that class, see Context.StartGroup.
 func (r *GoChart) SetClassName(name string) {
-	// TODO
+	if name == r.className {
+		return
+	} else if r.className != "" {
+		r.ctx.EndGroup()
+	}
+	if name != "" {
+		r.ctx.StartGroup("", name)
+	}
+	r.className = name
 }
 
 // SetStrokeColor sets the current stroke color.
@@ -83,19 +110,19 @@ func (r *GoChart) SetStrokeDashArray(dashArray []float64) {
 
 // MoveTo moves the cursor to a given point.
 func (r *GoChart) MoveTo(x, y int) {
-	r.ctx.MoveTo(float64(x), r.height-float64(y))
+	r.ctx.MoveTo(float64(x), float64(y))
 }
 
 // LineTo both starts a shape and draws a line to a given point
 // from the previous point.
 func (r *GoChart) LineTo(x, y int) {
-	r.ctx.LineTo(float64(x), r.height-float64(y))
+	r.ctx.LineTo(float64(x), float64(y))
 }
 
 // QuadCurveTo draws a quad curve.
 // cx and cy represent the bezier "control points".
 func (r *GoChart) QuadCurveTo(cx, cy, x, y int) {
-	r.ctx.QuadTo(float64(cx), r.height-float64(cy), float64(x), r.height-float64(y))
+	r.ctx.QuadTo(float64(cx), float64(cy), float64(x), float64(y))
 }
 
 // ArcTo draws an arc with a given center (cx,cy)
@@ -104,11 +131,11 @@ func (r *GoChart) ArcTo(cx, cy int, rx, ry, startAngle, delta float64) {
 	startAngle *= 180.0 / math.Pi
 	delta *= 180.0 / math.Pi
 
-	start := ellipsePos(rx, -ry, 0.0, float64(cx), r.height-float64(cy), startAngle)
+	start := ellipsePos(rx, ry, 0.0, float64(cx), float64(cy), startAngle)
 	if r.c.Empty() {
-		r.ctx.MoveTo(start.X, r.height-start.Y)
+		r.ctx.MoveTo(start.X, start.Y)
 	} else {
-		r.ctx.LineTo(start.X, r.height-start.Y)
+		r.ctx.LineTo(start.X, start.Y)
 	}
 	r.ctx.Arc(rx, ry, 0.0, startAngle, startAngle+delta)
 }
@@ -136,7 +163,7 @@ func (r *GoChart) FillStroke() {
 
 // Circle draws a circle at the given coords with a given radius.
 func (r *GoChart) Circle(radius float64, x, y int) {
-	r.ctx.DrawPath(float64(x), r.height-float64(y), Circle(radius))
+	r.ctx.DrawPath(float64(x), float64(y), Circle(radius))
 }
 
 // SetFont sets a font for a text field.
@@ -154,19 +181,25 @@ func (r *GoChart) SetFontSize(size float64) {
 	r.fontSize = size
 }
 
+// face returns the font face of the font size, which go-chart gives in points at the DPI of the chart, while a pixel of the chart is a millimeter of the canvas.
+func (r *GoChart) face() FontFace {
+	size := Mm(Pt(r.fontSize).Px(DPMM(r.dpi) * DPI))
+	return r.font.Face(size.Pt(), r.fontColor, FontRegular, FontNormal)
+}
+
 // Text draws a text blob.
 func (r *GoChart) Text(body string, x, y int) {
-	face := r.font.Face(r.fontSize*ptPerMm*r.dpi/72.0, r.fontColor, FontRegular, FontNormal)
+	face := r.face()
 	r.ctx.Push()
 	r.ctx.SetFillColor(r.fontColor)
-	r.ctx.ComposeView(Identity.Rotate(-r.textRotation * 180.0 / math.Pi))
-	r.ctx.DrawText(float64(x), r.height-float64(y), NewTextLine(face, body, Left))
+	r.ctx.RotateAbout(-r.textRotation*180.0/math.Pi, float64(x), r.height-float64(y)) // the view is in the coordinates of the canvas
+	r.ctx.DrawText(float64(x), float64(y), NewTextLine(face, body, Left))
 	r.ctx.Pop()
 }
 
 // MeasureText measures text.
 func (r *GoChart) MeasureText(body string) chart.Box {
-	p, _ := r.font.Face(r.fontSize*ptPerMm*r.dpi/72.0, r.fontColor, FontRegular, FontNormal).ToPath(body)
+	p, _ := r.face().ToPath(body)
 	bounds := p.Bounds()
 	bounds = bounds.Transform(Identity.Rotate(-r.textRotation * 180.0 / math.Pi))
 	return chart.Box{Left: int(bounds.X + 0.5), Top: int(bounds.Y + 0.5), Right: int((bounds.W + bounds.X) + 0.5), Bottom: int((bounds.H + bounds.Y) + 0.5)}
@@ -184,5 +217,14 @@ func (r *GoChart) ClearTextRotation() {
 
 // Save writes the image to the given writer.
 func (r *GoChart) Save(w io.Writer) error {
-	return r.writer(w, r.c)
+	r.SetClassName("") // end the open group
+	c := r.c
+	if r.background != nil {
+		c = New(r.c.W, r.c.H)
+		ctx := NewContext(c)
+		ctx.SetFillColor(r.background)
+		ctx.DrawPath(0.0, 0.0, Rectangle(c.W, c.H))
+		c.layers = append(c.layers, r.c.layers...)
+	}
+	return r.writer(w, c)
 }
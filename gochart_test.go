@@ -0,0 +1,95 @@
+package canvas
+
+import (
+	"image/color"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+func TestGoChartBackground(t *testing.T) {
+	var layers []layer
+	writer := func(w io.Writer, c *Canvas) error {
+		layers = c.layers
+		return nil
+	}
+
+	r, err := NewGoChart(writer)(20, 10)
+	test.Error(t, err)
+	r.SetFillColor(drawing.ColorRed)
+	r.MoveTo(0, 0)
+	r.LineTo(10, 0)
+	r.LineTo(10, 10)
+	r.Close()
+	r.Fill()
+	test.Error(t, r.Save(nil))
+	test.T(t, len(layers), 1)
+
+	r, err = NewGoChart(writer, WithBackground(White))(20, 10)
+	test.Error(t, err)
+	r.SetFillColor(drawing.ColorRed)
+	r.MoveTo(0, 0)
+	r.LineTo(10, 0)
+	r.LineTo(10, 10)
+	r.Close()
+	r.Fill()
+	test.Error(t, r.Save(nil))
+	test.T(t, len(layers), 2)
+	test.T(t, layers[0].style.FillColor, White)
+	test.T(t, layers[0].path.Bounds(), Rect{0.0, 0.0, 20.0, 10.0})
+	test.T(t, layers[1].style.FillColor, color.RGBA{255, 0, 0, 255})
+}
+
+func TestGoChartClassName(t *testing.T) {
+	var layers []layer
+	writer := func(w io.Writer, c *Canvas) error {
+		layers = c.layers
+		return nil
+	}
+
+	r, err := NewGoChart(writer)(20, 10)
+	test.Error(t, err)
+	r.SetClassName("series")
+	r.MoveTo(0, 0)
+	r.LineTo(10, 0)
+	r.Stroke()
+	r.SetClassName("series")
+	r.MoveTo(0, 5)
+	r.LineTo(10, 5)
+	r.Stroke()
+	r.SetClassName("axis")
+	r.MoveTo(0, 10)
+	r.LineTo(10, 10)
+	r.Stroke()
+	test.Error(t, r.Save(nil))
+	test.T(t, len(layers), 7)
+	test.T(t, *layers[0].group, group{"", "series"})
+	test.That(t, layers[3].groupEnd)
+	test.T(t, *layers[4].group, group{"", "axis"})
+	test.That(t, layers[6].groupEnd)
+}
+
+func TestGoChartTextRotation(t *testing.T) {
+	var layers []layer
+	writer := func(w io.Writer, c *Canvas) error {
+		layers = c.layers
+		return nil
+	}
+
+	r, err := NewGoChart(writer)(20, 10)
+	test.Error(t, err)
+	font := NewFontFamily("dejavu-serif")
+	test.Error(t, font.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+	r.(*GoChart).font = font
+	r.SetFontSize(10.0)
+	r.SetTextRotation(math.Pi / 2.0)
+	r.Text("A", 5, 2)
+	test.Error(t, r.Save(nil))
+
+	// the text is rotated about its position
+	test.T(t, len(layers), 1)
+	test.T(t, layers[0].m, Identity.Translate(5.0, 8.0).Rotate(-90.0))
+}
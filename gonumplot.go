@@ -144,10 +144,11 @@ func (r *GonumPlot) FillString(f vg.Font, pt vg.Point, text string) {
 // DrawImage draws the image, scaled to fit
 // the destination rectangle.
 func (r *GonumPlot) DrawImage(rect vg.Rectangle, img image.Image) {
-	//x, y := float64(rect.Min.X*mmPerPt), float64(rect.Min.Y*mmPerPt)
-	//w, h := float64(rect.Max.X*mmPerPt), float64(rect.Max.Y*mmPerPt)
-	//size := img.Size()
-	//if
-	//r.ctx.DrawImage(x, y, img2, dpm)
-	// TODO
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+	x, y := float64(rect.Min.X*mmPerPt), float64(rect.Min.Y*mmPerPt)
+	w, h := float64(rect.Size().X*mmPerPt), float64(rect.Size().Y*mmPerPt)
+	r.ctx.DrawImageTransformed(img, Identity.Translate(x, y).Scale(w/float64(size.X), h/float64(size.Y)))
 }
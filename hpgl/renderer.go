@@ -0,0 +1,225 @@
+package hpgl
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// HPGL is a renderer for pen plotters using the HP-GL language. Paths are collected per pen and written out when the renderer is closed, so that pen changes and pen-up travel can be minimized.
+type HPGL struct {
+	w             io.Writer
+	width, height float64
+	resolution    float64 // plotter units per mm
+	numPens       int
+	hatchAngle    float64
+	hatchSpacing  float64
+
+	pens      map[color.RGBA]int
+	polylines [][][]canvas.Point // per pen
+	warnings  []string
+}
+
+// New creates an HP-GL renderer for a pen plotter. Coordinates are written in plotter units of 0.025mm with the origin in the bottom-left corner. Only stroked paths are plotted, where each distinct stroke color is assigned to its own pen.
+func New(w io.Writer, width, height float64) *HPGL {
+	return &HPGL{
+		w:          w,
+		width:      width,
+		height:     height,
+		resolution: 40.0,
+		numPens:    8,
+		pens:       map[color.RGBA]int{},
+	}
+}
+
+// SetResolution sets the number of plotter units per millimeter, which is 40 by default.
+func (r *HPGL) SetResolution(resolution float64) {
+	r.resolution = resolution
+}
+
+// SetPens sets the number of pens of the plotter, which is 8 by default and at least 1. When there are more distinct colors than pens, pens are reused and a warning is added.
+func (r *HPGL) SetPens(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.numPens = n
+}
+
+// HatchFills enables filling paths by hatching them with lines at an angle (in degrees) and spacing (in mm) using the pen of the fill color, see Path.HatchFill. By default or when spacing is not positive, fills are skipped and a warning is added.
+func (r *HPGL) HatchFills(angle, spacing float64) {
+	r.hatchAngle = angle
+	r.hatchSpacing = spacing
+}
+
+// Warnings returns the unsupported features that were skipped while rendering, each reported once.
+func (r *HPGL) Warnings() []string {
+	return r.warnings
+}
+
+func (r *HPGL) warn(msg string) {
+	for _, warning := range r.warnings {
+		if warning == msg {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, msg)
+}
+
+// Close writes out all collected paths. Pens are selected in the order in which their colors first appeared, and for each pen the subpaths are reordered greedily to minimize pen-up travel, see optimize.
+func (r *HPGL) Close() error {
+	sb := strings.Builder{}
+	sb.WriteString("IN;PA;\n")
+	pos := [2]int{0, 0}
+	for i, polylines := range r.polylines {
+		if len(polylines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "SP%d;\n", i+1)
+		start := canvas.Point{float64(pos[0]) / r.resolution, float64(pos[1]) / r.resolution}
+		for _, polyline := range optimize(polylines, start) {
+			coords := r.plotterCoords(polyline)
+			if len(coords) < 2 {
+				continue
+			}
+			if coords[0] != pos {
+				fmt.Fprintf(&sb, "PU%d,%d;\n", coords[0][0], coords[0][1])
+			}
+			sb.WriteString("PD")
+			for j, coord := range coords[1:] {
+				if j != 0 {
+					sb.WriteString(",")
+				}
+				fmt.Fprintf(&sb, "%d,%d", coord[0], coord[1])
+			}
+			sb.WriteString(";\n")
+			pos = coords[len(coords)-1]
+		}
+	}
+	sb.WriteString("PU;SP0;\n")
+	_, err := io.WriteString(r.w, sb.String())
+	return err
+}
+
+// plotterCoords converts the coordinates to plotter units, removing consecutive duplicates.
+func (r *HPGL) plotterCoords(polyline []canvas.Point) [][2]int {
+	coords := make([][2]int, 0, len(polyline))
+	for _, p := range polyline {
+		coord := [2]int{int(math.Round(p.X * r.resolution)), int(math.Round(p.Y * r.resolution))}
+		if len(coords) == 0 || coords[len(coords)-1] != coord {
+			coords = append(coords, coord)
+		}
+	}
+	return coords
+}
+
+func (r *HPGL) Size() (float64, float64) {
+	return r.width, r.height
+}
+
+// pen returns the index of the pen for the given color, assigning new pens in order of appearance.
+func (r *HPGL) pen(col color.RGBA) int {
+	col.A = 255 // transparency is not supported
+	if pen, ok := r.pens[col]; ok {
+		return pen
+	}
+	pen := len(r.pens)
+	if r.numPens <= pen {
+		r.warn(fmt.Sprintf("more than %d colors, pens are reused", r.numPens))
+		pen %= r.numPens
+	}
+	r.pens[col] = pen
+	for len(r.polylines) <= pen {
+		r.polylines = append(r.polylines, nil)
+	}
+	return pen
+}
+
+func (r *HPGL) addPath(path *canvas.Path, col color.RGBA) {
+	pen := r.pen(col)
+	for _, ps := range path.Flatten().Split() {
+		if coords := ps.Coords(); 2 <= len(coords) {
+			r.polylines[pen] = append(r.polylines[pen], coords)
+		}
+	}
+}
+
+func (r *HPGL) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if path.Empty() {
+		return
+	}
+	path = path.Transform(m)
+
+	if style.FillColor.A != 0 {
+		if 0.0 < r.hatchSpacing {
+			r.addPath(path.HatchFill(r.hatchAngle, r.hatchSpacing), style.FillColor)
+		} else {
+			r.warn("fills are not supported and are skipped")
+		}
+	}
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		r.addPath(path, style.StrokeColor)
+	}
+}
+
+func (r *HPGL) RenderText(text *canvas.Text, m canvas.Matrix) {
+	text.RenderAsPath(r, m)
+}
+
+func (r *HPGL) RenderImage(img image.Image, m canvas.Matrix) {
+	r.warn("images are not supported and are skipped")
+}
+
+// optimize orders the polylines greedily by choosing the nearest polyline from the current position each time, where open polylines may be reversed to start at their nearest endpoint. Closed polylines are entered at their start point. Since the greedy order is not always better, the original order is returned when the greedy order doesn't have less pen-up travel.
+func optimize(polylines [][]canvas.Point, pos canvas.Point) [][]canvas.Point {
+	start := pos
+	done := make([]bool, len(polylines))
+	ordered := make([][]canvas.Point, 0, len(polylines))
+	for len(ordered) < len(polylines) {
+		best, bestDist, reverse := -1, math.Inf(1), false
+		for i, polyline := range polylines {
+			if done[i] {
+				continue
+			}
+			if dist := polyline[0].Sub(pos).Length(); dist < bestDist {
+				best, bestDist, reverse = i, dist, false
+			}
+			if dist := polyline[len(polyline)-1].Sub(pos).Length(); dist < bestDist {
+				best, bestDist, reverse = i, dist, true
+			}
+		}
+
+		polyline := polylines[best]
+		if reverse {
+			reversed := make([]canvas.Point, len(polyline))
+			for i, p := range polyline {
+				reversed[len(polyline)-1-i] = p
+			}
+			polyline = reversed
+		}
+		done[best] = true
+		ordered = append(ordered, polyline)
+		pos = polyline[len(polyline)-1]
+	}
+	if travel(polylines, start) <= travel(ordered, start) {
+		return polylines
+	}
+	return ordered
+}
+
+// travel returns the pen-up distance to plot the polylines in order, starting at the given position.
+func travel(polylines [][]canvas.Point, pos canvas.Point) float64 {
+	dist := 0.0
+	for _, polyline := range polylines {
+		dist += polyline[0].Sub(pos).Length()
+		pos = polyline[len(polyline)-1]
+	}
+	return dist
+}
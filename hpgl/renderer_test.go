@@ -0,0 +1,124 @@
+package hpgl
+
+import (
+	"bytes"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestHPGL(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M10 0L20 0"))
+	ctx.SetStrokeColor(canvas.Red)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 10L0 20L10 20z"))
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0L5 0"))
+	ctx.SetFillColor(canvas.Blue)
+	ctx.SetStrokeColor(canvas.Transparent)
+	ctx.DrawPath(50.0, 50.0, canvas.Rectangle(10.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	hpgl := New(buf, c.W, c.H)
+	c.Render(hpgl)
+	test.Error(t, hpgl.Close())
+	test.String(t, buf.String(), "IN;PA;\nSP1;\nPD200,0;\nPU400,0;\nPD800,0;\nSP2;\nPU0,400;\nPD0,800,400,800,0,400;\nPU;SP0;\n")
+	test.T(t, hpgl.Warnings(), []string{"fills are not supported and are skipped"})
+}
+
+func TestHPGLHatchFills(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 3.0))
+
+	buf := &bytes.Buffer{}
+	hpgl := New(buf, c.W, c.H)
+	hpgl.HatchFills(0.0, 1.0)
+	c.Render(hpgl)
+	test.Error(t, hpgl.Close())
+	test.String(t, buf.String(), "IN;PA;\nSP1;\nPU0,40;\nPD400,40;\nPU400,80;\nPD0,80;\nPU;SP0;\n")
+	test.T(t, len(hpgl.Warnings()), 0)
+}
+
+func TestHPGLPens(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Transparent)
+	for _, col := range []color.RGBA{canvas.Red, canvas.Green, canvas.Blue} {
+		ctx.SetStrokeColor(col)
+		ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0L1 0"))
+	}
+
+	buf := &bytes.Buffer{}
+	hpgl := New(buf, c.W, c.H)
+	hpgl.SetPens(2)
+	c.Render(hpgl)
+	test.Error(t, hpgl.Close())
+	test.String(t, buf.String(), "IN;PA;\nSP1;\nPD40,0;\nPD0,0;\nSP2;\nPD40,0;\nPU;SP0;\n")
+	test.T(t, hpgl.Warnings(), []string{"more than 2 colors, pens are reused"})
+
+	// at least one pen is used
+	for _, n := range []int{0, -1} {
+		buf.Reset()
+		hpgl = New(buf, c.W, c.H)
+		hpgl.SetPens(n)
+		c.Render(hpgl)
+		test.Error(t, hpgl.Close())
+		test.String(t, buf.String(), "IN;PA;\nSP1;\nPD40,0;\nPD0,0;\nPD40,0;\nPU;SP0;\n")
+		test.T(t, hpgl.Warnings(), []string{"more than 1 colors, pens are reused"})
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	polylines := [][]canvas.Point{}
+	for i := 0; i < 200; i++ {
+		start := canvas.Point{rng.Float64() * 100.0, rng.Float64() * 100.0}
+		end := start.Add(canvas.Point{rng.Float64()*10.0 - 5.0, rng.Float64()*10.0 - 5.0})
+		polylines = append(polylines, []canvas.Point{start, end})
+	}
+
+	ordered := optimize(polylines, canvas.Point{})
+	test.T(t, len(ordered), len(polylines))
+	test.That(t, travel(ordered, canvas.Point{}) <= travel(polylines, canvas.Point{}))
+	test.That(t, travel(ordered, canvas.Point{}) < travel(polylines, canvas.Point{})/2.0)
+
+	// every segment is plotted exactly once, possibly reversed
+	seen := map[[2]canvas.Point]int{}
+	for _, polyline := range polylines {
+		seen[[2]canvas.Point{polyline[0], polyline[1]}]++
+	}
+	for _, polyline := range ordered {
+		if _, ok := seen[[2]canvas.Point{polyline[0], polyline[1]}]; ok {
+			seen[[2]canvas.Point{polyline[0], polyline[1]}]--
+		} else {
+			seen[[2]canvas.Point{polyline[1], polyline[0]}]--
+		}
+	}
+	for _, n := range seen {
+		test.T(t, n, 0)
+	}
+
+	// the greedy order has more travel here, since it leaves the point on the other side behind
+	polylines = [][]canvas.Point{{{-1.5, 0.0}, {-1.5, 0.0}}, {{1.0, 0.0}, {1.0, 0.0}}, {{3.0, 0.0}, {3.0, 0.0}}}
+	test.T(t, optimize(polylines, canvas.Point{}), polylines)
+
+	// the travel never increases
+	for seed := int64(0); seed < 100; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		polylines := [][]canvas.Point{}
+		for i := 0; i < 1+rng.Intn(8); i++ {
+			start := canvas.Point{rng.Float64() * 100.0, rng.Float64() * 100.0}
+			end := start.Add(canvas.Point{rng.Float64()*50.0 - 25.0, rng.Float64()*50.0 - 25.0})
+			polylines = append(polylines, []canvas.Point{start, end})
+		}
+		test.That(t, travel(optimize(polylines, canvas.Point{}), canvas.Point{}) <= travel(polylines, canvas.Point{}), seed)
+	}
+}
@@ -0,0 +1,14 @@
+package hpgl
+
+import (
+	"io"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Writer writes the canvas as an HP-GL file for pen plotters. Only strokes are plotted and fills are skipped, use New and HatchFills to hatch fills instead.
+func Writer(w io.Writer, c *canvas.Canvas) error {
+	hpgl := New(w, c.W, c.H)
+	c.Render(hpgl)
+	return hpgl.Close()
+}
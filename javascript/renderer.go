@@ -0,0 +1,238 @@
+package javascript
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tdewolff/canvas"
+)
+
+// JavaScript is a renderer that writes JavaScript code drawing onto a CanvasRenderingContext2D named ctx, so that a canvas can be replayed in the browser.
+type JavaScript struct {
+	w             io.Writer
+	width, height float64
+	resolution    canvas.DPMM
+	function      string
+	textAsPaths   bool
+
+	begun bool
+	style canvas.Style
+}
+
+// New creates a JavaScript renderer for the HTML5 canvas. The output starts with a transformation that flips the y-axis and scales millimeters to pixels at 96 DPI, so that all coordinates are written in millimeters.
+func New(w io.Writer, width, height float64) *JavaScript {
+	return &JavaScript{
+		w:          w,
+		width:      width,
+		height:     height,
+		resolution: 96.0 * canvas.DPI,
+		style:      jsDefaultStyle,
+	}
+}
+
+// jsDefaultStyle is the initial state of an HTML canvas context, which has a miter limit of 10.
+var jsDefaultStyle = func() canvas.Style {
+	style := canvas.DefaultStyle
+	style.StrokeJoiner = canvas.MiterClipJoin(canvas.BevelJoin, 10.0)
+	return style
+}()
+
+// SetResolution sets the resolution of the HTML canvas, which is 96 DPI by default (ie. CSS pixels). It must be called before rendering.
+func (r *JavaScript) SetResolution(resolution canvas.DPMM) {
+	r.resolution = resolution
+}
+
+// WrapFunction wraps the output in a function declaration with the given name taking a CanvasRenderingContext2D as its only argument. By default the output expects a variable named ctx in scope. It must be called before rendering.
+func (r *JavaScript) WrapFunction(name string) {
+	r.function = name
+}
+
+// TextAsPaths draws text as glyph outlines instead of using fillText, so that the output does not depend on the fonts that are available in the browser.
+func (r *JavaScript) TextAsPaths(textAsPaths bool) {
+	r.textAsPaths = textAsPaths
+}
+
+// begin writes the function declaration if requested and the initial transformation.
+func (r *JavaScript) begin() {
+	if !r.begun {
+		if r.function != "" {
+			fmt.Fprintf(r.w, "function %s(ctx) {\n", r.function)
+		}
+		s := float64(r.resolution)
+		fmt.Fprintf(r.w, "ctx.save();\nctx.transform(%v,0,0,%v,0,%v);\n", dec(s), dec(-s), dec(r.height*s))
+		r.begun = true
+	}
+}
+
+func (r *JavaScript) Close() error {
+	r.begin()
+	fmt.Fprintf(r.w, "ctx.restore();\n")
+	if r.function != "" {
+		fmt.Fprintf(r.w, "}\n")
+	}
+	return nil
+}
+
+func (r *JavaScript) Size() (float64, float64) {
+	return r.width, r.height
+}
+
+func (r *JavaScript) writePath(path *canvas.Path) {
+	fmt.Fprintf(r.w, "ctx.beginPath();\n")
+	path.Iterate(func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, "ctx.moveTo(%v,%v);\n", dec(end.X), dec(end.Y))
+	}, func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, "ctx.lineTo(%v,%v);\n", dec(end.X), dec(end.Y))
+	}, func(start, cp, end canvas.Point) {
+		fmt.Fprintf(r.w, "ctx.quadraticCurveTo(%v,%v,%v,%v);\n", dec(cp.X), dec(cp.Y), dec(end.X), dec(end.Y))
+	}, func(start, cp1, cp2, end canvas.Point) {
+		fmt.Fprintf(r.w, "ctx.bezierCurveTo(%v,%v,%v,%v,%v,%v);\n", dec(cp1.X), dec(cp1.Y), dec(cp2.X), dec(cp2.Y), dec(end.X), dec(end.Y))
+	}, func(start canvas.Point, rx, ry, rot float64, large, sweep bool, end canvas.Point) {
+		cx, cy, theta0, theta1 := canvas.EllipseToCenter(start.X, start.Y, rx, ry, rot, large, sweep, end.X, end.Y)
+		rot, theta0, theta1 = rot*math.Pi/180.0, theta0*math.Pi/180.0, theta1*math.Pi/180.0
+		fmt.Fprintf(r.w, "ctx.ellipse(%v,%v,%v,%v,%v,%v,%v,%v);\n", dec(cx), dec(cy), dec(rx), dec(ry), dec(rot), dec(theta0), dec(theta1), !sweep)
+	}, func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, "ctx.closePath();\n")
+	})
+}
+
+func (r *JavaScript) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	fill := style.FillColor.A != 0
+	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	if path.Empty() || !fill && !stroke {
+		return
+	}
+	r.begin()
+	path = path.Transform(m)
+
+	strokeUnsupported := false
+	if _, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok {
+		strokeUnsupported = true
+	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+		if math.IsNaN(miter.Limit) {
+			strokeUnsupported = true
+		} else if _, ok := miter.GapJoiner.(canvas.BevelJoiner); !ok {
+			strokeUnsupported = true
+		}
+	}
+
+	r.writePath(path)
+	if fill {
+		if style.FillColor != r.style.FillColor {
+			fmt.Fprintf(r.w, "ctx.fillStyle=%s;\n", strconv.Quote(canvas.CSSColor(style.FillColor).String()))
+			r.style.FillColor = style.FillColor
+		}
+		if style.FillRule == canvas.EvenOdd {
+			fmt.Fprintf(r.w, "ctx.fill(\"evenodd\");\n")
+		} else {
+			fmt.Fprintf(r.w, "ctx.fill();\n")
+		}
+	}
+	if stroke && !strokeUnsupported {
+		if style.StrokeCapper != r.style.StrokeCapper {
+			if _, ok := style.StrokeCapper.(canvas.RoundCapper); ok {
+				fmt.Fprintf(r.w, "ctx.lineCap=\"round\";\n")
+			} else if _, ok := style.StrokeCapper.(canvas.SquareCapper); ok {
+				fmt.Fprintf(r.w, "ctx.lineCap=\"square\";\n")
+			} else if _, ok := style.StrokeCapper.(canvas.ButtCapper); ok {
+				fmt.Fprintf(r.w, "ctx.lineCap=\"butt\";\n")
+			} else {
+				panic("JavaScript: line cap not support")
+			}
+			r.style.StrokeCapper = style.StrokeCapper
+		}
+
+		if style.StrokeJoiner != r.style.StrokeJoiner {
+			if _, ok := style.StrokeJoiner.(canvas.BevelJoiner); ok {
+				fmt.Fprintf(r.w, "ctx.lineJoin=\"bevel\";\n")
+			} else if _, ok := style.StrokeJoiner.(canvas.RoundJoiner); ok {
+				fmt.Fprintf(r.w, "ctx.lineJoin=\"round\";\n")
+			} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+				fmt.Fprintf(r.w, "ctx.lineJoin=\"miter\";\n")
+				fmt.Fprintf(r.w, "ctx.miterLimit=%v;\n", dec(miter.Limit))
+			} else {
+				panic("JavaScript: line join not support")
+			}
+			r.style.StrokeJoiner = style.StrokeJoiner
+		}
+
+		if !float64sEqual(style.Dashes, r.style.Dashes) {
+			fmt.Fprintf(r.w, "ctx.setLineDash([")
+			for i, dash := range style.Dashes {
+				if i != 0 {
+					fmt.Fprintf(r.w, ",")
+				}
+				fmt.Fprintf(r.w, "%v", dec(dash))
+			}
+			fmt.Fprintf(r.w, "]);\n")
+			r.style.Dashes = style.Dashes
+		}
+		if style.DashOffset != r.style.DashOffset {
+			fmt.Fprintf(r.w, "ctx.lineDashOffset=%v;\n", dec(style.DashOffset))
+			r.style.DashOffset = style.DashOffset
+		}
+		if style.StrokeWidth != r.style.StrokeWidth {
+			fmt.Fprintf(r.w, "ctx.lineWidth=%v;\n", dec(style.StrokeWidth))
+			r.style.StrokeWidth = style.StrokeWidth
+		}
+		if style.StrokeColor != r.style.StrokeColor {
+			fmt.Fprintf(r.w, "ctx.strokeStyle=%s;\n", strconv.Quote(canvas.CSSColor(style.StrokeColor).String()))
+			r.style.StrokeColor = style.StrokeColor
+		}
+		fmt.Fprintf(r.w, "ctx.stroke();\n")
+	}
+
+	if stroke && strokeUnsupported {
+		// stroke settings unsupported by the HTML canvas, draw stroke explicitly
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		r.writePath(path)
+		if style.StrokeColor != r.style.FillColor {
+			fmt.Fprintf(r.w, "ctx.fillStyle=%s;\n", strconv.Quote(canvas.CSSColor(style.StrokeColor).String()))
+			r.style.FillColor = style.StrokeColor
+		}
+		fmt.Fprintf(r.w, "ctx.fill();\n")
+	}
+}
+
+// RenderText draws each text span using fillText with a CSS font string, or as glyph outlines when TextAsPaths is set. The browser must have the fonts available under their family names.
+func (r *JavaScript) RenderText(text *canvas.Text, m canvas.Matrix) {
+	if r.textAsPaths {
+		text.RenderAsPath(r, m)
+		return
+	}
+	r.begin()
+
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		ff := span.Face
+		font := ""
+		if ff.Style&canvas.FontItalic != 0 {
+			font += "italic "
+		}
+		if ff.Variant&canvas.FontSmallcaps != 0 {
+			font += "small-caps "
+		}
+		if boldness := ff.Boldness(); boldness != 400 {
+			font += fmt.Sprintf("%d ", boldness)
+		}
+		font += fmt.Sprintf("%vpx '%s'", dec(ff.Size*ff.Scale), ff.Name())
+
+		// flip the y-axis back so that the text is upright
+		mText := m.Translate(dx, y+ff.Voffset).ReflectY()
+		fmt.Fprintf(r.w, "ctx.save();\nctx.transform(%v,%v,%v,%v,%v,%v);\n", dec(mText[0][0]), dec(mText[1][0]), dec(mText[0][1]), dec(mText[1][1]), dec(mText[0][2]), dec(mText[1][2]))
+		fmt.Fprintf(r.w, "ctx.font=%s;\n", strconv.Quote(font))
+		fmt.Fprintf(r.w, "ctx.fillStyle=%s;\n", strconv.Quote(canvas.CSSColor(ff.Color).String()))
+		fmt.Fprintf(r.w, "ctx.fillText(%s,0,0);\n", strconv.Quote(span.Text))
+		fmt.Fprintf(r.w, "ctx.restore();\n")
+	})
+	text.RenderDecoration(r, m)
+}
+
+func (r *JavaScript) RenderImage(img image.Image, m canvas.Matrix) {
+	// TODO: (JavaScript) write image
+}
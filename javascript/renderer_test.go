@@ -0,0 +1,85 @@
+package javascript
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestJavaScript(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0H2V2H0zM0.5 0.5V1.5H1.5V0.5z"))
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Blue)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetStrokeCapper(canvas.RoundCapper{})
+	ctx.SetDashes(1.0, 2.0, 3.0)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M3 3Q4 4 5 3C6 2 7 4 8 3A1 1 0 0 1 9 4"))
+
+	buf := &bytes.Buffer{}
+	js := New(buf, c.W, c.H)
+	js.SetResolution(10.0)
+	js.WrapFunction("draw")
+	c.Render(js)
+	test.Error(t, js.Close())
+	test.String(t, buf.String(), `function draw(ctx) {
+ctx.save();
+ctx.transform(10,0,0,-10,0,100);
+ctx.beginPath();
+ctx.moveTo(0,0);
+ctx.lineTo(2,0);
+ctx.lineTo(2,2);
+ctx.lineTo(0,2);
+ctx.closePath();
+ctx.moveTo(.5,.5);
+ctx.lineTo(.5,1.5);
+ctx.lineTo(1.5,1.5);
+ctx.lineTo(1.5,.5);
+ctx.closePath();
+ctx.fillStyle="#f00";
+ctx.fill();
+ctx.beginPath();
+ctx.moveTo(3,3);
+ctx.quadraticCurveTo(4,4,5,3);
+ctx.bezierCurveTo(6,2,7,4,8,3);
+ctx.ellipse(8,4,1,1,0,4.712389,6.2831853,false);
+ctx.lineCap="round";
+ctx.lineJoin="miter";
+ctx.miterLimit=2;
+ctx.setLineDash([2,3]);
+ctx.lineDashOffset=1;
+ctx.lineWidth=.5;
+ctx.strokeStyle="#00f";
+ctx.stroke();
+ctx.restore();
+}
+`)
+}
+
+func TestJavaScriptText(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontItalic, canvas.FontNormal)
+	ctx.DrawText(2.0, 5.0, canvas.NewTextLine(face, "a\"b", canvas.Left))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.That(t, strings.Contains(buf.String(), `ctx.font="italic 4.2333333px 'dejavu-serif'";`), buf.String())
+	test.That(t, strings.Contains(buf.String(), `ctx.fillText("a\"b",0,0);`), buf.String())
+
+	buf.Reset()
+	js := New(buf, c.W, c.H)
+	js.TextAsPaths(true)
+	c.Render(js)
+	test.Error(t, js.Close())
+	test.That(t, !strings.Contains(buf.String(), "fillText"), buf.String())
+	test.That(t, strings.Contains(buf.String(), "ctx.fill();"), buf.String())
+}
@@ -0,0 +1,14 @@
+package javascript
+
+import (
+	"io"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Writer writes the canvas as JavaScript code that draws onto a CanvasRenderingContext2D named ctx. The HTML canvas element should be sized at 96 DPI, ie. c.W*96/25.4 by c.H*96/25.4 pixels.
+func Writer(w io.Writer, c *canvas.Canvas) error {
+	js := New(w, c.W, c.H)
+	c.Render(js)
+	return js.Close()
+}
@@ -0,0 +1,90 @@
+package canvas
+
+import "fmt"
+
+// Length is a physical length in millimeters, which is the unit used throughout the library. Lengths in other units are created by Mm, Cm, Pt, In and Px, and converted back by the methods of the same name, so that the conversion factors need not be remembered. For example, New(In(8.5).Mm(), In(11.0).Mm()) creates a canvas of US Letter size, and SetStrokeWidth(Pt(0.5).Mm()) sets a stroke width of half a point.
+type Length float64
+
+// Mm returns a length in millimeters.
+func Mm(mm float64) Length {
+	return Length(mm)
+}
+
+// Cm returns a length in centimeters.
+func Cm(cm float64) Length {
+	return Length(cm * 10.0)
+}
+
+// Pt returns a length in points (1/72 inch), which is the unit of font sizes.
+func Pt(pt float64) Length {
+	return Length(pt * mmPerPt)
+}
+
+// In returns a length in inches.
+func In(in float64) Length {
+	return Length(in * mmPerInch)
+}
+
+// Px returns a length in pixels at the given resolution, such as Px(100.0, 96.0*DPI).
+func Px(px float64, resolution DPMM) Length {
+	return Length(px / float64(resolution))
+}
+
+// Mm returns the length in millimeters.
+func (l Length) Mm() float64 {
+	return float64(l)
+}
+
+// Cm returns the length in centimeters.
+func (l Length) Cm() float64 {
+	return float64(l) / 10.0
+}
+
+// Pt returns the length in points (1/72 inch), which is the unit of font sizes.
+func (l Length) Pt() float64 {
+	return float64(l) / mmPerPt
+}
+
+// In returns the length in inches.
+func (l Length) In() float64 {
+	return float64(l) / mmPerInch
+}
+
+// Px returns the length in pixels at the given resolution, such as 96.0*DPI.
+func (l Length) Px(resolution DPMM) float64 {
+	return float64(l) * float64(resolution)
+}
+
+func (l Length) String() string {
+	return fmt.Sprintf("%gmm", float64(l))
+}
+
+// NewLength returns a new Canvas like New with its size given as lengths in any unit, such as NewLength(In(8.5), In(11.0)).
+func NewLength(width, height Length) *Canvas {
+	return New(width.Mm(), height.Mm())
+}
+
+// SizeLength returns the size of the canvas as lengths, see also SizeCm, SizePt, SizeIn and SizePx.
+func (c *Canvas) SizeLength() (Length, Length) {
+	return Length(c.W), Length(c.H)
+}
+
+// SizeCm returns the size of the canvas in centimeters.
+func (c *Canvas) SizeCm() (float64, float64) {
+	return Length(c.W).Cm(), Length(c.H).Cm()
+}
+
+// SizePt returns the size of the canvas in points (1/72 inch).
+func (c *Canvas) SizePt() (float64, float64) {
+	return Length(c.W).Pt(), Length(c.H).Pt()
+}
+
+// SizeIn returns the size of the canvas in inches.
+func (c *Canvas) SizeIn() (float64, float64) {
+	return Length(c.W).In(), Length(c.H).In()
+}
+
+// SizePx returns the size of the canvas in pixels at the given resolution, which is the size of the image of the rasterizer.
+func (c *Canvas) SizePx(resolution DPMM) (int, int) {
+	return int(Length(c.W).Px(resolution) + 0.5), int(Length(c.H).Px(resolution) + 0.5)
+}
@@ -0,0 +1,53 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestLength(t *testing.T) {
+	test.Float(t, In(1.0).Mm(), 25.4)
+	test.Float(t, Cm(2.54).In(), 1.0)
+	test.Float(t, Pt(72.0).In(), 1.0)
+	test.Float(t, Mm(25.4).Pt(), 72.0)
+	test.Float(t, Px(96.0, 96.0*DPI).In(), 1.0)
+	test.Float(t, In(1.0).Px(300.0*DPI), 300.0)
+	test.String(t, Cm(1.5).String(), "15mm")
+
+	c := NewLength(In(8.5), In(11.0))
+	test.Float(t, c.W, 215.9)
+	w, h := c.SizeIn()
+	test.Float(t, w, 8.5)
+	test.Float(t, h, 11.0)
+	w, h = c.SizePt()
+	test.Float(t, w, 612.0)
+	test.Float(t, h, 792.0)
+	w, h = c.SizeCm()
+	test.Float(t, w, 21.59)
+	lw, lh := c.SizeLength()
+	test.T(t, lw, In(8.5))
+	test.T(t, lh, In(11.0))
+
+	// a canvas of an inch is exactly as many pixels wide as the DPI
+	pw, ph := NewLength(Mm(25.4), Mm(12.7)).SizePx(96.0 * DPI)
+	test.T(t, pw, 96)
+	test.T(t, ph, 48)
+}
+
+func TestLengthFontSize(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular))
+
+	// the glyph height of 12pt is the same regardless of the unit it is given in
+	height := func(size Length) float64 {
+		p, _ := family.Face(size.Pt(), Black, FontRegular, FontNormal).ToPath("H")
+		return p.Bounds().H
+	}
+	expected := height(Pt(12.0))
+	test.That(t, 3.0 < expected && expected < 3.2, expected) // cap height of about 0.73em
+	test.Float(t, height(In(1.0/6.0)), expected)
+	test.Float(t, height(Mm(25.4/6.0)), expected)
+	test.Float(t, height(Cm(2.54/6.0)), expected)
+	test.Float(t, height(Px(16.0, 96.0*DPI)), expected)
+}
@@ -0,0 +1,582 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Paint is a fill whose color varies over a shape, such as LinearGradient. Its coordinates are those of the path being filled, so that it is transformed along with the path. At returns the color at a point, and Color returns a single representative color that is used by renderers that do not support the paint.
+type Paint interface {
+	At(x, y float64) color.RGBA
+	Color() color.RGBA
+}
+
+// Stop is a color stop of a gradient at an offset between 0 and 1.
+type Stop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// Spread specifies how a gradient continues beyond its first and last stops.
+type Spread int
+
+// see Spread
+const (
+	PadSpread     Spread = iota // extend the colors of the first and last stops
+	RepeatSpread                // repeat the gradient
+	ReflectSpread               // repeat the gradient while mirroring every other repetition
+)
+
+func (spread Spread) String() string {
+	switch spread {
+	case PadSpread:
+		return "pad"
+	case RepeatSpread:
+		return "repeat"
+	case ReflectSpread:
+		return "reflect"
+	}
+	return fmt.Sprintf("Spread(%d)", int(spread))
+}
+
+// apply maps a position along a gradient to the range [0,1].
+func (spread Spread) apply(t float64) float64 {
+	switch spread {
+	case RepeatSpread:
+		return t - math.Floor(t)
+	case ReflectSpread:
+		t = math.Mod(math.Abs(t), 2.0)
+		if 1.0 < t {
+			t = 2.0 - t
+		}
+		return t
+	}
+	return math.Max(0.0, math.Min(1.0, t))
+}
+
+// NewStops returns a copy of the stops sorted by offset, with the offsets clamped between 0 and 1. It returns an error for fewer than two stops.
+func NewStops(stops ...Stop) ([]Stop, error) {
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("bad gradient: at least two stops are required")
+	}
+	stops = append([]Stop{}, stops...)
+	for i := range stops {
+		stops[i].Offset = math.Max(0.0, math.Min(1.0, stops[i].Offset))
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		return stops[i].Offset < stops[j].Offset
+	})
+	return stops, nil
+}
+
+// stopsColor returns the color at offset t of the sorted stops, which is interpolated linearly between the premultiplied colors of the surrounding stops.
+func stopsColor(stops []Stop, t float64) color.RGBA {
+	if len(stops) == 0 {
+		return Transparent
+	} else if t <= stops[0].Offset {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if t < stops[i].Offset {
+			a, b := stops[i-1], stops[i]
+			f := (t - a.Offset) / (b.Offset - a.Offset)
+			lerp := func(x, y uint8) uint8 {
+				return uint8(float64(x) + f*(float64(y)-float64(x)) + 0.5)
+			}
+			return color.RGBA{lerp(a.Color.R, b.Color.R), lerp(a.Color.G, b.Color.G), lerp(a.Color.B, b.Color.B), lerp(a.Color.A, b.Color.A)}
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// LinearGradient is a paint that changes color along the line from (X0,Y0) to (X1,Y1), and is constant perpendicular to it. The stops must be sorted by offset, see NewLinearGradient.
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float64
+	Stops          []Stop
+	Spread
+}
+
+// NewLinearGradient returns a linear gradient from (x0,y0) to (x1,y1) with the given stops, which are sorted and clamped by NewStops. It returns an error for fewer than two stops.
+func NewLinearGradient(x0, y0, x1, y1 float64, spread Spread, stops ...Stop) (LinearGradient, error) {
+	stops, err := NewStops(stops...)
+	if err != nil {
+		return LinearGradient{}, err
+	}
+	return LinearGradient{x0, y0, x1, y1, stops, spread}, nil
+}
+
+// Offset returns the position of (x,y) along the gradient, which is 0 at (X0,Y0) and 1 at (X1,Y1), before applying the spread.
+func (g LinearGradient) Offset(x, y float64) float64 {
+	dx, dy := g.X1-g.X0, g.Y1-g.Y0
+	if d := dx*dx + dy*dy; d != 0.0 {
+		return ((x-g.X0)*dx + (y-g.Y0)*dy) / d
+	}
+	return 0.0
+}
+
+// At returns the color at (x,y).
+func (g LinearGradient) At(x, y float64) color.RGBA {
+	return stopsColor(g.Stops, g.Spread.apply(g.Offset(x, y)))
+}
+
+// Color returns the color halfway the gradient.
+func (g LinearGradient) Color() color.RGBA {
+	return stopsColor(g.Stops, 0.5)
+}
+
+// RadialGradient is a paint that changes color from the focal point (FX,FY) to the circle with center (CX,CY) and radius R, following the definition of radial gradients in SVG and PDF. The color at a point is that of the largest circle, interpolated between the focal point and the outer circle, that passes through it. The focal point should lie within the outer circle. The stops must be sorted by offset, see NewRadialGradient.
+type RadialGradient struct {
+	CX, CY, R, FX, FY float64
+	Stops             []Stop
+	Spread
+}
+
+// NewRadialGradient returns a radial gradient for the circle with center (cx,cy) and radius r, and with focal point (fx,fy), with the given stops, which are sorted and clamped by NewStops. It returns an error for fewer than two stops or a negative radius.
+func NewRadialGradient(cx, cy, r, fx, fy float64, spread Spread, stops ...Stop) (RadialGradient, error) {
+	if r < 0.0 {
+		return RadialGradient{}, fmt.Errorf("bad gradient: negative radius")
+	}
+	stops, err := NewStops(stops...)
+	if err != nil {
+		return RadialGradient{}, err
+	}
+	return RadialGradient{cx, cy, r, fx, fy, stops, spread}, nil
+}
+
+// Offset returns the position of (x,y) along the gradient, which is 0 at the focal point and 1 on the outer circle, before applying the spread. It returns false if no circle passes through the point, which happens only when the focal point lies outside the outer circle.
+func (g RadialGradient) Offset(x, y float64) (float64, bool) {
+	// solve |q - t*d| = t*R for the largest t with a non-negative radius
+	dx, dy := g.CX-g.FX, g.CY-g.FY
+	qx, qy := x-g.FX, y-g.FY
+	a := dx*dx + dy*dy - g.R*g.R
+	b := qx*dx + qy*dy
+	c := qx*qx + qy*qy
+	if Equal(a, 0.0) {
+		if b <= 0.0 {
+			return 0.0, false
+		}
+		return c / (2.0 * b), true
+	}
+	disc := b*b - a*c
+	if disc < 0.0 {
+		return 0.0, false
+	}
+	t0, t1 := (b-math.Sqrt(disc))/a, (b+math.Sqrt(disc))/a
+	if t1 < t0 {
+		t0, t1 = t1, t0
+	}
+	if 0.0 <= t1 {
+		return t1, true
+	}
+	return 0.0, false
+}
+
+// At returns the color at (x,y). A gradient with a zero radius has the color of the last stop.
+func (g RadialGradient) At(x, y float64) color.RGBA {
+	if g.R == 0.0 {
+		if len(g.Stops) == 0 {
+			return Transparent
+		}
+		return g.Stops[len(g.Stops)-1].Color
+	}
+	t, ok := g.Offset(x, y)
+	if !ok {
+		return Transparent
+	}
+	return stopsColor(g.Stops, g.Spread.apply(t))
+}
+
+// Color returns the color halfway the gradient.
+func (g RadialGradient) Color() color.RGBA {
+	if g.R == 0.0 && 0 < len(g.Stops) {
+		return g.Stops[len(g.Stops)-1].Color
+	}
+	return stopsColor(g.Stops, 0.5)
+}
+
+// AlongPathGradient is a stroke paint that changes color along the length of the stroked path, where offset 0 is the start and 1 is the end of the path, counting all its subpaths. It is drawn by Context, which splits the path into pieces of about constant color, see SetStrokePaint. The stops must be sorted by offset, see NewAlongPathGradient.
+type AlongPathGradient struct {
+	Stops []Stop
+}
+
+// NewAlongPathGradient returns a gradient along the stroked path with the given stops, which are sorted and clamped by NewStops. It returns an error for fewer than two stops.
+func NewAlongPathGradient(stops ...Stop) (AlongPathGradient, error) {
+	stops, err := NewStops(stops...)
+	if err != nil {
+		return AlongPathGradient{}, err
+	}
+	return AlongPathGradient{stops}, nil
+}
+
+// At returns the color halfway the gradient, as the color does not depend on the position in space.
+func (g AlongPathGradient) At(x, y float64) color.RGBA {
+	return g.Color()
+}
+
+// Color returns the color halfway the gradient.
+func (g AlongPathGradient) Color() color.RGBA {
+	return stopsColor(g.Stops, 0.5)
+}
+
+// alongPathTolerance is the maximum difference of a color component between neighbouring pieces of an AlongPathGradient.
+const alongPathTolerance = 4.0
+
+// alongPathPiece is a piece of a path with the color of an AlongPathGradient, where offset is the distance from the start of its subpath to the start of the piece. First and last are set for the ends of open subpaths, and next is set when the next piece continues the same subpath.
+type alongPathPiece struct {
+	path              *Path
+	col               color.RGBA
+	offset            float64
+	first, last, next bool
+}
+
+// pieces splits the path at the stops and in between them so that the color changes by at most alongPathTolerance between pieces. Each piece has the color at its middle.
+func (g AlongPathGradient) pieces(p *Path) []alongPathPiece {
+	length := p.Length()
+	if len(g.Stops) == 0 || length == 0.0 {
+		return nil
+	}
+
+	// split positions in [0,1] along the path
+	ts := []float64{0.0}
+	for i := 1; i < len(g.Stops); i++ {
+		a, b := g.Stops[i-1], g.Stops[i]
+		if b.Offset <= a.Offset {
+			continue
+		}
+		delta := math.Max(math.Max(math.Abs(float64(b.Color.R)-float64(a.Color.R)), math.Abs(float64(b.Color.G)-float64(a.Color.G))), math.Max(math.Abs(float64(b.Color.B)-float64(a.Color.B)), math.Abs(float64(b.Color.A)-float64(a.Color.A))))
+		n := math.Max(1.0, math.Ceil(delta/alongPathTolerance))
+		for k := 0.0; k <= n; k++ {
+			if t := a.Offset + k/n*(b.Offset-a.Offset); ts[len(ts)-1] < t {
+				ts = append(ts, t)
+			}
+		}
+	}
+	if ts[len(ts)-1] < 1.0 {
+		ts = append(ts, 1.0)
+	}
+
+	pieces := []alongPathPiece{}
+	start := 0.0 // distance from the start of the path to the current subpath
+	for _, sub := range p.Split() {
+		subLength := sub.Length()
+		splits := []float64{}
+		for _, t := range ts {
+			if d := t*length - start; Epsilon < d && d < subLength-Epsilon {
+				splits = append(splits, d)
+			}
+		}
+
+		closed := sub.Closed()
+		n := len(pieces)
+		offset := 0.0
+		for _, q := range sub.SplitAt(splits...) {
+			qLength := q.Length()
+			if qLength == 0.0 {
+				continue
+			}
+			mid := (start + offset + qLength/2.0) / length
+			i := sort.SearchFloat64s(ts, mid)
+			if 0 < i && i < len(ts) {
+				mid = (ts[i-1] + ts[i]) / 2.0
+			}
+			pieces = append(pieces, alongPathPiece{q, stopsColor(g.Stops, mid), offset, false, false, true})
+			offset += qLength
+		}
+		if n < len(pieces) {
+			pieces[len(pieces)-1].next = false
+			if !closed {
+				pieces[n].first = true
+				pieces[len(pieces)-1].last = true
+			}
+		}
+		start += subLength
+	}
+	return pieces
+}
+
+// RepeatMode specifies along which axes an ImagePattern repeats its image.
+type RepeatMode int
+
+// see RepeatMode
+const (
+	RepeatXY RepeatMode = iota // repeat horizontally and vertically
+	RepeatX                    // repeat horizontally only
+	RepeatY                    // repeat vertically only
+	NoRepeat                   // draw the image once
+)
+
+func (mode RepeatMode) String() string {
+	switch mode {
+	case RepeatXY:
+		return "repeat"
+	case RepeatX:
+		return "repeat-x"
+	case RepeatY:
+		return "repeat-y"
+	case NoRepeat:
+		return "no-repeat"
+	}
+	return fmt.Sprintf("RepeatMode(%d)", int(mode))
+}
+
+// RepeatsX returns true if the image repeats horizontally.
+func (mode RepeatMode) RepeatsX() bool {
+	return mode == RepeatXY || mode == RepeatX
+}
+
+// RepeatsY returns true if the image repeats vertically.
+func (mode RepeatMode) RepeatsY() bool {
+	return mode == RepeatXY || mode == RepeatY
+}
+
+// ImagePattern is a paint that tiles an image. The image occupies the rectangle from (0,0) to its size in pixels with its top row at the top, like for DrawImage, and M maps it to the coordinates of the path. Outside the image the pattern is transparent along the axes that do not repeat.
+type ImagePattern struct {
+	Img    image.Image
+	M      Matrix
+	Repeat RepeatMode
+}
+
+// At returns the color at (x,y), which is sampled bilinearly from the image.
+func (p ImagePattern) At(x, y float64) color.RGBA {
+	bounds := p.Img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return Transparent
+	}
+	pos := p.M.Inv().Dot(Point{x, y})
+	u, v := pos.X, float64(h)-pos.Y // from the top-left of the image
+	if !p.Repeat.RepeatsX() && (u < 0.0 || float64(w) <= u) || !p.Repeat.RepeatsY() && (v < 0.0 || float64(h) <= v) {
+		return Transparent
+	}
+
+	// indices of the pixels around the point, which wrap around or are clamped at the edges
+	index := func(f float64, n int, repeat bool) (int, int, float64) {
+		i := math.Floor(f - 0.5)
+		t := f - 0.5 - i
+		i0, i1 := int(i), int(i)+1
+		if repeat {
+			i0, i1 = ((i0%n)+n)%n, ((i1%n)+n)%n
+		} else {
+			if i0 < 0 {
+				i0 = 0
+			}
+			if n <= i1 {
+				i1 = n - 1
+			}
+		}
+		return i0, i1, t
+	}
+	x0, x1, tx := index(u, w, p.Repeat.RepeatsX())
+	y0, y1, ty := index(v, h, p.Repeat.RepeatsY())
+	at := func(x, y int) color.RGBA {
+		if img, ok := p.Img.(*image.RGBA); ok {
+			return img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+		}
+		return color.RGBAModel.Convert(p.Img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+	}
+	c00, c10, c01, c11 := at(x0, y0), at(x1, y0), at(x0, y1), at(x1, y1)
+	lerp := func(a, b, c, d uint8) uint8 {
+		top := float64(a) + tx*(float64(b)-float64(a))
+		bottom := float64(c) + tx*(float64(d)-float64(c))
+		return uint8(top + ty*(bottom-top) + 0.5)
+	}
+	return color.RGBA{lerp(c00.R, c10.R, c01.R, c11.R), lerp(c00.G, c10.G, c01.G, c11.G), lerp(c00.B, c10.B, c01.B, c11.B), lerp(c00.A, c10.A, c01.A, c11.A)}
+}
+
+// Color returns the average color of the image, which is sampled for large images.
+func (p ImagePattern) Color() color.RGBA {
+	bounds := p.Img.Bounds()
+	dx, dy := (bounds.Dx()+63)/64, (bounds.Dy()+63)/64
+	var r, g, b, a, n uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += dy {
+		for x := bounds.Min.X; x < bounds.Max.X; x += dx {
+			col := color.RGBAModel.Convert(p.Img.At(x, y)).(color.RGBA)
+			r, g, b, a = r+uint64(col.R), g+uint64(col.G), b+uint64(col.B), a+uint64(col.A)
+			n++
+		}
+	}
+	if n == 0 {
+		return Transparent
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+}
+
+// PathPattern is a paint that repeats a vector motif on a grid of cells of size CellW by CellH. The motif is the path Cell drawn with Style in the coordinates of the cell, where only its colors, stroke and fill rule are used. M maps the pattern to the coordinates of the filled path. Parts of the motif that extend beyond its cell are drawn in the neighbouring cells, so that a motif that crosses the cell edges tiles seamlessly.
+type PathPattern struct {
+	Cell         *Path
+	CellW, CellH float64
+	Style        Style
+	M            Matrix
+}
+
+// Empty returns true if the pattern draws nothing.
+func (p PathPattern) Empty() bool {
+	return p.Cell == nil || p.Cell.Empty() || p.CellW <= 0.0 || p.CellH <= 0.0 || p.Style.FillColor.A == 0 && (p.Style.StrokeColor.A == 0 || p.Style.StrokeWidth <= 0.0)
+}
+
+// outlines returns the fill and stroke outlines of the motif, which are nil when not drawn.
+func (p PathPattern) outlines() (*Path, *Path) {
+	var fill, stroke *Path
+	if p.Style.FillColor.A != 0 {
+		fill = p.Cell
+	}
+	if p.Style.StrokeColor.A != 0 && 0.0 < p.Style.StrokeWidth {
+		stroke = p.Cell
+		if 0 < len(p.Style.Dashes) {
+			stroke = stroke.Dash(p.Style.DashOffset, p.Style.Dashes...)
+		}
+		stroke = stroke.Stroke(p.Style.StrokeWidth, p.Style.StrokeCapper, p.Style.StrokeJoiner)
+	}
+	return fill, stroke
+}
+
+// Offsets returns the translations of the motif, in multiples of the cell size, of all copies that overlap the cell at the origin. Drawing these copies clipped to the cell produces a tile that repeats seamlessly.
+func (p PathPattern) Offsets() []Point {
+	if p.Empty() {
+		return nil
+	}
+	bounds := p.Cell.Bounds()
+	if fill, stroke := p.outlines(); stroke != nil {
+		bounds = stroke.Bounds()
+		if fill != nil {
+			bounds = bounds.Add(fill.Bounds())
+		}
+	}
+
+	offsets := []Point{}
+	x0, x1 := math.Floor(-(bounds.X+bounds.W)/p.CellW)+1.0, math.Ceil((p.CellW-bounds.X)/p.CellW)-1.0
+	y0, y1 := math.Floor(-(bounds.Y+bounds.H)/p.CellH)+1.0, math.Ceil((p.CellH-bounds.Y)/p.CellH)-1.0
+	for j := y0; j <= y1; j++ {
+		for i := x0; i <= x1; i++ {
+			offsets = append(offsets, Point{i * p.CellW, j * p.CellH})
+		}
+	}
+	return offsets
+}
+
+// At returns the color at (x,y). This evaluates the outlines of the motif for every call, renderers draw the motif instead.
+func (p PathPattern) At(x, y float64) color.RGBA {
+	if p.Empty() {
+		return Transparent
+	}
+	pos := p.M.Inv().Dot(Point{x, y})
+	pos.X -= math.Floor(pos.X/p.CellW) * p.CellW
+	pos.Y -= math.Floor(pos.Y/p.CellH) * p.CellH
+
+	over := func(src, dst color.RGBA) color.RGBA {
+		f := 1.0 - float64(src.A)/255.0
+		blend := func(s, d uint8) uint8 {
+			return uint8(float64(s) + f*float64(d) + 0.5)
+		}
+		return color.RGBA{blend(src.R, dst.R), blend(src.G, dst.G), blend(src.B, dst.B), blend(src.A, dst.A)}
+	}
+	col := Transparent
+	fill, stroke := p.outlines()
+	for _, offset := range p.Offsets() {
+		q := pos.Sub(offset)
+		if fill != nil && fill.Interior(q.X, q.Y, p.Style.FillRule) {
+			col = over(p.Style.FillColor, col)
+		}
+		if stroke != nil && stroke.Interior(q.X, q.Y, NonZero) {
+			col = over(p.Style.StrokeColor, col)
+		}
+	}
+	return col
+}
+
+// Color returns the average color of a cell, sampled on a grid.
+func (p PathPattern) Color() color.RGBA {
+	if p.Empty() {
+		return Transparent
+	}
+	const n = 8
+	var r, g, b, a float64
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			pos := p.M.Dot(Point{(float64(i) + 0.5) / n * p.CellW, (float64(j) + 0.5) / n * p.CellH})
+			col := p.At(pos.X, pos.Y)
+			r, g, b, a = r+float64(col.R), g+float64(col.G), b+float64(col.B), a+float64(col.A)
+		}
+	}
+	return color.RGBA{uint8(r/n/n + 0.5), uint8(g/n/n + 0.5), uint8(b/n/n + 0.5), uint8(a/n/n + 0.5)}
+}
+
+// scaleAlpha multiplies the opacity of a premultiplied color by alpha.
+func scaleAlpha(col color.RGBA, alpha float64) color.RGBA {
+	if alpha == 1.0 {
+		return col
+	}
+	return color.RGBA{uint8(float64(col.R)*alpha + 0.5), uint8(float64(col.G)*alpha + 0.5), uint8(float64(col.B)*alpha + 0.5), uint8(float64(col.A)*alpha + 0.5)}
+}
+
+// imageAlpha returns a copy of the image with its opacity multiplied by alpha.
+func imageAlpha(img image.Image, alpha float64) image.Image {
+	if alpha == 1.0 {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	mask := image.NewUniform(color.Alpha{uint8(alpha*255.0 + 0.5)})
+	draw.DrawMask(dst, dst.Bounds(), img, img.Bounds().Min, mask, image.Point{}, draw.Src)
+	return dst
+}
+
+// stopsAlpha returns a copy of the stops with their opacity multiplied by alpha.
+func stopsAlpha(stops []Stop, alpha float64) []Stop {
+	stops = append([]Stop{}, stops...)
+	for i := range stops {
+		stops[i].Color = scaleAlpha(stops[i].Color, alpha)
+	}
+	return stops
+}
+
+// alphaPaint multiplies the opacity of a paint by alpha.
+type alphaPaint struct {
+	Paint
+	alpha float64
+}
+
+func (p alphaPaint) At(x, y float64) color.RGBA {
+	return scaleAlpha(p.Paint.At(x, y), p.alpha)
+}
+
+func (p alphaPaint) Color() color.RGBA {
+	return scaleAlpha(p.Paint.Color(), p.alpha)
+}
+
+// paintAlpha returns the paint with its opacity multiplied by alpha. The paints of this package keep their type so that renderers can still write them natively.
+func paintAlpha(paint Paint, alpha float64) Paint {
+	if paint == nil || alpha == 1.0 {
+		return paint
+	}
+	switch p := paint.(type) {
+	case LinearGradient:
+		p.Stops = stopsAlpha(p.Stops, alpha)
+		return p
+	case RadialGradient:
+		p.Stops = stopsAlpha(p.Stops, alpha)
+		return p
+	case AlongPathGradient:
+		p.Stops = stopsAlpha(p.Stops, alpha)
+		return p
+	case ImagePattern:
+		p.Img = imageAlpha(p.Img, alpha)
+		return p
+	case PathPattern:
+		p.Style = styleAlpha(p.Style, alpha, alpha)
+		return p
+	}
+	return alphaPaint{paint, alpha}
+}
+
+// styleAlpha returns the style with the opacity of its fill and stroke multiplied by fillAlpha and strokeAlpha respectively.
+func styleAlpha(style Style, fillAlpha, strokeAlpha float64) Style {
+	style.FillColor = scaleAlpha(style.FillColor, fillAlpha)
+	style.FillPaint = paintAlpha(style.FillPaint, fillAlpha)
+	style.StrokeColor = scaleAlpha(style.StrokeColor, strokeAlpha)
+	style.StrokePaint = paintAlpha(style.StrokePaint, strokeAlpha)
+	return style
+}
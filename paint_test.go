@@ -0,0 +1,135 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestNewLinearGradient(t *testing.T) {
+	_, err := NewLinearGradient(0.0, 0.0, 1.0, 0.0, PadSpread, Stop{0.0, Black})
+	test.That(t, err != nil, "one stop is not enough")
+
+	g, err := NewLinearGradient(0.0, 0.0, 10.0, 0.0, PadSpread, Stop{1.5, White}, Stop{-1.0, Black}, Stop{0.5, Red})
+	test.Error(t, err)
+	test.T(t, g.Stops, []Stop{{0.0, Black}, {0.5, Red}, {1.0, White}})
+}
+
+func TestLinearGradient(t *testing.T) {
+	stops := []Stop{{0.0, Black}, {1.0, White}}
+	gray := func(y uint8) color.RGBA {
+		return color.RGBA{y, y, y, 255}
+	}
+
+	var tests = []struct {
+		spread Spread
+		x      float64
+		col    color.RGBA
+	}{
+		{PadSpread, -5.0, Black},
+		{PadSpread, 5.0, gray(128)},
+		{PadSpread, 15.0, White},
+		{RepeatSpread, 12.5, gray(64)},
+		{RepeatSpread, -2.5, gray(191)},
+		{ReflectSpread, 12.5, gray(191)},
+		{ReflectSpread, -2.5, gray(64)},
+		{ReflectSpread, 22.5, gray(64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spread.String(), func(t *testing.T) {
+			g := LinearGradient{0.0, 0.0, 10.0, 0.0, stops, tt.spread}
+			test.T(t, g.At(tt.x, 3.0), tt.col)
+		})
+	}
+
+	g := LinearGradient{0.0, 0.0, 0.0, 10.0, []Stop{{0.0, Transparent}, {1.0, Blue}}, PadSpread}
+	test.T(t, g.At(7.0, 5.0), color.RGBA{0, 0, 128, 128})
+	test.T(t, g.Color(), color.RGBA{0, 0, 128, 128})
+}
+
+func TestRadialGradient(t *testing.T) {
+	_, err := NewRadialGradient(0.0, 0.0, -1.0, 0.0, 0.0, PadSpread, Stop{0.0, Black}, Stop{1.0, White})
+	test.That(t, err != nil, "negative radius")
+
+	g, err := NewRadialGradient(0.0, 0.0, 10.0, 0.0, 0.0, PadSpread, Stop{0.0, Black}, Stop{1.0, White})
+	test.Error(t, err)
+	test.T(t, g.At(0.0, 0.0), Black)
+	test.T(t, g.At(0.0, -5.0), color.RGBA{128, 128, 128, 255})
+	test.T(t, g.At(20.0, 20.0), White)
+
+	// focal point halfway the center and the circle
+	g.FX = 5.0
+	offset, ok := g.Offset(5.0, 0.0)
+	test.That(t, ok)
+	test.Float(t, offset, 0.0)
+	offset, _ = g.Offset(-10.0, 0.0)
+	test.Float(t, offset, 1.0)
+	offset, _ = g.Offset(10.0, 0.0)
+	test.Float(t, offset, 1.0)
+	offset, _ = g.Offset(-2.5, 0.0)
+	test.Float(t, offset, 0.5)
+
+	g.R = 0.0
+	test.T(t, g.At(0.0, 0.0), White)
+	test.T(t, g.Color(), White)
+}
+
+func TestAlongPathGradient(t *testing.T) {
+	_, err := NewAlongPathGradient(Stop{0.0, Black})
+	test.That(t, err != nil, "one stop is not enough")
+
+	g, err := NewAlongPathGradient(Stop{0.0, Black}, Stop{0.5, Black}, Stop{1.0, color.RGBA{8, 0, 0, 255}})
+	test.Error(t, err)
+
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	p.LineTo(10.0, 0.0)
+	p.MoveTo(0.0, 5.0)
+	p.LineTo(10.0, 5.0)
+	pieces := g.pieces(p)
+	test.T(t, len(pieces), 3)
+	test.T(t, pieces[0].col, Black)
+	test.That(t, pieces[0].first && pieces[0].last, "first subpath is a single piece")
+	test.Float(t, pieces[1].path.Length(), 5.0)
+	test.T(t, pieces[1].col, color.RGBA{2, 0, 0, 255})
+	test.Float(t, pieces[2].offset, 5.0)
+	test.T(t, pieces[2].col, color.RGBA{6, 0, 0, 255})
+	test.That(t, pieces[1].first && !pieces[1].last && pieces[2].last, "caps at the ends of the second subpath")
+}
+
+func TestImagePattern(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(1, 0, color.RGBA{0, 0, 255, 255})
+	p := ImagePattern{img, Identity, RepeatX}
+	test.T(t, p.At(0.5, 0.5), Transparent)
+	test.T(t, p.At(1.5, 0.5), color.RGBA{0, 0, 255, 255})
+	test.T(t, p.At(1.0, 0.5), color.RGBA{0, 0, 128, 128})
+	test.T(t, p.At(3.5, 0.5), color.RGBA{0, 0, 255, 255})
+	test.T(t, p.At(1.5, 1.5), Transparent)
+	test.T(t, p.At(1.5, -0.5), Transparent)
+	test.T(t, p.Color(), color.RGBA{0, 0, 127, 127})
+	test.T(t, RepeatY.String(), "repeat-y")
+}
+
+func TestPathPattern(t *testing.T) {
+	style := DefaultStyle
+	style.FillColor = Black
+	dot := PathPattern{Circle(1.0).Translate(2.0, 2.0), 4.0, 4.0, style, Identity}
+	test.T(t, dot.Offsets(), []Point{{0.0, 0.0}})
+	test.T(t, dot.At(2.0, 2.0), Black)
+	test.T(t, dot.At(6.0, -2.0), Black)
+	test.T(t, dot.At(0.0, 0.0), Transparent)
+	test.T(t, dot.Color().A, uint8(48)) // 12 of 64 samples
+
+	// a dot on the corner of the cell is drawn in all four cells around it
+	corner := PathPattern{Circle(1.0), 4.0, 4.0, style, Identity.Scale(2.0, 2.0)}
+	test.T(t, corner.Offsets(), []Point{{0.0, 0.0}, {4.0, 0.0}, {0.0, 4.0}, {4.0, 4.0}})
+	test.T(t, corner.At(7.0, 8.0), Black)
+	test.T(t, corner.At(9.0, 8.0), Black)
+	test.T(t, corner.At(4.0, 4.0), Transparent)
+
+	test.That(t, PathPattern{Circle(1.0), 0.0, 4.0, style, Identity}.Empty())
+	test.T(t, PathPattern{}.Color(), Transparent)
+}
@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sort"
 	"strings"
@@ -89,9 +90,40 @@ func (p *Path) Equals(q *Path) bool {
 	return true
 }
 
-// Closed returns true if the last subpath of p is a closed path.
+// Closed returns true if every subpath of p ends with a Close command. Empty subpaths consisting of only a MoveTo are not closed, and neither is an empty path.
 func (p *Path) Closed() bool {
-	return 0 < len(p.d) && p.d[len(p.d)-1] == closeCmd
+	if len(p.d) == 0 {
+		return false
+	}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		if cmd != closeCmd && (i == len(p.d) || p.d[i] == moveToCmd) {
+			return false
+		}
+	}
+	return true
+}
+
+// SubpathCount returns the number of subpaths of p, including empty subpaths that consist of only a MoveTo.
+func (p *Path) SubpathCount() int {
+	n := 0
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		if p.d[i] == moveToCmd {
+			n++
+		}
+	}
+	return n
+}
+
+// HasCurves returns true if p contains any quadratic or cubic Bézier or elliptical arc commands.
+func (p *Path) HasCurves() bool {
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		if cmd := p.d[i]; cmd == quadToCmd || cmd == cubeToCmd || cmd == arcToCmd {
+			return true
+		}
+	}
+	return false
 }
 
 // Copy returns a copy of p.
@@ -111,7 +143,7 @@ func (p *Path) Append(q *Path) *Path {
 	return &Path{append(p.d, q.d...)}
 }
 
-// Join joins path q to p and returns a new path if succesful (otherwise either p or q are returned). Its like executing the commands in q to p in sequence, where if the first MoveTo of q doesn't coincide with p it will fallback to appending the paths.
+// Join joins path q to p and returns a new path if succesful (otherwise either p or q are returned). Its like executing the commands in q to p in sequence, where the first MoveTo of q is dropped so that the last subpath of p and the first subpath of q become one subpath. If the first MoveTo of q doesn't coincide with p, a LineTo will connect the two paths. Other subpaths of p and q remain separate subpaths. If the last subpath of p or the first subpath of q is closed, it will fallback to appending the paths.
 func (p *Path) Join(q *Path) *Path {
 	if q == nil || q.Empty() {
 		return p
@@ -120,7 +152,15 @@ func (p *Path) Join(q *Path) *Path {
 	}
 
 	if !Equal(p.d[len(p.d)-3], q.d[1]) || !Equal(p.d[len(p.d)-2], q.d[2]) {
-		return p.Append(q)
+		if p.d[len(p.d)-1] == closeCmd {
+			return p.Append(q)
+		}
+		for i := cmdLen(moveToCmd); i < len(q.d) && q.d[i] != moveToCmd; i += cmdLen(q.d[i]) {
+			if q.d[i] == closeCmd {
+				return p.Append(q)
+			}
+		}
+		p.LineTo(q.d[1], q.d[2])
 	}
 
 	q.d = q.d[cmdLen(moveToCmd):]
@@ -161,6 +201,176 @@ func (p *Path) Join(q *Path) *Path {
 	return p
 }
 
+// PathCmd is a path command, used to edit paths by command index.
+type PathCmd int
+
+// see PathCmd
+const (
+	MoveToCmd PathCmd = moveToCmd
+	LineToCmd PathCmd = lineToCmd
+	QuadToCmd PathCmd = quadToCmd
+	CubeToCmd PathCmd = cubeToCmd
+	ArcToCmd  PathCmd = arcToCmd
+	CloseCmd  PathCmd = closeCmd
+)
+
+// Len returns the number of arguments the path command takes, see Path.InsertCmd.
+func (cmd PathCmd) Len() int {
+	switch cmd {
+	case MoveToCmd, LineToCmd:
+		return 2
+	case QuadToCmd:
+		return 4
+	case CubeToCmd:
+		return 6
+	case ArcToCmd:
+		return 7
+	case CloseCmd:
+		return 0
+	}
+	return -1
+}
+
+// String returns the SVG letter of the path command.
+func (cmd PathCmd) String() string {
+	switch cmd {
+	case MoveToCmd:
+		return "M"
+	case LineToCmd:
+		return "L"
+	case QuadToCmd:
+		return "Q"
+	case CubeToCmd:
+		return "C"
+	case ArcToCmd:
+		return "A"
+	case CloseCmd:
+		return "z"
+	}
+	return "?"
+}
+
+// cmdIndices returns the indices into p.d of the path commands.
+func (p *Path) cmdIndices() []int {
+	is := []int{}
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		is = append(is, i)
+	}
+	return is
+}
+
+// repair makes sure that each subpath starts with a single MoveTo and that the end points of Close commands coincide with the start of their subpath.
+func (p *Path) repair() {
+	d := make([]float64, 0, len(p.d))
+	var start, end Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		n := cmdLen(cmd)
+		if cmd == moveToCmd {
+			start = Point{p.d[i+1], p.d[i+2]}
+			if 0 < len(d) && d[len(d)-1] == moveToCmd {
+				d = d[:len(d)-cmdLen(moveToCmd)]
+			}
+		} else if len(d) == 0 || d[len(d)-1] == closeCmd {
+			d = append(d, moveToCmd, end.X, end.Y, moveToCmd)
+			start = end
+		}
+		d = append(d, p.d[i:i+n]...)
+		if cmd == closeCmd {
+			d[len(d)-3] = start.X
+			d[len(d)-2] = start.Y
+		}
+		end = Point{d[len(d)-3], d[len(d)-2]}
+		i += n
+	}
+	p.d = d
+}
+
+// DeleteCmd deletes the i-th path command. Deleting a MoveTo will merge its subpath into the previous subpath, which continues from the end point of the previous command. Deleting the first MoveTo of the path or a MoveTo following a Close command is not allowed when it is followed by other commands, and will return an error.
+func (p *Path) DeleteCmd(i int) error {
+	is := p.cmdIndices()
+	if i < 0 || len(is) <= i {
+		return fmt.Errorf("bad command index %d: path has %d commands", i, len(is))
+	}
+
+	j := is[i]
+	cmd := p.d[j]
+	if cmd == moveToCmd && i+1 < len(is) && p.d[is[i+1]] != moveToCmd && (i == 0 || p.d[is[i-1]] == closeCmd) {
+		return fmt.Errorf("bad command index %d: cannot delete MoveTo that starts a subpath without a preceding open subpath", i)
+	}
+	p.d = append(p.d[:j:j], p.d[j+cmdLen(cmd):]...)
+	p.repair()
+	return nil
+}
+
+// InsertCmd inserts a path command before the i-th path command, or appends it when i equals the number of commands. The number of arguments must match cmd.Len(): MoveTo and LineTo take x,y, QuadTo takes cpx,cpy,x,y, CubeTo takes cpx1,cpy1,cpx2,cpy2,x,y, ArcTo takes rx,ry,rot,large,sweep,x,y with rot in degrees and large and sweep being 0 or 1, and Close takes no arguments. The first command of a path must be a MoveTo. Inserting a MoveTo directly before another MoveTo, such as at index zero, returns an error since it would start an empty subpath that has no effect.
+func (p *Path) InsertCmd(i int, cmd PathCmd, args ...float64) error {
+	is := p.cmdIndices()
+	if i < 0 || len(is) < i {
+		return fmt.Errorf("bad command index %d: path has %d commands", i, len(is))
+	} else if cmd.Len() < 0 {
+		return fmt.Errorf("bad command %d", cmd)
+	} else if len(args) != cmd.Len() {
+		return fmt.Errorf("bad arguments: command %v takes %d arguments, got %d", cmd, cmd.Len(), len(args))
+	} else if i == 0 && cmd != MoveToCmd {
+		return fmt.Errorf("bad command: path must start with a MoveTo")
+	} else if cmd == MoveToCmd && i < len(is) && p.d[is[i]] == moveToCmd {
+		return fmt.Errorf("bad command index %d: cannot insert MoveTo before another MoveTo", i)
+	}
+
+	var v []float64
+	switch cmd {
+	case CloseCmd:
+		v = []float64{closeCmd, 0.0, 0.0, closeCmd} // end point is set by repair
+	case ArcToCmd:
+		if (args[3] != 0.0 && args[3] != 1.0) || (args[4] != 0.0 && args[4] != 1.0) {
+			return fmt.Errorf("bad arguments: largeArc and sweep flags should be 0 or 1")
+		}
+		v = []float64{arcToCmd, math.Abs(args[0]), math.Abs(args[1]), angleNorm(args[2] * math.Pi / 180.0), fromArcFlags(args[3] == 1.0, args[4] == 1.0), args[5], args[6], arcToCmd}
+	default:
+		v = append([]float64{float64(cmd)}, args...)
+		v = append(v, float64(cmd))
+	}
+
+	j := len(p.d)
+	if i < len(is) {
+		j = is[i]
+	}
+	d := make([]float64, 0, len(p.d)+len(v))
+	d = append(d, p.d[:j]...)
+	d = append(d, v...)
+	p.d = append(d, p.d[j:]...)
+	p.repair()
+	return nil
+}
+
+// ReplaceCmd replaces the i-th path command by the commands of q. Unless the replaced command is a MoveTo, the first MoveTo of q is dropped so that q continues from the end point of the previous command. Commands following the replaced command are not moved.
+func (p *Path) ReplaceCmd(i int, q *Path) error {
+	is := p.cmdIndices()
+	if i < 0 || len(is) <= i {
+		return fmt.Errorf("bad command index %d: path has %d commands", i, len(is))
+	} else if i == 0 && (q == nil || len(q.d) == 0 || q.d[0] != moveToCmd) && 1 < len(is) {
+		return fmt.Errorf("bad command: path must start with a MoveTo")
+	}
+
+	j := is[i]
+	cmd := p.d[j]
+	var v []float64
+	if q != nil {
+		v = q.d
+		if cmd != moveToCmd && 0 < len(v) && v[0] == moveToCmd {
+			v = v[cmdLen(moveToCmd):]
+		}
+	}
+
+	d := make([]float64, 0, len(p.d)+len(v))
+	d = append(d, p.d[:j]...)
+	d = append(d, v...)
+	p.d = append(d, p.d[j+cmdLen(cmd):]...)
+	p.repair()
+	return nil
+}
+
 // Pos returns the current position of the path, which is the end point of the last command.
 func (p *Path) Pos() Point {
 	if 0 < len(p.d) {
@@ -618,71 +828,97 @@ func (p *Path) Bounds() Rect {
 // Length returns the length of the path in millimeters. The length is approximated for cubic Béziers.
 func (p *Path) Length() float64 {
 	d := 0.0
-	var start, end Point
+	var start Point
 	for i := 0; i < len(p.d); {
 		cmd := p.d[i]
-		switch cmd {
-		case moveToCmd:
-			end = Point{p.d[i+1], p.d[i+2]}
-		case lineToCmd, closeCmd:
-			end = Point{p.d[i+1], p.d[i+2]}
-			d += end.Sub(start).Length()
-		case quadToCmd:
-			cp := Point{p.d[i+1], p.d[i+2]}
-			end = Point{p.d[i+3], p.d[i+4]}
-			d += quadraticBezierLength(start, cp, end)
-		case cubeToCmd:
-			cp1 := Point{p.d[i+1], p.d[i+2]}
-			cp2 := Point{p.d[i+3], p.d[i+4]}
-			end = Point{p.d[i+5], p.d[i+6]}
-			d += cubicBezierLength(start, cp1, cp2, end)
-		case arcToCmd:
-			rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
-			large, sweep := toArcFlags(p.d[i+4])
-			end = Point{p.d[i+5], p.d[i+6]}
-			_, _, theta1, theta2 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
-			d += ellipseLength(rx, ry, theta1, theta2)
-		}
+		d += p.segmentLength(i, start)
 		i += cmdLen(cmd)
-		start = end
+		start = Point{p.d[i-3], p.d[i-2]}
 	}
 	return d
 }
 
+// segmentLength returns the length of the segment at index i, with start the start point of the segment. The length is approximated for cubic Béziers.
+func (p *Path) segmentLength(i int, start Point) float64 {
+	switch cmd := p.d[i]; cmd {
+	case lineToCmd, closeCmd:
+		end := Point{p.d[i+1], p.d[i+2]}
+		return end.Sub(start).Length()
+	case quadToCmd:
+		cp := Point{p.d[i+1], p.d[i+2]}
+		end := Point{p.d[i+3], p.d[i+4]}
+		return quadraticBezierLength(start, cp, end)
+	case cubeToCmd:
+		cp1 := Point{p.d[i+1], p.d[i+2]}
+		cp2 := Point{p.d[i+3], p.d[i+4]}
+		end := Point{p.d[i+5], p.d[i+6]}
+		return cubicBezierLength(start, cp1, cp2, end)
+	case arcToCmd:
+		rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
+		large, sweep := toArcFlags(p.d[i+4])
+		end := Point{p.d[i+5], p.d[i+6]}
+		_, _, theta1, theta2 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+		return ellipseLength(rx, ry, theta1, theta2)
+	}
+	return 0.0
+}
+
 // Transform transform the path by the given transformation matrix and returns a new path.
 func (p *Path) Transform(m Matrix) *Path {
 	p = p.Copy()
+	transformPathData(p.d, m)
+	return p
+}
+
+// AppendTransformed appends path q transformed by the given transformation matrix to p and returns a new path if succesful (otherwise p is returned). This avoids the intermediate copies of q.Transform(m) when stamping the same path at many positions.
+func (p *Path) AppendTransformed(q *Path, m Matrix) *Path {
+	if q == nil || q.Empty() {
+		return p
+	}
+	n := len(p.d)
+	p = &Path{append(p.d, q.d...)}
+	transformPathData(p.d[n:], m)
+	return p
+}
+
+// AppendAt appends path q translated by (x,y) to p and returns a new path if succesful (otherwise p is returned).
+func (p *Path) AppendAt(q *Path, x, y float64) *Path {
+	return p.AppendTransformed(q, Identity.Translate(x, y))
+}
+
+// transformPathData transforms the path data in-place by the given transformation matrix.
+func transformPathData(d []float64, m Matrix) {
 	_, _, _, xscale, yscale, _ := m.Decompose()
-	for i := 0; i < len(p.d); {
-		cmd := p.d[i]
+	for i := 0; i < len(d); {
+		cmd := d[i]
 		switch cmd {
 		case moveToCmd, lineToCmd, closeCmd:
-			end := m.Dot(Point{p.d[i+1], p.d[i+2]})
-			p.d[i+1] = end.X
-			p.d[i+2] = end.Y
+			end := m.Dot(Point{d[i+1], d[i+2]})
+			d[i+1] = end.X
+			d[i+2] = end.Y
 		case quadToCmd:
-			cp := m.Dot(Point{p.d[i+1], p.d[i+2]})
-			end := m.Dot(Point{p.d[i+3], p.d[i+4]})
-			p.d[i+1] = cp.X
-			p.d[i+2] = cp.Y
-			p.d[i+3] = end.X
-			p.d[i+4] = end.Y
+			cp := m.Dot(Point{d[i+1], d[i+2]})
+			end := m.Dot(Point{d[i+3], d[i+4]})
+			d[i+1] = cp.X
+			d[i+2] = cp.Y
+			d[i+3] = end.X
+			d[i+4] = end.Y
 		case cubeToCmd:
-			cp1 := m.Dot(Point{p.d[i+1], p.d[i+2]})
-			cp2 := m.Dot(Point{p.d[i+3], p.d[i+4]})
-			end := m.Dot(Point{p.d[i+5], p.d[i+6]})
-			p.d[i+1] = cp1.X
-			p.d[i+2] = cp1.Y
-			p.d[i+3] = cp2.X
-			p.d[i+4] = cp2.Y
-			p.d[i+5] = end.X
-			p.d[i+6] = end.Y
+			cp1 := m.Dot(Point{d[i+1], d[i+2]})
+			cp2 := m.Dot(Point{d[i+3], d[i+4]})
+			end := m.Dot(Point{d[i+5], d[i+6]})
+			d[i+1] = cp1.X
+			d[i+2] = cp1.Y
+			d[i+3] = cp2.X
+			d[i+4] = cp2.Y
+			d[i+5] = end.X
+			d[i+6] = end.Y
 		case arcToCmd:
-			rx := p.d[i+1]
-			ry := p.d[i+2]
-			phi := p.d[i+3]
-			large, sweep := toArcFlags(p.d[i+4])
-			end := m.Dot(Point{p.d[i+5], p.d[i+6]})
+			rx := d[i+1]
+			ry := d[i+2]
+			phi := d[i+3]
+			large, sweep := toArcFlags(d[i+4])
+			end := m.Dot(Point{d[i+5], d[i+6]})
 
 			// For ellipses written as the conic section equation in matrix form, we have:
 			// (x, y) E (x; y) = 0, with E = (1/rx^2, 0; 0, 1/ry^2)
@@ -711,16 +947,15 @@ func (p *Path) Transform(m Matrix) *Path {
 			if xscale*yscale < 0.0 { // flip x or y axis needs flipping of the sweep
 				sweep = !sweep
 			}
-			p.d[i+1] = rx
-			p.d[i+2] = ry
-			p.d[i+3] = phi
-			p.d[i+4] = fromArcFlags(large, sweep)
-			p.d[i+5] = end.X
-			p.d[i+6] = end.Y
+			d[i+1] = rx
+			d[i+2] = ry
+			d[i+3] = phi
+			d[i+4] = fromArcFlags(large, sweep)
+			d[i+5] = end.X
+			d[i+6] = end.Y
 		}
 		i += cmdLen(cmd)
 	}
-	return p
 }
 
 // Translate translates the path by (x,y) and returns a new path.
@@ -733,6 +968,24 @@ func (p *Path) Flatten() *Path {
 	return p.replace(nil, flattenQuadraticBezier, flattenCubicBezier, flattenEllipticArc)
 }
 
+// flatten flattens all Bézier and arc curves into linear segments like Flatten, but uses tolerance as the maximum deviation. If tolerance is not positive, Tolerance is used.
+func (p *Path) flatten(tolerance float64) *Path {
+	if tolerance <= 0.0 {
+		tolerance = Tolerance
+	}
+	quad := func(p0, p1, p2 Point) *Path {
+		cp1, cp2 := quadraticToCubicBezier(p0, p1, p2)
+		return strokeCubicBezier(p0, cp1, cp2, p2, 0.0, tolerance)
+	}
+	cube := func(p0, p1, p2, p3 Point) *Path {
+		return strokeCubicBezier(p0, p1, p2, p3, 0.0, tolerance)
+	}
+	arc := func(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path {
+		return arcToCube(start, rx, ry, phi, large, sweep, end).flatten(tolerance)
+	}
+	return p.replace(nil, quad, cube, arc)
+}
+
 // ReplaceArcs replaces ArcTo commands by CubeTo commands.
 func (p *Path) ReplaceArcs() *Path {
 	return p.replace(nil, nil, nil, arcToCube)
@@ -803,7 +1056,7 @@ func (p *Path) replace(
 	return p
 }
 
-// Markers returns an array of start, mid and end markers along the path at the path coordinates between commands. Align will align the markers with the path direction so that the markers orient towards the path's left.
+// Markers returns an array of start, mid and end markers along the path at the path coordinates between commands, similar to SVG's marker-start, marker-mid and marker-end. The end points of Bézier and arc segments are also mid vertices. Closed subpaths only have mid markers. Markers that are nil are skipped. Align will align the markers with the path direction so that the markers orient towards the path's left, for mid markers this is along the bisector of the incoming and outgoing direction.
 func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 	markers := []*Path{}
 	for _, ps := range p.Split() {
@@ -828,17 +1081,17 @@ func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 				case quadToCmd, cubeToCmd:
 					var cp1, cp2 Point
 					if cmd == quadToCmd {
-						cp := Point{p.d[i-5], p.d[i-4]}
+						cp := Point{ps.d[i-5], ps.d[i-4]}
 						cp1, cp2 = quadraticToCubicBezier(start, cp, end)
 					} else {
-						cp1 = Point{p.d[i-7], p.d[i-6]}
-						cp2 = Point{p.d[i-5], p.d[i-4]}
+						cp1 = Point{ps.d[i-7], ps.d[i-6]}
+						cp2 = Point{ps.d[i-5], ps.d[i-4]}
 					}
 					n0 = cubicBezierNormal(start, cp1, cp2, end, 0.0, 1.0)
 					n1 = cubicBezierNormal(start, cp1, cp2, end, 1.0, 1.0)
 				case arcToCmd:
-					rx, ry, phi := p.d[i-7], p.d[i-6], p.d[i-5]
-					large, sweep := toArcFlags(p.d[i-4])
+					rx, ry, phi := ps.d[i-7], ps.d[i-6], ps.d[i-5]
+					large, sweep := toArcFlags(ps.d[i-4])
 					_, _, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
 					n0 = ellipseNormal(rx, ry, phi, sweep, theta0, 1.0)
 					n1 = ellipseNormal(rx, ry, phi, sweep, theta1, 1.0)
@@ -861,6 +1114,9 @@ func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 				angle = n0.Angle()
 			}
 
+			if q == nil {
+				continue
+			}
 			m := Identity.Translate(start.X, start.Y)
 			if align {
 				m = m.Rotate((angle * 180.0 / math.Pi) + 90.0)
@@ -875,6 +1131,9 @@ func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 			angle = n1.Add(n0Start).Angle()
 		}
 
+		if q == nil {
+			continue
+		}
 		m := Identity.Translate(end.X, end.Y)
 		if align {
 			m = m.Rotate((angle * 180.0 / math.Pi) + 90.0)
@@ -884,6 +1143,221 @@ func (p *Path) Markers(first, mid, last *Path, align bool) []*Path {
 	return markers
 }
 
+// RoundCorners returns a new path where the corners between two straight line segments are replaced by circular arcs of radius r that are tangent to both segments. The radius is reduced for corners where the adjacent segments are too short, so that at most half of each segment is used by a corner. Corners adjoining Bézier or arc segments are left untouched. Closed subpaths are also rounded at the corner where they close.
+func (p *Path) RoundCorners(r float64) *Path {
+	if r <= 0.0 || Equal(r, 0.0) {
+		return p.Copy()
+	}
+
+	q := &Path{}
+	for _, ps := range p.Split() {
+		// gather segments, removing zero-length closing segments
+		type segment struct {
+			i          int // index of the command in ps.d
+			start, end Point
+		}
+		segs := []segment{}
+		closed := ps.Closed()
+		for i := cmdLen(moveToCmd); i < len(ps.d); {
+			cmd := ps.d[i]
+			start := Point{ps.d[i-3], ps.d[i-2]}
+			end := Point{ps.d[i+cmdLen(cmd)-3], ps.d[i+cmdLen(cmd)-2]}
+			if cmd != closeCmd || !start.Equals(end) {
+				segs = append(segs, segment{i, start, end})
+			}
+			i += cmdLen(cmd)
+		}
+		if len(segs) == 0 {
+			q.d = append(q.d, ps.d...)
+			continue
+		}
+
+		isLine := func(seg segment) bool {
+			cmd := ps.d[seg.i]
+			return cmd == lineToCmd || cmd == closeCmd
+		}
+
+		// calculate the corner at the end of each segment
+		type corner struct {
+			trim, r float64
+			sweep   bool
+		}
+		corners := make([]corner, len(segs))
+		startTrim := make([]float64, len(segs))
+		endTrim := make([]float64, len(segs))
+		for i := range segs {
+			j := i + 1
+			if j == len(segs) {
+				if !closed {
+					break
+				}
+				j = 0
+			}
+			if !isLine(segs[i]) || !isLine(segs[j]) {
+				continue
+			}
+
+			u := segs[i].end.Sub(segs[i].start)
+			v := segs[j].end.Sub(segs[j].start)
+			alpha := u.AngleBetween(v) // turning angle
+			if Equal(alpha, 0.0) || Equal(math.Abs(alpha), math.Pi) {
+				continue
+			}
+
+			rc := r
+			trim := rc * math.Tan(math.Abs(alpha)/2.0)
+			if maxTrim := math.Min(u.Length(), v.Length()) / 2.0; maxTrim < trim {
+				trim = maxTrim
+				rc = trim / math.Tan(math.Abs(alpha)/2.0)
+			}
+			corners[i] = corner{trim, rc, 0.0 < alpha}
+			endTrim[i] = trim
+			startTrim[j] = trim
+		}
+
+		trimmedStart := func(i int) Point {
+			return segs[i].start.Add(segs[i].end.Sub(segs[i].start).Norm(startTrim[i]))
+		}
+
+		start := trimmedStart(0)
+		q.MoveTo(start.X, start.Y)
+		for i, seg := range segs {
+			switch cmd := ps.d[seg.i]; cmd {
+			case lineToCmd, closeCmd:
+				end := seg.end.Sub(seg.end.Sub(seg.start).Norm(endTrim[i]))
+				q.LineTo(end.X, end.Y)
+			case quadToCmd:
+				q.QuadTo(ps.d[seg.i+1], ps.d[seg.i+2], seg.end.X, seg.end.Y)
+			case cubeToCmd:
+				q.CubeTo(ps.d[seg.i+1], ps.d[seg.i+2], ps.d[seg.i+3], ps.d[seg.i+4], seg.end.X, seg.end.Y)
+			case arcToCmd:
+				large, sweep := toArcFlags(ps.d[seg.i+4])
+				q.ArcTo(ps.d[seg.i+1], ps.d[seg.i+2], ps.d[seg.i+3]*180.0/math.Pi, large, sweep, seg.end.X, seg.end.Y)
+			}
+
+			if 0.0 < corners[i].trim {
+				end := trimmedStart((i + 1) % len(segs))
+				q.ArcTo(corners[i].r, corners[i].r, 0.0, false, corners[i].sweep, end.X, end.Y)
+			}
+		}
+		if closed {
+			q.Close()
+		}
+	}
+	return q
+}
+
+// ArrowStyle is the shape of an arrowhead, see Path.Arrow.
+type ArrowStyle int
+
+// see ArrowStyle
+const (
+	NoArrow ArrowStyle = iota
+	TriangleArrow
+	OpenArrow
+	CircleArrow
+	BarArrow
+)
+
+// Arrow returns a new path with arrowheads of the given styles added at the start and the end of each open subpath, oriented along the path's direction. The TriangleArrow is a closed triangle of length and width size with its apex at the end point, the OpenArrow consists of the two sides of that triangle, the CircleArrow is a closed circle of diameter size centered at the end point, and the BarArrow is a line of length size perpendicular to the path. If shorten is set, the subpath is shortened so that it ends at the base of a triangle or on the edge of a circle, so that the stroke doesn't stick out of the arrowhead.
+func (p *Path) Arrow(start, end ArrowStyle, size float64, shorten bool) *Path {
+	q := &Path{}
+	heads := &Path{}
+	for _, ps := range p.Split() {
+		if ps.Closed() || ps.Empty() {
+			q = q.Append(ps)
+			continue
+		}
+
+		var dirStart, dirEnd Point
+		var startPos, endPos Point
+		first := true
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			if cmd != moveToCmd {
+				d0, d1 := ps.direction(i, endPos)
+				if first {
+					dirStart = d0
+					startPos = endPos
+					first = false
+				}
+				dirEnd = d1
+			}
+			i += cmdLen(cmd)
+			endPos = Point{ps.d[i-3], ps.d[i-2]}
+		}
+
+		startHead, startShorten := arrowHead(start, startPos, dirStart.Neg(), size)
+		endHead, endShorten := arrowHead(end, endPos, dirEnd, size)
+		heads = heads.Append(startHead).Append(endHead)
+
+		if shorten && (0.0 < startShorten || 0.0 < endShorten) {
+			if length := ps.Length(); startShorten+endShorten < length {
+				pss := ps.SplitAt(startShorten, length-endShorten)
+				if 0.0 < startShorten {
+					pss = pss[1:]
+				}
+				ps = pss[0]
+			}
+		}
+		q = q.Append(ps)
+	}
+	return q.Append(heads)
+}
+
+// arrowHead returns the arrowhead path with its apex at pos pointing in direction dir, and the length by which the path should be shortened so that its stroke doesn't stick out of the arrowhead.
+func arrowHead(style ArrowStyle, pos, dir Point, size float64) (*Path, float64) {
+	right := dir.Rot90CW().Mul(size / 2.0)
+	base := pos.Sub(dir.Mul(size))
+
+	p := &Path{}
+	switch style {
+	case TriangleArrow:
+		p.MoveTo(pos.X, pos.Y)
+		p.LineTo(base.X-right.X, base.Y-right.Y)
+		p.LineTo(base.X+right.X, base.Y+right.Y)
+		p.Close()
+		return p, size
+	case OpenArrow:
+		p.MoveTo(base.X+right.X, base.Y+right.Y)
+		p.LineTo(pos.X, pos.Y)
+		p.LineTo(base.X-right.X, base.Y-right.Y)
+	case CircleArrow:
+		return Circle(size/2.0).Translate(pos.X, pos.Y), size / 2.0
+	case BarArrow:
+		p.MoveTo(pos.X+right.X, pos.Y+right.Y)
+		p.LineTo(pos.X-right.X, pos.Y-right.Y)
+	}
+	return p, 0.0
+}
+
+// direction returns the unit directions at the start and end of the segment at index i, with start the start point of the segment.
+func (p *Path) direction(i int, start Point) (Point, Point) {
+	cmd := p.d[i]
+	end := Point{p.d[i+cmdLen(cmd)-3], p.d[i+cmdLen(cmd)-2]}
+	switch cmd {
+	case lineToCmd, closeCmd:
+		d := end.Sub(start).Norm(1.0)
+		return d, d
+	case quadToCmd, cubeToCmd:
+		var cp1, cp2 Point
+		if cmd == quadToCmd {
+			cp := Point{p.d[i+1], p.d[i+2]}
+			cp1, cp2 = quadraticToCubicBezier(start, cp, end)
+		} else {
+			cp1 = Point{p.d[i+1], p.d[i+2]}
+			cp2 = Point{p.d[i+3], p.d[i+4]}
+		}
+		return cubicBezierNormal(start, cp1, cp2, end, 0.0, 1.0).Rot90CCW(), cubicBezierNormal(start, cp1, cp2, end, 1.0, 1.0).Rot90CCW()
+	case arcToCmd:
+		rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
+		large, sweep := toArcFlags(p.d[i+4])
+		_, _, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+		return ellipseDeriv(rx, ry, phi, sweep, theta0).Norm(1.0), ellipseDeriv(rx, ry, phi, sweep, theta1).Norm(1.0)
+	}
+	return Point{}, Point{}
+}
+
 // Split splits the path into its independent subpaths. The path is split before each MoveTo command. None of the subpaths shall be empty.
 func (p *Path) Split() []*Path {
 	ps := []*Path{}
@@ -1044,7 +1518,16 @@ func (p *Path) SplitAt(ts ...float64) []*Path {
 						theta := invL(ts[j] - T)
 						mid, large1, large2, ok := ellipseSplit(rx, ry, phi, cx, cy, startTheta, theta2, theta)
 						if !ok {
-							panic("theta not in elliptic arc range for splitting")
+							// theta is at the start or end of the remaining arc within numerical precision
+							if math.Abs(theta-theta2) < math.Abs(theta-startTheta) {
+								q.ArcTo(rx, ry, phi*180.0/math.Pi, nextLarge, sweep, end.X, end.Y)
+								startTheta = theta2
+							}
+							pos := q.Pos()
+							push()
+							q.MoveTo(pos.X, pos.Y)
+							j++
+							continue
 						}
 
 						q.ArcTo(rx, ry, phi*180.0/math.Pi, large1, sweep, mid.X, mid.Y)
@@ -1258,6 +1741,190 @@ func (p *Path) Dash(offset float64, d ...float64) *Path {
 	return q
 }
 
+// dashAdjustLimit is the maximum relative amount by which DashAdjusted scales a dash pattern, ie. the pattern is scaled by a factor between 0.9 and 1.1.
+const dashAdjustLimit = 0.1
+
+// DashAdjusted returns a new path that consists of dashes like Dash, but scales the dash pattern slightly so that an integer number of pattern repeats fits each closed subpath, avoiding a partial dash at the seam of Close. If corners is true, the pattern is fitted between each pair of consecutive corners instead, so that every corner is centered in a dash. Corners are the vertices where the direction of the path changes abruptly, and the end points of open subpaths. Closed subpaths without corners are adjusted as if corners is false. The pattern is scaled by at most 10%, ie. by a factor between 0.9 and 1.1, and where an integer number of repeats cannot be fitted within that limit, the pattern is not scaled. The offset is applied after scaling and shifts the dashes away from the seam or corners.
+func (p *Path) DashAdjusted(offset float64, corners bool, d ...float64) *Path {
+	offset, d = dashCanonical(offset, d)
+	if len(d) == 0 {
+		return p
+	} else if len(d) == 1 && d[0] == 0.0 {
+		return &Path{}
+	}
+
+	if len(d)%2 == 1 {
+		// if d is uneven length, dash and space lengths alternate. Duplicate d so that uneven indices are always spaces
+		d = append(d, d...)
+	}
+	period := 0.0
+	for _, dd := range d {
+		period += dd
+	}
+
+	q := &Path{}
+	for _, ps := range p.Split() {
+		closed := ps.Closed()
+		length, vertices := ps.dashCorners()
+		snap := corners && (!closed || 0 < len(vertices))
+		if !snap {
+			vertices = []float64{0.0, length}
+		} else if closed {
+			vertices = append(vertices, vertices[0]+length)
+		} else {
+			vertices = append(append([]float64{0.0}, vertices...), length)
+		}
+
+		phase := math.Mod(offset, period)
+		if phase < 0.0 {
+			phase += period
+		}
+		if snap {
+			phase += d[0] / 2.0
+		}
+
+		// determine the dashes between each pair of vertices, the intervals are within [0,length]
+		dashes := [][2]float64{}
+		for k := 1; k < len(vertices); k++ {
+			s0, s1 := vertices[k-1], vertices[k]
+			scale := 1.0
+			if closed || snap {
+				scale = dashAdjustScale(s1-s0, period)
+			}
+
+			i, pos := 0, s0-phase*scale
+			for pos < s1 {
+				if i%2 == 0 {
+					a, b := math.Max(pos, s0), math.Min(pos+d[i]*scale, s1)
+					if a < b {
+						dashes = dashAddInterval(dashes, a, b, length)
+					}
+				}
+				pos += d[i] * scale
+				i = (i + 1) % len(d)
+			}
+		}
+		if len(dashes) == 0 {
+			continue
+		} else if len(dashes) == 1 && dashes[0][0] <= Epsilon && length-Epsilon <= dashes[0][1] {
+			q = q.Append(ps)
+			continue
+		}
+
+		ts := []float64{}
+		for _, dash := range dashes {
+			for _, t := range dash {
+				if Epsilon < t && t < length-Epsilon {
+					ts = append(ts, t)
+				}
+			}
+		}
+		pd := ps.SplitAt(ts...)
+		on := make([]bool, len(pd))
+		for j := range pd {
+			t0, t1 := 0.0, length
+			if 0 < j {
+				t0 = ts[j-1]
+			}
+			if j < len(ts) {
+				t1 = ts[j]
+			}
+			mid := (t0 + t1) / 2.0
+			for _, dash := range dashes {
+				if dash[0] <= mid && mid <= dash[1] {
+					on[j] = true
+					break
+				}
+			}
+		}
+
+		qd := &Path{}
+		for j := 0; j < len(pd)-1; j++ {
+			if on[j] {
+				qd = qd.Append(pd[j])
+			}
+		}
+		if on[len(pd)-1] {
+			if closed && on[0] {
+				qd = pd[len(pd)-1].Join(qd)
+			} else {
+				qd = qd.Append(pd[len(pd)-1])
+			}
+		}
+		q = q.Append(qd)
+	}
+	return q
+}
+
+// dashAdjustScale returns the scale factor for the dash pattern so that an integer number of periods fits the length, or 1.0 if that exceeds dashAdjustLimit.
+func dashAdjustScale(length, period float64) float64 {
+	n := math.Round(length / period)
+	if n < 1.0 {
+		return 1.0
+	}
+	scale := length / (n * period)
+	if dashAdjustLimit < math.Abs(scale-1.0) {
+		return 1.0
+	}
+	return scale
+}
+
+// dashAddInterval adds the dash interval [a,b] to the sorted intervals, wrapping around at length and merging touching intervals.
+func dashAddInterval(dashes [][2]float64, a, b, length float64) [][2]float64 {
+	if length < b {
+		if a < length {
+			dashes = dashAddInterval(dashes, a, length, length)
+		}
+		return dashAddInterval(dashes, math.Max(a-length, 0.0), b-length, length)
+	}
+
+	k := sort.Search(len(dashes), func(k int) bool {
+		return a < dashes[k][0]
+	})
+	dashes = append(dashes, [2]float64{})
+	copy(dashes[k+1:], dashes[k:])
+	dashes[k] = [2]float64{a, b}
+
+	// merge touching intervals
+	for j := 1; j < len(dashes); j++ {
+		if dashes[j][0]-dashes[j-1][1] < Epsilon {
+			dashes[j-1][1] = math.Max(dashes[j-1][1], dashes[j][1])
+			dashes = append(dashes[:j], dashes[j+1:]...)
+			j--
+		}
+	}
+	return dashes
+}
+
+// dashCorners returns the length of the subpath and the lengths along it at which the direction changes abruptly, excluding the start and end of open subpaths.
+func (p *Path) dashCorners() (float64, []float64) {
+	length := 0.0
+	corners := []float64{}
+	var start, dirStart, dirEnd Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		if cmd != moveToCmd {
+			if segLength := p.segmentLength(i, start); Epsilon < segLength {
+				d0, d1 := p.direction(i, start)
+				if !dirEnd.IsZero() && (!Equal(dirEnd.PerpDot(d0), 0.0) || dirEnd.Dot(d0) < 0.0) {
+					corners = append(corners, length)
+				}
+				if dirStart.IsZero() {
+					dirStart = d0
+				}
+				dirEnd = d1
+				length += segLength
+			}
+		}
+		i += cmdLen(cmd)
+		start = Point{p.d[i-3], p.d[i-2]}
+	}
+	if p.Closed() && !dirStart.IsZero() && (!Equal(dirEnd.PerpDot(dirStart), 0.0) || dirEnd.Dot(dirStart) < 0.0) {
+		corners = append([]float64{0.0}, corners...)
+	}
+	return length, corners
+}
+
 // Reverse returns a new path that is the same path as p but in the reverse direction.
 func (p *Path) Reverse() *Path {
 	rp := &Path{}
@@ -1380,43 +2047,110 @@ func MustParseSVG(s string) *Path {
 	return p
 }
 
-// ParseSVG parses an SVG path data string.
+// ParseSVG parses an SVG path data string. It returns an error with the (1-based) byte position for unknown commands, missing or unparsable numbers and invalid arc flags, and always terminates.
 func ParseSVG(s string) (*Path, error) {
-	if len(s) == 0 {
-		return &Path{}, nil
+	parser := newSVGPathParser()
+	if _, err := parser.parse([]byte(s), true); err != nil {
+		return nil, err
+	}
+	return parser.p, nil
+}
+
+// ParseSVGReader parses SVG path data from a reader incrementally, keeping only the unparsed remainder of the input in memory, which allows to parse very large path data. The result and errors are identical to those of ParseSVG for the same input.
+func ParseSVGReader(r io.Reader) (*Path, error) {
+	parser := newSVGPathParser()
+	chunk := make([]byte, 4096)
+	buf := []byte{}
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		final := err == io.EOF
+		consumed, errParse := parser.parse(buf, final)
+		if errParse != nil {
+			return nil, errParse
+		} else if final {
+			return parser.p, nil
+		}
+		parser.offset += consumed
+		buf = append(buf[:0], buf[consumed:]...)
 	}
+}
+
+var svgCmdLens = map[byte]int{
+	'M': 2,
+	'Z': 0,
+	'L': 2,
+	'H': 1,
+	'V': 1,
+	'C': 6,
+	'S': 4,
+	'Q': 4,
+	'T': 2,
+	'A': 7,
+}
+
+// svgPathParser parses SVG path data and can be resumed when the data is split over several buffers.
+type svgPathParser struct {
+	p       *Path
+	q, c    Point // previous control points for T and S commands
+	p0      Point // current position
+	prevCmd byte
+	started bool
+	offset  int // position of the buffer in the path data
+}
 
-	path := []byte(s)
-	if path[0] < 'A' {
-		return nil, fmt.Errorf("bad path: path should start with command")
+func newSVGPathParser() *svgPathParser {
+	return &svgPathParser{
+		p:       &Path{},
+		prevCmd: 'z',
 	}
+}
 
-	cmdLens := map[byte]int{
-		'M': 2,
-		'Z': 0,
-		'L': 2,
-		'H': 1,
-		'V': 1,
-		'C': 6,
-		'S': 4,
-		'Q': 4,
-		'T': 2,
-		'A': 7,
+// svgNumberMayContinue returns true if b could be the start of a number that continues in the following data, ie. b consists only of characters that can be part of a number.
+func svgNumberMayContinue(b []byte) bool {
+	for _, c := range b {
+		if !(c >= '0' && c <= '9' || c == '.' || c == '-' || c == '+' || c == 'e' || c == 'E') {
+			return false
+		}
 	}
+	return true
+}
+
+// parse parses complete commands from path and returns the number of bytes consumed. If final is false, a command that might continue after the end of path is not parsed so that it can be resumed with more data.
+func (parser *svgPathParser) parse(path []byte, final bool) (int, error) {
+	cmdLens := svgCmdLens
 	f := [7]float64{}
 
+	p := parser.p
+	q, c := parser.q, parser.c
+	p0, p1 := parser.p0, parser.p0
+	prevCmd := parser.prevCmd
+	defer func() {
+		parser.q, parser.c = q, c
+		parser.p0 = p0
+		parser.prevCmd = prevCmd
+	}()
+
 	i := 0
-	p := &Path{}
-	var q, c Point
-	var p0, p1 Point
-	prevCmd := byte('z')
 	for {
 		i += skipCommaWhitespace(path[i:])
 		if len(path) <= i {
 			break
 		}
+		start := i
+		if !parser.started {
+			if path[i] < 'A' {
+				return start, fmt.Errorf("bad path: path should start with command")
+			}
+			parser.started = true
+		}
 
 		cmd := prevCmd
+		cmdPos := i
 		repeat := true
 		if cmd == 'z' || cmd == 'Z' || !(path[i] >= '0' && path[i] <= '9' || path[i] == '.' || path[i] == '-' || path[i] == '+') {
 			cmd = path[i]
@@ -1432,25 +2166,33 @@ func ParseSVG(s string) (*Path, error) {
 		for j := 0; j < cmdLens[CMD]; j++ {
 			if CMD == 'A' && (j == 3 || j == 4) {
 				// parse largeArc and sweep booleans for A command
-				if i < len(path) && path[i] == '1' {
+				if !final && i == len(path) {
+					return start, nil
+				} else if i < len(path) && path[i] == '1' {
 					f[j] = 1.0
 				} else if i < len(path) && path[i] == '0' {
 					f[j] = 0.0
 				} else {
-					return nil, fmt.Errorf("bad path: largeArc and sweep flags should be 0 or 1 in command '%c' at position %d", cmd, i+1)
+					return start, fmt.Errorf("bad path: largeArc and sweep flags should be 0 or 1 in command '%c' at position %d", cmd, parser.offset+i+1)
 				}
 				i++
 			} else {
+				if !final && svgNumberMayContinue(path[i:]) {
+					return start, nil
+				}
 				num, n := strconv.ParseFloat(path[i:])
 				if n == 0 {
 					if repeat && j == 0 && i < len(path) {
-						return nil, fmt.Errorf("bad path: unknown command '%c' at position %d", path[i], i+1)
+						return start, fmt.Errorf("bad path: unknown command '%c' at position %d", path[i], parser.offset+i+1)
 					} else if 1 < cmdLens[CMD] {
-						return nil, fmt.Errorf("bad path: sets of %d numbers should follow command '%c' at position %d", cmdLens[CMD], cmd, i+1)
+						return start, fmt.Errorf("bad path: sets of %d numbers should follow command '%c' at position %d", cmdLens[CMD], cmd, parser.offset+i+1)
 					} else {
-						return nil, fmt.Errorf("bad path: number should follow command '%c' at position %d", cmd, i+1)
+						return start, fmt.Errorf("bad path: number should follow command '%c' at position %d", cmd, parser.offset+i+1)
 					}
 				}
+				if math.IsInf(num, 0) || math.IsNaN(num) {
+					return start, fmt.Errorf("bad path: number out of range in command '%c' at position %d", cmd, parser.offset+i+1)
+				}
 				f[j] = num
 				i += n
 			}
@@ -1544,12 +2286,16 @@ func ParseSVG(s string) (*Path, error) {
 			}
 			p.ArcTo(rx, ry, rot, large, sweep, p1.X, p1.Y)
 		default:
-			return nil, fmt.Errorf("bad path: unknown command '%c' at position %d", cmd, i+1)
+			return start, fmt.Errorf("bad path: unknown command '%c' at position %d", cmd, parser.offset+cmdPos+1)
+		}
+		if i == start {
+			// guarantee forward progress
+			return start, fmt.Errorf("bad path: unexpected character '%c' at position %d", path[i], parser.offset+i+1)
 		}
 		prevCmd = cmd
 		p0 = p1
 	}
-	return p, nil
+	return i, nil
 }
 
 // String returns a string that represents the path similar to the SVG path data format (but not necessarily valid SVG).
@@ -1588,80 +2334,439 @@ func (p *Path) String() string {
 
 // ToSVG returns a string that represents the path in the SVG path data format with minifications.
 func (p *Path) ToSVG() string {
+	sb := strings.Builder{}
+	p.writeSVG(&sb, func(b []byte, f float64) []byte {
+		return append(b, num(f).String()...)
+	})
+	return sb.String()
+}
+
+// ToSVGWithoutArcs returns a string that represents the path in the SVG path data format like ToSVG, but with arcs replaced by cubic Béziers (see ReplaceArcs) for consumers that do not support the A command. The path itself is left untouched. Arcs are split into pieces of at most 90 degrees, which deviate at most 0.2% of the major radius from the true arc.
+func (p *Path) ToSVGWithoutArcs() string {
+	return p.ReplaceArcs().ToSVG()
+}
+
+// ToSVGPrec returns a string that represents the path in the SVG path data format with coordinates rounded to the given number of decimals, trailing zeros removed and leading zeros omitted (eg. ".5" instead of "0.5").
+func (p *Path) ToSVGPrec(decimals int) string {
+	sb := strings.Builder{}
+	p.WriteSVGPath(&sb, decimals)
+	return sb.String()
+}
+
+// WriteSVGPath writes the path in the SVG path data format to w, identical to the output of ToSVGPrec but without building the string in memory. It returns the number of bytes written.
+func (p *Path) WriteSVGPath(w io.Writer, decimals int) (int, error) {
+	return p.writeSVG(w, func(b []byte, f float64) []byte {
+		return appendDecimals(b, f, decimals)
+	})
+}
+
+// writeSVG writes the path in the SVG path data format to w, using num to append numbers. Commands are collected in a small buffer that is flushed to w when it fills up.
+func (p *Path) writeSVG(w io.Writer, num func([]byte, float64) []byte) (int, error) {
 	if p.Empty() {
-		return ""
+		return 0, nil
 	}
 
-	sb := strings.Builder{}
+	n := 0
+	b := make([]byte, 0, 512)
 	var x, y float64
 	for i := 0; i < len(p.d); {
 		cmd := p.d[i]
 		switch cmd {
 		case moveToCmd:
 			x, y = p.d[i+1], p.d[i+2]
-			fmt.Fprintf(&sb, "M%v %v", num(x), num(y))
+			b = append(b, 'M')
+			b = append(num(b, x), ' ')
+			b = num(b, y)
 		case lineToCmd:
 			xStart, yStart := x, y
 			x, y = p.d[i+1], p.d[i+2]
 			if Equal(x, xStart) && Equal(y, yStart) {
 				// nothing
 			} else if Equal(x, xStart) {
-				fmt.Fprintf(&sb, "V%v", num(y))
+				b = append(b, 'V')
+				b = num(b, y)
 			} else if Equal(y, yStart) {
-				fmt.Fprintf(&sb, "H%v", num(x))
+				b = append(b, 'H')
+				b = num(b, x)
 			} else {
-				fmt.Fprintf(&sb, "L%v %v", num(x), num(y))
+				b = append(b, 'L')
+				b = append(num(b, x), ' ')
+				b = num(b, y)
 			}
 		case quadToCmd:
 			x, y = p.d[i+3], p.d[i+4]
-			fmt.Fprintf(&sb, "Q%v %v %v %v", num(p.d[i+1]), num(p.d[i+2]), num(x), num(y))
+			b = append(b, 'Q')
+			b = append(num(b, p.d[i+1]), ' ')
+			b = append(num(b, p.d[i+2]), ' ')
+			b = append(num(b, x), ' ')
+			b = num(b, y)
 		case cubeToCmd:
 			x, y = p.d[i+5], p.d[i+6]
-			fmt.Fprintf(&sb, "C%v %v %v %v %v %v", num(p.d[i+1]), num(p.d[i+2]), num(p.d[i+3]), num(p.d[i+4]), num(x), num(y))
+			b = append(b, 'C')
+			b = append(num(b, p.d[i+1]), ' ')
+			b = append(num(b, p.d[i+2]), ' ')
+			b = append(num(b, p.d[i+3]), ' ')
+			b = append(num(b, p.d[i+4]), ' ')
+			b = append(num(b, x), ' ')
+			b = num(b, y)
 		case arcToCmd:
 			rx, ry := p.d[i+1], p.d[i+2]
 			rot := p.d[i+3] * 180.0 / math.Pi
 			large, sweep := toArcFlags(p.d[i+4])
 			x, y = p.d[i+5], p.d[i+6]
-			sLarge := "0"
+			if 90.0 <= rot {
+				rx, ry = ry, rx
+				rot -= 90.0
+			}
+			b = append(b, 'A')
+			b = append(num(b, rx), ' ')
+			b = append(num(b, ry), ' ')
+			b = append(num(b, rot), ' ')
 			if large {
-				sLarge = "1"
+				b = append(b, '1')
+			} else {
+				b = append(b, '0')
 			}
-			sSweep := "0"
 			if sweep {
-				sSweep = "1"
+				b = append(b, '1')
+			} else {
+				b = append(b, '0')
+			}
+			b = append(num(b, x), ' ')
+			b = num(b, y)
+		case closeCmd:
+			x, y = p.d[i+1], p.d[i+2]
+			b = append(b, 'z')
+		}
+		i += cmdLen(cmd)
+
+		if 384 < len(b) || i == len(p.d) {
+			m, err := w.Write(b)
+			n += m
+			if err != nil {
+				return n, err
 			}
+			b = b[:0]
+		}
+	}
+	return n, nil
+}
+
+// ToSVGMinified returns a string that represents the path in the SVG path data format with coordinates rounded to the given number of decimals, and using the shortest of absolute and relative commands. Separators are omitted where the grammar allows, repeated commands are implicit, and the S and T shorthands are used when the control point is the reflection of the previous one. Since relative coordinates are computed from the rounded positions, the rounding errors do not accumulate.
+func (p *Path) ToSVGMinified(decimals int) string {
+	if p.Empty() {
+		return ""
+	}
+
+	m := svgPathMinifier{
+		decimals: decimals,
+		tol:      0.5 * math.Pow(10.0, -float64(decimals)),
+	}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			m.moveTo(Point{p.d[i+1], p.d[i+2]})
+		case lineToCmd:
+			m.lineTo(Point{p.d[i+1], p.d[i+2]})
+		case quadToCmd:
+			m.quadTo(Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]})
+		case cubeToCmd:
+			m.cubeTo(Point{p.d[i+1], p.d[i+2]}, Point{p.d[i+3], p.d[i+4]}, Point{p.d[i+5], p.d[i+6]})
+		case arcToCmd:
+			rx, ry := p.d[i+1], p.d[i+2]
+			rot := p.d[i+3] * 180.0 / math.Pi
+			large, sweep := toArcFlags(p.d[i+4])
 			if 90.0 <= rot {
 				rx, ry = ry, rx
 				rot -= 90.0
 			}
-			fmt.Fprintf(&sb, "A%v %v %v %s%s%v %v", num(rx), num(ry), num(rot), sLarge, sSweep, num(p.d[i+5]), num(p.d[i+6]))
+			m.arcTo(rx, ry, rot, large, sweep, Point{p.d[i+5], p.d[i+6]})
 		case closeCmd:
-			x, y = p.d[i+1], p.d[i+2]
-			fmt.Fprintf(&sb, "z")
+			m.close()
+		}
+		i += cmdLen(cmd)
+	}
+	return m.sb.String()
+}
+
+// svgPathMinifier writes minified SVG path data. It keeps track of the positions as they will be parsed back, ie. after rounding, so that relative coordinates and reflected control points are exact.
+type svgPathMinifier struct {
+	sb       strings.Builder
+	decimals int
+	tol      float64 // half of the rounding unit
+
+	pos, start Point // parsed current and subpath start positions
+	ctrl       Point // parsed last control point of the previous Bézier
+	prevCmd    byte  // previous command, either 'C', 'Q' or 0
+	implicit   byte  // command that may be omitted
+	lastNum    string
+}
+
+// svgToken is a number or an arc flag in the SVG path data.
+type svgToken struct {
+	s    string
+	flag bool
+}
+
+// num formats f and returns the formatted number as a token and its value as it will be parsed back.
+func (m *svgPathMinifier) num(f float64) (svgToken, float64) {
+	s := formatDecimals(f, m.decimals)
+	v, _ := strconv.ParseFloat([]byte(s))
+	return svgToken{s: s}, v
+}
+
+// encode returns the command as it would be written after the current output, and the last written number.
+func (m *svgPathMinifier) encode(cmd byte, tokens []svgToken) (string, string) {
+	b := []byte{}
+	lastNum := m.lastNum
+	if cmd != m.implicit {
+		b = append(b, cmd)
+		lastNum = ""
+	}
+	for _, token := range tokens {
+		if token.flag {
+			if lastNum != "" {
+				b = append(b, ' ')
+			}
+			lastNum = ""
+		} else {
+			if lastNum != "" && token.s[0] != '-' && (token.s[0] != '.' || strings.IndexByte(lastNum, '.') == -1) {
+				b = append(b, ' ')
+			}
+			lastNum = token.s
+		}
+		b = append(b, token.s...)
+	}
+	return string(b), lastNum
+}
+
+// write writes the shortest of the absolute (upper case) and relative (lower case) variants of a command. It returns true if the relative variant was chosen.
+func (m *svgPathMinifier) write(cmd byte, abs, rel []svgToken) bool {
+	s, lastNum := m.encode(cmd, abs)
+	relative := false
+	if rel != nil {
+		if sRel, lastNumRel := m.encode(cmd+'a'-'A', rel); len(sRel) < len(s) {
+			s, lastNum = sRel, lastNumRel
+			cmd += 'a' - 'A'
+			relative = true
+		}
+	}
+	m.sb.WriteString(s)
+	m.lastNum = lastNum
+	m.implicit = cmd
+	if cmd == 'M' {
+		m.implicit = 'L'
+	} else if cmd == 'm' {
+		m.implicit = 'l'
+	}
+	return relative
+}
+
+// point returns the tokens and parsed positions of an absolute and a relative point.
+func (m *svgPathMinifier) point(p Point) ([]svgToken, Point, []svgToken, Point) {
+	x, px := m.num(p.X)
+	y, py := m.num(p.Y)
+	dx, pdx := m.num(p.X - m.pos.X)
+	dy, pdy := m.num(p.Y - m.pos.Y)
+	return []svgToken{x, y}, Point{px, py}, []svgToken{dx, dy}, m.pos.Add(Point{pdx, pdy})
+}
+
+func (m *svgPathMinifier) moveTo(p Point) {
+	abs, pAbs, rel, pRel := m.point(p)
+	if m.write('M', abs, rel) {
+		m.pos = pRel
+	} else {
+		m.pos = pAbs
+	}
+	m.start = m.pos
+	m.prevCmd = 0
+}
+
+func (m *svgPathMinifier) lineTo(p Point) {
+	x, px := m.num(p.X)
+	y, py := m.num(p.Y)
+	dx, pdx := m.num(p.X - m.pos.X)
+	dy, pdy := m.num(p.Y - m.pos.Y)
+	if pdx == 0.0 && pdy == 0.0 {
+		return
+	} else if pdy == 0.0 {
+		if m.write('H', []svgToken{x}, []svgToken{dx}) {
+			m.pos.X += pdx
+		} else {
+			m.pos.X = px
+		}
+	} else if pdx == 0.0 {
+		if m.write('V', []svgToken{y}, []svgToken{dy}) {
+			m.pos.Y += pdy
+		} else {
+			m.pos.Y = py
+		}
+	} else if m.write('L', []svgToken{x, y}, []svgToken{dx, dy}) {
+		m.pos = m.pos.Add(Point{pdx, pdy})
+	} else {
+		m.pos = Point{px, py}
+	}
+	m.prevCmd = 0
+}
+
+// reflects returns true if the control point is the reflection of the previous control point of the same command.
+func (m *svgPathMinifier) reflects(cmd byte, cp Point) bool {
+	reflection := m.pos.Mul(2.0).Sub(m.ctrl)
+	return m.prevCmd == cmd && math.Abs(cp.X-reflection.X) <= m.tol && math.Abs(cp.Y-reflection.Y) <= m.tol
+}
+
+func (m *svgPathMinifier) quadTo(cp, p Point) {
+	abs, pAbs, rel, pRel := m.point(p)
+	if m.reflects('Q', cp) {
+		m.ctrl = m.pos.Mul(2.0).Sub(m.ctrl)
+		if m.write('T', abs, rel) {
+			m.pos = pRel
+		} else {
+			m.pos = pAbs
+		}
+	} else {
+		cpAbs, pcpAbs, cpRel, pcpRel := m.point(cp)
+		if m.write('Q', append(cpAbs, abs...), append(cpRel, rel...)) {
+			m.ctrl, m.pos = pcpRel, pRel
+		} else {
+			m.ctrl, m.pos = pcpAbs, pAbs
+		}
+	}
+	m.prevCmd = 'Q'
+}
+
+func (m *svgPathMinifier) cubeTo(cp1, cp2, p Point) {
+	abs, pAbs, rel, pRel := m.point(p)
+	cp2Abs, pcp2Abs, cp2Rel, pcp2Rel := m.point(cp2)
+	abs, rel = append(cp2Abs, abs...), append(cp2Rel, rel...)
+	cmd := byte('S')
+	if !m.reflects('C', cp1) {
+		cp1Abs, _, cp1Rel, _ := m.point(cp1)
+		abs, rel = append(cp1Abs, abs...), append(cp1Rel, rel...)
+		cmd = 'C'
+	}
+	if m.write(cmd, abs, rel) {
+		m.ctrl, m.pos = pcp2Rel, pRel
+	} else {
+		m.ctrl, m.pos = pcp2Abs, pAbs
+	}
+	m.prevCmd = 'C'
+}
+
+func (m *svgPathMinifier) arcTo(rx, ry, rot float64, large, sweep bool, p Point) {
+	srx, _ := m.num(rx)
+	sry, _ := m.num(ry)
+	srot, _ := m.num(rot)
+	sLarge, sSweep := svgToken{"0", true}, svgToken{"0", true}
+	if large {
+		sLarge.s = "1"
+	}
+	if sweep {
+		sSweep.s = "1"
+	}
+	abs, pAbs, rel, pRel := m.point(p)
+	head := []svgToken{srx, sry, srot, sLarge, sSweep}
+	if m.write('A', append(head, abs...), append(head, rel...)) {
+		m.pos = pRel
+	} else {
+		m.pos = pAbs
+	}
+	m.prevCmd = 0
+}
+
+func (m *svgPathMinifier) close() {
+	m.sb.WriteByte('z')
+	m.lastNum = ""
+	m.implicit = 0
+	m.pos = m.start
+	m.prevCmd = 0
+}
+
+// Canonical returns a normalized string of the path in the SVG path data format that is suitable for comparing paths, eg. when diffing output. It uses only absolute M, L, Q, C, A, and Z commands separated by spaces, and all coordinates have exactly the given number of decimals. Arcs have their major radius first, a rotation in [0,180) degrees (zero for circles), and flags of 0 or 1. Paths that are equal after rounding to the given number of decimals produce identical strings.
+func (p *Path) Canonical(decimals int) string {
+	num := func(f float64) string {
+		return formatFixed(f, decimals)
+	}
+
+	sb := strings.Builder{}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		if 0 < i {
+			sb.WriteByte(' ')
+		}
+		switch cmd {
+		case moveToCmd:
+			fmt.Fprintf(&sb, "M%s %s", num(p.d[i+1]), num(p.d[i+2]))
+		case lineToCmd:
+			fmt.Fprintf(&sb, "L%s %s", num(p.d[i+1]), num(p.d[i+2]))
+		case quadToCmd:
+			fmt.Fprintf(&sb, "Q%s %s %s %s", num(p.d[i+1]), num(p.d[i+2]), num(p.d[i+3]), num(p.d[i+4]))
+		case cubeToCmd:
+			fmt.Fprintf(&sb, "C%s %s %s %s %s %s", num(p.d[i+1]), num(p.d[i+2]), num(p.d[i+3]), num(p.d[i+4]), num(p.d[i+5]), num(p.d[i+6]))
+		case arcToCmd:
+			rx, ry := num(p.d[i+1]), num(p.d[i+2])
+			rot := num(p.d[i+3] * 180.0 / math.Pi)
+			if rx == ry || rot == num(180.0) {
+				rot = num(0.0)
+			}
+			large, sweep := toArcFlags(p.d[i+4])
+			sLarge, sSweep := "0", "0"
+			if large {
+				sLarge = "1"
+			}
+			if sweep {
+				sSweep = "1"
+			}
+			fmt.Fprintf(&sb, "A%s %s %s %s %s %s %s", rx, ry, rot, sLarge, sSweep, num(p.d[i+5]), num(p.d[i+6]))
+		case closeCmd:
+			sb.WriteString("Z")
 		}
 		i += cmdLen(cmd)
 	}
 	return sb.String()
 }
 
-// ToPS returns a string that represents the path in the PostScript data format.
+// ToPS returns a string that represents the path in the PostScript data format, using the moveto, lineto, curveto, arc, arcn, and closepath operators. Circular arcs are written using arc (counter clockwise) or arcn (clockwise), and elliptical arcs are converted to cubic Béziers. Coordinates are in the PostScript default user space where the y-axis points upwards, same as for paths. It can be used verbatim in a PostScript or EPS file when followed by a painting operator such as fill or stroke.
 func (p *Path) ToPS() string {
+	sb := strings.Builder{}
+	p.writePS(&sb, func(b []byte, f float64) []byte {
+		return append(b, dec(f).String()...)
+	})
+	return sb.String()
+}
+
+// ToPSPrec returns a string that represents the path in the PostScript data format like ToPS, but with coordinates and angles rounded to the given number of decimals.
+func (p *Path) ToPSPrec(decimals int) string {
+	sb := strings.Builder{}
+	p.writePS(&sb, func(b []byte, f float64) []byte {
+		return appendDecimals(b, f, decimals)
+	})
+	return sb.String()
+}
+
+func (p *Path) writePS(w io.Writer, num func([]byte, float64) []byte) (int, error) {
 	if p.Empty() {
-		return ""
+		return 0, nil
 	}
 
-	sb := strings.Builder{}
+	b := make([]byte, 0, 512)
+	nums := func(op string, fs ...float64) {
+		for _, f := range fs {
+			b = append(num(b, f), ' ')
+		}
+		b = append(append(b, op...), ' ')
+	}
 	var x, y float64
 	for i := 0; i < len(p.d); {
 		cmd := p.d[i]
 		switch cmd {
 		case moveToCmd:
 			x, y = p.d[i+1], p.d[i+2]
-			fmt.Fprintf(&sb, " %v %v moveto", dec(x), dec(y))
+			nums("moveto", x, y)
 		case lineToCmd:
 			x, y = p.d[i+1], p.d[i+2]
-			fmt.Fprintf(&sb, " %v %v lineto", dec(x), dec(y))
+			nums("lineto", x, y)
 		case quadToCmd, cubeToCmd:
 			var start, cp1, cp2 Point
 			start = Point{x, y}
@@ -1673,35 +2778,55 @@ func (p *Path) ToPS() string {
 				cp2 = Point{p.d[i+3], p.d[i+4]}
 				x, y = p.d[i+5], p.d[i+6]
 			}
-			fmt.Fprintf(&sb, " %v %v %v %v %v %v curveto", dec(cp1.X), dec(cp1.Y), dec(cp2.X), dec(cp2.Y), dec(x), dec(y))
+			nums("curveto", cp1.X, cp1.Y, cp2.X, cp2.Y, x, y)
 		case arcToCmd:
-			x0, y0 := x, y
+			start := Point{x, y}
 			rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
 			large, sweep := toArcFlags(p.d[i+4])
 			x, y = p.d[i+5], p.d[i+6]
 
-			cx, cy, theta0, theta1 := ellipseToCenter(x0, y0, rx, ry, phi, large, sweep, x, y)
-			theta0 = theta0 * 180.0 / math.Pi
-			theta1 = theta1 * 180.0 / math.Pi
-			rot := phi * 180.0 / math.Pi
+			if Equal(rx, ry) {
+				cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, x, y)
 
-			fmt.Fprintf(&sb, " %v %v %v %v %v %v %v ellipse", dec(cx), dec(cy), dec(rx), dec(ry), dec(theta0), dec(theta1), dec(rot))
-			if !sweep {
-				fmt.Fprintf(&sb, "n")
+				// the angles are relative to the rotated frame of the ellipse, start within [0,360)
+				offset := angleNorm(theta0+phi) - theta0
+				if Equal(theta0+offset, 2.0*math.Pi) {
+					offset -= 2.0 * math.Pi
+				}
+				theta0 += offset
+				theta1 += offset
+				theta0 = theta0 * 180.0 / math.Pi
+				theta1 = theta1 * 180.0 / math.Pi
+				if sweep {
+					nums("arc", cx, cy, rx, theta0, theta1)
+				} else {
+					nums("arcn", cx, cy, rx, theta0, theta1)
+				}
+			} else {
+				for _, bezier := range ellipseToCubicBeziers(start, rx, ry, phi, large, sweep, Point{x, y}) {
+					nums("curveto", bezier[1].X, bezier[1].Y, bezier[2].X, bezier[2].Y, bezier[3].X, bezier[3].Y)
+				}
 			}
 		case closeCmd:
 			x, y = p.d[i+1], p.d[i+2]
-			fmt.Fprintf(&sb, " closepath")
+			b = append(b, "closepath "...)
 		}
 		i += cmdLen(cmd)
 	}
-	return sb.String()[1:] // remove the first space
+	return w.Write(b[:len(b)-1]) // remove the last space
 }
 
-// ToPDF returns a string that represents the path in the PDF data format.
+// ToPDF returns a string that represents the path in the PDF data format, using the m, l, c, re, and h operators where elliptical arcs are converted to cubic Béziers. It can be used verbatim in a content stream when followed by a painting operator such as f, S, or B.
 func (p *Path) ToPDF() string {
+	sb := strings.Builder{}
+	p.WritePDF(&sb)
+	return sb.String()
+}
+
+// WritePDF writes the path in the PDF data format to w, identical to the output of ToPDF. It returns the number of bytes written.
+func (p *Path) WritePDF(w io.Writer) (int, error) {
 	if p.Empty() {
-		return ""
+		return 0, nil
 	}
 	p = p.replace(nil, nil, nil, arcToCube)
 
@@ -1712,6 +2837,11 @@ func (p *Path) ToPDF() string {
 		switch cmd {
 		case moveToCmd:
 			x, y = p.d[i+1], p.d[i+2]
+			if width, height, n := p.pdfRectangle(i); n != 0 {
+				fmt.Fprintf(&sb, " %v %v %v %v re", dec(x), dec(y), dec(width), dec(height))
+				i += n
+				continue
+			}
 			fmt.Fprintf(&sb, " %v %v m", dec(x), dec(y))
 		case lineToCmd:
 			x, y = p.d[i+1], p.d[i+2]
@@ -1736,7 +2866,29 @@ func (p *Path) ToPDF() string {
 		}
 		i += cmdLen(cmd)
 	}
-	return sb.String()[1:] // remove the first space
+	return io.WriteString(w, sb.String()[1:]) // remove the first space
+}
+
+// pdfRectangle returns the width and height of the subpath starting with the MoveTo at index i if it is a closed axis-aligned rectangle of which the first side is horizontal, which is how the PDF re operator draws rectangles. It also returns the length of the subpath in the path data, which is zero if the subpath is not such a rectangle.
+func (p *Path) pdfRectangle(i int) (float64, float64, int) {
+	n := cmdLen(moveToCmd)
+	pts := []Point{{p.d[i+1], p.d[i+2]}}
+	for i+n < len(p.d) && p.d[i+n] == lineToCmd && len(pts) < 5 {
+		pts = append(pts, Point{p.d[i+n+1], p.d[i+n+2]})
+		n += cmdLen(lineToCmd)
+	}
+	if len(p.d) <= i+n || p.d[i+n] != closeCmd || len(pts) < 4 {
+		return 0.0, 0.0, 0
+	} else if len(pts) == 5 && !pts[4].Equals(pts[0]) {
+		return 0.0, 0.0, 0
+	}
+	n += cmdLen(closeCmd)
+
+	w, h := pts[1].X-pts[0].X, pts[2].Y-pts[1].Y
+	if Equal(w, 0.0) || Equal(h, 0.0) || !Equal(pts[1].Y, pts[0].Y) || !Equal(pts[2].X, pts[1].X) || !Equal(pts[3].Y, pts[2].Y) || !Equal(pts[3].X, pts[0].X) {
+		return 0.0, 0.0, 0
+	}
+	return w, h, n
 }
 
 // ToRasterizer rasterizes the path using the given rasterizer with dpm the dots-per-millimeter.
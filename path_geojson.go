@@ -0,0 +1,181 @@
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// geoJSONGeometry is a GeoJSON geometry object, see RFC 7946.
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates,omitempty"`
+	Geometries  interface{} `json:"geometries,omitempty"`
+}
+
+// geoJSONObject is a GeoJSON geometry or feature object as read from input.
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+}
+
+// ToGeoJSON returns the path as a GeoJSON geometry object (RFC 7946). The path is flattened with the given tolerance (see Flatten), or Tolerance if it is not positive. Closed subpaths are converted to a Polygon or MultiPolygon following the NonZero fill rule, so that subpaths within a subpath of the opposite orientation are holes, and open subpaths are converted to a LineString or MultiLineString. Following the right-hand rule, exterior rings are counter clockwise and interior rings are clockwise. A path with both closed and open subpaths results in a GeometryCollection. Coordinates are not projected.
+func (p *Path) ToGeoJSON(tolerance float64) ([]byte, error) {
+	g := p.ogcGeometry(tolerance)
+
+	var geometry geoJSONGeometry
+	if 0 < len(g.polygons) && 0 < len(g.lines) {
+		geometry.Type = "GeometryCollection"
+		geometry.Geometries = []geoJSONGeometry{geoJSONPolygons(g.polygons), geoJSONLines(g.lines)}
+	} else if 0 < len(g.polygons) {
+		geometry = geoJSONPolygons(g.polygons)
+	} else if 0 < len(g.lines) {
+		geometry = geoJSONLines(g.lines)
+	} else {
+		geometry.Type = "GeometryCollection"
+		geometry.Geometries = []geoJSONGeometry{}
+	}
+	return json.Marshal(geometry)
+}
+
+func geoJSONPolygons(polygons [][][]Point) geoJSONGeometry {
+	coords := make([][][][2]float64, len(polygons))
+	for i, polygon := range polygons {
+		coords[i] = make([][][2]float64, len(polygon))
+		for j, ring := range polygon {
+			coords[i][j] = geoJSONPositions(ring)
+		}
+	}
+	if len(coords) == 1 {
+		return geoJSONGeometry{Type: "Polygon", Coordinates: coords[0]}
+	}
+	return geoJSONGeometry{Type: "MultiPolygon", Coordinates: coords}
+}
+
+func geoJSONLines(lines [][]Point) geoJSONGeometry {
+	coords := make([][][2]float64, len(lines))
+	for i, line := range lines {
+		coords[i] = geoJSONPositions(line)
+	}
+	if len(coords) == 1 {
+		return geoJSONGeometry{Type: "LineString", Coordinates: coords[0]}
+	}
+	return geoJSONGeometry{Type: "MultiLineString", Coordinates: coords}
+}
+
+func geoJSONPositions(coords []Point) [][2]float64 {
+	positions := make([][2]float64, len(coords))
+	for i, coord := range coords {
+		positions[i] = [2]float64{coord.X, coord.Y}
+	}
+	return positions
+}
+
+// PathFromGeoJSON parses a GeoJSON geometry or feature object (RFC 7946) and returns the path of its geometry. LineString, MultiLineString, Polygon, MultiPolygon, and GeometryCollection geometries are supported, where line strings are converted to open subpaths and polygon rings to closed subpaths. Coordinates are not projected and altitudes are ignored. A feature without geometry returns an empty path.
+func PathFromGeoJSON(data []byte) (*Path, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("bad GeoJSON: %w", err)
+	}
+	if obj.Type == "Feature" {
+		if obj.Geometry == nil {
+			return &Path{}, nil
+		}
+		obj = *obj.Geometry
+	}
+
+	p := &Path{}
+	if err := p.appendGeoJSON(obj); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Path) appendGeoJSON(obj geoJSONObject) error {
+	var err error
+	switch obj.Type {
+	case "LineString":
+		var coords [][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			err = p.appendGeoJSONPositions(coords, false)
+		}
+	case "MultiLineString":
+		var coords [][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			for _, line := range coords {
+				if err = p.appendGeoJSONPositions(line, false); err != nil {
+					break
+				}
+			}
+		}
+	case "Polygon":
+		var coords [][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			for _, ring := range coords {
+				if err = p.appendGeoJSONPositions(ring, true); err != nil {
+					break
+				}
+			}
+		}
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+		Polygons:
+			for _, polygon := range coords {
+				for _, ring := range polygon {
+					if err = p.appendGeoJSONPositions(ring, true); err != nil {
+						break Polygons
+					}
+				}
+			}
+		}
+	case "GeometryCollection":
+		for _, geometry := range obj.Geometries {
+			if err = p.appendGeoJSON(geometry); err != nil {
+				return err
+			}
+		}
+	case "":
+		return fmt.Errorf("bad GeoJSON: missing type")
+	default:
+		return fmt.Errorf("bad GeoJSON: unsupported geometry type '%s'", obj.Type)
+	}
+	if err != nil && obj.Coordinates == nil {
+		return fmt.Errorf("bad GeoJSON: missing coordinates for %s", obj.Type)
+	} else if _, ok := err.(*json.UnmarshalTypeError); ok {
+		return fmt.Errorf("bad GeoJSON: invalid coordinates for %s", obj.Type)
+	}
+	return err
+}
+
+// appendGeoJSONPositions appends the positions as a subpath. Rings must be closed and are closed in the path as well.
+func (p *Path) appendGeoJSONPositions(positions [][]float64, ring bool) error {
+	coords := make([]Point, len(positions))
+	for i, position := range positions {
+		if len(position) < 2 {
+			return fmt.Errorf("bad GeoJSON: position must have at least two elements")
+		}
+		coords[i] = Point{position[0], position[1]}
+	}
+
+	if ring {
+		if len(coords) < 4 {
+			return fmt.Errorf("bad GeoJSON: ring must have at least four positions")
+		} else if !coords[0].Equals(coords[len(coords)-1]) {
+			return fmt.Errorf("bad GeoJSON: ring must be closed")
+		}
+		coords = coords[:len(coords)-1]
+	} else if len(coords) < 2 {
+		return fmt.Errorf("bad GeoJSON: line string must have at least two positions")
+	}
+
+	p.MoveTo(coords[0].X, coords[0].Y)
+	for _, coord := range coords[1:] {
+		p.LineTo(coord.X, coord.Y)
+	}
+	if ring {
+		p.Close()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathToGeoJSON(t *testing.T) {
+	var tts = []struct {
+		p       string
+		geojson string
+	}{
+		{"", `{"type":"GeometryCollection","geometries":[]}`},
+		{"M0 0L10 0L10 10", `{"type":"LineString","coordinates":[[0,0],[10,0],[10,10]]}`},
+		{"M0 0L10 0M0 5L10 5", `{"type":"MultiLineString","coordinates":[[[0,0],[10,0]],[[0,5],[10,5]]]}`},
+		{"M0 0L0 10L10 10L10 0zM2 2L8 2L8 8L2 8z", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[2,2],[2,8],[8,8],[8,2],[2,2]]]}`},
+		{"M0 0L5 0L5 5zM10 0L15 0L15 5z", `{"type":"MultiPolygon","coordinates":[[[[0,0],[5,0],[5,5],[0,0]]],[[[10,0],[15,0],[15,5],[10,0]]]]}`},
+		{"M0 0L5 0L5 5zM10 0L15 0", `{"type":"GeometryCollection","geometries":[{"type":"Polygon","coordinates":[[[0,0],[5,0],[5,5],[0,0]]]},{"type":"LineString","coordinates":[[10,0],[15,0]]}]}`},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			b, err := MustParseSVG(tt.p).ToGeoJSON(0.0)
+			test.Error(t, err)
+			test.String(t, string(b), tt.geojson)
+		})
+	}
+}
+
+func TestPathGeoJSONRoundTrip(t *testing.T) {
+	var tts = []string{
+		"M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z", // donut
+		"M0 0L10 0L10 10M20 0L30 0M0 -5L-5 -10",  // multi-part line
+		"M0 0L5 0L5 5zM10 0L15 0",
+	}
+	for _, tt := range tts {
+		t.Run(tt, func(t *testing.T) {
+			p := MustParseSVG(tt)
+			b, err := p.ToGeoJSON(0.0)
+			test.Error(t, err)
+			q, err := PathFromGeoJSON(b)
+			test.Error(t, err)
+			test.T(t, q, p)
+		})
+	}
+
+	// curved donut
+	p := Circle(5.0).Append(Circle(2.0).Reverse())
+	b, err := p.ToGeoJSON(0.01)
+	test.Error(t, err)
+	q, err := PathFromGeoJSON(b)
+	test.Error(t, err)
+	test.T(t, len(q.Split()), 2)
+	test.That(t, q.Interior(0.0, 0.0, NonZero) == false)
+	test.That(t, q.Interior(3.5, 0.0, NonZero))
+}
+
+func TestPathFromGeoJSON(t *testing.T) {
+	var tts = []struct {
+		geojson string
+		p       string
+	}{
+		{`{"type":"LineString","coordinates":[[0,0],[10,0,100]]}`, "M0 0L10 0"},
+		{`{"type":"Feature","properties":{"name":"a"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,0]]]}}`, "M0 0L10 0L10 10z"},
+		{`{"type":"Feature","properties":null,"geometry":null}`, ""},
+		{`{"type":"MultiPolygon","coordinates":[]}`, ""},
+		{`{"type":"GeometryCollection","geometries":[{"type":"LineString","coordinates":[[0,0],[10,0]]},{"type":"MultiLineString","coordinates":[[[0,5],[10,5]]]}]}`, "M0 0L10 0M0 5L10 5"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.geojson, func(t *testing.T) {
+			p, err := PathFromGeoJSON([]byte(tt.geojson))
+			test.Error(t, err)
+			test.T(t, p, MustParseSVG(tt.p))
+		})
+	}
+}
+
+func TestPathFromGeoJSONErrors(t *testing.T) {
+	var tts = []struct {
+		geojson string
+		err     string
+	}{
+		{`{"type":"Point","coordinates":[0,0]}`, "bad GeoJSON: unsupported geometry type 'Point'"},
+		{`{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[0,0]]}}`, "bad GeoJSON: unsupported geometry type 'MultiPoint'"},
+		{`{"coordinates":[[0,0],[1,1]]}`, "bad GeoJSON: missing type"},
+		{`{"type":"LineString"}`, "bad GeoJSON: missing coordinates for LineString"},
+		{`{"type":"LineString","coordinates":[0,0]}`, "bad GeoJSON: invalid coordinates for LineString"},
+		{`{"type":"LineString","coordinates":[[0,0],[1]]}`, "bad GeoJSON: position must have at least two elements"},
+		{`{"type":"LineString","coordinates":[[0,0]]}`, "bad GeoJSON: line string must have at least two positions"},
+		{`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, "bad GeoJSON: ring must be closed"},
+		{`{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, "bad GeoJSON: ring must have at least four positions"},
+		{`{"type":`, "bad GeoJSON: unexpected end of JSON input"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.geojson, func(t *testing.T) {
+			_, err := PathFromGeoJSON([]byte(tt.geojson))
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
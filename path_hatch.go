@@ -0,0 +1,40 @@
+package canvas
+
+import "sort"
+
+// HatchFill returns parallel line segments at an angle of angleDeg degrees counter clockwise and at a distance of spacing from each other that fill the interior of the path using the NonZero fill rule, so that holes are excluded. Open subpaths are implicitly closed. The lines are clipped exactly at the path's boundary, also for Bézier and arc segments. The first line is at a distance of spacing from the boundary of the path's bounding box (in the hatching direction) and lines that coincide with that boundary are not included, so that hatching a rectangle of height h with horizontal lines of spacing 1 results in h-1 lines. The returned path is meant to be stroked, and cross hatching can be obtained by calling HatchFill twice.
+func (p *Path) HatchFill(angleDeg, spacing float64) *Path {
+	if spacing <= 0.0 || p.Empty() {
+		return &Path{}
+	}
+
+	// rotate the path so that the hatching lines are horizontal
+	q := p.Transform(Identity.Rotate(-angleDeg))
+	bounds := q.Bounds()
+
+	hatch := &Path{}
+	for y := bounds.Y + spacing; y < bounds.Y+bounds.H-Epsilon; y += spacing {
+		crossings := crossingsHorizontal(q, y)
+		sort.Slice(crossings, func(i, j int) bool {
+			return crossings[i].x < crossings[j].x
+		})
+
+		// spans where the winding number is non-zero are inside, touching spans are merged
+		x0, winding, inside := 0.0, 0, false
+		for i, crossing := range crossings {
+			if !inside {
+				x0 = crossing.x
+				inside = true
+			}
+			winding += crossing.dir
+			if winding == 0 && (i+1 == len(crossings) || Epsilon < crossings[i+1].x-crossing.x) {
+				if Epsilon < crossing.x-x0 {
+					hatch.MoveTo(x0, y)
+					hatch.LineTo(crossing.x, y)
+				}
+				inside = false
+			}
+		}
+	}
+	return hatch.Transform(Identity.Rotate(angleDeg))
+}
@@ -0,0 +1,46 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathHatchFill(t *testing.T) {
+	var tts = []struct {
+		orig    string
+		angle   float64
+		spacing float64
+		hatch   string
+	}{
+		{"M0 0H4V3H0z", 0.0, 1.0, "M0 1L4 1M0 2L4 2"},
+		{"M0 0H4V3H0", 0.0, 1.0, "M0 1L4 1M0 2L4 2"},
+		{"M0 0V3H4V0z", 0.0, 1.0, "M0 1L4 1M0 2L4 2"},
+		{"M0 0H4V3H0z", 90.0, 1.0, "M3 0L3 3M2 0L2 3M1 0L1 3"},
+		{"M0 0H4V3H0z", 0.0, 0.0, ""},
+		{"M0 0H6V6H0zM2 2V4H4V2z", 0.0, 1.0, "M0 1L6 1M0 2L6 2M0 3L2 3M4 3L6 3M0 4L2 4M4 4L6 4M0 5L6 5"},   // hole
+		{"M0 0H6V6H0zM2 2H4V4H2z", 0.0, 1.0, "M0 1L6 1M0 2L6 2M0 3L6 3M0 4L6 4M0 5L6 5"},                   // same winding
+		{"M0 0L2 2L4 0L4 4L0 4z", 0.0, 1.0, "M0 1L1 1M3 1L4 1M0 2L4 2M0 3L4 3"},                            // vertex on line
+		{"M0 0A2 2 0 0 1 0 4A2 2 0 0 1 0 0z", 0.0, 1.0, "M-1.7321 1L1.7321 1M-2 2L2 2M-1.7321 3L1.7321 3"}, // arcs
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).HatchFill(tt.angle, tt.spacing), MustParseSVG(tt.hatch))
+		})
+	}
+
+	// lines are clipped exactly at the curve
+	p := MustParseSVG("M0 0C0 4 4 4 4 0z").HatchFill(0.0, 0.5)
+	p.Iterate(func(Point, Point) {}, func(p0, p1 Point) {
+		for _, pos := range []Point{p0, p1} {
+			t0, t1, t2 := solveCubicFormula(0.0, -12.0, 12.0, -pos.Y)
+			for _, t := range []float64{t0, t1, t2} {
+				if !math.IsNaN(t) && 0.0 <= t && t <= 1.0 && Equal(cubicBezierPos(Point{0.0, 0.0}, Point{0.0, 4.0}, Point{4.0, 4.0}, Point{4.0, 0.0}, t).X, pos.X) {
+					return
+				}
+			}
+		}
+		test.Fail(t, "hatch line not clipped at the curve", p0, p1)
+	}, func(Point, Point, Point) {}, func(Point, Point, Point, Point) {}, func(Point, float64, float64, float64, bool, bool, Point) {}, func(Point, Point) {})
+}
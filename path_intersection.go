@@ -62,3 +62,114 @@ func intersectionCircleCircle(c0 Point, r0 float64, c1 Point, r1 float64) (Point
 	i2 := Point{c1.Y - c0.Y, c0.X - c1.X}.Mul(c)
 	return i0.Add(i1).Add(i2), i0.Add(i1).Sub(i2), true
 }
+
+// crossing is where a path crosses a horizontal line, with dir +1 when the path runs upwards and -1 when it runs downwards.
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// crossingsHorizontal returns all crossings of the path with the horizontal line at y, which are found exactly for Bézier and arc segments. Open subpaths are implicitly closed. Points where the path touches the line are not counted and vertices on the line are counted once, so that the running sum of the directions of the sorted crossings is the winding number.
+func crossingsHorizontal(p *Path, y float64) []crossing {
+	const eps = 1e-9
+	crossings := []crossing{}
+	add := func(t, x, dy float64) {
+		if t < -eps || 1.0+eps < t || math.IsNaN(t) {
+			return
+		} else if t < eps {
+			if dy < 0.0 {
+				crossings = append(crossings, crossing{x, -1})
+			}
+		} else if 1.0-eps < t {
+			if 0.0 < dy {
+				crossings = append(crossings, crossing{x, 1})
+			}
+		} else if 0.0 < dy {
+			crossings = append(crossings, crossing{x, 1})
+		} else if dy < 0.0 {
+			crossings = append(crossings, crossing{x, -1})
+		}
+	}
+	line := func(p0, p1 Point) {
+		if p0.Y != p1.Y {
+			t := (y - p0.Y) / (p1.Y - p0.Y)
+			add(t, p0.Interpolate(p1, t).X, p1.Y-p0.Y)
+		}
+	}
+	cube := func(p0, p1, p2, p3 Point) {
+		a := -p0.Y + 3.0*p1.Y - 3.0*p2.Y + p3.Y
+		b := 3.0*p0.Y - 6.0*p1.Y + 3.0*p2.Y
+		c := -3.0*p0.Y + 3.0*p1.Y
+		d := p0.Y - y
+		t1, t2, t3 := solveCubicFormula(a, b, c, d)
+		for _, t := range []float64{t1, t2, t3} {
+			if !math.IsNaN(t) {
+				add(t, cubicBezierPos(p0, p1, p2, p3, t).X, cubicBezierDeriv(p0, p1, p2, p3, t).Y)
+			}
+		}
+	}
+	arc := func(p0 Point, rx, ry, phi float64, large, sweep bool, p1 Point) {
+		cx, cy, theta0, theta1 := ellipseToCenter(p0.X, p0.Y, rx, ry, phi, large, sweep, p1.X, p1.Y)
+		dtheta := theta1 - theta0
+		if dtheta == 0.0 {
+			return
+		}
+
+		// solve A*cos(theta) + B*sin(theta) = y-cy
+		sinphi, cosphi := math.Sincos(phi)
+		A, B := rx*sinphi, ry*cosphi
+		R := math.Hypot(A, B)
+		if R < math.Abs(y-cy) {
+			return
+		}
+		alpha := math.Atan2(B, A)
+		beta := math.Acos(math.Max(-1.0, math.Min(1.0, (y-cy)/R)))
+		for _, theta := range []float64{alpha + beta, alpha - beta} {
+			dt := angleNorm(theta - theta0)
+			if dtheta < 0.0 {
+				dt = angleNorm(theta0 - theta)
+			}
+			if 2.0*math.Pi-eps < dt {
+				dt = 0.0
+			}
+			t := dt / math.Abs(dtheta)
+			theta = theta0 + t*dtheta
+			add(t, ellipsePos(rx, ry, phi, cx, cy, theta).X, ellipseDeriv(rx, ry, phi, sweep, theta).Y)
+			if beta == 0.0 {
+				break
+			}
+		}
+	}
+
+	var start, end, moveTo Point
+	closed := true
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		start, end = end, Point{p.d[i-3], p.d[i-2]}
+		switch cmd {
+		case moveToCmd:
+			if !closed {
+				line(start, moveTo)
+			}
+			moveTo = end
+			closed = true
+			continue
+		case lineToCmd, closeCmd:
+			line(start, end)
+		case quadToCmd:
+			cp1, cp2 := quadraticToCubicBezier(start, Point{p.d[i-5], p.d[i-4]}, end)
+			cube(start, cp1, cp2, end)
+		case cubeToCmd:
+			cube(start, Point{p.d[i-7], p.d[i-6]}, Point{p.d[i-5], p.d[i-4]}, end)
+		case arcToCmd:
+			large, sweep := toArcFlags(p.d[i-4])
+			arc(start, p.d[i-7], p.d[i-6], p.d[i-5], large, sweep, end)
+		}
+		closed = cmd == closeCmd
+	}
+	if !closed {
+		line(end, moveTo)
+	}
+	return crossings
+}
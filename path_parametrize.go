@@ -0,0 +1,171 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// PathParametrization is a precomputed arc-length table of a path that allows to find the position and direction at a certain length along the path in O(log n). It is immutable and thus safe for use by concurrent readers.
+type PathParametrization struct {
+	d       []float64
+	pieces  []parametrizationPiece
+	lengths []float64 // cumulative length at the end of each piece
+}
+
+// parametrizationPiece is part of a segment between parametric values t0 and t1, for arcs these are the angles in radians.
+type parametrizationPiece struct {
+	i      int // index of the segment's command in d
+	start  Point
+	center Point // only for arcs
+	t0, t1 float64
+}
+
+// Parametrize returns an arc-length table of the path, where each segment is subdivided until the position at a given length deviates less than tolerance from the exact position along the path. If tolerance is not positive, Tolerance is used. Subpaths are traversed one after another, with MoveTo commands adding no length.
+func (p *Path) Parametrize(tolerance float64) *PathParametrization {
+	if tolerance <= 0.0 {
+		tolerance = Tolerance
+	}
+	pp := &PathParametrization{
+		d: append([]float64{}, p.d...),
+	}
+
+	total := 0.0
+	var start, end Point
+	for i := 0; i < len(pp.d); {
+		cmd := pp.d[i]
+		switch cmd {
+		case moveToCmd:
+			end = Point{pp.d[i+1], pp.d[i+2]}
+		case lineToCmd, closeCmd:
+			end = Point{pp.d[i+1], pp.d[i+2]}
+			if length := end.Sub(start).Length(); 0.0 < length {
+				total += length
+				pp.pieces = append(pp.pieces, parametrizationPiece{i, start, Point{}, 0.0, 1.0})
+				pp.lengths = append(pp.lengths, total)
+			}
+		case quadToCmd, cubeToCmd, arcToCmd:
+			piece := parametrizationPiece{i: i, start: start, t0: 0.0, t1: 1.0}
+			if cmd == quadToCmd {
+				end = Point{pp.d[i+3], pp.d[i+4]}
+			} else if cmd == cubeToCmd {
+				end = Point{pp.d[i+5], pp.d[i+6]}
+			} else {
+				rx, ry, phi := pp.d[i+1], pp.d[i+2], pp.d[i+3]
+				large, sweep := toArcFlags(pp.d[i+4])
+				end = Point{pp.d[i+5], pp.d[i+6]}
+				piece.center.X, piece.center.Y, piece.t0, piece.t1 = ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+			}
+			total = pp.subdivide(piece, total, tolerance, 0)
+		}
+		i += cmdLen(cmd)
+		start = end
+	}
+	return pp
+}
+
+// subdivide adds the piece, subdividing it recursively until linear interpolation of the parametric value by length is accurate within tolerance. It returns the new total length.
+func (pp *PathParametrization) subdivide(piece parametrizationPiece, total, tolerance float64, depth int) float64 {
+	speed := func(t float64) float64 {
+		return pp.deriv(piece, t).Length()
+	}
+
+	// compare the lengths at the quarters with those of linear interpolation
+	dt := (piece.t1 - piece.t0) / 4.0
+	var lengths [4]float64
+	for k := 0; k < 4; k++ {
+		t := piece.t0 + float64(k)*dt
+		lengths[k] = math.Abs(gaussLegendre7(speed, t, t+dt))
+	}
+	length := lengths[0] + lengths[1] + lengths[2] + lengths[3]
+	deviation := math.Max(math.Abs(lengths[0]-length/4.0), math.Abs(lengths[3]-length/4.0))
+	deviation = math.Max(deviation, math.Abs(lengths[0]+lengths[1]-length/2.0))
+	if depth < 16 && tolerance < deviation {
+		tm := (piece.t0 + piece.t1) / 2.0
+		piece0, piece1 := piece, piece
+		piece0.t1, piece1.t0 = tm, tm
+		total = pp.subdivide(piece0, total, tolerance, depth+1)
+		return pp.subdivide(piece1, total, tolerance, depth+1)
+	}
+	if 0.0 < length {
+		total += length
+		pp.pieces = append(pp.pieces, piece)
+		pp.lengths = append(pp.lengths, total)
+	}
+	return total
+}
+
+// pos returns the position at parametric value t of the piece's segment.
+func (pp *PathParametrization) pos(piece parametrizationPiece, t float64) Point {
+	i, start := piece.i, piece.start
+	switch pp.d[i] {
+	case lineToCmd, closeCmd:
+		return start.Interpolate(Point{pp.d[i+1], pp.d[i+2]}, t)
+	case quadToCmd:
+		return quadraticBezierPos(start, Point{pp.d[i+1], pp.d[i+2]}, Point{pp.d[i+3], pp.d[i+4]}, t)
+	case cubeToCmd:
+		return cubicBezierPos(start, Point{pp.d[i+1], pp.d[i+2]}, Point{pp.d[i+3], pp.d[i+4]}, Point{pp.d[i+5], pp.d[i+6]}, t)
+	case arcToCmd:
+		return ellipsePos(pp.d[i+1], pp.d[i+2], pp.d[i+3], piece.center.X, piece.center.Y, t)
+	}
+	return start
+}
+
+// deriv returns the derivative in the direction of the path at parametric value t of the piece's segment.
+func (pp *PathParametrization) deriv(piece parametrizationPiece, t float64) Point {
+	i, start := piece.i, piece.start
+	switch pp.d[i] {
+	case lineToCmd, closeCmd:
+		return Point{pp.d[i+1], pp.d[i+2]}.Sub(start)
+	case quadToCmd:
+		return quadraticBezierDeriv(start, Point{pp.d[i+1], pp.d[i+2]}, Point{pp.d[i+3], pp.d[i+4]}, t)
+	case cubeToCmd:
+		return cubicBezierDeriv(start, Point{pp.d[i+1], pp.d[i+2]}, Point{pp.d[i+3], pp.d[i+4]}, Point{pp.d[i+5], pp.d[i+6]}, t)
+	case arcToCmd:
+		rx, ry, phi := pp.d[i+1], pp.d[i+2], pp.d[i+3]
+		_, sweep := toArcFlags(pp.d[i+4])
+		return ellipseDeriv(rx, ry, phi, sweep, t)
+	}
+	return Point{}
+}
+
+// Length returns the total length of the path.
+func (pp *PathParametrization) Length() float64 {
+	if len(pp.lengths) == 0 {
+		return 0.0
+	}
+	return pp.lengths[len(pp.lengths)-1]
+}
+
+// At returns the position and the unit tangent in the direction of the path at length d along the path. Lengths outside the range [0,Length()] are clamped. For an empty path it returns zero points.
+func (pp *PathParametrization) At(d float64) (Point, Point) {
+	if len(pp.pieces) == 0 {
+		if 0 < len(pp.d) {
+			return Point{pp.d[1], pp.d[2]}, Point{}
+		}
+		return Point{}, Point{}
+	}
+
+	d = math.Max(0.0, math.Min(d, pp.Length()))
+	k := sort.SearchFloat64s(pp.lengths, d)
+	if k == len(pp.pieces) {
+		k--
+	}
+	piece := pp.pieces[k]
+
+	d0 := 0.0
+	if 0 < k {
+		d0 = pp.lengths[k-1]
+	}
+	t := piece.t0
+	if d0 < pp.lengths[k] {
+		t += (piece.t1 - piece.t0) * (d - d0) / (pp.lengths[k] - d0)
+	}
+
+	pos := pp.pos(piece, t)
+	tangent := pp.deriv(piece, t)
+	if tangent.IsZero() {
+		// cusp or coinciding control points
+		tangent = pp.pos(piece, piece.t1).Sub(pp.pos(piece, piece.t0))
+	}
+	return pos, tangent.Norm(1.0)
+}
@@ -0,0 +1,107 @@
+package canvas
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathParametrize(t *testing.T) {
+	var tts = []struct {
+		p       string
+		d       float64
+		pos     Point
+		tangent Point
+	}{
+		{"", 0.0, Point{}, Point{}},
+		{"M5 5", 1.0, Point{5.0, 5.0}, Point{}},
+		{"M0 0L10 0", 2.5, Point{2.5, 0.0}, Point{1.0, 0.0}},
+		{"M0 0L10 0", -1.0, Point{0.0, 0.0}, Point{1.0, 0.0}},
+		{"M0 0L10 0", 11.0, Point{10.0, 0.0}, Point{1.0, 0.0}},
+		{"M0 0L10 0L10 10", 15.0, Point{10.0, 5.0}, Point{0.0, 1.0}},
+		{"M0 0L10 0M20 0L20 10", 15.0, Point{20.0, 5.0}, Point{0.0, 1.0}},
+		{"M0 0L10 0L10 10z", 20.0 + 5.0*math.Sqrt2, Point{5.0, 5.0}, Point{-math.Sqrt2 / 2.0, -math.Sqrt2 / 2.0}},
+		{"M10 0A10 10 0 0 1 -10 0", 5.0 * math.Pi, Point{0.0, 10.0}, Point{-1.0, 0.0}},
+		{"M10 0A10 10 0 0 0 -10 0", 5.0 * math.Pi, Point{0.0, -10.0}, Point{-1.0, 0.0}},
+		{"M0 0Q5 0 10 0", 7.5, Point{7.5, 0.0}, Point{1.0, 0.0}},
+		{"M0 0C0 0 10 0 10 0", 5.0, Point{5.0, 0.0}, Point{1.0, 0.0}},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.p, " ", tt.d), func(t *testing.T) {
+			pp := MustParseSVG(tt.p).Parametrize(1e-6)
+			pos, tangent := pp.At(tt.d)
+			test.That(t, math.Abs(pos.X-tt.pos.X) < 1e-4 && math.Abs(pos.Y-tt.pos.Y) < 1e-4, pos, "!=", tt.pos)
+			test.That(t, math.Abs(tangent.X-tt.tangent.X) < 1e-6 && math.Abs(tangent.Y-tt.tangent.Y) < 1e-6, tangent, "!=", tt.tangent)
+		})
+	}
+}
+
+func TestPathParametrizeCurves(t *testing.T) {
+	// reference positions by walking densely sampled segments
+	p0, p1, p2, p3 := Point{0.0, 0.0}, Point{30.0, 0.0}, Point{0.0, 20.0}, Point{30.0, 20.0}
+	q1, q2 := Point{40.0, 20.0}, Point{40.0, 30.0}
+	a := Point{60.0, 40.0}
+	cx, cy, theta0, theta1 := EllipseToCenter(q2.X, q2.Y, 20.0, 30.0, 30.0, true, false, a.X, a.Y)
+	segments := []func(float64) Point{
+		func(t float64) Point { return CubicBezierAt(p0, p1, p2, p3, t) },
+		func(t float64) Point { return QuadBezierAt(p3, q1, q2, t) },
+		func(t float64) Point { return EllipsePos(20.0, 30.0, 30.0, cx, cy, theta0+t*(theta1-theta0)) },
+	}
+	var ref []Point
+	for _, f := range segments {
+		for k := 0; k < 100000; k++ {
+			ref = append(ref, f(float64(k)/100000.0))
+		}
+	}
+	ref = append(ref, a)
+	refLength := 0.0
+	for k := 1; k < len(ref); k++ {
+		refLength += ref[k].Sub(ref[k-1]).Length()
+	}
+
+	p := MustParseSVG("M0 0C30 0 0 20 30 20Q40 20 40 30A20 30 30 1 0 60 40")
+	for _, tolerance := range []float64{0.1, 0.01} {
+		pp := p.Parametrize(tolerance)
+		test.That(t, math.Abs(pp.Length()-refLength) < tolerance, pp.Length(), "!=", refLength)
+		k, length := 0, 0.0
+		for d := 0.5; d < pp.Length(); d += 3.0 {
+			for length+ref[k+1].Sub(ref[k]).Length() < d {
+				length += ref[k+1].Sub(ref[k]).Length()
+				k++
+			}
+			pos, tangent := pp.At(d)
+			test.That(t, pos.Sub(ref[k]).Length() < tolerance, "position at", d, ":", pos, "!=", ref[k])
+			test.Float(t, tangent.Length(), 1.0)
+		}
+	}
+}
+
+func BenchmarkPathParametrize(b *testing.B) {
+	p := &Path{}
+	p.MoveTo(0.0, 0.0)
+	for i := 0; i < 2500; i++ {
+		x := float64(4 * i)
+		p.LineTo(x+1.0, 0.0)
+		p.QuadTo(x+1.5, 1.0, x+2.0, 0.0)
+		p.CubeTo(x+2.0, 2.0, x+3.0, -2.0, x+3.0, 0.0)
+		p.ArcTo(0.5, 0.5, 0.0, false, true, x+4.0, 0.0)
+	}
+	length := p.Length()
+
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			d := float64(i%100) / 100.0 * length
+			_ = p.SplitAt(d)[0].Pos()
+		}
+	})
+	b.Run("table", func(b *testing.B) {
+		pp := p.Parametrize(0.01)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d := float64(i%100) / 100.0 * length
+			_, _ = pp.At(d)
+		}
+	})
+}
@@ -117,10 +117,10 @@ func (RoundJoiner) String() string {
 	return "Round"
 }
 
-// MiterJoin connects two path elements by extending the ends of the paths as lines until they meet. If this point is further than 2 mm * (strokeWidth / 2.0) away, this will result in a bevel join.
+// MiterJoin connects two path elements by extending the ends of the paths as lines until they meet. If the miter length exceeds 2 times the stroke width, this will result in a bevel join.
 var MiterJoin Joiner = MiterJoiner{BevelJoin, 2.0}
 
-// MiterClipJoin returns a MiterJoiner with given limit*strokeWidth/2.0 in mm upon which the gapJoiner function will be used. Limit can be NaN so that the gapJoiner is never used.
+// MiterClipJoin returns a MiterJoiner with given limit upon which the gapJoiner function will be used. The limit is the maximum ratio of the miter length to the stroke width, like the miter limit of SVG and PDF. Limit can be NaN so that the gapJoiner is never used.
 func MiterClipJoin(gapJoiner Joiner, limit float64) Joiner {
 	return MiterJoiner{gapJoiner, limit}
 }
@@ -171,10 +171,10 @@ func (j MiterJoiner) String() string {
 	return "MiterClip"
 }
 
-// ArcsJoin connects two path elements by extending the ends of the paths as circle arcs until they meet. If this point is further than 10 mm * (strokeWidth / 2.0) away, this will result in a bevel join.
+// ArcsJoin connects two path elements by extending the ends of the paths as circle arcs, with the curvature of the ends of the paths, until they meet as the SVG 2 arcs line join. If the arcs do not meet it will result in a miter join, and if the miter length exceeds 10 times the stroke width this will result in a bevel join.
 var ArcsJoin Joiner = ArcsJoiner{BevelJoin, 10.0}
 
-// ArcsClipJoin returns an ArcsJoiner with given limit in mm*strokeWidth/2.0 upon which the gapJoiner function will be used. Limit can be NaN so that the gapJoiner is never used.
+// ArcsClipJoin returns an ArcsJoiner with given limit upon which the gapJoiner function will be used. The limit is the maximum ratio of the miter length to the stroke width, like the miter limit of SVG. Limit can be NaN so that the gapJoiner is never used.
 func ArcsClipJoin(gapJoiner Joiner, limit float64) Joiner {
 	return ArcsJoiner{gapJoiner, limit}
 }
@@ -226,8 +226,8 @@ func (j ArcsJoiner) Join(rhs, lhs *Path, halfWidth float64, pivot, n0, n1 Point,
 		i0, i1, ok = intersectionCircleCircle(c0, R0, c1, R1)
 	}
 	if !ok {
-		// no intersection
-		j.GapJoiner.Join(rhs, lhs, halfWidth, pivot, n0, n1, r0, r1)
+		// no intersection, fall back to a miter join as required by SVG 2
+		MiterJoiner{j.GapJoiner, j.Limit}.Join(rhs, lhs, halfWidth, pivot, n0, n1, r0, r1)
 		return
 	}
 
@@ -65,8 +65,10 @@ func TestPathStroke(t *testing.T) {
 		// cut by limit
 		{"M0 0A2 2 0 0 1 2 2L5 2", 2.0, ButtCap, ArcsClipJoin(BevelJoin, 1.0), "M0 -1A3 3 0 0 1 3 2L2 1L5 1L5 3L2 3L1 2A1 1 0 0 0 0 1z"},
 
-		// no intersection
-		{"M0 0A2 2 0 0 1 2 2L5 2", 3.0, ButtCap, ArcsClipJoin(BevelJoin, 10.0), "M0 -1.5A3.5 3.5 0 0 1 3.5 2L2 .5L5 .5L5 3.5L2 3.5L.5 2A.5 .5 0 0 0 0 1.5z"},
+		// no intersection, falls back to a miter join
+		{"M0 0A2 2 0 0 1 2 2L5 2", 3.0, ButtCap, ArcsClipJoin(BevelJoin, 10.0), "M0 -1.5A3.5 3.5 0 0 1 3.5 2L2 .5L5 .5L5 3.5L.5 3.5L.5 2A.5 .5 0 0 0 0 1.5z"},
+		{"M-1 0A1 1 0 0 1 0 0A0.6 0.6 0 0 0 0 1.2", 1.0, ButtCap, ArcsJoin, "M-1.25 -0.4330127A1.5 1.5 0 0 1 0.25 -0.4330127L1.8660254 0.5L0 0.5A0.1 0.1 0 0 0 0 0.7L0 1.7A1.1 1.1 0 0 1 0 -0.5L-0.25 0.4330127A0.5 0.5 0 0 0 -0.75 0.4330127z"},
+		{"M-1 0A1 1 0 0 1 0 0A0.6 0.6 0 0 0 0 1.2", 1.0, ButtCap, ArcsClipJoin(BevelJoin, 3.0), "M-1.25 -0.4330127A1.5 1.5 0 0 1 0.25 -0.4330127L0 0.5A0.1 0.1 0 0 0 0 0.7L0 1.7A1.1 1.1 0 0 1 0 -0.5L-0.25 0.4330127A0.5 0.5 0 0 0 -0.75 0.4330127z"},
 	}
 	for j, tt := range tts {
 		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
@@ -1,11 +1,17 @@
 package canvas
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
+	"math/rand"
 	"os"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/tdewolff/test"
 	"gonum.org/v1/plot"
@@ -37,6 +43,52 @@ func TestPathClosed(t *testing.T) {
 	test.That(t, MustParseSVG("M5 0L5 10z").Closed())
 	test.That(t, !MustParseSVG("M5 0L5 10zM5 10").Closed())
 	test.That(t, MustParseSVG("M5 0L5 10zM5 10z").Closed())
+	test.That(t, !MustParseSVG("M5 0L5 10M5 15L10 15z").Closed())
+	test.That(t, !MustParseSVG("M5 0L5 10zM5 15L10 15").Closed())
+	test.That(t, MustParseSVG("M5 0L5 10zM5 15L10 15z").Closed())
+	test.That(t, MustParseSVG("M5 0L5 10zL10 15z").Closed())
+	test.That(t, !MustParseSVG("M5 0").Closed())
+	test.That(t, !(&Path{}).Closed())
+}
+
+func TestPathSubpathCount(t *testing.T) {
+	var tts = []struct {
+		p         string
+		n         int
+		hasCurves bool
+	}{
+		{"", 0, false},
+		{"M5 0", 1, false},
+		{"L5 0", 1, false},
+		{"L5 0L5 5z", 1, false},
+		{"M5 0L5 10M5 15L10 15z", 2, false},
+		{"M5 0L5 10zL10 15", 2, false},
+		{"M5 0L5 10zM5 10", 2, false},
+		{"M5 0L5 10M5 10M10 10", 2, false},
+		{"M5 0Q10 0 10 10", 1, true},
+		{"M5 0L5 10M5 15C10 15 10 20 5 20z", 2, true},
+		{"L5 0A5 5 0 0 1 10 0", 1, true},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			p := MustParseSVG(tt.p)
+			test.T(t, p.SubpathCount(), tt.n)
+			test.T(t, p.HasCurves(), tt.hasCurves)
+			test.T(t, testing.AllocsPerRun(10, func() {
+				_ = p.SubpathCount()
+				_ = p.HasCurves()
+				_ = p.Closed()
+			}), 0.0)
+		})
+	}
+
+	p := (&Path{}).LineTo(5.0, 0.0)
+	test.T(t, p.SubpathCount(), 1)
+	p.Close()
+	test.That(t, p.Closed())
+	p.LineTo(0.0, 5.0)
+	test.T(t, p.SubpathCount(), 2)
+	test.That(t, !p.Closed())
 }
 
 func TestPathAppend(t *testing.T) {
@@ -55,13 +107,13 @@ func TestPathJoin(t *testing.T) {
 	test.T(t, (&Path{}).Join(MustParseSVG("M5 0L5 10")), MustParseSVG("M5 0L5 10"))
 
 	p := MustParseSVG("M5 0L5 10").Join(MustParseSVG("L10 15"))
-	test.T(t, p, MustParseSVG("M5 0L5 10M0 0L10 15"))
+	test.T(t, p, MustParseSVG("M5 0L5 10L0 0L10 15"))
 
 	p = MustParseSVG("M5 0L5 10").Join(MustParseSVG("M5 10L10 15"))
 	test.T(t, p, MustParseSVG("M5 0L5 10L10 15"))
 
 	p = MustParseSVG("M5 0L5 10").Join(MustParseSVG("L10 15M20 15L25 15"))
-	test.T(t, p, MustParseSVG("M5 0L5 10M0 0L10 15M20 15L25 15"))
+	test.T(t, p, MustParseSVG("M5 0L5 10L0 0L10 15M20 15L25 15"))
 
 	p = MustParseSVG("M5 0L5 10").Join(MustParseSVG("M5 10L10 15M20 15L25 15"))
 	test.T(t, p, MustParseSVG("M5 0L5 10L10 15M20 15L25 15"))
@@ -71,6 +123,75 @@ func TestPathJoin(t *testing.T) {
 
 	p = MustParseSVG("M5 0L10 5").Join(MustParseSVG("L5 5z"))
 	test.T(t, p, MustParseSVG("M5 0L10 5M0 0L5 5z"))
+
+	p = MustParseSVG("M5 0L10 5z").Join(MustParseSVG("M20 0L25 5"))
+	test.T(t, p, MustParseSVG("M5 0L10 5zM20 0L25 5"))
+
+	// connect disjoint polylines
+	p = MustParseSVG("M0 0L10 0L10 10").Join(MustParseSVG("M20 10L20 0L30 0"))
+	test.T(t, p, MustParseSVG("M0 0L10 0L10 10L20 10L20 0L30 0"))
+	test.T(t, len(p.Split()), 1)
+}
+
+func TestPathEditCmds(t *testing.T) {
+	var tts = []struct {
+		orig string
+		edit func(*Path) error
+		res  string
+	}{
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.DeleteCmd(1) }, "M0 0L10 10z"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.DeleteCmd(3) }, "M0 0L10 0L10 10"},
+		{"M0 0L10 0L10 10M20 0L30 0z", func(p *Path) error { return p.DeleteCmd(3) }, "M0 0L10 0L10 10L30 0z"},
+		{"M0 0L10 0M20 0", func(p *Path) error { return p.DeleteCmd(2) }, "M0 0L10 0"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(1, LineToCmd, 5.0, -5.0) }, "M0 0L5 -5L10 0L10 10z"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(2, MoveToCmd, 20.0, 0.0) }, "M0 0L10 0M20 0L10 10z"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(4, LineToCmd, 20.0, 0.0) }, "M0 0L10 0L10 10zM0 0L20 0"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(4, MoveToCmd, 20.0, 0.0) }, "M0 0L10 0L10 10zM20 0"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(3, LineToCmd, 0.0, 10.0) }, "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(2, CloseCmd) }, "M0 0L10 0zM0 0L10 10z"},
+		{"", func(p *Path) error { return p.InsertCmd(0, MoveToCmd, 5.0, 5.0) }, "M5 5"},
+		{"M0 0L10 0L10 10", func(p *Path) error { return p.InsertCmd(3, CloseCmd) }, "M0 0L10 0L10 10z"},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(2, QuadToCmd, 15.0, 5.0, 10.0, 10.0) }, "M0 0L10 0Q15 5 10 10"},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(2, CubeToCmd, 15.0, 5.0, 15.0, 5.0, 10.0, 10.0) }, "M0 0L10 0C15 5 15 5 10 10"},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(2, ArcToCmd, 5.0, 5.0, 0.0, 0.0, 1.0, 10.0, 10.0) }, "M0 0L10 0A5 5 0 0 1 10 10"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.ReplaceCmd(1, MustParseSVG("M0 0L5 -5L10 0")) }, "M0 0L5 -5L10 0L10 10z"},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.ReplaceCmd(0, MustParseSVG("M5 5")) }, "M5 5L10 0L10 10z"},
+		{"M0 0L10 0L10 10zM20 0L30 0", func(p *Path) error { return p.ReplaceCmd(4, MustParseSVG("M20 10")) }, "M0 0L10 0L10 10zM20 10L30 0"},
+	}
+	for i, tt := range tts {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			p := MustParseSVG(tt.orig)
+			test.Error(t, tt.edit(p))
+			test.T(t, p, MustParseSVG(tt.res))
+		})
+	}
+
+	var errs = []struct {
+		orig string
+		edit func(*Path) error
+	}{
+		{"M0 0L10 0", func(p *Path) error { return p.DeleteCmd(-1) }},
+		{"M0 0L10 0", func(p *Path) error { return p.DeleteCmd(2) }},
+		{"M0 0L10 0", func(p *Path) error { return p.DeleteCmd(0) }},
+		{"M0 0L10 0zM20 0L30 0", func(p *Path) error { return p.DeleteCmd(3) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(3, LineToCmd, 20.0, 0.0) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(0, LineToCmd, 20.0, 0.0) }},
+		{"M0 0L10 0L10 10z", func(p *Path) error { return p.InsertCmd(0, MoveToCmd, 5.0, 5.0) }},
+		{"M0 0L10 0M20 0L30 0", func(p *Path) error { return p.InsertCmd(2, MoveToCmd, 5.0, 5.0) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(1, LineToCmd, 20.0) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(1, CloseCmd, 20.0, 0.0) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(1, PathCmd(3)) }},
+		{"M0 0L10 0", func(p *Path) error { return p.InsertCmd(1, ArcToCmd, 5.0, 5.0, 0.0, 2.0, 1.0, 10.0, 10.0) }},
+		{"M0 0L10 0", func(p *Path) error { return p.ReplaceCmd(2, MustParseSVG("L5 5")) }},
+		{"M0 0L10 0", func(p *Path) error { return p.ReplaceCmd(0, nil) }},
+	}
+	for i, tt := range errs {
+		t.Run(fmt.Sprint("error", i), func(t *testing.T) {
+			p := MustParseSVG(tt.orig)
+			test.That(t, tt.edit(p) != nil)
+			test.T(t, p, MustParseSVG(tt.orig))
+		})
+	}
 }
 
 func TestPathCoords(t *testing.T) {
@@ -282,6 +403,46 @@ func TestPathTransform(t *testing.T) {
 	}
 }
 
+func TestPathAppendTransformed(t *testing.T) {
+	marker := MustParseSVG("M0 0L10 0Q15 10 20 0C23 10 27 10 30 0A10 5 0 0 0 10 10z")
+	m := Identity.Rotate(120).Scale(1, -2)
+
+	p := MustParseSVG("M0 0L5 5")
+	test.T(t, p.AppendTransformed(marker, m), p.Append(marker.Transform(m)))
+	test.T(t, p.AppendTransformed(nil, m), p)
+	test.T(t, (&Path{}).AppendTransformed(marker, m), marker.Transform(m))
+	test.T(t, p.AppendAt(marker, 3.0, 4.0), p.Append(marker.Translate(3.0, 4.0)))
+	test.T(t, p.AppendAt(marker, 3.0, 4.0).ToSVG(), p.Append(marker.Translate(3.0, 4.0)).ToSVG())
+	test.T(t, p, MustParseSVG("M0 0L5 5"))
+}
+
+func BenchmarkPathAppendTransformed(b *testing.B) {
+	marker := &Path{}
+	for i := 0; i < 25; i++ {
+		marker.LineTo(float64(i), float64(i%2))
+		marker.QuadTo(float64(i)+0.5, 2.0, float64(i)+1.0, float64(i%2))
+	}
+
+	b.Run("naive", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			p := &Path{}
+			for i := 0; i < 10000; i++ {
+				p = p.Append(marker.Copy().Translate(float64(i), 0.0))
+			}
+		}
+	})
+	b.Run("AppendAt", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			p := &Path{}
+			for i := 0; i < 10000; i++ {
+				p = p.AppendAt(marker, float64(i), 0.0)
+			}
+		}
+	})
+}
+
 func TestPathReplace(t *testing.T) {
 	line := func(p0, p1 Point) *Path {
 		return (&Path{}).MoveTo(p0.X, p0.Y).LineTo(p1.X, p1.Y-5.0)
@@ -388,6 +549,79 @@ func TestPathMarkersAligned(t *testing.T) {
 	}
 }
 
+func TestPathMarkersOrient(t *testing.T) {
+	marker := MustParseSVG("M1 0L-1 1L-1 -1z") // triangle pointing along the x-axis
+
+	p := MustParseSVG("M0 0L10 10L20 0L30 10")
+	ps := p.Markers(marker, marker, marker, true)
+	test.T(t, len(ps), 4)
+	test.T(t, ps[0].StartPos(), Point{math.Sqrt(0.5), math.Sqrt(0.5)})               // along the first segment
+	test.T(t, ps[1].StartPos(), Point{11.0, 10.0})                                   // along the bisector
+	test.T(t, ps[2].StartPos(), Point{21.0, 0.0})                                    // along the bisector
+	test.T(t, ps[3].StartPos(), Point{30.0 + math.Sqrt(0.5), 10.0 + math.Sqrt(0.5)}) // along the last segment
+
+	ps = p.Markers(nil, marker, nil, false)
+	test.T(t, len(ps), 2)
+	test.T(t, ps[0], MustParseSVG("M11 10L9 11L9 9z"))
+	test.T(t, ps[1], MustParseSVG("M21 0L19 1L19 -1z"))
+
+	// control points of curves in the second subpath
+	p = MustParseSVG("M0 0L10 0M0 10Q10 20 20 10")
+	ps = p.Markers(marker, nil, marker, true)
+	test.T(t, len(ps), 4)
+	test.T(t, ps[2].StartPos(), Point{math.Sqrt(0.5), 10.0 + math.Sqrt(0.5)})
+	test.T(t, ps[3].StartPos(), Point{20.0 + math.Sqrt(0.5), 10.0 - math.Sqrt(0.5)})
+}
+
+func TestPathRoundCorners(t *testing.T) {
+	var tts = []struct {
+		orig    string
+		r       float64
+		rounded string
+	}{
+		{"M0 0L10 0L10 10", 0.0, "M0 0L10 0L10 10"},
+		{"M0 0L10 0L10 10", 2.0, "M0 0L8 0A2 2 0 0 1 10 2L10 10"},
+		{"M0 0L10 0L20 0", 2.0, "M0 0L20 0"},
+		{"M0 0L10 0L10 -10", 2.0, "M0 0L8 0A2 2 0 0 0 10 -2L10 -10"},
+		{"M0 0L10 0L10 10z", 1.0, "M2.4142 0L9 0A1 1 0 0 1 10 1L10 7.5858A1 1 0 0 1 8.2929 8.2929L1.7071 1.7071A1 1 0 0 1 2.4142 0z"},
+		{"M0 0L10 0L10 2", 2.0, "M0 0L9 0A1 1 0 0 1 10 1L10 2"},
+		{"M0 0L10 0Q20 0 20 10L20 20L30 20", 2.0, "M0 0L10 0Q20 0 20 10L20 18A2 2 0 0 0 22 20L30 20"},
+		{"M0 0L10 0L10 10M20 0L30 0L30 10", 2.0, "M0 0L8 0A2 2 0 0 1 10 2L10 10M20 0L28 0A2 2 0 0 1 30 2L30 10"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).RoundCorners(tt.r), MustParseSVG(tt.rounded))
+		})
+	}
+
+	test.T(t, Rectangle(10.0, 10.0).RoundCorners(2.0), RoundedRect(0.0, 0.0, 10.0, 10.0, 2.0))
+	test.T(t, Rectangle(4.0, 10.0).RoundCorners(3.0), RoundedRect(0.0, 0.0, 4.0, 10.0, 2.0))
+}
+
+func TestPathArrow(t *testing.T) {
+	var tts = []struct {
+		orig       string
+		start, end ArrowStyle
+		shorten    bool
+		arrow      string
+	}{
+		{"M0 0L10 0", NoArrow, NoArrow, false, "M0 0L10 0"},
+		{"M0 0L10 0", NoArrow, TriangleArrow, false, "M0 0L10 0M10 0L8 1L8 -1z"},
+		{"M0 0L10 0", NoArrow, TriangleArrow, true, "M0 0L8 0M10 0L8 1L8 -1z"},
+		{"M0 0L10 0", TriangleArrow, TriangleArrow, true, "M2 0L8 0M0 0L2 -1L2 1zM10 0L8 1L8 -1z"},
+		{"M0 0L10 0", OpenArrow, BarArrow, true, "M0 0L10 0M2 1L0 0L2 -1M10 -1L10 1"},
+		{"M0 0L10 0", NoArrow, CircleArrow, true, "M0 0L9 0M11 0A1 1 0 0 1 9 0A1 1 0 0 1 11 0z"},
+		{"M0 0L0 10L10 10z", TriangleArrow, TriangleArrow, true, "M0 0L0 10L10 10z"},
+		{"M0 0C0 5 5 10 10 10", TriangleArrow, TriangleArrow, false, "M0 0C0 5 5 10 10 10M0 0L1 2L-1 2zM10 10L8 11L8 9z"},
+		{"M10 0A10 10 0 0 1 0 10", NoArrow, TriangleArrow, false, "M10 0A10 10 0 0 1 0 10M0 10L2 9L2 11z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Arrow(tt.start, tt.end, 2.0, tt.shorten), MustParseSVG(tt.arrow))
+		})
+	}
+}
+
 func TestPathSplit(t *testing.T) {
 	var tts = []struct {
 		orig  string
@@ -455,6 +689,11 @@ func TestPathSplitAt(t *testing.T) {
 			}
 		})
 	}
+
+	// split points at the end of arcs within numerical precision
+	l := Circle(5.0).Length()
+	test.T(t, len(Circle(5.0).SplitAt(l/4.0, l/2.0, 3.0*l/4.0)), 4)
+	test.T(t, len(Circle(5.0).Dash(0.0, 3.927).Split()), 4)
 }
 
 func TestDashCanonical(t *testing.T) {
@@ -531,6 +770,48 @@ func TestPathDash(t *testing.T) {
 	}
 }
 
+func TestPathDashAdjusted(t *testing.T) {
+	var tts = []struct {
+		orig    string
+		offset  float64
+		corners bool
+		d       []float64
+		dashes  string
+	}{
+		{"", 0.0, true, []float64{0.0}, ""},
+		{"L10 0", 0.0, true, []float64{}, "L10 0"},
+		{"L30 0", 0.0, false, []float64{4.0}, "L4 0M8 0L12 0M16 0L20 0M24 0L28 0"},
+		{"L30 0", 0.0, true, []float64{4.0}, "L1.875 0M5.625 0L9.375 0M13.125 0L16.875 0M20.625 0L24.375 0M28.125 0L30 0"},
+		{"L31 0L31 31L0 31z", 0.0, false, []float64{4.0}, "L3.875 0M7.75 0L11.625 0M15.5 0L19.375 0M23.25 0L27.125 0M31 0L31 3.875M31 7.75L31 11.625M31 15.5L31 19.375M31 23.25L31 27.125M31 31L27.125 31M23.25 31L19.375 31M15.5 31L11.625 31M7.75 31L3.875 31M0 31L0 27.125M0 23.25L0 19.375M0 15.5L0 11.625M0 7.75L0 3.875"},
+		{"L30 0L30 30L0 30z", 0.0, true, []float64{4.0}, "M0 1.875L0 0L1.875 0M5.625 0L9.375 0M13.125 0L16.875 0M20.625 0L24.375 0M28.125 0L30 0L30 1.875M30 5.625L30 9.375M30 13.125L30 16.875M30 20.625L30 24.375M30 28.125L30 30L28.125 30M24.375 30L20.625 30M16.875 30L13.125 30M9.375 30L5.625 30M1.875 30L0 30L0 28.125M0 24.375L0 20.625M0 16.875L0 13.125M0 9.375L0 5.625"},
+		{"M15 0L30 0L30 30L0 30L0 0z", 0.0, true, []float64{4.0}, "M13.125 0L16.875 0M20.625 0L24.375 0M28.125 0L30 0L30 1.875M30 5.625L30 9.375M30 13.125L30 16.875M30 20.625L30 24.375M30 28.125L30 30L28.125 30M24.375 30L20.625 30M16.875 30L13.125 30M9.375 30L5.625 30M1.875 30L0 30L0 28.125M0 24.375L0 20.625M0 16.875L0 13.125M0 9.375L0 5.625M0 1.875L0 0L1.875 0M5.625 0L9.375 0"},
+		{"L30 0L30 30L0 30z", 0.0, true, []float64{30.0}, "L15 0M30 0L30 15M30 30L15 30M0 30L0 15"},
+		{"L30 0L30 30L0 30z", 0.0, true, []float64{3.0, 1.0}, "M0 1.40625L0 0L1.40625 0M2.34375 0L5.15625 0M6.09375 0L8.90625 0M9.84375 0L12.65625 0M13.59375 0L16.40625 0M17.34375 0L20.15625 0M21.09375 0L23.90625 0M24.84375 0L27.65625 0M28.59375 0L30 0L30 1.40625M30 2.34375L30 5.15625M30 6.09375L30 8.90625M30 9.84375L30 12.65625M30 13.59375L30 16.40625M30 17.34375L30 20.15625M30 21.09375L30 23.90625M30 24.84375L30 27.65625M30 28.59375L30 30L28.59375 30M27.65625 30L24.84375 30M23.90625 30L21.09375 30M20.15625 30L17.34375 30M16.40625 30L13.59375 30M12.65625 30L9.84375 30M8.90625 30L6.09375 30M5.15625 30L2.34375 30M1.40625 30L0 30L0 28.59375M0 27.65625L0 24.84375M0 23.90625L0 21.09375M0 20.15625L0 17.34375M0 16.40625L0 13.59375M0 12.65625L0 9.84375M0 8.90625L0 6.09375M0 5.15625L0 2.34375"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.orig, tt.corners, tt.d), func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).DashAdjusted(tt.offset, tt.corners, tt.d...), MustParseSVG(tt.dashes))
+		})
+	}
+
+	// dashes are symmetric about every corner of a square
+	for _, dash := range MustParseSVG("L30 0L30 30L0 30z").DashAdjusted(0.0, true, 4.0, 4.0).Split() {
+		coords := dash.Coords()
+		if len(coords) == 3 {
+			test.Float(t, coords[1].Sub(coords[0]).Length(), 1.875)
+			test.Float(t, coords[2].Sub(coords[1]).Length(), 1.875)
+			test.Float(t, coords[1].X*(coords[1].X-30.0), 0.0)
+			test.Float(t, coords[1].Y*(coords[1].Y-30.0), 0.0)
+		} else {
+			test.T(t, len(coords), 2)
+			test.Float(t, coords[1].Sub(coords[0]).Length(), 3.75)
+		}
+	}
+
+	// the dashes of a circle end at the end points of its arcs
+	test.T(t, len(Circle(5.0).DashAdjusted(0.0, false, 4.0).Split()), 4)
+}
+
 func TestPathReverse(t *testing.T) {
 	var tts = []struct {
 		orig string
@@ -584,6 +865,35 @@ func TestPathParseSVG(t *testing.T) {
 		{"A10 10 0 0 0 40 0", "A20 20 0 0 0 40 0"},  // scale ellipse
 		{"A10 5 90 0 0 40 0", "A40 20 90 0 0 40 0"}, // scale ellipse
 		{"A10 5 0 0020 0", "A10 5 0 0 0 20 0"},      // parse boolean flags
+		{" \tM10 0L20 0 ", "M10 0L20 0"},            // leading whitespace
+		{" ", ""},
+
+		// compact arc flags as in minified output of svgo or Illustrator
+		{"M10 10a5 5 0 011.2 3.4", "M10 10a5 5 0 0 1 1.2 3.4"},
+		{"M0 0a2.5 2.5 0 10-5 0", "M0 0a2.5 2.5 0 1 0 -5 0"},
+		{"M0 0A1 1 0 00.5.5", "M0 0A1 1 0 0 0 0.5 0.5"},
+		{"M0 0a5,5,0,1,1,10,0", "M0 0a5 5 0 1 1 10 0"},
+		{"M0 0a5 5 0 01-3-4", "M0 0a5 5 0 0 1 -3 -4"},
+		{"M0 0a1 1 0 011 1 1 1 0 011 1", "M0 0a1 1 0 0 1 1 1a1 1 0 0 1 1 1"},
+		{"M0 0a1 1 0 0111 1", "M0 0a1 1 0 0 1 11 1"},
+
+		// number notation as exported by d3 and CAD tools
+		{"M1e-5 -2.5E3", "M0.00001 -2500"},
+		{"M1E+2 3e+0", "M100 3"},
+		{"M.5.5L-.5-.5", "M0.5 0.5L-0.5 -0.5"},
+		{"M5-3L+5+3", "M5 -3L5 3"},
+		{"M1.5e-3-2", "M0.0015 -2"},
+		{"M1e2.5", "M100 0.5"},
+		{"M1-.5e1", "M1 -5"},
+		{"M0.0.0", "M0 0"},
+		{"M0,0L10,0,10,10", "M0 0L10 0L10 10"},
+		{"M0 0c.5.5 1.5-.5 2 0s1.5.5 2 0", "M0 0C0.5 0.5 1.5 -0.5 2 0C2.5 0.5 3.5 0.5 4 0"},
+
+		// implicit LineTo after MoveTo
+		{"M0 0 10 0 10 10z", "M0 0L10 0L10 10z"},
+		{"m0 0 10 0 0 10z", "m0 0l10 0l0 10z"},
+		{"M5 5 10 0 0 10zm20 0 5 5", "M5 5L10 0L0 10zm20 0l5 5"},
+		{"m5 5 10 0 0 10zm20 0 5 5", "m5 5l10 0l0 10zm20 0l5 5"},
 
 		// go-fuzz
 		{"V0 ", ""},
@@ -606,6 +916,21 @@ func TestPathParseSVGErrors(t *testing.T) {
 		{"MM", "bad path: sets of 2 numbers should follow command 'M' at position 2"},
 		{"A10 10 000 20 0", "bad path: largeArc and sweep flags should be 0 or 1 in command 'A' at position 12"},
 		{"A10 10 0 23 20 0", "bad path: largeArc and sweep flags should be 0 or 1 in command 'A' at position 10"},
+		{"M0 0#", "bad path: unknown command '#' at position 5"},
+		{"M0 0X5", "bad path: unknown command 'X' at position 5"},
+		{"M0 0 .", "bad path: unknown command '.' at position 6"},
+		{"M0 0 z 5", "bad path: unknown command '5' at position 8"},
+		{"M0 0L5", "bad path: sets of 2 numbers should follow command 'L' at position 7"},
+		{"M0 0L5 5 6", "bad path: sets of 2 numbers should follow command 'L' at position 11"},
+		{"M0 0A5 5 0 1", "bad path: largeArc and sweep flags should be 0 or 1 in command 'A' at position 13"},
+		{"M0 0H", "bad path: number should follow command 'H' at position 6"},
+		{"M--5 0", "bad path: sets of 2 numbers should follow command 'M' at position 2"},
+		{"M1e 5", "bad path: sets of 2 numbers should follow command 'M' at position 3"},
+		{"M.e1 0", "bad path: sets of 2 numbers should follow command 'M' at position 2"},
+		{"M-. 0", "bad path: sets of 2 numbers should follow command 'M' at position 2"},
+		{"M1e500 0", "bad path: number out of range in command 'M' at position 2"},
+		{"M0 0a1 1 0 21 1 1", "bad path: largeArc and sweep flags should be 0 or 1 in command 'a' at position 12"},
+		{"M0 0a1 1 0 0 1.5 1 1", "bad path: sets of 7 numbers should follow command 'a' at position 21"},
 
 		// go-fuzz
 		{"V4-z\n0ìGßIzØ", "bad path: unknown command '-' at position 3"},
@@ -622,6 +947,111 @@ func TestPathParseSVGErrors(t *testing.T) {
 	}
 }
 
+func TestPathParseSVGTermination(t *testing.T) {
+	inputs := []string{}
+	for _, valid := range []string{
+		"M10 0L20 0H30V10C40 10 50 10 50 0Q55 10 60 0A5 5 0 0 0 70 0Z",
+		"m10 0l10 0h10v10c10 0 20 0 20 -10s10 10 10 0q5 10 10 0t10 0a5 5 0 1 1 10 0z",
+		"M.5.5-1e2,3E-1 2 3L-.5-.5",
+	} {
+		// all truncations of valid paths
+		for n := 0; n <= len(valid); n++ {
+			inputs = append(inputs, valid[:n])
+		}
+	}
+
+	// garbage mixed with path data characters
+	alphabet := "MmZzLlHhVvCcSsQqTtAaXx#0123456789.-+eE ,\n\t\x00\xff"
+	r := rand.New(rand.NewSource(42))
+	for n := 0; n < 5000; n++ {
+		b := make([]byte, r.Intn(32))
+		for i := range b {
+			if r.Intn(4) == 0 {
+				b[i] = byte(r.Intn(256))
+			} else {
+				b[i] = alphabet[r.Intn(len(alphabet))]
+			}
+		}
+		inputs = append(inputs, string(b))
+	}
+
+	done := make(chan bool)
+	go func() {
+		for _, input := range inputs {
+			p, err := ParseSVG(input)
+			if (p == nil) == (err == nil) {
+				t.Errorf("%q: either path or error must be nil", input)
+			}
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		test.Fail(t, "ParseSVG did not terminate")
+	}
+}
+
+func TestPathParseSVGReader(t *testing.T) {
+	inputs := []string{
+		"",
+		" ",
+		"M10 0L20 0H30V10C40 10 50 10 50 0Q55 10 60 0A5 5 0 0 0 70 0Z",
+		"m10 0l10 0h10v10c10 0 20 0 20 -10s10 10 10 0q5 10 10 0t10 0a5 5 0 1 1 10 0z",
+		"M.5.5-1e2,3E-1 2 3L-.5-.5",
+		"M1.5e-3-2 10 10 20 20",
+		"M10 10a5 5 0 011.2 3.4a2.5 2.5 0 10-5 0",
+		"M0 0 10 0 10 10zm5 5 1 1",
+		"M0 0L12345.6789e-2 987654321",
+
+		// errors
+		"5",
+		"M0 0#",
+		"M0 0L5",
+		"M0 0A5 5 0 1",
+		"M0 0a1 1 0 21 1 1",
+		"M1e500 0",
+		"M0 0L5 5 6",
+	}
+	r := rand.New(rand.NewSource(42))
+	for n := 0; n < 500; n++ {
+		b := make([]byte, r.Intn(32))
+		for i := range b {
+			b[i] = "MmZzLlHhVvCcSsQqTtAa#0123456789.-+eE ,"[r.Intn(38)]
+		}
+		inputs = append(inputs, string(b))
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			p, err := ParseSVG(input)
+			p2, err2 := ParseSVGReader(iotest.OneByteReader(strings.NewReader(input)))
+			if err != nil {
+				test.That(t, err2 != nil)
+				test.T(t, err2.Error(), err.Error())
+			} else {
+				test.Error(t, err2)
+				test.T(t, p2, p)
+			}
+		})
+	}
+
+	// larger inputs span multiple buffers
+	sb := strings.Builder{}
+	sb.WriteString("M0 0")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&sb, "L%d.125 %de-1c1 2 3 4 5 6", i, i)
+	}
+	p, err := ParseSVG(sb.String())
+	test.Error(t, err)
+	p2, err := ParseSVGReader(strings.NewReader(sb.String()))
+	test.Error(t, err)
+	test.T(t, p2, p)
+
+	_, err = ParseSVGReader(iotest.ErrReader(iotest.ErrTimeout))
+	test.T(t, err, iotest.ErrTimeout)
+}
+
 func TestPathToSVG(t *testing.T) {
 	var tts = []struct {
 		orig string
@@ -644,6 +1074,268 @@ func TestPathToSVG(t *testing.T) {
 	}
 }
 
+func TestPathToSVGWithoutArcs(t *testing.T) {
+	var tts = []*Path{
+		Circle(10.0),
+		MustParseSVG("M10 0A20 5 30 1 1 0 10"),
+	}
+	for _, p := range tts {
+		t.Run(p.String(), func(t *testing.T) {
+			svg := p.ToSVGWithoutArcs()
+			test.That(t, !strings.ContainsAny(svg, "Aa"), svg)
+			test.That(t, strings.ContainsAny(p.ToSVG(), "A"), "path is untouched")
+
+			// both paths consist of arcs of the same ellipse
+			rx, ry, phi := p.d[5], p.d[6], p.d[7]
+			large, sweep := toArcFlags(p.d[8])
+			cx, cy, _, _ := ellipseToCenter(p.d[1], p.d[2], rx, ry, phi, large, sweep, p.d[9], p.d[10])
+
+			// sample the Béziers and measure the deviation from the ellipse
+			q := MustParseSVG(svg)
+			var start Point
+			for i := 0; i < len(q.d); {
+				cmd := q.d[i]
+				if cmd == cubeToCmd {
+					cp1, cp2, end := Point{q.d[i+1], q.d[i+2]}, Point{q.d[i+3], q.d[i+4]}, Point{q.d[i+5], q.d[i+6]}
+					for j := 0; j <= 16; j++ {
+						pos := cubicBezierPos(start, cp1, cp2, end, float64(j)/16.0).Sub(Point{cx, cy}).Rot(-phi, Point{})
+						r := math.Sqrt(pos.X*pos.X/rx/rx + pos.Y*pos.Y/ry/ry)
+						test.That(t, math.Abs(r-1.0)*rx <= 0.002*rx, pos, "deviates", math.Abs(r-1.0)*rx)
+					}
+				}
+				i += cmdLen(cmd)
+				start = Point{q.d[i-3], q.d[i-2]}
+			}
+		})
+	}
+}
+
+func TestPathToSVGPrec(t *testing.T) {
+	var tts = []struct {
+		orig     string
+		decimals int
+		svg      string
+	}{
+		{"", 2, ""},
+		{"M1.23456 -1.23456L10 0.5", 2, "M1.23 -1.23L10 .5"},
+		{"M1.23456 -0.23456L10 -0.004", 2, "M1.23 -.23L10 0"},
+		{"M0.5 0.5L1.5 0.5L1.5 20.25z", 1, "M.5 .5H1.5V20.2z"},
+		{"M0.5 0.5L1.5 0.5L1.5 20.25z", 0, "M0 0H2V20z"},
+		{"M0 0Q1.23456 2 3 4C5 7 7.000001 8 9 10", 3, "M0 0Q1.235 2 3 4C5 7 7 8 9 10"},
+		{"A5 5 0 0 1 0.5 0", 2, "M0 0A5 5 0 01.5 0"},
+		{"M0.00001 0L1 0", 2, "M0 0H1"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.orig, tt.decimals), func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).ToSVGPrec(tt.decimals), tt.svg)
+		})
+	}
+
+	// round-trip at high precision
+	p := MustParseSVG("M1.23456789012345 -9876.54321098765L0.000123456789 3.14159265358979Q1e-7 2.5 3.333333333333 4C5 6 7 8 9 10A7.5 2.5 30 1 0 20 20z")
+	q := MustParseSVG(p.ToSVGPrec(15))
+	test.T(t, len(q.d), len(p.d))
+	for i := range p.d {
+		test.That(t, math.Abs(p.d[i]-q.d[i]) < 1e-12, p.d[i], "!=", q.d[i])
+	}
+}
+
+func TestPathToSVGMinified(t *testing.T) {
+	var tts = []struct {
+		orig     string
+		decimals int
+		svg      string
+	}{
+		{"", 2, ""},
+		{"M100 100L101 101L102 100", 2, "M100 100l1 1 1-1"},
+		{"M0.5 0.5L0.25 0.75", 2, "M.5.5.25.75"},
+		{"M100 100L110 100L110 110L100 110z", 2, "M100 100h10v10H100z"},
+		{"M10 10L20 20M30 30L40 20", 2, "M10 10 20 20M30 30 40 20"},
+		{"M10 10L20 20M25 25L24 26", 2, "M10 10 20 20m5 5-1 1"},
+		{"M0 0C1 2 3 4 5 5C7 6 9 8 10 10", 2, "M0 0C1 2 3 4 5 5s4 3 5 5"},
+		{"M0 0Q5 5 10 0Q15 -5 20 0Q25 5 30 0", 2, "M0 0Q5 5 10 0T20 0 30 0"},
+		{"M100 100A5 5 0 0 1 110 100A5 5 0 1 0 120 100", 2, "M100 100a5 5 0 0110 0 5 5 0 1010 0"},
+		{"M1.234 5.678L2.345 6.789", 1, "M1.2 5.7 2.3 6.8"},
+		{"M1.234 5.678L1.345 5.789", 1, "M1.2 5.7l.1.1"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.orig, tt.decimals), func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).ToSVGMinified(tt.decimals), tt.svg)
+		})
+	}
+}
+
+func TestPathToSVGMinifiedCorpus(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	if err := family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	glyphs, _ := family.Face(48.0, Black, FontRegular, FontNormal).ToPath("Sphinx of black quartz, judge my vow! 0123456789")
+
+	// chart with a polyline, grid and markers
+	chart := &Path{}
+	for i := 0; i <= 10; i++ {
+		chart = chart.Append(Rectangle(1.0, 100.0).Translate(float64(i)*25.0, 0.0))
+	}
+	line := &Polyline{}
+	for i := 0; i <= 50; i++ {
+		x := float64(i) * 5.0
+		line.Add(x, 50.0+40.0*math.Sin(x/20.0))
+	}
+	chart = chart.Append(line.ToPath())
+	chart = chart.Append(line.Smoothen())
+	for i := 0; i <= 10; i++ {
+		chart = chart.Append(Circle(2.0).Translate(float64(i)*25.0, 50.0+40.0*math.Sin(float64(i)*25.0/20.0)))
+	}
+
+	for name, p := range map[string]*Path{"glyphs": glyphs, "chart": chart} {
+		t.Run(name, func(t *testing.T) {
+			for _, decimals := range []int{1, 2, 3} {
+				prec := p.ToSVGPrec(decimals)
+				minified := p.ToSVGMinified(decimals)
+				test.That(t, len(minified) < len(prec), decimals, len(minified), "<", len(prec))
+
+				if decimals < 2 {
+					continue // rounding merges or removes tiny segments
+				}
+
+				// parses back with errors within rounding, which do not accumulate
+				tolerance := 0.5*math.Pow(10.0, -float64(decimals)) + 1e-9
+				q, err := ParseSVG(minified)
+				test.Error(t, err)
+				test.T(t, len(q.d), len(p.d))
+				for i := range q.d {
+					test.That(t, math.Abs(q.d[i]-p.d[i]) <= tolerance, decimals, i, q.d[i], "!=", p.d[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPathCanonical(t *testing.T) {
+	var tts = []struct {
+		decimals  int
+		paths     []string
+		canonical string
+	}{
+		{2, []string{""}, ""},
+		{2, []string{
+			"M0 0L10 0L10 10L0 10z",
+			"M0 0h10v10H0z",
+			"m0 0 10 0 0 10-10 0z",
+			"M0 0H10V10H0Z",
+			"M0.000001 -0.000001L10 0L10.004 10L0 10z",
+		}, "M0.00 0.00 L10.00 0.00 L10.00 10.00 L0.00 10.00 Z"},
+		{1, []string{
+			"M0 0C1 2 3 4 5 5C7 6 9 8 10 10",
+			"M0 0C1 2 3 4 5 5S9 8 10 10",
+			"m0 0c1 2 3 4 5 5s4 3 5 5",
+		}, "M0.0 0.0 C1.0 2.0 3.0 4.0 5.0 5.0 C7.0 6.0 9.0 8.0 10.0 10.0"},
+		{1, []string{
+			"M0 0Q5 5 10 0Q15 -5 20 0",
+			"M0 0Q5 5 10 0T20 0",
+			"m0 0q5 5 10 0t10 0",
+		}, "M0.0 0.0 Q5.0 5.0 10.0 0.0 Q15.0 -5.0 20.0 0.0"},
+		{2, []string{
+			"M0 0A10 5 30 0 1 10 0",
+			"M0 0A5 10 120 0 1 10 0",
+			"M0 0A10 5 210 0 1 10 0",
+			"m0 0a10 5 -150 0 1 10 0",
+		}, "M0.00 0.00 A10.00 5.00 30.00 0 1 10.00 0.00"},
+		{0, []string{
+			"M0 0A5 5 0 1 0 10 0",
+			"M0 0A5 5 45 1 0 10 0",
+			"M0 0A5 5 30 1010 0",
+		}, "M0 0 A5 5 0 1 0 10 0"},
+	}
+	for _, tt := range tts {
+		for _, orig := range tt.paths {
+			t.Run(orig, func(t *testing.T) {
+				test.T(t, MustParseSVG(orig).Canonical(tt.decimals), tt.canonical)
+			})
+		}
+	}
+}
+
+// chunkWriter records the sizes of the writes and fails after a number of bytes.
+type chunkWriter struct {
+	bytes.Buffer
+	writes int
+	limit  int
+}
+
+func (w *chunkWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if 0 < w.limit && w.limit < w.Len()+len(b) {
+		n, _ := w.Buffer.Write(b[:w.limit-w.Len()])
+		return n, io.ErrShortWrite
+	}
+	return w.Buffer.Write(b)
+}
+
+func TestPathWriteSVGPath(t *testing.T) {
+	var tts = []struct {
+		orig     string
+		decimals int
+		svg      string
+	}{
+		{"", 2, ""},
+		{"M1.23456 -0.23456L10 -0.004", 2, "M1.23 -.23L10 0"},
+		{"M0.5 0.5L1.5 0.5L1.5 20.25z", 1, "M.5 .5H1.5V20.2z"},
+		{"M0 0Q1.23456 2 3 4C5 7 7.000001 8 9 10", 3, "M0 0Q1.235 2 3 4C5 7 7 8 9 10"},
+		{"M0 0A10 5 120 1 0 -0.5 0", 2, "M0 0A5 10 30 10-.5 0"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.orig, tt.decimals), func(t *testing.T) {
+			w := &chunkWriter{}
+			n, err := MustParseSVG(tt.orig).WriteSVGPath(w, tt.decimals)
+			test.Error(t, err)
+			test.T(t, n, len(tt.svg))
+			test.String(t, w.String(), tt.svg)
+		})
+	}
+
+	// flushes in chunks
+	p := &Path{}
+	for i := 0; i < 1000; i++ {
+		p.LineTo(float64(i+1), float64(i%2)+0.125)
+	}
+	w := &chunkWriter{}
+	n, err := p.WriteSVGPath(w, 3)
+	test.Error(t, err)
+	test.T(t, n, w.Len())
+	test.String(t, w.String(), p.ToSVGPrec(3))
+	test.That(t, 1 < w.writes, "buffered writes")
+	test.That(t, w.writes < 100, "buffered writes")
+
+	// returns write errors
+	w = &chunkWriter{limit: 100}
+	n, err = p.WriteSVGPath(w, 3)
+	test.T(t, err, io.ErrShortWrite)
+	test.T(t, n, 100)
+}
+
+func BenchmarkPathWriteSVGPath(b *testing.B) {
+	p := &Path{}
+	for i := 0; i < 500000; i++ {
+		p.LineTo(float64(i), float64(i%2))
+		p.CubeTo(float64(i)+0.25, 2.0, float64(i)+0.75, -2.0, float64(i)+1.0, float64(i%2))
+	}
+
+	b.Run("ToSVGPrec", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			io.WriteString(ioutil.Discard, p.ToSVGPrec(3))
+		}
+	})
+	b.Run("WriteSVGPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			p.WriteSVGPath(ioutil.Discard, 3)
+		}
+	})
+}
+
 func TestPathToPS(t *testing.T) {
 	var tts = []struct {
 		orig string
@@ -652,8 +1344,15 @@ func TestPathToPS(t *testing.T) {
 		{"", ""},
 		{"L10 0Q15 10 20 0M20 10C20 20 30 20 30 10z", "0 0 moveto 10 0 lineto 13.333333 6.6666667 16.666667 6.6666667 20 0 curveto 20 10 moveto 20 20 30 20 30 10 curveto closepath"},
 		{"L10 0M20 0L30 0", "0 0 moveto 10 0 lineto 20 0 moveto 30 0 lineto"},
-		{"A5 5 0 0 1 10 0", "0 0 moveto 5 0 5 5 180 360 0 ellipse"},
-		{"A10 5 90 0 0 10 0", "0 0 moveto 5 0 10 5 90 -90 90 ellipsen"},
+		{"A5 5 0 0 1 10 0", "0 0 moveto 5 0 5 180 360 arc"},
+		{"A5 5 0 0 0 10 0", "0 0 moveto 5 0 5 180 0 arcn"},
+		{"M5 0A5 5 90 0 1 -5 0", "5 0 moveto 0 0 5 0 180 arc"},
+		{"M5 0A5 5 90 0 0 -5 0", "5 0 moveto 0 0 5 0 -180 arcn"},
+		{"M0 5A5 5 30 0 1 0 -5", "0 5 moveto 0 0 5 90 270 arc"},
+		{"M0 -5A5 5 120 1 1 5 0", "0 -5 moveto 5 -5 5 180 450 arc"},
+		{"A10 5 90 0 0 10 0", "0 0 moveto 0 5.4858377 2.2570812 10 5 10 curveto 7.7429189 10 10 5.4858377 10 0 curveto"},
+		{"M0 0H2V2H0z", "0 0 moveto 2 0 lineto 2 2 lineto 0 2 lineto closepath"},
+		{"M1 0A1 1 0 0 1 -1 0A1 1 0 0 1 1 0z", "1 0 moveto 0 0 1 0 180 arc 0 0 1 180 360 arc closepath"},
 	}
 	for _, tt := range tts {
 		t.Run(tt.orig, func(t *testing.T) {
@@ -662,6 +1361,16 @@ func TestPathToPS(t *testing.T) {
 	}
 }
 
+func TestPathToPSPrec(t *testing.T) {
+	p := MustParseSVG("M0.12345 0.5L1.5 2.66666Q3 3 4 0")
+	test.T(t, p.ToPSPrec(2), ".12 .5 moveto 1.5 2.67 lineto 2.5 2.89 3.33 2 4 0 curveto")
+	test.T(t, p.ToPSPrec(0), "0 0 moveto 2 3 lineto 2 3 3 2 4 0 curveto")
+
+	// y-axis points upwards in both the path and PostScript user space
+	p = Rectangle(2.0, 1.0).Translate(1.0, 3.0)
+	test.T(t, p.ToPS(), "1 3 moveto 3 3 lineto 3 4 lineto 1 4 lineto closepath")
+}
+
 func TestPathToPDF(t *testing.T) {
 	var tts = []struct {
 		orig string
@@ -670,10 +1379,22 @@ func TestPathToPDF(t *testing.T) {
 		{"", ""},
 		{"L10 0Q15 10 20 0M20 10C20 20 30 20 30 10z", "0 0 m 10 0 l 13.333333 6.6666667 16.666667 6.6666667 20 0 c 20 10 m 20 20 30 20 30 10 c h"},
 		{"L10 0M20 0L30 0", "0 0 m 10 0 l 20 0 m 30 0 l"},
+		{"M1 2H4V7H1z", "1 2 3 5 re"},
+		{"M4 2H1V7H4L4 2z", "4 2 -3 5 re"},
+		{"M1 2V7H4V2z", "1 2 m 1 7 l 4 7 l 4 2 l h"},
+		{"M1 2H4V7H1", "1 2 m 4 2 l 4 7 l 1 7 l"},
+		{"M1 0A1 1 0 0 1 -1 0A1 1 0 0 1 1 0z", "1 0 m 1 .54858377 .54858377 1 0 1 c -.54858377 1 -1 .54858377 -1 0 c -1 -.54858377 -.54858377 -1 0 -1 c .54858377 -1 1 -.54858377 1 0 c h"},
+		{"M0 0H4V4H0zM1 1V3H3V1z", "0 0 4 4 re 1 1 m 1 3 l 3 3 l 3 1 l h"},
 	}
 	for _, tt := range tts {
 		t.Run(tt.orig, func(t *testing.T) {
 			test.T(t, MustParseSVG(tt.orig).ToPDF(), tt.ps)
+
+			sb := strings.Builder{}
+			n, err := MustParseSVG(tt.orig).WritePDF(&sb)
+			test.Error(t, err)
+			test.T(t, n, len(tt.ps))
+			test.T(t, sb.String(), tt.ps)
 		})
 	}
 }
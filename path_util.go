@@ -142,6 +142,55 @@ func ellipseSplit(rx, ry, phi, cx, cy, theta0, theta1, theta float64) (Point, bo
 	return mid, large0, large1, true
 }
 
+// EllipsePos returns the position on the ellipse with radii rx and ry, rotated counter clockwise by rot in degrees, centered at (cx,cy), at angle theta in degrees. The angle is taken before the ellipse has been stretched and rotated.
+func EllipsePos(rx, ry, rot, cx, cy, theta float64) Point {
+	return ellipsePos(rx, ry, rot*math.Pi/180.0, cx, cy, theta*math.Pi/180.0)
+}
+
+// EllipseToCenter converts an elliptical arc from (x1,y1) to (x2,y2) in endpoint parametrization, as used by ArcTo, to the center parametrization. It returns the center (cx,cy) and the angles theta0 and theta1 in degrees between which the arc runs, with theta1 < theta0 if the arc runs clockwise. Radii that are too small to span the end points are scaled up as for ArcTo. See https://www.w3.org/TR/SVG/implnote.html#ArcImplementationNotes
+func EllipseToCenter(x1, y1, rx, ry, rot float64, large, sweep bool, x2, y2 float64) (cx, cy, theta0, theta1 float64) {
+	cx, cy, theta0, theta1 = ellipseToCenter(x1, y1, rx, ry, rot*math.Pi/180.0, large, sweep, x2, y2)
+	return cx, cy, theta0 * 180.0 / math.Pi, theta1 * 180.0 / math.Pi
+}
+
+// EllipseFromCenter is the inverse of EllipseToCenter and converts an elliptical arc in center parametrization, with radii rx and ry, rotated counter clockwise by rot in degrees, centered at (cx,cy) and running from angle theta0 to theta1 in degrees, to the endpoint parametrization as used by ArcTo. It returns the start point, the large-arc and sweep flags and the end point. Arcs spanning 360 degrees or more cannot be represented by one ArcTo and are reduced modulo 360 degrees.
+func EllipseFromCenter(cx, cy, rx, ry, rot, theta0, theta1 float64) (x1, y1 float64, large, sweep bool, x2, y2 float64) {
+	phi := rot * math.Pi / 180.0
+	theta0 *= math.Pi / 180.0
+	theta1 *= math.Pi / 180.0
+
+	sweep = theta0 < theta1
+	large = math.Mod(math.Abs(theta1-theta0), 2.0*math.Pi) > math.Pi
+	start := ellipsePos(rx, ry, phi, cx, cy, theta0)
+	end := ellipsePos(rx, ry, phi, cx, cy, theta1)
+	return start.X, start.Y, large, sweep, end.X, end.Y
+}
+
+// SplitArcAt splits an elliptical arc from (x1,y1) to (x2,y2) in endpoint parametrization, as used by ArcTo, at angle theta in degrees of the center parametrization (see EllipseToCenter). Both parts share the returned radii, which are scaled up when too small to span the end points, the rotation and the sweep flag of the original arc. It returns the split point, which is the end point of the first and the start point of the second part, and the large-arc flags of both parts. When theta does not lie strictly on the arc, ok is false.
+func SplitArcAt(x1, y1, rx, ry, rot float64, large, sweep bool, x2, y2, theta float64) (rxSplit, rySplit float64, mid Point, large0, large1, ok bool) {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	phi := rot * math.Pi / 180.0
+	if lambda := ellipseRadiiCorrection(Point{x1, y1}, rx, ry, phi, Point{x2, y2}); lambda > 1.0 {
+		rx *= lambda
+		ry *= lambda
+	}
+
+	cx, cy, theta0, theta1 := ellipseToCenter(x1, y1, rx, ry, phi, large, sweep, x2, y2)
+	theta *= math.Pi / 180.0
+	if !angleBetween(theta, theta0, theta1) {
+		return rx, ry, Point{}, false, false, false
+	}
+
+	// move theta into the range of theta0 and theta1
+	if sweep {
+		theta = theta0 + angleNorm(theta-theta0)
+	} else {
+		theta = theta0 - angleNorm(theta0-theta)
+	}
+	mid, large0, large1, ok = ellipseSplit(rx, ry, phi, cx, cy, theta0, theta1, theta)
+	return rx, ry, mid, large0, large1, ok
+}
+
 func arcToQuad(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path {
 	p := &Path{}
 	p.MoveTo(start.X, start.Y)
@@ -519,6 +568,84 @@ func cubicBezierSplit(p0, p1, p2, p3 Point, t float64) (Point, Point, Point, Poi
 	return q0, q1, q2, q3, r0, r1, r2, r3
 }
 
+// QuadBezierAt returns the position on the quadratic Bézier with start point p0, control point p1 and end point p2 at t in [0,1].
+func QuadBezierAt(p0, p1, p2 Point, t float64) Point {
+	return quadraticBezierPos(p0, p1, p2, t)
+}
+
+// QuadBezierDerivAt returns the derivative with respect to t of the quadratic Bézier with start point p0, control point p1 and end point p2 at t in [0,1].
+func QuadBezierDerivAt(p0, p1, p2 Point, t float64) Point {
+	return quadraticBezierDeriv(p0, p1, p2, t)
+}
+
+// SplitQuadBezier splits the quadratic Bézier with start point p0, control point p1 and end point p2 at t in [0,1], and returns the start, control and end points of the first and the second part respectively.
+func SplitQuadBezier(p0, p1, p2 Point, t float64) (Point, Point, Point, Point, Point, Point) {
+	return quadraticBezierSplit(p0, p1, p2, t)
+}
+
+// CubicBezierAt returns the position on the cubic Bézier with start point p0, control points p1 and p2 and end point p3 at t in [0,1].
+func CubicBezierAt(p0, p1, p2, p3 Point, t float64) Point {
+	return cubicBezierPos(p0, p1, p2, p3, t)
+}
+
+// CubicBezierDerivAt returns the derivative with respect to t of the cubic Bézier with start point p0, control points p1 and p2 and end point p3 at t in [0,1].
+func CubicBezierDerivAt(p0, p1, p2, p3 Point, t float64) Point {
+	return cubicBezierDeriv(p0, p1, p2, p3, t)
+}
+
+// CubicBezierDeriv2At returns the second derivative with respect to t of the cubic Bézier with start point p0, control points p1 and p2 and end point p3 at t in [0,1].
+func CubicBezierDeriv2At(p0, p1, p2, p3 Point, t float64) Point {
+	return cubicBezierDeriv2(p0, p1, p2, p3, t)
+}
+
+// SplitCubicBezier splits the cubic Bézier with start point p0, control points p1 and p2 and end point p3 at t in [0,1], and returns the start, control and end points of the first and the second part respectively. This is the same subdivision as used for flattening and stroking.
+func SplitCubicBezier(p0, p1, p2, p3 Point, t float64) (Point, Point, Point, Point, Point, Point, Point, Point) {
+	return cubicBezierSplit(p0, p1, p2, p3, t)
+}
+
+// parametricToCubicBeziers approximates the parametric curve f between t0 and t1 by cubic Béziers, subdividing until the distance of a few sample points on f to the Bézier is within tolerance. Derivatives are estimated numerically and clamped so that f may have singular derivatives at the end points.
+func parametricToCubicBeziers(f func(float64) Point, t0, t1, tolerance float64) [][4]Point {
+	beziers := [][4]Point{}
+	var fit func(float64, float64, Point, Point, int)
+	fit = func(t0, t1 float64, p0, p3 Point, depth int) {
+		h := (t1 - t0) * 1e-6
+		d := p3.Sub(p0).Length()
+		d0 := f(t0 + h).Sub(p0).Div(3.0 * 1e-6)
+		d1 := p3.Sub(f(t1 - h)).Div(3.0 * 1e-6)
+		p1 := p0.Add(d0.Norm(math.Min(d0.Length(), d/2.0)))
+		p2 := p3.Sub(d1.Norm(math.Min(d1.Length(), d/2.0)))
+
+		if depth < 16 {
+			for _, t := range []float64{0.2, 0.4, 0.6, 0.8} {
+				q := f(t0 + t*(t1-t0))
+
+				// project q onto the Bézier using Newton's method
+				s := t
+				for i := 0; i < 5; i++ {
+					diff := cubicBezierPos(p0, p1, p2, p3, s).Sub(q)
+					deriv := cubicBezierDeriv(p0, p1, p2, p3, s)
+					denom := deriv.Dot(deriv) + diff.Dot(cubicBezierDeriv2(p0, p1, p2, p3, s))
+					if Equal(denom, 0.0) {
+						break
+					}
+					s = math.Max(0.0, math.Min(1.0, s-diff.Dot(deriv)/denom))
+				}
+
+				if tolerance < cubicBezierPos(p0, p1, p2, p3, s).Sub(q).Length() {
+					tm := (t0 + t1) / 2.0
+					pm := f(tm)
+					fit(t0, tm, p0, pm, depth+1)
+					fit(tm, t1, pm, p3, depth+1)
+					return
+				}
+			}
+		}
+		beziers = append(beziers, [4]Point{p0, p1, p2, p3})
+	}
+	fit(t0, t1, f(t0), f(t1), 0)
+	return beziers
+}
+
 func addCubicBezierLine(p *Path, p0, p1, p2, p3 Point, t, d float64) {
 	if p0.X == p3.X && p0.Y == p3.X && (p0.X == p1.X && p0.Y == p1.Y || p0.X == p2.X && p0.Y == p2.Y) {
 		// Bézier has p0=p1=p3 or p0=p2=p3 and thus has no surface or length
@@ -1,6 +1,7 @@
 package canvas
 
 import (
+	"fmt"
 	"math"
 	"testing"
 
@@ -88,6 +89,97 @@ func TestEllipseSplit(t *testing.T) {
 	test.That(t, !large1)
 }
 
+func TestEllipseExported(t *testing.T) {
+	p := EllipsePos(2.0, 1.0, 90.0, 1.0, 0.5, 0.0)
+	test.Float(t, p.X, 1.0)
+	test.Float(t, p.Y, 2.5)
+	p = EllipsePos(2.0, 1.0, 45.0, 0.0, 0.0, 90.0)
+	test.Float(t, p.X, -math.Sqrt2/2.0)
+	test.Float(t, p.Y, math.Sqrt2/2.0)
+
+	var tts = []struct {
+		x1, y1, rx, ry, rot float64
+		large, sweep        bool
+		x2, y2              float64
+		cx, cy              float64
+		theta0, theta1      float64
+	}{
+		{0.0, 0.0, 2.0, 2.0, 0.0, false, false, 2.0, 2.0, 2.0, 0.0, 180.0, 90.0},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, false, 2.0, 2.0, 0.0, 2.0, 270.0, 0.0},
+		{0.0, 0.0, 2.0, 2.0, 0.0, false, true, 2.0, 2.0, 0.0, 2.0, 270.0, 360.0},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 2.0, 0.0, 180.0, 450.0},
+		{0.0, 0.0, 2.0, 1.0, 90.0, false, false, 1.0, 2.0, 1.0, 0.0, 90.0, 0.0},
+		{math.Sqrt2, math.Sqrt2, 2.0, 1.0, 45.0, false, true, -math.Sqrt2 / 2.0, math.Sqrt2 / 2.0, 0.0, 0.0, 0.0, 90.0},
+		{0.0, 0.0, 0.1, 0.1, 0.0, false, false, 1.0, 0.0, 0.5, 0.0, 180.0, 0.0},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.x1, tt.y1, tt.rx, tt.ry, tt.rot, tt.large, tt.sweep, tt.x2, tt.y2), func(t *testing.T) {
+			cx, cy, theta0, theta1 := EllipseToCenter(tt.x1, tt.y1, tt.rx, tt.ry, tt.rot, tt.large, tt.sweep, tt.x2, tt.y2)
+			test.Float(t, cx, tt.cx)
+			test.Float(t, cy, tt.cy)
+			test.Float(t, theta0, tt.theta0)
+			test.Float(t, theta1, tt.theta1)
+
+			if tt.rx != 0.1 { // radii are scaled up
+				x1, y1, large, sweep, x2, y2 := EllipseFromCenter(cx, cy, tt.rx, tt.ry, tt.rot, theta0, theta1)
+				test.Float(t, x1, tt.x1)
+				test.Float(t, y1, tt.y1)
+				test.T(t, large, tt.large)
+				test.T(t, sweep, tt.sweep)
+				test.Float(t, x2, tt.x2)
+				test.Float(t, y2, tt.y2)
+			}
+		})
+	}
+}
+
+func TestSplitArcAt(t *testing.T) {
+	var tts = []struct {
+		x1, y1, rx, ry, rot float64
+		large, sweep        bool
+		x2, y2, theta       float64
+		ok                  bool
+		r                   float64
+		mid                 Point
+		large0, large1      bool
+	}{
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 270.0, true, 2.0, Point{2.0, -2.0}, false, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 225.0, true, 2.0, Point{2.0 - math.Sqrt2, -math.Sqrt2}, false, true},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 45.0, true, 2.0, Point{2.0 + math.Sqrt2, math.Sqrt2}, true, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, false, 2.0, 2.0, 45.0, true, 2.0, Point{math.Sqrt2, 2.0 + math.Sqrt2}, true, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, false, false, 2.0, 2.0, 135.0, true, 2.0, Point{2.0 - math.Sqrt2, math.Sqrt2}, false, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, false, true, 2.0, 2.0, 315.0, true, 2.0, Point{math.Sqrt2, 2.0 - math.Sqrt2}, false, false},
+		{0.0, 0.0, 2.0, 1.0, 90.0, false, false, 1.0, 2.0, 45.0, true, 2.0, Point{1.0 - math.Sqrt2/2.0, math.Sqrt2}, false, false},
+		{0.0, 0.0, 0.1, 0.1, 0.0, false, false, 1.0, 0.0, 90.0, true, 0.5, Point{0.5, 0.5}, false, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 90.0, false, 2.0, Point{}, false, false},
+		{0.0, 0.0, 2.0, 2.0, 0.0, true, true, 2.0, 2.0, 135.0, false, 2.0, Point{}, false, false},
+		{0.0, 0.0, 0.1, 0.1, 0.0, false, false, 1.0, 0.0, 270.0, false, 0.5, Point{}, false, false},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.large, tt.sweep, tt.theta), func(t *testing.T) {
+			rx, ry, mid, large0, large1, ok := SplitArcAt(tt.x1, tt.y1, tt.rx, tt.ry, tt.rot, tt.large, tt.sweep, tt.x2, tt.y2, tt.theta)
+			test.T(t, ok, tt.ok)
+			test.Float(t, rx, tt.r)
+			test.Float(t, ry, tt.r*tt.ry/tt.rx)
+			test.Float(t, mid.X, tt.mid.X)
+			test.Float(t, mid.Y, tt.mid.Y)
+			test.T(t, large0, tt.large0)
+			test.T(t, large1, tt.large1)
+
+			if ok {
+				// both parts must lie on the original arc
+				cx, cy, theta0, theta1 := EllipseToCenter(tt.x1, tt.y1, rx, ry, tt.rot, large0, tt.sweep, mid.X, mid.Y)
+				cx2, cy2, theta2, theta3 := EllipseToCenter(mid.X, mid.Y, rx, ry, tt.rot, large1, tt.sweep, tt.x2, tt.y2)
+				test.Float(t, cx, cx2)
+				test.Float(t, cy, cy2)
+				test.Float(t, math.Mod(theta1-theta2, 360.0), 0.0)
+				_, _, phi0, phi1 := EllipseToCenter(tt.x1, tt.y1, tt.rx, tt.ry, tt.rot, tt.large, tt.sweep, tt.x2, tt.y2)
+				test.Float(t, math.Abs(theta1-theta0)+math.Abs(theta3-theta2), math.Abs(phi1-phi0))
+			}
+		})
+	}
+}
+
 func TestArcToQuad(t *testing.T) {
 	Epsilon = 1e-2
 	test.T(t, arcToQuad(Point{0.0, 0.0}, 100.0, 100.0, 0.0, false, false, Point{200.0, 0.0}), MustParseSVG("M0 0Q0 100 100 100Q200 100 200 0"))
@@ -174,6 +266,39 @@ func TestCubicBezier(t *testing.T) {
 	test.T(t, q3, Point{1.0, 1.0})
 }
 
+func TestBezierSplitProperty(t *testing.T) {
+	near := func(a, b Point) bool {
+		return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+	}
+
+	var tts = []struct {
+		p0, p1, p2, p3 Point
+	}{
+		{Point{0.0, 0.0}, Point{1.0, 2.0}, Point{3.0, -1.0}, Point{4.0, 1.0}},
+		{Point{0.0, 0.0}, Point{5.0, 5.0}, Point{-1.0, 5.0}, Point{4.0, 0.0}},
+		{Point{1.0, 1.0}, Point{1.0, 1.0}, Point{2.0, 3.0}, Point{2.0, 3.0}},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.p0, tt.p1, tt.p2, tt.p3), func(t *testing.T) {
+			for _, split := range []float64{0.0, 0.25, 0.5, 0.8, 1.0} {
+				a0, a1, a2, b0, b1, b2 := SplitQuadBezier(tt.p0, tt.p1, tt.p2, split)
+				c0, c1, c2, c3, d0, d1, d2, d3 := SplitCubicBezier(tt.p0, tt.p1, tt.p2, tt.p3, split)
+				for _, s := range []float64{0.0, 0.1, 0.5, 0.9, 1.0} {
+					test.That(t, near(QuadBezierAt(a0, a1, a2, s), QuadBezierAt(tt.p0, tt.p1, tt.p2, split*s)), "quad first half", split, s)
+					test.That(t, near(QuadBezierAt(b0, b1, b2, s), QuadBezierAt(tt.p0, tt.p1, tt.p2, split+(1.0-split)*s)), "quad second half", split, s)
+					test.That(t, near(CubicBezierAt(c0, c1, c2, c3, s), CubicBezierAt(tt.p0, tt.p1, tt.p2, tt.p3, split*s)), "cubic first half", split, s)
+					test.That(t, near(CubicBezierAt(d0, d1, d2, d3, s), CubicBezierAt(tt.p0, tt.p1, tt.p2, tt.p3, split+(1.0-split)*s)), "cubic second half", split, s)
+
+					// derivatives scale with the length of the parameter interval
+					test.That(t, near(QuadBezierDerivAt(a0, a1, a2, s), QuadBezierDerivAt(tt.p0, tt.p1, tt.p2, split*s).Mul(split)), "quad first half derivative", split, s)
+					test.That(t, near(CubicBezierDerivAt(d0, d1, d2, d3, s), CubicBezierDerivAt(tt.p0, tt.p1, tt.p2, tt.p3, split+(1.0-split)*s).Mul(1.0-split)), "cubic second half derivative", split, s)
+					test.That(t, near(CubicBezierDeriv2At(c0, c1, c2, c3, s), CubicBezierDeriv2At(tt.p0, tt.p1, tt.p2, tt.p3, split*s).Mul(split*split)), "cubic first half second derivative", split, s)
+				}
+			}
+		})
+	}
+}
+
 func TestCubicBezierStrokeHelpers(t *testing.T) {
 	p0, p1, p2, p3 := Point{0.0, 0.0}, Point{0.666667, 0.0}, Point{1.0, 0.333333}, Point{1.0, 1.0}
 
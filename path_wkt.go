@@ -0,0 +1,487 @@
+package canvas
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WKB geometry type codes, see the OGC Simple Features specification.
+const (
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// ogcGeometry is the flattened geometry of a path, where polygons consist of an exterior ring followed by its interior rings. Rings are closed, ie. their first and last points are equal.
+type ogcGeometry struct {
+	polygons [][][]Point
+	lines    [][]Point
+}
+
+// ogcGeometry flattens the path and splits it into polygons for the closed subpaths and lines for the open subpaths. Consecutive duplicate points and degenerate subpaths are removed. Closed subpaths are classified by their containment and orientation following the NonZero fill rule, which is the default of Context: closed subpaths whose inside is filled and outside is not are exterior rings, those whose outside is filled and inside is not are holes of the smallest exterior ring that contains them, and other closed subpaths don't bound the filled area and are dropped. Exterior rings are oriented counter clockwise and interior rings clockwise.
+func (p *Path) ogcGeometry(tolerance float64) ogcGeometry {
+	g := ogcGeometry{}
+	rings := [][]Point{}
+	for _, ps := range p.flatten(tolerance).Split() {
+		coords := []Point{}
+		for _, coord := range ps.Coords() {
+			if len(coords) == 0 || !coord.Equals(coords[len(coords)-1]) {
+				coords = append(coords, coord)
+			}
+		}
+		if ps.Closed() {
+			if 1 < len(coords) && coords[0].Equals(coords[len(coords)-1]) {
+				coords = coords[:len(coords)-1]
+			}
+			if 3 <= len(coords) {
+				rings = append(rings, append(coords, coords[0]))
+			}
+		} else if 2 <= len(coords) {
+			g.lines = append(g.lines, coords)
+		}
+	}
+
+	// determine for each ring the rings that contain it, and the winding numbers just inside and outside of it
+	depths := make([]int, len(rings))
+	windings := make([]int, len(rings)) // winding number just outside the ring
+	orientations := make([]int, len(rings))
+	containers := make([][]int, len(rings))
+	for i, ring := range rings {
+		orientations[i] = 1
+		if ringArea(ring) < 0.0 {
+			orientations[i] = -1
+		}
+	}
+	for i, ring := range rings {
+		test := ring[0].Interpolate(ring[1], 0.5)
+		for j, other := range rings {
+			if i != j && ringContains(other, test) {
+				containers[i] = append(containers[i], j)
+				depths[i]++
+				windings[i] += orientations[j]
+			}
+		}
+	}
+
+	// rings that separate a filled from an unfilled region following the NonZero fill rule are kept, exterior rings have their inside filled
+	exterior := make([]bool, len(rings))
+	polygons := make([]int, len(rings)) // index into g.polygons for exterior rings
+	for i, ring := range rings {
+		inside, outside := windings[i]+orientations[i] != 0, windings[i] != 0
+		if inside && !outside {
+			if orientations[i] < 0 {
+				reversePoints(ring)
+			}
+			exterior[i] = true
+			polygons[i] = len(g.polygons)
+			g.polygons = append(g.polygons, [][]Point{ring})
+		}
+	}
+	for i, ring := range rings {
+		inside, outside := windings[i]+orientations[i] != 0, windings[i] != 0
+		if !inside && outside {
+			// add the hole to the innermost exterior ring that contains it
+			parent := -1
+			for _, j := range containers[i] {
+				if exterior[j] && (parent == -1 || depths[parent] < depths[j]) {
+					parent = j
+				}
+			}
+			if parent != -1 {
+				if 0 < orientations[i] {
+					reversePoints(ring)
+				}
+				k := polygons[parent]
+				g.polygons[k] = append(g.polygons[k], ring)
+			}
+		}
+	}
+	return g
+}
+
+// ringArea returns the signed area of a closed ring using the Shoelace formula, it is positive for counter clockwise rings.
+func ringArea(ring []Point) float64 {
+	area := 0.0
+	for i := 1; i < len(ring); i++ {
+		area += ring[i-1].PerpDot(ring[i])
+	}
+	return area / 2.0
+}
+
+// ringContains returns true if point p lies inside the closed ring using the even-odd rule.
+func ringContains(ring []Point, p Point) bool {
+	inside := false
+	for i := 1; i < len(ring); i++ {
+		a, b := ring[i-1], ring[i]
+		if (a.Y <= p.Y) != (b.Y <= p.Y) {
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if p.X < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func reversePoints(coords []Point) {
+	for i, j := 0, len(coords)-1; i < j; i, j = i+1, j-1 {
+		coords[i], coords[j] = coords[j], coords[i]
+	}
+}
+
+// ToWKT returns the path in the Well-Known Text format of the OGC Simple Features specification. The path is flattened with the given tolerance (see Flatten), or Tolerance if it is not positive. Closed subpaths are converted to a POLYGON or MULTIPOLYGON following the NonZero fill rule, so that subpaths within a subpath of the opposite orientation are holes, and open subpaths are converted to a LINESTRING or MULTILINESTRING. Following the OGC conventions, exterior rings are counter clockwise and interior rings are clockwise. A path with both closed and open subpaths results in a GEOMETRYCOLLECTION.
+func (p *Path) ToWKT(tolerance float64) string {
+	g := p.ogcGeometry(tolerance)
+
+	sb := strings.Builder{}
+	if 0 < len(g.polygons) && 0 < len(g.lines) {
+		sb.WriteString("GEOMETRYCOLLECTION (")
+		writeWKTPolygons(&sb, g.polygons)
+		sb.WriteString(", ")
+		writeWKTLines(&sb, g.lines)
+		sb.WriteString(")")
+	} else if 0 < len(g.polygons) {
+		writeWKTPolygons(&sb, g.polygons)
+	} else if 0 < len(g.lines) {
+		writeWKTLines(&sb, g.lines)
+	} else {
+		sb.WriteString("GEOMETRYCOLLECTION EMPTY")
+	}
+	return sb.String()
+}
+
+func writeWKTPolygons(sb *strings.Builder, polygons [][][]Point) {
+	if len(polygons) == 1 {
+		sb.WriteString("POLYGON ")
+		writeWKTPolygon(sb, polygons[0])
+		return
+	}
+	sb.WriteString("MULTIPOLYGON (")
+	for i, polygon := range polygons {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		writeWKTPolygon(sb, polygon)
+	}
+	sb.WriteString(")")
+}
+
+func writeWKTPolygon(sb *strings.Builder, rings [][]Point) {
+	sb.WriteString("(")
+	for i, ring := range rings {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		writeWKTCoords(sb, ring)
+	}
+	sb.WriteString(")")
+}
+
+func writeWKTLines(sb *strings.Builder, lines [][]Point) {
+	if len(lines) == 1 {
+		sb.WriteString("LINESTRING ")
+		writeWKTCoords(sb, lines[0])
+		return
+	}
+	sb.WriteString("MULTILINESTRING (")
+	for i, line := range lines {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		writeWKTCoords(sb, line)
+	}
+	sb.WriteString(")")
+}
+
+func writeWKTCoords(sb *strings.Builder, coords []Point) {
+	sb.WriteString("(")
+	for i, coord := range coords {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(formatWKTNumber(coord.X))
+		sb.WriteString(" ")
+		sb.WriteString(formatWKTNumber(coord.Y))
+	}
+	sb.WriteString(")")
+}
+
+func formatWKTNumber(f float64) string {
+	if f == 0.0 {
+		f = 0.0 // remove negative zero
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ToWKB returns the path in the little-endian Well-Known Binary format of the OGC Simple Features specification. It encodes the same geometry as ToWKT.
+func (p *Path) ToWKB(tolerance float64) []byte {
+	g := p.ogcGeometry(tolerance)
+
+	b := []byte{}
+	if 0 < len(g.polygons) && 0 < len(g.lines) {
+		b = appendWKBHeader(b, wkbGeometryCollection)
+		b = appendWKBUint32(b, 2)
+		b = appendWKBPolygons(b, g.polygons)
+		b = appendWKBLines(b, g.lines)
+	} else if 0 < len(g.polygons) {
+		b = appendWKBPolygons(b, g.polygons)
+	} else if 0 < len(g.lines) {
+		b = appendWKBLines(b, g.lines)
+	} else {
+		b = appendWKBHeader(b, wkbGeometryCollection)
+		b = appendWKBUint32(b, 0)
+	}
+	return b
+}
+
+func appendWKBHeader(b []byte, geometryType uint32) []byte {
+	b = append(b, 1) // little-endian
+	return appendWKBUint32(b, geometryType)
+}
+
+func appendWKBUint32(b []byte, n uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], n)
+	return append(b, buf[:]...)
+}
+
+func appendWKBCoords(b []byte, coords []Point) []byte {
+	var buf [8]byte
+	b = appendWKBUint32(b, uint32(len(coords)))
+	for _, coord := range coords {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(coord.X))
+		b = append(b, buf[:]...)
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(coord.Y))
+		b = append(b, buf[:]...)
+	}
+	return b
+}
+
+func appendWKBPolygons(b []byte, polygons [][][]Point) []byte {
+	if 1 < len(polygons) {
+		b = appendWKBHeader(b, wkbMultiPolygon)
+		b = appendWKBUint32(b, uint32(len(polygons)))
+	}
+	for _, polygon := range polygons {
+		b = appendWKBHeader(b, wkbPolygon)
+		b = appendWKBUint32(b, uint32(len(polygon)))
+		for _, ring := range polygon {
+			b = appendWKBCoords(b, ring)
+		}
+	}
+	return b
+}
+
+func appendWKBLines(b []byte, lines [][]Point) []byte {
+	if 1 < len(lines) {
+		b = appendWKBHeader(b, wkbMultiLineString)
+		b = appendWKBUint32(b, uint32(len(lines)))
+	}
+	for _, line := range lines {
+		b = appendWKBHeader(b, wkbLineString)
+		b = appendWKBCoords(b, line)
+	}
+	return b
+}
+
+////////////////////////////////////////////////////////////////
+
+// FromWKT parses a LINESTRING, POLYGON, MULTILINESTRING, MULTIPOLYGON, or GEOMETRYCOLLECTION of those in the Well-Known Text format and returns the path. Line strings are converted to open subpaths and polygon rings to closed subpaths. Keywords are case-insensitive.
+func FromWKT(s string) (*Path, error) {
+	r := &wktParser{s: s}
+	p := &Path{}
+	if err := r.geometry(p); err != nil {
+		return nil, err
+	}
+	r.skipWhitespace()
+	if r.pos < len(r.s) {
+		return nil, r.errorf("unexpected character '%c'", r.s[r.pos])
+	}
+	return p, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (r *wktParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("bad WKT: "+format+" at position %d", append(a, r.pos+1)...)
+}
+
+func (r *wktParser) skipWhitespace() {
+	for r.pos < len(r.s) && (r.s[r.pos] == ' ' || r.s[r.pos] == '\t' || r.s[r.pos] == '\n' || r.s[r.pos] == '\r') {
+		r.pos++
+	}
+}
+
+func (r *wktParser) keyword() string {
+	r.skipWhitespace()
+	start := r.pos
+	for r.pos < len(r.s) && ('a' <= r.s[r.pos] && r.s[r.pos] <= 'z' || 'A' <= r.s[r.pos] && r.s[r.pos] <= 'Z') {
+		r.pos++
+	}
+	return strings.ToUpper(r.s[start:r.pos])
+}
+
+func (r *wktParser) consume(c byte) bool {
+	r.skipWhitespace()
+	if r.pos < len(r.s) && r.s[r.pos] == c {
+		r.pos++
+		return true
+	}
+	return false
+}
+
+func (r *wktParser) expect(c byte) error {
+	if !r.consume(c) {
+		if r.pos == len(r.s) {
+			return r.errorf("expected '%c' but got end of input", c)
+		}
+		return r.errorf("expected '%c'", c)
+	}
+	return nil
+}
+
+// empty parses an optional EMPTY keyword, it returns false if the opening parenthesis follows instead.
+func (r *wktParser) empty() (bool, error) {
+	r.skipWhitespace()
+	pos := r.pos
+	if keyword := r.keyword(); keyword == "EMPTY" {
+		return true, nil
+	} else if keyword != "" {
+		r.pos = pos
+		return false, r.errorf("unexpected keyword '%s'", keyword)
+	}
+	return false, r.expect('(')
+}
+
+func (r *wktParser) geometry(p *Path) error {
+	r.skipWhitespace()
+	pos := r.pos
+	keyword := r.keyword()
+	switch keyword {
+	case "LINESTRING":
+		return r.coords(p, false)
+	case "POLYGON":
+		return r.polygon(p)
+	case "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION":
+		if empty, err := r.empty(); err != nil {
+			return err
+		} else if empty {
+			return nil
+		}
+		for {
+			var err error
+			if keyword == "MULTILINESTRING" {
+				err = r.coords(p, false)
+			} else if keyword == "MULTIPOLYGON" {
+				err = r.polygon(p)
+			} else {
+				err = r.geometry(p)
+			}
+			if err != nil {
+				return err
+			} else if !r.consume(',') {
+				break
+			}
+		}
+		return r.expect(')')
+	case "":
+		if r.pos == len(r.s) {
+			return r.errorf("expected geometry but got end of input")
+		}
+		return r.errorf("unexpected character '%c'", r.s[r.pos])
+	}
+	r.pos = pos
+	return r.errorf("unsupported geometry type '%s'", keyword)
+}
+
+func (r *wktParser) polygon(p *Path) error {
+	if empty, err := r.empty(); err != nil {
+		return err
+	} else if empty {
+		return nil
+	}
+	for {
+		if err := r.coords(p, true); err != nil {
+			return err
+		} else if !r.consume(',') {
+			break
+		}
+	}
+	return r.expect(')')
+}
+
+// coords parses a parenthesized list of coordinates and appends it as a subpath. Rings must be closed and are closed in the path as well.
+func (r *wktParser) coords(p *Path, ring bool) error {
+	if empty, err := r.empty(); err != nil {
+		return err
+	} else if empty {
+		return nil
+	}
+	pos := r.pos
+	coords := []Point{}
+	for {
+		x, err := r.number()
+		if err != nil {
+			return err
+		}
+		y, err := r.number()
+		if err != nil {
+			return err
+		}
+		coords = append(coords, Point{x, y})
+		if !r.consume(',') {
+			break
+		}
+	}
+	if err := r.expect(')'); err != nil {
+		return err
+	}
+
+	if ring {
+		if len(coords) < 4 {
+			r.pos = pos
+			return r.errorf("ring must have at least four points")
+		} else if !coords[0].Equals(coords[len(coords)-1]) {
+			r.pos = pos
+			return r.errorf("ring must be closed")
+		}
+		coords = coords[:len(coords)-1]
+	} else if len(coords) < 2 {
+		r.pos = pos
+		return r.errorf("line string must have at least two points")
+	}
+
+	p.MoveTo(coords[0].X, coords[0].Y)
+	for _, coord := range coords[1:] {
+		p.LineTo(coord.X, coord.Y)
+	}
+	if ring {
+		p.Close()
+	}
+	return nil
+}
+
+func (r *wktParser) number() (float64, error) {
+	r.skipWhitespace()
+	start := r.pos
+	for r.pos < len(r.s) && (r.s[r.pos] == '+' || r.s[r.pos] == '-' || r.s[r.pos] == '.' || r.s[r.pos] == 'e' || r.s[r.pos] == 'E' || '0' <= r.s[r.pos] && r.s[r.pos] <= '9') {
+		r.pos++
+	}
+	f, err := strconv.ParseFloat(r.s[start:r.pos], 64)
+	if err != nil {
+		r.pos = start
+		if r.pos == len(r.s) {
+			return 0.0, r.errorf("expected number but got end of input")
+		}
+		return 0.0, r.errorf("expected number")
+	}
+	return f, nil
+}
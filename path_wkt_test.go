@@ -0,0 +1,194 @@
+package canvas
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathToWKT(t *testing.T) {
+	var tts = []struct {
+		p   string
+		wkt string
+	}{
+		{"", "GEOMETRYCOLLECTION EMPTY"},
+		{"M0 0", "GEOMETRYCOLLECTION EMPTY"},
+		{"M0 0L10 0L10 10", "LINESTRING (0 0, 10 0, 10 10)"},
+		{"M0 0L10 0M0 5L10 5", "MULTILINESTRING ((0 0, 10 0), (0 5, 10 5))"},
+		{"M0 0L10 0L10 10L0 10z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"},
+		{"M0 0L0 10L10 10L10 0z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"},
+		{"M0 0L10 0L10 10L0 10L0 0z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"},
+		{"M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2))"},
+		{"M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2zM4 4L6 4L6 6L4 6z", "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2)), ((4 4, 6 4, 6 6, 4 6, 4 4)))"},
+		{"M2 2L2 8L8 8L8 2zM0 0L10 0L10 10L0 10z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2))"},
+		{"M0 0L0 10L10 10L10 0zM2 2L8 2L8 8L2 8z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2))"},                                                                       // reversed rings
+		{"M0 0L0 10L10 10L10 0zM2 2L8 2L8 8L2 8zM4 4L4 6L6 6L6 4z", "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 8, 8 8, 8 2, 2 2)), ((4 4, 6 4, 6 6, 4 6, 4 4)))"},                  // reversed rings with an island
+		{"M0 0L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"},                                                                                                  // a ring of the same orientation is filled
+		{"M0 0L10 0L10 10L0 10zM2 2L8 2L8 8L2 8zM4 4L4 6L6 6L6 4zM4.5 4.5L4.5 5.5L5.5 5.5L5.5 4.5z", "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (4.5 4.5, 4.5 5.5, 5.5 5.5, 5.5 4.5, 4.5 4.5))"}, // winding numbers 1, 2, 1 and 0
+		{"M0 0L5 0L5 5L0 5zM10 0L15 0L15 5L10 5z", "MULTIPOLYGON (((0 0, 5 0, 5 5, 0 5, 0 0)), ((10 0, 15 0, 15 5, 10 5, 10 0)))"},
+		{"M0 0L10 0L10 10L0 10zM20 0L30 0", "GEOMETRYCOLLECTION (POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0)), LINESTRING (20 0, 30 0))"},
+		{"M0 0L10 0z", "GEOMETRYCOLLECTION EMPTY"},
+		{"M-1.5 0L1.5 0L0 2z", "POLYGON ((-1.5 0, 1.5 0, 0 2, -1.5 0))"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.String(t, MustParseSVG(tt.p).ToWKT(0.0), tt.wkt)
+		})
+	}
+}
+
+func TestPathWKTRoundTrip(t *testing.T) {
+	var tts = []string{
+		"M0 0L10 0L10 10",
+		"M0 0L10 0M0 5L10 5",
+		"M0 0L10 0L10 10L0 10z",
+		"M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z",
+		"M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2zM4 4L6 4L6 6L4 6z",
+		"M0 0L10 0L10 10L0 10zM20 0L30 0",
+	}
+	for _, tt := range tts {
+		t.Run(tt, func(t *testing.T) {
+			p := MustParseSVG(tt)
+			q, err := FromWKT(p.ToWKT(0.0))
+			test.Error(t, err)
+			test.T(t, q, p)
+		})
+	}
+}
+
+func TestPathWKTCurves(t *testing.T) {
+	p := Circle(5.0)
+	p = p.Append(Circle(2.0).Reverse())
+	p = p.Append(MustParseSVG("M10 0Q15 5 20 0C20 5 25 5 25 0"))
+
+	tolerance := 0.01
+	wkt := p.ToWKT(tolerance)
+	test.That(t, wkt[:len("GEOMETRYCOLLECTION (POLYGON ((")] == "GEOMETRYCOLLECTION (POLYGON ((", wkt)
+
+	g := p.ogcGeometry(tolerance)
+	test.T(t, len(g.polygons), 1)
+	test.T(t, len(g.polygons[0]), 2)
+	test.T(t, len(g.lines), 1)
+
+	// validity: rings are closed, have no consecutive duplicate points, and are oriented following OGC
+	for i, ring := range g.polygons[0] {
+		test.That(t, 4 <= len(ring))
+		test.T(t, ring[0], ring[len(ring)-1])
+		for j := 1; j < len(ring); j++ {
+			test.That(t, !ring[j].Equals(ring[j-1]), "duplicate point")
+		}
+		test.That(t, (i == 0) == (0.0 < ringArea(ring)), "ring orientation")
+	}
+	for j := 1; j < len(g.lines[0]); j++ {
+		test.That(t, !g.lines[0][j].Equals(g.lines[0][j-1]), "duplicate point")
+	}
+
+	// the area of the flattened polygon is close to the area of the annulus
+	area := ringArea(g.polygons[0][0]) + ringArea(g.polygons[0][1])
+	test.That(t, math.Abs(area-math.Pi*(25.0-4.0)) < 2.0*math.Pi*(5.0+2.0)*tolerance, area)
+
+	// round-trip
+	q, err := FromWKT(wkt)
+	test.Error(t, err)
+	test.String(t, q.ToWKT(tolerance), wkt)
+}
+
+func TestPathToWKB(t *testing.T) {
+	putUint32 := func(b []byte, n uint32) []byte {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], n)
+		return append(b, buf[:]...)
+	}
+	header := func(b []byte, geometryType, n uint32) []byte {
+		b = append(b, 1)
+		b = putUint32(b, geometryType)
+		return putUint32(b, n)
+	}
+	coords := func(b []byte, fs ...float64) []byte {
+		var buf [8]byte
+		b = putUint32(b, uint32(len(fs)/2))
+		for _, f := range fs {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+			b = append(b, buf[:]...)
+		}
+		return b
+	}
+
+	b := header(nil, 7, 0)
+	test.Bytes(t, (&Path{}).ToWKB(0.0), b)
+
+	b = []byte{1}
+	b = putUint32(b, 2)
+	b = coords(b, 0, 0, 10, 0)
+	test.Bytes(t, MustParseSVG("M0 0L10 0").ToWKB(0.0), b)
+
+	b = header(nil, 3, 2)
+	b = coords(b, 0, 0, 10, 0, 10, 10, 0, 10, 0, 0)
+	b = coords(b, 2, 2, 2, 8, 8, 8, 8, 2, 2, 2)
+	test.Bytes(t, MustParseSVG("M0 0L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z").ToWKB(0.0), b)
+
+	b = header(nil, 7, 2)
+	b = header(b, 6, 2)
+	b = header(b, 3, 1)
+	b = coords(b, 0, 0, 5, 0, 5, 5, 0, 0)
+	b = header(b, 3, 1)
+	b = coords(b, 10, 0, 15, 0, 15, 5, 10, 0)
+	b = header(b, 5, 2)
+	b = append(b, 1)
+	b = putUint32(b, 2)
+	b = coords(b, 20, 0, 30, 0)
+	b = append(b, 1)
+	b = putUint32(b, 2)
+	b = coords(b, 20, 5, 30, 5)
+	test.Bytes(t, MustParseSVG("M0 0L5 0L5 5zM10 0L15 0L15 5zM20 0L30 0M20 5L30 5").ToWKB(0.0), b)
+}
+
+func TestFromWKT(t *testing.T) {
+	var tts = []struct {
+		wkt string
+		p   string
+	}{
+		{"GEOMETRYCOLLECTION EMPTY", ""},
+		{"LINESTRING EMPTY", ""},
+		{"linestring(0 0,10 0,10 10)", "M0 0L10 0L10 10"},
+		{" LineString ( 0 0 , 1e1 -5 ) ", "M0 0L10 -5"},
+		{"POLYGON ((0 0, 10 0, 10 10, 0 0))", "M0 0L10 0L10 10z"},
+		{"MULTIPOLYGON (((0 0, 10 0, 10 10, 0 0)), EMPTY, ((20 0, 30 0, 30 10, 20 0)))", "M0 0L10 0L10 10zM20 0L30 0L30 10z"},
+		{"MULTILINESTRING ((0 0, 10 0), (0 5, 10 5))", "M0 0L10 0M0 5L10 5"},
+		{"GEOMETRYCOLLECTION (LINESTRING (0 0, 10 0), GEOMETRYCOLLECTION (POLYGON ((0 0, 10 0, 10 10, 0 0))))", "M0 0L10 0M0 0L10 0L10 10z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.wkt, func(t *testing.T) {
+			p, err := FromWKT(tt.wkt)
+			test.Error(t, err)
+			test.T(t, p, MustParseSVG(tt.p))
+		})
+	}
+}
+
+func TestFromWKTErrors(t *testing.T) {
+	var tts = []struct {
+		wkt string
+		err string
+	}{
+		{"", "bad WKT: expected geometry but got end of input at position 1"},
+		{"(0 0, 1 1)", "bad WKT: unexpected character '(' at position 1"},
+		{"POINT (0 0)", "bad WKT: unsupported geometry type 'POINT' at position 1"},
+		{"LINESTRING", "bad WKT: expected '(' but got end of input at position 11"},
+		{"LINESTRING FULL", "bad WKT: unexpected keyword 'FULL' at position 12"},
+		{"LINESTRING (0 0, 1)", "bad WKT: expected number at position 19"},
+		{"LINESTRING (0 0, 1 1", "bad WKT: expected ')' but got end of input at position 21"},
+		{"LINESTRING (0 0)", "bad WKT: line string must have at least two points at position 13"},
+		{"POLYGON ((0 0, 1 0, 1 1))", "bad WKT: ring must have at least four points at position 11"},
+		{"POLYGON ((0 0, 1 0, 1 1, 0 1))", "bad WKT: ring must be closed at position 11"},
+		{"LINESTRING (0 0, 1 1) x", "bad WKT: unexpected character 'x' at position 23"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.wkt, func(t *testing.T) {
+			_, err := FromWKT(tt.wkt)
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
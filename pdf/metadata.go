@@ -0,0 +1,174 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"time"
+)
+
+// pdfDate formats a date as a PDF date string, such as D:20060102150405+01'00'.
+func pdfDate(t time.Time) string {
+	s := t.Format("D:20060102150405")
+	_, offset := t.Zone()
+	if offset == 0 {
+		return s + "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return s + fmt.Sprintf("%c%02d'%02d'", sign, offset/3600, offset/60%60)
+}
+
+// xmpMetadata returns an XMP metadata packet with the same document information as the Info dictionary. If conformance is not empty, the document is identified as PDF/A with the given part and conformance level.
+func xmpMetadata(title, subject, keywords, author, producer string, creationDate time.Time, part int, conformance string) []byte {
+	escape := func(s string) string {
+		buf := &bytes.Buffer{}
+		xml.EscapeText(buf, []byte(s))
+		return buf.String()
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<?xpacket begin=\"\xEF\xBB\xBF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	fmt.Fprintf(buf, "<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	fmt.Fprintf(buf, "<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\"")
+	if conformance != "" {
+		fmt.Fprintf(buf, " xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"")
+	}
+	fmt.Fprintf(buf, ">\n<dc:format>application/pdf</dc:format>\n")
+	if title != "" {
+		fmt.Fprintf(buf, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", escape(title))
+	}
+	if subject != "" {
+		fmt.Fprintf(buf, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", escape(subject))
+	}
+	if author != "" {
+		fmt.Fprintf(buf, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", escape(author))
+	}
+	if keywords != "" {
+		fmt.Fprintf(buf, "<pdf:Keywords>%s</pdf:Keywords>\n", escape(keywords))
+	}
+	fmt.Fprintf(buf, "<pdf:Producer>%s</pdf:Producer>\n", escape(producer))
+	fmt.Fprintf(buf, "<xmp:CreateDate>%s</xmp:CreateDate>\n", creationDate.Format(time.RFC3339))
+	if conformance != "" {
+		fmt.Fprintf(buf, "<pdfaid:part>%d</pdfaid:part>\n<pdfaid:conformance>%s</pdfaid:conformance>\n", part, conformance)
+	}
+	fmt.Fprintf(buf, "</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
+
+// sfntEmbeddable returns whether the license of a TrueType or OpenType font allows embedding its outlines, as given by the fsType field of the OS/2 table. Fonts without an OS/2 table are embeddable.
+func sfntEmbeddable(b []byte) bool {
+	if len(b) < 12 {
+		return false
+	}
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	for i := 0; i < numTables && 12+16*i+16 <= len(b); i++ {
+		record := b[12+16*i:]
+		if string(record[:4]) == "OS/2" {
+			offset := int(binary.BigEndian.Uint32(record[8:]))
+			if len(b) < offset+10 {
+				return false
+			}
+			fsType := binary.BigEndian.Uint16(b[offset+8:])
+			// restricted license embedding or bitmap embedding only
+			return fsType&0x000F != 0x0002 && fsType&0x0200 == 0
+		}
+	}
+	return true
+}
+
+// srgbICCProfile returns an ICC version 2 display profile for the sRGB color space with D50 adapted primaries and a sampled transfer function.
+func srgbICCProfile() []byte {
+	s15Fixed16 := func(v float64) uint32 {
+		return uint32(int32(math.Round(v * 65536.0)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15Fixed16(x))
+		binary.BigEndian.PutUint32(b[12:], s15Fixed16(y))
+		binary.BigEndian.PutUint32(b[16:], s15Fixed16(z))
+		return b
+	}
+
+	description := "sRGB IEC61966-2.1"
+	desc := make([]byte, 12+len(description)+1+4+4+2+1+67)
+	copy(desc, "desc")
+	binary.BigEndian.PutUint32(desc[8:], uint32(len(description)+1))
+	copy(desc[12:], description)
+
+	copyright := "No copyright, use freely"
+	cprt := make([]byte, 8+len(copyright)+1)
+	copy(cprt, "text")
+	copy(cprt[8:], copyright)
+
+	n := 1024
+	curv := make([]byte, 12+2*n)
+	copy(curv, "curv")
+	binary.BigEndian.PutUint32(curv[8:], uint32(n))
+	for i := 0; i < n; i++ {
+		v := float64(i) / float64(n-1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(curv[12+2*i:], uint16(math.Round(v*65535.0)))
+	}
+
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	tags := []tag{
+		{"desc", desc},
+		{"cprt", cprt},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curv},
+		{"gTRC", curv},
+		{"bTRC", curv},
+	}
+
+	// the curves of the three channels share their data
+	b := make([]byte, 128+4+12*len(tags))
+	offsets := map[*byte]int{}
+	binary.BigEndian.PutUint32(b[128:], uint32(len(tags)))
+	for i, tag := range tags {
+		offset, ok := offsets[&tag.data[0]]
+		if !ok {
+			offset = len(b)
+			offsets[&tag.data[0]] = offset
+			b = append(b, tag.data...)
+			for len(b)%4 != 0 {
+				b = append(b, 0)
+			}
+		}
+		entry := b[128+4+12*i:]
+		copy(entry, tag.sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(offset))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(tag.data)))
+	}
+
+	// header
+	binary.BigEndian.PutUint32(b[0:], uint32(len(b)))
+	binary.BigEndian.PutUint32(b[8:], 0x02100000) // version 2.1
+	copy(b[12:], "mntr")
+	copy(b[16:], "RGB ")
+	copy(b[20:], "XYZ ")
+	for i, v := range []uint16{2000, 1, 1, 0, 0, 0} { // date and time
+		binary.BigEndian.PutUint16(b[24+2*i:], v)
+	}
+	copy(b[36:], "acsp")
+	binary.BigEndian.PutUint32(b[68:], s15Fixed16(0.9642)) // D50 illuminant
+	binary.BigEndian.PutUint32(b[72:], s15Fixed16(1.0))
+	binary.BigEndian.PutUint32(b[76:], s15Fixed16(0.8249))
+	return b
+}
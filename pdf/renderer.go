@@ -3,9 +3,12 @@ package pdf
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/md5"
 	"encoding/ascii85"
 	"encoding/binary"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"image"
 	"image/color"
 	"io"
@@ -13,15 +16,26 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/tdewolff/canvas"
 	canvasFont "github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/canvas/rasterizer"
 )
 
 type PDF struct {
 	w             *pdfPageWriter
 	width, height float64
 	imgEnc        canvas.ImageEncoding
+
+	blurs          []pdfBlur
+	blurResolution canvas.DPMM
+}
+
+// pdfBlur records the elements of a blur layer, which is rasterized when it ends.
+type pdfBlur struct {
+	c     *canvas.Canvas
+	sigma float64
 }
 
 // NewPDF creates a portable document format renderer.
@@ -31,6 +45,8 @@ func New(w io.Writer, width, height float64) *PDF {
 		width:  width,
 		height: height,
 		imgEnc: canvas.Lossless,
+
+		blurResolution: canvas.DPMM(10.0),
 	}
 }
 
@@ -38,10 +54,22 @@ func (r *PDF) SetImageEncoding(enc canvas.ImageEncoding) {
 	r.imgEnc = enc
 }
 
+// SetBlurResolution sets the resolution at which blur layers are rasterized, since PDF has no blur filter, which is 10 dots-per-millimeter (254 DPI) by default.
+func (r *PDF) SetBlurResolution(resolution canvas.DPMM) {
+	r.blurResolution = resolution
+}
+
+// SetCompression sets whether page content streams are compressed using Flate (zlib), which is on by default. Turning it off can be useful for debugging. Embedded fonts and images are always compressed.
 func (r *PDF) SetCompression(compress bool) {
 	r.w.pdf.SetCompression(compress)
 }
 
+// SetFontSubsetting sets whether TrueType fonts are embedded with only the glyphs that are used, which is on by default and greatly reduces the file size. When off, the complete font files are embedded. It must be called before rendering text.
+func (r *PDF) SetFontSubsetting(subset bool) {
+	r.w.pdf.SetFontSubsetting(subset)
+}
+
+// SetInfo sets the document information, which is written to the Info dictionary and the XMP metadata stream.
 func (r *PDF) SetInfo(title, subject, keywords, author string) {
 	r.w.pdf.SetTitle(title)
 	r.w.pdf.SetSubject(subject)
@@ -49,9 +77,26 @@ func (r *PDF) SetInfo(title, subject, keywords, author string) {
 	r.w.pdf.SetAuthor(author)
 }
 
-// NewPage starts adds a new page where further rendering will be written to
+// SetCreationDate sets the creation date of the document, which is the time of closing the PDF by default.
+func (r *PDF) SetCreationDate(creationDate time.Time) {
+	r.w.pdf.SetCreationDate(creationDate)
+}
+
+// AddBookmark adds an item to the document outline that points to a vertical position y (in millimeters from the bottom) on the page with the given index, starting at zero. Level zero adds a top-level item, and higher levels nest the item under the previous item with a lower level. Levels deeper than one more than the previous level are clamped. Close returns an error if the page does not exist.
+func (r *PDF) AddBookmark(title string, level, page int, y float64) {
+	r.w.pdf.AddBookmark(title, level, page, y)
+}
+
+// SetPDFA sets whether the document conforms to PDF/A-2b for long-term archiving. It identifies the document as PDF/A in the XMP metadata and adds an output intent with an embedded sRGB ICC profile, which is required for the device RGB colors and transparency groups. Close returns an error if the document cannot conform, such as when a font does not allow embedding.
+func (r *PDF) SetPDFA(pdfa bool) {
+	r.w.pdf.SetPDFA(pdfa)
+}
+
+// NewPage adds a new page of the given size where further rendering will be written to. Resources such as fonts are shared between pages and are written once.
 func (r *PDF) NewPage(width, height float64) {
 	r.w = r.w.pdf.NewPage(width, height)
+	r.width = width
+	r.height = height
 }
 
 func (r *PDF) Close() error {
@@ -63,9 +108,12 @@ func (r *PDF) Size() (float64, float64) {
 }
 
 func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if n := len(r.blurs); 0 < n {
+		r.blurs[n-1].c.RenderPath(path, style, m)
+		return
+	}
 	fill := style.FillColor.A != 0
 	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
-	differentAlpha := fill && stroke && style.FillColor.A != style.StrokeColor.A
 
 	// PDFs don't support the arcs joiner, miter joiner (not clipped), or miter joiner (clipped) with non-bevel fallback
 	strokeUnsupported := false
@@ -87,6 +135,10 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	closed := false
 	data := path.Transform(m).ToPDF()
+	if style.FillPaint != nil && r.w.FillPaint(data, style.FillRule, style.FillPaint, float64(style.FillColor.A)/255.0, m, path.Bounds()) {
+		fill = false
+	}
+	differentAlpha := fill && stroke && style.FillColor.A != style.StrokeColor.A
 	if 1 < len(data) && data[len(data)-1] == 'h' {
 		data = data[:len(data)-2]
 		closed = true
@@ -94,7 +146,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	if !stroke || !strokeUnsupported {
 		if fill && !stroke {
-			r.w.SetFillColor(style.FillColor)
+			r.setFillColor(style.FillColor, style.FillCMYK, style.FillSpot)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -102,7 +154,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 				r.w.Write([]byte("*"))
 			}
 		} else if !fill && stroke {
-			r.w.SetStrokeColor(style.StrokeColor)
+			r.setStrokeColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
 			r.w.SetLineWidth(style.StrokeWidth)
 			r.w.SetLineCap(style.StrokeCapper)
 			r.w.SetLineJoin(style.StrokeJoiner)
@@ -114,13 +166,10 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			} else {
 				r.w.Write([]byte(" S"))
 			}
-			if style.FillRule == canvas.EvenOdd {
-				r.w.Write([]byte("*"))
-			}
 		} else if fill && stroke {
 			if !differentAlpha {
-				r.w.SetFillColor(style.FillColor)
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.setFillColor(style.FillColor, style.FillCMYK, style.FillSpot)
+				r.setStrokeColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -136,7 +185,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 			} else {
-				r.w.SetFillColor(style.FillColor)
+				r.setFillColor(style.FillColor, style.FillCMYK, style.FillSpot)
 				r.w.Write([]byte(" "))
 				r.w.Write([]byte(data))
 				r.w.Write([]byte(" f"))
@@ -144,7 +193,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.setStrokeColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -156,15 +205,12 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 				} else {
 					r.w.Write([]byte(" S"))
 				}
-				if style.FillRule == canvas.EvenOdd {
-					r.w.Write([]byte("*"))
-				}
 			}
 		}
 	} else {
 		// stroke && strokeUnsupported
 		if fill {
-			r.w.SetFillColor(style.FillColor)
+			r.setFillColor(style.FillColor, style.FillCMYK, style.FillSpot)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -173,23 +219,47 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			}
 		}
 
-		// stroke settings unsupported by PDF, draw stroke explicitly
+		// stroke settings unsupported by PDF, draw stroke explicitly in the same coordinates as the path data, so that the stroke width and dashes are not scaled
+		path = path.Transform(m)
 		if 0 < len(style.Dashes) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
 		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
 
-		r.w.SetFillColor(style.StrokeColor)
+		r.setFillColor(style.StrokeColor, style.StrokeCMYK, style.StrokeSpot)
 		r.w.Write([]byte(" "))
 		r.w.Write([]byte(path.ToPDF()))
 		r.w.Write([]byte(" f"))
-		if style.FillRule == canvas.EvenOdd {
-			r.w.Write([]byte("*"))
-		}
+	}
+}
+
+// setFillColor sets the fill color, which uses a separation color space if spot is not nil or the CMYK color space if cmyk is not nil.
+func (r *PDF) setFillColor(col color.RGBA, cmyk *color.CMYK, spot *canvas.SpotColor) {
+	if spot != nil {
+		r.w.SetFillSpot(*spot, col.A)
+	} else if cmyk != nil {
+		r.w.SetFillCMYK(*cmyk, col.A)
+	} else {
+		r.w.SetFillColor(col)
+	}
+}
+
+// setStrokeColor sets the stroke color, which uses a separation color space if spot is not nil or the CMYK color space if cmyk is not nil.
+func (r *PDF) setStrokeColor(col color.RGBA, cmyk *color.CMYK, spot *canvas.SpotColor) {
+	if spot != nil {
+		r.w.SetStrokeSpot(*spot, col.A)
+	} else if cmyk != nil {
+		r.w.SetStrokeCMYK(*cmyk, col.A)
+	} else {
+		r.w.SetStrokeColor(col)
 	}
 }
 
 func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
+	if n := len(r.blurs); 0 < n {
+		r.blurs[n-1].c.RenderText(text, m)
+		return
+	}
 	r.w.StartTextObject()
 
 	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
@@ -221,9 +291,47 @@ func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
 }
 
 func (r *PDF) RenderImage(img image.Image, m canvas.Matrix) {
+	if n := len(r.blurs); 0 < n {
+		r.blurs[n-1].c.RenderImage(img, m)
+		return
+	}
 	r.w.DrawImage(img, r.imgEnc, m)
 }
 
+// StartLink adds a link annotation to the URL over the bounding box of the area.
+func (r *PDF) StartLink(url string, area *canvas.Path, m canvas.Matrix) {
+	r.w.AddLink(url, area.Bounds().Transform(m))
+}
+
+func (r *PDF) EndLink() {
+}
+
+// SetBlendMode sets the blend mode of the elements that follow on the current page. Note that PDF viewers vary in their support of blend modes, so that the output may look slightly different across viewers.
+func (r *PDF) SetBlendMode(mode canvas.BlendMode) {
+	if n := len(r.blurs); 0 < n {
+		r.blurs[n-1].c.SetBlendMode(mode)
+		return
+	}
+	r.w.SetBlendMode(mode)
+}
+
+// StartBlur starts recording the elements of a blur layer, which are rasterized and blurred by EndBlur since PDF has no blur filter.
+func (r *PDF) StartBlur(sigma float64) {
+	r.blurs = append(r.blurs, pdfBlur{canvas.New(r.width, r.height), sigma})
+}
+
+// EndBlur rasterizes and blurs the last started blur layer, and draws it as an image cropped to the visible pixels.
+func (r *PDF) EndBlur() {
+	if len(r.blurs) == 0 {
+		return
+	}
+	blur := r.blurs[len(r.blurs)-1]
+	r.blurs = r.blurs[:len(r.blurs)-1]
+	if img, m := rasterizer.DrawBlurred(blur.c, r.blurResolution, blur.sigma); img != nil {
+		r.RenderImage(img, m)
+	}
+}
+
 type pdfWriter struct {
 	w   io.Writer
 	err error
@@ -231,23 +339,34 @@ type pdfWriter struct {
 	pos        int
 	objOffsets []int
 
-	fonts    map[*canvas.Font]pdfRef
+	fonts    map[*canvas.Font]*pdfFont
+	spots    map[string]pdfRef // separation color spaces by spot color name
 	pages    []*pdfPageWriter
 	compress bool
-	title    string
-	subject  string
-	keywords string
-	author   string
+	subset   bool
+	pdfa     bool
+	outline  []pdfBookmark
+	hash     hash.Hash // hash of the output for the document ID
+
+	title        string
+	subject      string
+	keywords     string
+	author       string
+	creationDate time.Time
 }
 
 func newPDFWriter(writer io.Writer) *pdfWriter {
 	w := &pdfWriter{
 		w:          writer,
-		fonts:      map[*canvas.Font]pdfRef{},
+		fonts:      map[*canvas.Font]*pdfFont{},
+		spots:      map[string]pdfRef{},
 		objOffsets: []int{0, 0, 0}, // catalog, metadata, page tree
+		compress:   true,
+		subset:     true,
+		hash:       md5.New(),
 	}
 
-	w.write("%%PDF-1.7\n")
+	w.write("%%PDF-1.7\n%%\xE2\xE3\xCF\xD3\n") // binary comment marks the file as binary
 	return w
 }
 
@@ -255,6 +374,38 @@ func (w *pdfWriter) SetCompression(compress bool) {
 	w.compress = compress
 }
 
+func (w *pdfWriter) SetFontSubsetting(subset bool) {
+	w.subset = subset
+}
+
+type pdfBookmark struct {
+	title string
+	level int
+	page  int
+	y     float64
+}
+
+func (w *pdfWriter) AddBookmark(title string, level, page int, y float64) {
+	maxLevel := 0
+	if 0 < len(w.outline) {
+		maxLevel = w.outline[len(w.outline)-1].level + 1
+	}
+	if level < 0 {
+		level = 0
+	} else if maxLevel < level {
+		level = maxLevel
+	}
+	w.outline = append(w.outline, pdfBookmark{title, level, page, y})
+}
+
+func (w *pdfWriter) SetPDFA(pdfa bool) {
+	w.pdfa = pdfa
+}
+
+func (w *pdfWriter) SetCreationDate(creationDate time.Time) {
+	w.creationDate = creationDate
+}
+
 func (w *pdfWriter) SetTitle(title string) {
 	w.title = title
 }
@@ -276,6 +427,7 @@ func (w *pdfWriter) writeBytes(b []byte) {
 		return
 	}
 	n, err := w.w.Write(b)
+	w.hash.Write(b[:n])
 	w.pos += n
 	w.err = err
 }
@@ -284,7 +436,7 @@ func (w *pdfWriter) write(s string, v ...interface{}) {
 	if w.err != nil {
 		return
 	}
-	n, err := fmt.Fprintf(w.w, s, v...)
+	n, err := fmt.Fprintf(io.MultiWriter(w.w, w.hash), s, v...)
 	w.pos += n
 	w.err = err
 }
@@ -294,6 +446,7 @@ type pdfName string
 type pdfArray []interface{}
 type pdfDict map[pdfName]interface{}
 type pdfFilter string
+type pdfHexString []byte
 type pdfStream struct {
 	dict   pdfDict
 	stream []byte
@@ -302,10 +455,13 @@ type pdfStream struct {
 const (
 	pdfFilterASCII85 pdfFilter = "ASCII85Decode"
 	pdfFilterFlate   pdfFilter = "FlateDecode"
+	pdfFilterDCT     pdfFilter = "DCTDecode"
 )
 
 func (w *pdfWriter) writeVal(i interface{}) {
 	switch v := i.(type) {
+	case nil:
+		w.write("null")
 	case bool:
 		if v {
 			w.write("true")
@@ -317,13 +473,28 @@ func (w *pdfWriter) writeVal(i interface{}) {
 	case float64:
 		w.write("%v", dec(v))
 	case string:
+		for _, c := range v {
+			if c < 0x20 && c != '\n' && c != '\t' || 0x7F <= c {
+				// text strings that are not ASCII are encoded in UTF-16BE with a byte order mark
+				w.write("<FEFF")
+				for _, u := range utf16.Encode([]rune(v)) {
+					w.write("%04X", u)
+				}
+				w.write(">")
+				return
+			}
+		}
 		v = strings.Replace(v, `\`, `\\`, -1)
 		v = strings.Replace(v, `(`, `\(`, -1)
 		v = strings.Replace(v, `)`, `\)`, -1)
 		w.write("(%v)", v)
+	case pdfHexString:
+		w.write("<%X>", []byte(v))
 	case pdfRef:
 		w.write("%v 0 R", v)
-	case pdfName, pdfFilter:
+	case pdfName:
+		w.write("/%v", pdfEscapeName(string(v)))
+	case pdfFilter:
 		w.write("/%v", v)
 	case pdfArray:
 		w.write("[")
@@ -388,6 +559,8 @@ func (w *pdfWriter) writeVal(i interface{}) {
 				w := zlib.NewWriter(&b2)
 				w.Write(b)
 				w.Close()
+			case pdfFilterDCT:
+				b2.Write(b) // already JPEG encoded
 			}
 			b = b2.Bytes()
 		}
@@ -410,9 +583,56 @@ func (w *pdfWriter) writeObject(val interface{}) pdfRef {
 	return pdfRef(len(w.objOffsets))
 }
 
-func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
-	if ref, ok := w.fonts[font]; ok {
-		return ref
+// reserveObject returns a reference for an object that is written later using writeReservedObject.
+func (w *pdfWriter) reserveObject() pdfRef {
+	w.objOffsets = append(w.objOffsets, 0)
+	return pdfRef(len(w.objOffsets))
+}
+
+func (w *pdfWriter) writeReservedObject(ref pdfRef, val interface{}) {
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n", ref)
+	w.writeVal(val)
+	w.write("\nendobj\n")
+}
+
+type pdfFont struct {
+	ref       pdfRef
+	font      *canvas.Font
+	mediatype string
+	b         []byte
+	subset    bool
+
+	glyphIDs []uint16          // original glyph IDs by CID when subsetting
+	cids     map[uint16]uint16 // CIDs by original glyph ID when subsetting
+	runes    map[uint16]rune   // characters by CID for the ToUnicode CMap
+}
+
+// indicesOf returns the CIDs for the string, which are the glyph IDs of the font or of the subset, and records the characters of the glyphs.
+func (f *pdfFont) indicesOf(s string) []uint16 {
+	indices := f.font.IndicesOf(s)
+	runes := []rune(s)
+	for i, glyphID := range indices {
+		if f.subset {
+			cid, ok := f.cids[glyphID]
+			if !ok {
+				cid = uint16(len(f.glyphIDs))
+				f.glyphIDs = append(f.glyphIDs, glyphID)
+				f.cids[glyphID] = cid
+			}
+			indices[i] = cid
+		}
+		if _, ok := f.runes[indices[i]]; !ok && indices[i] != 0 {
+			f.runes[indices[i]] = runes[i]
+		}
+	}
+	return indices
+}
+
+// getFont returns the font resource, which is written when closing the PDF so that only the used glyphs are embedded.
+func (w *pdfWriter) getFont(font *canvas.Font) *pdfFont {
+	if f, ok := w.fonts[font]; ok {
+		return f
 	}
 
 	mediatype, b := font.Raw()
@@ -428,23 +648,71 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		}
 	}
 
-	ffSubtype := ""
-	cidSubtype := ""
-	if mediatype == "font/truetype" {
-		ffSubtype = "TrueType"
-		cidSubtype = "CIDFontType2"
-	} else if mediatype == "font/opentype" {
-		ffSubtype = "OpenType"
-		cidSubtype = "CIDFontType0"
+	f := &pdfFont{
+		ref:       w.reserveObject(),
+		font:      font,
+		mediatype: mediatype,
+		b:         b,
+		subset:    w.subset && mediatype == "font/truetype", // CFF fonts are not subset
+		glyphIDs:  []uint16{0},
+		cids:      map[uint16]uint16{0: 0},
+		runes:     map[uint16]rune{},
+	}
+	w.fonts[font] = f
+	return f
+}
+
+func (w *pdfWriter) writeFont(f *pdfFont) {
+	b := f.b
+	baseFont := strings.ReplaceAll(f.font.Name(), " ", "_")
+	var cidToGIDMap interface{} = pdfName("Identity")
+	if f.subset {
+		sfnt, err := canvasFont.ParseSFNT(append([]byte{}, b...))
+		if err == nil {
+			b, err = sfnt.Subset(f.glyphIDs)
+		}
+		if err != nil {
+			// embed the full font instead, the CIDs are mapped to the original glyph IDs
+			b = f.b
+			gids := make([]byte, 2*len(f.glyphIDs))
+			for cid, glyphID := range f.glyphIDs {
+				binary.BigEndian.PutUint16(gids[2*cid:], glyphID)
+			}
+			cidToGIDMap = w.writeObject(pdfStream{
+				dict: pdfDict{
+					"Filter": pdfFilterFlate,
+				},
+				stream: gids,
+			})
+		} else {
+			// subset fonts are prefixed by a tag of six uppercase letters
+			hash := fnv.New32a()
+			binary.Write(hash, binary.BigEndian, f.glyphIDs)
+			tag, h := make([]byte, 6), hash.Sum32()
+			for i := range tag {
+				tag[i] = 'A' + byte(h%26)
+				h /= 26
+			}
+			baseFont = string(tag) + "+" + baseFont
+		}
 	}
 
-	units := font.UnitsPerEm()
-	f := 1000 / units // factor to cancel the units and scale to 1000 (pdf spec)
+	units := f.font.UnitsPerEm()
+	s := 1000 / units // factor to cancel the units and scale to 1000 (pdf spec)
 
-	fWidths := font.Widths(units)
+	fWidths := f.font.Widths(units)
+	if f.subset {
+		subsetWidths := make([]float64, len(f.glyphIDs))
+		for cid, glyphID := range f.glyphIDs {
+			if int(glyphID) < len(fWidths) {
+				subsetWidths[cid] = fWidths[glyphID]
+			}
+		}
+		fWidths = subsetWidths
+	}
 	widths := make([]int, 0, len(fWidths))
 	for _, w := range fWidths {
-		widths = append(widths, int(w*f+0.5))
+		widths = append(widths, int(w*s+0.5))
 	}
 
 	// shorten glyph widths array
@@ -477,26 +745,40 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		W = append(W, i, arr)
 	}
 
-	baseFont := strings.ReplaceAll(font.Name(), " ", "_")
-	bounds := font.Bounds(units)
-	metrics := font.Metrics(units)
-	fontfileRef := w.writeObject(pdfStream{
+	cidSubtype := "CIDFontType2"
+	fontfileKey := pdfName("FontFile2")
+	fontfile := pdfStream{
 		dict: pdfDict{
-			"Subtype": pdfName(ffSubtype),
-			"Filter":  pdfFilterFlate,
+			"Filter": pdfFilterFlate,
 		},
 		stream: b,
+	}
+	if f.mediatype == "font/opentype" {
+		cidSubtype = "CIDFontType0"
+		fontfileKey = pdfName("FontFile3")
+		fontfile.dict["Subtype"] = pdfName("OpenType")
+	}
+
+	bounds := f.font.Bounds(units)
+	metrics := f.font.Metrics(units)
+	fontfileRef := w.writeObject(fontfile)
+	toUnicodeRef := w.writeObject(pdfStream{
+		dict: pdfDict{
+			"Filter": pdfFilterFlate,
+		},
+		stream: pdfToUnicode(f.runes),
 	})
-	ref := w.writeObject(pdfDict{
-		"Type":     pdfName("Font"),
-		"Subtype":  pdfName("Type0"),
-		"BaseFont": pdfName(baseFont),
-		"Encoding": pdfName("Identity-H"),
+	w.writeReservedObject(f.ref, pdfDict{
+		"Type":      pdfName("Font"),
+		"Subtype":   pdfName("Type0"),
+		"BaseFont":  pdfName(baseFont),
+		"Encoding":  pdfName("Identity-H"),
+		"ToUnicode": toUnicodeRef,
 		"DescendantFonts": pdfArray{pdfDict{
 			"Type":        pdfName("Font"),
 			"Subtype":     pdfName(cidSubtype),
 			"BaseFont":    pdfName(baseFont),
-			"CIDToGIDMap": pdfName("Identity"),
+			"CIDToGIDMap": cidToGIDMap,
 			"DW":          DW,
 			"W":           W,
 			"CIDSystemInfo": pdfDict{
@@ -508,53 +790,217 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 				"Type":        pdfName("FontDescriptor"),
 				"FontName":    pdfName(baseFont),
 				"Flags":       4,
-				"FontBBox":    pdfArray{int(f * bounds.X), -int(f * (bounds.Y + bounds.H)), int(f * (bounds.X + bounds.W)), -int(f * bounds.Y)},
-				"ItalicAngle": font.ItalicAngle(),
-				"Ascent":      int(f * metrics.Ascent),
-				"Descent":     -int(f * metrics.Descent),
-				"CapHeight":   -int(f * metrics.CapHeight),
+				"FontBBox":    pdfArray{int(s * bounds.X), -int(s * (bounds.Y + bounds.H)), int(s * (bounds.X + bounds.W)), -int(s * bounds.Y)},
+				"ItalicAngle": f.font.ItalicAngle(),
+				"Ascent":      int(s * metrics.Ascent),
+				"Descent":     -int(s * metrics.Descent),
+				"CapHeight":   -int(s * metrics.CapHeight),
 				"StemV":       80, // taken from Inkscape, should be calculated somehow
 				"StemH":       80,
-				"FontFile3":   fontfileRef,
+				fontfileKey:   fontfileRef,
 			},
 		}},
 	})
-	w.fonts[font] = ref
-	return ref
+}
+
+// pdfToUnicode returns a CMap that maps CIDs to Unicode characters, which is used for text extraction.
+func pdfToUnicode(runes map[uint16]rune) []byte {
+	cids := make([]int, 0, len(runes))
+	for cid := range runes {
+		cids = append(cids, int(cid))
+	}
+	sort.Ints(cids)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	fmt.Fprintf(buf, "/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	fmt.Fprintf(buf, "/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	fmt.Fprintf(buf, "1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(cids); i += 100 {
+		n := len(cids) - i
+		if 100 < n {
+			n = 100 // at most 100 entries per block
+		}
+		fmt.Fprintf(buf, "%d beginbfchar\n", n)
+		for _, cid := range cids[i : i+n] {
+			fmt.Fprintf(buf, "<%04X> <", cid)
+			for _, c := range utf16.Encode([]rune{runes[uint16(cid)]}) {
+				fmt.Fprintf(buf, "%04X", c)
+			}
+			fmt.Fprintf(buf, ">\n")
+		}
+		fmt.Fprintf(buf, "endbfchar\n")
+	}
+	fmt.Fprintf(buf, "endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return buf.Bytes()
+}
+
+// writeOutline writes the outline items with all items open and returns the reference to the outline dictionary.
+func (w *pdfWriter) writeOutline(pages pdfArray) pdfRef {
+	root := w.reserveObject()
+	refs := make([]pdfRef, len(w.outline))
+	for i := range w.outline {
+		refs[i] = w.reserveObject()
+	}
+
+	// find the parent and children of each item, where -1 is the root
+	parents := make([]int, len(w.outline))
+	children := map[int][]int{}
+	stack := []int{}
+	for i, bookmark := range w.outline {
+		stack = stack[:bookmark.level]
+		parent := -1
+		if 0 < len(stack) {
+			parent = stack[len(stack)-1]
+		}
+		parents[i] = parent
+		children[parent] = append(children[parent], i)
+		stack = append(stack, i)
+	}
+
+	// count returns the number of descendants of the item
+	var count func(int) int
+	count = func(i int) int {
+		n := len(children[i])
+		for _, child := range children[i] {
+			n += count(child)
+		}
+		return n
+	}
+	ref := func(i int) pdfRef {
+		if i == -1 {
+			return root
+		}
+		return refs[i]
+	}
+
+	for i, bookmark := range w.outline {
+		item := pdfDict{
+			"Title":  bookmark.title,
+			"Parent": ref(parents[i]),
+			"Dest":   pdfArray{pages[bookmark.page], pdfName("XYZ"), nil, bookmark.y * ptPerMm, nil},
+		}
+		siblings := children[parents[i]]
+		for j, sibling := range siblings {
+			if sibling == i {
+				if 0 < j {
+					item["Prev"] = refs[siblings[j-1]]
+				}
+				if j+1 < len(siblings) {
+					item["Next"] = refs[siblings[j+1]]
+				}
+			}
+		}
+		if kids := children[i]; 0 < len(kids) {
+			item["First"] = refs[kids[0]]
+			item["Last"] = refs[kids[len(kids)-1]]
+			item["Count"] = count(i)
+		}
+		w.writeReservedObject(refs[i], item)
+	}
+
+	kids := children[-1]
+	w.writeReservedObject(root, pdfDict{
+		"Type":  pdfName("Outlines"),
+		"First": refs[kids[0]],
+		"Last":  refs[kids[len(kids)-1]],
+		"Count": count(-1),
+	})
+	return root
 }
 
 func (w *pdfWriter) Close() error {
+	if w.pdfa {
+		for font, f := range w.fonts {
+			if !sfntEmbeddable(f.b) {
+				return fmt.Errorf("PDF/A: font %s does not allow embedding", font.Name())
+			}
+		}
+	}
+	for _, bookmark := range w.outline {
+		if bookmark.page < 0 || len(w.pages) <= bookmark.page {
+			return fmt.Errorf("bookmark %s: page %d does not exist", bookmark.title, bookmark.page)
+		}
+	}
+	if w.creationDate.IsZero() {
+		w.creationDate = time.Now()
+	}
+
 	// TODO: write pages directly to stream instead of using bytes.Buffer
 	kids := pdfArray{}
 	for _, p := range w.pages {
 		kids = append(kids, p.writePage(pdfRef(3)))
 	}
 
+	// fonts, in order of use
+	fonts := make([]*pdfFont, 0, len(w.fonts))
+	for _, f := range w.fonts {
+		fonts = append(fonts, f)
+	}
+	sort.Slice(fonts, func(i, j int) bool {
+		return fonts[i].ref < fonts[j].ref
+	})
+	for _, f := range fonts {
+		w.writeFont(f)
+	}
+
 	// document catalog
-	w.objOffsets[0] = w.pos
-	w.write("%v 0 obj\n", 1)
-	w.writeVal(pdfDict{
+	producer := "tdewolff/canvas"
+	part, conformance := 0, ""
+	if w.pdfa {
+		part, conformance = 2, "B"
+	}
+	catalog := pdfDict{
 		"Type":  pdfName("Catalog"),
 		"Pages": pdfRef(3),
-	})
+		"Metadata": w.writeObject(pdfStream{
+			dict: pdfDict{
+				"Type":    pdfName("Metadata"),
+				"Subtype": pdfName("XML"),
+			},
+			stream: xmpMetadata(w.title, w.subject, w.keywords, w.author, producer, w.creationDate, part, conformance),
+		}),
+	}
+	if 0 < len(w.outline) {
+		catalog["Outlines"] = w.writeOutline(kids)
+		catalog["PageMode"] = pdfName("UseOutlines")
+	}
+	if w.pdfa {
+		catalog["OutputIntents"] = pdfArray{pdfDict{
+			"Type":                      pdfName("OutputIntent"),
+			"S":                         pdfName("GTS_PDFA1"),
+			"OutputConditionIdentifier": "sRGB IEC61966-2.1",
+			"Info":                      "sRGB IEC61966-2.1",
+			"DestOutputProfile": w.writeObject(pdfStream{
+				dict: pdfDict{
+					"N":      3,
+					"Filter": pdfFilterFlate,
+				},
+				stream: srgbICCProfile(),
+			}),
+		}}
+	}
+	w.objOffsets[0] = w.pos
+	w.write("%v 0 obj\n", 1)
+	w.writeVal(catalog)
 	w.write("\nendobj\n")
 
 	// metadata
 	info := pdfDict{
-		"Producer":     "tdewolff/canvas",
-		"CreationDate": time.Now().Format("D:20060102150405Z0700"),
+		"Producer":     producer,
+		"CreationDate": pdfDate(w.creationDate),
 	}
 	if w.title != "" {
-		info["title"] = w.title
+		info["Title"] = w.title
 	}
 	if w.subject != "" {
-		info["subject"] = w.subject
+		info["Subject"] = w.subject
 	}
 	if w.keywords != "" {
-		info["keywords"] = w.keywords
+		info["Keywords"] = w.keywords
 	}
 	if w.author != "" {
-		info["author"] = w.author
+		info["Author"] = w.author
 	}
 
 	w.objOffsets[1] = w.pos
@@ -578,10 +1024,12 @@ func (w *pdfWriter) Close() error {
 		w.write("%010d 00000 n \n", objOffset)
 	}
 	w.write("trailer\n")
+	id := pdfHexString(w.hash.Sum(nil)) // hash of the document up to the trailer
 	w.writeVal(pdfDict{
 		"Root": pdfRef(1),
 		"Size": len(w.objOffsets) + 1,
 		"Info": pdfRef(2),
+		"ID":   pdfArray{id, id},
 	})
 	w.write("\nstartxref\n%v\n%%%%EOF", xrefOffset)
 	return w.err
@@ -592,11 +1040,14 @@ type pdfPageWriter struct {
 	pdf           *pdfWriter
 	width, height float64
 	resources     pdfDict
+	annots        pdfArray
 
 	graphicsStates map[float64]pdfName
+	blendStates    map[canvas.BlendMode]pdfName
 	alpha          float64
-	fillColor      color.RGBA
-	strokeColor    color.RGBA
+	blendMode      canvas.BlendMode
+	fillColor      color.Color // color.RGBA, color.CMYK or canvas.SpotColor
+	strokeColor    color.Color
 	lineWidth      float64
 	lineCap        int
 	lineJoin       int
@@ -611,15 +1062,27 @@ type pdfPageWriter struct {
 }
 
 func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
+	page := w.newContentWriter(width, height)
+	w.pages = append(w.pages, page)
+
+	m := canvas.Identity.Scale(ptPerMm, ptPerMm)
+	fmt.Fprintf(page, " %v %v %v %v %v %v cm", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	return page
+}
+
+// newContentWriter returns a writer for a content stream with its own resources and the default graphics state, which is used for pages and patterns.
+func (w *pdfWriter) newContentWriter(width, height float64) *pdfPageWriter {
 	// for defaults see https://help.adobe.com/pdfl_sdk/15/PDFL_SDK_HTMLHelp/PDFL_SDK_HTMLHelp/API_References/PDFL_API_Reference/PDFEdit_Layer/General.html#_t_PDEGraphicState
-	page := &pdfPageWriter{
+	return &pdfPageWriter{
 		Buffer:         &bytes.Buffer{},
 		pdf:            w,
 		width:          width,
 		height:         height,
 		resources:      pdfDict{},
 		graphicsStates: map[float64]pdfName{},
+		blendStates:    map[canvas.BlendMode]pdfName{},
 		alpha:          1.0,
+		blendMode:      canvas.BlendNormal,
 		fillColor:      canvas.Black,
 		strokeColor:    canvas.Black,
 		lineWidth:      1.0,
@@ -634,11 +1097,6 @@ func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
 		textCharSpace:  0.0,
 		textRenderMode: 0,
 	}
-	w.pages = append(w.pages, page)
-
-	m := canvas.Identity.Scale(ptPerMm, ptPerMm)
-	fmt.Fprintf(page, " %v %v %v %v %v %v cm", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
-	return page
 }
 
 func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
@@ -654,7 +1112,7 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 		stream.dict["Filter"] = pdfFilterFlate
 	}
 	contents := w.pdf.writeObject(stream)
-	return w.pdf.writeObject(pdfDict{
+	page := pdfDict{
 		"Type":      pdfName("Page"),
 		"Parent":    parent,
 		"MediaBox":  pdfArray{0.0, 0.0, w.width * ptPerMm, w.height * ptPerMm},
@@ -666,6 +1124,29 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 			"CS":   pdfName("DeviceRGB"),
 		},
 		"Contents": contents,
+	}
+	if 0 < len(w.annots) {
+		annots := pdfArray{}
+		for _, annot := range w.annots {
+			annots = append(annots, w.pdf.writeObject(annot))
+		}
+		page["Annots"] = annots
+	}
+	return w.pdf.writeObject(page)
+}
+
+// AddLink adds a link annotation to the URL over the rectangle in millimeters.
+func (w *pdfPageWriter) AddLink(url string, rect canvas.Rect) {
+	w.annots = append(w.annots, pdfDict{
+		"Type":    pdfName("Annot"),
+		"Subtype": pdfName("Link"),
+		"Rect":    pdfArray{rect.X * ptPerMm, rect.Y * ptPerMm, (rect.X + rect.W) * ptPerMm, (rect.Y + rect.H) * ptPerMm},
+		"Border":  pdfArray{0, 0, 0},
+		"F":       4, // print
+		"A": pdfDict{
+			"S":   pdfName("URI"),
+			"URI": url,
+		},
 	})
 }
 
@@ -677,6 +1158,15 @@ func (w *pdfPageWriter) SetAlpha(alpha float64) {
 	}
 }
 
+// SetBlendMode sets the blend mode through a graphics state with a BM entry.
+func (w *pdfPageWriter) SetBlendMode(mode canvas.BlendMode) {
+	if mode != w.blendMode {
+		gs := w.getBlendGS(mode)
+		fmt.Fprintf(w, " /%v gs", gs)
+		w.blendMode = mode
+	}
+}
+
 func (w *pdfPageWriter) SetFillColor(fillColor color.RGBA) {
 	a := float64(fillColor.A) / 255.0
 	if fillColor != w.fillColor {
@@ -703,6 +1193,44 @@ func (w *pdfPageWriter) SetStrokeColor(strokeColor color.RGBA) {
 	w.SetAlpha(a)
 }
 
+// SetFillCMYK sets the fill color in the DeviceCMYK color space with the given alpha.
+func (w *pdfPageWriter) SetFillCMYK(fillColor color.CMYK, alpha uint8) {
+	if fillColor != w.fillColor {
+		fmt.Fprintf(w, " %v %v %v %v k", dec(float64(fillColor.C)/255.0), dec(float64(fillColor.M)/255.0), dec(float64(fillColor.Y)/255.0), dec(float64(fillColor.K)/255.0))
+		w.fillColor = fillColor
+	}
+	w.SetAlpha(float64(alpha) / 255.0)
+}
+
+// SetStrokeCMYK sets the stroke color in the DeviceCMYK color space with the given alpha.
+func (w *pdfPageWriter) SetStrokeCMYK(strokeColor color.CMYK, alpha uint8) {
+	if strokeColor != w.strokeColor {
+		fmt.Fprintf(w, " %v %v %v %v K", dec(float64(strokeColor.C)/255.0), dec(float64(strokeColor.M)/255.0), dec(float64(strokeColor.Y)/255.0), dec(float64(strokeColor.K)/255.0))
+		w.strokeColor = strokeColor
+	}
+	w.SetAlpha(float64(alpha) / 255.0)
+}
+
+// SetFillSpot sets the fill color to the tint of a spot color in its separation color space with the given alpha.
+func (w *pdfPageWriter) SetFillSpot(fillColor canvas.SpotColor, alpha uint8) {
+	if fillColor != w.fillColor {
+		name := w.getSeparationCS(fillColor)
+		fmt.Fprintf(w, " /%v cs %v scn", name, dec(math.Max(0.0, math.Min(1.0, fillColor.Tint))))
+		w.fillColor = fillColor
+	}
+	w.SetAlpha(float64(alpha) / 255.0)
+}
+
+// SetStrokeSpot sets the stroke color to the tint of a spot color in its separation color space with the given alpha.
+func (w *pdfPageWriter) SetStrokeSpot(strokeColor canvas.SpotColor, alpha uint8) {
+	if strokeColor != w.strokeColor {
+		name := w.getSeparationCS(strokeColor)
+		fmt.Fprintf(w, " /%v CS %v SCN", name, dec(math.Max(0.0, math.Min(1.0, strokeColor.Tint))))
+		w.strokeColor = strokeColor
+	}
+	w.SetAlpha(float64(alpha) / 255.0)
+}
+
 func (w *pdfPageWriter) SetLineWidth(lineWidth float64) {
 	if lineWidth != w.lineWidth {
 		fmt.Fprintf(w, " %v w", dec(lineWidth))
@@ -727,6 +1255,7 @@ func (w *pdfPageWriter) SetLineCap(capper canvas.Capper) {
 	}
 }
 
+// SetLineJoin sets the line join and the miter limit.
 func (w *pdfPageWriter) SetLineJoin(joiner canvas.Joiner) {
 	var lineJoin int
 	var miterLimit float64
@@ -739,7 +1268,8 @@ func (w *pdfPageWriter) SetLineJoin(joiner canvas.Joiner) {
 		if math.IsNaN(miter.Limit) {
 			panic("PDF: line join not support")
 		} else {
-			miterLimit = miter.Limit
+			// the miter limit is the ratio of the miter length and the line width
+			miterLimit = math.Max(1.0, miter.Limit)
 		}
 	} else {
 		panic("PDF: line join not support")
@@ -754,27 +1284,33 @@ func (w *pdfPageWriter) SetLineJoin(joiner canvas.Joiner) {
 	}
 }
 
+// SetDashes sets the dash array and phase, an array of odd length is repeated twice like in SVG. A dash array with only zeros draws a solid line.
 func (w *pdfPageWriter) SetDashes(dashPhase float64, dashArray []float64) {
-	if len(dashArray)%2 == 1 {
-		dashArray = append(dashArray, dashArray...)
+	totalLength := 0.0
+	for _, dash := range dashArray {
+		totalLength += dash
 	}
 
-	// PDF can't handle negative dash phases
-	if dashPhase < 0.0 {
-		totalLength := 0.0
-		for _, dash := range dashArray {
-			totalLength += dash
+	// copy so that we never modify the dash array of the style
+	dashes := make([]float64, 0, 2*len(dashArray)+1)
+	if 0.0 < totalLength {
+		dashes = append(dashes, dashArray...)
+		if len(dashArray)%2 == 1 {
+			dashes = append(dashes, dashArray...)
+			totalLength *= 2.0
 		}
-		for dashPhase < 0.0 {
-			dashPhase += totalLength
+
+		// PDF can't handle negative dash phases
+		if dashPhase < 0.0 {
+			dashPhase = math.Mod(dashPhase, totalLength) + totalLength
 		}
+	} else {
+		dashPhase = 0.0
 	}
-
-	dashes := append(dashArray, dashPhase)
+	dashes = append(dashes, dashPhase)
 	if !float64sEqual(dashes, w.dashes) {
 		if len(dashes) == 1 {
 			fmt.Fprintf(w, " [] 0 d")
-			dashes[0] = 0.0
 		} else {
 			fmt.Fprintf(w, " [%v", dec(dashes[0]))
 			for _, dash := range dashes[1 : len(dashes)-1] {
@@ -794,7 +1330,7 @@ func (w *pdfPageWriter) SetFont(font *canvas.Font, size float64) {
 		w.font = font
 		w.fontSize = size
 
-		ref := w.pdf.getFont(font)
+		ref := w.pdf.getFont(font).ref
 		if _, ok := w.resources["Font"]; !ok {
 			w.resources["Font"] = pdfDict{}
 		} else {
@@ -884,7 +1420,7 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		}
 
 		buf := &bytes.Buffer{}
-		indices := w.font.IndicesOf(s)
+		indices := w.pdf.getFont(w.font).indicesOf(s)
 		binary.Write(buf, binary.BigEndian, indices)
 
 		s = buf.String()
@@ -939,7 +1475,257 @@ func (w *pdfPageWriter) DrawImage(img image.Image, enc canvas.ImageEncoding, m c
 	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
 }
 
+// FillPaint fills the path data, which is given in page coordinates, with a paint whose coordinates are mapped to page coordinates by m. Gradients are drawn as an axial or radial shading clipped by the path, where bounds are the bounds of the untransformed path. The shading has no varying opacity, and alpha is used for the whole fill. It returns false if the paint is not supported.
+func (w *pdfPageWriter) FillPaint(data string, rule canvas.FillRule, paint canvas.Paint, alpha float64, m canvas.Matrix, bounds canvas.Rect) bool {
+	corners := []canvas.Point{{bounds.X, bounds.Y}, {bounds.X + bounds.W, bounds.Y}, {bounds.X, bounds.Y + bounds.H}, {bounds.X + bounds.W, bounds.Y + bounds.H}}
+
+	var shading pdfDict
+	switch gradient := paint.(type) {
+	case canvas.LinearGradient:
+		if len(gradient.Stops) == 0 || gradient.X0 == gradient.X1 && gradient.Y0 == gradient.Y1 {
+			return false
+		}
+		t0, t1 := math.Inf(1), math.Inf(-1)
+		for _, p := range corners {
+			t := gradient.Offset(p.X, p.Y)
+			t0, t1 = math.Min(t0, t), math.Max(t1, t)
+		}
+		function, t0, t1, ok := pdfGradientFunction(gradient.Stops, gradient.Spread, t0, t1)
+		if !ok {
+			return false
+		}
+		dx, dy := gradient.X1-gradient.X0, gradient.Y1-gradient.Y0
+		shading = pdfDict{
+			"ShadingType": 2,
+			"Coords":      pdfArray{gradient.X0 + t0*dx, gradient.Y0 + t0*dy, gradient.X0 + t1*dx, gradient.Y0 + t1*dy},
+			"Domain":      pdfArray{t0, t1},
+			"Function":    function,
+		}
+	case canvas.ImagePattern:
+		w.fillPattern(data, rule, gradient, m)
+		return true
+	case canvas.PathPattern:
+		if gradient.Empty() {
+			return false
+		}
+		w.fillPathPattern(data, rule, gradient, m)
+		return true
+	case canvas.RadialGradient:
+		if len(gradient.Stops) == 0 || gradient.R == 0.0 {
+			return false // filled by the color of the last stop
+		}
+		t1 := 0.0
+		for _, p := range corners {
+			if t, ok := gradient.Offset(p.X, p.Y); ok {
+				t1 = math.Max(t1, t)
+			}
+		}
+		function, t0, t1, ok := pdfGradientFunction(gradient.Stops, gradient.Spread, 0.0, t1)
+		if !ok {
+			return false
+		}
+		dx, dy := gradient.CX-gradient.FX, gradient.CY-gradient.FY
+		shading = pdfDict{
+			"ShadingType": 3,
+			"Coords":      pdfArray{gradient.FX + t0*dx, gradient.FY + t0*dy, t0 * gradient.R, gradient.FX + t1*dx, gradient.FY + t1*dy, t1 * gradient.R},
+			"Domain":      pdfArray{t0, t1},
+			"Function":    function,
+		}
+	default:
+		return false
+	}
+	shading["ColorSpace"] = pdfName("DeviceRGB")
+	shading["Extend"] = pdfArray{true, true}
+
+	ref := w.pdf.writeObject(shading)
+	if _, ok := w.resources["Shading"]; !ok {
+		w.resources["Shading"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Sh%d", len(w.resources["Shading"].(pdfDict))))
+	w.resources["Shading"].(pdfDict)[name] = ref
+
+	fmt.Fprintf(w, " q %v W", data)
+	if rule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	prevAlpha := w.alpha
+	fmt.Fprintf(w, " n")
+	w.SetAlpha(alpha)
+	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v sh Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
+	w.alpha = prevAlpha
+	return true
+}
+
+// fillPattern fills the path data with an image pattern, which is written as a tiling pattern that draws the image. Along axes that do not repeat, the step is made so large that the image appears only once.
+func (w *pdfPageWriter) fillPattern(data string, rule canvas.FillRule, pattern canvas.ImagePattern, m canvas.Matrix) {
+	size := pattern.Img.Bounds().Size()
+	width, height := float64(size.X), float64(size.Y)
+	xStep, yStep := width, height
+	if !pattern.Repeat.RepeatsX() {
+		xStep = 1e6
+	}
+	if !pattern.Repeat.RepeatsY() {
+		yStep = 1e6
+	}
+
+	// the pattern matrix maps to the default coordinate system of the page, which is in points
+	pm := canvas.Identity.Scale(ptPerMm, ptPerMm).Mul(m).Mul(pattern.M)
+	ref := w.pdf.writeObject(pdfStream{
+		dict: pdfDict{
+			"Type":        pdfName("Pattern"),
+			"PatternType": 1,
+			"PaintType":   1,
+			"TilingType":  1,
+			"BBox":        pdfArray{0.0, 0.0, width, height},
+			"XStep":       xStep,
+			"YStep":       yStep,
+			"Matrix":      pdfArray{pm[0][0], pm[1][0], pm[0][1], pm[1][1], pm[0][2], pm[1][2]},
+			"Resources": pdfDict{
+				"XObject": pdfDict{"Im0": w.writeImage(pattern.Img, canvas.Lossless)},
+			},
+		},
+		stream: []byte(fmt.Sprintf("q %v 0 0 %v 0 0 cm /Im0 Do Q", dec(width), dec(height))),
+	})
+	w.fillWithPattern(ref, data, rule)
+}
+
+// fillWithPattern fills the path data with the pattern, which carries its own opacity.
+func (w *pdfPageWriter) fillWithPattern(ref pdfRef, data string, rule canvas.FillRule) {
+	if _, ok := w.resources["Pattern"]; !ok {
+		w.resources["Pattern"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("P%d", len(w.resources["Pattern"].(pdfDict))))
+	w.resources["Pattern"].(pdfDict)[name] = ref
+
+	prevAlpha := w.alpha
+	fmt.Fprintf(w, " q")
+	w.SetAlpha(1.0)
+	fmt.Fprintf(w, " /Pattern cs /%v scn %v f", name, data)
+	if rule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	fmt.Fprintf(w, " Q")
+	w.alpha = prevAlpha
+}
+
+// fillPathPattern fills the path data with a path pattern, which is written as a tiling pattern whose content draws the copies of the motif that overlap the cell.
+func (w *pdfPageWriter) fillPathPattern(data string, rule canvas.FillRule, pattern canvas.PathPattern, m canvas.Matrix) {
+	content := w.pdf.newContentWriter(pattern.CellW, pattern.CellH)
+	r := &PDF{w: content, width: pattern.CellW, height: pattern.CellH, imgEnc: canvas.Lossless}
+	style := pattern.Style
+	style.FillPaint, style.StrokePaint = nil, nil
+	for _, offset := range pattern.Offsets() {
+		r.RenderPath(pattern.Cell, style, canvas.Identity.Translate(offset.X, offset.Y))
+	}
+
+	pm := canvas.Identity.Scale(ptPerMm, ptPerMm).Mul(m).Mul(pattern.M)
+	ref := w.pdf.writeObject(pdfStream{
+		dict: pdfDict{
+			"Type":        pdfName("Pattern"),
+			"PatternType": 1,
+			"PaintType":   1,
+			"TilingType":  1,
+			"BBox":        pdfArray{0.0, 0.0, pattern.CellW, pattern.CellH},
+			"XStep":       pattern.CellW,
+			"YStep":       pattern.CellH,
+			"Matrix":      pdfArray{pm[0][0], pm[1][0], pm[0][1], pm[1][1], pm[0][2], pm[1][2]},
+			"Resources":   content.resources,
+		},
+		stream: content.Bytes(),
+	})
+	w.fillWithPattern(ref, data, rule)
+}
+
+// pdfGradientFunction returns the function of a shading for the gradient stops and its domain. For the pad spread method the domain is [0,1], otherwise it covers the range [t0,t1] that the path spans along the gradient by repeating the stops, which PDF does not support natively. It returns false if that range is too large.
+func pdfGradientFunction(stops []canvas.Stop, spread canvas.Spread, t0, t1 float64) (pdfDict, float64, float64, bool) {
+	if spread == canvas.PadSpread {
+		t0, t1 = 0.0, 1.0
+	} else {
+		t0, t1 = math.Floor(t0), math.Ceil(t1)
+		if t1 <= t0 {
+			t1 = t0 + 1.0
+		} else if 256.0 < t1-t0 {
+			return nil, 0.0, 0.0, false
+		}
+	}
+
+	// colors along the domain, where the first and last stop extend to the start and end of each period
+	type point struct {
+		t   float64
+		col color.RGBA
+	}
+	points := []point{}
+	for k := t0; k < t1; k++ {
+		period := append([]point{{0.0, stops[0].Color}}, make([]point, len(stops))...)
+		for i, stop := range stops {
+			period[i+1] = point{stop.Offset, stop.Color}
+		}
+		period = append(period, point{1.0, stops[len(stops)-1].Color})
+		if spread == canvas.ReflectSpread && math.Mod(math.Abs(k), 2.0) == 1.0 {
+			for i, j := 0, len(period)-1; i < j; i, j = i+1, j-1 {
+				period[i], period[j] = period[j], period[i]
+			}
+			for i := range period {
+				period[i].t = 1.0 - period[i].t
+			}
+		}
+		for _, p := range period {
+			points = append(points, point{k + p.t, p.col})
+		}
+	}
+
+	rgb := func(col color.RGBA) pdfArray {
+		if col.A == 0 {
+			return pdfArray{0.0, 0.0, 0.0}
+		}
+		a := float64(col.A) / 255.0
+		return pdfArray{math.Min(1.0, float64(col.R)/255.0/a), math.Min(1.0, float64(col.G)/255.0/a), math.Min(1.0, float64(col.B)/255.0/a)}
+	}
+	functions, domainBounds, encode := pdfArray{}, pdfArray{}, pdfArray{}
+	for i := 1; i < len(points); i++ {
+		if points[i].t <= points[i-1].t {
+			continue
+		}
+		if 0 < len(functions) {
+			domainBounds = append(domainBounds, points[i-1].t)
+		}
+		functions = append(functions, pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{0.0, 1.0},
+			"C0":           rgb(points[i-1].col),
+			"C1":           rgb(points[i].col),
+			"N":            1,
+		})
+		encode = append(encode, 0.0, 1.0)
+	}
+	if len(functions) == 1 && t0 == 0.0 && t1 == 1.0 {
+		return functions[0].(pdfDict), t0, t1, true
+	}
+	return pdfDict{
+		"FunctionType": 3,
+		"Domain":       pdfArray{t0, t1},
+		"Functions":    functions,
+		"Bounds":       domainBounds,
+		"Encode":       encode,
+	}, t0, t1, true
+}
+
 func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pdfName {
+	ref := w.writeImage(img, enc)
+	if _, ok := w.resources["XObject"]; !ok {
+		w.resources["XObject"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
+	w.resources["XObject"].(pdfDict)[name] = ref
+	return name
+}
+
+// writeImage writes the image as an XObject with a soft mask for transparency. The data of JPEG images is written as is.
+func (w *pdfPageWriter) writeImage(img image.Image, enc canvas.ImageEncoding) pdfRef {
+	if ref, ok := w.writeJPEG(img); ok {
+		return ref
+	}
+
 	size := img.Bounds().Size()
 	sp := img.Bounds().Min // starting point
 	b := make([]byte, size.X*size.Y*3)
@@ -989,19 +1775,121 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pd
 	}
 
 	// TODO: (PDF) implement JPXFilter for lossy image compression
-	ref := w.pdf.writeObject(pdfStream{
+	return w.pdf.writeObject(pdfStream{
 		dict:   dict,
 		stream: b,
 	})
+}
 
-	if _, ok := w.resources["XObject"]; !ok {
-		w.resources["XObject"] = pdfDict{}
+// writeJPEG writes a JPEG image with its original data as an XObject using the DCTDecode filter. It returns false if the image is not a JPEG image with gray or YCbCr colors.
+func (w *pdfPageWriter) writeJPEG(img image.Image) (pdfRef, bool) {
+	jpg, ok := img.(canvas.JPEGImage)
+	if !ok {
+		return 0, false
 	}
-	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
-	w.resources["XObject"].(pdfDict)[name] = ref
+	var colorSpace pdfName
+	switch jpg.Image.(type) {
+	case *image.Gray:
+		colorSpace = "DeviceGray"
+	case *image.YCbCr:
+		colorSpace = "DeviceRGB"
+	default:
+		return 0, false // Adobe CMYK images may be inverted
+	}
+	size := img.Bounds().Size()
+	return w.pdf.writeObject(pdfStream{
+		dict: pdfDict{
+			"Type":             pdfName("XObject"),
+			"Subtype":          pdfName("Image"),
+			"Width":            size.X,
+			"Height":           size.Y,
+			"ColorSpace":       colorSpace,
+			"BitsPerComponent": 8,
+			"Interpolate":      true,
+			"Filter":           pdfFilterDCT,
+		},
+		stream: jpg.Data,
+	}), true
+}
+
+// getSeparationCS returns the resource name of the separation color space of the spot color, which is shared by all spot colors with the same name in the document.
+func (w *pdfPageWriter) getSeparationCS(spot canvas.SpotColor) pdfName {
+	ref, ok := w.pdf.spots[spot.Name]
+	if !ok {
+		// the tint transform interpolates linearly between no ink and the alternate color
+		var alternate pdfName
+		var c0, c1 pdfArray
+		if cmyk, ok := spot.Alternate.(color.CMYK); ok {
+			alternate = "DeviceCMYK"
+			c0 = pdfArray{0.0, 0.0, 0.0, 0.0}
+			c1 = pdfArray{float64(cmyk.C) / 255.0, float64(cmyk.M) / 255.0, float64(cmyk.Y) / 255.0, float64(cmyk.K) / 255.0}
+		} else {
+			r, g, b, _ := spot.Alternate.RGBA()
+			alternate = "DeviceRGB"
+			c0 = pdfArray{1.0, 1.0, 1.0}
+			c1 = pdfArray{float64(r>>8) / 255.0, float64(g>>8) / 255.0, float64(b>>8) / 255.0}
+		}
+		ref = w.pdf.writeObject(pdfArray{
+			pdfName("Separation"),
+			pdfName(spot.Name),
+			alternate,
+			pdfDict{
+				"FunctionType": 2,
+				"Domain":       pdfArray{0.0, 1.0},
+				"C0":           c0,
+				"C1":           c1,
+				"N":            1,
+			},
+		})
+		w.pdf.spots[spot.Name] = ref
+	}
+
+	if _, ok := w.resources["ColorSpace"]; !ok {
+		w.resources["ColorSpace"] = pdfDict{}
+	}
+	for name, csRef := range w.resources["ColorSpace"].(pdfDict) {
+		if ref == csRef {
+			return name
+		}
+	}
+	name := pdfName(fmt.Sprintf("CS%d", len(w.resources["ColorSpace"].(pdfDict))))
+	w.resources["ColorSpace"].(pdfDict)[name] = ref
 	return name
 }
 
+func (w *pdfPageWriter) getBlendGS(mode canvas.BlendMode) pdfName {
+	if name, ok := w.blendStates[mode]; ok {
+		return name
+	}
+	name := pdfName(fmt.Sprintf("BM%d", len(w.blendStates)))
+	w.blendStates[mode] = name
+
+	if _, ok := w.resources["ExtGState"]; !ok {
+		w.resources["ExtGState"] = pdfDict{}
+	}
+	w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+		"BM": pdfName(pdfBlendMode(mode)),
+	}
+	return name
+}
+
+// pdfBlendMode returns the PDF name of the blend mode, such as ColorDodge.
+func pdfBlendMode(mode canvas.BlendMode) string {
+	name := []byte{}
+	upper := true
+	for _, c := range []byte(mode.String()) {
+		if c == '-' {
+			upper = true
+			continue
+		} else if upper {
+			c -= 'a' - 'A'
+			upper = false
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
 func (w *pdfPageWriter) getOpacityGS(a float64) pdfName {
 	if name, ok := w.graphicsStates[a]; ok {
 		return name
@@ -2,9 +2,21 @@ package pdf
 
 import (
 	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
 	"image"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf16"
 
 	"github.com/tdewolff/canvas"
 	"github.com/tdewolff/test"
@@ -59,6 +71,84 @@ func TestPDFPath(t *testing.T) {
 	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm /A0 gs 1 0 0 rg /A1 gs 0 0 1 RG 5 w 1 J 1 j [1 2 3 1 2 3] 2 d")
 }
 
+func TestPDFStrokeStyle(t *testing.T) {
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Transparent
+	style.StrokeColor = canvas.Black
+	style.StrokeWidth = 2.0
+	style.StrokeCapper = canvas.RoundCap
+	style.StrokeJoiner = canvas.RoundJoin
+	style.DashOffset = -1.0
+	style.Dashes = []float64{3.0, 2.0, 1.0}
+	polyline := canvas.MustParseSVG("M0 0L10 0L10 10L20 10")
+
+	pdf := New(&bytes.Buffer{}, 50.0, 50.0)
+	pdf.RenderPath(polyline, style, canvas.Identity.Translate(5.0, 5.0))
+	pdf.RenderPath(polyline, style, canvas.Identity.Translate(5.0, 25.0).Scale(2.0, 2.0))
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm 2 w 1 J 1 j [3 2 1 3 2 1] 11 d 5 5 m 15 5 l 15 15 l 25 15 l S 5 25 m 25 25 l 25 45 l 45 45 l S")
+	test.T(t, style.Dashes, []float64{3.0, 2.0, 1.0})
+
+	// the miter limit is the ratio of the miter length and the stroke width, which does not depend on the stroke width
+	style.StrokeJoiner = canvas.MiterJoiner{canvas.BevelJoin, 4.0}
+	style.Dashes = nil
+	pdf = New(&bytes.Buffer{}, 50.0, 50.0)
+	pdf.RenderPath(polyline, style, canvas.Identity)
+	style.StrokeWidth = 1.0
+	pdf.RenderPath(polyline, style, canvas.Identity)
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm 2 w 1 J 4 M 0 0 m 10 0 l 10 10 l 20 10 l S 1 w 0 0 m 10 0 l 10 10 l 20 10 l S")
+
+	// unsupported joiners are stroked explicitly in output coordinates
+	style.StrokeCapper = canvas.ButtCap
+	style.StrokeJoiner = canvas.ArcsJoin
+	pdf = New(&bytes.Buffer{}, 50.0, 50.0)
+	pdf.RenderPath(canvas.MustParseSVG("M0 0L10 0"), style, canvas.Identity.Scale(2.0, 2.0))
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm 0 -.5 20 1 re f")
+}
+
+func TestPDFCMYK(t *testing.T) {
+	c := canvas.New(50.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(color.CMYK{255, 0, 0, 0})
+	ctx.SetStrokeColor(color.CMYK{0, 0, 0, 255})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.DrawPath(20.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.SetFillColor(canvas.Red)
+	ctx.SetStrokeColor(color.CMYK{0, 0, 0, 255})
+	ctx.DrawPath(0.0, 20.0, canvas.Rectangle(10.0, 10.0))
+
+	pdf := New(&bytes.Buffer{}, c.W, c.H)
+	c.Render(pdf)
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm 1 0 0 0 k 0 0 0 1 K 2 M 0 0 10 10 re B 20 0 10 10 re B 1 0 0 rg 0 20 10 10 re B")
+}
+
+func TestPDFSpotColor(t *testing.T) {
+	pantone := canvas.SpotColor{"PANTONE 186 C", color.CMYK{0, 255, 204, 20}, 1.0}
+	gold := canvas.SpotColor{"Gold", color.RGBA{255, 204, 0, 255}, 0.5}
+
+	c := canvas.New(50.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(pantone)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	pantone.Tint = 0.25
+	ctx.SetFillColor(pantone)
+	ctx.SetStrokeColor(gold)
+	ctx.DrawPath(20.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm /CS0 cs 1 scn 0 0 10 10 re f /CS0 cs .25 scn /CS1 CS .5 SCN 2 M 20 0 10 10 re B")
+	test.Error(t, pdf.Close())
+
+	// both fills share the same color space object
+	out := buf.String()
+	test.T(t, strings.Count(out, "/Separation"), 2)
+	test.That(t, strings.Contains(out, "[/Separation /PANTONE#20186#20C /DeviceCMYK << /C0 [0 0 0 0] /C1 [0 1 .8 .07843137] /Domain [0 1] /FunctionType 2 /N 1 >>]"), out)
+	test.That(t, strings.Contains(out, "[/Separation /Gold /DeviceRGB << /C0 [1 1 1] /C1 [1 .8 0] /Domain [0 1] /FunctionType 2 /N 1 >>]"), out)
+	test.That(t, regexp.MustCompile(`/ColorSpace << /CS0 \d+ 0 R /CS1 \d+ 0 R >>`).MatchString(out), out)
+}
+
 func TestPDFText(t *testing.T) {
 	//dejaVuSerif := NewFontFamily("dejavu-serif")
 	//dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -93,6 +183,45 @@ func TestPDFImage(t *testing.T) {
 	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm q 0 0 2 2 re W n 0 0 m 0 2 l 2 2 l 2 0 l h W n 2 0 0 2 0 0 cm /Im0 Do Q")
 }
 
+func TestPDFImageTransformed(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 0, color.RGBA{0, 0, 0, 128})
+	var jpg bytes.Buffer
+	test.Error(t, jpeg.Encode(&jpg, img, nil))
+	jpgImg, err := canvas.NewJPEGImage(jpg.Bytes())
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawImageTransformed(img, canvas.Identity.Translate(10.0, 20.0).Rotate(90.0))
+	ctx.DrawImage(30.0, 20.0, jpgImg, 1.0)
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	test.That(t, strings.Contains(buf.String(), " 0 2 -2 0 10 20 cm /Im0 Do Q"), buf.String())
+	test.That(t, strings.Contains(buf.String(), " 2 0 0 2 30 20 cm /Im1 Do Q"), buf.String())
+
+	// soft mask, image with transparency and JPEG image
+	images, masked, jpegs := 0, 0, 0
+	for _, obj := range pdfObjects(t, buf.String()) {
+		if strings.Contains(obj, "/Subtype /Image") {
+			images++
+			if strings.Contains(obj, "/SMask") {
+				masked++
+			} else if strings.Contains(obj, "/Filter /DCTDecode") {
+				jpegs++
+				test.That(t, strings.Contains(obj, string(jpg.Bytes())), "JPEG data must be embedded untouched")
+			}
+		}
+	}
+	test.T(t, images, 3)
+	test.T(t, masked, 1)
+	test.T(t, jpegs, 1)
+}
+
 func TestPDFMultipage(t *testing.T) {
 	buf := &bytes.Buffer{}
 	pdf := New(buf, 210, 297)
@@ -106,3 +235,627 @@ func TestPDFMultipage(t *testing.T) {
 	nbPages := strings.Count(out, "/Type /Page ")
 	test.That(t, nbPages == 2, "expected 2 pages, got", nbPages)
 }
+
+func TestPDFPages(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	cs := []*canvas.Canvas{}
+	for _, size := range [][2]float64{{210.0, 297.0}, {297.0, 210.0}, {100.0, 50.0}} {
+		c := canvas.New(size[0], size[1])
+		ctx := canvas.NewContext(c)
+		ctx.DrawPath(10.0, 10.0, canvas.Rectangle(20.0, 10.0))
+		ctx.DrawText(10.0, 30.0, canvas.NewTextLine(face, "page", canvas.Left))
+		cs = append(cs, c)
+	}
+
+	buf := &bytes.Buffer{}
+	test.Error(t, WritePages(buf, cs...))
+	out := buf.String()
+
+	// read the objects and the page tree
+	objects := pdfObjects(t, out)
+	pages := objects["3"]
+	test.That(t, strings.Contains(pages, "/Type /Pages"), pages)
+	test.That(t, strings.Contains(pages, "/Count 3"), pages)
+	kids := pages[strings.Index(pages, "/Kids [")+7:]
+	kids = kids[:strings.Index(kids, "]")]
+	refs := strings.Split(strings.Replace(kids, " 0 R", "", -1), " ")
+	test.T(t, len(refs), 3)
+
+	mediaBoxes := []string{"[0 0 595.27559 841.88976]", "[0 0 841.88976 595.27559]", "[0 0 283.46457 141.73228]"}
+	for i, ref := range refs {
+		page := objects[ref]
+		test.That(t, strings.Contains(page, "/Type /Page "), page)
+		test.That(t, strings.Contains(page, "/Parent 3 0 R"), page)
+		test.That(t, strings.Contains(page, "/MediaBox "+mediaBoxes[i]), page)
+	}
+
+	// the font is shared between pages
+	test.T(t, strings.Count(out, "/Subtype /Type0"), 1)
+
+	// the cross-reference table has all objects
+	xref := out[strings.Index(out, "xref\n0 ")+7:]
+	test.T(t, xref[:strings.Index(xref, "\n")], strconv.Itoa(len(objects)+1))
+
+	test.That(t, WritePages(&bytes.Buffer{}) != nil, "no canvases must return an error")
+}
+
+// pdfObjects returns the objects by their object number.
+func pdfObjects(t *testing.T, out string) map[string]string {
+	objects := map[string]string{}
+	for _, obj := range strings.Split(out, "endobj")[:strings.Count(out, "endobj")] {
+		i := strings.Index(obj, " 0 obj\n")
+		test.That(t, i != -1)
+		fields := strings.Fields(obj[:i])
+		objects[fields[len(fields)-1]] = obj[i+7:]
+	}
+	return objects
+}
+
+// pdfRefValue returns the object number of the reference with the given key in a dictionary.
+func pdfRefValue(obj, key string) string {
+	i := strings.Index(obj, "/"+key+" ")
+	if i == -1 {
+		return ""
+	}
+	val := obj[i+len(key)+2:]
+	return val[:strings.Index(val, " ")]
+}
+
+// pdfStreamData returns the data of a stream object, decompressed if needed.
+func pdfStreamData(t *testing.T, obj string) string {
+	i := strings.Index(obj, " stream\n")
+	test.That(t, i != -1, obj)
+	dict := obj[:i]
+	n, err := strconv.Atoi(pdfRefValue(dict, "Length"))
+	test.Error(t, err)
+	stream := obj[i+8 : i+8+n]
+	test.String(t, obj[i+8+n:i+8+n+10], "\nendstream")
+	if strings.Contains(dict, "/Filter /FlateDecode") {
+		r, err := zlib.NewReader(strings.NewReader(stream))
+		test.Error(t, err)
+		b, err := ioutil.ReadAll(r)
+		test.Error(t, err)
+		stream = string(b)
+	}
+	return stream
+}
+
+// pdfContentStreams returns the page content streams, decompressed if needed.
+func pdfContentStreams(t *testing.T, out string) []string {
+	objects := pdfObjects(t, out)
+	streams := []string{}
+	for i := 1; i <= len(objects); i++ {
+		if obj := objects[strconv.Itoa(i)]; strings.Contains(obj, "/Type /Page ") {
+			streams = append(streams, pdfStreamData(t, objects[pdfRefValue(obj, "Contents")]))
+		}
+	}
+	return streams
+}
+
+func TestPDFCompression(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(0.1)
+	for i := 0; i < 1000; i++ {
+		x := float64(i%100) + 0.5
+		ctx.DrawPath(x, 0.0, canvas.MustParseSVG("M0 0L0.25 50L0 100"))
+	}
+
+	compressed, uncompressed := &bytes.Buffer{}, &bytes.Buffer{}
+	pdf := New(compressed, c.W, c.H)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+
+	pdf = New(uncompressed, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+
+	test.That(t, strings.Contains(compressed.String(), "/Filter /FlateDecode"))
+	test.That(t, !strings.Contains(uncompressed.String(), "/Filter /FlateDecode"))
+	test.That(t, compressed.Len() < uncompressed.Len()/5, compressed.Len(), uncompressed.Len())
+
+	streams := pdfContentStreams(t, compressed.String())
+	test.T(t, len(streams), 1)
+	test.String(t, streams[0], pdfContentStreams(t, uncompressed.String())[0])
+}
+
+// pdfExtractText returns the text of the content streams using the ToUnicode CMaps of the fonts. Text of consecutive TJ operators is separated by newlines.
+func pdfExtractText(t *testing.T, out string) string {
+	objects := pdfObjects(t, out)
+
+	// read the ToUnicode CMaps by font resource name
+	cmaps := map[string]map[string]string{}
+	for _, obj := range objects {
+		if !strings.Contains(obj, "/Type /Page ") {
+			continue
+		}
+		fonts := obj[strings.Index(obj, "/Font <<")+8:]
+		fonts = fonts[:strings.Index(fonts, ">>")]
+		fields := strings.Fields(fonts)
+		for i := 0; i+1 < len(fields); i += 3 {
+			cmap := map[string]string{}
+			toUnicode := pdfStreamData(t, objects[pdfRefValue(objects[fields[i+1]], "ToUnicode")])
+			for _, line := range strings.Split(toUnicode, "\n") {
+				var cid, unicode string
+				if n, _ := fmt.Sscanf(line, "<%4s> <%s", &cid, &unicode); n == 2 {
+					b, err := hex.DecodeString(strings.TrimSuffix(unicode, ">"))
+					test.Error(t, err)
+					units := make([]uint16, len(b)/2)
+					for j := range units {
+						units[j] = binary.BigEndian.Uint16(b[2*j:])
+					}
+					cmap[strings.ToUpper(cid)] = string(utf16.Decode(units))
+				}
+			}
+			cmaps[fields[i][1:]] = cmap
+		}
+	}
+
+	sb := strings.Builder{}
+	for _, stream := range pdfContentStreams(t, out) {
+		var cmap map[string]string
+		fields := strings.Fields(stream)
+		for i, field := range fields {
+			if field == "Tf" {
+				cmap = cmaps[fields[i-2][1:]]
+			}
+		}
+
+		inString := false
+		var s []byte
+		for i := 0; i < len(stream); i++ {
+			if !inString {
+				if stream[i] == '(' {
+					inString = true
+				} else if strings.HasPrefix(stream[i:], "]TJ") && 0 < sb.Len() {
+					sb.WriteString("\n")
+				}
+				continue
+			}
+			if stream[i] == '\\' {
+				i++
+				s = append(s, stream[i])
+			} else if stream[i] == ')' {
+				for j := 0; j+1 < len(s); j += 2 {
+					sb.WriteString(cmap[fmt.Sprintf("%04X", binary.BigEndian.Uint16(s[j:]))])
+				}
+				s = s[:0]
+				inString = false
+			} else {
+				s = append(s, stream[i])
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func TestPDFFontSubsetting(t *testing.T) {
+	ttf, err := ioutil.ReadFile("../font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, dejaVuSerif.LoadFont(ttf, canvas.FontRegular))
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(10.0, 80.0, canvas.NewTextLine(face, "Hello, World!", canvas.Left))
+	ctx.DrawText(10.0, 50.0, canvas.NewTextLine(face, "Ünïcödé (€)", canvas.Left))
+
+	var fontFiles [2]string
+	for i, subset := range []bool{true, false} {
+		buf := &bytes.Buffer{}
+		pdf := New(buf, c.W, c.H)
+		pdf.SetFontSubsetting(subset)
+		c.Render(pdf)
+		test.Error(t, pdf.Close())
+		out := buf.String()
+
+		objects := pdfObjects(t, out)
+		for _, obj := range objects {
+			if strings.Contains(obj, "/Subtype /Type0") {
+				test.That(t, strings.Contains(obj, "/Subtype /CIDFontType2"), obj)
+				test.That(t, strings.Contains(obj, "/CIDToGIDMap /Identity"), obj)
+				test.That(t, strings.Contains(obj, "/W ["), obj)
+				test.That(t, strings.Contains(obj, "+dejavu-serif") == subset, obj)
+				fontFiles[i] = pdfStreamData(t, objects[pdfRefValue(obj, "FontFile2")])
+			}
+		}
+		test.String(t, pdfExtractText(t, out), "Hello, World!\nÜnïcödé (€)")
+	}
+	test.That(t, len(fontFiles[0]) < len(ttf)/20, "subset font must be much smaller than the font file", len(fontFiles[0]), len(ttf))
+	test.T(t, len(fontFiles[1]), len(ttf))
+}
+
+func TestPDFFontSubsettingFailure(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(10.0, 80.0, canvas.NewTextLine(face, "Hello", canvas.Left))
+
+	// a font that cannot be subset is embedded in full, with its CIDs mapped to the glyph IDs
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	c.Render(pdf)
+	var glyphIDs []uint16
+	for _, f := range pdf.w.pdf.fonts {
+		f.b = f.b[:64]
+		glyphIDs = f.glyphIDs
+	}
+	test.Error(t, pdf.Close())
+	out := buf.String()
+
+	objects := pdfObjects(t, out)
+	found := false
+	for _, obj := range objects {
+		if strings.Contains(obj, "/Subtype /Type0") {
+			test.That(t, !strings.Contains(obj, "+dejavu-serif"), obj)
+			test.T(t, pdfStreamData(t, objects[pdfRefValue(obj, "FontFile2")]), string(pdf.w.pdf.fonts[face.Font].b))
+			gids := pdfStreamData(t, objects[pdfRefValue(obj, "CIDToGIDMap")])
+			test.T(t, len(gids), 2*len(glyphIDs))
+			for cid, glyphID := range glyphIDs {
+				test.T(t, binary.BigEndian.Uint16([]byte(gids[2*cid:])), glyphID)
+			}
+			found = true
+		}
+	}
+	test.That(t, found)
+	test.String(t, pdfExtractText(t, out), "Hello")
+}
+
+func TestPDFMetadata(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210.0, 297.0)
+	pdf.SetInfo("Résumé", "Subject", "a, b", "Jane <Doe>")
+	pdf.SetCreationDate(time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5*3600+30*60)))
+	test.Error(t, pdf.Close())
+	out := buf.String()
+	objects := pdfObjects(t, out)
+
+	info := objects["2"]
+	test.That(t, strings.Contains(info, "/CreationDate (D:20200102030405+05'30')"), info)
+	test.That(t, strings.Contains(info, "/Title <FEFF005200E900730075006D00E9>"), info)
+	test.That(t, strings.Contains(info, "/Subject (Subject)"), info)
+	test.That(t, strings.Contains(info, "/Keywords (a, b)"), info)
+	test.That(t, strings.Contains(info, "/Author (Jane <Doe>)"), info)
+
+	catalog := objects["1"]
+	metadata := objects[pdfRefValue(catalog, "Metadata")]
+	test.That(t, strings.Contains(metadata, "/Type /Metadata /Subtype /XML"), metadata)
+	test.That(t, !strings.Contains(catalog, "/OutputIntents"), catalog)
+
+	var xmp struct {
+		Description struct {
+			Title   string `xml:"title>Alt>li"`
+			Creator string `xml:"creator>Seq>li"`
+			Date    string `xml:"CreateDate"`
+			Part    string `xml:"part"`
+		} `xml:"RDF>Description"`
+	}
+	test.Error(t, xml.Unmarshal([]byte(pdfStreamData(t, metadata)), &xmp))
+	test.String(t, xmp.Description.Title, "Résumé")
+	test.String(t, xmp.Description.Creator, "Jane <Doe>")
+	test.String(t, xmp.Description.Date, "2020-01-02T03:04:05+05:30")
+	test.String(t, xmp.Description.Part, "")
+
+	trailer := out[strings.Index(out, "trailer\n"):]
+	id := regexp.MustCompile(`/ID \[<([0-9A-F]{32})> <([0-9A-F]{32})>\]`).FindStringSubmatch(trailer)
+	test.That(t, id != nil, trailer)
+	test.String(t, id[1], id[2])
+}
+
+func TestPDFA(t *testing.T) {
+	ttf, err := ioutil.ReadFile("../font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, dejaVuSerif.LoadFont(ttf, canvas.FontRegular))
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(10.0, 80.0, canvas.NewTextLine(face, "archive", canvas.Left))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetPDFA(true)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+	objects := pdfObjects(t, out)
+
+	catalog := objects["1"]
+	test.That(t, strings.Contains(catalog, "/OutputIntents [<< /Type /OutputIntent /DestOutputProfile "), catalog)
+	test.That(t, strings.Contains(catalog, "/S /GTS_PDFA1"), catalog)
+	metadata := pdfStreamData(t, objects[pdfRefValue(catalog, "Metadata")])
+	test.That(t, strings.Contains(metadata, "<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>"), metadata)
+
+	profile := pdfStreamData(t, objects[pdfRefValue(catalog, "DestOutputProfile")])
+	test.T(t, int(binary.BigEndian.Uint32([]byte(profile))), len(profile))
+	test.String(t, profile[12:24], "mntrRGB XYZ ")
+	test.String(t, profile[36:40], "acsp")
+	test.T(t, binary.BigEndian.Uint32([]byte(profile[128:])), uint32(9))
+
+	// fonts that do not allow embedding
+	i := strings.Index(string(ttf), "OS/2")
+	offset := binary.BigEndian.Uint32(ttf[i+8:])
+	restricted := append([]byte{}, ttf...)
+	binary.BigEndian.PutUint16(restricted[offset+8:], 0x0002)
+	test.That(t, sfntEmbeddable(ttf))
+	test.That(t, !sfntEmbeddable(restricted))
+
+	restrictedFamily := canvas.NewFontFamily("restricted")
+	test.Error(t, restrictedFamily.LoadFont(restricted, canvas.FontRegular))
+	face = restrictedFamily.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	ctx.DrawText(10.0, 50.0, canvas.NewTextLine(face, "restricted", canvas.Left))
+
+	pdf = New(&bytes.Buffer{}, c.W, c.H)
+	pdf.SetPDFA(true)
+	c.Render(pdf)
+	err = pdf.Close()
+	test.That(t, err != nil, "font that does not allow embedding must return an error")
+	test.That(t, strings.Contains(err.Error(), "does not allow embedding"), err)
+}
+
+func TestPDFLinks(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.StartLink("https://example.com/outer", 10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.StartLink("https://example.com/inner", 15.0, 22.0, canvas.Rectangle(5.0, 5.0))
+	ctx.DrawPath(15.0, 22.0, canvas.Rectangle(5.0, 5.0))
+	ctx.EndLink()
+	ctx.EndLink()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	objects := pdfObjects(t, buf.String())
+
+	var page string
+	for _, obj := range objects {
+		if strings.Contains(obj, "/Type /Page ") {
+			page = obj
+		}
+	}
+	annots := regexp.MustCompile(`/Annots \[(\d+) 0 R (\d+) 0 R\]`).FindStringSubmatch(page)
+	test.That(t, annots != nil, page)
+
+	// the PDF and canvas coordinate systems both have the y-axis pointing up
+	test.String(t, objects[annots[1]], "<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com/outer) >> /Border [0 0 0] /F 4 /Rect [28.346457 56.692913 85.03937 85.03937] >>\n")
+	test.String(t, objects[annots[2]], "<< /Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com/inner) >> /Border [0 0 0] /F 4 /Rect [42.519685 62.362205 56.692913 76.535433] >>\n")
+}
+
+func TestPDFBookmarks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210.0, 297.0)
+	pdf.NewPage(210.0, 297.0)
+	pdf.AddBookmark("Chapter 1", 0, 0, 280.0)
+	pdf.AddBookmark("Section 1.1", 1, 0, 200.0)
+	pdf.AddBookmark("Détails", 3, 1, 250.0) // clamped to level 2
+	pdf.AddBookmark("Section 1.2", 1, 1, 100.0)
+	pdf.AddBookmark("Chapter 2", 0, 1, 50.0)
+	test.Error(t, pdf.Close())
+	objects := pdfObjects(t, buf.String())
+
+	catalog := objects["1"]
+	test.That(t, strings.Contains(catalog, "/PageMode /UseOutlines"), catalog)
+	root := objects[pdfRefValue(catalog, "Outlines")]
+	test.That(t, strings.Contains(root, "/Type /Outlines"), root)
+	test.String(t, pdfRefValue(root, "Count"), "5")
+
+	item := func(ref, key string) string {
+		obj := objects[pdfRefValue(objects[ref], key)]
+		test.That(t, obj != "", ref, key)
+		return pdfRefValue(objects[ref], key)
+	}
+	title := func(ref string) string {
+		obj := objects[ref]
+		obj = obj[strings.Index(obj, "/Title ")+7:]
+		return obj[:strings.Index(obj, " >>")] // the title is the last key
+	}
+	outlines := pdfRefValue(catalog, "Outlines")
+	chapter1, chapter2 := item(outlines, "First"), item(outlines, "Last")
+	test.String(t, title(chapter1), "(Chapter 1)")
+	test.String(t, title(chapter2), "(Chapter 2)")
+	test.String(t, item(chapter1, "Next"), chapter2)
+	test.String(t, item(chapter2, "Prev"), chapter1)
+	test.String(t, item(chapter1, "Parent"), outlines)
+	test.String(t, pdfRefValue(objects[chapter1], "Count"), "3")
+	test.That(t, !strings.Contains(objects[chapter2], "/First"), objects[chapter2])
+
+	section11, section12 := item(chapter1, "First"), item(chapter1, "Last")
+	test.String(t, title(section11), "(Section 1.1)")
+	test.String(t, title(section12), "(Section 1.2)")
+	test.String(t, item(section11, "Next"), section12)
+	test.String(t, item(section11, "Parent"), chapter1)
+	test.String(t, pdfRefValue(objects[section11], "Count"), "1")
+
+	details := item(section11, "First")
+	test.String(t, item(section11, "Last"), details)
+	test.String(t, title(details), "<FEFF004400E9007400610069006C0073>")
+	test.String(t, item(details, "Parent"), section11)
+
+	// destinations point to the pages
+	pages := objects["3"]
+	kids := strings.Fields(pages[strings.Index(pages, "/Kids [")+7:])
+	test.That(t, strings.Contains(objects[chapter1], "/Dest ["+kids[0]+" 0 R /XYZ null 793.70079 null]"), objects[chapter1])
+	test.That(t, strings.Contains(objects[details], "/Dest ["+kids[3]+" 0 R /XYZ null 708.66142 null]"), objects[details])
+
+	pdf = New(&bytes.Buffer{}, 210.0, 297.0)
+	pdf.AddBookmark("Missing", 0, 1, 0.0)
+	test.That(t, pdf.Close() != nil, "bookmark to a page that does not exist must return an error")
+}
+
+func TestPDFBlur(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.StartBlur(1.0)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.EndBlur()
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	pdf.SetBlurResolution(canvas.DPMM(2.0))
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+
+	// the blurred rectangle is drawn as a transparent image that extends about three standard deviations beyond it
+	var img string
+	for _, obj := range pdfObjects(t, buf.String()) {
+		if strings.Contains(obj, "/Subtype /Image") && strings.Contains(obj, "/SMask") {
+			test.That(t, img == "", "only one image")
+			img = obj
+		}
+	}
+	test.That(t, img != "", "blur must be rasterized")
+	width, _ := strconv.Atoi(pdfRefValue(img, "Width"))
+	height, _ := strconv.Atoi(pdfRefValue(img, "Height"))
+	test.That(t, 40 < width && width <= 52, width)
+	test.That(t, 20 < height && height <= 32, height)
+}
+
+func TestPDFBlendMode(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctx.Push()
+	ctx.SetBlendMode(canvas.BlendColorDodge)
+	ctx.DrawPath(10.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctx.Pop()
+	ctx.DrawPath(20.0, 0.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	test.That(t, strings.Contains(buf.String(), "/BM0 << /BM /ColorDodge >>"), buf.String())
+	test.That(t, strings.Contains(buf.String(), "/BM1 << /BM /Normal >>"), buf.String())
+	test.That(t, strings.Contains(buf.String(), "re f /BM0 gs 10 0 20 10 re f /BM1 gs"), buf.String())
+}
+
+func TestPDFLinearGradient(t *testing.T) {
+	g, err := canvas.NewLinearGradient(0.0, 0.0, 20.0, 0.0, canvas.PadSpread, canvas.Stop{0.0, canvas.Black}, canvas.Stop{0.5, canvas.Red}, canvas.Stop{1.0, canvas.White})
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+
+	test.That(t, strings.Contains(out, " q 10 20 20 10 re W n 1 0 0 1 10 20 cm /Sh0 sh Q"), out)
+	test.That(t, strings.Contains(out, "/Shading << /Sh0 "), out)
+
+	var shading string
+	for _, obj := range pdfObjects(t, out) {
+		if strings.Contains(obj, "/ShadingType 2") {
+			shading = obj
+		}
+	}
+	test.That(t, shading != "", "axial shading expected")
+	test.That(t, strings.Contains(shading, "/Coords [0 0 20 0]"), shading)
+	test.That(t, strings.Contains(shading, "/FunctionType 3"), shading)
+	test.That(t, strings.Contains(shading, "/Bounds [.5]"), shading)
+}
+
+func TestPDFRadialGradient(t *testing.T) {
+	g, err := canvas.NewRadialGradient(10.0, 5.0, 10.0, 12.0, 5.0, canvas.PadSpread, canvas.Stop{0.0, canvas.White}, canvas.Stop{1.0, canvas.Black})
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+
+	var shading string
+	for _, obj := range pdfObjects(t, buf.String()) {
+		if strings.Contains(obj, "/ShadingType 3") {
+			shading = obj
+		}
+	}
+	test.That(t, shading != "", "radial shading expected")
+	test.That(t, strings.Contains(shading, "/Coords [12 5 0 10 5 10]"), shading)
+	test.That(t, strings.Contains(shading, "/C0 [1 1 1] /C1 [0 0 0]"), shading)
+}
+
+func TestPDFImagePattern(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.ImagePattern{img, canvas.Identity.Scale(5.0, 5.0), canvas.RepeatXY})
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+	test.That(t, strings.Contains(out, " q /Pattern cs /P0 scn 10 20 20 10 re f Q"), out)
+
+	var pattern string
+	for _, obj := range pdfObjects(t, out) {
+		if strings.Contains(obj, "/PatternType 1") {
+			pattern = obj
+		}
+	}
+	test.That(t, pattern != "", "tiling pattern expected")
+	test.That(t, strings.Contains(pattern, "/XStep 2 /YStep 2"), pattern)
+	test.That(t, strings.Contains(pattern, "/Resources << /XObject << /Im0 "), pattern)
+	test.That(t, strings.Contains(pattern, "q 2 0 0 2 0 0 cm /Im0 Do Q"), pattern)
+}
+
+func TestPDFPathPattern(t *testing.T) {
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Black
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.PathPattern{canvas.Rectangle(2.0, 2.0).Translate(-1.0, -1.0), 4.0, 4.0, style, canvas.Identity})
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, c.W, c.H)
+	pdf.SetCompression(false)
+	c.Render(pdf)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+	test.That(t, strings.Contains(out, " q /Pattern cs /P0 scn 10 20 20 10 re f Q"), out)
+
+	var pattern string
+	for _, obj := range pdfObjects(t, out) {
+		if strings.Contains(obj, "/PatternType 1") {
+			pattern = obj
+		}
+	}
+	test.That(t, pattern != "", "tiling pattern expected")
+	test.That(t, strings.Contains(pattern, "/XStep 4 /YStep 4"), pattern)
+
+	// a motif on the corner of the cell is drawn once for each cell it overlaps
+	test.T(t, strings.Count(pattern, " re f"), 4, pattern)
+	test.That(t, strings.Contains(pattern, "-1 -1 2 2 re f"), pattern)
+	test.That(t, strings.Contains(pattern, "3 3 2 2 re f"), pattern)
+}
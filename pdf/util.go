@@ -37,3 +37,17 @@ func (f dec) String() string {
 	}
 	return s
 }
+
+// pdfEscapeName escapes the characters of a name that are delimiters, whitespace or outside the printable ASCII range using the #XX notation, such as the spaces in /PANTONE#20186#20C.
+func pdfEscapeName(s string) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || '~' < c || strings.IndexByte("#()<>[]{}/%", c) != -1 {
+			fmt.Fprintf(&sb, "#%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/tdewolff/canvas"
@@ -12,3 +13,18 @@ func Writer(w io.Writer, c *canvas.Canvas) error {
 	c.Render(pdf)
 	return pdf.Close()
 }
+
+// WritePages writes the canvases as a PDF file with one page per canvas, where each page has the size of its canvas.
+func WritePages(w io.Writer, cs ...*canvas.Canvas) error {
+	if len(cs) == 0 {
+		return fmt.Errorf("no pages")
+	}
+	pdf := New(w, cs[0].W, cs[0].H)
+	for i, c := range cs {
+		if i != 0 {
+			pdf.NewPage(c.W, c.H)
+		}
+		c.Render(pdf)
+	}
+	return pdf.Close()
+}
@@ -0,0 +1,143 @@
+package rasterizer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Animation is a sequence of canvases of equal size that is encoded as an animated image.
+type Animation struct {
+	W, H   float64
+	frames []*canvas.Canvas
+	delays []time.Duration
+}
+
+// NewAnimation returns a new animation of the given size in millimeters.
+func NewAnimation(w, h float64) *Animation {
+	return &Animation{
+		W: w,
+		H: h,
+	}
+}
+
+// AddFrame adds a frame that is shown for the given duration, which is rounded to hundredths of a second. It returns an error if the canvas size is different from the animation size.
+func (a *Animation) AddFrame(c *canvas.Canvas, delay time.Duration) error {
+	if !canvas.Equal(c.W, a.W) || !canvas.Equal(c.H, a.H) {
+		return fmt.Errorf("frame size %gx%g differs from animation size %gx%g", c.W, c.H, a.W, a.H)
+	}
+	a.frames = append(a.frames, c)
+	a.delays = append(a.delays, delay)
+	return nil
+}
+
+// GIFAnimationOptions are the options for encoding an animated GIF.
+type GIFAnimationOptions struct {
+	LoopCount     int         // 0 loops forever, -1 shows each frame once, n repeats the animation n times
+	Disposal      byte        // disposal method of each frame, zero means gif.DisposalBackground or gif.DisposalNone when using deltas
+	SharedPalette bool        // use one palette for all frames instead of a palette per frame
+	Deltas        bool        // only encode the region that changed with respect to the previous frame, requires gif.DisposalNone
+	Background    color.Color // frames are drawn over the background color, nil is transparent
+	Dither        bool        // use Floyd-Steinberg dithering when mapping colors to the palette
+}
+
+// EncodeGIF rasterizes the frames at the given resolution and writes them as an animated GIF. Palettes of at most 256 colors are made using median cut quantization, see DrawPaletted. When using deltas, pixels that become transparent are not cleared, so an opaque background should be used if that may happen.
+func (a *Animation) EncodeGIF(w io.Writer, resolution canvas.DPMM, opts *GIFAnimationOptions) error {
+	if len(a.frames) == 0 {
+		return fmt.Errorf("animation has no frames")
+	}
+	if opts == nil {
+		opts = &GIFAnimationOptions{}
+	}
+	disposal := opts.Disposal
+	if opts.Deltas {
+		if disposal != 0 && disposal != gif.DisposalNone {
+			return fmt.Errorf("frame deltas require gif.DisposalNone disposal")
+		}
+		disposal = gif.DisposalNone
+	} else if disposal == 0 {
+		disposal = gif.DisposalBackground
+	}
+
+	imgs := make([]*image.RGBA, len(a.frames))
+	rects := make([]image.Rectangle, len(a.frames))
+	for i, c := range a.frames {
+		imgs[i] = image.NewRGBA(image.Rect(0, 0, int(a.W*float64(resolution)+0.5), int(a.H*float64(resolution)+0.5)))
+		if opts.Background != nil {
+			draw.Draw(imgs[i], imgs[i].Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+		}
+		c.Render(New(imgs[i], resolution))
+
+		rects[i] = imgs[i].Bounds()
+		if opts.Deltas && 0 < i {
+			rects[i] = changedBounds(imgs[i-1], imgs[i])
+		}
+	}
+
+	var shared color.Palette
+	if opts.SharedPalette {
+		shared = quantize(imgs, rects, 256)
+	}
+
+	size := imgs[0].Bounds().Size()
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, len(imgs)),
+		Delay:     make([]int, len(imgs)),
+		Disposal:  make([]byte, len(imgs)),
+		LoopCount: opts.LoopCount,
+		Config: image.Config{
+			Width:  size.X,
+			Height: size.Y,
+		},
+	}
+	if shared != nil {
+		g.Config.ColorModel = shared
+	}
+	for i, img := range imgs {
+		pal := shared
+		if pal == nil {
+			pal = quantize(imgs[i:i+1], rects[i:i+1], 256)
+		}
+		g.Image[i] = toPaletted(img, rects[i], pal, opts.Dither)
+		g.Delay[i] = int(math.Round(a.delays[i].Seconds() * 100.0))
+		g.Disposal[i] = disposal
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// changedBounds returns the bounding rectangle of the pixels that differ between both images. Since GIF frames cannot be empty, it returns a single pixel if the images are equal.
+func changedBounds(prev, cur *image.RGBA) image.Rectangle {
+	rect := image.Rectangle{}
+	bounds := cur.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if prev.RGBAAt(x, y) != cur.RGBAAt(x, y) {
+				rect = rect.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if rect.Empty() {
+		rect = image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+1, bounds.Min.Y+1)
+	}
+	return rect
+}
+
+// quantize returns a palette of at most n colors using median cut quantization of the colors in the given regions of the images.
+func quantize(imgs []*image.RGBA, rects []image.Rectangle, n int) color.Palette {
+	counts := map[color.RGBA]int{}
+	for i, img := range imgs {
+		for y := rects[i].Min.Y; y < rects[i].Max.Y; y++ {
+			for x := rects[i].Min.X; x < rects[i].Max.X; x++ {
+				counts[img.RGBAAt(x, y)]++
+			}
+		}
+	}
+	return medianCut(counts, n)
+}
@@ -0,0 +1,83 @@
+package rasterizer
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func animationFrame(x float64) *canvas.Canvas {
+	c := canvas.New(10.0, 5.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(x, 1.0, canvas.Rectangle(2.0, 2.0))
+	return c
+}
+
+func TestAnimationGIF(t *testing.T) {
+	a := NewAnimation(10.0, 5.0)
+	test.Error(t, a.AddFrame(animationFrame(0.0), 100*time.Millisecond))
+	test.Error(t, a.AddFrame(animationFrame(4.0), 200*time.Millisecond))
+	test.Error(t, a.AddFrame(animationFrame(4.0), 50*time.Millisecond))
+	test.That(t, a.AddFrame(canvas.New(5.0, 5.0), time.Second) != nil, "mismatched frame size must return an error")
+
+	for _, opts := range []*GIFAnimationOptions{
+		nil,
+		{LoopCount: 2, Disposal: gif.DisposalBackground},
+		{SharedPalette: true, Background: canvas.White},
+		{Dither: true, Background: canvas.White},
+		{Deltas: true, Background: canvas.White},
+	} {
+		buf := &bytes.Buffer{}
+		test.Error(t, a.EncodeGIF(buf, 2.0, opts))
+
+		g, err := gif.DecodeAll(buf)
+		test.Error(t, err)
+		test.T(t, len(g.Image), 3)
+		test.T(t, g.Delay, []int{10, 20, 5})
+		test.T(t, g.Config.Width, 20)
+		test.T(t, g.Config.Height, 10)
+		if opts != nil {
+			test.T(t, g.LoopCount, opts.LoopCount)
+		}
+		if opts != nil && opts.Deltas {
+			test.T(t, g.Disposal[0], byte(gif.DisposalNone))
+		} else {
+			test.T(t, g.Disposal[0], byte(gif.DisposalBackground))
+		}
+
+		// compose frames as a viewer would
+		img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+		frames := []*image.RGBA{}
+		for i, frame := range g.Image {
+			if 0 < i && g.Disposal[i-1] == gif.DisposalBackground {
+				prev := g.Image[i-1].Bounds()
+				draw.Draw(img, prev, image.Transparent, image.Point{}, draw.Src)
+			}
+			draw.Draw(img, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+			frames = append(frames, image.NewRGBA(img.Bounds()))
+			copy(frames[len(frames)-1].Pix, img.Pix)
+		}
+		red := color.RGBAModel.Convert(canvas.Red)
+		test.T(t, frames[0].At(1, 5), red)
+		test.That(t, frames[1].At(1, 5) != red, "pixel must change between frames")
+		test.T(t, frames[1].At(9, 5), red)
+		test.T(t, frames[2].At(9, 5), red)
+
+		if opts != nil && opts.Deltas {
+			test.T(t, g.Image[0].Bounds(), image.Rect(0, 0, 20, 10))
+			test.T(t, g.Image[1].Bounds(), image.Rect(0, 4, 12, 8))
+			test.T(t, g.Image[2].Bounds(), image.Rect(0, 0, 1, 1))
+		}
+	}
+
+	test.That(t, a.EncodeGIF(&bytes.Buffer{}, 2.0, &GIFAnimationOptions{Deltas: true, Disposal: gif.DisposalPrevious}) != nil, "deltas with disposal must return an error")
+	test.That(t, NewAnimation(10.0, 5.0).EncodeGIF(&bytes.Buffer{}, 2.0, nil) != nil, "no frames must return an error")
+}
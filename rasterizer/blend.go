@@ -0,0 +1,51 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// drawBlend composites the source over the destination in the rectangle like draw.DrawMask with draw.Over, but mixes the colors with the blend mode where they overlap. The mask may be nil for full coverage.
+func drawBlend(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mask image.Image, mp image.Point, mode canvas.BlendMode) {
+	clip := r.Intersect(dst.Bounds())
+	if clip.Empty() {
+		return
+	}
+	sp = sp.Add(clip.Min.Sub(r.Min))
+	mp = mp.Add(clip.Min.Sub(r.Min))
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		for x := clip.Min.X; x < clip.Max.X; x++ {
+			dx, dy := x-clip.Min.X, y-clip.Min.Y
+			coverage := uint32(0xffff)
+			if mask != nil {
+				if _, _, _, coverage = mask.At(mp.X+dx, mp.Y+dy).RGBA(); coverage == 0 {
+					continue
+				}
+			}
+			sr, sg, sb, sa := src.At(sp.X+dx, sp.Y+dy).RGBA()
+			if sa == 0 {
+				continue
+			}
+			dr, dg, db, da := dst.At(x, y).RGBA()
+			dst.Set(x, y, blendColor(dr, dg, db, da, sr*coverage/0xffff, sg*coverage/0xffff, sb*coverage/0xffff, sa*coverage/0xffff, mode))
+		}
+	}
+}
+
+// blendColor returns the source color composited over the backdrop color with the blend mode, where both are premultiplied 16-bit colors. See https://www.w3.org/TR/compositing-1/#blending
+func blendColor(dr, dg, db, da, sr, sg, sb, sa uint32, mode canvas.BlendMode) color.RGBA64 {
+	ab, as := float64(da)/0xffff, float64(sa)/0xffff
+	channel := func(d, s uint32) uint16 {
+		cb, cs := float64(d)/0xffff, float64(s)/0xffff
+		c := cs*(1.0-ab) + cb*(1.0-as)
+		if 0.0 < ab && 0.0 < as {
+			c += as * ab * mode.Blend(math.Min(1.0, cb/ab), math.Min(1.0, cs/as))
+		}
+		return uint16(c*0xffff + 0.5)
+	}
+	return color.RGBA64{channel(dr, sr), channel(dg, sg), channel(db, sb), uint16((as+ab-as*ab)*0xffff + 0.5)}
+}
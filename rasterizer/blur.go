@@ -0,0 +1,114 @@
+package rasterizer
+
+import (
+	"image"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// Blur blurs the image in place with a Gaussian of standard deviation sigma in pixels, which is a no-op when sigma is not positive. The kernel is normalized and pixels outside the image are transparent, so that the total alpha is preserved except for what spreads beyond the bounds, and since premultiplied colors are blurred the borders are faded out but not darkened.
+func Blur(img *image.RGBA, sigma float64) {
+	if sigma <= 0.0 || img.Rect.Empty() {
+		return
+	}
+
+	// Gaussian kernel up to three standard deviations
+	radius := int(math.Ceil(3.0 * sigma))
+	kernel := make([]float32, 2*radius+1)
+	sum := 0.0
+	for i := range kernel {
+		x := float64(i - radius)
+		k := math.Exp(-x * x / (2.0 * sigma * sigma))
+		kernel[i] = float32(k)
+		sum += k
+	}
+	for i := range kernel {
+		kernel[i] /= float32(sum)
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	buf := make([]float32, 4*w*h)
+	n := w
+	if n < h {
+		n = h
+	}
+	line := make([]float32, 4*n)
+
+	// horizontal pass from the image into the buffer
+	for y := 0; y < h; y++ {
+		pix := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			for c := 0; c < 4; c++ {
+				line[4*x+c] = float32(pix[4*x+c])
+			}
+		}
+		convolve(buf[4*w*y:4*w*(y+1)], line[:4*w], kernel)
+	}
+
+	// vertical pass from the buffer into the image
+	col := make([]float32, 4*h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			copy(line[4*y:4*y+4], buf[4*(w*y+x):])
+		}
+		convolve(col, line[:4*h], kernel)
+		for y := 0; y < h; y++ {
+			pix := img.Pix[img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y):]
+			a := col[4*y+3]
+			for c := 0; c < 4; c++ {
+				// premultiplied colors never exceed alpha
+				pix[c] = uint8(math.Min(float64(col[4*y+c]), float64(a)) + 0.5)
+			}
+		}
+	}
+}
+
+// convolve convolves a line of RGBA pixels with the kernel into dst, where pixels outside the line are transparent.
+func convolve(dst, src, kernel []float32) {
+	n := len(src) / 4
+	radius := len(kernel) / 2
+	for x := 0; x < n; x++ {
+		var r, g, b, a float32
+		i0, i1 := x-radius, x+radius
+		if i0 < 0 {
+			i0 = 0
+		}
+		if n <= i1 {
+			i1 = n - 1
+		}
+		for i := i0; i <= i1; i++ {
+			k := kernel[i-x+radius]
+			r += k * src[4*i]
+			g += k * src[4*i+1]
+			b += k * src[4*i+2]
+			a += k * src[4*i+3]
+		}
+		dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = r, g, b, a
+	}
+}
+
+// DrawBlurred draws the canvas at the given resolution, blurs it with a Gaussian of standard deviation sigma in millimeters and crops it to the visible pixels. It returns the image and the matrix that draws it in place with Renderer.RenderImage, or a nil image when nothing is visible. It is used by the renderers that do not support blur natively, such as PDF and EPS.
+func DrawBlurred(c *canvas.Canvas, resolution canvas.DPMM, sigma float64) (image.Image, canvas.Matrix) {
+	img := Draw(c, resolution)
+	Blur(img, sigma*float64(resolution))
+
+	crop := image.Rectangle{}
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] != 0 {
+				crop = crop.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if crop.Empty() {
+		return nil, canvas.Identity
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(dst, dst.Rect, img, crop.Min, draw.Src)
+	dpm := float64(resolution)
+	m := canvas.Identity.Translate(float64(crop.Min.X)/dpm, float64(img.Rect.Max.Y-crop.Max.Y)/dpm).Scale(1.0/dpm, 1.0/dpm)
+	return dst, m
+}
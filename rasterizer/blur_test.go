@@ -0,0 +1,140 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func alphaSum(img *image.RGBA) int {
+	sum := 0
+	for i := 3; i < len(img.Pix); i += 4 {
+		sum += int(img.Pix[i])
+	}
+	return sum
+}
+
+func TestBlur(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 60, 50))
+	for y := 20; y < 30; y++ {
+		for x := 20; x < 35; x++ {
+			img.SetRGBA(x, y, color.RGBA{0, 128, 128, 128})
+		}
+	}
+	orig := image.NewRGBA(img.Rect)
+	copy(orig.Pix, img.Pix)
+
+	// sigma of zero is a no-op
+	Blur(img, 0.0)
+	test.T(t, img.Pix, orig.Pix)
+
+	// kernel is normalized
+	Blur(img, 3.0)
+	test.That(t, img.RGBAAt(19, 25).A < 128 && 0 < img.RGBAAt(12, 25).A, img.RGBAAt(19, 25), img.RGBAAt(12, 25))
+	sum, origSum := alphaSum(img), alphaSum(orig)
+	test.That(t, math.Abs(float64(sum-origSum)) < 0.01*float64(origSum), sum, origSum)
+	for i := 0; i < len(img.Pix); i += 4 {
+		test.That(t, img.Pix[i+1] <= img.Pix[i+3] && img.Pix[i] == 0, img.Pix[i:i+4])
+	}
+
+	// borders fade out but are not darkened
+	img = image.NewRGBA(image.Rect(10, 10, 30, 30))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+		if i%4 == 2 {
+			img.Pix[i] = 0
+		}
+	}
+	Blur(img, 2.0)
+	corner := img.RGBAAt(10, 10)
+	test.That(t, corner.A < 128, corner)
+	test.T(t, corner.R, corner.A)
+	test.T(t, corner.G, corner.A)
+	test.T(t, img.RGBAAt(20, 20), color.RGBA{255, 255, 0, 255})
+}
+
+func TestRendererBlur(t *testing.T) {
+	c := canvas.New(30.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(2.0, 2.0, canvas.Rectangle(6.0, 6.0))
+	ctx.StartBlur(1.0)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(12.0, 5.0, canvas.Rectangle(10.0, 10.0))
+	ctx.EndBlur()
+
+	img := Draw(c, canvas.DPMM(2.0))
+	test.T(t, img.RGBAAt(10, 30), color.RGBA{0, 0, 255, 255}) // unblurred
+	test.T(t, img.RGBAAt(34, 20), color.RGBA{255, 0, 0, 255}) // center of the blurred square
+	edge := img.RGBAAt(22, 20)
+	test.That(t, 0 < edge.A && edge.A < 255 && edge.R == edge.A, edge)
+	outside := img.RGBAAt(20, 20)
+	test.That(t, 0 < outside.A && outside.A < edge.A, outside)
+
+	// blurring without drawing anything leaves the image unchanged
+	c2 := canvas.New(30.0, 20.0)
+	c2.StartBlur(2.0)
+	c2.EndBlur()
+	test.T(t, alphaSum(Draw(c2, canvas.DPMM(2.0))), 0)
+
+	parallel := DrawWithOptions(c, canvas.DPMM(2.0), &DrawOptions{Workers: 3})
+	test.T(t, parallel.Pix, img.Pix)
+}
+
+func TestDrawBlurred(t *testing.T) {
+	c := canvas.New(30.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(10.0, 5.0, canvas.Rectangle(5.0, 5.0))
+
+	img, m := DrawBlurred(c, canvas.DPMM(2.0), 1.0)
+	size := img.Bounds().Size()
+	test.That(t, 10 < size.X && size.X < 30 && 10 < size.Y && size.Y < 30, size)
+
+	// the image is centered on the square
+	center := m.Dot(canvas.Point{float64(size.X) / 2.0, float64(size.Y) / 2.0})
+	test.Float(t, center.X, 12.5)
+	test.Float(t, center.Y, 7.5)
+
+	img, _ = DrawBlurred(canvas.New(30.0, 20.0), canvas.DPMM(2.0), 1.0)
+	test.That(t, img == nil)
+}
+
+func TestRendererShadow(t *testing.T) {
+	shadow := color.RGBA{0, 0, 0, 128}
+	c := canvas.New(30.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.SetShadow(3.0, -1.0, 0.5, shadow)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(10.0, 10.0))
+
+	// same as drawing the blurred silhouette by hand
+	golden := canvas.New(30.0, 20.0)
+	ctx = canvas.NewContext(golden)
+	ctx.StartBlur(0.5)
+	ctx.SetFillColor(shadow)
+	ctx.DrawPath(8.0, 4.0, canvas.Rectangle(10.0, 10.0))
+	ctx.EndBlur()
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(10.0, 10.0))
+
+	img := Draw(c, canvas.DPMM(2.0))
+	test.T(t, img.Pix, Draw(golden, canvas.DPMM(2.0)).Pix)
+	test.T(t, img.RGBAAt(20, 20), color.RGBA{255, 0, 0, 255})
+	test.T(t, img.RGBAAt(31, 24), shadow) // right of the square
+	test.T(t, img.RGBAAt(8, 20), color.RGBA{})
+
+	// the offset is in the coordinate system of the view
+	c = canvas.New(30.0, 20.0)
+	ctx = canvas.NewContext(c)
+	ctx.SetShadow(4.0, 0.0, 0.0, shadow)
+	ctx.RotateAbout(90.0, 15.0, 10.0)
+	ctx.DrawPath(10.0, 5.0, canvas.Rectangle(10.0, 10.0))
+	img = Draw(c, canvas.DPMM(2.0))
+	test.T(t, img.RGBAAt(30, 6), shadow)        // above the square
+	test.T(t, img.RGBAAt(42, 20), color.RGBA{}) // not to the right
+	test.T(t, img.RGBAAt(30, 20), canvas.Black) // square on top
+}
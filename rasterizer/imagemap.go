@@ -0,0 +1,142 @@
+package rasterizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// HitArea is a polygon of a hit region in pixels of the image, see HitAreas.
+type HitArea struct {
+	ID      string   `json:"id"`
+	Polygon [][2]int `json:"polygon"` // vertices in clockwise order, where the y-axis points down
+}
+
+// HitAreaOptions are the options for HitAreas.
+type HitAreaOptions struct {
+	Tolerance float64 // maximum deviation of the polygons from the regions in pixels, zero uses half a pixel
+	Prune     bool    // omit regions that are hidden by opaque regions drawn later
+}
+
+// HitAreas returns the polygons of the hit regions of the canvas, see canvas.Context.SetHitID, in pixels of the image that is drawn at the given resolution by Draw or PNGWriter. The areas of the topmost regions come first, so that the first area that contains a point is the one that is hit, as for HTML image maps. Holes in the regions, such as the inside of a stroked circle, are not represented. When pruning, regions of which no pixel is visible because of the opaque regions above them are omitted. The options may be nil.
+func HitAreas(c *canvas.Canvas, resolution canvas.DPMM, opts *HitAreaOptions) []HitArea {
+	if opts == nil {
+		opts = &HitAreaOptions{}
+	}
+	tolerance := opts.Tolerance
+	if tolerance <= 0.0 {
+		tolerance = 0.5
+	}
+
+	// flatten in a scaled coordinate system so that canvas.Tolerance corresponds to the tolerance in pixels
+	w, h := c.SizePx(resolution)
+	scale := canvas.Tolerance / tolerance
+	m := canvas.Identity.Scale(scale, scale).Translate(0.0, float64(h)).Scale(float64(resolution), -float64(resolution))
+	m2 := canvas.Identity.Scale(1.0/scale, 1.0/scale)
+
+	var covered []uint8 // pixels that are hidden by opaque regions
+	if opts.Prune {
+		covered = make([]uint8, w*h)
+	}
+
+	areas := []HitArea{}
+	regions := c.HitRegions()
+	for i := len(regions) - 1; 0 <= i; i-- {
+		region := regions[i]
+		if opts.Prune {
+			mask := RasterizeMask(region.Path, w, h, canvas.Identity.Scale(float64(resolution), float64(resolution)), region.FillRule)
+			visible := false
+			for j, a := range mask.Pix {
+				if 128 <= a && covered[j] < 128 {
+					visible = true
+				}
+				if region.Opaque && covered[j] < a {
+					covered[j] = a
+				}
+			}
+			if !visible {
+				continue
+			}
+		}
+
+		// skip degenerate subpaths, which have no area
+		path := &canvas.Path{}
+		subpaths := []*canvas.Path{}
+		for _, subpath := range region.Path.Transform(m).Flatten().Transform(m2).Split() {
+			if 3 <= len(subpath.Coords()) {
+				path = path.Append(subpath.Copy())
+				subpaths = append(subpaths, subpath)
+			}
+		}
+		fillings := path.Filling(region.FillRule)
+		for j, subpath := range subpaths {
+			if !fillings[j] {
+				continue
+			}
+			if polygon := hitPolygon(subpath.Coords()); polygon != nil {
+				areas = append(areas, HitArea{region.ID, polygon})
+			}
+		}
+	}
+	return areas
+}
+
+// hitPolygon returns the polygon through the coordinates rounded to pixels in clockwise order, or nil if it has no area.
+func hitPolygon(coords []canvas.Point) [][2]int {
+	polygon := [][2]int{}
+	for _, coord := range coords {
+		p := [2]int{int(coord.X + 0.5), int(coord.Y + 0.5)}
+		if len(polygon) == 0 || p != polygon[len(polygon)-1] {
+			polygon = append(polygon, p)
+		}
+	}
+	if 1 < len(polygon) && polygon[0] == polygon[len(polygon)-1] {
+		polygon = polygon[:len(polygon)-1]
+	}
+
+	// shoelace formula, which is positive for clockwise polygons when the y-axis points down
+	area := 0
+	for i := range polygon {
+		p, q := polygon[i], polygon[(i+1)%len(polygon)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	if area == 0 {
+		return nil
+	} else if area < 0 {
+		for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+			polygon[i], polygon[j] = polygon[j], polygon[i]
+		}
+	}
+	return polygon
+}
+
+// ImageMapWriter writes the hit areas of the canvas as an HTML <map> element with the given name, to be used with an image of the canvas at the same resolution by <img usemap="#name">. Each polygon of HitAreas is written as an <area> element that links to the fragment of the percent-encoded hit ID. The options may be nil.
+func ImageMapWriter(resolution canvas.DPMM, name string, opts *HitAreaOptions) canvas.Writer {
+	return func(w io.Writer, c *canvas.Canvas) error {
+		sb := &strings.Builder{}
+		fmt.Fprintf(sb, "<map name=\"%s\">\n", html.EscapeString(name))
+		for _, area := range HitAreas(c, resolution, opts) {
+			coords := make([]string, 0, 2*len(area.Polygon))
+			for _, p := range area.Polygon {
+				coords = append(coords, fmt.Sprint(p[0]), fmt.Sprint(p[1]))
+			}
+			href := html.EscapeString("#" + url.PathEscape(area.ID))
+			fmt.Fprintf(sb, "<area shape=\"poly\" coords=\"%s\" href=\"%s\" alt=\"%s\">\n", strings.Join(coords, ","), href, html.EscapeString(area.ID))
+		}
+		sb.WriteString("</map>\n")
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+}
+
+// HitAreasJSONWriter writes the hit areas of the canvas as a JSON array of objects with an id and a polygon, see HitAreas. The options may be nil.
+func HitAreasJSONWriter(resolution canvas.DPMM, opts *HitAreaOptions) canvas.Writer {
+	return func(w io.Writer, c *canvas.Canvas) error {
+		return json.NewEncoder(w).Encode(HitAreas(c, resolution, opts))
+	}
+}
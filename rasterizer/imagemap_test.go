@@ -0,0 +1,107 @@
+package rasterizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestHitAreas(t *testing.T) {
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetHitID("a")
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawRectangle(2.0, 2.0, 6.0, 4.0)
+	ctx.SetHitID("b")
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPolygon(canvas.Point{6.0, 3.0}, canvas.Point{10.0, 3.0}, canvas.Point{10.0, 7.0})
+	ctx.SetHitID("ring")
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Green)
+	ctx.DrawCircle(15.0, 5.0, 3.0)
+
+	// the coordinates are pixels of the PNG image with the y-axis pointing down
+	buf := &bytes.Buffer{}
+	test.Error(t, PNGWriter(5.0)(buf, c))
+	img, err := png.Decode(buf)
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 100, 50))
+
+	areas := HitAreas(c, 5.0, nil)
+	test.T(t, len(areas), 3) // the hole of the ring is not represented
+	test.T(t, areas[0], HitArea{"ring", areas[0].Polygon})
+	test.T(t, areas[1], HitArea{"b", [][2]int{{50, 15}, {50, 35}, {30, 35}}})
+	test.T(t, areas[2], HitArea{"a", [][2]int{{10, 20}, {40, 20}, {40, 40}, {10, 40}}})
+	test.T(t, color.RGBAModel.Convert(img.At(12, 38)), canvas.Red)
+	test.T(t, color.RGBAModel.Convert(img.At(48, 33)), canvas.Blue)
+
+	// the polygons are clockwise on screen
+	for _, area := range areas {
+		sum := 0
+		for i, p := range area.Polygon {
+			q := area.Polygon[(i+1)%len(area.Polygon)]
+			sum += p[0]*q[1] - q[0]*p[1]
+		}
+		test.That(t, 0 < sum, area.ID, sum)
+	}
+
+	// the ring is within half a pixel of the circle of radius 3.5mm
+	for _, p := range areas[0].Polygon {
+		dx, dy := float64(p[0])-75.0, float64(p[1])-25.0
+		r := dx*dx + dy*dy
+		test.That(t, 16.5*16.5 <= r && r <= 18.5*18.5, p)
+	}
+
+	buf = &bytes.Buffer{}
+	test.Error(t, ImageMapWriter(5.0, "chart", nil)(buf, c))
+	test.That(t, bytes.HasPrefix(buf.Bytes(), []byte("<map name=\"chart\">\n<area shape=\"poly\" coords=\"")))
+	test.That(t, bytes.HasSuffix(buf.Bytes(), []byte("\" href=\"#b\" alt=\"b\">\n<area shape=\"poly\" coords=\"10,20,40,20,40,40,10,40\" href=\"#a\" alt=\"a\">\n</map>\n")), buf.String())
+
+	buf = &bytes.Buffer{}
+	test.Error(t, HitAreasJSONWriter(5.0, nil)(buf, c))
+	areas2 := []HitArea{}
+	test.Error(t, json.Unmarshal(buf.Bytes(), &areas2))
+	test.T(t, areas2, areas)
+}
+
+func TestImageMapWriterEscape(t *testing.T) {
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetHitID("a b#c%d&\"e")
+	ctx.DrawRectangle(2.0, 2.0, 6.0, 4.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, ImageMapWriter(5.0, "chart", nil)(buf, c))
+	test.String(t, buf.String(), "<map name=\"chart\">\n<area shape=\"poly\" coords=\"10,20,40,20,40,40,10,40\" href=\"#a%20b%23c%25d&amp;%22e\" alt=\"a b#c%d&amp;&#34;e\">\n</map>\n")
+}
+
+func TestHitAreasPrune(t *testing.T) {
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetHitID("hidden")
+	ctx.DrawRectangle(2.0, 2.0, 4.0, 4.0)
+	ctx.SetHitID("partly")
+	ctx.DrawRectangle(10.0, 2.0, 6.0, 4.0)
+	ctx.SetHitID("translucent")
+	ctx.SetFillColor(color.RGBA{0, 0, 0, 128})
+	ctx.DrawRectangle(10.0, 2.0, 6.0, 4.0)
+	ctx.SetHitID("top")
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawRectangle(1.0, 1.0, 12.0, 8.0)
+
+	ids := func(areas []HitArea) []string {
+		ids := []string{}
+		for _, area := range areas {
+			ids = append(ids, area.ID)
+		}
+		return ids
+	}
+	test.T(t, ids(HitAreas(c, 5.0, nil)), []string{"top", "translucent", "partly", "hidden"})
+	test.T(t, ids(HitAreas(c, 5.0, &HitAreaOptions{Prune: true})), []string{"top", "translucent", "partly"})
+}
@@ -0,0 +1,125 @@
+package rasterizer
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// maskSubsamples is the number of scanlines per row of pixels for anti-aliasing. Along the scanline the coverage is computed exactly.
+const maskSubsamples = 16
+
+type maskEdge struct {
+	x0, y0, x1, y1 float64 // y0 < y1
+	dir            int
+}
+
+// RasterizeMask fills the path into a new alpha image of w by h pixels with anti-aliasing, using the given fill rule. The matrix transforms the path to pixel coordinates, where the y-axis points upwards from the bottom of the image like in the canvas coordinate system. For paths in millimeters, pass canvas.Identity.Scale(resolution, resolution) with resolution in dots-per-millimeter. Open subpaths are closed implicitly.
+func RasterizeMask(p *canvas.Path, w, h int, m canvas.Matrix, rule canvas.FillRule) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	if w <= 0 || h <= 0 || p.Empty() {
+		return mask
+	}
+
+	// collect the non-horizontal edges in image coordinates, where the y-axis points downwards
+	edges := []maskEdge{}
+	for _, ps := range p.Transform(m).Flatten().Split() {
+		coords := ps.Coords()
+		if len(coords) < 2 {
+			continue
+		}
+		if !coords[0].Equals(coords[len(coords)-1]) {
+			coords = append(coords, coords[0])
+		}
+		for i := 1; i < len(coords); i++ {
+			a := canvas.Point{coords[i-1].X, float64(h) - coords[i-1].Y}
+			b := canvas.Point{coords[i].X, float64(h) - coords[i].Y}
+			if a.Y < b.Y {
+				edges = append(edges, maskEdge{a.X, a.Y, b.X, b.Y, 1})
+			} else if b.Y < a.Y {
+				edges = append(edges, maskEdge{b.X, b.Y, a.X, a.Y, -1})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].y0 < edges[j].y0
+	})
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	acc := make([]float64, w)
+	active := []maskEdge{}
+	crossings := []crossing{}
+	next := 0
+	for row := 0; row < h; row++ {
+		for i := range acc {
+			acc[i] = 0.0
+		}
+		for k := 0; k < maskSubsamples; k++ {
+			y := float64(row) + (float64(k)+0.5)/maskSubsamples
+
+			// update the active edges
+			for next < len(edges) && edges[next].y0 <= y {
+				active = append(active, edges[next])
+				next++
+			}
+			crossings = crossings[:0]
+			n := 0
+			for _, e := range active {
+				if y < e.y1 {
+					active[n] = e
+					n++
+					if e.y0 <= y {
+						crossings = append(crossings, crossing{e.x0 + (y-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+					}
+				}
+			}
+			active = active[:n]
+			sort.Slice(crossings, func(i, j int) bool {
+				return crossings[i].x < crossings[j].x
+			})
+
+			// accumulate the coverage of the spans that are inside
+			winding := 0
+			for i := 0; i+1 < len(crossings); i++ {
+				winding += crossings[i].dir
+				inside := winding != 0
+				if rule == canvas.EvenOdd {
+					inside = winding%2 != 0
+				}
+				if inside {
+					addSpan(acc, crossings[i].x, crossings[i+1].x, 1.0/maskSubsamples)
+				}
+			}
+		}
+		for i, a := range acc {
+			mask.Pix[row*mask.Stride+i] = uint8(math.Round(math.Min(a, 1.0) * 255.0))
+		}
+	}
+	return mask
+}
+
+// addSpan adds the coverage of the horizontal span from x0 to x1 with weight f to the pixels of acc.
+func addSpan(acc []float64, x0, x1, f float64) {
+	x0 = math.Max(x0, 0.0)
+	x1 = math.Min(x1, float64(len(acc)))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		acc[i0] += (x1 - x0) * f
+		return
+	}
+	acc[i0] += (float64(i0+1) - x0) * f
+	for i := i0 + 1; i < i1; i++ {
+		acc[i] += f
+	}
+	if i1 < len(acc) {
+		acc[i1] += (x1 - float64(i1)) * f
+	}
+}
@@ -0,0 +1,53 @@
+package rasterizer
+
+import (
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestRasterizeMask(t *testing.T) {
+	// full coverage
+	mask := RasterizeMask(canvas.Rectangle(10.0, 8.0), 10, 8, canvas.Identity, canvas.NonZero)
+	for _, a := range mask.Pix {
+		test.T(t, a, uint8(255))
+	}
+
+	// half coverage by a triangle, with intermediate values along the diagonal
+	mask = RasterizeMask(canvas.MustParseSVG("M0 0H20V20z"), 20, 20, canvas.Identity, canvas.NonZero)
+	sum := 0
+	for _, a := range mask.Pix {
+		sum += int(a)
+	}
+	avg := float64(sum) / float64(len(mask.Pix))
+	test.That(t, 127.0 < avg && avg < 128.0, avg)
+	test.T(t, mask.AlphaAt(0, 19).A, uint8(128)) // on the diagonal at the bottom left
+	test.T(t, mask.AlphaAt(19, 19).A, uint8(255))
+	test.T(t, mask.AlphaAt(0, 0).A, uint8(0))
+
+	// scaled to pixels and partially outside
+	mask = RasterizeMask(canvas.Rectangle(2.0, 1.0).Translate(-0.5, 0.125), 4, 4, canvas.Identity.Scale(2.0, 2.0), canvas.NonZero)
+	test.Bytes(t, mask.Pix, []byte{
+		0, 0, 0, 0,
+		64, 64, 64, 0,
+		255, 255, 255, 0,
+		191, 191, 191, 0,
+	})
+
+	// fill rules
+	p := canvas.MustParseSVG("M0 0H4V4H0zM1 1H3V3H1z")
+	mask = RasterizeMask(p, 4, 4, canvas.Identity, canvas.NonZero)
+	test.T(t, mask.AlphaAt(2, 2).A, uint8(255))
+	mask = RasterizeMask(p, 4, 4, canvas.Identity, canvas.EvenOdd)
+	test.T(t, mask.AlphaAt(2, 2).A, uint8(0))
+	test.T(t, mask.AlphaAt(0, 0).A, uint8(255))
+
+	// circle area
+	mask = RasterizeMask(canvas.Circle(10.0).Translate(16.0, 16.0), 32, 32, canvas.Identity, canvas.NonZero)
+	sum = 0
+	for _, a := range mask.Pix {
+		sum += int(a)
+	}
+	test.That(t, 312.0 < float64(sum)/255.0 && float64(sum)/255.0 < 316.0, float64(sum)/255.0)
+}
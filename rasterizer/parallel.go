@@ -0,0 +1,93 @@
+package rasterizer
+
+import (
+	"image"
+	"runtime"
+
+	"github.com/tdewolff/canvas"
+)
+
+// parallelRenderer transforms, strokes and rasterizes paths on multiple goroutines, while a single goroutine draws them onto the image in the original order. Since the drawing is the same as for the serial renderer, the result is identical.
+type parallelRenderer struct {
+	*Renderer
+	workers chan struct{}    // limits the number of paths being prepared
+	order   chan chan func() // prepared draw functions in draw order
+	done    chan struct{}
+}
+
+// newParallelRenderer returns a renderer that uses the given number of goroutines to prepare paths, or runtime.GOMAXPROCS(0) goroutines if not positive. Close must be called to wait for all elements to be drawn.
+func newParallelRenderer(r *Renderer, workers int) *parallelRenderer {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &parallelRenderer{
+		Renderer: r,
+		workers:  make(chan struct{}, workers),
+		order:    make(chan chan func(), 16*workers),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		for prepared := range p.order {
+			if draw := <-prepared; draw != nil {
+				draw()
+			}
+		}
+		close(p.done)
+	}()
+	return p
+}
+
+func (p *parallelRenderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	prepared := make(chan func(), 1)
+	p.order <- prepared
+	p.workers <- struct{}{}
+	go func() {
+		prepared <- p.Renderer.preparePath(path, style, m)
+		<-p.workers
+	}()
+}
+
+func (p *parallelRenderer) RenderText(text *canvas.Text, m canvas.Matrix) {
+	text.RenderAsPath(p, m)
+}
+
+func (p *parallelRenderer) RenderImage(img image.Image, m canvas.Matrix) {
+	p.queue(func() {
+		p.Renderer.RenderImage(img, m)
+	})
+}
+
+func (p *parallelRenderer) Erase(region *canvas.Path, m canvas.Matrix) {
+	p.queue(func() {
+		p.Renderer.Erase(region, m)
+	})
+}
+
+func (p *parallelRenderer) SetBlendMode(mode canvas.BlendMode) {
+	p.queue(func() {
+		p.Renderer.SetBlendMode(mode)
+	})
+}
+
+func (p *parallelRenderer) StartBlur(sigma float64) {
+	p.queue(func() {
+		p.Renderer.StartBlur(sigma)
+	})
+}
+
+func (p *parallelRenderer) EndBlur() {
+	p.queue(p.Renderer.EndBlur)
+}
+
+// queue queues a function that is run on the image in draw order.
+func (p *parallelRenderer) queue(f func()) {
+	prepared := make(chan func(), 1)
+	prepared <- f
+	p.order <- prepared
+}
+
+// Close waits until all elements are drawn.
+func (p *parallelRenderer) Close() {
+	close(p.order)
+	<-p.done
+}
@@ -0,0 +1,65 @@
+package rasterizer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+// denseCanvas returns a canvas with n small overlapping paths that are filled and stroked with translucent colors.
+func denseCanvas(n int) *canvas.Canvas {
+	random := rand.New(rand.NewSource(0))
+	c := canvas.New(200.0, 200.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeWidth(0.3)
+	star := canvas.StarPolygon(5, 2.0, 1.0, true)
+	for i := 0; i < n; i++ {
+		ctx.SetFillColor(color.RGBA{uint8(random.Intn(128)), uint8(random.Intn(128)), 0, 128})
+		ctx.SetStrokeColor(color.RGBA{0, 0, uint8(random.Intn(200)), 200})
+		ctx.DrawPath(random.Float64()*200.0, random.Float64()*200.0, star)
+	}
+	return c
+}
+
+func TestDrawParallel(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(40.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := denseCanvas(2000)
+	ctx := canvas.NewContext(c)
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(2, 3, canvas.Red)
+	ctx.DrawImage(50.0, 50.0, img, 0.2)
+	ctx.DrawText(20.0, 100.0, canvas.NewTextLine(face, "Parallel", canvas.Left))
+	ctx.SetFillColor(color.RGBA{0, 128, 0, 128})
+	ctx.SetDashes(0.0, 1.0, 0.5)
+	ctx.DrawPath(10.0, 10.0, canvas.Circle(50.0))
+
+	serial := Draw(c, canvas.DPMM(2.0))
+	for _, workers := range []int{2, 7, -1} {
+		parallel := DrawWithOptions(c, canvas.DPMM(2.0), &DrawOptions{Workers: workers})
+		test.T(t, parallel.Pix, serial.Pix, "workers", workers)
+	}
+
+	serial = DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Supersample: 2, NoAA: true})
+	parallel := DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Supersample: 2, NoAA: true, Workers: 4})
+	test.T(t, parallel.Pix, serial.Pix)
+}
+
+func BenchmarkDrawParallel(b *testing.B) {
+	c := denseCanvas(100000)
+	for workers := 1; workers <= runtime.GOMAXPROCS(0); workers *= 2 {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				DrawWithOptions(c, canvas.DPMM(4.0), &DrawOptions{Workers: workers})
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// DrawPaletted draws the canvas on a new paletted image with given resolution (in dots-per-millimeter). Colors are mapped to the nearest color of the palette, or are dithered using Floyd-Steinberg error diffusion which avoids banding of gradual color changes. If the palette is nil, a palette of 256 colors is made using median cut quantization.
+func DrawPaletted(c *canvas.Canvas, resolution canvas.DPMM, p color.Palette, dither bool) *image.Paletted {
+	img := Draw(c, resolution)
+	if p == nil {
+		p = MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 256), img)
+	}
+	return toPaletted(img, img.Bounds(), p, dither)
+}
+
+func toPaletted(img image.Image, rect image.Rectangle, p color.Palette, dither bool) *image.Paletted {
+	dst := image.NewPaletted(rect, p)
+	if dither {
+		draw.FloydSteinberg.Draw(dst, rect, img, rect.Min)
+	} else {
+		draw.Draw(dst, rect, img, rect.Min, draw.Src)
+	}
+	return dst
+}
+
+// MedianCutQuantizer is a quantizer that makes a palette by repeatedly splitting the box of colors with the largest range at the median of the channel with the largest range, until there are as many boxes as the capacity of the palette. Each box contributes the weighted average of its colors. It implements image/draw.Quantizer and can be used for gif.Options.
+type MedianCutQuantizer struct{}
+
+// Quantize appends at most cap(p)-len(p) colors to p that represent the colors in m.
+func (q MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	counts := map[color.RGBA]int{}
+	bounds := m.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			counts[color.RGBAModel.Convert(m.At(x, y)).(color.RGBA)]++
+		}
+	}
+	return append(p, medianCut(counts, cap(p)-len(p))...)
+}
+
+type colorCount struct {
+	c [4]uint8
+	n int
+}
+
+// medianCut returns a palette of at most n colors for the given color frequencies.
+func medianCut(counts map[color.RGBA]int, n int) color.Palette {
+	cols := make([]colorCount, 0, len(counts))
+	for col, count := range counts {
+		cols = append(cols, colorCount{[4]uint8{col.R, col.G, col.B, col.A}, count})
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		for k := 0; k < 4; k++ {
+			if cols[i].c[k] != cols[j].c[k] {
+				return cols[i].c[k] < cols[j].c[k]
+			}
+		}
+		return false
+	})
+
+	boxes := [][]colorCount{cols}
+	if len(cols) <= n {
+		boxes = make([][]colorCount, len(cols))
+		for i := range cols {
+			boxes[i] = cols[i : i+1]
+		}
+	}
+	for len(boxes) < n {
+		// find the box and channel with the largest range
+		ibox, channel, maxRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for k := 0; k < 4; k++ {
+				min, max := box[0].c[k], box[0].c[k]
+				for _, col := range box[1:] {
+					if col.c[k] < min {
+						min = col.c[k]
+					} else if max < col.c[k] {
+						max = col.c[k]
+					}
+				}
+				if ibox == -1 || maxRange < int(max-min) {
+					ibox, channel, maxRange = i, k, int(max-min)
+				}
+			}
+		}
+		if ibox == -1 {
+			break // all boxes have one color
+		}
+
+		// split at the median of the pixels
+		box := boxes[ibox]
+		sort.SliceStable(box, func(i, j int) bool {
+			return box[i].c[channel] < box[j].c[channel]
+		})
+		total := 0
+		for _, col := range box {
+			total += col.n
+		}
+		i, sum := 1, box[0].n
+		for i < len(box)-1 && sum+box[i].n <= total/2 {
+			sum += box[i].n
+			i++
+		}
+		boxes[ibox] = box[:i]
+		boxes = append(boxes, box[i:])
+	}
+
+	p := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var sum [4]int
+		total := 0
+		for _, col := range box {
+			for k := 0; k < 4; k++ {
+				sum[k] += int(col.c[k]) * col.n
+			}
+			total += col.n
+		}
+		p[i] = color.RGBA{
+			uint8((sum[0] + total/2) / total),
+			uint8((sum[1] + total/2) / total),
+			uint8((sum[2] + total/2) / total),
+			uint8((sum[3] + total/2) / total),
+		}
+	}
+	return p
+}
@@ -0,0 +1,93 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func gradientCanvas() *canvas.Canvas {
+	c := canvas.New(64.0, 16.0)
+	ctx := canvas.NewContext(c)
+	for i := 0; i < 64; i++ {
+		v := uint8(i * 4)
+		ctx.SetFillColor(color.RGBA{v, v / 2, 255 - v, 255})
+		ctx.DrawPath(float64(i), 0.0, canvas.Rectangle(1.0, 16.0))
+	}
+	return c
+}
+
+// blockError returns the mean squared error between the averages of 4x4 pixel blocks, which is how the eye perceives dithering.
+func blockError(a, b image.Image) float64 {
+	sum, n := 0.0, 0
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y+4 <= bounds.Max.Y; y += 4 {
+		for x := bounds.Min.X; x+4 <= bounds.Max.X; x += 4 {
+			var ca, cb [3]float64
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					ra, ga, ba, _ := a.At(x+i, y+j).RGBA()
+					rb, gb, bb, _ := b.At(x+i, y+j).RGBA()
+					ca[0], ca[1], ca[2] = ca[0]+float64(ra), ca[1]+float64(ga), ca[2]+float64(ba)
+					cb[0], cb[1], cb[2] = cb[0]+float64(rb), cb[1]+float64(gb), cb[2]+float64(bb)
+				}
+			}
+			for k := 0; k < 3; k++ {
+				d := (ca[k] - cb[k]) / 16.0 / 0xffff
+				sum += d * d
+			}
+			n += 3
+		}
+	}
+	return sum / float64(n)
+}
+
+func TestDrawPaletted(t *testing.T) {
+	c := gradientCanvas()
+	img := Draw(c, 4.0)
+	p := color.Palette{
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+		color.RGBA{255, 128, 0, 255},
+		color.RGBA{128, 64, 128, 255},
+		color.RGBA{255, 255, 255, 255},
+	}
+
+	plain := DrawPaletted(c, 4.0, p, false)
+	dithered := DrawPaletted(c, 4.0, p, true)
+	test.T(t, plain.Bounds(), img.Bounds())
+	test.T(t, dithered.Bounds(), img.Bounds())
+	errPlain, errDithered := blockError(img, plain), blockError(img, dithered)
+	test.That(t, errDithered < errPlain/2.0, errDithered, errPlain)
+
+	// median cut palette
+	quantized := DrawPaletted(c, 4.0, nil, false)
+	test.That(t, len(quantized.Palette) <= 256)
+	test.That(t, blockError(img, quantized) < errDithered, blockError(img, quantized), errDithered)
+}
+
+func TestMedianCutQuantizer(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+	img.SetRGBA(1, 0, color.RGBA{255, 0, 0, 255})
+	img.SetRGBA(2, 0, color.RGBA{0, 0, 255, 255})
+	img.SetRGBA(3, 0, color.RGBA{0, 0, 0, 0})
+
+	// exact colors when there are few colors
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 256), img)
+	test.T(t, len(p), 3)
+	for x := 0; x < 4; x++ {
+		test.T(t, p.Convert(img.At(x, 0)), img.At(x, 0))
+	}
+
+	// two boxes split along the red channel at the median pixel, appended to the existing palette
+	p = MedianCutQuantizer{}.Quantize(append(make(color.Palette, 0, 3), color.White), img)
+	test.T(t, len(p), 3)
+	test.T(t, p[0], color.Color(color.White))
+	test.T(t, p[1], color.Color(color.RGBA{0, 0, 128, 128}))
+	test.T(t, p[2], color.Color(color.RGBA{255, 0, 0, 255}))
+}
@@ -2,6 +2,8 @@ package rasterizer
 
 import (
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/tdewolff/canvas"
 	"golang.org/x/image/draw"
@@ -12,85 +14,420 @@ import (
 // Draw draws the canvas on a new image with given resolution (in dots-per-millimeter).
 // Higher resolution will result in bigger images.
 func Draw(c *canvas.Canvas, resolution canvas.DPMM) *image.RGBA {
-	img := image.NewRGBA(image.Rect(0, 0, int(c.W*float64(resolution)+0.5), int(c.H*float64(resolution)+0.5)))
+	w, h := c.SizePx(resolution)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
 	ras := New(img, resolution)
 	c.Render(ras)
 	return img
 }
 
+// DrawRGBA64 draws the canvas on a new image with 16 bits per channel like Draw, so that anti-aliasing and transparency are blended without the banding of 8 bits per channel.
+func DrawRGBA64(c *canvas.Canvas, resolution canvas.DPMM) *image.RGBA64 {
+	w, h := c.SizePx(resolution)
+	img := image.NewRGBA64(image.Rect(0, 0, w, h))
+	ras := New(img, resolution)
+	c.Render(ras)
+	return img
+}
+
+// DrawOptions are the options for rasterizing a canvas.
+type DrawOptions struct {
+	Supersample int     // rasterize at n times the resolution and average blocks of n by n pixels, zero or one rasterizes at the resolution
+	NoAA        bool    // disable anti-aliasing so that pixels are either inside or outside a path, which is faster
+	Backend     Backend // scan converter of paths
+	Workers     int     // number of goroutines that stroke and rasterize paths concurrently, zero renders serially and a negative number uses runtime.GOMAXPROCS(0), the result is the same regardless
+}
+
+// DrawWithOptions draws the canvas on a new image like Draw using the given options, which may be nil. Supersampling smoothens edges that the exact area coverage of the anti-aliasing does not, such as those of thin adjoining shapes, at the cost of n squared the time and memory.
+func DrawWithOptions(c *canvas.Canvas, resolution canvas.DPMM, opts *DrawOptions) *image.RGBA {
+	w, h := c.SizePx(resolution)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	drawWithOptions(img, c, resolution, opts)
+	return img
+}
+
+// drawWithOptions draws the canvas on the image, which is rasterized at a higher resolution first when supersampling.
+func drawWithOptions(img draw.Image, c *canvas.Canvas, resolution canvas.DPMM, opts *DrawOptions) {
+	if opts == nil {
+		opts = &DrawOptions{}
+	}
+	render := func(img draw.Image, resolution canvas.DPMM) {
+		ras := New(img, resolution)
+		ras.SetAntialiasing(!opts.NoAA)
+		ras.SetBackend(opts.Backend)
+		if opts.Workers == 0 || opts.Workers == 1 {
+			c.Render(ras)
+			return
+		}
+		parallel := newParallelRenderer(ras, opts.Workers)
+		c.Render(parallel)
+		parallel.Close()
+	}
+
+	n := opts.Supersample
+	if n <= 1 {
+		render(img, resolution)
+		return
+	}
+
+	size := img.Bounds().Size()
+	large := image.NewRGBA64(image.Rect(0, 0, size.X*n, size.Y*n))
+	render(large, resolution*canvas.DPMM(n))
+
+	// box filter of premultiplied colors
+	min := img.Bounds().Min
+	nn := uint32(n * n)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			var r, g, b, a uint32
+			for j := 0; j < n; j++ {
+				for i := 0; i < n; i++ {
+					col := large.RGBA64At(x*n+i, y*n+j)
+					r += uint32(col.R)
+					g += uint32(col.G)
+					b += uint32(col.B)
+					a += uint32(col.A)
+				}
+			}
+			img.Set(min.X+x, min.Y+y, color.RGBA64{uint16((r + nn/2) / nn), uint16((g + nn/2) / nn), uint16((b + nn/2) / nn), uint16((a + nn/2) / nn)})
+		}
+	}
+}
+
+// Fit specifies how a canvas is scaled to an image size with a different aspect ratio.
+type Fit int
+
+// see Fit
+const (
+	Contain Fit = iota // scale uniformly to fit inside the image, centered with margins on two sides
+	Cover              // scale uniformly to cover the image, centered and cropped on two sides
+	Stretch            // scale horizontally and vertically to fill the image, distorting the aspect ratio
+)
+
+// DrawSize draws the canvas on a new image of exactly w by h pixels, scaled to the image size according to the fit. The image is filled with the background color first, which is visible in the margins when using Contain, and may be nil to keep the image transparent. To draw at an exact resolution in DPI, use Draw with a resolution such as 300.0*canvas.DPI instead.
+func DrawSize(c *canvas.Canvas, w, h int, fit Fit, background color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if background != nil {
+		draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	}
+	if c.W <= 0.0 || c.H <= 0.0 || w <= 0 || h <= 0 {
+		return img
+	}
+
+	sx, sy := float64(w)/c.W, float64(h)/c.H // pixels per millimeter
+	if fit == Stretch {
+		r := NewInRect(img, canvas.DPMM(sy), img.Bounds())
+		c.Render(viewRenderer{r, canvas.Identity.Scale(sx/sy, 1.0)})
+		return img
+	}
+
+	s := math.Min(sx, sy)
+	if fit == Cover {
+		s = math.Max(sx, sy)
+	}
+	cw, ch := int(c.W*s+0.5), int(c.H*s+0.5)
+	rect := image.Rect(0, 0, cw, ch).Add(image.Point{(w - cw) / 2, (h - ch) / 2})
+	c.Render(NewInRect(img, canvas.DPMM(s), rect))
+	return img
+}
+
+// viewRenderer is a renderer that transforms everything by a view matrix before rendering.
+type viewRenderer struct {
+	*Renderer
+	view canvas.Matrix
+}
+
+func (r viewRenderer) View() canvas.Matrix {
+	return r.view
+}
+
+// DrawInto draws the canvas onto an existing image with the top-left corner of the canvas at the given offset in pixels, without allocating an intermediate image. Elements are composited over the existing pixels and are clipped to the area of the canvas and the bounds of the image, so that the pixels outside are left untouched.
+func DrawInto(dst draw.Image, c *canvas.Canvas, resolution canvas.DPMM, offset image.Point) {
+	w, h := c.SizePx(resolution)
+	rect := image.Rect(0, 0, w, h).Add(offset)
+	c.Render(NewInRect(dst, resolution, rect))
+}
+
+// Backend is the scan converter that computes the coverage of paths.
+type Backend int
+
+// see Backend
+const (
+	VectorBackend   Backend = iota // golang.org/x/image/vector, which is fast but only supports the non-zero fill rule, so that even-odd fills use RasterizeMask
+	ScanlineBackend                // RasterizeMask, which honors the fill rule of the style
+)
+
+// eraseStyle fills the region of Erase.
+var eraseStyle = canvas.Style{
+	FillColor:   canvas.Black,
+	StrokeColor: canvas.Transparent,
+	FillRule:    canvas.NonZero,
+}
+
 type Renderer struct {
 	img        draw.Image
 	resolution canvas.DPMM
+	rect       image.Rectangle // area of the canvas in the image
+	bounds     image.Rectangle // bounds of the image, which stay the same while drawing to blur layers
+	aliased    bool
+	backend    Backend
+	blend      canvas.BlendMode
+	erase      bool // paths clear the image instead of drawing onto it
+	blurs      []blurLayer
+}
+
+// blurLayer is the image that was drawn to before a blur started, and the standard deviation of the blur in millimeters.
+type blurLayer struct {
+	img   draw.Image
+	sigma float64
 }
 
 // New creates a renderer that draws to a rasterized image.
 func New(img draw.Image, resolution canvas.DPMM) *Renderer {
+	return NewInRect(img, resolution, img.Bounds())
+}
+
+// NewInRect creates a renderer that draws to the given area of a rasterized image, which may extend beyond the bounds of the image. Everything is clipped to the area and the bounds of the image.
+func NewInRect(img draw.Image, resolution canvas.DPMM, rect image.Rectangle) *Renderer {
 	return &Renderer{
 		img:        img,
 		resolution: resolution,
+		rect:       rect,
+		bounds:     img.Bounds(),
 	}
 }
 
+// SetAntialiasing sets whether edges are anti-aliased, which is on by default. Without anti-aliasing, pixels are drawn when their center is inside the path.
+func (r *Renderer) SetAntialiasing(antialias bool) {
+	r.aliased = !antialias
+}
+
+// SetBackend sets the scan converter that computes the coverage of paths, which is VectorBackend by default.
+func (r *Renderer) SetBackend(backend Backend) {
+	r.backend = backend
+}
+
 // Size returns the width and height in millimeters
 func (r *Renderer) Size() (float64, float64) {
-	size := r.img.Bounds().Size()
+	size := r.rect.Size()
 	return float64(size.X) / float64(r.resolution), float64(size.Y) / float64(r.resolution)
 }
 
 func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	// TODO: use fill rule (EvenOdd, NonZero) for rasterizer
+	if draw := r.preparePath(path, style, m); draw != nil {
+		draw()
+	}
+}
+
+// preparePath transforms, strokes and rasterizes the path and returns a function that draws it onto the image, or nil if nothing is visible. It does not modify the image nor the renderer so that paths can be prepared concurrently.
+func (r *Renderer) preparePath(path *canvas.Path, style canvas.Style, m canvas.Matrix) func() {
 	path = path.Transform(m)
 
-	strokeWidth := 0.0
+	// the stroke outline is included in the bounds, as miter joins may extend far beyond the stroke width
+	bounds := path.Bounds()
+	var strokePath *canvas.Path
 	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
-		strokeWidth = style.StrokeWidth
+		strokePath = path
+		if 0 < len(style.Dashes) {
+			strokePath = strokePath.Dash(style.DashOffset, style.Dashes...)
+		}
+		strokePath = strokePath.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		bounds = bounds.Add(strokePath.Bounds())
 	}
 
-	size := r.img.Bounds().Size()
-	bounds := path.Bounds()
+	// clip area in pixels from the bottom-left of the canvas
+	clip := r.rect.Intersect(r.bounds)
+	x0, x1 := clip.Min.X-r.rect.Min.X, clip.Max.X-r.rect.Min.X
+	y0, y1 := r.rect.Max.Y-clip.Max.Y, r.rect.Max.Y-clip.Min.Y
+
 	dx, dy := 0, 0
 	resolution := float64(r.resolution)
-	x := int((bounds.X - strokeWidth) * resolution)
-	y := int((bounds.Y - strokeWidth) * resolution)
-	w := int((bounds.W+2*strokeWidth)*resolution) + 1
-	h := int((bounds.H+2*strokeWidth)*resolution) + 1
-	if (x+w <= 0 || size.X <= x) && (y+h <= 0 || size.Y <= y) {
-		return // outside canvas
+	x := int(math.Floor(bounds.X * resolution))
+	y := int(math.Floor(bounds.Y * resolution))
+	w := int(math.Ceil((bounds.X+bounds.W)*resolution)) - x + 1
+	h := int(math.Ceil((bounds.Y+bounds.H)*resolution)) - y + 1
+	if x+w <= x0 || x1 <= x || y+h <= y0 || y1 <= y {
+		return nil // outside canvas
 	}
 
-	if x < 0 {
-		dx = -x
-		x = 0
+	if x < x0 {
+		dx = x0 - x
+		w -= dx
+		x = x0
 	}
-	if y < 0 {
-		dy = -y
-		y = 0
+	if y < y0 {
+		dy = y0 - y
+		h -= dy
+		y = y0
 	}
-	if size.X <= x+w {
-		w = size.X - x
+	if x1 <= x+w {
+		w = x1 - x
 	}
-	if size.Y <= y+h {
-		h = size.Y - y
+	if y1 <= y+h {
+		h = y1 - y
 	}
 	if w <= 0 || h <= 0 {
-		return // has no size
+		return nil // has no size
+	}
+
+	rect := image.Rect(r.rect.Min.X+x, r.rect.Max.Y-y, r.rect.Min.X+x+w, r.rect.Max.Y-y-h)
+	rasterize := func(path *canvas.Path, rule canvas.FillRule, col color.RGBA, paint canvas.Paint) func() {
+		// the vector backend only supports the non-zero fill rule
+		scanline := r.backend == ScanlineBackend || rule == canvas.EvenOdd
+		if paint == nil && !scanline {
+			ras := vector.NewRasterizer(w, h)
+			path.ToRasterizer(ras, resolution)
+			return func() {
+				r.draw(ras, rect, col, image.Point{dx, dy})
+			}
+		}
+
+		var mask *image.Alpha
+		if scanline {
+			mask = RasterizeMask(path, w, h, canvas.Identity.Scale(resolution, resolution), rule)
+		} else {
+			ras := vector.NewRasterizer(w, h)
+			path.ToRasterizer(ras, resolution)
+			mask = image.NewAlpha(image.Rect(0, 0, w, h))
+			ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+		}
+		if r.aliased {
+			threshold(mask)
+		}
+
+		var src image.Image = image.NewUniform(col)
+		sp := rect.Min
+		if pattern, ok := paint.(canvas.PathPattern); ok {
+			tm := canvas.Identity.Translate(-float64(x)/resolution, -float64(y)/resolution).Mul(m)
+			if tile := r.drawPattern(pattern, tm, w, h); tile != nil {
+				src, sp = tile, image.Point{}
+			}
+		} else if paint != nil {
+			// map pixel coordinates to the coordinates of the untransformed path
+			pm := canvas.Identity.Translate(-float64(r.rect.Min.X)/resolution, float64(r.rect.Max.Y)/resolution).Scale(1.0/resolution, -1.0/resolution)
+			src = paintImage{paint, m.Inv().Mul(pm)}
+		}
+		return func() {
+			r.composite(rect, src, sp, mask, image.Point{})
+		}
 	}
 
 	path = path.Translate(-float64(x)/resolution, -float64(y)/resolution)
-	if style.FillColor.A != 0 {
-		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.FillColor), image.Point{dx, dy})
+	var fill, stroke func()
+	if style.FillPaint != nil {
+		fill = rasterize(path, style.FillRule, style.FillColor, style.FillPaint)
+	} else if style.FillColor.A != 0 {
+		fill = rasterize(path, style.FillRule, style.FillColor, nil)
 	}
-	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
-		if 0 < len(style.Dashes) {
-			path = path.Dash(style.DashOffset, style.Dashes...)
+	if strokePath != nil {
+		strokePath = strokePath.Translate(-float64(x)/resolution, -float64(y)/resolution)
+		stroke = rasterize(strokePath, canvas.NonZero, style.StrokeColor, nil)
+	}
+	return func() {
+		if fill != nil {
+			fill()
+		}
+		if stroke != nil {
+			stroke()
+		}
+	}
+}
+
+// draw draws the color onto the image in the given rectangle using the coverage of the rasterizer as a mask, which is thresholded at one half when anti-aliasing is disabled.
+func (r *Renderer) draw(ras *vector.Rasterizer, rect image.Rectangle, col color.RGBA, sp image.Point) {
+	if !r.aliased && r.blend == canvas.BlendNormal && !r.erase {
+		ras.Draw(r.img, rect, image.NewUniform(col), sp)
+		return
+	}
+
+	rect = rect.Canon()
+	mask := image.NewAlpha(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	if r.aliased {
+		threshold(mask)
+	}
+	r.composite(rect, image.NewUniform(col), sp, mask, image.Point{})
+}
+
+// composite draws the source over the image in the given rectangle using the mask, with the colors mixed by the blend mode of the renderer. When erasing, the image is cleared using the mask instead.
+func (r *Renderer) composite(rect image.Rectangle, src image.Image, sp image.Point, mask image.Image, mp image.Point) {
+	if r.erase {
+		draw.DrawMask(r.img, rect, image.Transparent, image.Point{}, mask, mp, draw.Src)
+		return
+	} else if r.blend == canvas.BlendNormal {
+		draw.DrawMask(r.img, rect, src, sp, mask, mp, draw.Over)
+		return
+	}
+	drawBlend(r.img, rect, src, sp, mask, mp, r.blend)
+}
+
+// drawPattern draws the copies of the motif of a path pattern that overlap an image of w by h pixels, where m maps the coordinates of the filled path to millimeters from the bottom-left of the image. It returns nil if the pattern would need too many copies.
+func (r *Renderer) drawPattern(pattern canvas.PathPattern, m canvas.Matrix, w, h int) *image.RGBA {
+	offsets := pattern.Offsets()
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	// range of the cells that overlap the image, in pattern coordinates
+	resolution := float64(r.resolution)
+	inv := m.Mul(pattern.M).Inv()
+	u0, u1, v0, v1 := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, corner := range []canvas.Point{{0.0, 0.0}, {float64(w) / resolution, 0.0}, {0.0, float64(h) / resolution}, {float64(w) / resolution, float64(h) / resolution}} {
+		p := inv.Dot(corner)
+		u0, u1 = math.Min(u0, p.X), math.Max(u1, p.X)
+		v0, v1 = math.Min(v0, p.Y), math.Max(v1, p.Y)
+	}
+
+	// copies of the motif that overlap those cells, in multiples of the cell size
+	i0, i1 := math.Floor(u0/pattern.CellW), math.Floor(u1/pattern.CellW)
+	j0, j1 := math.Floor(v0/pattern.CellH), math.Floor(v1/pattern.CellH)
+	first, last := offsets[0], offsets[len(offsets)-1]
+	i0, i1 = i0+math.Round(first.X/pattern.CellW), i1+math.Round(last.X/pattern.CellW)
+	j0, j1 = j0+math.Round(first.Y/pattern.CellH), j1+math.Round(last.Y/pattern.CellH)
+	if 100000.0 < (i1-i0+1.0)*(j1-j0+1.0) {
+		return nil
+	}
+
+	style := pattern.Style
+	style.FillPaint, style.StrokePaint = nil, nil
+	tile := image.NewRGBA(image.Rect(0, 0, w, h))
+	sub := New(tile, r.resolution)
+	sub.aliased, sub.backend = r.aliased, r.backend
+	for j := j0; j <= j1; j++ {
+		for i := i0; i <= i1; i++ {
+			sub.RenderPath(pattern.Cell, style, m.Mul(pattern.M).Translate(i*pattern.CellW, j*pattern.CellH))
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+	}
+	return tile
+}
+
+// paintImage is an image of infinite size that evaluates a paint at the centers of its pixels, where m maps pixel coordinates to the coordinates of the paint.
+type paintImage struct {
+	paint canvas.Paint
+	m     canvas.Matrix
+}
+
+func (img paintImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
 
-		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.StrokeColor), image.Point{dx, dy})
+func (img paintImage) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (img paintImage) At(x, y int) color.Color {
+	p := img.m.Dot(canvas.Point{float64(x) + 0.5, float64(y) + 0.5})
+	return img.paint.At(p.X, p.Y)
+}
+
+// threshold sets the coverage of the mask to either zero or one at one half.
+func threshold(mask *image.Alpha) {
+	for i, a := range mask.Pix {
+		if a < 128 {
+			mask.Pix[i] = 0
+		} else {
+			mask.Pix[i] = 255
+		}
 	}
 }
 
@@ -107,12 +444,67 @@ func (r *Renderer) RenderImage(img image.Image, m canvas.Matrix) {
 	draw.Draw(img2, image.Rect(margin, margin, size.X+margin, size.Y+margin), img, sp, draw.Over)
 
 	// draw to destination image
-	// note that we need to correct for the added margin in origin and m
+	// note that we need to correct for the added margin in the origin
 	// TODO: optimize when transformation is only translation or stretch
 	origin := m.Dot(canvas.Point{-float64(margin), float64(img2.Bounds().Size().Y - margin)}).Mul(float64(r.resolution))
-	m = m.Scale(float64(r.resolution)*(float64(size.X+margin)/float64(size.X)), float64(r.resolution)*(float64(size.Y+margin)/float64(size.Y)))
+	m = m.Scale(float64(r.resolution), float64(r.resolution))
+
+	aff3 := f64.Aff3{m[0][0], -m[0][1], float64(r.rect.Min.X) + origin.X, -m[1][0], m[1][1], float64(r.rect.Max.Y) - origin.Y}
+	clip := r.rect.Intersect(r.bounds)
+	if r.blend != canvas.BlendNormal {
+		// transform onto a transparent layer first so that it can be blended as a whole
+		layer := image.NewRGBA(clip)
+		draw.BiLinear.Transform(layer, aff3, img2, img2.Bounds(), draw.Over, nil)
+		r.composite(clip, layer, clip.Min, nil, image.Point{})
+		return
+	}
+	dst := r.img
+	if clip != r.img.Bounds() {
+		dst = clipImage{r.img, clip}
+	}
+	draw.BiLinear.Transform(dst, aff3, img2, img2.Bounds(), draw.Over, nil)
+}
+
+// Erase makes the region transformed by the matrix transparent, using the coverage of the region so that its edges are anti-aliased.
+func (r *Renderer) Erase(region *canvas.Path, m canvas.Matrix) {
+	if draw := r.preparePath(region, eraseStyle, m); draw != nil {
+		r.erase = true
+		draw()
+		r.erase = false
+	}
+}
+
+// SetBlendMode sets the blend mode for the elements that follow, which mixes their colors with the colors of the image below them.
+func (r *Renderer) SetBlendMode(mode canvas.BlendMode) {
+	r.blend = mode
+}
+
+// StartBlur starts drawing to a transparent layer of the size of the canvas area in the image, which is blurred and drawn over the image by EndBlur.
+func (r *Renderer) StartBlur(sigma float64) {
+	r.blurs = append(r.blurs, blurLayer{r.img, sigma})
+	r.img = image.NewRGBA(r.rect.Intersect(r.bounds))
+}
+
+// EndBlur blurs the layer of the last started blur and draws it over the image that was drawn to before.
+func (r *Renderer) EndBlur() {
+	if len(r.blurs) == 0 {
+		return
+	}
+	blur := r.blurs[len(r.blurs)-1]
+	r.blurs = r.blurs[:len(r.blurs)-1]
+
+	layer := r.img.(*image.RGBA)
+	Blur(layer, blur.sigma*float64(r.resolution))
+	r.img = blur.img
+	r.composite(layer.Rect, layer, layer.Rect.Min, nil, image.Point{})
+}
+
+// clipImage restricts drawing to the given bounds of the image.
+type clipImage struct {
+	draw.Image
+	bounds image.Rectangle
+}
 
-	h := float64(r.img.Bounds().Size().Y)
-	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X, -m[1][0], m[1][1], h - origin.Y}
-	draw.CatmullRom.Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, nil)
+func (img clipImage) Bounds() image.Rectangle {
+	return img.bounds
 }
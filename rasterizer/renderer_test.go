@@ -0,0 +1,800 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestRendererCMYK(t *testing.T) {
+	c := canvas.New(2.0, 2.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(color.CMYK{255, 0, 0, 0})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(2.0, 2.0))
+
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(0, 0), color.RGBA{0, 255, 255, 255})
+	test.T(t, img.RGBAAt(1, 1), color.RGBA{0, 255, 255, 255})
+}
+
+func TestRendererSpotColor(t *testing.T) {
+	c := canvas.New(2.0, 2.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.SpotColor{"PANTONE 186 C", color.CMYK{0, 255, 204, 20}, 1.0})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(2.0, 2.0))
+
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(0, 0), color.RGBA{235, 0, 47, 255})
+}
+
+func TestRendererAntialiasing(t *testing.T) {
+	c := canvas.New(40.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0L40 0L40 13z"))
+
+	// intermediate alpha levels along the diagonal edge
+	levels := func(img *image.RGBA) map[uint8]bool {
+		levels := map[uint8]bool{}
+		for i := 0; i < len(img.Pix); i += 4 {
+			if a := img.Pix[i+3]; a != 0 && a != 255 {
+				levels[a] = true
+			}
+		}
+		return levels
+	}
+
+	aa := Draw(c, canvas.DPMM(1.0))
+	test.That(t, 20 < len(levels(aa)), len(levels(aa)))
+	test.T(t, aa.RGBAAt(39, 39), color.RGBA{0, 0, 0, 255})
+	test.T(t, aa.RGBAAt(0, 0), color.RGBA{0, 0, 0, 0})
+
+	noAA := DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{NoAA: true})
+	test.T(t, len(levels(noAA)), 0)
+	test.T(t, noAA.RGBAAt(39, 39), color.RGBA{0, 0, 0, 255})
+	test.T(t, noAA.RGBAAt(0, 0), color.RGBA{0, 0, 0, 0})
+
+	// supersampling without anti-aliasing has n*n+1 coverage levels
+	ss := DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Supersample: 4, NoAA: true})
+	n := len(levels(ss))
+	test.That(t, 5 < n && n <= 15, n)
+
+	ssAA := DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Supersample: 4})
+	test.That(t, 20 < len(levels(ssAA)), len(levels(ssAA)))
+	for i := range aa.Pix {
+		diff := int(aa.Pix[i]) - int(ssAA.Pix[i])
+		test.That(t, -8 <= diff && diff <= 8, i, aa.Pix[i], ssAA.Pix[i])
+	}
+}
+
+func TestDrawInto(t *testing.T) {
+	gray := color.RGBA{128, 128, 128, 255}
+	dst := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(gray), image.Point{}, draw.Src)
+
+	a := canvas.New(20.0, 20.0)
+	ctxA := canvas.NewContext(a)
+	ctxA.SetFillColor(color.RGBA{255, 0, 0, 255})
+	ctxA.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctxA.SetFillColor(color.RGBA{0, 0, 128, 128})
+	ctxA.DrawPath(-10.0, -10.0, canvas.Rectangle(40.0, 40.0)) // translucent and larger than the canvas
+
+	b := canvas.New(20.0, 20.0)
+	ctxB := canvas.NewContext(b)
+	ctxB.SetFillColor(color.RGBA{0, 255, 0, 255})
+	ctxB.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 20.0))
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	ctxB.DrawImage(0.0, 12.0, img, 1.0)
+
+	DrawInto(dst, a, canvas.DPMM(1.0), image.Point{5, 5})
+	DrawInto(dst, b, canvas.DPMM(1.0), image.Point{50, 30}) // partially outside the image
+
+	// canvas A at (5,5)-(25,25) with red in the bottom half, composited under the translucent blue
+	test.T(t, dst.RGBAAt(10, 20), color.RGBA{127, 0, 128, 255})
+	test.T(t, dst.RGBAAt(10, 10), color.RGBA{63, 63, 192, 255})
+
+	// canvas B at (50,30)-(70,50) is clipped to the image and equals drawing it separately
+	ref := Draw(b, canvas.DPMM(1.0))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			test.T(t, dst.RGBAAt(50+x, 30+y), ref.RGBAAt(x, y), x, y)
+		}
+	}
+
+	// pixels outside the canvases are untouched
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 60; x++ {
+			if !image.Pt(x, y).In(image.Rect(5, 5, 25, 25)) && !image.Pt(x, y).In(image.Rect(50, 30, 60, 40)) {
+				test.T(t, dst.RGBAAt(x, y), gray, x, y)
+			}
+		}
+	}
+}
+
+func TestDrawSize(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(100.0, 50.0))
+
+	// bounds of the black pixels
+	content := func(img *image.RGBA) image.Rectangle {
+		rect := image.Rectangle{}
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				if img.RGBAAt(x, y) == (color.RGBA{0, 0, 0, 255}) {
+					rect = rect.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+		return rect
+	}
+
+	img := DrawSize(c, 192, 108, Contain, canvas.White)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 192, 108))
+	test.T(t, content(img), image.Rect(0, 6, 192, 102))
+	test.T(t, img.RGBAAt(96, 2), color.RGBA{255, 255, 255, 255})
+	test.T(t, img.RGBAAt(96, 105), color.RGBA{255, 255, 255, 255})
+
+	img = DrawSize(c, 100, 100, Contain, nil)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 100, 100))
+	test.T(t, content(img), image.Rect(0, 25, 100, 75))
+	test.T(t, img.RGBAAt(50, 10), color.RGBA{0, 0, 0, 0})
+
+	img = DrawSize(c, 100, 100, Cover, canvas.White)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 100, 100))
+	test.T(t, content(img), image.Rect(0, 0, 100, 100))
+
+	img = DrawSize(c, 30, 40, Stretch, canvas.White)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 30, 40))
+	test.T(t, content(img), image.Rect(0, 0, 30, 40))
+
+	// the content is scaled instead of cropped
+	c = canvas.New(100.0, 50.0)
+	ctx = canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(50.0, 50.0))
+	img = DrawSize(c, 40, 40, Stretch, canvas.White)
+	test.T(t, content(img), image.Rect(0, 0, 20, 40))
+	img = DrawSize(c, 40, 40, Cover, canvas.White)
+	test.T(t, content(img), image.Rect(0, 0, 20, 40))
+}
+
+func TestRendererBackend(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(30.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(50.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(color.RGBA{0, 0, 128, 128})
+	ctx.SetStrokeColor(canvas.Red)
+	ctx.SetStrokeWidth(0.5)
+	ctx.DrawPath(25.0, 25.0, canvas.StarPolygon(7, 20.0, 8.0, true))
+	ctx.DrawPath(5.0, 5.0, canvas.Circle(4.0))
+	ctx.DrawText(5.0, 35.0, canvas.NewTextLine(face, "Ag", canvas.Left))
+
+	vector := DrawWithOptions(c, canvas.DPMM(4.0), &DrawOptions{Backend: VectorBackend})
+	scanline := DrawWithOptions(c, canvas.DPMM(4.0), &DrawOptions{Backend: ScanlineBackend})
+	// the backends flatten curves differently and the scanline backend uses 16 scanlines per pixel, so that edges differ slightly
+	total := 0
+	for i := range vector.Pix {
+		diff := int(vector.Pix[i]) - int(scanline.Pix[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		test.That(t, diff <= 40, i, vector.Pix[i], scanline.Pix[i])
+		total += diff
+	}
+	test.That(t, float64(total)/float64(len(vector.Pix)) < 0.25, total)
+
+	// only the scanline backend honors the even-odd fill rule
+	c = canvas.New(30.0, 30.0)
+	ctx = canvas.NewContext(c)
+	ctx.SetFillRule(canvas.EvenOdd)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(30.0, 30.0).Append(canvas.Rectangle(10.0, 10.0).Translate(10.0, 10.0)))
+	scanline = DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Backend: ScanlineBackend})
+	test.T(t, scanline.RGBAAt(5, 5), color.RGBA{0, 0, 0, 255})
+	test.T(t, scanline.RGBAAt(15, 15), color.RGBA{0, 0, 0, 0})
+
+	// aliased coverage
+	scanline = DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Backend: ScanlineBackend, NoAA: true})
+	test.T(t, scanline.RGBAAt(15, 15), color.RGBA{0, 0, 0, 0})
+}
+
+func benchmarkBackends(b *testing.B, c *canvas.Canvas) {
+	for _, backend := range []Backend{VectorBackend, ScanlineBackend} {
+		name := "vector"
+		if backend == ScanlineBackend {
+			name = "scanline"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				DrawWithOptions(c, canvas.DPMM(4.0), &DrawOptions{Backend: backend})
+			}
+		})
+	}
+}
+
+func BenchmarkBackendText(b *testing.B) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	if err := family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		b.Fatal(err)
+	}
+	face := family.Face(10.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(200.0, 200.0)
+	ctx := canvas.NewContext(c)
+	for y := 5.0; y < 200.0; y += 5.0 {
+		ctx.DrawText(2.0, y, canvas.NewTextLine(face, "The quick brown fox jumps over the lazy dog 0123456789", canvas.Left))
+	}
+	benchmarkBackends(b, c)
+}
+
+func BenchmarkBackendPolygons(b *testing.B) {
+	benchmarkBackends(b, denseCanvas(10000))
+}
+
+func TestRecorderReplay(t *testing.T) {
+	draw := func(r canvas.Renderer) {
+		ctx := canvas.NewContext(r)
+		ctx.SetFillColor(canvas.Red)
+		ctx.DrawPath(10.0, 5.0, canvas.Circle(4.0))
+		ctx.SetFillColor(color.RGBA{0, 0, 128, 128})
+		ctx.DrawPath(12.0, 2.0, canvas.Rectangle(10.0, 6.0))
+	}
+
+	direct := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw(New(direct, canvas.DPMM(2.0)))
+
+	rec := canvas.NewRecorder(30.0, 20.0)
+	draw(rec)
+	test.T(t, Draw(&rec.Canvas, canvas.DPMM(2.0)).Pix, direct.Pix)
+
+	replayed := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	rec.Replay(New(replayed, canvas.DPMM(2.0)))
+	test.T(t, replayed.Pix, direct.Pix)
+
+	// replaying twice the size is the same as rasterizing at twice the resolution
+	scaled := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	rec.ReplayScaled(New(scaled, canvas.DPMM(2.0)), canvas.Identity.Scale(2.0, 2.0))
+	test.T(t, scaled.Pix, Draw(&rec.Canvas, canvas.DPMM(4.0)).Pix)
+}
+
+func TestRendererLinearGradient(t *testing.T) {
+	g, err := canvas.NewLinearGradient(0.0, 0.0, 20.0, 0.0, canvas.PadSpread, canvas.Stop{0.0, canvas.Black}, canvas.Stop{1.0, canvas.White})
+	test.Error(t, err)
+
+	for _, backend := range []Backend{VectorBackend, ScanlineBackend} {
+		c := canvas.New(40.0, 10.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillPaint(g)
+		ctx.DrawPath(10.0, 0.0, canvas.Rectangle(20.0, 10.0))
+
+		img := image.NewRGBA(image.Rect(0, 0, 40, 10))
+		r := New(img, canvas.DPMM(1.0))
+		r.SetBackend(backend)
+		c.Render(r)
+
+		test.T(t, img.RGBAAt(5, 5), color.RGBA{})
+		test.T(t, img.RGBAAt(10, 5).R, uint8(6))
+		test.T(t, img.RGBAAt(29, 5).R, uint8(249))
+		for x := 11; x < 30; x++ {
+			prev, col := img.RGBAAt(x-1, 5), img.RGBAAt(x, 5)
+			test.That(t, prev.R < col.R, "pixel values must increase", x)
+			test.T(t, col.A, uint8(255))
+		}
+	}
+}
+
+func TestRendererRadialGradient(t *testing.T) {
+	// centered on pixel (20,20)
+	g, err := canvas.NewRadialGradient(20.5, 19.5, 10.0, 20.5, 19.5, canvas.PadSpread, canvas.Stop{0.0, canvas.Black}, canvas.Stop{1.0, canvas.White})
+	test.Error(t, err)
+
+	c := canvas.New(40.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(40.0, 40.0))
+
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(20, 20), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(25, 20), color.RGBA{128, 128, 128, 255})
+	test.T(t, img.RGBAAt(20, 15), color.RGBA{128, 128, 128, 255})
+	test.T(t, img.RGBAAt(35, 20), color.RGBA{255, 255, 255, 255})
+	test.T(t, img.RGBAAt(2, 2), color.RGBA{255, 255, 255, 255})
+}
+
+func TestRendererAlongPathGradient(t *testing.T) {
+	g, err := canvas.NewAlongPathGradient(canvas.Stop{0.0, canvas.Red}, canvas.Stop{1.0, canvas.Blue})
+	test.Error(t, err)
+
+	c := canvas.New(40.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokePaint(g)
+	ctx.SetStrokeWidth(4.0)
+	ctx.MoveTo(0.0, 5.0)
+	ctx.LineTo(40.0, 5.0)
+	ctx.Stroke()
+
+	img := Draw(c, canvas.DPMM(1.0))
+	start, middle, end := img.RGBAAt(0, 5), img.RGBAAt(20, 5), img.RGBAAt(39, 5)
+	test.That(t, 245 < start.R && start.B < 10, start)
+	test.That(t, 120 < middle.R && middle.R < 136 && 120 < middle.B && middle.B < 136, middle)
+	test.That(t, end.R < 10 && 245 < end.B, end)
+	test.T(t, middle.A, uint8(255))
+	test.T(t, img.RGBAAt(20, 1), color.RGBA{})
+}
+
+func TestRendererImagePattern(t *testing.T) {
+	checker := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	checker.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+	checker.SetRGBA(1, 0, color.RGBA{255, 255, 255, 255})
+	checker.SetRGBA(0, 1, color.RGBA{255, 255, 255, 255})
+	checker.SetRGBA(1, 1, color.RGBA{0, 0, 0, 255})
+
+	c := canvas.New(8.0, 8.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.ImagePattern{checker, canvas.Identity, canvas.RepeatXY})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(8.0, 8.0))
+
+	img := Draw(c, canvas.DPMM(1.0))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			test.T(t, img.RGBAAt(x, y), checker.RGBAAt(x%2, y%2), x, y)
+		}
+	}
+
+	c = canvas.New(8.0, 8.0)
+	ctx = canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.ImagePattern{checker, canvas.Identity.Translate(2.0, 2.0).Scale(2.0, 2.0), canvas.NoRepeat})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(8.0, 8.0))
+
+	img = Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(1, 5), color.RGBA{}) // left of the image
+	test.T(t, img.RGBAAt(6, 5), color.RGBA{}) // right of the image
+	test.T(t, img.RGBAAt(3, 1), color.RGBA{}) // above the image
+	test.T(t, img.RGBAAt(2, 2), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(5, 2), color.RGBA{255, 255, 255, 255})
+	test.T(t, img.RGBAAt(2, 5), color.RGBA{255, 255, 255, 255})
+	test.T(t, img.RGBAAt(5, 5), color.RGBA{0, 0, 0, 255})
+}
+
+func TestRendererPathPattern(t *testing.T) {
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Black
+	dots := canvas.PathPattern{canvas.Circle(1.0).Translate(2.0, 2.0), 4.0, 4.0, style, canvas.Identity}
+
+	c := canvas.New(24.0, 16.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(dots)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(18.5, 12.0))
+
+	img := Draw(c, canvas.DPMM(2.0))
+	at := func(x, y float64) color.RGBA {
+		return img.RGBAAt(int(x*2.0), int((16.0-y)*2.0))
+	}
+
+	n := 0
+	for y := 2.0; y < 16.0; y += 4.0 {
+		for x := 2.0; x < 24.0; x += 4.0 {
+			if at(x, y).A == 255 {
+				n++
+			}
+			test.T(t, at(x-2.0, y-2.0), color.RGBA{}) // between the dots
+		}
+	}
+	test.T(t, n, 15)
+
+	// the dots in the last column are clipped by the rectangle
+	test.T(t, at(18.1, 2.0), color.RGBA{0, 0, 0, 255})
+	test.T(t, at(18.6, 2.0), color.RGBA{})
+
+	// a dot on the corner of the cells is drawn in all four cells
+	corner := canvas.PathPattern{canvas.Circle(1.0), 4.0, 4.0, style, canvas.Identity}
+	c = canvas.New(8.0, 8.0)
+	ctx = canvas.NewContext(c)
+	ctx.SetFillPaint(corner)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(8.0, 8.0))
+
+	img = Draw(c, canvas.DPMM(2.0))
+	test.T(t, img.RGBAAt(7, 7), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(8, 7), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(7, 8), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(8, 8), color.RGBA{0, 0, 0, 255})
+	test.T(t, img.RGBAAt(4, 4), color.RGBA{})
+}
+
+func TestRendererLineStyle(t *testing.T) {
+	// a sharp join of 30 degrees at (20,10) and a line end at (20,25), the miter length of the join is 3.86 times the stroke width
+	angle := 30.0 * math.Pi / 180.0
+	join := &canvas.Path{}
+	join.MoveTo(2.0, 10.0)
+	join.LineTo(20.0, 10.0)
+	join.LineTo(20.0-18.0*math.Cos(angle), 10.0+18.0*math.Sin(angle))
+	line := &canvas.Path{}
+	line.MoveTo(2.0, 25.0)
+	line.LineTo(20.0, 25.0)
+
+	render := func(capper canvas.Capper, joiner canvas.Joiner, miterLimit float64) func(float64, float64) uint8 {
+		c := canvas.New(30.0, 30.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillColor(canvas.Transparent)
+		ctx.SetStrokeColor(canvas.Black)
+		ctx.SetStrokeWidth(2.0)
+		ctx.SetStrokeCapper(capper)
+		ctx.SetStrokeJoiner(joiner)
+		if !math.IsNaN(miterLimit) {
+			ctx.SetMiterLimit(miterLimit)
+		}
+		ctx.DrawPath(0.0, 0.0, join)
+		ctx.DrawPath(0.0, 0.0, line)
+
+		img := Draw(c, canvas.DPMM(10.0))
+		return func(x, y float64) uint8 {
+			return img.RGBAAt(int(x*10.0), int((30.0-y)*10.0)).A
+		}
+	}
+
+	// points at a distance from the join on the outside of the corner
+	outside := func(d float64) (float64, float64) {
+		return 20.0 + d*math.Cos(angle/2.0), 10.0 - d*math.Sin(angle/2.0)
+	}
+
+	at := render(canvas.ButtCap, canvas.MiterJoin, 4.0)
+	test.T(t, at(outside(0.6)), uint8(255))
+	test.T(t, at(outside(3.0)), uint8(255)) // within the miter limit of 4
+	test.T(t, at(outside(4.2)), uint8(0))
+	test.T(t, at(20.5, 25.0), uint8(0))
+
+	at = render(canvas.ButtCap, canvas.MiterJoin, math.NaN())
+	test.T(t, at(outside(0.6)), uint8(0)) // exceeds the default miter limit of 2
+
+	at = render(canvas.ButtCap, canvas.MiterJoin, 3.0)
+	test.T(t, at(outside(0.6)), uint8(0)) // falls back to a bevel join
+	test.T(t, at(outside(3.0)), uint8(0))
+
+	at = render(canvas.RoundCap, canvas.RoundJoin, math.NaN())
+	test.T(t, at(outside(0.6)), uint8(255))
+	test.T(t, at(outside(1.5)), uint8(0))
+	test.T(t, at(20.5, 25.0), uint8(255))
+	test.T(t, at(20.85, 25.85), uint8(0))
+
+	at = render(canvas.SquareCap, canvas.BevelJoin, math.NaN())
+	test.T(t, at(outside(0.6)), uint8(0))
+	test.T(t, at(outside(0.1)), uint8(255))
+	test.T(t, at(20.5, 25.0), uint8(255))
+	test.T(t, at(20.85, 25.85), uint8(255))
+	test.T(t, at(21.2, 25.0), uint8(0))
+}
+
+func TestRendererFillRule(t *testing.T) {
+	// a star with crossing edges and a donut of two rings in the same direction
+	star := &canvas.Path{}
+	for i := 0; i < 5; i++ {
+		theta := math.Pi/2.0 + float64(2*i)*2.0*math.Pi/5.0
+		if i == 0 {
+			star.MoveTo(8.0*math.Cos(theta), 8.0*math.Sin(theta))
+		} else {
+			star.LineTo(8.0*math.Cos(theta), 8.0*math.Sin(theta))
+		}
+	}
+	star.Close()
+	donut := canvas.Circle(8.0).Append(canvas.Circle(4.0))
+
+	for _, backend := range []Backend{VectorBackend, ScanlineBackend} {
+		for _, rule := range []canvas.FillRule{canvas.NonZero, canvas.EvenOdd} {
+			c := canvas.New(40.0, 20.0)
+			ctx := canvas.NewContext(c)
+			ctx.SetFillRule(rule)
+			ctx.DrawPath(10.0, 10.0, star)
+			ctx.DrawPath(30.0, 10.0, donut)
+
+			img := image.NewRGBA(image.Rect(0, 0, 80, 40))
+			ras := New(img, canvas.DPMM(2.0))
+			ras.SetBackend(backend)
+			c.Render(ras)
+			at := func(x, y float64) uint8 {
+				return img.RGBAAt(int(x*2.0), int((20.0-y)*2.0)).A
+			}
+
+			hole := uint8(255)
+			if rule == canvas.EvenOdd {
+				hole = 0
+			}
+			test.T(t, at(10.0, 10.0), hole, backend, rule)       // center of the star
+			test.T(t, at(10.0, 16.0), uint8(255), backend, rule) // tip of the star
+			test.T(t, at(30.0, 10.0), hole, backend, rule)       // center of the donut
+			test.T(t, at(30.0, 16.0), uint8(255), backend, rule) // ring of the donut
+		}
+	}
+}
+
+func TestRendererOpacity(t *testing.T) {
+	c := canvas.New(30.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.SetFillOpacity(0.5)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctx.Push()
+	ctx.SetGlobalAlpha(0.5)
+	ctx.SetFillColor(canvas.Blue)
+	ctx.SetFillOpacity(1.0)
+	ctx.DrawPath(10.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctx.Pop()
+
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(5, 5), color.RGBA{128, 0, 0, 128})
+	test.T(t, img.RGBAAt(25, 5), color.RGBA{0, 0, 128, 128})
+
+	// blue over red: the color is the sum of the premultiplied colors, where red is weighed by the transparency of blue
+	a := 128.0 / 255.0
+	overlap := color.RGBA{uint8(128.0*(1.0-a) + 0.5), 0, 128, uint8(128.0 + 128.0*(1.0-a) + 0.5)}
+	col := img.RGBAAt(15, 5)
+	test.That(t, math.Abs(float64(col.R)-float64(overlap.R)) <= 1.0 && col.G == 0 && col.B == overlap.B && math.Abs(float64(col.A)-float64(overlap.A)) <= 1.0, col, overlap)
+}
+
+func TestRendererPartialStyle(t *testing.T) {
+	// a partial style gets the default capper and joiner so that strokes can be drawn
+	c := canvas.New(30.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStyle(canvas.Style{FillColor: canvas.Red, StrokeColor: canvas.Blue, StrokeWidth: 2.0, StrokeTransparency: 0.5})
+	ctx.DrawPath(5.0, 2.0, canvas.Rectangle(20.0, 6.0))
+	ctx.DrawLine(0.0, 9.0, 30.0, 9.0)
+
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(15, 5), color.RGBA{255, 0, 0, 255})
+	test.T(t, img.RGBAAt(2, 0), color.RGBA{0, 0, 128, 128})
+}
+
+func TestRendererBlendMode(t *testing.T) {
+	backdrop := color.RGBA{200, 100, 50, 255}
+	source := color.RGBA{50, 150, 220, 255}
+	tests := []struct {
+		mode  canvas.BlendMode
+		blend func(cb, cs float64) float64
+	}{
+		{canvas.BlendNormal, func(cb, cs float64) float64 { return cs }},
+		{canvas.BlendMultiply, func(cb, cs float64) float64 { return cb * cs }},
+		{canvas.BlendScreen, func(cb, cs float64) float64 { return cb + cs - cb*cs }},
+		{canvas.BlendOverlay, func(cb, cs float64) float64 {
+			if cb <= 0.5 {
+				return 2.0 * cb * cs
+			}
+			return 1.0 - 2.0*(1.0-cb)*(1.0-cs)
+		}},
+		{canvas.BlendDarken, math.Min},
+		{canvas.BlendLighten, math.Max},
+		{canvas.BlendDifference, func(cb, cs float64) float64 { return math.Abs(cb - cs) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			c := canvas.New(20.0, 10.0)
+			ctx := canvas.NewContext(c)
+			ctx.SetFillColor(backdrop)
+			ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 10.0))
+			ctx.SetFillColor(source)
+			ctx.Push()
+			ctx.SetBlendMode(tt.mode)
+			ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+			ctx.Pop()
+			ctx.DrawPath(10.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+			blend := func(cb, cs uint8) uint8 {
+				return uint8(tt.blend(float64(cb)/255.0, float64(cs)/255.0)*255.0 + 0.5)
+			}
+			expected := color.RGBA{blend(backdrop.R, source.R), blend(backdrop.G, source.G), blend(backdrop.B, source.B), 255}
+			for _, workers := range []int{1, 4} {
+				img := DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Workers: workers})
+				col := img.RGBAAt(5, 5)
+				test.That(t, absDiff(col.R, expected.R) <= 1 && absDiff(col.G, expected.G) <= 1 && absDiff(col.B, expected.B) <= 1 && col.A == 255, col, expected)
+
+				// the blend mode is restored by Pop
+				test.T(t, img.RGBAAt(15, 5), source)
+			}
+		})
+	}
+
+	// translucent source over an opaque backdrop: the blended color is mixed with the source color by the alpha of the source
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.White)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.SetBlendMode(canvas.BlendMultiply)
+	ctx.SetFillColor(color.RGBA{0, 0, 128, 128}) // blue at half opacity
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	col := Draw(c, canvas.DPMM(1.0)).RGBAAt(5, 5)
+	test.That(t, absDiff(col.R, 127) <= 1 && absDiff(col.G, 127) <= 1 && col.B == 255 && col.A == 255, col)
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
+
+func TestRendererImageTransformed(t *testing.T) {
+	// test pattern of four quadrants, where the top-right quadrant is transparent
+	quadrants := [4]color.RGBA{canvas.Red, canvas.Transparent, canvas.Green, canvas.Blue}
+	pattern := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			pattern.SetRGBA(x, y, quadrants[2*(y/10)+x/10])
+		}
+	}
+
+	c := canvas.New(40.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.White)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(40.0, 40.0))
+	m := canvas.Identity.Translate(20.0, 5.0).Rotate(30.0)
+	ctx.DrawImageTransformed(pattern, m)
+	img := Draw(c, canvas.DPMM(1.0))
+
+	// compare against the test pattern at the pixels whose centers fall well inside a quadrant
+	inv := m.Inv()
+	n := 0
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			p := inv.Dot(canvas.Point{float64(x) + 0.5, 40.0 - float64(y) - 0.5})
+			u, v := p.X, 20.0-p.Y // pixel coordinates in the test pattern
+			du, dv := math.Abs(u-10.0), math.Abs(v-10.0)
+			if du < 2.0 || dv < 2.0 || u < 2.0 || 18.0 < u || v < 2.0 || 18.0 < v {
+				continue
+			}
+			expected := quadrants[2*int(v/10.0)+int(u/10.0)]
+			if expected.A == 0 {
+				expected = canvas.White
+			}
+			col := img.RGBAAt(x, y)
+			test.That(t, absDiff(col.R, expected.R) <= 1 && absDiff(col.G, expected.G) <= 1 && absDiff(col.B, expected.B) <= 1 && col.A == 255, x, y, col, expected)
+			n++
+		}
+	}
+	test.That(t, 100 < n, n)
+
+	// the backdrop is untouched outside the rotated image
+	test.T(t, img.RGBAAt(2, 2), canvas.White)
+	test.T(t, img.RGBAAt(37, 37), canvas.White)
+}
+
+func TestRendererCoordSystem(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(30.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	draw := func(coordSystem canvas.CoordSystem, text bool) *image.RGBA {
+		c := canvas.New(40.0, 30.0)
+		ctx := canvas.NewContext(c)
+		ctx.Translate(2.0, 0.0)
+		ctx.SetCoordSystem(coordSystem)
+		ctx.Push()
+		ctx.SetCoordSystem(canvas.CartesianII) // restored by Pop
+		ctx.Pop()
+		if text {
+			y := 5.0
+			if coordSystem == canvas.CartesianIV {
+				y = 25.0
+			}
+			ctx.DrawText(5.0, y, canvas.NewTextLine(face, "Fy", canvas.Left))
+			return Draw(c, canvas.DPMM(2.0))
+		}
+
+		// asymmetric shape as the current path and a circle at a position
+		ctx.SetFillColor(canvas.Red)
+		ctx.MoveTo(5.0, 2.0)
+		ctx.LineTo(30.0, 5.0)
+		ctx.LineTo(10.0, 20.0)
+		ctx.Arc(3.0, 3.0, 0.0, 180.0, 270.0)
+		ctx.Close()
+		ctx.Fill()
+		ctx.SetFillColor(canvas.Blue)
+		ctx.DrawPath(30.0, 20.0, canvas.Circle(4.0))
+		return Draw(c, canvas.DPMM(2.0))
+	}
+
+	// the figure is mirrored vertically
+	up, down := draw(canvas.CartesianI, false), draw(canvas.CartesianIV, false)
+	size := up.Bounds().Size()
+	red, blue := 0, 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			// anti-aliasing of the edges may differ slightly
+			col, col2 := up.RGBAAt(x, y), down.RGBAAt(x, size.Y-1-y)
+			test.That(t, absDiff(col.R, col2.R) <= 16 && absDiff(col.B, col2.B) <= 16 && absDiff(col.A, col2.A) <= 16, x, y, col, col2)
+			if col == canvas.Red {
+				red++
+			} else if col == canvas.Blue {
+				blue++
+			}
+		}
+	}
+	test.That(t, 500 < red && 100 < blue, red, blue)
+
+	// text stays upright at the mirrored position
+	test.T(t, draw(canvas.CartesianIV, true).Pix, draw(canvas.CartesianI, true).Pix)
+}
+
+func TestRendererFit(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(-13.7, 41.3, canvas.Circle(3.3))
+	ctx.SetStrokeColor(canvas.Blue)
+	ctx.SetStrokeWidth(1.5)
+	ctx.Rotate(17.0)
+	ctx.DrawPath(52.9, 108.1, canvas.StarPolygon(5, 6.0, 2.5, true))
+	c.Fit(2.0)
+
+	// the content is tight against the margin of 2mm on all sides
+	img := Draw(c, canvas.DPMM(10.0))
+	size := img.Bounds().Size()
+	x0, y0, x1, y1 := size.X, size.Y, 0, 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if img.RGBAAt(x, y).A != 0 {
+				if x < x0 {
+					x0 = x
+				}
+				if y < y0 {
+					y0 = y
+				}
+				if x1 < x+1 {
+					x1 = x + 1
+				}
+				if y1 < y+1 {
+					y1 = y + 1
+				}
+			}
+		}
+	}
+	for _, margin := range []int{x0, y0, size.X - x1, size.Y - y1} {
+		test.That(t, 19 <= margin && margin <= 21, margin, x0, y0, x1, y1, size)
+	}
+}
+
+func TestRendererErase(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.Erase(2.0, 2.0, canvas.Rectangle(4.0, 4.0))
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(3.0, 3.0, canvas.Rectangle(1.0, 1.0))
+
+	for _, draw := range []func(*canvas.Canvas) *image.RGBA{
+		func(c *canvas.Canvas) *image.RGBA { return Draw(c, canvas.DPMM(1.0)) },
+		func(c *canvas.Canvas) *image.RGBA {
+			return DrawWithOptions(c, canvas.DPMM(1.0), &DrawOptions{Workers: 4})
+		},
+	} {
+		img := draw(c)
+		test.T(t, img.RGBAAt(0, 0), canvas.Red)
+		test.T(t, img.RGBAAt(2, 7), color.RGBA{})
+		test.T(t, img.RGBAAt(3, 6), canvas.Blue)
+		test.T(t, img.RGBAAt(5, 4), color.RGBA{})
+	}
+
+	// the erased region shows the background
+	c.SetBackground(canvas.Yellow)
+	img := Draw(c, canvas.DPMM(1.0))
+	test.T(t, img.RGBAAt(0, 0), canvas.Red)
+	test.T(t, img.RGBAAt(2, 7), canvas.Yellow)
+	test.T(t, img.RGBAAt(3, 6), canvas.Blue)
+
+	// a reused canvas has nothing of the previous drawing
+	c.Reset()
+	ctx = canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Green)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(5.0, 5.0))
+	fresh := canvas.New(10.0, 10.0)
+	ctx = canvas.NewContext(fresh)
+	ctx.SetFillColor(canvas.Green)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(5.0, 5.0))
+	test.T(t, Draw(c, canvas.DPMM(1.0)).Pix, Draw(fresh, canvas.DPMM(1.0)).Pix)
+}
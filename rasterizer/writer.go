@@ -1,21 +1,126 @@
 package rasterizer
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"math"
 
 	"github.com/tdewolff/canvas"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
 )
 
-// PNGWriter writes the canvas as a PNG file
+// PNGWriter writes the canvas as a PNG file, where the pHYs chunk is set to the given resolution so that the image keeps the physical size of the canvas.
 func PNGWriter(resolution canvas.DPMM) canvas.Writer {
+	return PNGWriterWithOptions(resolution, nil)
+}
+
+// PNGCompressionWriter writes the canvas as a PNG file like PNGWriter, but using the given compression level such as png.BestCompression.
+func PNGCompressionWriter(resolution canvas.DPMM, level png.CompressionLevel) canvas.Writer {
+	return PNGWriterWithOptions(resolution, &PNGOptions{CompressionLevel: level})
+}
+
+// PNGOptions are the options for PNG encoding.
+type PNGOptions struct {
+	CompressionLevel png.CompressionLevel
+	Depth16          bool   // rasterize and write with 16 bits per channel
+	SRGB             bool   // write an sRGB chunk to tag the colors as sRGB
+	ICCProfile       []byte // ICC profile to write in an iCCP chunk, this takes precedence over SRGB
+	ICCProfileName   string // name of the ICC profile, which is "ICC profile" if empty
+	DrawOptions             // supersampling and anti-aliasing
+}
+
+// PNGWriterWithOptions writes the canvas as a PNG file like PNGWriter using the given options, which may be nil.
+func PNGWriterWithOptions(resolution canvas.DPMM, opts *PNGOptions) canvas.Writer {
+	if opts == nil {
+		opts = &PNGOptions{}
+	}
 	return func(w io.Writer, c *canvas.Canvas) error {
-		img := Draw(c, resolution)
+		var img draw.Image
+		width, height := c.SizePx(resolution)
+		bounds := image.Rect(0, 0, width, height)
+		if opts.Depth16 {
+			img = image.NewRGBA64(bounds)
+		} else {
+			img = image.NewRGBA(bounds)
+		}
+		drawWithOptions(img, c, resolution, &opts.DrawOptions)
 		// TODO: optimization: cache img until canvas changes
-		return png.Encode(w, img)
+
+		buf := &bytes.Buffer{}
+		enc := &png.Encoder{CompressionLevel: opts.CompressionLevel}
+		if err := enc.Encode(buf, img); err != nil {
+			return err
+		}
+
+		ppm := uint32(math.Round(float64(resolution) * 1000.0)) // pixels per meter
+		phys := make([]byte, 9)
+		binary.BigEndian.PutUint32(phys[0:], ppm)
+		binary.BigEndian.PutUint32(phys[4:], ppm)
+		phys[8] = 1 // unit is meter
+		chunks := [][]byte{pngChunk("pHYs", phys)}
+
+		if opts.ICCProfile != nil {
+			name := opts.ICCProfileName
+			if name == "" {
+				name = "ICC profile"
+			} else if 79 < len(name) {
+				return fmt.Errorf("ICC profile name must be at most 79 bytes")
+			}
+			iccp := &bytes.Buffer{}
+			iccp.WriteString(name)
+			iccp.Write([]byte{0, 0}) // null separator and compression method
+			zw := zlib.NewWriter(iccp)
+			if _, err := zw.Write(opts.ICCProfile); err != nil {
+				return err
+			} else if err := zw.Close(); err != nil {
+				return err
+			}
+			chunks = append(chunks, pngChunk("iCCP", iccp.Bytes()))
+		} else if opts.SRGB {
+			chunks = append(chunks, pngChunk("sRGB", []byte{0})) // perceptual rendering intent
+		}
+		return writePNGChunks(w, buf.Bytes(), chunks)
+	}
+}
+
+// pngChunk returns a PNG chunk of the given type and data, including its length and CRC.
+func pngChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 4+4+len(data)+4)
+	binary.BigEndian.PutUint32(chunk[0:], uint32(len(data)))
+	copy(chunk[4:], typ)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+	return chunk
+}
+
+// writePNGChunks writes the PNG file b to w with the chunks inserted after the IHDR chunk, since png.Encode does not write ancillary chunks such as the resolution.
+func writePNGChunks(w io.Writer, b []byte, chunks [][]byte) error {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4 // signature, length, type, data, CRC
+	if len(b) < ihdrEnd || string(b[12:16]) != "IHDR" {
+		return fmt.Errorf("bad PNG: expected IHDR chunk")
+	}
+
+	if _, err := w.Write(b[:ihdrEnd]); err != nil {
+		return err
 	}
+	for _, chunk := range chunks {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(b[ihdrEnd:])
+	return err
 }
 
 // JPGWriter writes the canvas as a JPG file
@@ -27,11 +132,115 @@ func JPGWriter(resolution canvas.DPMM, opts *jpeg.Options) canvas.Writer {
 	}
 }
 
-// GIFWriter writes the canvas as a GIF file
+// GIFWriter writes the canvas as a GIF file. If no quantizer is given in the options, a palette is made using MedianCutQuantizer. Colors are dithered using Floyd-Steinberg unless a different drawer is given.
 func GIFWriter(resolution canvas.DPMM, opts *gif.Options) canvas.Writer {
 	return func(w io.Writer, c *canvas.Canvas) error {
 		img := Draw(c, resolution)
 		// TODO: optimization: cache img until canvas changes
+		if opts == nil || opts.Quantizer == nil {
+			gifOpts := gif.Options{NumColors: 256}
+			if opts != nil {
+				gifOpts = *opts
+			}
+			gifOpts.Quantizer = MedianCutQuantizer{}
+			opts = &gifOpts
+		}
 		return gif.Encode(w, img, opts)
 	}
 }
+
+// BMPWriter writes the canvas as a BMP file. Since BMP files are commonly read without alpha channel, the canvas is drawn over the given background color, which is white if nil. An opaque background results in a 24-bit BMP file.
+func BMPWriter(resolution canvas.DPMM, background color.Color) canvas.Writer {
+	return func(w io.Writer, c *canvas.Canvas) error {
+		if background == nil {
+			background = canvas.White
+		}
+		width, height := c.SizePx(resolution)
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+		c.Render(New(img, resolution))
+		return bmp.Encode(w, img)
+	}
+}
+
+// TIFFWriter writes the canvas as a TIFF file, where the XResolution and YResolution tags are set to the given resolution so that the image keeps the physical size of the canvas. Options select the compression and may be nil. Only tiff.Uncompressed and tiff.Deflate are supported by the encoder, for which the differencing predictor is not used.
+func TIFFWriter(resolution canvas.DPMM, opts *tiff.Options) canvas.Writer {
+	return func(w io.Writer, c *canvas.Canvas) error {
+		if opts != nil && opts.Compression != tiff.Uncompressed && opts.Compression != tiff.Deflate {
+			return fmt.Errorf("unsupported TIFF compression: only uncompressed and Deflate are supported")
+		}
+
+		img := Draw(c, resolution)
+		// TODO: optimization: cache img until canvas changes
+		buf := &bytes.Buffer{}
+		if err := tiff.Encode(buf, img, opts); err != nil {
+			return err
+		}
+		if err := setTIFFResolution(buf.Bytes(), resolution); err != nil {
+			return err
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+}
+
+// setTIFFResolution overwrites the resolution tags of the first image file directory in b, since tiff.Encode always writes 72 DPI.
+func setTIFFResolution(b []byte, resolution canvas.DPMM) error {
+	if len(b) < 8 || string(b[:4]) != "II*\x00" {
+		return fmt.Errorf("bad TIFF: expected little-endian header")
+	}
+	dpi := float64(resolution) * 25.4
+	num, den := uint32(math.Round(dpi*1e4)), uint32(1e4)
+
+	ifd := int(binary.LittleEndian.Uint32(b[4:]))
+	if len(b) < ifd+2 {
+		return fmt.Errorf("bad TIFF: image file directory out of bounds")
+	}
+	n := int(binary.LittleEndian.Uint16(b[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + 12*i
+		if len(b) < entry+12 {
+			return fmt.Errorf("bad TIFF: image file directory out of bounds")
+		}
+		tag := binary.LittleEndian.Uint16(b[entry:])
+		if tag == 282 || tag == 283 { // XResolution and YResolution
+			offset := int(binary.LittleEndian.Uint32(b[entry+8:]))
+			if len(b) < offset+8 {
+				return fmt.Errorf("bad TIFF: resolution out of bounds")
+			}
+			binary.LittleEndian.PutUint32(b[offset:], num)
+			binary.LittleEndian.PutUint32(b[offset+4:], den)
+		} else if tag == 296 { // ResolutionUnit
+			binary.LittleEndian.PutUint16(b[entry+8:], 2) // inch
+		}
+	}
+	return nil
+}
+
+// WebPOptions are the options for WebP encoding. Nil options or a zero value result in lossless encoding.
+type WebPOptions struct {
+	Lossy   bool
+	Quality float64 // quality for lossy encoding, in [0,100]
+}
+
+// WebPEncoderFunc encodes an image as WebP.
+type WebPEncoderFunc func(w io.Writer, img image.Image, opts *WebPOptions) error
+
+var webpEncoder WebPEncoderFunc
+
+// RegisterWebPEncoder registers the encoder used by WebPWriter. There is no pure-Go WebP encoder in the standard library or golang.org/x/image, so that users can choose an encoder (eg. a cgo binding to libwebp) without this package depending on it.
+func RegisterWebPEncoder(encoder WebPEncoderFunc) {
+	webpEncoder = encoder
+}
+
+// WebPWriter writes the canvas as a WebP file using the encoder registered with RegisterWebPEncoder. It returns an error if no encoder was registered.
+func WebPWriter(resolution canvas.DPMM, opts *WebPOptions) canvas.Writer {
+	return func(w io.Writer, c *canvas.Canvas) error {
+		if webpEncoder == nil {
+			return fmt.Errorf("no WebP encoder registered, see RegisterWebPEncoder")
+		}
+		img := Draw(c, resolution)
+		// TODO: optimization: cache img until canvas changes
+		return webpEncoder(w, img, opts)
+	}
+}
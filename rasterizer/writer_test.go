@@ -0,0 +1,317 @@
+package rasterizer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func TestTIFFWriter(t *testing.T) {
+	c := canvas.New(10.0, 5.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(5.0, 5.0))
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(5.0, 2.5, canvas.Rectangle(5.0, 2.5))
+
+	pngBuf := &bytes.Buffer{}
+	test.Error(t, PNGWriter(2.0)(pngBuf, c))
+	imgPNG, err := png.Decode(pngBuf)
+	test.Error(t, err)
+
+	for _, opts := range []*tiff.Options{nil, {Compression: tiff.Uncompressed}, {Compression: tiff.Deflate}} {
+		buf := &bytes.Buffer{}
+		test.Error(t, TIFFWriter(2.0, opts)(buf, c))
+
+		// resolution tags
+		b := buf.Bytes()
+		ifd := int(binary.LittleEndian.Uint32(b[4:]))
+		found := 0
+		for i := 0; i < int(binary.LittleEndian.Uint16(b[ifd:])); i++ {
+			entry := ifd + 2 + 12*i
+			switch binary.LittleEndian.Uint16(b[entry:]) {
+			case 282, 283:
+				offset := int(binary.LittleEndian.Uint32(b[entry+8:]))
+				num, den := binary.LittleEndian.Uint32(b[offset:]), binary.LittleEndian.Uint32(b[offset+4:])
+				test.Float(t, float64(num)/float64(den), 50.8)
+				found++
+			case 296:
+				test.T(t, binary.LittleEndian.Uint16(b[entry+8:]), uint16(2))
+				found++
+			}
+		}
+		test.T(t, found, 3)
+
+		img, err := tiff.Decode(buf)
+		test.Error(t, err)
+		test.T(t, img.Bounds(), imgPNG.Bounds())
+		test.T(t, img.Bounds().Dx(), 20)
+		test.T(t, img.Bounds().Dy(), 10)
+		for _, p := range [][2]int{{2, 2}, {15, 2}, {15, 8}, {9, 9}} {
+			r0, g0, b0, a0 := imgPNG.At(p[0], p[1]).RGBA()
+			r1, g1, b1, a1 := img.At(p[0], p[1]).RGBA()
+			test.T(t, [4]uint32{r1, g1, b1, a1}, [4]uint32{r0, g0, b0, a0})
+		}
+		red, green, blue, _ := img.At(2, 2).RGBA()
+		test.T(t, [3]uint32{red, green, blue}, [3]uint32{0xffff, 0, 0})
+	}
+
+	err = TIFFWriter(2.0, &tiff.Options{Compression: tiff.LZW})(&bytes.Buffer{}, c)
+	test.That(t, err != nil, "LZW compression must return an error")
+}
+
+func TestBMPWriter(t *testing.T) {
+	c := canvas.New(10.0, 5.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(2.0, 1.0, canvas.Rectangle(3.0, 2.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, BMPWriter(2.0, nil)(buf, c))
+	test.T(t, binary.LittleEndian.Uint16(buf.Bytes()[28:]), uint16(24)) // bits per pixel
+
+	img, err := bmp.Decode(buf)
+	test.Error(t, err)
+	test.T(t, img.Bounds().Dx(), 20)
+	test.T(t, img.Bounds().Dy(), 10)
+
+	// the rectangle spans x=[4,10) and y=[4,8) in pixels, with the y-axis pointing down
+	for _, tt := range []struct {
+		x, y int
+		col  color.Color
+	}{
+		{4, 4, canvas.Red},
+		{9, 7, canvas.Red},
+		{3, 4, canvas.White},
+		{10, 7, canvas.White},
+		{4, 3, canvas.White},
+		{9, 8, canvas.White},
+	} {
+		test.T(t, color.RGBAModel.Convert(img.At(tt.x, tt.y)), color.RGBAModel.Convert(tt.col))
+	}
+
+	buf.Reset()
+	test.Error(t, BMPWriter(2.0, canvas.Black)(buf, c))
+	img, err = bmp.Decode(buf)
+	test.Error(t, err)
+	test.T(t, color.RGBAModel.Convert(img.At(0, 0)), color.RGBAModel.Convert(canvas.Black))
+}
+
+func TestWebPWriter(t *testing.T) {
+	c := canvas.New(10.0, 5.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(5.0, 5.0))
+
+	RegisterWebPEncoder(nil)
+	err := WebPWriter(2.0, nil)(&bytes.Buffer{}, c)
+	test.That(t, err != nil, "must return an error without a registered encoder")
+
+	var encodedImg image.Image
+	var encodedOpts *WebPOptions
+	RegisterWebPEncoder(func(w io.Writer, img image.Image, opts *WebPOptions) error {
+		encodedImg, encodedOpts = img, opts
+		_, err := w.Write([]byte("RIFF"))
+		return err
+	})
+	defer RegisterWebPEncoder(nil)
+
+	buf := &bytes.Buffer{}
+	opts := &WebPOptions{Lossy: true, Quality: 80.0}
+	test.Error(t, WebPWriter(2.0, opts)(buf, c))
+	test.String(t, buf.String(), "RIFF")
+	test.T(t, encodedImg.Bounds(), image.Rect(0, 0, 20, 10))
+	test.T(t, encodedOpts, opts)
+
+	encodeErr := errors.New("encoder error")
+	RegisterWebPEncoder(func(w io.Writer, img image.Image, opts *WebPOptions) error {
+		return encodeErr
+	})
+	test.T(t, WebPWriter(2.0, nil)(&bytes.Buffer{}, c), encodeErr)
+}
+
+func TestEncoderOptions(t *testing.T) {
+	c := canvas.New(20.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.White)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 20.0))
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(10.0, 10.0, canvas.Circle(8.0))
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(2.0, 2.0, canvas.RegularPolygon(5, 6.0, true))
+
+	low, high := &bytes.Buffer{}, &bytes.Buffer{}
+	test.Error(t, JPGWriter(5.0, &jpeg.Options{Quality: 30})(low, c))
+	test.Error(t, JPGWriter(5.0, &jpeg.Options{Quality: 95})(high, c))
+	test.That(t, low.Len() < high.Len(), low.Len(), high.Len())
+	for _, buf := range []*bytes.Buffer{low, high} {
+		img, err := jpeg.Decode(buf)
+		test.Error(t, err)
+		test.T(t, img.Bounds(), image.Rect(0, 0, 100, 100))
+	}
+
+	low, high = &bytes.Buffer{}, &bytes.Buffer{}
+	test.Error(t, PNGCompressionWriter(5.0, png.NoCompression)(low, c))
+	test.Error(t, PNGCompressionWriter(5.0, png.BestCompression)(high, c))
+	test.That(t, high.Len() < low.Len(), high.Len(), low.Len())
+	for _, buf := range []*bytes.Buffer{low, high} {
+		img, err := png.Decode(buf)
+		test.Error(t, err)
+		test.T(t, img.Bounds(), image.Rect(0, 0, 100, 100))
+	}
+}
+
+func TestWriterBackground(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(30.0, 30.0, canvas.Circle(5.0))
+	c.SetBackground(canvas.Yellow)
+	c.Fit(2.0) // the background covers the fitted canvas
+
+	buf := &bytes.Buffer{}
+	test.Error(t, PNGWriter(5.0)(buf, c))
+	img, err := png.Decode(buf)
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 70, 70))
+	for _, p := range []image.Point{{0, 0}, {69, 0}, {0, 69}, {69, 69}} {
+		test.T(t, color.RGBAModel.Convert(img.At(p.X, p.Y)), color.Color(canvas.Yellow), p)
+	}
+	test.T(t, color.RGBAModel.Convert(img.At(35, 35)), color.Color(canvas.Red))
+
+	// JPEG has no transparency and would be black without a background
+	buf = &bytes.Buffer{}
+	test.Error(t, JPGWriter(5.0, &jpeg.Options{Quality: 95})(buf, c))
+	img, err = jpeg.Decode(buf)
+	test.Error(t, err)
+	for _, p := range []image.Point{{0, 0}, {69, 0}, {0, 69}, {69, 69}} {
+		r, g, b, _ := img.At(p.X, p.Y).RGBA()
+		test.That(t, 0xf000 < r && 0xf000 < g && b < 0x1000, p, r, g, b)
+	}
+}
+
+func TestPNGWriterResolution(t *testing.T) {
+	c := canvas.New(25.4, 12.7)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	for _, tt := range []struct {
+		dpi           float64
+		ppm           uint32
+		width, height int
+	}{
+		{96.0, 3780, 96, 48},
+		{300.0, 11811, 300, 150},
+	} {
+		buf := &bytes.Buffer{}
+		test.Error(t, PNGWriter(canvas.DPMM(tt.dpi/25.4))(buf, c))
+
+		// walk the chunks and check their CRCs
+		b := buf.Bytes()[8:]
+		types := []string{}
+		for 0 < len(b) {
+			n := int(binary.BigEndian.Uint32(b))
+			types = append(types, string(b[4:8]))
+			test.T(t, binary.BigEndian.Uint32(b[8+n:]), crc32.ChecksumIEEE(b[4:8+n]))
+			if string(b[4:8]) == "pHYs" {
+				test.T(t, n, 9)
+				test.T(t, binary.BigEndian.Uint32(b[8:]), tt.ppm)
+				test.T(t, binary.BigEndian.Uint32(b[12:]), tt.ppm)
+				test.T(t, b[16], byte(1))
+			}
+			b = b[12+n:]
+		}
+		test.T(t, types[:2], []string{"IHDR", "pHYs"})
+		test.T(t, types[len(types)-1], "IEND")
+
+		img, err := png.Decode(buf)
+		test.Error(t, err)
+		test.T(t, img.Bounds(), image.Rect(0, 0, tt.width, tt.height))
+	}
+}
+
+func pngChunks(t *testing.T, b []byte) map[string][]byte {
+	chunks := map[string][]byte{}
+	b = b[8:]
+	for 0 < len(b) {
+		n := int(binary.BigEndian.Uint32(b))
+		test.T(t, binary.BigEndian.Uint32(b[8+n:]), crc32.ChecksumIEEE(b[4:8+n]))
+		chunks[string(b[4:8])] = b[8 : 8+n]
+		b = b[12+n:]
+	}
+	return chunks
+}
+
+func TestPNGDepth16(t *testing.T) {
+	// the bottom row of pixels is a ramp of coverage from 0 to 1
+	c := canvas.New(100.0, 1.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.MustParseSVG("M0 0H100V0.05z"))
+
+	levels := func(img image.Image) int {
+		alphas := map[uint32]bool{}
+		y := img.Bounds().Max.Y - 1
+		for x := 0; x < img.Bounds().Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			alphas[a] = true
+		}
+		return len(alphas)
+	}
+
+	for _, depth16 := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		test.Error(t, PNGWriterWithOptions(20.0, &PNGOptions{Depth16: depth16})(buf, c))
+		chunks := pngChunks(t, buf.Bytes())
+		test.T(t, chunks["IHDR"][8], map[bool]byte{false: 8, true: 16}[depth16]) // bit depth
+
+		img, err := png.Decode(buf)
+		test.Error(t, err)
+		test.T(t, img.Bounds(), image.Rect(0, 0, 2000, 20))
+		if depth16 {
+			test.That(t, 1000 < levels(img), levels(img))
+		} else {
+			test.That(t, levels(img) <= 256, levels(img))
+		}
+	}
+}
+
+func TestPNGColorSpace(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, PNGWriterWithOptions(1.0, &PNGOptions{SRGB: true})(buf, c))
+	chunks := pngChunks(t, buf.Bytes())
+	test.Bytes(t, chunks["sRGB"], []byte{0})
+	_, ok := chunks["iCCP"]
+	test.That(t, !ok)
+
+	profile := []byte("not a real ICC profile")
+	buf.Reset()
+	test.Error(t, PNGWriterWithOptions(1.0, &PNGOptions{SRGB: true, ICCProfile: profile, ICCProfileName: "Test"})(buf, c))
+	chunks = pngChunks(t, buf.Bytes())
+	_, ok = chunks["sRGB"]
+	test.That(t, !ok)
+	iccp := chunks["iCCP"]
+	test.String(t, string(iccp[:6]), "Test\x00\x00")
+	zr, err := zlib.NewReader(bytes.NewReader(iccp[6:]))
+	test.Error(t, err)
+	data, err := ioutil.ReadAll(zr)
+	test.Error(t, err)
+	test.Bytes(t, data, profile)
+
+	_, err = png.Decode(buf)
+	test.Error(t, err)
+}
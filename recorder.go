@@ -0,0 +1,832 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+)
+
+// Recorder records the drawing operations passed to it as a Renderer, so that they can be replayed onto any number of other renderers and sizes without running the drawing code again. Paths and dash arrays are copied when recorded, so that they can be modified afterwards. It is a Canvas, and can also be written to files or fitted. Recordings can be persisted with MarshalBinary and UnmarshalBinary.
+type Recorder struct {
+	Canvas
+
+	embedFonts bool
+	families   map[string]*FontFamily // font families for fonts that are referenced by name
+}
+
+// NewRecorder returns a new Recorder of the given size in mm.
+func NewRecorder(width, height float64) *Recorder {
+	return &Recorder{
+		Canvas:   *New(width, height),
+		families: map[string]*FontFamily{},
+	}
+}
+
+// Replay renders the recorded drawing operations to the renderer.
+func (r *Recorder) Replay(renderer Renderer) {
+	r.Render(renderer)
+}
+
+// ReplayScaled renders the recorded drawing operations to the renderer with all elements transformed by the matrix, such as Identity.Scale(2.0, 2.0) to replay onto a renderer twice the size. Stroke widths and blurs are not scaled.
+func (r *Recorder) ReplayScaled(renderer Renderer, m Matrix) {
+	r.RenderView(renderer, m)
+}
+
+// EmbedFonts sets whether MarshalBinary embeds the font files, which is off by default. Otherwise fonts are referenced by the name and style of their font family, and the font family must be added with AddFontFamily before calling UnmarshalBinary.
+func (r *Recorder) EmbedFonts(embedFonts bool) {
+	r.embedFonts = embedFonts
+}
+
+// AddFontFamily makes the fonts of the font family available to UnmarshalBinary for recordings that reference fonts by name.
+func (r *Recorder) AddFontFamily(family *FontFamily) {
+	if r.families == nil {
+		r.families = map[string]*FontFamily{}
+	}
+	r.families[family.name] = family
+}
+
+////////////////////////////////////////////////////////////////
+
+// The binary format of a recording starts with the magic string, a version number and the size of the canvas, followed by records of a type byte, a length and the data. Records of unknown types are skipped, so that new types can be added without changing the version. Font families are written in records before the first text that uses them and are referenced by their index.
+const (
+	recordMagic   = "CNVSREC"
+	recordVersion = 1
+)
+
+const (
+	fontFamilyRecord byte = iota + 1
+	pathRecord
+	textRecord
+	imageRecord
+	linkRecord
+	linkEndRecord
+	groupRecord
+	groupEndRecord
+	titleRecord
+	blurRecord
+	blurEndRecord
+	blendRecord
+	backgroundRecord
+	eraseRecord
+	hitRecord
+)
+
+var recordDecorators = []FontDecorator{FontUnderline, FontOverline, FontStrikethrough, FontDoubleUnderline, FontDottedUnderline, FontDashedUnderline, FontSineUnderline, FontSawtoothUnderline}
+
+// MarshalBinary encodes the recorded drawing operations, including paths, styles, text and images, in a versioned binary format. Visible named layers are encoded as groups in their z-order. It returns an error for custom cappers, joiners and font decorators, which cannot be encoded.
+func (r *Recorder) MarshalBinary() ([]byte, error) {
+	w := &recordWriter{}
+	w.WriteString(recordMagic)
+	w.uvarint(recordVersion)
+	w.float(r.W)
+	w.float(r.H)
+
+	families := map[*FontFamily]int{}
+	if r.background != nil {
+		rec := &recordWriter{families: families}
+		rec.style(*r.background)
+		if rec.err != nil {
+			return nil, rec.err
+		}
+		w.record(backgroundRecord, rec.Bytes())
+	}
+	hitID := ""
+	for _, l := range r.elements() {
+		if l.hitID != hitID && (l.path != nil || l.text != nil || l.img != nil) {
+			rec := &recordWriter{}
+			rec.str(l.hitID)
+			w.record(hitRecord, rec.Bytes())
+			hitID = l.hitID
+		}
+		rec := &recordWriter{families: families}
+		var typ byte
+		if l.path != nil {
+			typ = pathRecord
+			rec.style(l.style)
+			rec.matrix(l.m)
+			rec.path(l.path)
+		} else if l.text != nil {
+			for _, face := range textFaces(l.text) {
+				if _, ok := families[face.family]; !ok && face.family != nil {
+					families[face.family] = len(families)
+					w.record(fontFamilyRecord, r.encodeFontFamily(face.family))
+				}
+			}
+			typ = textRecord
+			rec.matrix(l.m)
+			rec.text(l.text)
+		} else if l.img != nil {
+			typ = imageRecord
+			rec.matrix(l.m)
+			b := &bytes.Buffer{}
+			if err := png.Encode(b, l.img); err != nil {
+				return nil, err
+			}
+			rec.bytes(b.Bytes())
+		} else if l.link != nil {
+			typ = linkRecord
+			rec.str(l.link.url)
+			rec.matrix(l.m)
+			rec.path(l.link.area)
+		} else if l.linkEnd {
+			typ = linkEndRecord
+		} else if l.group != nil {
+			typ = groupRecord
+			rec.str(l.group.id)
+			rec.str(l.group.class)
+		} else if l.groupEnd {
+			typ = groupEndRecord
+		} else if l.title != nil {
+			typ = titleRecord
+			rec.str(l.title.title)
+			rec.str(l.title.desc)
+		} else if l.blur != nil {
+			typ = blurRecord
+			rec.float(*l.blur)
+		} else if l.blurEnd {
+			typ = blurEndRecord
+		} else if l.blend != nil {
+			typ = blendRecord
+			rec.uvarint(uint64(*l.blend))
+		} else if l.erase != nil {
+			typ = eraseRecord
+			rec.matrix(l.m)
+			rec.path(l.erase)
+		} else {
+			continue
+		}
+		if rec.err != nil {
+			return nil, rec.err
+		}
+		w.record(typ, rec.Bytes())
+	}
+	return w.Bytes(), nil
+}
+
+// encodeFontFamily encodes the name and typographic options of the font family, and its fonts by style, which are embedded if enabled.
+func (r *Recorder) encodeFontFamily(family *FontFamily) []byte {
+	styles := make([]int, 0, len(family.fonts))
+	for style := range family.fonts {
+		styles = append(styles, int(style))
+	}
+	sort.Ints(styles)
+
+	w := &recordWriter{}
+	w.str(family.name)
+	w.uvarint(uint64(family.options))
+	w.uvarint(uint64(len(styles)))
+	for _, style := range styles {
+		w.uvarint(uint64(style))
+		if r.embedFonts {
+			w.WriteByte(1)
+			w.bytes(family.fonts[FontStyle(style)].raw)
+		} else {
+			w.WriteByte(0)
+		}
+	}
+	return w.Bytes()
+}
+
+// textFaces returns the font faces of the spans and decorations of the text.
+func textFaces(text *Text) []FontFace {
+	faces := []FontFace{}
+	for _, line := range text.lines {
+		for _, span := range line.spans {
+			faces = append(faces, span.Face)
+		}
+		for _, deco := range line.decos {
+			faces = append(faces, deco.face)
+		}
+	}
+	return faces
+}
+
+// UnmarshalBinary replaces the recording by the drawing operations decoded from the binary format of MarshalBinary. Fonts that are not embedded are looked up in the font families added with AddFontFamily.
+func (r *Recorder) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(recordMagic)) {
+		return fmt.Errorf("bad recording: invalid header")
+	}
+	d := &recordReader{b: b[len(recordMagic):]}
+	if version := d.uvarint(); d.err == nil && version != recordVersion {
+		return fmt.Errorf("bad recording: unsupported version %d", version)
+	}
+	width, height := d.float(), d.float()
+
+	layers := []layer{}
+	var background *Style
+	hitID := "" // hit ID of the following paths, text and images
+	families := []*FontFamily{}
+	for d.err == nil && 0 < len(d.b) {
+		typ := d.byte()
+		rec := &recordReader{b: d.next(d.uvarint()), families: families}
+		switch typ {
+		case fontFamilyRecord:
+			family, err := r.decodeFontFamily(rec)
+			if err != nil {
+				return err
+			}
+			families = append(families, family)
+		case pathRecord:
+			style := rec.style()
+			m := rec.matrix()
+			layers = append(layers, layer{path: rec.path(), m: m, style: style, hitID: hitID})
+		case textRecord:
+			m := rec.matrix()
+			layers = append(layers, layer{text: rec.text(), m: m, hitID: hitID})
+		case imageRecord:
+			m := rec.matrix()
+			img, err := png.Decode(bytes.NewReader(rec.bytes()))
+			if err != nil && rec.err == nil {
+				rec.err = fmt.Errorf("bad recording: %w", err)
+			}
+			layers = append(layers, layer{img: img, m: m, hitID: hitID})
+		case linkRecord:
+			url := rec.str()
+			m := rec.matrix()
+			layers = append(layers, layer{link: &link{url, rec.path()}, m: m})
+		case linkEndRecord:
+			layers = append(layers, layer{linkEnd: true})
+		case groupRecord:
+			layers = append(layers, layer{group: &group{rec.str(), rec.str()}})
+		case groupEndRecord:
+			layers = append(layers, layer{groupEnd: true})
+		case titleRecord:
+			layers = append(layers, layer{title: &titleDesc{rec.str(), rec.str()}})
+		case blurRecord:
+			sigma := rec.float()
+			layers = append(layers, layer{blur: &sigma})
+		case blurEndRecord:
+			layers = append(layers, layer{blurEnd: true})
+		case blendRecord:
+			mode := BlendMode(rec.uvarint())
+			layers = append(layers, layer{blend: &mode})
+		case hitRecord:
+			hitID = rec.str()
+		case eraseRecord:
+			m := rec.matrix()
+			layers = append(layers, layer{erase: rec.path(), m: m})
+		case backgroundRecord:
+			style := rec.style()
+			background = &style
+		}
+		if rec.err != nil {
+			return rec.err
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	r.layers = layers
+	r.named = nil
+	r.background = background
+	r.W, r.H = width, height
+	return nil
+}
+
+// decodeFontFamily decodes a font family with embedded fonts, or with the fonts of the added font family of the same name.
+func (r *Recorder) decodeFontFamily(d *recordReader) (*FontFamily, error) {
+	family := NewFontFamily(d.str())
+	family.options = TypographicOptions(d.uvarint())
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		style := FontStyle(d.uvarint())
+		if d.byte() == 1 {
+			font, err := parseFont(family.name, d.bytes())
+			if err != nil {
+				return nil, fmt.Errorf("bad recording: font %s: %w", family.name, err)
+			}
+			font.Use(family.options)
+			family.fonts[style] = font
+		} else if added, ok := r.families[family.name]; ok && added.fonts[style] != nil {
+			family.fonts[style] = added.fonts[style]
+		} else if d.err == nil {
+			return nil, fmt.Errorf("bad recording: font family %s with style %d must be added or embedded", family.name, style)
+		}
+	}
+	return family, d.err
+}
+
+////////////////////////////////////////////////////////////////
+
+// recordWriter encodes values of a recording, and keeps the first error of values that cannot be encoded.
+type recordWriter struct {
+	bytes.Buffer
+	families map[*FontFamily]int
+	err      error
+}
+
+func (w *recordWriter) record(typ byte, b []byte) {
+	w.WriteByte(typ)
+	w.uvarint(uint64(len(b)))
+	w.Write(b)
+}
+
+func (w *recordWriter) uvarint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func (w *recordWriter) float(f float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	w.Write(b[:])
+}
+
+func (w *recordWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.Write(b)
+}
+
+func (w *recordWriter) str(s string) {
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *recordWriter) color(c color.RGBA) {
+	w.Write([]byte{c.R, c.G, c.B, c.A})
+}
+
+func (w *recordWriter) cmyk(c *color.CMYK) {
+	if c == nil {
+		w.WriteByte(0)
+		return
+	}
+	w.Write([]byte{1, c.C, c.M, c.Y, c.K})
+}
+
+func (w *recordWriter) spot(c *SpotColor) {
+	if c == nil {
+		w.WriteByte(0)
+		return
+	}
+	w.WriteByte(1)
+	w.str(c.Name)
+	w.float(c.Tint)
+	if cmyk, ok := c.Alternate.(color.CMYK); ok {
+		w.cmyk(&cmyk)
+	} else {
+		w.WriteByte(0)
+		r, g, b, a := c.Alternate.RGBA()
+		w.color(color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)})
+	}
+}
+
+func (w *recordWriter) matrix(m Matrix) {
+	for _, row := range m {
+		for _, v := range row {
+			w.float(v)
+		}
+	}
+}
+
+func (w *recordWriter) path(p *Path) {
+	w.uvarint(uint64(len(p.d)))
+	for _, v := range p.d {
+		w.float(v)
+	}
+}
+
+func (w *recordWriter) style(style Style) {
+	w.color(style.FillColor)
+	w.color(style.StrokeColor)
+	w.cmyk(style.FillCMYK)
+	w.cmyk(style.StrokeCMYK)
+	w.spot(style.FillSpot)
+	w.spot(style.StrokeSpot)
+	w.float(style.StrokeWidth)
+	switch style.StrokeCapper.(type) {
+	case ButtCapper:
+		w.WriteByte(0)
+	case RoundCapper:
+		w.WriteByte(1)
+	case SquareCapper:
+		w.WriteByte(2)
+	default:
+		w.err = fmt.Errorf("unsupported capper %T", style.StrokeCapper)
+	}
+	w.joiner(style.StrokeJoiner)
+	w.float(style.DashOffset)
+	w.uvarint(uint64(len(style.Dashes)))
+	for _, dash := range style.Dashes {
+		w.float(dash)
+	}
+	w.uvarint(uint64(style.FillRule))
+	w.float(style.Shadow.DX)
+	w.float(style.Shadow.DY)
+	w.float(style.Shadow.Sigma)
+	w.color(style.Shadow.Color)
+	w.paint(style.FillPaint)
+	w.paint(style.StrokePaint)
+}
+
+func (w *recordWriter) paint(paint Paint) {
+	switch p := paint.(type) {
+	case nil:
+		w.WriteByte(0)
+	case LinearGradient:
+		w.WriteByte(1)
+		w.float(p.X0)
+		w.float(p.Y0)
+		w.float(p.X1)
+		w.float(p.Y1)
+		w.stops(p.Stops, p.Spread)
+	case RadialGradient:
+		w.WriteByte(2)
+		w.float(p.CX)
+		w.float(p.CY)
+		w.float(p.R)
+		w.float(p.FX)
+		w.float(p.FY)
+		w.stops(p.Stops, p.Spread)
+	case AlongPathGradient:
+		w.WriteByte(3)
+		w.stops(p.Stops, PadSpread)
+	case ImagePattern:
+		w.WriteByte(4)
+		b := &bytes.Buffer{}
+		if err := png.Encode(b, p.Img); err != nil {
+			w.err = err
+			return
+		}
+		w.bytes(b.Bytes())
+		w.matrix(p.M)
+		w.uvarint(uint64(p.Repeat))
+	case PathPattern:
+		w.WriteByte(5)
+		if p.Cell == nil {
+			p.Cell = &Path{}
+		}
+		w.path(p.Cell)
+		w.float(p.CellW)
+		w.float(p.CellH)
+		w.style(p.Style)
+		w.matrix(p.M)
+	default:
+		w.err = fmt.Errorf("unsupported paint %T", paint)
+	}
+}
+
+func (w *recordWriter) stops(stops []Stop, spread Spread) {
+	w.uvarint(uint64(spread))
+	w.uvarint(uint64(len(stops)))
+	for _, stop := range stops {
+		w.float(stop.Offset)
+		w.color(stop.Color)
+	}
+}
+
+func (w *recordWriter) joiner(joiner Joiner) {
+	switch j := joiner.(type) {
+	case BevelJoiner:
+		w.WriteByte(0)
+	case RoundJoiner:
+		w.WriteByte(1)
+	case MiterJoiner:
+		w.WriteByte(2)
+		w.joiner(j.GapJoiner)
+		w.float(j.Limit)
+	case ArcsJoiner:
+		w.WriteByte(3)
+		w.joiner(j.GapJoiner)
+		w.float(j.Limit)
+	default:
+		w.err = fmt.Errorf("unsupported joiner %T", joiner)
+	}
+}
+
+func (w *recordWriter) face(ff FontFace) {
+	index, ok := w.families[ff.family]
+	if !ok {
+		w.err = fmt.Errorf("font face without font family")
+		return
+	}
+	w.uvarint(uint64(index))
+	fontStyle := -1
+	for style, font := range ff.family.fonts {
+		if font == ff.Font {
+			fontStyle = int(style)
+		}
+	}
+	if fontStyle == -1 {
+		w.err = fmt.Errorf("font %s not in its font family", ff.Font.name)
+		return
+	}
+	w.uvarint(uint64(fontStyle))
+	w.float(ff.Size)
+	w.uvarint(uint64(ff.Style))
+	w.uvarint(uint64(ff.Variant))
+	w.color(ff.Color)
+	w.uvarint(uint64(len(ff.deco)))
+	for _, deco := range ff.deco {
+		i := 0
+		for i < len(recordDecorators) && recordDecorators[i] != deco {
+			i++
+		}
+		if i == len(recordDecorators) {
+			w.err = fmt.Errorf("unsupported font decorator %T", deco)
+		}
+		w.uvarint(uint64(i))
+	}
+	w.float(ff.Scale)
+	w.float(ff.Voffset)
+	w.float(ff.FauxBold)
+	w.float(ff.FauxItalic)
+}
+
+func (w *recordWriter) text(text *Text) {
+	w.uvarint(uint64(len(text.lines)))
+	for _, line := range text.lines {
+		w.float(line.y)
+		w.uvarint(uint64(len(line.spans)))
+		for _, span := range line.spans {
+			w.face(span.Face)
+			w.str(span.Text)
+			w.float(span.width)
+			w.uvarint(uint64(len(span.boundaries)))
+			for _, boundary := range span.boundaries {
+				w.uvarint(uint64(boundary.kind))
+				w.uvarint(uint64(boundary.pos))
+				w.uvarint(uint64(boundary.size))
+			}
+			w.float(span.dx)
+			w.float(span.SentenceSpacing)
+			w.float(span.WordSpacing)
+			w.float(span.GlyphSpacing)
+		}
+		w.uvarint(uint64(len(line.decos)))
+		for _, deco := range line.decos {
+			w.face(deco.face)
+			w.float(deco.x0)
+			w.float(deco.x1)
+		}
+	}
+}
+
+// recordReader decodes values of a recording. After an error it returns zero values, and the error is kept.
+type recordReader struct {
+	b        []byte
+	families []*FontFamily
+	err      error
+}
+
+// next returns the next n bytes.
+func (d *recordReader) next(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	} else if uint64(len(d.b)) < n {
+		d.err = fmt.Errorf("bad recording: unexpected end")
+		d.b = nil
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *recordReader) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *recordReader) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(d.b)
+	if size <= 0 {
+		d.err = fmt.Errorf("bad recording: invalid number")
+		return 0
+	}
+	d.b = d.b[size:]
+	return n
+}
+
+// length returns a number of elements that are at least size bytes each, which must fit in the remaining data.
+func (d *recordReader) length(size int) int {
+	n := d.uvarint()
+	if uint64(len(d.b))/uint64(size) < n {
+		d.err = fmt.Errorf("bad recording: unexpected end")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *recordReader) float() float64 {
+	if b := d.next(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0.0
+}
+
+func (d *recordReader) bytes() []byte {
+	return d.next(d.uvarint())
+}
+
+func (d *recordReader) str() string {
+	return string(d.bytes())
+}
+
+func (d *recordReader) color() color.RGBA {
+	if b := d.next(4); b != nil {
+		return color.RGBA{b[0], b[1], b[2], b[3]}
+	}
+	return color.RGBA{}
+}
+
+func (d *recordReader) cmyk() *color.CMYK {
+	if d.byte() != 1 {
+		return nil
+	}
+	if b := d.next(4); b != nil {
+		return &color.CMYK{b[0], b[1], b[2], b[3]}
+	}
+	return nil
+}
+
+func (d *recordReader) spot() *SpotColor {
+	if d.byte() != 1 {
+		return nil
+	}
+	spot := &SpotColor{Name: d.str(), Tint: d.float()}
+	if cmyk := d.cmyk(); cmyk != nil {
+		spot.Alternate = *cmyk
+	} else {
+		spot.Alternate = d.color()
+	}
+	return spot
+}
+
+func (d *recordReader) matrix() Matrix {
+	m := Matrix{}
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] = d.float()
+		}
+	}
+	return m
+}
+
+func (d *recordReader) path() *Path {
+	p := &Path{make([]float64, d.length(8))}
+	for i := range p.d {
+		p.d[i] = d.float()
+	}
+	return p
+}
+
+func (d *recordReader) style() Style {
+	style := Style{}
+	style.FillColor = d.color()
+	style.StrokeColor = d.color()
+	style.FillCMYK = d.cmyk()
+	style.StrokeCMYK = d.cmyk()
+	style.FillSpot = d.spot()
+	style.StrokeSpot = d.spot()
+	style.StrokeWidth = d.float()
+	switch d.byte() {
+	case 0:
+		style.StrokeCapper = ButtCap
+	case 1:
+		style.StrokeCapper = RoundCap
+	case 2:
+		style.StrokeCapper = SquareCap
+	default:
+		d.fail("capper")
+	}
+	style.StrokeJoiner = d.joiner()
+	style.DashOffset = d.float()
+	style.Dashes = make([]float64, d.length(8))
+	for i := range style.Dashes {
+		style.Dashes[i] = d.float()
+	}
+	style.FillRule = FillRule(d.uvarint())
+	style.Shadow = Shadow{d.float(), d.float(), d.float(), d.color()}
+	style.FillPaint = d.paint()
+	style.StrokePaint = d.paint()
+	return style
+}
+
+func (d *recordReader) paint() Paint {
+	switch d.byte() {
+	case 0:
+		return nil
+	case 1:
+		g := LinearGradient{X0: d.float(), Y0: d.float(), X1: d.float(), Y1: d.float()}
+		g.Stops, g.Spread = d.stops()
+		return g
+	case 2:
+		g := RadialGradient{CX: d.float(), CY: d.float(), R: d.float(), FX: d.float(), FY: d.float()}
+		g.Stops, g.Spread = d.stops()
+		return g
+	case 3:
+		g := AlongPathGradient{}
+		g.Stops, _ = d.stops()
+		return g
+	case 4:
+		img, err := png.Decode(bytes.NewReader(d.bytes()))
+		if err != nil && d.err == nil {
+			d.err = fmt.Errorf("bad recording: %w", err)
+		}
+		return ImagePattern{img, d.matrix(), RepeatMode(d.uvarint())}
+	case 5:
+		return PathPattern{d.path(), d.float(), d.float(), d.style(), d.matrix()}
+	}
+	d.fail("paint")
+	return nil
+}
+
+func (d *recordReader) stops() ([]Stop, Spread) {
+	spread := Spread(d.uvarint())
+	stops := make([]Stop, d.length(12))
+	for i := range stops {
+		stops[i] = Stop{d.float(), d.color()}
+	}
+	return stops, spread
+}
+
+func (d *recordReader) joiner() Joiner {
+	switch d.byte() {
+	case 0:
+		return BevelJoin
+	case 1:
+		return RoundJoin
+	case 2:
+		return MiterJoiner{d.joiner(), d.float()}
+	case 3:
+		return ArcsJoiner{d.joiner(), d.float()}
+	}
+	d.fail("joiner")
+	return BevelJoin
+}
+
+func (d *recordReader) fail(what string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("bad recording: invalid %s", what)
+	}
+}
+
+func (d *recordReader) face() FontFace {
+	ff := FontFace{}
+	index := d.uvarint()
+	if uint64(len(d.families)) <= index {
+		d.fail("font family")
+		return ff
+	}
+	ff.family = d.families[index]
+	ff.Font = ff.family.fonts[FontStyle(d.uvarint())]
+	if ff.Font == nil {
+		d.fail("font")
+		return ff
+	}
+	ff.Size = d.float()
+	ff.Style = FontStyle(d.uvarint())
+	ff.Variant = FontVariant(d.uvarint())
+	ff.Color = d.color()
+	n := d.length(1)
+	for i := 0; i < n; i++ {
+		if deco := d.uvarint(); deco < uint64(len(recordDecorators)) {
+			ff.deco = append(ff.deco, recordDecorators[deco])
+		} else {
+			d.fail("font decorator")
+		}
+	}
+	ff.Scale = d.float()
+	ff.Voffset = d.float()
+	ff.FauxBold = d.float()
+	ff.FauxItalic = d.float()
+	return ff
+}
+
+func (d *recordReader) text() *Text {
+	text := &Text{[]line{}, map[*Font]bool{}}
+	nLines := d.length(1)
+	for i := 0; i < nLines && d.err == nil; i++ {
+		l := line{y: d.float()}
+		nSpans := d.length(1)
+		for j := 0; j < nSpans && d.err == nil; j++ {
+			span := TextSpan{Face: d.face(), Text: d.str(), width: d.float()}
+			nBoundaries := d.length(3)
+			for k := 0; k < nBoundaries; k++ {
+				span.boundaries = append(span.boundaries, textBoundary{textBoundaryKind(d.uvarint()), int(d.uvarint()), int(d.uvarint())})
+			}
+			span.dx = d.float()
+			span.SentenceSpacing = d.float()
+			span.WordSpacing = d.float()
+			span.GlyphSpacing = d.float()
+			l.spans = append(l.spans, span)
+			text.fonts[span.Face.Font] = true
+		}
+		nDecos := d.length(1)
+		for j := 0; j < nDecos && d.err == nil; j++ {
+			l.decos = append(l.decos, decoSpan{d.face(), d.float(), d.float()})
+		}
+		text.lines = append(text.lines, l)
+	}
+	return text
+}
@@ -0,0 +1,101 @@
+package canvas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+type customJoiner struct{}
+
+func (customJoiner) Join(rhs, lhs *Path, halfWidth float64, pivot, n0, n1 Point, r0, r1 float64) {
+}
+
+func TestRecorderBinary(t *testing.T) {
+	rec := NewRecorder(100.0, 50.0)
+	ctx := NewContext(rec)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetDashes(1.0, 2.0, 3.0)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	rec.SetBlendMode(BlendMultiply)
+	ctx.StartBlur(1.5)
+	ctx.EndBlur()
+	rec.SetBackground(Yellow)
+
+	b, err := rec.MarshalBinary()
+	test.Error(t, err)
+	rec2 := &Recorder{}
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, rec2.W, 100.0)
+	test.T(t, len(rec2.layers), 4)
+	test.T(t, rec2.layers[0].path, rec.layers[0].path)
+	test.T(t, rec2.layers[0].m, rec.layers[0].m)
+	test.T(t, rec2.layers[0].style.Dashes, []float64{2.0, 3.0})
+	test.T(t, rec2.layers[0].style.StrokeJoiner, MiterJoin)
+	test.T(t, *rec2.layers[1].blend, BlendMultiply)
+	test.T(t, *rec2.layers[2].blur, 1.5)
+	test.T(t, rec2.background.FillColor, Yellow)
+
+	// records of unknown types are skipped
+	b2 := append(append([]byte{}, b...), 200, 3, 1, 2, 3, groupEndRecord, 0)
+	test.Error(t, rec2.UnmarshalBinary(b2))
+	test.T(t, len(rec2.layers), 5)
+	test.That(t, rec2.layers[4].groupEnd)
+
+	test.That(t, rec2.UnmarshalBinary(b[:len(b)-5]) != nil, "truncated recording must return an error")
+	test.That(t, rec2.UnmarshalBinary([]byte("PNG")) != nil, "bad header must return an error")
+	b[len(recordMagic)] = 2
+	test.That(t, rec2.UnmarshalBinary(b) != nil, "unsupported version must return an error")
+
+	rec3 := NewRecorder(100.0, 50.0)
+	NewContext(rec3).Erase(10.0, 20.0, Circle(2.0))
+	b, err = rec3.MarshalBinary()
+	test.Error(t, err)
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, len(rec2.layers), 1)
+	test.T(t, rec2.layers[0].erase, rec3.layers[0].erase)
+	test.T(t, rec2.layers[0].m, Identity.Translate(10.0, 20.0))
+
+	ctx.SetStrokeJoiner(customJoiner{})
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	_, err = rec.MarshalBinary()
+	test.That(t, err != nil, "custom joiner must return an error")
+}
+
+func TestRecorderBinaryPaint(t *testing.T) {
+	g, err := NewLinearGradient(0.0, 0.0, 10.0, 5.0, ReflectSpread, Stop{0.0, Red}, Stop{1.0, Blue})
+	test.Error(t, err)
+
+	rec := NewRecorder(100.0, 50.0)
+	ctx := NewContext(rec)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+
+	b, err := rec.MarshalBinary()
+	test.Error(t, err)
+	rec2 := &Recorder{}
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, rec2.layers[0].style.FillPaint, Paint(g))
+
+	rg, err := NewRadialGradient(5.0, 5.0, 5.0, 4.0, 3.0, RepeatSpread, Stop{0.0, Red}, Stop{1.0, Blue})
+	test.Error(t, err)
+	ctx.SetFillPaint(rg)
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	b, err = rec.MarshalBinary()
+	test.Error(t, err)
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, rec2.layers[1].style.FillPaint, Paint(rg))
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(1, 0, Red)
+	ctx.SetFillPaint(ImagePattern{img, Identity.Scale(2.0, 2.0), RepeatY})
+	ctx.DrawPath(10.0, 20.0, Rectangle(5.0, 5.0))
+	b, err = rec.MarshalBinary()
+	test.Error(t, err)
+	test.Error(t, rec2.UnmarshalBinary(b))
+	pattern := rec2.layers[2].style.FillPaint.(ImagePattern)
+	test.T(t, pattern.M, Identity.Scale(2.0, 2.0))
+	test.T(t, pattern.Repeat, RepeatY)
+	test.T(t, pattern.At(3.0, 1.0), Red)
+}
@@ -47,6 +47,91 @@ func RoundedRectangle(w, h, r float64) *Path {
 	return p
 }
 
+// RoundedRect returns a rectangle at (x,y) with width w and height h with rounded corners of radius r. The radius is clamped when it exceeds half the width or height, and a zero radius returns a plain rectangle.
+func RoundedRect(x, y, w, h, r float64) *Path {
+	return RoundedRectCorners(x, y, w, h, r, r, r, r)
+}
+
+// RoundedRectCorners returns a rectangle at (x,y) with width w and height h with rounded corners of radii tl, tr, br, and bl for the top-left, top-right, bottom-right, and bottom-left corner respectively, similar to CSS's border-radius. When adjacent radii together exceed the side length, all radii are scaled down by the same factor as CSS does.
+func RoundedRectCorners(x, y, w, h, tl, tr, br, bl float64) *Path {
+	rs, ok := rectCornerRadii(w, h, [4]float64{tl, tr, br, bl})
+	if !ok {
+		return &Path{}
+	} else if rs == [4]float64{} {
+		return Rectangle(w, h).Translate(x, y)
+	}
+	tl, tr, br, bl = rs[0], rs[1], rs[2], rs[3]
+
+	p := &Path{}
+	p.MoveTo(x+bl, y)
+	p.LineTo(x+w-br, y)
+	p.ArcTo(br, br, 0.0, false, true, x+w, y+br)
+	p.LineTo(x+w, y+h-tr)
+	p.ArcTo(tr, tr, 0.0, false, true, x+w-tr, y+h)
+	p.LineTo(x+tl, y+h)
+	p.ArcTo(tl, tl, 0.0, false, true, x, y+h-tl)
+	p.LineTo(x, y+bl)
+	p.ArcTo(bl, bl, 0.0, false, true, x+bl, y)
+	p.Close()
+	return p
+}
+
+// BeveledRect returns a rectangle at (x,y) with width w and height h with its corners cut off at 45 degrees at distance cut from the corner. The cut is clamped to half the smaller dimension, and a zero cut returns a plain rectangle.
+func BeveledRect(x, y, w, h, cut float64) *Path {
+	rs, ok := rectCornerRadii(w, h, [4]float64{cut, cut, cut, cut})
+	if !ok {
+		return &Path{}
+	} else if rs == [4]float64{} {
+		return Rectangle(w, h).Translate(x, y)
+	}
+	cut = rs[0]
+
+	p := &Path{}
+	p.MoveTo(x+cut, y)
+	p.LineTo(x+w-cut, y)
+	p.LineTo(x+w, y+cut)
+	p.LineTo(x+w, y+h-cut)
+	p.LineTo(x+w-cut, y+h)
+	p.LineTo(x+cut, y+h)
+	p.LineTo(x, y+h-cut)
+	p.LineTo(x, y+cut)
+	p.Close()
+	return p
+}
+
+// rectCornerRadii validates the corner sizes (radii or cuts) for a rectangle of width w and height h. Negative sizes are set to zero and sizes are scaled down uniformly when adjacent corners would overlap. It returns false if the rectangle is empty.
+func rectCornerRadii(w, h float64, rs [4]float64) ([4]float64, bool) {
+	if w < Epsilon || h < Epsilon {
+		return rs, false
+	}
+
+	for i := range rs {
+		if rs[i] < Epsilon {
+			rs[i] = 0.0
+		}
+	}
+
+	f := 1.0
+	if sum := rs[0] + rs[1]; w < sum {
+		f = math.Min(f, w/sum)
+	}
+	if sum := rs[2] + rs[3]; w < sum {
+		f = math.Min(f, w/sum)
+	}
+	if sum := rs[0] + rs[3]; h < sum {
+		f = math.Min(f, h/sum)
+	}
+	if sum := rs[1] + rs[2]; h < sum {
+		f = math.Min(f, h/sum)
+	}
+	if f < 1.0 {
+		for i := range rs {
+			rs[i] *= f
+		}
+	}
+	return rs, true
+}
+
 // BeveledRectangle returns a rectangle with width w and height h with beveled corners at distance r from the corner.
 func BeveledRectangle(w, h, r float64) *Path {
 	if Equal(w, 0.0) || Equal(h, 0.0) {
@@ -72,21 +157,149 @@ func BeveledRectangle(w, h, r float64) *Path {
 	return p
 }
 
-// Circle returns a circle with radius r.
+// Circle returns a circle with radius r in counter clockwise direction.
 func Circle(r float64) *Path {
 	return Ellipse(r, r)
 }
 
-// Ellipse returns an ellipse with radii rx,ry.
+// CircleCW returns a circle with radius r in clockwise direction, which can be used to cut out holes using the NonZero fill rule.
+func CircleCW(r float64) *Path {
+	return EllipseCW(r, r)
+}
+
+// Ellipse returns an ellipse with radii rx,ry in counter clockwise direction.
 func Ellipse(rx, ry float64) *Path {
+	return ellipse(rx, ry, true)
+}
+
+// EllipseCW returns an ellipse with radii rx,ry in clockwise direction, which can be used to cut out holes using the NonZero fill rule.
+func EllipseCW(rx, ry float64) *Path {
+	return ellipse(rx, ry, false)
+}
+
+func ellipse(rx, ry float64, sweep bool) *Path {
 	if Equal(rx, 0.0) || Equal(ry, 0.0) {
 		return &Path{}
 	}
 
 	p := &Path{}
 	p.MoveTo(rx, 0.0)
-	p.ArcTo(rx, ry, 0.0, false, true, -rx, 0.0)
-	p.ArcTo(rx, ry, 0.0, false, true, rx, 0.0)
+	p.ArcTo(rx, ry, 0.0, false, sweep, -rx, 0.0)
+	p.ArcTo(rx, ry, 0.0, false, sweep, rx, 0.0)
+	p.Close()
+	return p
+}
+
+// OpenArc returns a circular arc centered at (cx,cy) with radius r, running counter clockwise from angle startDeg to endDeg in degrees. The path is not closed. Spans that cross the 0/360 degree boundary are supported, and spans of more than 360 degrees are clamped to a full circle.
+func OpenArc(cx, cy, r, startDeg, endDeg float64) *Path {
+	span := arcSpan(startDeg, endDeg)
+	if r < Epsilon || Equal(span, 0.0) {
+		return &Path{}
+	}
+
+	sintheta, costheta := math.Sincos(startDeg * math.Pi / 180.0)
+	p := &Path{}
+	p.MoveTo(cx+r*costheta, cy+r*sintheta)
+	p.Arc(r, r, 0.0, startDeg, startDeg+span)
+	return p
+}
+
+// Chord returns a circular arc centered at (cx,cy) with radius r, running counter clockwise from angle startDeg to endDeg in degrees, that is closed by a straight line between its end points. See OpenArc for how the angles are interpreted.
+func Chord(cx, cy, r, startDeg, endDeg float64) *Path {
+	p := OpenArc(cx, cy, r, startDeg, endDeg)
+	p.Close()
+	return p
+}
+
+// Pie returns a circular sector (wedge) centered at (cx,cy) with radius r, running counter clockwise from angle startDeg to endDeg in degrees, and with straight lines from its end points to the center. See OpenArc for how the angles are interpreted. A span of 360 degrees or more returns a circle.
+func Pie(cx, cy, r, startDeg, endDeg float64) *Path {
+	p := OpenArc(cx, cy, r, startDeg, endDeg)
+	if !p.Empty() && arcSpan(startDeg, endDeg) < 360.0 {
+		p.LineTo(cx, cy)
+	}
+	p.Close()
+	return p
+}
+
+// arcSpan returns the counter clockwise span in degrees from startDeg to endDeg, within [0,360].
+func arcSpan(startDeg, endDeg float64) float64 {
+	span := endDeg - startDeg
+	if 360.0 <= span {
+		return 360.0
+	}
+	span = math.Mod(span, 360.0)
+	if span < 0.0 {
+		span += 360.0
+	}
+	return span
+}
+
+// Spiral returns an Archimedean spiral centered at (cx,cy) with radius a+b*theta for theta in radians, which runs counter clockwise for the given number of turns starting at angle zero. The curve is approximated by cubic Béziers with a maximum deviation of tolerance. The path is not closed.
+func Spiral(cx, cy, a, b, turns, tolerance float64) *Path {
+	if turns <= 0.0 || Equal(a, 0.0) && Equal(b, 0.0) {
+		return &Path{}
+	}
+	return spiral(cx, cy, turns, tolerance, func(theta float64) float64 {
+		return a + b*theta
+	})
+}
+
+// LogSpiral returns a logarithmic spiral centered at (cx,cy) with radius a*exp(b*theta) for theta in radians, which runs counter clockwise for the given number of turns starting at angle zero. The spiral grows outwards for positive b and inwards for negative b. The curve is approximated by cubic Béziers with a maximum deviation of tolerance. The path is not closed.
+func LogSpiral(cx, cy, a, b, turns, tolerance float64) *Path {
+	if turns <= 0.0 || Equal(a, 0.0) {
+		return &Path{}
+	}
+	return spiral(cx, cy, turns, tolerance, func(theta float64) float64 {
+		return a * math.Exp(b*theta)
+	})
+}
+
+// spiral returns a spiral centered at (cx,cy) with radius r(theta), see Spiral.
+func spiral(cx, cy, turns, tolerance float64, r func(float64) float64) *Path {
+	if tolerance <= 0.0 {
+		tolerance = Tolerance
+	}
+
+	f := func(theta float64) Point {
+		sintheta, costheta := math.Sincos(theta)
+		r := r(theta)
+		return Point{cx + r*costheta, cy + r*sintheta}
+	}
+
+	n := int(math.Ceil(4.0 * turns)) // start with at most quarter turns
+	dtheta := 2.0 * math.Pi * turns / float64(n)
+
+	p := &Path{}
+	start := f(0.0)
+	p.MoveTo(start.X, start.Y)
+	for i := 0; i < n; i++ {
+		for _, bezier := range parametricToCubicBeziers(f, float64(i)*dtheta, float64(i+1)*dtheta, tolerance) {
+			p.CubeTo(bezier[1].X, bezier[1].Y, bezier[2].X, bezier[2].Y, bezier[3].X, bezier[3].Y)
+		}
+	}
+	return p
+}
+
+// Superellipse returns a superellipse (or Lamé curve) centered at (cx,cy) with radii rx and ry, that satisfies |x/rx|^n + |y/ry|^n = 1. For n=2 it is an ellipse, for n=4 a squircle, and for n=1 a rhombus. The curve is approximated by cubic Béziers with a maximum deviation of Tolerance. n must be positive.
+func Superellipse(cx, cy, rx, ry, n float64) *Path {
+	if rx < Epsilon || ry < Epsilon || n <= 0.0 {
+		return &Path{}
+	}
+
+	f := func(theta float64) Point {
+		sintheta, costheta := math.Sincos(theta)
+		x := math.Copysign(math.Pow(math.Abs(costheta), 2.0/n), costheta)
+		y := math.Copysign(math.Pow(math.Abs(sintheta), 2.0/n), sintheta)
+		return Point{cx + rx*x, cy + ry*y}
+	}
+
+	p := &Path{}
+	p.MoveTo(cx+rx, cy)
+	for i := 0; i < 4; i++ {
+		for _, bezier := range parametricToCubicBeziers(f, float64(i)*math.Pi/2.0, float64(i+1)*math.Pi/2.0, Tolerance) {
+			p.CubeTo(bezier[1].X, bezier[1].Y, bezier[2].X, bezier[2].Y, bezier[3].X, bezier[3].Y)
+		}
+	}
 	p.Close()
 	return p
 }
@@ -127,7 +340,11 @@ func StarPolygon(n int, R, r float64, up bool) *Path {
 	if n < 3 || Equal(R, 0.0) || Equal(r, 0.0) {
 		return &Path{}
 	}
+	return starPolygon(n, R, r, up)
+}
 
+// starPolygon returns a star polygon of n points with alternating radius R and r without validating the arguments, see StarPolygon.
+func starPolygon(n int, R, r float64, up bool) *Path {
 	n *= 2
 	dtheta := 2.0 * math.Pi / float64(n)
 	theta0 := 0.5 * math.Pi
@@ -151,4 +368,54 @@ func StarPolygon(n int, R, r float64, up bool) *Path {
 	return p
 }
 
-// TODO: Grid
+// Star returns a star with n points centered at (cx,cy), with the points at radius rOuter and the vertices between the points at radius rInner. The first point is pointing north and the star is rotated by rot in degrees counter clockwise. It is the StarPolygon moved into place, so that unlike RegularStarPolygon the edges do not intersect and it fills the same for both fill rules. n must be 2 or more and 0 < rInner < rOuter.
+func Star(n int, cx, cy, rOuter, rInner, rot float64) *Path {
+	if n < 2 || rInner <= 0.0 || rOuter <= rInner {
+		return &Path{}
+	}
+	return starPolygon(n, rOuter, rInner, true).Transform(Identity.Translate(cx, cy).Rotate(rot))
+}
+
+// Grid returns the interior lines of a grid of nx by ny cells inside the rectangle at (x,y) with width w and height h. The outer border is not included, so that nx or ny equal to one produces no lines in that direction. The returned path consists of separate lines and is meant to be stroked.
+func Grid(x, y, w, h float64, nx, ny int) *Path {
+	p := &Path{}
+	if w < Epsilon || h < Epsilon || nx < 1 || ny < 1 {
+		return p
+	}
+
+	for i := 1; i < nx; i++ {
+		xi := x + w*float64(i)/float64(nx)
+		p.MoveTo(xi, y)
+		p.LineTo(xi, y+h)
+	}
+	for j := 1; j < ny; j++ {
+		yj := y + h*float64(j)/float64(ny)
+		p.MoveTo(x, yj)
+		p.LineTo(x+w, yj)
+	}
+	return p
+}
+
+// Ticks returns tick marks of the given length at every step from x0 to x1 along the horizontal line at y when alongX is true, or at every step from x0 to x1 along the vertical line at x=y when alongX is false. Ticks point up or right for a positive length and down or left for a negative length. When x1 is (nearly) a multiple of step away from x0, the last tick lands exactly at x1. The returned path consists of separate lines and is meant to be stroked.
+func Ticks(alongX bool, x0, x1, y, step, length float64) *Path {
+	p := &Path{}
+	if step <= 0.0 || x1 < x0 || Equal(length, 0.0) {
+		return p
+	}
+
+	n := int(math.Floor((x1-x0)/step + Epsilon))
+	for i := 0; i <= n; i++ {
+		x := x0 + float64(i)*step
+		if i == n && Equal(x, x1) {
+			x = x1
+		}
+		if alongX {
+			p.MoveTo(x, y)
+			p.LineTo(x, y+length)
+		} else {
+			p.MoveTo(y, x)
+			p.LineTo(y+length, x)
+		}
+	}
+	return p
+}
@@ -1,6 +1,7 @@
 package canvas
 
 import (
+	"math"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -19,6 +20,10 @@ func TestShapes(t *testing.T) {
 	test.T(t, BeveledRectangle(5.0, 10.0, 2.0), MustParseSVG("M0 2 2 0 3 0 5 2 5 8 3 10 2 10 0 8z"))
 	test.T(t, Circle(0.0), &Path{})
 	test.T(t, Circle(2.0), MustParseSVG("M2 0A2 2 0 0 1 -2 0A2 2 0 0 1 2 0z"))
+	test.T(t, CircleCW(0.0), &Path{})
+	test.T(t, CircleCW(2.0), MustParseSVG("M2 0A2 2 0 0 0 -2 0A2 2 0 0 0 2 0z"))
+	test.T(t, Ellipse(3.0, 2.0), MustParseSVG("M3 0A3 2 0 0 1 -3 0A3 2 0 0 1 3 0z"))
+	test.T(t, EllipseCW(3.0, 2.0), MustParseSVG("M3 0A3 2 0 0 0 -3 0A3 2 0 0 0 3 0z"))
 	test.T(t, RegularPolygon(2, 2.0, true), &Path{})
 	test.T(t, RegularPolygon(4, 0.0, true), &Path{})
 	test.T(t, RegularPolygon(4, 2.0, true), MustParseSVG("M0 2 -2 0 0 -2 2 0z"))
@@ -27,3 +32,247 @@ func TestShapes(t *testing.T) {
 	test.T(t, StarPolygon(4, 4.0, 2.0, true), MustParseSVG("M0 4 -1.41 1.41 -4 0 -1.41 -1.41 0 -4 1.41 -1.41 4 0 1.41 1.41z"))
 	test.T(t, StarPolygon(3, 4.0, 2.0, false), MustParseSVG("M-3.4641 2L-1.7321 -1L0 -4L1.7321 -1L3.4641 2L0 2z"))
 }
+
+func TestCircleHole(t *testing.T) {
+	p := Rectangle(10.0, 10.0).Translate(-5.0, -5.0)
+	test.That(t, p.CCW())
+	test.That(t, !CircleCW(2.0).CCW())
+
+	p = p.Append(CircleCW(2.0))
+	test.That(t, !p.Interior(0.0, 0.0, NonZero), "hole under nonzero")
+	test.That(t, p.Interior(3.0, 3.0, NonZero))
+	test.That(t, p.Append(Circle(2.0)).Interior(0.0, 0.0, NonZero))
+}
+
+func TestStar(t *testing.T) {
+	test.T(t, Star(1, 0.0, 0.0, 4.0, 2.0, 0.0), &Path{})
+	test.T(t, Star(5, 0.0, 0.0, 2.0, 4.0, 0.0), &Path{})
+	test.T(t, Star(5, 0.0, 0.0, 4.0, 0.0, 0.0), &Path{})
+
+	p := Star(5, 1.0, 2.0, 4.0, 2.0, 0.0)
+	coords := p.Coords()
+	test.T(t, len(coords), 11)
+	test.T(t, coords[10], coords[0])
+	for i, coord := range coords[:10] {
+		r := 4.0
+		if i%2 == 1 {
+			r = 2.0
+		}
+		theta := math.Pi/2.0 + float64(i)*math.Pi/5.0
+		test.T(t, coord, Point{1.0 + r*math.Cos(theta), 2.0 + r*math.Sin(theta)})
+	}
+	test.That(t, p.CCW(), "star must have positive area")
+	test.That(t, p.Interior(1.0, 2.0, NonZero))
+	test.That(t, p.Interior(1.0, 2.0, EvenOdd))
+
+	p = Star(5, 1.0, 2.0, 4.0, 2.0, 90.0)
+	test.T(t, p.StartPos(), Point{-3.0, 2.0})
+	test.T(t, p, StarPolygon(5, 4.0, 2.0, true).Transform(Identity.Rotate(90.0)).Translate(1.0, 2.0))
+
+	// a star with two points has four vertices
+	p = Star(2, 1.0, 2.0, 4.0, 1.0, 0.0)
+	test.T(t, p, MustParseSVG("M1 6L0 2L1 -2L2 2z"))
+	test.That(t, p.CCW())
+}
+
+// pathArea returns the signed area enclosed by the closed subpaths of p, positive for counter clockwise subpaths.
+func pathArea(p *Path) float64 {
+	area := 0.0
+	line := func(p0, p1 Point) {
+		area += p0.PerpDot(p1) / 2.0
+	}
+	cube := func(p0, p1, p2, p3 Point) {
+		area += gaussLegendre5(func(t float64) float64 {
+			return cubicBezierPos(p0, p1, p2, p3, t).PerpDot(cubicBezierDeriv(p0, p1, p2, p3, t)) / 2.0
+		}, 0.0, 1.0)
+	}
+	arc := func(p0 Point, rx, ry, rot float64, large, sweep bool, p1 Point) {
+		phi := rot * math.Pi / 180.0
+		cx, cy, theta0, theta1 := ellipseToCenter(p0.X, p0.Y, rx, ry, phi, large, sweep, p1.X, p1.Y)
+		n := math.Ceil(math.Abs(theta1-theta0) / (math.Pi / 8.0))
+		for i := 0.0; i < n; i++ {
+			a, b := theta0+i/n*(theta1-theta0), theta0+(i+1.0)/n*(theta1-theta0)
+			area += gaussLegendre7(func(theta float64) float64 {
+				return ellipsePos(rx, ry, phi, cx, cy, theta).PerpDot(ellipseDeriv(rx, ry, phi, true, theta)) / 2.0
+			}, a, b)
+		}
+	}
+	p.Iterate(func(Point, Point) {}, line, func(p0, p1, p2 Point) {
+		c1, c2 := quadraticToCubicBezier(p0, p1, p2)
+		cube(p0, c1, c2, p2)
+	}, cube, arc, line)
+	return area
+}
+
+func TestRoundedRect(t *testing.T) {
+	Epsilon = 1e-10
+	test.T(t, RoundedRect(1.0, 2.0, 0.0, 10.0, 2.0), &Path{})
+	test.T(t, RoundedRect(1.0, 2.0, 5.0, 10.0, 0.0), MustParseSVG("M1 2H6V12H1z"))
+	test.T(t, RoundedRect(1.0, 2.0, 5.0, 10.0, 2.0), MustParseSVG("M3 2L4 2A2 2 0 0 1 6 4L6 10A2 2 0 0 1 4 12L3 12A2 2 0 0 1 1 10L1 4A2 2 0 0 1 3 2z"))
+	test.T(t, RoundedRect(0.0, 0.0, 4.0, 10.0, 5.0), MustParseSVG("M2 0A2 2 0 0 1 4 2L4 8A2 2 0 0 1 2 10A2 2 0 0 1 0 8L0 2A2 2 0 0 1 2 0z"))
+	test.T(t, RoundedRectCorners(0.0, 0.0, 10.0, 10.0, 1.0, 2.0, 3.0, 0.0), MustParseSVG("M0 0L7 0A3 3 0 0 1 10 3L10 8A2 2 0 0 1 8 10L1 10A1 1 0 0 1 0 9z"))
+	test.T(t, RoundedRectCorners(0.0, 0.0, 10.0, 4.0, 0.0, 6.0, 2.0, 0.0), MustParseSVG("M0 0L9 0A1 1 0 0 1 10 1A3 3 0 0 1 7 4L0 4z"))
+
+	area := pathArea(RoundedRect(0.0, 0.0, 10.0, 6.0, 2.0)) // w·h − (4−π)·r²
+	test.That(t, math.Abs(area-(60.0-(4.0-math.Pi)*4.0)) < 1e-4, "area", area)
+}
+
+func TestBeveledRect(t *testing.T) {
+	Epsilon = 1e-10
+	test.T(t, BeveledRect(1.0, 2.0, 5.0, 0.0, 1.0), &Path{})
+	test.T(t, BeveledRect(1.0, 2.0, 5.0, 10.0, 0.0), MustParseSVG("M1 2H6V12H1z"))
+
+	p := BeveledRect(1.0, 2.0, 5.0, 10.0, 1.0)
+	test.T(t, p, MustParseSVG("M2 2L5 2L6 3L6 11L5 12L2 12L1 11L1 3z"))
+	test.That(t, p.Closed())
+	test.T(t, p.Coords(), []Point{{2.0, 2.0}, {5.0, 2.0}, {6.0, 3.0}, {6.0, 11.0}, {5.0, 12.0}, {2.0, 12.0}, {1.0, 11.0}, {1.0, 3.0}, {2.0, 2.0}})
+
+	// cut is clamped to half the smaller dimension
+	test.T(t, BeveledRect(0.0, 0.0, 4.0, 10.0, 3.0), MustParseSVG("M2 0L4 2L4 8L2 10L0 8L0 2z"))
+}
+
+func TestPie(t *testing.T) {
+	Epsilon = 1e-10
+	test.T(t, OpenArc(0.0, 0.0, 0.0, 0.0, 90.0), &Path{})
+	test.T(t, OpenArc(0.0, 0.0, 4.0, 90.0, 90.0), &Path{})
+	test.T(t, OpenArc(1.0, 2.0, 4.0, 0.0, 90.0), MustParseSVG("M5 2A4 4 0 0 1 1 6"))
+	test.T(t, OpenArc(1.0, 2.0, 4.0, 0.0, 270.0), MustParseSVG("M5 2A4 4 0 1 1 1 -2"))
+	test.T(t, Chord(1.0, 2.0, 4.0, 0.0, 90.0), MustParseSVG("M5 2A4 4 0 0 1 1 6z"))
+	test.T(t, Pie(1.0, 2.0, 4.0, 0.0, 90.0), MustParseSVG("M5 2A4 4 0 0 1 1 6L1 2z"))
+
+	// spans crossing the 0/360 boundary
+	test.T(t, Pie(0.0, 0.0, 4.0, 270.0, 0.0), Pie(0.0, 0.0, 4.0, -90.0, 0.0))
+	test.T(t, Pie(0.0, 0.0, 4.0, 270.0, 0.0), MustParseSVG("M0 -4A4 4 0 0 1 4 0L0 0z"))
+
+	// spans of more than 360 degrees
+	test.T(t, Pie(0.0, 0.0, 4.0, 0.0, 720.0), MustParseSVG("M4 0A4 4 0 0 1 -4 0A4 4 0 0 1 4 0z"))
+	test.T(t, OpenArc(0.0, 0.0, 4.0, 0.0, 450.0), MustParseSVG("M4 0A4 4 0 0 1 -4 0A4 4 0 0 1 4 0"))
+
+	test.That(t, math.Abs(pathArea(Pie(1.0, 2.0, 4.0, 30.0, 120.0))-4.0*math.Pi) < 1e-4)
+	test.That(t, math.Abs(pathArea(Pie(1.0, 2.0, 4.0, 315.0, 45.0))-4.0*math.Pi) < 1e-4)
+	test.That(t, math.Abs(pathArea(Pie(1.0, 2.0, 4.0, 0.0, 270.0))-12.0*math.Pi) < 1e-4)
+	test.That(t, math.Abs(pathArea(Chord(1.0, 2.0, 4.0, 0.0, 90.0))-8.0*(math.Pi/2.0-1.0)) < 1e-4)
+}
+
+func TestSpiral(t *testing.T) {
+	test.T(t, Spiral(0.0, 0.0, 1.0, 1.0, 0.0, 0.01), &Path{})
+	test.T(t, Spiral(0.0, 0.0, 0.0, 0.0, 2.0, 0.01), &Path{})
+
+	tolerance := 0.001
+	p := Spiral(1.0, 2.0, 1.0, 0.5, 3.0, tolerance)
+	test.That(t, !p.Closed())
+	test.T(t, p.StartPos(), Point{2.0, 2.0})
+	test.T(t, p.Pos(), Point{2.0 + 3.0*math.Pi, 2.0})
+
+	// the radius grows monotonically with the (unwrapped) angle and follows r = a+b*theta
+	theta, prevTheta, prevR := 0.0, 0.0, 0.0
+	p.Iterate(func(Point, Point) {}, func(Point, Point) {}, func(Point, Point, Point) {}, func(p0, p1, p2, p3 Point) {
+		for i := 0; i <= 10; i++ {
+			v := cubicBezierPos(p0, p1, p2, p3, float64(i)/10.0).Sub(Point{1.0, 2.0})
+			dtheta := angleNorm(v.Angle() - prevTheta)
+			if math.Pi < dtheta {
+				dtheta -= 2.0 * math.Pi
+			}
+			theta += dtheta
+			prevTheta = v.Angle()
+
+			r := v.Length()
+			test.That(t, prevR <= r, "radius must grow", prevR, r)
+			test.That(t, math.Abs(r-(1.0+0.5*theta)) <= tolerance, "deviation", r, 1.0+0.5*theta)
+			prevR = r
+		}
+	}, func(Point, float64, float64, float64, bool, bool, Point) {}, func(Point, Point) {})
+	test.That(t, math.Abs(theta-6.0*math.Pi) < 1e-6, "turns", theta)
+}
+
+func TestLogSpiral(t *testing.T) {
+	test.T(t, LogSpiral(0.0, 0.0, 1.0, 0.1, 0.0, 0.01), &Path{})
+	test.T(t, LogSpiral(0.0, 0.0, 0.0, 0.1, 2.0, 0.01), &Path{})
+
+	tolerance := 0.001
+	p := LogSpiral(1.0, 2.0, 1.0, 0.1, 2.5, tolerance)
+	test.That(t, !p.Closed())
+	test.T(t, p.StartPos(), Point{2.0, 2.0})
+	test.T(t, p.Pos(), Point{1.0 - math.Exp(0.5*math.Pi), 2.0})
+
+	// the radius grows monotonically with the (unwrapped) angle and follows r = a*exp(b*theta)
+	theta, prevTheta, prevR := 0.0, 0.0, 0.0
+	p.Iterate(func(Point, Point) {}, func(Point, Point) {}, func(Point, Point, Point) {}, func(p0, p1, p2, p3 Point) {
+		for i := 0; i <= 10; i++ {
+			v := cubicBezierPos(p0, p1, p2, p3, float64(i)/10.0).Sub(Point{1.0, 2.0})
+			dtheta := angleNorm(v.Angle() - prevTheta)
+			if math.Pi < dtheta {
+				dtheta -= 2.0 * math.Pi
+			}
+			theta += dtheta
+			prevTheta = v.Angle()
+
+			r := v.Length()
+			test.That(t, prevR <= r, "radius must grow", prevR, r)
+			// the distance to the curve is the radial distance times the cosine of the constant pitch angle
+			test.That(t, math.Abs(r-math.Exp(0.1*theta))/math.Sqrt(1.0+0.1*0.1) <= tolerance, "deviation", r, math.Exp(0.1*theta))
+			prevR = r
+		}
+	}, func(Point, float64, float64, float64, bool, bool, Point) {}, func(Point, Point) {})
+	test.That(t, math.Abs(theta-5.0*math.Pi) < 1e-6, "turns", theta)
+
+	// a negative growth factor spirals inwards
+	p = LogSpiral(0.0, 0.0, 4.0, -0.2, 1.0, tolerance)
+	test.T(t, p.Pos(), Point{4.0 * math.Exp(-0.4*math.Pi), 0.0})
+}
+
+func TestSuperellipse(t *testing.T) {
+	test.T(t, Superellipse(0.0, 0.0, 0.0, 2.0, 4.0), &Path{})
+	test.T(t, Superellipse(0.0, 0.0, 3.0, 2.0, 0.0), &Path{})
+
+	tolerance := Tolerance
+	Tolerance = 0.001
+	defer func() { Tolerance = tolerance }()
+
+	p := Superellipse(1.0, 2.0, 3.0, 2.0, 0.5)
+	test.That(t, p.Closed())
+	test.That(t, p.CCW())
+
+	for _, n := range []float64{1.0, 2.0, 4.0, 10.0} {
+		p := Superellipse(1.0, 2.0, 3.0, 2.0, n)
+		test.That(t, p.Closed())
+		test.That(t, p.CCW())
+		p.Iterate(func(Point, Point) {}, func(Point, Point) {}, func(Point, Point, Point) {}, func(p0, p1, p2, p3 Point) {
+			for i := 0; i <= 10; i++ {
+				v := cubicBezierPos(p0, p1, p2, p3, float64(i)/10.0).Sub(Point{1.0, 2.0})
+				f := math.Pow(math.Abs(v.X/3.0), n) + math.Pow(math.Abs(v.Y/2.0), n)
+
+				// scale radially onto the curve where |x/rx|^n + |y/ry|^n = 1 to measure the deviation
+				dist := v.Length() * math.Abs(1.0-math.Pow(f, -1.0/n))
+				test.That(t, dist <= Tolerance, "deviation", n, v, dist)
+			}
+		}, func(Point, float64, float64, float64, bool, bool, Point) {}, func(Point, Point) {})
+	}
+}
+
+func TestGrid(t *testing.T) {
+	Epsilon = 1e-10
+	test.T(t, Grid(0.0, 0.0, 0.0, 10.0, 2, 2), &Path{})
+	test.T(t, Grid(0.0, 0.0, 10.0, 10.0, 0, 2), &Path{})
+	test.T(t, Grid(0.0, 0.0, 10.0, 10.0, 1, 1), &Path{})
+	test.T(t, Grid(1.0, 2.0, 10.0, 6.0, 2, 1), MustParseSVG("M6 2L6 8"))
+	test.T(t, Grid(1.0, 2.0, 10.0, 6.0, 2, 3), MustParseSVG("M6 2L6 8M1 4L11 4M1 6L11 6"))
+
+	p := Grid(0.0, 0.0, 1.0, 1.0, 3, 7)
+	test.T(t, len(p.Split()), 2+6)
+	test.T(t, p.Coords()[2], Point{2.0 / 3.0, 0.0})
+	test.T(t, p.Coords()[len(p.Coords())-1], Point{1.0, 6.0 / 7.0})
+}
+
+func TestTicks(t *testing.T) {
+	Epsilon = 1e-10
+	test.T(t, Ticks(true, 0.0, 10.0, 0.0, 0.0, 1.0), &Path{})
+	test.T(t, Ticks(true, 10.0, 0.0, 0.0, 1.0, 1.0), &Path{})
+	test.T(t, Ticks(true, 0.0, 10.0, 2.0, 5.0, 1.0), MustParseSVG("M0 2L0 3M5 2L5 3M10 2L10 3"))
+	test.T(t, Ticks(true, 0.0, 12.0, 2.0, 5.0, -1.0), MustParseSVG("M0 2L0 1M5 2L5 1M10 2L10 1"))
+	test.T(t, Ticks(false, 0.0, 10.0, 2.0, 5.0, -1.0), MustParseSVG("M2 0L1 0M2 5L1 5M2 10L1 10"))
+
+	p := Ticks(true, 0.0, 1.0, 0.0, 0.1, 0.5)
+	test.T(t, len(p.Split()), 11)
+	test.That(t, p.Coords()[20].X == 1.0, "last tick exactly on the far edge")
+}
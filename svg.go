@@ -0,0 +1,776 @@
+package canvas
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/tdewolff/parse/v2/strconv"
+)
+
+// ParseSVGRect returns the path of an SVG rect element at (x,y) with width w and height h, and corners rounded with radii rx and ry. A negative rx or ry is treated as unspecified (auto) and takes the value of the other radius, and radii are clamped to half the width and height respectively. A non-positive width or height returns an empty path.
+func ParseSVGRect(x, y, w, h, rx, ry float64) *Path {
+	if w <= 0.0 || h <= 0.0 {
+		return &Path{}
+	}
+	if rx < 0.0 && ry < 0.0 {
+		rx, ry = 0.0, 0.0
+	} else if rx < 0.0 {
+		rx = ry
+	} else if ry < 0.0 {
+		ry = rx
+	}
+	rx = math.Min(rx, w/2.0)
+	ry = math.Min(ry, h/2.0)
+
+	p := &Path{}
+	if rx == 0.0 || ry == 0.0 {
+		p.MoveTo(x, y)
+		p.LineTo(x+w, y)
+		p.LineTo(x+w, y+h)
+		p.LineTo(x, y+h)
+		p.Close()
+		return p
+	}
+	p.MoveTo(x+rx, y)
+	p.LineTo(x+w-rx, y)
+	p.ArcTo(rx, ry, 0.0, false, true, x+w, y+ry)
+	p.LineTo(x+w, y+h-ry)
+	p.ArcTo(rx, ry, 0.0, false, true, x+w-rx, y+h)
+	p.LineTo(x+rx, y+h)
+	p.ArcTo(rx, ry, 0.0, false, true, x, y+h-ry)
+	p.LineTo(x, y+ry)
+	p.ArcTo(rx, ry, 0.0, false, true, x+rx, y)
+	p.Close()
+	return p
+}
+
+// ParseSVGCircle returns the path of an SVG circle element centered at (cx,cy) with radius r. A non-positive radius returns an empty path.
+func ParseSVGCircle(cx, cy, r float64) *Path {
+	return ParseSVGEllipse(cx, cy, r, r)
+}
+
+// ParseSVGEllipse returns the path of an SVG ellipse element centered at (cx,cy) with radii rx and ry. Like SVG, it starts at the rightmost point and consists of four arcs. A non-positive radius returns an empty path.
+func ParseSVGEllipse(cx, cy, rx, ry float64) *Path {
+	if rx <= 0.0 || ry <= 0.0 {
+		return &Path{}
+	}
+
+	p := &Path{}
+	p.MoveTo(cx+rx, cy)
+	p.ArcTo(rx, ry, 0.0, false, true, cx, cy+ry)
+	p.ArcTo(rx, ry, 0.0, false, true, cx-rx, cy)
+	p.ArcTo(rx, ry, 0.0, false, true, cx, cy-ry)
+	p.ArcTo(rx, ry, 0.0, false, true, cx+rx, cy)
+	p.Close()
+	return p
+}
+
+// ParseSVGLine returns the path of an SVG line element from (x1,y1) to (x2,y2).
+func ParseSVGLine(x1, y1, x2, y2 float64) *Path {
+	p := &Path{}
+	p.MoveTo(x1, y1)
+	p.LineTo(x2, y2)
+	return p
+}
+
+// ParseSVGPolyline returns the open path of an SVG polyline element, where points is a list of coordinates separated by whitespace and/or commas, eg. "0,0 10,0 10,10".
+func ParseSVGPolyline(points string) (*Path, error) {
+	return parseSVGPoints(points, false)
+}
+
+// ParseSVGPolygon returns the closed path of an SVG polygon element, where points is a list of coordinates separated by whitespace and/or commas, eg. "0,0 10,0 10,10".
+func ParseSVGPolygon(points string) (*Path, error) {
+	return parseSVGPoints(points, true)
+}
+
+func parseSVGPoints(points string, close bool) (*Path, error) {
+	nums, err := parseSVGNumbers(points)
+	if err != nil {
+		return nil, fmt.Errorf("bad points: %v", err)
+	} else if len(nums)%2 != 0 {
+		return nil, fmt.Errorf("bad points: odd number of coordinates")
+	}
+
+	p := &Path{}
+	for i := 0; i < len(nums); i += 2 {
+		if i == 0 {
+			p.MoveTo(nums[i], nums[i+1])
+		} else {
+			p.LineTo(nums[i], nums[i+1])
+		}
+	}
+	if close && 0 < len(nums) {
+		p.Close()
+	}
+	return p, nil
+}
+
+// MustParseSVGShape parses an SVG shape element and panics if it fails.
+func MustParseSVGShape(name string, attrs map[string]string) *Path {
+	p, err := ParseSVGShape(name, attrs)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// ParseSVGShape returns the path of an SVG shape element with the given name and attributes, ie. path, rect, circle, ellipse, line, polyline, or polygon. Missing attributes default to zero, and lengths can be numbers optionally followed by the px unit. Percentages and other units are not supported and return an error, as do negative sizes.
+func ParseSVGShape(name string, attrs map[string]string) (*Path, error) {
+	var err error
+	length := func(attr string) float64 {
+		if err != nil || strings.TrimSpace(attrs[attr]) == "" {
+			return 0.0
+		}
+		var f float64
+		if f, err = parseSVGLength(attrs[attr]); err != nil {
+			err = fmt.Errorf("bad shape: %v in attribute '%s'", err, attr)
+		}
+		return f
+	}
+	size := func(attr string) float64 {
+		f := length(attr)
+		if err == nil && f < 0.0 {
+			err = fmt.Errorf("bad shape: negative value in attribute '%s'", attr)
+		}
+		return f
+	}
+	radius := func(attr string) float64 {
+		// unspecified radii are negative
+		if s, ok := attrs[attr]; !ok || strings.TrimSpace(s) == "auto" {
+			return -1.0
+		}
+		return size(attr)
+	}
+
+	var p *Path
+	switch name {
+	case "path":
+		return ParseSVG(attrs["d"])
+	case "rect":
+		x, y := length("x"), length("y")
+		w, h := size("width"), size("height")
+		p = ParseSVGRect(x, y, w, h, radius("rx"), radius("ry"))
+	case "circle":
+		p = ParseSVGCircle(length("cx"), length("cy"), size("r"))
+	case "ellipse":
+		cx, cy := length("cx"), length("cy")
+		rx, ry := radius("rx"), radius("ry")
+		if rx < 0.0 {
+			rx = ry
+		} else if ry < 0.0 {
+			ry = rx
+		}
+		p = ParseSVGEllipse(cx, cy, rx, ry)
+	case "line":
+		p = ParseSVGLine(length("x1"), length("y1"), length("x2"), length("y2"))
+	case "polyline":
+		return ParseSVGPolyline(attrs["points"])
+	case "polygon":
+		return ParseSVGPolygon(attrs["points"])
+	default:
+		return nil, fmt.Errorf("bad shape: unsupported element '%s'", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MustParseSVGTransform parses an SVG transform attribute and panics if it fails.
+func MustParseSVGTransform(s string) Matrix {
+	m, err := ParseSVGTransform(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// ParseSVGTransform parses an SVG transform attribute, such as "translate(10,20) rotate(45) scale(2)", into a matrix. It supports the matrix, translate, scale, rotate (with an optional center), skewX, and skewY functions, which are composed from left to right so that the rightmost is applied first. Angles are in degrees and an empty string returns the identity matrix.
+func ParseSVGTransform(s string) (Matrix, error) {
+	b := []byte(s)
+	m := Identity
+	for i := skipCommaWhitespace(b); i < len(b); i += skipCommaWhitespace(b[i:]) {
+		start := i
+		for i < len(b) && ('a' <= b[i] && b[i] <= 'z' || 'A' <= b[i] && b[i] <= 'Z') {
+			i++
+		}
+		name := string(b[start:i])
+		if name == "" {
+			return Identity, fmt.Errorf("bad transform: unexpected character '%c' at position %d", b[i], i+1)
+		}
+		i += skipWhitespace(b[i:])
+		if len(b) <= i || b[i] != '(' {
+			return Identity, fmt.Errorf("bad transform: expected '(' after '%s' at position %d", name, i+1)
+		}
+		i++
+
+		args := []float64{}
+		for i += skipWhitespace(b[i:]); i < len(b) && b[i] != ')'; i += skipCommaWhitespace(b[i:]) {
+			num, n := strconv.ParseFloat(b[i:])
+			if n == 0 {
+				return Identity, fmt.Errorf("bad transform: unexpected character '%c' in '%s' at position %d", b[i], name, i+1)
+			} else if math.IsInf(num, 0) || math.IsNaN(num) {
+				return Identity, fmt.Errorf("bad transform: number out of range in '%s' at position %d", name, i+1)
+			}
+			args = append(args, num)
+			i += n
+		}
+		if len(b) <= i {
+			return Identity, fmt.Errorf("bad transform: expected ')' after '%s'", name)
+		}
+		i++
+
+		switch n := len(args); {
+		case name == "matrix" && n == 6:
+			m = m.Mul(Matrix{
+				{args[0], args[2], args[4]},
+				{args[1], args[3], args[5]},
+			})
+		case name == "translate" && (n == 1 || n == 2):
+			ty := 0.0
+			if n == 2 {
+				ty = args[1]
+			}
+			m = m.Translate(args[0], ty)
+		case name == "scale" && (n == 1 || n == 2):
+			sy := args[0]
+			if n == 2 {
+				sy = args[1]
+			}
+			m = m.Scale(args[0], sy)
+		case name == "rotate" && n == 1:
+			m = m.Rotate(args[0])
+		case name == "rotate" && n == 3:
+			m = m.RotateAbout(args[0], args[1], args[2])
+		case name == "skewX" && n == 1:
+			m = m.Shear(math.Tan(args[0]*math.Pi/180.0), 0.0)
+		case name == "skewY" && n == 1:
+			m = m.Shear(0.0, math.Tan(args[0]*math.Pi/180.0))
+		case name == "matrix" || name == "translate" || name == "scale" || name == "rotate" || name == "skewX" || name == "skewY":
+			return Identity, fmt.Errorf("bad transform: wrong number of arguments for '%s' at position %d", name, start+1)
+		default:
+			return Identity, fmt.Errorf("bad transform: unknown function '%s' at position %d", name, start+1)
+		}
+	}
+	return m, nil
+}
+
+func skipWhitespace(b []byte) int {
+	i := 0
+	for i < len(b) && (b[i] == ' ' || b[i] == '\n' || b[i] == '\r' || b[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// ParseSVGFile parses an SVG document and draws it onto a new canvas with the size of the document, see Context.DrawSVG. It returns warnings for the unsupported features that were skipped.
+func ParseSVGFile(r io.Reader) (*Canvas, []string, error) {
+	im := &svgImporter{
+		dec:    xml.NewDecoder(r),
+		warned: map[string]bool{},
+		defs:   map[string]xml.StartElement{},
+	}
+	root, w, h, view, err := im.root()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := New(w, h)
+	err = im.draw(NewContext(c), root, Identity.Translate(0.0, h).ReflectY().Mul(view))
+	return c, im.warnings, err
+}
+
+// DrawSVG parses an SVG document and draws it with its top-left corner at the top-left of the context, using the document's size and viewBox. It supports path and the basic shape elements, groups with transforms, use elements that reference shapes in defs elements, and the fill, stroke, and opacity presentation attributes and style properties. Unsupported features such as text, images, gradients, and filters are skipped and returned as warnings.
+func (c *Context) DrawSVG(r io.Reader) ([]string, error) {
+	im := &svgImporter{
+		dec:    xml.NewDecoder(r),
+		warned: map[string]bool{},
+		defs:   map[string]xml.StartElement{},
+	}
+	root, _, _, view, err := im.root()
+	if err != nil {
+		return nil, err
+	}
+
+	err = im.draw(c, root, Identity.Translate(0.0, c.Height()).ReflectY().Mul(view))
+	return im.warnings, err
+}
+
+type svgImporter struct {
+	dec      *xml.Decoder
+	warnings []string
+	warned   map[string]bool
+	defs     map[string]xml.StartElement // shapes in defs elements by ID
+}
+
+// svgState is the inherited state of an SVG element.
+type svgState struct {
+	m       Matrix
+	props   map[string]string
+	opacity float64
+}
+
+func (im *svgImporter) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	if !im.warned[warning] {
+		im.warnings = append(im.warnings, warning)
+		im.warned[warning] = true
+	}
+}
+
+// root reads until the root element and returns it together with the document's size in millimeters and the transformation from user units to millimeters, with the y-axis pointing down.
+func (im *svgImporter) root() (xml.StartElement, float64, float64, Matrix, error) {
+	for {
+		token, err := im.dec.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, 0.0, 0.0, Identity, fmt.Errorf("bad svg: missing svg element")
+		} else if err != nil {
+			return xml.StartElement{}, 0.0, 0.0, Identity, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "svg" {
+				return start, 0.0, 0.0, Identity, fmt.Errorf("bad svg: root element should be svg instead of '%s'", start.Name.Local)
+			}
+			attrs := svgAttrs(start)
+
+			var viewBox [4]float64
+			hasViewBox := false
+			if s, ok := attrs["viewBox"]; ok {
+				nums, err := parseSVGNumbers(s)
+				if err != nil || len(nums) != 4 || nums[2] <= 0.0 || nums[3] <= 0.0 {
+					return start, 0.0, 0.0, Identity, fmt.Errorf("bad svg: invalid viewBox '%s'", s)
+				}
+				copy(viewBox[:], nums)
+				hasViewBox = true
+			}
+
+			w, okW := parseSVGAbsLength(attrs["width"])
+			h, okH := parseSVGAbsLength(attrs["height"])
+			if !hasViewBox && (!okW || !okH) {
+				return start, 0.0, 0.0, Identity, fmt.Errorf("bad svg: missing width and height or viewBox")
+			} else if !okW {
+				w = viewBox[2] * mmPerPx
+			} else if !okH {
+				h = viewBox[3] * mmPerPx
+			}
+			if !hasViewBox {
+				viewBox = [4]float64{0.0, 0.0, w / mmPerPx, h / mmPerPx}
+			}
+
+			// center the viewBox and scale uniformly, ie. xMidYMid meet
+			scale := math.Min(w/viewBox[2], h/viewBox[3])
+			x := (w-viewBox[2]*scale)/2.0 - viewBox[0]*scale
+			y := (h-viewBox[3]*scale)/2.0 - viewBox[1]*scale
+			return start, w, h, Identity.Translate(x, y).Scale(scale, scale), nil
+		}
+	}
+}
+
+// draw draws the children of element start.
+func (im *svgImporter) draw(ctx *Context, start xml.StartElement, view Matrix) error {
+	state := svgState{
+		m:       view,
+		props:   map[string]string{},
+		opacity: 1.0,
+	}
+	if state, ok := im.state(start, state); ok {
+		return im.drawChildren(ctx, state)
+	}
+	return im.dec.Skip()
+}
+
+func (im *svgImporter) drawChildren(ctx *Context, state svgState) error {
+	for {
+		token, err := im.dec.Token()
+		if err == io.EOF {
+			return fmt.Errorf("bad svg: unexpected end of document")
+		} else if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			name := token.Name.Local
+			switch name {
+			case "g", "a":
+				if child, ok := im.state(token, state); ok {
+					if err := im.drawChildren(ctx, child); err != nil {
+						return err
+					}
+					continue
+				}
+			case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+				if child, ok := im.state(token, state); ok {
+					im.drawShape(ctx, name, svgAttrs(token), child)
+				}
+			case "use":
+				if child, ok := im.state(token, state); ok {
+					im.drawUse(ctx, token, child)
+				}
+			case "defs":
+				if err := im.readDefs(); err != nil {
+					return err
+				}
+				continue
+			case "title", "desc", "metadata":
+				// no content to draw
+			default:
+				im.warn("unsupported element '%s'", name)
+			}
+			if err := im.dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readDefs reads the children of a defs element and keeps the shapes with an ID, so that they can be referenced by use elements.
+func (im *svgImporter) readDefs() error {
+	for {
+		token, err := im.dec.Token()
+		if err == io.EOF {
+			return fmt.Errorf("bad svg: unexpected end of document")
+		} else if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+				if id := svgAttrs(token)["id"]; id != "" {
+					im.defs[id] = token.Copy()
+				}
+			}
+			if err := im.dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// drawUse draws the shape referenced by a use element, translated by its x and y attributes. Only shapes defined earlier in a defs element can be referenced.
+func (im *svgImporter) drawUse(ctx *Context, start xml.StartElement, state svgState) {
+	href := ""
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "href" && (attr.Name.Space == "" || attr.Name.Space == "http://www.w3.org/1999/xlink") {
+			href = attr.Value
+		}
+	}
+	def, ok := im.defs[strings.TrimPrefix(href, "#")]
+	if !strings.HasPrefix(href, "#") || !ok {
+		im.warn("unsupported reference '%s'", href)
+		return
+	}
+
+	attrs := svgAttrs(start)
+	var x, y float64
+	var err error
+	if val, ok := attrs["x"]; ok {
+		if x, err = parseSVGLength(val); err != nil {
+			im.warn("%v", err)
+			return
+		}
+	}
+	if val, ok := attrs["y"]; ok {
+		if y, err = parseSVGLength(val); err != nil {
+			im.warn("%v", err)
+			return
+		}
+	}
+	state.m = state.m.Translate(x, y)
+	if state, ok := im.state(def, state); ok {
+		im.drawShape(ctx, def.Name.Local, svgAttrs(def), state)
+	}
+}
+
+// state returns the state of an element that inherits from its parent, or false if the element is not displayed.
+func (im *svgImporter) state(start xml.StartElement, parent svgState) (svgState, bool) {
+	attrs := svgAttrs(start)
+	state := svgState{
+		m:       parent.m,
+		props:   make(map[string]string, len(parent.props)),
+		opacity: parent.opacity,
+	}
+	for key, val := range parent.props {
+		state.props[key] = val
+	}
+
+	// presentation attributes are overridden by the style attribute
+	props := map[string]string{}
+	for key, val := range attrs {
+		if _, ok := svgProperties[key]; ok {
+			props[key] = strings.TrimSpace(val)
+		}
+	}
+	for _, decl := range strings.Split(attrs["style"], ";") {
+		if colon := strings.IndexByte(decl, ':'); colon != -1 {
+			key := strings.TrimSpace(decl[:colon])
+			if _, ok := svgProperties[key]; ok {
+				props[key] = strings.TrimSpace(decl[colon+1:])
+			} else {
+				im.warn("unsupported property '%s'", key)
+			}
+		}
+	}
+	for key, val := range props {
+		if val == "inherit" {
+			continue
+		}
+		switch key {
+		case "display":
+			if val == "none" {
+				return state, false
+			}
+		case "opacity":
+			if opacity, ok := parseSVGOpacity(val); ok {
+				state.opacity *= opacity
+			} else {
+				im.warn("invalid opacity '%s'", val)
+			}
+		case "clip-path", "mask", "filter":
+			if val != "none" {
+				im.warn("unsupported property '%s'", key)
+			}
+		default:
+			state.props[key] = val
+		}
+	}
+	if state.props["visibility"] == "hidden" || state.props["visibility"] == "collapse" {
+		return state, false
+	}
+
+	if s, ok := attrs["transform"]; ok {
+		m, err := ParseSVGTransform(s)
+		if err != nil {
+			im.warn("%v", err)
+			return state, false
+		}
+		state.m = state.m.Mul(m)
+	}
+	return state, true
+}
+
+// svgProperties are the supported presentation attributes and style properties.
+var svgProperties = map[string]bool{
+	"color":             true,
+	"display":           true,
+	"visibility":        true,
+	"opacity":           true,
+	"fill":              true,
+	"fill-opacity":      true,
+	"fill-rule":         true,
+	"stroke":            true,
+	"stroke-opacity":    true,
+	"stroke-width":      true,
+	"stroke-linecap":    true,
+	"stroke-linejoin":   true,
+	"stroke-miterlimit": true,
+	"stroke-dasharray":  true,
+	"stroke-dashoffset": true,
+	"clip-path":         true,
+	"mask":              true,
+	"filter":            true,
+}
+
+func (im *svgImporter) drawShape(ctx *Context, name string, attrs map[string]string, state svgState) {
+	p, err := ParseSVGShape(name, attrs)
+	if err != nil {
+		im.warn("%v", err)
+		return
+	} else if p.Empty() {
+		return
+	}
+	p = p.Transform(state.m)
+	scale := math.Sqrt(math.Abs(state.m.Det()))
+
+	style := DefaultStyle
+	style.FillColor = im.paint(state, "fill", "black")
+	style.StrokeColor = im.paint(state, "stroke", "none")
+	if state.props["fill-rule"] == "evenodd" {
+		style.FillRule = EvenOdd
+	}
+	style.StrokeWidth = im.length(state, "stroke-width", 1.0) * scale
+	switch state.props["stroke-linecap"] {
+	case "round":
+		style.StrokeCapper = RoundCap
+	case "square":
+		style.StrokeCapper = SquareCap
+	}
+	miterLimit := im.length(state, "stroke-miterlimit", 4.0)
+	switch state.props["stroke-linejoin"] {
+	case "round":
+		style.StrokeJoiner = RoundJoin
+	case "bevel":
+		style.StrokeJoiner = BevelJoin
+	case "arcs":
+		style.StrokeJoiner = ArcsClipJoin(BevelJoin, miterLimit)
+	default:
+		style.StrokeJoiner = MiterClipJoin(BevelJoin, miterLimit)
+	}
+	if s := state.props["stroke-dasharray"]; s != "" && s != "none" {
+		dashes, err := parseSVGNumbers(strings.Replace(s, "px", "", -1))
+		if err != nil {
+			im.warn("invalid stroke-dasharray '%s'", s)
+		} else {
+			for i := range dashes {
+				dashes[i] *= scale
+			}
+			style.Dashes = dashes
+			style.DashOffset = im.length(state, "stroke-dashoffset", 0.0) * scale
+		}
+	}
+
+	ctx.Push()
+	ctx.Style = style
+	ctx.DrawPath(0.0, 0.0, p)
+	ctx.Pop()
+}
+
+// paint returns the color of the fill or stroke property including its opacity.
+func (im *svgImporter) paint(state svgState, key, def string) color.RGBA {
+	s, ok := state.props[key]
+	if !ok {
+		s = def
+	}
+	if s == "currentColor" {
+		s = state.props["color"]
+	}
+	if s == "none" {
+		return Transparent
+	} else if strings.HasPrefix(s, "url(") {
+		im.warn("unsupported paint '%s'", s)
+		return Transparent
+	}
+
+	col, err := ParseColor(s)
+	if err != nil {
+		im.warn("%v", err)
+		return Transparent
+	}
+	opacity := state.opacity
+	if s, ok := state.props[key+"-opacity"]; ok {
+		if f, ok := parseSVGOpacity(s); ok {
+			opacity *= f
+		} else {
+			im.warn("invalid %s-opacity '%s'", key, s)
+		}
+	}
+	if opacity < 1.0 {
+		col.R = uint8(float64(col.R)*opacity + 0.5)
+		col.G = uint8(float64(col.G)*opacity + 0.5)
+		col.B = uint8(float64(col.B)*opacity + 0.5)
+		col.A = uint8(float64(col.A)*opacity + 0.5)
+	}
+	return col
+}
+
+// length returns the length of a property in user units.
+func (im *svgImporter) length(state svgState, key string, def float64) float64 {
+	s, ok := state.props[key]
+	if !ok {
+		return def
+	}
+	f, err := parseSVGLength(s)
+	if err != nil || f < 0.0 {
+		im.warn("invalid %s '%s'", key, s)
+		return def
+	}
+	return f
+}
+
+func svgAttrs(start xml.StartElement) map[string]string {
+	attrs := make(map[string]string, len(start.Attr))
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" {
+			attrs[attr.Name.Local] = attr.Value
+		}
+	}
+	return attrs
+}
+
+// mmPerPx is the size of a CSS pixel in millimeters.
+const mmPerPx = 25.4 / 96.0
+
+// parseSVGLength parses a length in user units, which is a number optionally followed by the px unit.
+func parseSVGLength(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "%") {
+		return 0.0, fmt.Errorf("percentages are not supported")
+	}
+	s = strings.TrimSuffix(s, "px")
+	num, n := strconv.ParseFloat([]byte(s))
+	if n == 0 || n != len(s) || math.IsInf(num, 0) || math.IsNaN(num) {
+		return 0.0, fmt.Errorf("invalid length '%s'", s)
+	}
+	return num, nil
+}
+
+// parseSVGAbsLength parses an absolute length with an optional unit and returns it in millimeters, where a number without unit is in pixels.
+func parseSVGAbsLength(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	units := []struct {
+		unit  string
+		scale float64
+	}{
+		{"mm", 1.0},
+		{"cm", 10.0},
+		{"in", mmPerInch},
+		{"pt", mmPerPt},
+		{"pc", 12.0 * mmPerPt},
+		{"px", mmPerPx},
+	}
+	scale := mmPerPx
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.unit) {
+			s = s[:len(s)-len(unit.unit)]
+			scale = unit.scale
+			break
+		}
+	}
+	num, n := strconv.ParseFloat([]byte(s))
+	if n == 0 || n != len(s) || math.IsInf(num, 0) || math.IsNaN(num) || num <= 0.0 {
+		return 0.0, false
+	}
+	return num * scale, true
+}
+
+// parseSVGOpacity parses an opacity as a number or percentage, clamped to [0,1].
+func parseSVGOpacity(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	if strings.HasSuffix(s, "%") {
+		s = s[:len(s)-1]
+		scale = 0.01
+	}
+	num, n := strconv.ParseFloat([]byte(s))
+	if n == 0 || n != len(s) || math.IsNaN(num) {
+		return 0.0, false
+	}
+	return math.Max(0.0, math.Min(1.0, num*scale)), true
+}
+
+// parseSVGNumbers parses a list of numbers separated by whitespace and/or commas.
+func parseSVGNumbers(s string) ([]float64, error) {
+	b := []byte(s)
+	nums := []float64{}
+	for i := skipCommaWhitespace(b); i < len(b); i += skipCommaWhitespace(b[i:]) {
+		num, n := strconv.ParseFloat(b[i:])
+		if n == 0 {
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", b[i], i+1)
+		} else if math.IsInf(num, 0) || math.IsNaN(num) {
+			return nil, fmt.Errorf("number out of range at position %d", i+1)
+		}
+		nums = append(nums, num)
+		i += n
+	}
+	return nums, nil
+}
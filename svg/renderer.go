@@ -2,6 +2,8 @@ package svg
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/md5"
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
@@ -11,39 +13,169 @@ import (
 	"image/png"
 	"io"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/tdewolff/canvas"
+	canvasFont "github.com/tdewolff/canvas/font"
 )
 
 type SVG struct {
 	w             io.Writer
+	root          *rootWriter
+	gz            *gzip.Writer // nil if not compressed
 	width, height float64
+	title, desc   string
 	embedFonts    bool
-	fonts         map[*canvas.Font]bool
+	subsetFonts   bool
+	textAsPaths   bool
+	replaceArcs   bool
+	fonts         map[*canvas.Font]map[uint16]bool // used glyph IDs when subsetting
+	fontList      []*canvas.Font
 	maskID        int
+	filterID      int
+	gradientID    int
 	imgEnc        canvas.ImageEncoding
+	links         int
+	precision     int
+	dedupSize     int
+	paths         map[[md5.Size]byte]int // path IDs by hash of the path data, -1 if used only once
+	pathID        int
+	groups        []*svgGroup // open groups, the writer is the buffer of the innermost group
+	blend         canvas.BlendMode
 
 	classes []string
 }
 
+// svgAttr is an inherited presentation attribute of an element.
+type svgAttr struct {
+	key, val string
+}
+
+// hoistable returns true if the attribute can be moved onto the enclosing group. Blend modes cannot, since a group with a blend mode is composited as a whole, while its elements would be blended separately.
+func (attr svgAttr) hoistable() bool {
+	return !strings.Contains(attr.val, "mix-blend-mode")
+}
+
+// svgElement is an element with presentation attributes that may be hoisted onto the enclosing group when all its children share them. Raw elements are written verbatim and prevent hoisting.
+type svgElement struct {
+	start string // start of the tag up to the presentation attributes
+	attrs []svgAttr
+	end   string // rest of the element after the presentation attributes
+	raw   bool
+}
+
+func (el svgElement) writeTo(w io.Writer, hoisted []svgAttr) {
+	fmt.Fprintf(w, "%s", el.start)
+Attrs:
+	for _, attr := range el.attrs {
+		for _, h := range hoisted {
+			if attr == h {
+				continue Attrs
+			}
+		}
+		fmt.Fprintf(w, ` %s="%s"`, attr.key, attr.val)
+	}
+	fmt.Fprintf(w, "%s", el.end)
+}
+
+type svgGroup struct {
+	id, class   string
+	title, desc string
+	blur        float64   // standard deviation of the blur filter, zero for none
+	w           io.Writer // writer of the parent
+	buf         *bytes.Buffer
+	elements    []svgElement
+}
+
+// flush adds everything written to the group's buffer since the last element as a raw element.
+func (g *svgGroup) flush() {
+	if 0 < g.buf.Len() {
+		g.elements = append(g.elements, svgElement{start: g.buf.String(), raw: true})
+		g.buf.Reset()
+	}
+}
+
+// rootWriter writes the start tag of the root element before the first output, so that the document title can be set after creating the renderer.
+type rootWriter struct {
+	w       io.Writer
+	r       *SVG
+	started bool
+}
+
+func (w *rootWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.r.writeStart(w.w)
+	}
+	return w.w.Write(b)
+}
+
 // New creates a scalable vector graphics (SVG) renderer.
 func New(w io.Writer, width, height float64) *SVG {
-	fmt.Fprintf(w, `<svg version="1.1" width="%vmm" height="%vmm" viewBox="0 0 %v %v" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`, dec(width), dec(height), dec(width), dec(height))
-	return &SVG{
-		w:          w,
-		width:      width,
-		height:     height,
-		embedFonts: true,
-		fonts:      map[*canvas.Font]bool{},
-		maskID:     0,
-		imgEnc:     canvas.Lossless,
-		classes:    []string{},
+	r := &SVG{
+		width:       width,
+		height:      height,
+		embedFonts:  true,
+		subsetFonts: true,
+		fonts:       map[*canvas.Font]map[uint16]bool{},
+		maskID:      0,
+		imgEnc:      canvas.Lossless,
+		precision:   canvas.Precision,
+		dedupSize:   64,
+		paths:       map[[md5.Size]byte]int{},
+		classes:     []string{},
+	}
+	r.root = &rootWriter{w: w, r: r}
+	r.w = r.root
+	return r
+}
+
+// writeStart writes the start tag of the root element followed by the document title and description.
+func (r *SVG) writeStart(w io.Writer) {
+	fmt.Fprintf(w, `<svg version="1.1" width="%vmm" height="%vmm" viewBox="0 0 %v %v" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"`, r.num(r.width), r.num(r.height), r.num(r.width), r.num(r.height))
+	if r.title != "" || r.desc != "" {
+		fmt.Fprintf(w, ` role="img"`)
+	}
+	if r.title != "" {
+		fmt.Fprintf(w, ` aria-labelledby="svg-title"`)
+	}
+	if r.desc != "" {
+		fmt.Fprintf(w, ` aria-describedby="svg-desc"`)
+	}
+	fmt.Fprintf(w, ">")
+	if r.title != "" {
+		fmt.Fprintf(w, `<title id="svg-title">%s</title>`, xmlEscape(r.title))
+	}
+	if r.desc != "" {
+		fmt.Fprintf(w, `<desc id="svg-desc">%s</desc>`, xmlEscape(r.desc))
 	}
 }
 
+// NewSVGZ creates a scalable vector graphics renderer like New, but compresses the output with gzip as for .svgz files. Close must be called to flush the compressed stream.
+func NewSVGZ(w io.Writer, width, height float64) *SVG {
+	gz := gzip.NewWriter(w)
+	svg := New(gz, width, height)
+	svg.gz = gz
+	return svg
+}
+
 func (r *SVG) Close() error {
+	for ; 0 < r.links; r.links-- {
+		fmt.Fprintf(r.w, "</a>")
+	}
+	for 0 < len(r.groups) {
+		r.EndGroup()
+	}
+	if r.embedFonts && r.subsetFonts {
+		r.writeFontSubsets()
+	}
 	_, err := fmt.Fprintf(r.w, "</svg>")
+	if r.gz != nil {
+		if errClose := r.gz.Close(); err == nil {
+			err = errClose
+		}
+	}
 	return err
 }
 
@@ -68,6 +200,136 @@ func (r *SVG) RemoveClass(class string) {
 	}
 }
 
+// StartGroup starts a group element with the given ID and class, which may be empty. Presentation attributes shared by all elements of the group are written once on the group element.
+func (r *SVG) StartGroup(id, class string) {
+	if 0 < len(r.groups) {
+		r.groups[len(r.groups)-1].flush()
+	}
+	group := &svgGroup{
+		id:    id,
+		class: class,
+		w:     r.w,
+		buf:   &bytes.Buffer{},
+	}
+	r.groups = append(r.groups, group)
+	r.w = group.buf
+}
+
+// SetTitle sets the title and description of the innermost open group, or of the document when no group is open, which are written as <title> and <desc> elements for assistive technologies. Either may be empty. The document is labelled by its title with role="img", which requires the title to be set before anything is rendered.
+func (r *SVG) SetTitle(title, desc string) {
+	if 0 < len(r.groups) {
+		group := r.groups[len(r.groups)-1]
+		group.title, group.desc = title, desc
+	} else if !r.root.started {
+		r.title, r.desc = title, desc
+	} else {
+		writeTitle(r.w, title, desc)
+	}
+}
+
+// writeTitle writes the title and description elements if they are not empty.
+func writeTitle(w io.Writer, title, desc string) {
+	if title != "" {
+		fmt.Fprintf(w, "<title>%s</title>", xmlEscape(title))
+	}
+	if desc != "" {
+		fmt.Fprintf(w, "<desc>%s</desc>", xmlEscape(desc))
+	}
+}
+
+// EndGroup ends the last started group.
+func (r *SVG) EndGroup() {
+	if len(r.groups) == 0 {
+		return
+	}
+	group := r.groups[len(r.groups)-1]
+	group.flush()
+	r.groups = r.groups[:len(r.groups)-1]
+	r.w = group.w
+
+	// hoist the attributes that all elements have in common
+	var hoisted []svgAttr
+	for i, el := range group.elements {
+		if el.raw {
+			hoisted = nil
+			break
+		} else if i == 0 {
+			for _, attr := range el.attrs {
+				if attr.hoistable() {
+					hoisted = append(hoisted, attr)
+				}
+			}
+			continue
+		}
+		n := 0
+		for _, attr := range hoisted {
+			for _, attr2 := range el.attrs {
+				if attr == attr2 {
+					hoisted[n] = attr
+					n++
+					break
+				}
+			}
+		}
+		hoisted = hoisted[:n]
+	}
+
+	start := &strings.Builder{}
+	fmt.Fprintf(start, "<g")
+	if group.id != "" {
+		fmt.Fprintf(start, ` id="%s"`, xmlEscape(group.id))
+	}
+	if group.class != "" {
+		fmt.Fprintf(start, ` class="%s"`, xmlEscape(group.class))
+	}
+	end := &strings.Builder{}
+	if 0.0 < group.blur {
+		id := fmt.Sprintf("f%v", r.filterID)
+		r.filterID++
+		fmt.Fprintf(start, ` filter="url(#%s)"`, id)
+		fmt.Fprintf(end, ">")
+		writeTitle(end, group.title, group.desc)
+		fmt.Fprintf(end, `<defs><filter id="%s" filterUnits="userSpaceOnUse" x="0" y="0" width="%v" height="%v"><feGaussianBlur stdDeviation="%v"/></filter></defs>`, id, r.num(r.width), r.num(r.height), r.num(group.blur))
+	} else {
+		fmt.Fprintf(end, ">")
+		writeTitle(end, group.title, group.desc)
+	}
+	for _, el := range group.elements {
+		el.writeTo(end, hoisted)
+	}
+	fmt.Fprintf(end, "</g>")
+	r.writeElement(svgElement{start.String(), hoisted, end.String(), false})
+}
+
+// StartBlur starts a group that is blurred by a feGaussianBlur filter with the standard deviation sigma in millimeters. The filter region covers the document so that the blur is not clipped to the bounding box of the group.
+func (r *SVG) StartBlur(sigma float64) {
+	r.StartGroup("", "")
+	r.groups[len(r.groups)-1].blur = sigma
+}
+
+// EndBlur ends the last started blur.
+func (r *SVG) EndBlur() {
+	r.EndGroup()
+}
+
+// writeElement writes an element, or adds it to the innermost group.
+func (r *SVG) writeElement(el svgElement) {
+	if 0 < len(r.groups) {
+		group := r.groups[len(r.groups)-1]
+		group.flush()
+		group.elements = append(group.elements, el)
+		return
+	}
+	el.writeTo(r.w, nil)
+}
+
+func (r *SVG) classesAttr() string {
+	if len(r.classes) != 0 {
+		return fmt.Sprintf(` class="%s"`, strings.Join(r.classes, " "))
+	}
+	return ""
+}
+
 func (r *SVG) writeClasses(w io.Writer) {
 	if len(r.classes) != 0 {
 		fmt.Fprintf(w, `" class="%s`, strings.Join(r.classes, " "))
@@ -78,6 +340,31 @@ func (r *SVG) EmbedFonts(embedFonts bool) {
 	r.embedFonts = embedFonts
 }
 
+// SubsetFonts sets whether embedded TrueType fonts contain only the glyphs that are used, which is on by default and greatly reduces the file size. The fonts are then written in a <style> element at the end of the document when calling Close. It must be called before rendering text.
+func (r *SVG) SubsetFonts(subsetFonts bool) {
+	r.subsetFonts = subsetFonts
+}
+
+// TextAsPaths writes text as filled glyph outlines instead of text elements, so that the output does not depend on font support of the viewer but the text cannot be selected or searched. Text that uses features that cannot be expressed by text elements, such as sentence spacing or synthesized bold and italic styles, is always written as outlines.
+func (r *SVG) TextAsPaths(textAsPaths bool) {
+	r.textAsPaths = textAsPaths
+}
+
+// ReplaceArcs sets whether arcs are written as cubic Béziers instead of using the A command, which is not supported by some consumers.
+func (r *SVG) ReplaceArcs(replaceArcs bool) {
+	r.replaceArcs = replaceArcs
+}
+
+// DeduplicatePaths sets the minimum size in bytes of the path data for which repeated paths are deduplicated, which is 64 by default. A path that differs from a previous path only by a translation is written once in a <defs> element and referenced by <use> elements with their own position and style. Zero disables deduplication.
+func (r *SVG) DeduplicatePaths(minSize int) {
+	r.dedupSize = minSize
+}
+
+// SetPrecision sets the maximum number of decimals of all numbers in the output, such as coordinates, transformations and style values, which is canvas.Precision by default. It must be called before anything is rendered.
+func (r *SVG) SetPrecision(decimals int) {
+	r.precision = decimals
+}
+
 func (r *SVG) SetImageEncoding(enc canvas.ImageEncoding) {
 	r.imgEnc = enc
 }
@@ -87,7 +374,7 @@ func (r *SVG) writeFonts(fonts []*canvas.Font) {
 	for i, font := range fonts {
 		if _, ok := r.fonts[font]; !ok {
 			is = append(is, i)
-			r.fonts[font] = true
+			r.fonts[font] = nil
 		}
 	}
 
@@ -105,6 +392,64 @@ func (r *SVG) writeFonts(fonts []*canvas.Font) {
 	}
 }
 
+// addGlyphs registers the glyphs used by the text for the font subsets.
+func (r *SVG) addGlyphs(text *canvas.Text) {
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		font := span.Face.Font
+		glyphIDs, ok := r.fonts[font]
+		if !ok {
+			glyphIDs = map[uint16]bool{0: true}
+			r.fonts[font] = glyphIDs
+			r.fontList = append(r.fontList, font)
+		}
+		s := span.Text
+		if span.Face.Variant&canvas.FontSmallcaps != 0 {
+			// small capitals may be synthesized from the capitals by the viewer
+			s += strings.ToUpper(s)
+		}
+		for _, glyphID := range font.IndicesOf(s) {
+			glyphIDs[glyphID] = true
+		}
+	})
+}
+
+// writeFontSubsets writes the fonts used by the text with only the used glyphs. Fonts that cannot be subset, such as CFF fonts, are embedded completely.
+func (r *SVG) writeFontSubsets() {
+	if len(r.fontList) == 0 {
+		return
+	}
+
+	fmt.Fprintf(r.w, "<style>")
+	for _, font := range r.fontList {
+		mediatype, b := font.Raw()
+		if sfnt, err := parseSFNT(b); err == nil && sfnt.IsTrueType {
+			glyphIDs := make([]uint16, 0, len(r.fonts[font]))
+			for glyphID := range r.fonts[font] {
+				glyphIDs = append(glyphIDs, glyphID)
+			}
+			sort.Slice(glyphIDs, func(i, j int) bool { return glyphIDs[i] < glyphIDs[j] })
+			if subset, err := sfnt.Subset(glyphIDs); err == nil {
+				mediatype, b = "font/truetype", subset
+			}
+		}
+		fmt.Fprintf(r.w, "\n@font-face{font-family:'%s';src:url('data:%s;base64,", font.Name(), mediatype)
+		encoder := base64.NewEncoder(base64.StdEncoding, r.w)
+		encoder.Write(b)
+		encoder.Close()
+		fmt.Fprintf(r.w, "');}")
+	}
+	fmt.Fprintf(r.w, "\n</style>")
+}
+
+// parseSFNT parses a font in any of the supported formats as an SFNT font.
+func parseSFNT(b []byte) (*canvasFont.SFNT, error) {
+	b, err := canvasFont.ToSFNT(b)
+	if err != nil {
+		return nil, err
+	}
+	return canvasFont.ParseSFNT(append([]byte{}, b...))
+}
+
 func (r *SVG) Size() (float64, float64) {
 	return r.width, r.height
 }
@@ -113,36 +458,121 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	fill := style.FillColor.A != 0
 	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
 
-	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
-	fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
+	strokeUnsupported := isStrokeUnsupported(style)
+
+	m = canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)
+	defs, fillPaint := "", ""
+	if style.FillPaint != nil {
+		b := &strings.Builder{}
+		if id, ok := r.writePaint(b, style.FillPaint, m); ok {
+			defs, fillPaint = b.String(), fmt.Sprintf("url(#%s)", id)
+			fill = true
+		}
+	}
+
+	if 0 < r.dedupSize && fillPaint == "" && !(stroke && strokeUnsupported) {
+		// paths that differ only by a translation share their path data, the stroke width is not affected by a translation
+		linear := m
+		linear[0][2], linear[1][2] = 0.0, 0.0
+		shape := &strings.Builder{}
+		r.writePath(shape, path.Transform(linear))
+		if r.dedupSize <= shape.Len() {
+			key := md5.Sum([]byte(shape.String()))
+			id, ok := r.paths[key]
+			if !ok {
+				r.paths[key] = -1
+			} else {
+				b := &strings.Builder{}
+				if id == -1 {
+					id = r.pathID
+					r.pathID++
+					r.paths[key] = id
+					fmt.Fprintf(b, `<defs><path id="p%d" d="%s"/></defs>`, id, shape.String())
+				}
+				fmt.Fprintf(b, `<use xlink:href="#p%d"`, id)
+				if m[0][2] != 0.0 {
+					fmt.Fprintf(b, ` x="%v"`, r.num(m[0][2]))
+				}
+				if m[1][2] != 0.0 {
+					fmt.Fprintf(b, ` y="%v"`, r.num(m[1][2]))
+				}
+				r.writeElement(svgElement{b.String(), r.styleAttrs(style, fill, stroke, strokeUnsupported, ""), r.classesAttr() + "/>", false})
+				return
+			}
+		}
+	}
+
+	path = path.Transform(m)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `%s<path d="`, defs)
+	r.writePath(b, path)
+	fmt.Fprintf(b, `"`)
+	r.writeElement(svgElement{b.String(), r.styleAttrs(style, fill, stroke, strokeUnsupported, fillPaint), r.classesAttr() + "/>", false})
+
+	if stroke && strokeUnsupported {
+		// stroke settings unsupported by PDF, draw stroke explicitly
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		b := &strings.Builder{}
+		fmt.Fprintf(b, `<path d="`)
+		r.writePath(b, path)
+		fmt.Fprintf(b, `"`)
+		attrs := []svgAttr{}
+		if style.StrokeColor != canvas.Black {
+			attrs = append(attrs, svgAttr{"fill", r.color(style.StrokeColor)})
+		}
+		if style.FillRule == canvas.EvenOdd {
+			attrs = append(attrs, svgAttr{"fill-rule", "evenodd"})
+		}
+		if r.blend != canvas.BlendNormal {
+			attrs = append(attrs, svgAttr{"style", "mix-blend-mode:" + r.blend.String()})
+		}
+		r.writeElement(svgElement{b.String(), attrs, r.classesAttr() + "/>", false})
+	}
+}
 
-	strokeUnsupported := false
+// isStrokeUnsupported returns true if SVG cannot express the line join of the style, in which case the stroke is drawn as a filled outline.
+func isStrokeUnsupported(style canvas.Style) bool {
 	if arcs, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok && math.IsNaN(arcs.Limit) {
-		strokeUnsupported = true
+		return true
 	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
 		if math.IsNaN(miter.Limit) {
-			strokeUnsupported = true
+			return true
 		} else if _, ok := miter.GapJoiner.(canvas.BevelJoiner); !ok {
-			strokeUnsupported = true
+			return true
 		}
 	}
+	return false
+}
 
+// styleAttrs returns the fill and stroke attributes of a path element.
+func (r *SVG) styleAttrs(style canvas.Style, fill, stroke, strokeUnsupported bool, fillPaint string) []svgAttr {
+	attrs := []svgAttr{}
 	if !stroke {
 		if fill {
-			if style.FillColor != canvas.Black {
-				fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(style.FillColor))
+			if fillPaint != "" {
+				attrs = append(attrs, svgAttr{"fill", fillPaint})
+			} else if style.FillColor != canvas.Black {
+				attrs = append(attrs, svgAttr{"fill", r.color(style.FillColor)})
 			}
 			if style.FillRule == canvas.EvenOdd {
-				fmt.Fprintf(r.w, `" fill-rule="evenodd`)
+				attrs = append(attrs, svgAttr{"fill-rule", "evenodd"})
 			}
 		} else {
-			fmt.Fprintf(r.w, `" fill="none`)
+			attrs = append(attrs, svgAttr{"fill", "none"})
+		}
+		if r.blend != canvas.BlendNormal {
+			attrs = append(attrs, svgAttr{"style", "mix-blend-mode:" + r.blend.String()})
 		}
 	} else {
 		b := &strings.Builder{}
 		if fill {
-			if style.FillColor != canvas.Black {
-				fmt.Fprintf(b, ";fill:%v", canvas.CSSColor(style.FillColor))
+			if fillPaint != "" {
+				fmt.Fprintf(b, ";fill:%v", fillPaint)
+			} else if style.FillColor != canvas.Black {
+				fmt.Fprintf(b, ";fill:%v", r.color(style.FillColor))
 			}
 			if style.FillRule == canvas.EvenOdd {
 				fmt.Fprintf(b, ";fill-rule:evenodd")
@@ -151,9 +581,9 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			fmt.Fprintf(b, ";fill:none")
 		}
 		if stroke && !strokeUnsupported {
-			fmt.Fprintf(b, `;stroke:%v`, canvas.CSSColor(style.StrokeColor))
+			fmt.Fprintf(b, `;stroke:%v`, r.color(style.StrokeColor))
 			if style.StrokeWidth != 1.0 {
-				fmt.Fprintf(b, ";stroke-width:%v", dec(style.StrokeWidth))
+				fmt.Fprintf(b, ";stroke-width:%v", r.num(style.StrokeWidth))
 			}
 			if _, ok := style.StrokeCapper.(canvas.RoundCapper); ok {
 				fmt.Fprintf(b, ";stroke-linecap:round")
@@ -169,50 +599,166 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			} else if arcs, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok && !math.IsNaN(arcs.Limit) {
 				fmt.Fprintf(b, ";stroke-linejoin:arcs")
 				if !canvas.Equal(arcs.Limit, 4.0) {
-					fmt.Fprintf(b, ";stroke-miterlimit:%v", dec(arcs.Limit))
+					fmt.Fprintf(b, ";stroke-miterlimit:%v", r.num(arcs.Limit))
 				}
 			} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok && !math.IsNaN(miter.Limit) {
 				// a miter line join is the default
-				if !canvas.Equal(miter.Limit*2.0/style.StrokeWidth, 4.0) {
-					fmt.Fprintf(b, ";stroke-miterlimit:%v", dec(miter.Limit*2.0/style.StrokeWidth))
+				if !canvas.Equal(miter.Limit, 4.0) {
+					fmt.Fprintf(b, ";stroke-miterlimit:%v", r.num(miter.Limit))
 				}
 			} else {
 				panic("SVG: line join not support")
 			}
 
 			if 0 < len(style.Dashes) {
-				fmt.Fprintf(b, ";stroke-dasharray:%v", dec(style.Dashes[0]))
+				fmt.Fprintf(b, ";stroke-dasharray:%v", r.num(style.Dashes[0]))
 				for _, dash := range style.Dashes[1:] {
-					fmt.Fprintf(b, " %v", dec(dash))
+					fmt.Fprintf(b, " %v", r.num(dash))
 				}
 				if 0.0 != style.DashOffset {
-					fmt.Fprintf(b, ";stroke-dashoffset:%v", dec(style.DashOffset))
+					fmt.Fprintf(b, ";stroke-dashoffset:%v", r.num(style.DashOffset))
 				}
 			}
 		}
+		if r.blend != canvas.BlendNormal {
+			fmt.Fprintf(b, ";mix-blend-mode:%v", r.blend)
+		}
 		if 0 < b.Len() {
-			fmt.Fprintf(r.w, `" style="%s`, b.String()[1:])
+			attrs = append(attrs, svgAttr{"style", b.String()[1:]})
 		}
 	}
-	r.writeClasses(r.w)
-	fmt.Fprintf(r.w, `"/>`)
+	return attrs
+}
 
-	if stroke && strokeUnsupported {
-		// stroke settings unsupported by PDF, draw stroke explicitly
-		if 0 < len(style.Dashes) {
-			path = path.Dash(style.DashOffset, style.Dashes...)
+// writePaint writes the definition of a paint to w, where m is the transformation of the path that it fills. It returns the ID of the definition, or false if the paint is not supported.
+func (r *SVG) writePaint(w io.Writer, paint canvas.Paint, m canvas.Matrix) (string, bool) {
+	var tag string
+	var stops []canvas.Stop
+	var spread canvas.Spread
+	id := fmt.Sprintf("g%v", r.gradientID)
+	switch p := paint.(type) {
+	case canvas.LinearGradient:
+		tag, stops, spread = "linearGradient", p.Stops, p.Spread
+		fmt.Fprintf(w, `<defs><linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%v" y1="%v" x2="%v" y2="%v"`, id, r.num(p.X0), r.num(p.Y0), r.num(p.X1), r.num(p.Y1))
+	case canvas.RadialGradient:
+		tag, stops, spread = "radialGradient", p.Stops, p.Spread
+		fmt.Fprintf(w, `<defs><radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%v" cy="%v" r="%v"`, id, r.num(p.CX), r.num(p.CY), r.num(p.R))
+		if p.FX != p.CX || p.FY != p.CY {
+			fmt.Fprintf(w, ` fx="%v" fy="%v"`, r.num(p.FX), r.num(p.FY))
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
-		fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
-		if style.StrokeColor != canvas.Black {
-			fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(style.StrokeColor))
+	case canvas.ImagePattern:
+		r.writePattern(w, id, p, m)
+		r.gradientID++
+		return id, true
+	case canvas.PathPattern:
+		if p.Empty() {
+			return "", false
 		}
-		if style.FillRule == canvas.EvenOdd {
-			fmt.Fprintf(r.w, `" fill-rule="evenodd`)
+		r.writePathPattern(w, id, p, m)
+		r.gradientID++
+		return id, true
+	default:
+		return "", false
+	}
+	r.gradientID++
+
+	if spread != canvas.PadSpread {
+		fmt.Fprintf(w, ` spreadMethod="%v"`, spread)
+	}
+	if !m.Equals(canvas.Identity) {
+		fmt.Fprintf(w, ` gradientTransform="matrix(%v %v %v %v %v %v)"`, r.num(m[0][0]), r.num(m[1][0]), r.num(m[0][1]), r.num(m[1][1]), r.num(m[0][2]), r.num(m[1][2]))
+	}
+	fmt.Fprintf(w, ">")
+	for _, stop := range stops {
+		col, a := stop.Color, float64(stop.Color.A)/255.0
+		if col.A != 0 && col.A != 255 {
+			unpremultiply := func(c uint8) uint8 {
+				return uint8(math.Min(255.0, float64(c)/a+0.5))
+			}
+			col = color.RGBA{unpremultiply(col.R), unpremultiply(col.G), unpremultiply(col.B), 255}
 		}
-		r.writeClasses(r.w)
-		fmt.Fprintf(r.w, `"/>`)
+		fmt.Fprintf(w, `<stop offset="%v" stop-color="%v"`, r.num(stop.Offset), canvas.CSSColor(color.RGBA{col.R, col.G, col.B, 255}))
+		if a != 1.0 {
+			fmt.Fprintf(w, ` stop-opacity="%v"`, r.num(a))
+		}
+		fmt.Fprintf(w, "/>")
+	}
+	fmt.Fprintf(w, "</%s></defs>", tag)
+	return id, true
+}
+
+// writePattern writes the definition of an image pattern to w. Along axes that do not repeat, the tile is made so large that the image appears only once.
+func (r *SVG) writePattern(w io.Writer, id string, pattern canvas.ImagePattern, m canvas.Matrix) {
+	size := pattern.Img.Bounds().Size()
+	width, height := float64(size.X), float64(size.Y)
+	if !pattern.Repeat.RepeatsX() {
+		width = 1e6
+	}
+	if !pattern.Repeat.RepeatsY() {
+		height = 1e6
+	}
+
+	// the image is placed with its top-left corner at the origin of the tile
+	m = m.Mul(pattern.M).Translate(0.0, float64(size.Y)).Scale(1.0, -1.0)
+	fmt.Fprintf(w, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="%v" height="%v"`, id, r.num(width), r.num(height))
+	if !m.Equals(canvas.Identity) {
+		fmt.Fprintf(w, ` patternTransform="matrix(%v %v %v %v %v %v)"`, r.num(m[0][0]), r.num(m[1][0]), r.num(m[0][1]), r.num(m[1][1]), r.num(m[0][2]), r.num(m[1][2]))
+	}
+	fmt.Fprintf(w, `><image width="%d" height="%d" xlink:href="data:image/png;base64,`, size.X, size.Y)
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if err := png.Encode(encoder, pattern.Img); err != nil {
+		panic(err)
+	}
+	if err := encoder.Close(); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(w, `"/></pattern></defs>`)
+}
+
+// writePathPattern writes the definition of a path pattern to w. The copies of the motif that overlap the cell are all drawn so that the pattern tiles seamlessly.
+func (r *SVG) writePathPattern(w io.Writer, id string, pattern canvas.PathPattern, m canvas.Matrix) {
+	m = m.Mul(pattern.M)
+	fmt.Fprintf(w, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="%v" height="%v"`, id, r.num(pattern.CellW), r.num(pattern.CellH))
+	if !m.Equals(canvas.Identity) {
+		fmt.Fprintf(w, ` patternTransform="matrix(%v %v %v %v %v %v)"`, r.num(m[0][0]), r.num(m[1][0]), r.num(m[0][1]), r.num(m[1][1]), r.num(m[0][2]), r.num(m[1][2]))
+	}
+	fmt.Fprintf(w, ">")
+
+	style := pattern.Style
+	fill := style.FillColor.A != 0
+	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	strokeUnsupported := isStrokeUnsupported(style)
+	for _, offset := range pattern.Offsets() {
+		cell := pattern.Cell.Translate(offset.X, offset.Y)
+		if fill || stroke && !strokeUnsupported {
+			b := &strings.Builder{}
+			fmt.Fprintf(b, `<path d="`)
+			r.writePath(b, cell)
+			fmt.Fprintf(b, `"`)
+			svgElement{b.String(), r.styleAttrs(style, fill, stroke && !strokeUnsupported, false, ""), "/>", false}.writeTo(w, nil)
+		}
+		if stroke && strokeUnsupported {
+			if 0 < len(style.Dashes) {
+				cell = cell.Dash(style.DashOffset, style.Dashes...)
+			}
+			fmt.Fprintf(w, `<path d="`)
+			r.writePath(w, cell.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner))
+			fmt.Fprintf(w, `"`)
+			if style.StrokeColor != canvas.Black {
+				fmt.Fprintf(w, ` fill="%v"`, r.color(style.StrokeColor))
+			}
+			fmt.Fprintf(w, "/>")
+		}
+	}
+	fmt.Fprintf(w, "</pattern></defs>")
+}
+
+// writePath streams the path data to the writer, with coordinates rounded to the precision of the renderer.
+func (r *SVG) writePath(w io.Writer, path *canvas.Path) {
+	if r.replaceArcs {
+		path = path.ReplaceArcs()
 	}
+	path.WriteSVGPath(w, r.precision)
 }
 
 func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
@@ -247,15 +793,15 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 			fmt.Fprintf(buf, ` small-caps`)
 		}
 
-		fmt.Fprintf(buf, ` %vpx %s`, num(ff.Size*ff.Scale), ff.Name())
+		fmt.Fprintf(buf, ` %vpx %s`, r.num(ff.Size*ff.Scale), ff.Name())
 		buf.ReadByte()
 		buf.WriteTo(r.w)
 
 		if ff.Color != ffMain.Color {
-			fmt.Fprintf(r.w, `;fill:%v`, canvas.CSSColor(ff.Color))
+			fmt.Fprintf(r.w, `;fill:%v`, r.color(ff.Color))
 		}
 	} else if differences == 1 && ff.Color != ffMain.Color {
-		fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(ff.Color))
+		fmt.Fprintf(r.w, `" fill="%v`, r.color(ff.Color))
 	} else if 0 < differences {
 		fmt.Fprintf(r.w, `" style="`)
 		buf := &bytes.Buffer{}
@@ -269,7 +815,7 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 			fmt.Fprintf(buf, `;font-variant:small-caps`)
 		}
 		if ff.Color != ffMain.Color {
-			fmt.Fprintf(buf, `;fill:%v`, canvas.CSSColor(ff.Color))
+			fmt.Fprintf(buf, `;fill:%v`, r.color(ff.Color))
 		}
 		buf.ReadByte()
 		buf.WriteTo(r.w)
@@ -277,8 +823,17 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 }
 
 func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
+	if r.textAsPaths || !textElementSupported(text) {
+		text.RenderAsPath(r, m)
+		return
+	}
+
 	if r.embedFonts {
-		r.writeFonts(text.Fonts())
+		if r.subsetFonts {
+			r.addGlyphs(text)
+		} else {
+			r.writeFonts(text.Fonts())
+		}
 	}
 
 	if text.Empty() {
@@ -291,9 +846,9 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 	if m.IsTranslation() {
 		x0, y0 = m.Pos()
 		y0 = r.height - y0
-		fmt.Fprintf(r.w, `<text x="%v" y="%v`, num(x0), num(y0))
+		fmt.Fprintf(r.w, `<text x="%v" y="%v`, r.num(x0), r.num(y0))
 	} else {
-		fmt.Fprintf(r.w, `<text transform="%s`, m.ToSVG(r.height))
+		fmt.Fprintf(r.w, `<text transform="%s`, r.transform(m))
 	}
 	fmt.Fprintf(r.w, `" style="font:`)
 	if ffMain.Style&canvas.FontItalic != 0 {
@@ -305,20 +860,23 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 	if ffMain.Variant&canvas.FontSmallcaps != 0 {
 		fmt.Fprintf(r.w, ` small-caps`)
 	}
-	fmt.Fprintf(r.w, ` %vpx %s`, num(ffMain.Size*ffMain.Scale), ffMain.Name())
+	fmt.Fprintf(r.w, ` %vpx %s`, r.num(ffMain.Size*ffMain.Scale), ffMain.Name())
 	if ffMain.Color != canvas.Black {
-		fmt.Fprintf(r.w, `;fill:%v`, canvas.CSSColor(ffMain.Color))
+		fmt.Fprintf(r.w, `;fill:%v`, r.color(ffMain.Color))
+	}
+	if r.blend != canvas.BlendNormal {
+		fmt.Fprintf(r.w, `;mix-blend-mode:%v`, r.blend)
 	}
 	r.writeClasses(r.w)
 	fmt.Fprintf(r.w, `">`)
 
 	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
-		fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+dx), num(y0-y-span.Face.Voffset))
+		fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, r.num(x0+dx), r.num(y0-y-span.Face.Voffset))
 		if span.WordSpacing > 0.0 {
-			fmt.Fprintf(r.w, `" word-spacing="%v`, num(span.WordSpacing))
+			fmt.Fprintf(r.w, `" word-spacing="%v`, r.num(span.WordSpacing))
 		}
 		if span.GlyphSpacing > 0.0 {
-			fmt.Fprintf(r.w, `" letter-spacing="%v`, num(span.GlyphSpacing))
+			fmt.Fprintf(r.w, `" letter-spacing="%v`, r.num(span.GlyphSpacing))
 		}
 		r.writeFontStyle(span.Face, ffMain)
 		r.writeClasses(r.w)
@@ -330,10 +888,24 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 	text.RenderDecoration(r, m)
 }
 
+// textElementSupported returns true if the text can be written as a text element that renders the same as its glyph outlines.
+func textElementSupported(text *canvas.Text) bool {
+	supported := true
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		if span.SentenceSpacing != 0.0 || span.Face.FauxBold != 0.0 || span.Face.FauxItalic != 0.0 {
+			supported = false
+		}
+	})
+	return supported
+}
+
 func (r *SVG) RenderImage(img image.Image, m canvas.Matrix) {
 	refMask := ""
 	mimetype := "image/png"
-	if r.imgEnc == canvas.Lossy {
+	jpg, isJPEG := img.(canvas.JPEGImage)
+	if isJPEG {
+		mimetype = "image/jpeg" // embed the original data, JPEG images are opaque
+	} else if r.imgEnc == canvas.Lossy {
 		mimetype = "image/jpg"
 		if opaqueImg, ok := img.(interface{ Opaque() bool }); !ok || !opaqueImg.Opaque() {
 			hasMask := false
@@ -375,10 +947,14 @@ func (r *SVG) RenderImage(img image.Image, m canvas.Matrix) {
 
 	m = m.Translate(0.0, float64(img.Bounds().Size().Y))
 	fmt.Fprintf(r.w, `<image transform="%s" width="%d" height="%d" xlink:href="data:%s;base64,`,
-		m.ToSVG(r.height), img.Bounds().Size().X, img.Bounds().Size().Y, mimetype)
+		r.transform(m), img.Bounds().Size().X, img.Bounds().Size().Y, mimetype)
 
 	encoder := base64.NewEncoder(base64.StdEncoding, r.w)
-	if mimetype == "image/jpg" {
+	if isJPEG {
+		if _, err := encoder.Write(jpg.Data); err != nil {
+			panic(err)
+		}
+	} else if mimetype == "image/jpg" {
 		if err := jpeg.Encode(encoder, img, nil); err != nil {
 			panic(err)
 		}
@@ -394,6 +970,27 @@ func (r *SVG) RenderImage(img image.Image, m canvas.Matrix) {
 	if refMask != "" {
 		fmt.Fprintf(r.w, `" mask="url(#%s)`, refMask)
 	}
+	if r.blend != canvas.BlendNormal {
+		fmt.Fprintf(r.w, `" style="mix-blend-mode:%v`, r.blend)
+	}
 	r.writeClasses(r.w)
 	fmt.Fprintf(r.w, `"/>`)
 }
+
+// SetBlendMode sets the blend mode of the elements that follow, which is written as the mix-blend-mode property of each element.
+func (r *SVG) SetBlendMode(mode canvas.BlendMode) {
+	r.blend = mode
+}
+
+// StartLink wraps the following elements in an anchor to the URL until EndLink is called. The area is not used since the elements themselves are clickable.
+func (r *SVG) StartLink(url string, area *canvas.Path, m canvas.Matrix) {
+	fmt.Fprintf(r.w, `<a xlink:href="%s">`, xmlEscape(url))
+	r.links++
+}
+
+func (r *SVG) EndLink() {
+	if 0 < r.links {
+		fmt.Fprintf(r.w, "</a>")
+		r.links--
+	}
+}
@@ -1,9 +1,39 @@
 package svg
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"html"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/tdewolff/canvas"
+	canvasFont "github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/canvas/rasterizer"
+	"github.com/tdewolff/test"
 )
 
+func TestSVGReplaceArcs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 100.0, 100.0)
+	svg.RenderPath(canvas.Circle(10.0), canvas.DefaultStyle, canvas.Identity)
+	test.That(t, strings.Contains(buf.String(), "A"), buf.String())
+
+	buf.Reset()
+	svg.ReplaceArcs(true)
+	svg.RenderPath(canvas.Circle(10.0), canvas.DefaultStyle, canvas.Identity)
+	test.That(t, !strings.Contains(buf.String(), "A"), buf.String())
+	test.That(t, strings.Contains(buf.String(), "C"), buf.String())
+}
+
 func TestSVGText(t *testing.T) {
 	//dejaVuSerif := NewFontFamily("dejavu-serif")
 	//dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -30,3 +60,824 @@ func TestSVGText(t *testing.T) {
 	//s := regexp.MustCompile(`base64,.+'`).ReplaceAllString(buf.String(), "base64,'") // remove embedded font
 	//test.String(t, s, `<style>`+"\n"+`@font-face{font-family:'dejavu-serif';src:url('data:font/truetype;base64,');}`+"\n"+`@font-face{font-family:'eb-garamond';src:url('data:font/opentype;base64,');}`+"\n"+`</style><text x="0" y="0" style="font: 12px dejavu-serif"><tspan x="0" y="7.421875" style="font:8px dejavu-serif">dejaVu8</tspan><tspan x="0" y="20.453125" letter-spacing="1" style="font-style:italic;fill:#f00">glyphspacing</tspan><tspan x="0" y="33.725625" style="font:700 6.996px dejavu-serif">dejaVu12sub</tspan><tspan x="0" y="38.5" style="font:700 10px eb-garamond">garamond10</tspan></text><path d="M0 22.703125H91.71875V21.803125H0z" fill="#f00"/>`)
 }
+
+func TestSVGRoundTrip(t *testing.T) {
+	c := canvas.New(60.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(20.0, 10.0))
+	ctx.SetFillColor(color.RGBA{0, 0, 128, 128})
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(1.5)
+	ctx.DrawPath(40.0, 20.0, canvas.Circle(10.0))
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Green)
+	ctx.SetStrokeCapper(canvas.RoundCap)
+	ctx.SetStrokeJoiner(canvas.RoundJoin)
+	ctx.SetDashes(0.0, 4.0, 2.0)
+	ctx.DrawPath(5.0, 30.0, canvas.MustParseSVG("L15 5Q25 -5 30 5"))
+	ctx.ResetStyle()
+	ctx.Rotate(10.0)
+	ctx.SetFillRule(canvas.EvenOdd)
+	ctx.DrawPath(20.0, 15.0, canvas.Rectangle(10.0, 10.0), canvas.Rectangle(4.0, 4.0).Translate(3.0, 3.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	c2, warnings, err := canvas.ParseSVGFile(buf)
+	test.Error(t, err)
+	test.T(t, len(warnings), 0)
+	test.Float(t, c2.W, c.W)
+	test.Float(t, c2.H, c.H)
+
+	img := rasterizer.Draw(c, 4.0)
+	img2 := rasterizer.Draw(c2, 4.0)
+	test.T(t, img2.Bounds(), img.Bounds())
+	diff := 0
+	for i := range img.Pix {
+		if d := int(img.Pix[i]) - int(img2.Pix[i]); d < -8 || 8 < d {
+			diff++
+		}
+	}
+	test.That(t, diff < len(img.Pix)/200, diff, "channels differ")
+}
+
+func TestSVGFillRule(t *testing.T) {
+	// a star with crossing edges and a donut of two rings in the same direction
+	star := canvas.MustParseSVG("M0 8L-4.702 -6.472L7.608 2.472L-7.608 2.472L4.702 -6.472z")
+	donut := canvas.Circle(8.0).Append(canvas.Circle(4.0))
+	for _, rule := range []canvas.FillRule{canvas.NonZero, canvas.EvenOdd} {
+		c := canvas.New(40.0, 20.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillRule(rule)
+		ctx.DrawPath(10.0, 10.0, star)
+		ctx.DrawPath(30.0, 10.0, donut)
+
+		buf := &bytes.Buffer{}
+		test.Error(t, Writer(buf, c))
+		test.T(t, strings.Contains(buf.String(), `fill-rule="evenodd"`), rule == canvas.EvenOdd)
+		c2, _, err := canvas.ParseSVGFile(buf)
+		test.Error(t, err)
+
+		img := rasterizer.Draw(c2, 2.0)
+		at := func(x, y float64) uint8 {
+			return img.RGBAAt(int(x*2.0), int((20.0-y)*2.0)).A
+		}
+		hole := uint8(255)
+		if rule == canvas.EvenOdd {
+			hole = 0
+		}
+		test.T(t, at(10.0, 10.0), hole, rule)       // center of the star
+		test.T(t, at(10.0, 16.0), uint8(255), rule) // tip of the star
+		test.T(t, at(30.0, 10.0), hole, rule)       // center of the donut
+		test.T(t, at(30.0, 16.0), uint8(255), rule) // ring of the donut
+	}
+}
+
+func TestSVGLinks(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.StartLink("https://example.com/?a=1&b=2", 10.0, 10.0, canvas.Rectangle(20.0, 10.0))
+	ctx.DrawPath(10.0, 10.0, canvas.Rectangle(20.0, 10.0))
+	ctx.StartLink("https://example.com/inner", 15.0, 12.0, canvas.Rectangle(5.0, 5.0))
+	ctx.DrawPath(15.0, 12.0, canvas.Rectangle(5.0, 5.0))
+	ctx.EndLink()
+	ctx.EndLink()
+	ctx.DrawPath(50.0, 50.0, canvas.Rectangle(5.0, 5.0))
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, c.W, c.H)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	test.String(t, buf.String()[strings.Index(buf.String(), "<a"):], `<a xlink:href="https://example.com/?a=1&amp;b=2"><path d="M10 90H30V80H10z"/><a xlink:href="https://example.com/inner"><path d="M15 88H20V83H15z"/></a></a><path d="M50 50H55V45H50z"/></svg>`)
+}
+
+func TestSVGZ(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	for i := 0; i < 100; i++ {
+		ctx.DrawPath(float64(i), 0.0, canvas.Rectangle(0.5, 10.0))
+	}
+	ctx.StartLink("https://example.com", 0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	svg, svgz := &bytes.Buffer{}, &bytes.Buffer{}
+	test.Error(t, Writer(svg, c))
+	test.Error(t, SVGZWriter(svgz, c))
+	test.That(t, svgz.Len() < svg.Len()/5, svgz.Len(), svg.Len())
+
+	r, err := gzip.NewReader(svgz)
+	test.Error(t, err)
+	b, err := ioutil.ReadAll(r)
+	test.Error(t, err)
+	test.String(t, string(b), svg.String())
+}
+
+func TestSVGDeduplication(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(0.1)
+	marker := canvas.StarPolygon(5, 0.5, 0.2, true)
+	for i := 0; i < 5000; i++ {
+		x := float64(i%100) + 0.5
+		y := float64((i*37)%100) + 0.5
+		if i%2 == 0 {
+			ctx.SetFillColor(canvas.Red)
+		} else {
+			ctx.SetFillColor(canvas.Blue)
+		}
+		ctx.DrawPath(x, y, marker)
+	}
+	ctx.SetFillColor(canvas.Green)
+	ctx.DrawPath(10.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	ctx.DrawPath(20.0, 10.0, canvas.Rectangle(5.0, 5.0))
+
+	buf, bufDedup := &bytes.Buffer{}, &bytes.Buffer{}
+	svg := New(buf, c.W, c.H)
+	svg.DeduplicatePaths(0)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	test.Error(t, Writer(bufDedup, c))
+	test.That(t, bufDedup.Len() < buf.Len()/2, bufDedup.Len(), buf.Len())
+	test.T(t, strings.Count(bufDedup.String(), "<defs>"), 1) // the rectangles are too small
+	test.T(t, strings.Count(bufDedup.String(), "<use "), 4999)
+
+	c1, warnings, err := canvas.ParseSVGFile(buf)
+	test.Error(t, err)
+	test.T(t, len(warnings), 0)
+	c2, warnings, err := canvas.ParseSVGFile(bufDedup)
+	test.Error(t, err)
+	test.T(t, len(warnings), 0)
+
+	img := rasterizer.Draw(c1, 4.0)
+	img2 := rasterizer.Draw(c2, 4.0)
+	diff := 0
+	for i := range img.Pix {
+		if d := int(img.Pix[i]) - int(img2.Pix[i]); d < -2 || 2 < d {
+			diff++
+		}
+	}
+	test.T(t, diff, 0)
+}
+
+func TestSVGGroups(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.StartGroup("background", "")
+	ctx.SetFillColor(canvas.Gray)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(100.0, 100.0))
+	ctx.EndGroup()
+	ctx.StartGroup("data", "chart <1>")
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(10.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	ctx.StartGroup("", "markers")
+	ctx.DrawPath(20.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	ctx.DrawPath(30.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	ctx.EndGroup()
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(40.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	ctx.EndGroup()
+	ctx.StartGroup("open", "")
+	ctx.DrawPath(50.0, 10.0, canvas.Rectangle(5.0, 5.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+
+	type element struct {
+		XMLName  xml.Name
+		ID       string    `xml:"id,attr"`
+		Class    string    `xml:"class,attr"`
+		Fill     string    `xml:"fill,attr"`
+		Children []element `xml:",any"`
+	}
+	root := element{}
+	test.Error(t, xml.Unmarshal(buf.Bytes(), &root))
+	test.T(t, len(root.Children), 3)
+
+	background := root.Children[0]
+	test.T(t, background.XMLName.Local, "g")
+	test.T(t, background.ID, "background")
+	test.T(t, background.Fill, "#808080")
+	test.T(t, len(background.Children), 1)
+	test.T(t, background.Children[0].Fill, "")
+
+	data := root.Children[1]
+	test.T(t, data.ID, "data")
+	test.T(t, data.Class, "chart <1>")
+	test.T(t, data.Fill, "") // the last child is blue
+	test.T(t, len(data.Children), 3)
+	test.T(t, data.Children[0].Fill, "#f00")
+	markers := data.Children[1]
+	test.T(t, markers.XMLName.Local, "g")
+	test.T(t, markers.Class, "markers")
+	test.T(t, markers.Fill, "#f00")
+	test.T(t, len(markers.Children), 2)
+	test.T(t, markers.Children[0].Fill, "")
+	test.T(t, markers.Children[1].Fill, "")
+	test.T(t, data.Children[2].Fill, "#00f")
+
+	// groups are closed by Close
+	test.T(t, root.Children[2].ID, "open")
+	test.T(t, root.Children[2].Fill, "#00f")
+}
+
+func TestSVGLayers(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	data := c.Layer("data")
+	grid := c.Layer("grid")
+	grid.SetZIndex(data.ZIndex() - 1)
+	data.SetFillColor(canvas.Red)
+	data.DrawPath(10.0, 10.0, canvas.Rectangle(5.0, 5.0))
+	grid.SetFillColor(canvas.Gray)
+	grid.DrawPath(0.0, 0.0, canvas.Rectangle(100.0, 100.0))
+	debug := c.Layer("debug")
+	debug.SetFillColor(canvas.Blue)
+	debug.DrawPath(20.0, 20.0, canvas.Rectangle(5.0, 5.0))
+	debug.SetVisible(false)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+
+	type element struct {
+		XMLName  xml.Name
+		ID       string    `xml:"id,attr"`
+		Fill     string    `xml:"fill,attr"`
+		Children []element `xml:",any"`
+	}
+	root := element{}
+	test.Error(t, xml.Unmarshal(buf.Bytes(), &root))
+	test.T(t, len(root.Children), 2)
+	test.T(t, root.Children[0].XMLName.Local, "g")
+	test.T(t, root.Children[0].ID, "grid")
+	test.T(t, root.Children[0].Fill, "#808080")
+	test.T(t, root.Children[1].XMLName.Local, "g")
+	test.T(t, root.Children[1].ID, "data")
+	test.T(t, root.Children[1].Fill, "#f00")
+	test.That(t, !strings.Contains(buf.String(), "debug"))
+	test.That(t, !strings.Contains(buf.String(), "#00f"))
+}
+
+func TestSVGBackground(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(5.0, 5.0))
+	c.SetBackground(canvas.White)
+	c.Fit(1.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.String(t, buf.String(), `<svg version="1.1" width="7mm" height="7mm" viewBox="0 0 7 7" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><path d="M0 7H7V0H0z" fill="#fff"/><path d="M1 6H6V1H1z" fill="#f00"/></svg>`)
+}
+
+// drawSVGText draws the text elements of an SVG document onto the canvas using the glyph outlines of the font face, like a viewer would.
+func drawSVGText(t *testing.T, c *canvas.Canvas, svg string, face canvas.FontFace) {
+	reText := regexp.MustCompile(`<text (?:x="[^"]*" y="[^"]*"|transform="([^"]*)")[^>]*>(.*?)</text>`)
+	reTspan := regexp.MustCompile(`<tspan x="([^"]*)" y="([^"]*)"[^>]*>(.*?)</tspan>`)
+	for _, text := range reText.FindAllStringSubmatch(svg, -1) {
+		m := canvas.Identity
+		if text[1] != "" {
+			var err error
+			m, err = canvas.ParseSVGTransform(text[1])
+			test.Error(t, err)
+		}
+		for _, tspan := range reTspan.FindAllStringSubmatch(text[2], -1) {
+			x, _ := strconv.ParseFloat(tspan[1], 64)
+			y, _ := strconv.ParseFloat(tspan[2], 64)
+			p, _ := face.ToPath(html.UnescapeString(tspan[3]))
+			p = p.Transform(canvas.Identity.ReflectYAbout(c.H/2.0).Mul(m).Translate(x, y).Scale(1.0, -1.0))
+			c.RenderPath(p, canvas.DefaultStyle, canvas.Identity)
+		}
+	}
+}
+
+func TestSVGTextElements(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 60.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(5.0, 50.0, canvas.NewTextLine(face, "Hello & <world>\nSecond line", canvas.Left))
+	ctx.Translate(50.0, 10.0)
+	ctx.Rotate(30.0)
+	ctx.Scale(1.5, 1.5)
+	ctx.DrawText(0.0, 0.0, canvas.NewTextLine(face, "Rotated", canvas.Left))
+
+	bufText, bufPaths := &bytes.Buffer{}, &bytes.Buffer{}
+	svg := New(bufText, c.W, c.H)
+	svg.EmbedFonts(false)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	svg = New(bufPaths, c.W, c.H)
+	svg.TextAsPaths(true)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+
+	out := bufText.String()
+	test.T(t, strings.Count(out, "<text "), 2)
+	test.T(t, strings.Count(out, "<path "), 0)
+	test.That(t, strings.Contains(out, `<text x="5" y="10" style="font: 4.23333333px dejavu-serif"><tspan x="5" y="10">Hello &amp; &lt;world&gt;</tspan><tspan x="5" y="`), out)
+	test.That(t, strings.Contains(out, `>Second line</tspan></text>`), out)
+	test.That(t, strings.Contains(out, `<text transform="translate(50,50) rotate(-15) scale(1.5,1.5) rotate(-15)"`), out)
+	test.T(t, strings.Count(bufPaths.String(), "<text "), 0)
+
+	// the text elements are positioned the same as the glyph outlines
+	c1, _, err := canvas.ParseSVGFile(bufPaths)
+	test.Error(t, err)
+	c2 := canvas.New(c.W, c.H)
+	drawSVGText(t, c2, out, face)
+	img := rasterizer.Draw(c1, 10.0)
+	img2 := rasterizer.Draw(c2, 10.0)
+	diff := 0
+	for i := range img.Pix {
+		if d := int(img.Pix[i]) - int(img2.Pix[i]); d < -8 || 8 < d {
+			diff++
+		}
+	}
+	test.That(t, diff < 10, diff, "channels differ")
+
+	// synthesized styles are written as outlines
+	italic := family.Face(12.0, canvas.Black, canvas.FontItalic, canvas.FontNormal)
+	c = canvas.New(100.0, 60.0)
+	ctx = canvas.NewContext(c)
+	ctx.DrawText(5.0, 50.0, canvas.NewTextLine(italic, "Italic", canvas.Left))
+	bufText.Reset()
+	test.Error(t, Writer(bufText, c))
+	test.T(t, strings.Count(bufText.String(), "<text "), 0)
+	test.T(t, strings.Count(bufText.String(), "<path "), 1)
+}
+
+func TestSVGEmbedFonts(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 60.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(5.0, 50.0, canvas.NewTextLine(face, "Hello world", canvas.Left))
+	ctx.DrawText(5.0, 30.0, canvas.NewTextLine(face, "Café", canvas.Left))
+
+	bufSubset, bufFull := &bytes.Buffer{}, &bytes.Buffer{}
+	svg := New(bufSubset, c.W, c.H)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	svg = New(bufFull, c.W, c.H)
+	svg.SubsetFonts(false)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+
+	reFont := regexp.MustCompile(`<style>\n@font-face{font-family:'([^']+)';src:url\('data:font/truetype;base64,([^']+)'\);}\n</style>`)
+	matches := reFont.FindStringSubmatch(bufSubset.String())
+	test.That(t, matches != nil, "style element with the embedded font is missing")
+	test.T(t, matches[1], face.Name())
+	test.That(t, strings.Contains(bufSubset.String(), `px `+matches[1]+`"`), "font family of the text elements must match the embedded font")
+	test.That(t, strings.HasSuffix(bufSubset.String(), "</style></svg>"))
+
+	b, err := base64.StdEncoding.DecodeString(matches[2])
+	test.Error(t, err)
+	sfnt, err := canvasFont.ParseSFNT(b)
+	test.Error(t, err)
+	for _, r := range "Helo wrdCafé" {
+		test.That(t, sfnt.GlyphIndex(r) != 0, "missing glyph for", string(r))
+	}
+	test.T(t, sfnt.GlyphIndex('x'), uint16(0))
+
+	_, raw := face.Font.Raw()
+	test.That(t, bufSubset.Len() < len(raw)/20, bufSubset.Len(), len(raw))
+	test.That(t, len(raw) < bufFull.Len(), bufFull.Len(), len(raw))
+	test.T(t, strings.Count(bufFull.String(), "@font-face"), 1)
+
+	// fonts are not embedded when disabled
+	bufFull.Reset()
+	svg = New(bufFull, c.W, c.H)
+	svg.EmbedFonts(false)
+	c.Render(svg)
+	test.Error(t, svg.Close())
+	test.T(t, strings.Count(bufFull.String(), "<style>"), 0)
+}
+
+func TestSVGTitle(t *testing.T) {
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetTitle("Sales & <profit> in €", "Quarterly résumé")
+	ctx.StartGroup("bars", "")
+	ctx.SetTitle("Bars", "")
+	ctx.DrawPath(10.0, 10.0, canvas.Rectangle(5.0, 20.0))
+	ctx.DrawPath(20.0, 10.0, canvas.Rectangle(5.0, 30.0))
+	ctx.EndGroup()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.HasPrefix(out, `<svg version="1.1" width="100mm" height="100mm" viewBox="0 0 100 100" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" role="img" aria-labelledby="svg-title" aria-describedby="svg-desc"><title id="svg-title">Sales &amp; &lt;profit&gt; in €</title><desc id="svg-desc">Quarterly résumé</desc><g id="bars"`), out)
+
+	type element struct {
+		ID       string    `xml:"id,attr"`
+		Title    string    `xml:"title"`
+		Desc     string    `xml:"desc"`
+		Children []element `xml:"g"`
+	}
+	var root struct {
+		element
+		Role       string `xml:"role,attr"`
+		LabelledBy string `xml:"aria-labelledby,attr"`
+	}
+	test.Error(t, xml.Unmarshal(buf.Bytes(), &root))
+	test.T(t, root.Role, "img")
+	test.T(t, root.LabelledBy, "svg-title")
+	test.T(t, root.Title, "Sales & <profit> in €")
+	test.T(t, root.Desc, "Quarterly résumé")
+	test.T(t, len(root.Children), 1)
+	test.T(t, root.Children[0].ID, "bars")
+	test.T(t, root.Children[0].Title, "Bars")
+
+	// without a title the root element is not labelled
+	buf.Reset()
+	svg := New(buf, 10.0, 10.0)
+	test.Error(t, svg.Close())
+	test.String(t, buf.String(), `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"></svg>`)
+}
+
+func TestSVGDeterministic(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(100.0, 100.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetTitle("Chart", "Deterministic output")
+	ctx.StartGroup("shapes", "layer")
+	ctx.SetFillColor(color.RGBA{0, 0, 128, 128})
+	ctx.SetStrokeColor(canvas.Red)
+	ctx.SetStrokeWidth(1.0 / 3.0)
+	ctx.SetDashes(0.1, 2.0/3.0, 1.0/7.0)
+	for i := 0; i < 5; i++ {
+		ctx.DrawPath(10.0+float64(i)*10.0/3.0, 20.0, canvas.StarPolygon(5, 4.0, 2.0, true))
+	}
+	ctx.EndGroup()
+	ctx.Rotate(10.0 / 3.0)
+	ctx.DrawText(10.0, 80.0, canvas.NewTextLine(face, "Deterministic", canvas.Left))
+	ctx.DrawPath(30.0, 50.0, canvas.Circle(100.0/7.0))
+
+	render := func(precision int) string {
+		buf := &bytes.Buffer{}
+		svg := New(buf, c.W, c.H)
+		svg.SetPrecision(precision)
+		c.Render(svg)
+		test.Error(t, svg.Close())
+		return buf.String()
+	}
+
+	out := render(canvas.Precision)
+	for i := 0; i < 10; i++ {
+		test.String(t, render(canvas.Precision), out)
+	}
+
+	out = render(2)
+	test.String(t, render(2), out)
+	withoutFonts := regexp.MustCompile(`base64,[^']*`).ReplaceAllString(out, "")
+	test.That(t, !regexp.MustCompile(`\.[0-9]{3}`).MatchString(withoutFonts), withoutFonts)
+	test.That(t, strings.Contains(out, `<text transform="translate(5.33,19.55) rotate(-3.33)" style="font: 4.23px dejavu-serif">`), out)
+	test.That(t, strings.Contains(out, `style="fill:rgba(0,0,255,.5);stroke:#f00;stroke-width:.33;stroke-miterlimit:2;stroke-dasharray:.67 .14;stroke-dashoffset:.1"`), out)
+}
+
+func TestSVGLineStyle(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(2.0)
+	polyline := canvas.MustParseSVG("M0 0L10 0L0 5")
+	ctx.DrawPath(0.0, 0.0, polyline)
+	ctx.SetMiterLimit(3.0)
+	ctx.DrawPath(0.0, 10.0, polyline)
+	ctx.SetStrokeCapper(canvas.RoundCap)
+	ctx.SetStrokeJoiner(canvas.RoundJoin)
+	ctx.DrawPath(0.0, 20.0, polyline)
+	ctx.SetStrokeCapper(canvas.SquareCap)
+	ctx.SetStrokeJoiner(canvas.BevelJoin)
+	ctx.DrawPath(0.0, 30.0, polyline)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<path d="M0 50H10L0 45" style="fill:none;stroke:#000;stroke-width:2;stroke-miterlimit:2"/>`), out)
+	test.That(t, strings.Contains(out, `<path d="M0 40H10L0 35" style="fill:none;stroke:#000;stroke-width:2;stroke-miterlimit:3"/>`), out)
+	test.That(t, strings.Contains(out, `<path d="M0 30H10L0 25" style="fill:none;stroke:#000;stroke-width:2;stroke-linecap:round;stroke-linejoin:round"/>`), out)
+	test.That(t, strings.Contains(out, `<path d="M0 20H10L0 15" style="fill:none;stroke:#000;stroke-width:2;stroke-linecap:square;stroke-linejoin:bevel"/>`), out)
+}
+
+func TestSVGOpacity(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.SetStrokeColor(canvas.Blue)
+	ctx.SetFillOpacity(0.5)
+	ctx.Push()
+	ctx.SetGlobalAlpha(0.5)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.Pop()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.That(t, strings.Contains(buf.String(), `<path d="M0 50H10V40H0z" style="fill:rgba(255,0,0,.25098039);stroke:rgba(0,0,255,.50196078);stroke-miterlimit:2"/>`), buf.String())
+}
+
+func TestSVGBlendMode(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.Push()
+	ctx.SetBlendMode(canvas.BlendMultiply)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(5.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.SetStrokeColor(canvas.Blue)
+	ctx.DrawPath(20.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.Pop()
+	ctx.DrawPath(40.0, 0.0, canvas.Rectangle(10.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.That(t, strings.Contains(buf.String(), `<path d="M5 50H15V40H5z" fill="#f00" style="mix-blend-mode:multiply"/>`), buf.String())
+	test.That(t, strings.Contains(buf.String(), `<path d="M20 50H30V40H20z" style="fill:#f00;stroke:#00f;stroke-miterlimit:2;mix-blend-mode:multiply"/>`), buf.String())
+	test.That(t, strings.Contains(buf.String(), `<path d="M40 50H50V40H40z"/>`), buf.String())
+
+	// blend modes are not hoisted onto groups
+	c = canvas.New(100.0, 50.0)
+	ctx = canvas.NewContext(c)
+	ctx.StartGroup("g", "")
+	ctx.SetBlendMode(canvas.BlendScreen)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.DrawPath(20.0, 0.0, canvas.Rectangle(10.0, 10.0))
+	ctx.EndGroup()
+
+	buf.Reset()
+	test.Error(t, Writer(buf, c))
+	test.That(t, strings.Contains(buf.String(), `<g id="g"><path d="M0 50H10V40H0z" style="mix-blend-mode:screen"/>`), buf.String())
+}
+
+func TestSVGImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 0, canvas.Red)
+	var jpg bytes.Buffer
+	test.Error(t, jpeg.Encode(&jpg, img, nil))
+	jpgImg, err := canvas.NewJPEGImage(jpg.Bytes())
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawImageTransformed(img, canvas.Identity.Translate(10.0, 20.0).Rotate(90.0))
+	ctx.DrawImage(30.0, 20.0, jpgImg, 1.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	test.That(t, strings.Contains(buf.String(), `<image transform="translate(8,30) rotate(-90)" width="2" height="2" xlink:href="data:image/png;base64,`), buf.String())
+
+	// JPEG data is embedded untouched
+	test.That(t, strings.Contains(buf.String(), `<image transform="translate(30,28)" width="2" height="2" xlink:href="data:image/jpeg;base64,`+base64.StdEncoding.EncodeToString(jpg.Bytes())+`"/>`), buf.String())
+}
+
+func TestSVGViewHelpers(t *testing.T) {
+	mark := canvas.Rectangle(4.0, 2.0)
+	draw := func(transform func(ctx *canvas.Context)) string {
+		c := canvas.New(100.0, 50.0)
+		ctx := canvas.NewContext(c)
+		ctx.Translate(10.0, 5.0)
+		ctx.Push()
+		transform(ctx)
+		ctx.DrawPath(20.0, 10.0, mark)
+		ctx.Pop()
+		test.T(t, ctx.View(), canvas.Identity.Translate(10.0, 5.0))
+		ctx.DrawPath(20.0, 10.0, mark)
+
+		buf := &bytes.Buffer{}
+		test.Error(t, Writer(buf, c))
+		return buf.String()
+	}
+
+	var tests = []struct {
+		name    string
+		helpers func(ctx *canvas.Context)
+		view    canvas.Matrix
+	}{
+		{"Translate", func(ctx *canvas.Context) { ctx.Translate(3.0, 4.0) }, canvas.Identity.Translate(3.0, 4.0)},
+		{"Rotate", func(ctx *canvas.Context) { ctx.Rotate(30.0) }, canvas.Identity.Rotate(30.0)},
+		{"RotateAbout", func(ctx *canvas.Context) { ctx.RotateAbout(30.0, 22.0, 11.0) }, canvas.Identity.Translate(22.0, 11.0).Rotate(30.0).Translate(-22.0, -11.0)},
+		{"Scale", func(ctx *canvas.Context) { ctx.Scale(2.0, 0.5) }, canvas.Identity.Scale(2.0, 0.5)},
+		{"ScaleAbout", func(ctx *canvas.Context) { ctx.ScaleAbout(2.0, 0.5, 22.0, 11.0) }, canvas.Identity.Translate(22.0, 11.0).Scale(2.0, 0.5).Translate(-22.0, -11.0)},
+		{"Shear", func(ctx *canvas.Context) { ctx.Shear(0.5, 0.0) }, canvas.Identity.Shear(0.5, 0.0)},
+		{"ShearAbout", func(ctx *canvas.Context) { ctx.ShearAbout(0.5, 0.0, 22.0, 11.0) }, canvas.Identity.Translate(22.0, 11.0).Shear(0.5, 0.0).Translate(-22.0, -11.0)},
+		{"Composed", func(ctx *canvas.Context) {
+			ctx.RotateAbout(45.0, 22.0, 11.0)
+			ctx.Scale(1.5, 1.5)
+		}, canvas.Identity.Translate(22.0, 11.0).Rotate(45.0).Translate(-22.0, -11.0).Scale(1.5, 1.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := tt.view
+			test.String(t, draw(tt.helpers), draw(func(ctx *canvas.Context) { ctx.ComposeView(view) }))
+		})
+	}
+}
+
+func TestSVGBlur(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.StartBlur(1.5)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.DrawPath(40.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.EndBlur()
+	ctx.StartBlur(0.0)
+	ctx.DrawPath(70.0, 20.0, canvas.Rectangle(20.0, 10.0))
+	ctx.EndBlur()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<g filter="url(#f0)"><defs><filter id="f0" filterUnits="userSpaceOnUse" x="0" y="0" width="100" height="50"><feGaussianBlur stdDeviation="1.5"/></filter></defs><path`), out)
+	test.T(t, strings.Count(out, "<filter"), 1)
+	test.T(t, strings.Count(out, "</g>"), 2)
+}
+
+func TestSVGShadow(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.SetShadow(1.0, -1.0, 0.5, canvas.Black)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<g filter="url(#f0)"><defs><filter id="f0" filterUnits="userSpaceOnUse" x="0" y="0" width="100" height="50"><feGaussianBlur stdDeviation=".5"/></filter></defs><path d="M11 31H31V21H11z"/></g><path d="M10 30H30V20H10z" fill="#f00"/>`), out)
+}
+
+func TestSVGRecorder(t *testing.T) {
+	draw := func(r canvas.Renderer) {
+		ctx := canvas.NewContext(r)
+		ctx.SetFillColor(canvas.Red)
+		ctx.SetStrokeColor(canvas.Blue)
+		ctx.SetDashes(0.5, 1.0, 2.0)
+		ctx.DrawPath(10.0, 20.0, canvas.Circle(5.0))
+		ctx.StartGroup("bars", "")
+		ctx.DrawPath(30.0, 20.0, canvas.Rectangle(5.0, 20.0))
+		ctx.EndGroup()
+	}
+
+	direct := &bytes.Buffer{}
+	svg := New(direct, 100.0, 50.0)
+	draw(svg)
+	test.Error(t, svg.Close())
+
+	rec := canvas.NewRecorder(100.0, 50.0)
+	draw(rec)
+	replayed := &bytes.Buffer{}
+	svg = New(replayed, 100.0, 50.0)
+	rec.Replay(svg)
+	test.Error(t, svg.Close())
+	test.String(t, replayed.String(), direct.String())
+}
+
+func TestSVGRecorderBinary(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	test.Error(t, family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular))
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	underlined := family.Face(12.0, canvas.Red, canvas.FontItalic, canvas.FontNormal, canvas.FontUnderline)
+
+	rec := canvas.NewRecorder(100.0, 60.0)
+	ctx := canvas.NewContext(rec)
+	ctx.SetTitle("Chart", "All record types")
+	ctx.StartGroup("layer", "data")
+	ctx.SetFillColor(color.CMYK{0, 100, 100, 0})
+	ctx.SetStrokeColor(canvas.SpotColor{"PANTONE 186 C", color.CMYK{0, 100, 81, 4}, 0.5})
+	ctx.SetStrokeJoiner(canvas.MiterClipJoin(canvas.RoundJoin, 4.0))
+	ctx.SetStrokeCapper(canvas.RoundCap)
+	ctx.SetDashes(0.5, 2.0, 1.0)
+	ctx.SetFillRule(canvas.EvenOdd)
+	ctx.DrawPath(10.0, 10.0, canvas.Circle(5.0))
+	ctx.EndGroup()
+	ctx.ResetStyle()
+	ctx.StartLink("https://example.com", 20.0, 30.0, canvas.Rectangle(20.0, 5.0))
+	ctx.SetShadow(0.5, -0.5, 0.3, color.RGBA{0, 0, 0, 128})
+	ctx.DrawText(20.0, 30.0, canvas.NewRichText().Add(face, "Recorded ").Add(underlined, "text").ToText(60.0, 20.0, canvas.Left, canvas.Top, 0.0, 0.0))
+	ctx.EndLink()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 0, canvas.Green)
+	ctx.DrawImage(80.0, 40.0, img, 1.0)
+
+	render := func(r canvas.Renderer) string {
+		buf := &bytes.Buffer{}
+		svg := New(buf, 100.0, 60.0)
+		r.(*canvas.Recorder).Replay(svg)
+		test.Error(t, svg.Close())
+		return buf.String()
+	}
+	out := render(rec)
+	for _, elem := range []string{"<title", "<g id=\"layer\"", "<a ", "feGaussianBlur", "<image"} {
+		test.That(t, strings.Contains(out, elem), elem, out)
+	}
+
+	// fonts referenced by name
+	b, err := rec.MarshalBinary()
+	test.Error(t, err)
+	rec2 := canvas.NewRecorder(0.0, 0.0)
+	test.That(t, rec2.UnmarshalBinary(b) != nil, "font family must be added")
+	rec2.AddFontFamily(family)
+	test.Error(t, rec2.UnmarshalBinary(b))
+	test.T(t, rec2.W, 100.0)
+	test.T(t, rec2.H, 60.0)
+	test.String(t, render(rec2), out)
+
+	// embedded fonts
+	rec.EmbedFonts(true)
+	b2, err := rec.MarshalBinary()
+	test.Error(t, err)
+	test.That(t, len(b) < len(b2))
+	rec3 := canvas.NewRecorder(0.0, 0.0)
+	test.Error(t, rec3.UnmarshalBinary(b2))
+	test.String(t, render(rec3), out)
+}
+
+func TestSVGLinearGradient(t *testing.T) {
+	g, err := canvas.NewLinearGradient(0.0, 0.0, 20.0, 0.0, canvas.ReflectSpread, canvas.Stop{0.0, canvas.Black}, canvas.Stop{1.0, color.RGBA{0, 0, 128, 128}})
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<defs><linearGradient id="g0" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="20" y2="0" spreadMethod="reflect" gradientTransform="matrix(1 0 0 -1 10 30)"><stop offset="0" stop-color="#000"/><stop offset="1" stop-color="#00f" stop-opacity=".50196078"/></linearGradient></defs><path d="M10 30H30V20H10z" fill="url(#g0)"/>`), out)
+}
+
+func TestSVGRadialGradient(t *testing.T) {
+	g, err := canvas.NewRadialGradient(10.0, 5.0, 10.0, 12.0, 5.0, canvas.PadSpread, canvas.Stop{0.0, canvas.White}, canvas.Stop{1.0, canvas.Black})
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(g)
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<defs><radialGradient id="g0" gradientUnits="userSpaceOnUse" cx="10" cy="5" r="10" fx="12" fy="5" gradientTransform="matrix(1 0 0 -1 10 30)"><stop offset="0" stop-color="#fff"/><stop offset="1" stop-color="#000"/></radialGradient></defs><path d="M10 30H30V20H10z" fill="url(#g0)"/>`), out)
+}
+
+func TestSVGAlongPathGradient(t *testing.T) {
+	g, err := canvas.NewAlongPathGradient(canvas.Stop{0.0, canvas.Black}, canvas.Stop{1.0, color.RGBA{0, 0, 8, 255}})
+	test.Error(t, err)
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokePaint(g)
+	ctx.MoveTo(10.0, 10.0)
+	ctx.LineTo(30.0, 10.0)
+	ctx.Stroke()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<path d="M10 40H30" style="fill:none;stroke:#000006;stroke-miterlimit:2"/><path d="M10 40H20" style="fill:none;stroke:#000002;stroke-miterlimit:2"/>`), out)
+}
+
+func TestSVGImagePattern(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.ImagePattern{img, canvas.Identity.Scale(5.0, 5.0), canvas.RepeatX})
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<defs><pattern id="g0" patternUnits="userSpaceOnUse" width="2" height="1000000" patternTransform="matrix(5 0 0 5 10 20)"><image width="2" height="2" xlink:href="data:image/png;base64,`), out)
+	test.That(t, strings.Contains(out, `"/></pattern></defs><path d="M10 30H30V20H10z" fill="url(#g0)"/>`), out)
+}
+
+func TestSVGPathPattern(t *testing.T) {
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Black
+
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillPaint(canvas.PathPattern{canvas.Circle(1.0), 4.0, 4.0, style, canvas.Identity})
+	ctx.DrawPath(10.0, 20.0, canvas.Rectangle(20.0, 10.0))
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	out := buf.String()
+	test.That(t, strings.Contains(out, `<defs><pattern id="g0" patternUnits="userSpaceOnUse" width="4" height="4" patternTransform="matrix(1 0 0 -1 10 30)"><path d="M1 0A1 1 0 01-1 0A1 1 0 011 0z"/>`), out)
+	test.That(t, strings.Contains(out, `</pattern></defs><path d="M10 30H30V20H10z" fill="url(#g0)"/>`), out)
+
+	// a motif on the corner of the cell is drawn once for each cell it overlaps
+	pattern := out[strings.Index(out, "<pattern"):strings.Index(out, "</pattern>")]
+	test.T(t, strings.Count(pattern, "<path "), 4)
+}
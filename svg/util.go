@@ -1,37 +1,71 @@
 package svg
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
-	"math"
+	"image/color"
+	"strconv"
 	"strings"
 
 	"github.com/tdewolff/canvas"
-	"github.com/tdewolff/minify/v2"
 )
 
 ////////////////////////////////////////////////////////////////
 
-type num float64
+// num formats a number with at most the precision of the renderer in decimals. All numbers in the output are formatted by num so that it only depends on the precision.
+func (r *SVG) num(f float64) string {
+	b := strconv.AppendFloat(nil, f, 'f', r.precision, 64)
+	if bytes.IndexByte(b, '.') != -1 {
+		b = bytes.TrimRight(b, "0")
+		b = bytes.TrimSuffix(b, []byte("."))
+	}
+	if len(b) == 2 && b[0] == '-' && b[1] == '0' {
+		b = b[1:]
+	} else if 2 < len(b) && b[0] == '0' && b[1] == '.' {
+		b = b[1:]
+	} else if 3 < len(b) && b[0] == '-' && b[1] == '0' && b[2] == '.' {
+		b = append([]byte{'-'}, b[2:]...)
+	}
+	return string(b)
+}
 
-func (f num) String() string {
-	s := fmt.Sprintf("%.*g", canvas.Precision, f)
-	if num(math.MaxInt32) < f || f < num(math.MinInt32) {
-		if i := strings.IndexAny(s, ".eE"); i == -1 {
-			s += ".0"
-		}
+// color formats a color as a CSS color, using rgba() for translucent colors.
+func (r *SVG) color(c color.RGBA) string {
+	if c.A == 255 || c.A == 0 {
+		return canvas.CSSColor(c).String()
 	}
-	return string(minify.Number([]byte(s), canvas.Precision))
+	a := float64(c.A) / 255.0
+	return fmt.Sprintf("rgba(%d,%d,%d,%v)", int(float64(c.R)/a), int(float64(c.G)/a), int(float64(c.B)/a), r.num(a))
 }
 
-type dec float64
+// transform formats an affine transformation matrix as an SVG transform, where the y-axis is flipped for the height of the document.
+func (r *SVG) transform(m canvas.Matrix) string {
+	tx, ty, theta, sx, sy, phi := m.Decompose()
+
+	s := &strings.Builder{}
+	if !canvas.Equal(m[0][2], 0.0) || !canvas.Equal(m[1][2], 0.0) {
+		fmt.Fprintf(s, " translate(%v,%v)", r.num(tx), r.num(r.height-ty))
+	}
+	if !canvas.Equal(theta, 0.0) {
+		fmt.Fprintf(s, " rotate(%v)", r.num(theta))
+	}
+	if !canvas.Equal(sx, 1.0) || !canvas.Equal(sy, 1.0) {
+		fmt.Fprintf(s, " scale(%v,%v)", r.num(sx), r.num(sy))
+	}
+	if !canvas.Equal(phi, 0.0) {
+		fmt.Fprintf(s, " rotate(%v)", r.num(phi))
+	}
 
-func (f dec) String() string {
-	s := fmt.Sprintf("%.*f", canvas.Precision, f)
-	s = string(minify.Decimal([]byte(s), canvas.Precision))
-	if dec(math.MaxInt32) < f || f < dec(math.MinInt32) {
-		if i := strings.IndexByte(s, '.'); i == -1 {
-			s += ".0"
-		}
+	if s.Len() == 0 {
+		return ""
 	}
-	return s
+	return s.String()[1:]
+}
+
+// xmlEscape escapes a string for use in XML text or attribute values.
+func xmlEscape(s string) string {
+	buf := &bytes.Buffer{}
+	xml.EscapeText(buf, []byte(s))
+	return buf.String()
 }
@@ -12,3 +12,10 @@ func Writer(w io.Writer, c *canvas.Canvas) error {
 	c.Render(svg)
 	return svg.Close()
 }
+
+// SVGZWriter writes the canvas as a gzip compressed SVG file, as used for .svgz files.
+func SVGZWriter(w io.Writer, c *canvas.Canvas) error {
+	svg := NewSVGZ(w, c.W, c.H)
+	c.Render(svg)
+	return svg.Close()
+}
@@ -0,0 +1,193 @@
+package canvas
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestParseSVGShape(t *testing.T) {
+	var tts = []struct {
+		name  string
+		attrs map[string]string
+		p     *Path
+	}{
+		{"rect", map[string]string{"x": "1", "y": "2", "width": "10", "height": "5"}, MustParseSVG("M1 2H11V7H1z")},
+		{"rect", map[string]string{"width": "10px", "height": " 5 "}, MustParseSVG("M0 0H10V5H0z")},
+		{"rect", map[string]string{"width": "10", "height": "6", "rx": "2"}, MustParseSVG("M2 0H8A2 2 0 0 1 10 2V4A2 2 0 0 1 8 6H2A2 2 0 0 1 0 4V2A2 2 0 0 1 2 0z")},
+		{"rect", map[string]string{"width": "10", "height": "6", "rx": "2", "ry": "1"}, MustParseSVG("M2 0H8A2 1 0 0 1 10 1V5A2 1 0 0 1 8 6H2A2 1 0 0 1 0 5V1A2 1 0 0 1 2 0z")},
+		{"rect", map[string]string{"width": "10", "height": "6", "rx": "auto", "ry": "1"}, MustParseSVG("M1 0H9A1 1 0 0 1 10 1V5A1 1 0 0 1 9 6H1A1 1 0 0 1 0 5V1A1 1 0 0 1 1 0z")},
+		{"rect", map[string]string{"width": "10", "height": "6", "rx": "8"}, MustParseSVG("M5 0A5 3 0 0 1 10 3A5 3 0 0 1 5 6A5 3 0 0 1 0 3A5 3 0 0 1 5 0z")},
+		{"rect", map[string]string{"width": "0", "height": "6"}, &Path{}},
+		{"circle", map[string]string{"cx": "5", "cy": "5", "r": "2"}, MustParseSVG("M7 5A2 2 0 0 1 5 7A2 2 0 0 1 3 5A2 2 0 0 1 5 3A2 2 0 0 1 7 5z")},
+		{"circle", map[string]string{"r": "0"}, &Path{}},
+		{"ellipse", map[string]string{"cx": "5", "cy": "5", "rx": "4", "ry": "2"}, MustParseSVG("M9 5A4 2 0 0 1 5 7A4 2 0 0 1 1 5A4 2 0 0 1 5 3A4 2 0 0 1 9 5z")},
+		{"ellipse", map[string]string{"rx": "2"}, MustParseSVG("M2 0A2 2 0 0 1 0 2A2 2 0 0 1 -2 0A2 2 0 0 1 0 -2A2 2 0 0 1 2 0z")},
+		{"line", map[string]string{"x1": "1", "y1": "2", "x2": "3", "y2": "4"}, MustParseSVG("M1 2L3 4")},
+		{"polyline", map[string]string{"points": "0,0 10,0 10,10"}, MustParseSVG("M0 0H10V10")},
+		{"polyline", map[string]string{"points": " 0 0,10-5 1e1 1e1 "}, MustParseSVG("M0 0L10 -5L10 10")},
+		{"polyline", map[string]string{"points": ""}, &Path{}},
+		{"polygon", map[string]string{"points": "0,0 10,0 10,10"}, MustParseSVG("M0 0H10V10z")},
+		{"path", map[string]string{"d": "M0 0L10 0"}, MustParseSVG("M0 0L10 0")},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseSVGShape(tt.name, tt.attrs)
+			test.Error(t, err)
+			test.T(t, p, tt.p)
+		})
+	}
+}
+
+func TestParseSVGShapeErrors(t *testing.T) {
+	var tts = []struct {
+		name  string
+		attrs map[string]string
+		err   string
+	}{
+		{"rect", map[string]string{"width": "50%", "height": "5"}, "bad shape: percentages are not supported in attribute 'width'"},
+		{"rect", map[string]string{"width": "5mm", "height": "5"}, "bad shape: invalid length '5mm' in attribute 'width'"},
+		{"rect", map[string]string{"width": "-5", "height": "5"}, "bad shape: negative value in attribute 'width'"},
+		{"circle", map[string]string{"r": "-1"}, "bad shape: negative value in attribute 'r'"},
+		{"polygon", map[string]string{"points": "0,0 10"}, "bad points: odd number of coordinates"},
+		{"polygon", map[string]string{"points": "0,0 1x"}, "bad points: unexpected character 'x' at position 6"},
+		{"text", map[string]string{}, "bad shape: unsupported element 'text'"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := ParseSVGShape(tt.name, tt.attrs)
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestParseSVGTransform(t *testing.T) {
+	var tts = []struct {
+		s string
+		m Matrix
+	}{
+		{"", Identity},
+		{"  ", Identity},
+		{"matrix(1 2 3 4 5 6)", Matrix{{1.0, 3.0, 5.0}, {2.0, 4.0, 6.0}}},
+		{"translate(10,20)", Identity.Translate(10.0, 20.0)},
+		{"translate(10)", Identity.Translate(10.0, 0.0)},
+		{"translate( 10 , -20 )", Identity.Translate(10.0, -20.0)},
+		{"scale(2)", Identity.Scale(2.0, 2.0)},
+		{"scale(2,-3)", Identity.Scale(2.0, -3.0)},
+		{"rotate(90)", Matrix{{0.0, -1.0, 0.0}, {1.0, 0.0, 0.0}}},
+		{"rotate(90 10 10)", Matrix{{0.0, -1.0, 20.0}, {1.0, 0.0, 0.0}}},
+		{"skewX(45)", Matrix{{1.0, 1.0, 0.0}, {0.0, 1.0, 0.0}}},
+		{"skewY(45)", Matrix{{1.0, 0.0, 0.0}, {1.0, 1.0, 0.0}}},
+		{"translate(10,20) rotate(45) scale(2)", Identity.Translate(10.0, 20.0).Rotate(45.0).Scale(2.0, 2.0)},
+		{"translate(10,20),scale(2)", Identity.Translate(10.0, 20.0).Scale(2.0, 2.0)},
+		{"translate(10,20)scale(2)", Identity.Translate(10.0, 20.0).Scale(2.0, 2.0)},
+		{"scale(2) translate(10,20)", Matrix{{2.0, 0.0, 20.0}, {0.0, 2.0, 40.0}}},
+		{"translate (1e1 .5)", Identity.Translate(10.0, 0.5)},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			m, err := ParseSVGTransform(tt.s)
+			test.Error(t, err)
+			test.T(t, m, tt.m)
+		})
+	}
+
+	// applied right to left
+	p := MustParseSVG("M1 0L2 0").Transform(MustParseSVGTransform("translate(10,0) scale(2)"))
+	test.T(t, p, MustParseSVG("M12 0L14 0"))
+}
+
+func TestParseSVGTransformErrors(t *testing.T) {
+	var tts = []struct {
+		s   string
+		err string
+	}{
+		{"translate", "bad transform: expected '(' after 'translate' at position 10"},
+		{"translate(10", "bad transform: expected ')' after 'translate'"},
+		{"translate(10 x)", "bad transform: unexpected character 'x' in 'translate' at position 14"},
+		{"translate(1,2,3)", "bad transform: wrong number of arguments for 'translate' at position 1"},
+		{"rotate(1,2)", "bad transform: wrong number of arguments for 'rotate' at position 1"},
+		{"matrix(1,2,3)", "bad transform: wrong number of arguments for 'matrix' at position 1"},
+		{"scale()", "bad transform: wrong number of arguments for 'scale' at position 1"},
+		{"scale(1) skew(10)", "bad transform: unknown function 'skew' at position 10"},
+		{"scale(1) (10)", "bad transform: unexpected character '(' at position 10"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			_, err := ParseSVGTransform(tt.s)
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestParseSVGFile(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="20mm" height="10mm" viewBox="0 0 40 20">
+	<title>Test</title>
+	<defs><linearGradient id="g"/></defs>
+	<rect width="10" height="10" fill="#f00"/>
+	<g transform="translate(20,0)" style="fill:none;stroke:rgba(0,0,255,0.5);stroke-width:2">
+		<path d="M0 0L10 10" stroke-linecap="round"/>
+		<circle cx="5" cy="5" r="5" display="none"/>
+	</g>
+	<line x1="0" y1="20" x2="40" y2="20" fill="none" stroke="url(#g)"/>
+	<text x="0" y="0">text</text>
+	<filter id="f"/>
+</svg>`
+
+	c, warnings, err := ParseSVGFile(strings.NewReader(doc))
+	test.Error(t, err)
+	test.Float(t, c.W, 20.0)
+	test.Float(t, c.H, 10.0)
+	test.T(t, warnings, []string{"unsupported paint 'url(#g)'", "unsupported element 'text'", "unsupported element 'filter'"})
+	test.T(t, len(c.layers), 2) // the line has neither fill nor stroke
+
+	// y-axis is flipped and viewBox is scaled by 0.5
+	test.T(t, c.layers[0].path.Transform(c.layers[0].m), MustParseSVG("M0 10H5V5H0z"))
+	test.T(t, c.layers[0].style.FillColor, Red)
+	test.T(t, c.layers[1].path.Transform(c.layers[1].m), MustParseSVG("M10 10L15 5"))
+	test.T(t, c.layers[1].style.FillColor, Transparent)
+	test.T(t, c.layers[1].style.StrokeColor, color.RGBA{0, 0, 128, 128})
+	test.Float(t, c.layers[1].style.StrokeWidth, 1.0)
+	test.T(t, c.layers[1].style.StrokeCapper, RoundCap)
+}
+
+func TestParseSVGFileUse(t *testing.T) {
+	doc := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="20" height="10">
+	<defs><rect id="r" width="2" height="2" stroke="#00f"/></defs>
+	<use xlink:href="#r" x="5" y="1" fill="#f00"/>
+	<use href="#r" transform="translate(10,0)" fill="#f00" stroke="#0f0"/>
+	<use href="#none"/>
+</svg>`
+
+	c, warnings, err := ParseSVGFile(strings.NewReader(doc))
+	test.Error(t, err)
+	test.T(t, warnings, []string{"unsupported reference '#none'"})
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.layers[0].path.Transform(c.layers[0].m).Bounds(), Rect{5.0 * mmPerPx, 7.0 * mmPerPx, 2.0 * mmPerPx, 2.0 * mmPerPx})
+	test.T(t, c.layers[0].style.FillColor, Red)
+	test.T(t, c.layers[0].style.StrokeColor, Blue) // attributes of the referenced element take precedence
+	test.T(t, c.layers[1].path.Transform(c.layers[1].m).Bounds(), Rect{10.0 * mmPerPx, 8.0 * mmPerPx, 2.0 * mmPerPx, 2.0 * mmPerPx})
+	test.T(t, c.layers[1].style.StrokeColor, Blue)
+}
+
+func TestParseSVGFileErrors(t *testing.T) {
+	var tts = []struct {
+		doc string
+		err string
+	}{
+		{``, "bad svg: missing svg element"},
+		{`<html/>`, "bad svg: root element should be svg instead of 'html'"},
+		{`<svg/>`, "bad svg: missing width and height or viewBox"},
+		{`<svg viewBox="0 0 10"/>`, "bad svg: invalid viewBox '0 0 10'"},
+		{`<svg width="10" height="10"><g>`, "XML syntax error on line 1: unexpected EOF"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.doc, func(t *testing.T) {
+			_, _, err := ParseSVGFile(strings.NewReader(tt.doc))
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -115,7 +115,7 @@ func (r *TeX) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 				fmt.Fprintf(r.w, "\n\\pgfsetroundjoin")
 			} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok && !math.IsNaN(miter.Limit) && miter.GapJoiner == canvas.BevelJoin {
 				fmt.Fprintf(r.w, "\n\\pgfsetmiterjoin")
-				fmt.Fprintf(r.w, "\n\\pgfsetmiterlimit{%vmm}", dec(miter.Limit))
+				fmt.Fprintf(r.w, "\n\\pgfsetmiterlimit{%v}", dec(miter.Limit))
 			} else {
 				panic("TeX: line join not support")
 			}
@@ -422,6 +422,23 @@ func (t *Text) Empty() bool {
 	return true
 }
 
+// withAlpha returns a copy of the text with the opacity of its colors multiplied by alpha.
+func (t *Text) withAlpha(alpha float64) *Text {
+	lines := make([]line, len(t.lines))
+	for i, l := range t.lines {
+		lines[i] = line{make([]TextSpan, len(l.spans)), make([]decoSpan, len(l.decos)), l.y}
+		for j, span := range l.spans {
+			span.Face.Color = scaleAlpha(span.Face.Color, alpha)
+			lines[i].spans[j] = span
+		}
+		for j, deco := range l.decos {
+			deco.face.Color = scaleAlpha(deco.face.Color, alpha)
+			lines[i].decos[j] = deco
+		}
+	}
+	return &Text{lines, t.fonts}
+}
+
 // Height returns the height of the text using the font metrics, this is usually more than the bounds of the glyph outlines.
 func (t *Text) Heights() (float64, float64) {
 	if len(t.lines) == 0 {
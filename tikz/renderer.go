@@ -0,0 +1,299 @@
+package tikz
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// TikZ is a renderer that writes a tikzpicture environment for LaTeX documents, so that text is typeset using the fonts of the document.
+type TikZ struct {
+	w             io.Writer
+	width, height float64
+	unit          string
+	unitPerMm     float64
+	decimals      int
+	textAsPaths   bool
+
+	begun  bool
+	colors map[color.RGBA]string
+}
+
+// New creates a TikZ renderer. By default coordinates are written in centimeters with four decimals, and text is written as nodes.
+func New(w io.Writer, width, height float64) *TikZ {
+	return &TikZ{
+		w:         w,
+		width:     width,
+		height:    height,
+		unit:      "cm",
+		unitPerMm: 0.1,
+		decimals:  4,
+		colors:    map[color.RGBA]string{},
+	}
+}
+
+// SetUnit sets the unit of the coordinates, which must be either "cm" or "pt" (TeX points), and the number of decimals they are rounded to. It must be called before rendering.
+func (r *TikZ) SetUnit(unit string, decimals int) {
+	switch unit {
+	case "cm":
+		r.unitPerMm = 0.1
+	case "pt":
+		r.unitPerMm = 72.27 / 25.4
+	default:
+		panic("TikZ: unit must be cm or pt")
+	}
+	r.unit = unit
+	r.decimals = decimals
+}
+
+// TextAsPaths writes text as filled glyph outlines instead of nodes, so that the output does not depend on the fonts of the document.
+func (r *TikZ) TextAsPaths(textAsPaths bool) {
+	r.textAsPaths = textAsPaths
+}
+
+// begin writes the start of the tikzpicture environment, including a bounding box of the size of the canvas.
+func (r *TikZ) begin() {
+	if !r.begun {
+		fmt.Fprintf(r.w, "\\begin{tikzpicture}[x=1%s,y=1%s]\n", r.unit, r.unit)
+		fmt.Fprintf(r.w, "\\useasboundingbox (0,0) rectangle (%s,%s);\n", r.num(r.width), r.num(r.height))
+		r.begun = true
+	}
+}
+
+func (r *TikZ) Close() error {
+	r.begin()
+	_, err := fmt.Fprintf(r.w, "\\end{tikzpicture}\n")
+	return err
+}
+
+func (r *TikZ) Size() (float64, float64) {
+	return r.width, r.height
+}
+
+// num formats a length in millimeters in the output unit.
+func (r *TikZ) num(f float64) string {
+	s := strconv.FormatFloat(f*r.unitPerMm, 'f', r.decimals, 64)
+	if strings.IndexByte(s, '.') != -1 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func (r *TikZ) point(p canvas.Point) string {
+	return "(" + r.num(p.X) + "," + r.num(p.Y) + ")"
+}
+
+// getColor returns the name of the color, defining it first if it is used for the first time. The alpha channel is ignored.
+func (r *TikZ) getColor(col color.RGBA) string {
+	if col.A != 0 && col.A != 255 {
+		A := float64(col.A) / 255.0
+		col.R = uint8(math.Round(float64(col.R) / A))
+		col.G = uint8(math.Round(float64(col.G) / A))
+		col.B = uint8(math.Round(float64(col.B) / A))
+	}
+	col.A = 255
+	if name, ok := r.colors[col]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("canvasColor%d", len(r.colors))
+	fmt.Fprintf(r.w, "\\definecolor{%s}{RGB}{%d,%d,%d}\n", name, col.R, col.G, col.B)
+	r.colors[col] = name
+	return name
+}
+
+func opacity(col color.RGBA) string {
+	return strconv.FormatFloat(float64(col.A)/255.0, 'f', 3, 64)
+}
+
+func (r *TikZ) writePath(path *canvas.Path) {
+	path = path.ReplaceArcs()
+	path.Iterate(func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, " %s", r.point(end))
+	}, func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, " -- %s", r.point(end))
+	}, func(start, cp, end canvas.Point) {
+		cp1 := start.Interpolate(cp, 2.0/3.0)
+		cp2 := end.Interpolate(cp, 2.0/3.0)
+		fmt.Fprintf(r.w, " .. controls %s and %s .. %s", r.point(cp1), r.point(cp2), r.point(end))
+	}, func(start, cp1, cp2, end canvas.Point) {
+		fmt.Fprintf(r.w, " .. controls %s and %s .. %s", r.point(cp1), r.point(cp2), r.point(end))
+	}, nil, func(start, end canvas.Point) {
+		fmt.Fprintf(r.w, " -- cycle")
+	})
+}
+
+func (r *TikZ) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	fill := style.FillColor.A != 0
+	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	if path.Empty() || !fill && !stroke {
+		return
+	}
+	r.begin()
+	path = path.Transform(m)
+
+	strokeUnsupported := false
+	if _, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok {
+		strokeUnsupported = true // TikZ has no arcs line join
+	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+		if math.IsNaN(miter.Limit) {
+			strokeUnsupported = true
+		} else if _, ok := miter.GapJoiner.(canvas.BevelJoiner); !ok {
+			strokeUnsupported = true
+		}
+	}
+
+	options := []string{}
+	if fill {
+		options = append(options, "fill="+r.getColor(style.FillColor))
+		if style.FillColor.A != 255 {
+			options = append(options, "fill opacity="+opacity(style.FillColor))
+		}
+		if style.FillRule == canvas.EvenOdd {
+			options = append(options, "even odd rule")
+		}
+	}
+	if stroke && !strokeUnsupported {
+		options = append(options, "draw="+r.getColor(style.StrokeColor))
+		if style.StrokeColor.A != 255 {
+			options = append(options, "draw opacity="+opacity(style.StrokeColor))
+		}
+		options = append(options, "line width="+r.num(style.StrokeWidth)+r.unit)
+		if _, ok := style.StrokeCapper.(canvas.RoundCapper); ok {
+			options = append(options, "line cap=round")
+		} else if _, ok := style.StrokeCapper.(canvas.SquareCapper); ok {
+			options = append(options, "line cap=rect")
+		} else if _, ok := style.StrokeCapper.(canvas.ButtCapper); !ok {
+			panic("TikZ: line cap not support")
+		}
+		if _, ok := style.StrokeJoiner.(canvas.BevelJoiner); ok {
+			options = append(options, "line join=bevel")
+		} else if _, ok := style.StrokeJoiner.(canvas.RoundJoiner); ok {
+			options = append(options, "line join=round")
+		} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
+			// a miter line join is the default
+			if !canvas.Equal(miter.Limit, 10.0) {
+				options = append(options, "miter limit="+strconv.FormatFloat(miter.Limit, 'f', -1, 64))
+			}
+		} else {
+			panic("TikZ: line join not support")
+		}
+		if 0 < len(style.Dashes) {
+			dashes := ""
+			for i, dash := range style.Dashes {
+				if i%2 == 0 {
+					dashes += " on " + r.num(dash) + r.unit
+				} else {
+					dashes += " off " + r.num(dash) + r.unit
+				}
+			}
+			if len(style.Dashes)%2 == 1 {
+				// an odd number of dashes is repeated twice
+				for i, dash := range style.Dashes {
+					if i%2 == 0 {
+						dashes += " off " + r.num(dash) + r.unit
+					} else {
+						dashes += " on " + r.num(dash) + r.unit
+					}
+				}
+			}
+			options = append(options, "dash pattern="+dashes[1:])
+			if style.DashOffset != 0.0 {
+				options = append(options, "dash phase="+r.num(style.DashOffset)+r.unit)
+			}
+		}
+	}
+
+	if 0 < len(options) {
+		fmt.Fprintf(r.w, "\\path[%s]", strings.Join(options, ","))
+		r.writePath(path)
+		fmt.Fprintf(r.w, ";\n")
+	}
+
+	if stroke && strokeUnsupported {
+		// stroke settings unsupported by TikZ, draw stroke explicitly
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		options := []string{"fill=" + r.getColor(style.StrokeColor)}
+		if style.StrokeColor.A != 255 {
+			options = append(options, "fill opacity="+opacity(style.StrokeColor))
+		}
+		fmt.Fprintf(r.w, "\\path[%s]", strings.Join(options, ","))
+		r.writePath(path)
+		fmt.Fprintf(r.w, ";\n")
+	}
+}
+
+// escape escapes the characters that have a special meaning in LaTeX.
+func escape(s string) string {
+	sb := strings.Builder{}
+	for _, c := range s {
+		switch c {
+		case '\\':
+			sb.WriteString("\\textbackslash{}")
+		case '~':
+			sb.WriteString("\\textasciitilde{}")
+		case '^':
+			sb.WriteString("\\textasciicircum{}")
+		case '#', '$', '%', '&', '_', '{', '}':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// RenderText writes each text span as a node at its baseline, using the font size, style, and color of the span but the font family of the document. Text that is skewed or scaled non-uniformly is written as glyph outlines.
+func (r *TikZ) RenderText(text *canvas.Text, m canvas.Matrix) {
+	if r.textAsPaths || !m.IsRigid() {
+		text.RenderAsPath(r, m)
+		return
+	}
+	r.begin()
+
+	_, _, rot, _, _, _ := m.Decompose()
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		size := span.Face.Size * span.Face.Scale * 72.27 / 25.4 // in TeX points
+		font := fmt.Sprintf("\\fontsize{%s}{%s}\\selectfont", strconv.FormatFloat(size, 'f', 2, 64), strconv.FormatFloat(1.2*size, 'f', 2, 64))
+		if span.Face.Boldness() >= 600 {
+			font += "\\bfseries"
+		}
+		if span.Face.Style&canvas.FontItalic != 0 {
+			font += "\\itshape"
+		}
+		if span.Face.Variant&canvas.FontSmallcaps != 0 {
+			font += "\\scshape"
+		}
+
+		options := []string{"anchor=base west", "inner sep=0", "font=" + font}
+		if span.Face.Color != canvas.Black {
+			options = append(options, "text="+r.getColor(span.Face.Color))
+			if span.Face.Color.A != 255 {
+				options = append(options, "text opacity="+opacity(span.Face.Color))
+			}
+		}
+		if !canvas.Equal(rot, 0.0) {
+			options = append(options, "rotate="+strconv.FormatFloat(rot, 'f', -1, 64))
+		}
+		pos := m.Dot(canvas.Point{dx, y + span.Face.Voffset})
+		fmt.Fprintf(r.w, "\\node[%s] at %s {%s};\n", strings.Join(options, ","), r.point(pos), escape(span.Text))
+	})
+	text.RenderDecoration(r, m)
+}
+
+func (r *TikZ) RenderImage(img image.Image, m canvas.Matrix) {
+	// TODO: (TikZ) write image
+}
@@ -0,0 +1,79 @@
+package tikz
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testGolden(t *testing.T, filename string, b []byte) {
+	filename = filepath.Join("testdata", filename)
+	if *update {
+		test.Error(t, ioutil.WriteFile(filename, b, 0644))
+	}
+	golden, err := ioutil.ReadFile(filename)
+	test.Error(t, err)
+	test.String(t, string(b), string(golden))
+}
+
+func drawFigure() *canvas.Canvas {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		panic(err)
+	}
+
+	c := canvas.New(50.0, 30.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Steelblue)
+	ctx.DrawPath(15.0, 15.0, canvas.Circle(10.0))
+
+	ctx.SetFillColor(canvas.Transparent)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetDashes(1.0, 2.0, 1.0)
+	ctx.DrawPath(30.0, 5.0, canvas.MustParseSVG("M0 0L5 10L10 0L15 10"))
+
+	ctx.ResetStyle()
+	face := dejaVuSerif.Face(12.0, canvas.Red, canvas.FontItalic, canvas.FontNormal)
+	ctx.DrawText(30.0, 25.0, canvas.NewTextLine(face, "Label & 50%", canvas.Left))
+	return c
+}
+
+func TestTikZ(t *testing.T) {
+	c := drawFigure()
+
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	testGolden(t, "figure.tex", buf.Bytes())
+
+	buf.Reset()
+	tikz := New(buf, c.W, c.H)
+	tikz.SetUnit("pt", 2)
+	c.Render(tikz)
+	test.Error(t, tikz.Close())
+	testGolden(t, "figure_pt.tex", buf.Bytes())
+}
+
+func TestTikZTextAsPaths(t *testing.T) {
+	c := drawFigure()
+
+	buf := &bytes.Buffer{}
+	tikz := New(buf, c.W, c.H)
+	tikz.TextAsPaths(true)
+	c.Render(tikz)
+	test.Error(t, tikz.Close())
+	test.That(t, !strings.Contains(buf.String(), "\\node"))
+	test.That(t, strings.Count(buf.String(), "\\path") > 2)
+}
+
+func TestTikZEscape(t *testing.T) {
+	test.String(t, escape(`a\b{c}$d&e#f_g%h~i^j`), `a\textbackslash{}b\{c\}\$d\&e\#f\_g\%h\textasciitilde{}i\textasciicircum{}j`)
+}
@@ -0,0 +1,14 @@
+package tikz
+
+import (
+	"io"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Writer writes the canvas as a tikzpicture environment for inclusion in a LaTeX document (\usepackage{tikz}). Text is typeset by LaTeX using the document's font family.
+func Writer(w io.Writer, c *canvas.Canvas) error {
+	tikz := New(w, c.W, c.H)
+	c.Render(tikz)
+	return tikz.Close()
+}
@@ -1,10 +1,12 @@
 package canvas
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"image/color"
 	"math"
+	"strconv"
 	"strings"
 
 	"github.com/tdewolff/minify/v2"
@@ -68,6 +70,47 @@ func (f num) String() string {
 	return string(minify.Number([]byte(s), Precision))
 }
 
+// formatDecimals formats f rounded to the given number of decimals, removing trailing zeros and the leading zero for values between -1 and 1.
+func formatDecimals(f float64, decimals int) string {
+	return string(appendDecimals(nil, f, decimals))
+}
+
+// appendDecimals appends f to b formatted as by formatDecimals.
+func appendDecimals(b []byte, f float64, decimals int) []byte {
+	if decimals < 0 {
+		decimals = 0
+	}
+	start := len(b)
+	b = strconv.AppendFloat(b, f, 'f', decimals, 64)
+	num := b[start:]
+	if bytes.IndexByte(num, '.') != -1 {
+		num = bytes.TrimRight(num, "0")
+		num = bytes.TrimSuffix(num, []byte("."))
+	}
+	if len(num) == 2 && num[0] == '-' && num[1] == '0' {
+		num = num[1:]
+	} else if 2 < len(num) && num[0] == '0' && num[1] == '.' {
+		num = num[1:]
+	} else if 3 < len(num) && num[0] == '-' && num[1] == '0' && num[2] == '.' {
+		num[1] = '-'
+		num = num[1:]
+	}
+	n := copy(b[start:], num)
+	return b[:start+n]
+}
+
+// formatFixed formats f with exactly the given number of decimals, where negative zero is formatted as zero.
+func formatFixed(f float64, decimals int) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+	b := strconv.AppendFloat(nil, f, 'f', decimals, 64)
+	if b[0] == '-' && strings.Trim(string(b[1:]), "0.") == "" {
+		b = b[1:]
+	}
+	return string(b)
+}
+
 type dec float64
 
 func (f dec) String() string {